@@ -0,0 +1,51 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package main
+
+import (
+	"fmt"
+
+	"github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/user"
+	"github.com/elastic/cloud-on-k8s/pkg/utils/k8s"
+	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+var credentialsCmd = &cobra.Command{
+	Use:   "credentials ELASTICSEARCH_NAME",
+	Short: "Print the password of the elastic user of an Elasticsearch cluster",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ns, err := targetNamespace()
+		if err != nil {
+			return err
+		}
+		c, err := newWrappedClient()
+		if err != nil {
+			return err
+		}
+		password, err := elasticPassword(c, types.NamespacedName{Namespace: ns, Name: args[0]})
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(cmd.OutOrStdout(), password)
+		return err
+	},
+}
+
+// elasticPassword returns the password of the elastic user of the given Elasticsearch cluster.
+func elasticPassword(c k8s.Client, es types.NamespacedName) (string, error) {
+	var secret corev1.Secret
+	secretName := user.ElasticExternalUsersSecretName(es.Name)
+	if err := c.Get(types.NamespacedName{Namespace: es.Namespace, Name: secretName}, &secret); err != nil {
+		return "", err
+	}
+	password, exists := secret.Data[user.ExternalUserName]
+	if !exists {
+		return "", fmt.Errorf("no %s user in secret %s/%s", user.ExternalUserName, es.Namespace, secretName)
+	}
+	return string(password), nil
+}
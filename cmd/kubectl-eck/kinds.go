@@ -0,0 +1,79 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	apmv1alpha1 "github.com/elastic/cloud-on-k8s/pkg/apis/apm/v1alpha1"
+	esv1alpha1 "github.com/elastic/cloud-on-k8s/pkg/apis/elasticsearch/v1alpha1"
+	kbv1alpha1 "github.com/elastic/cloud-on-k8s/pkg/apis/kibana/v1alpha1"
+	apmconfig "github.com/elastic/cloud-on-k8s/pkg/controller/apmserver/config"
+	apmname "github.com/elastic/cloud-on-k8s/pkg/controller/apmserver/name"
+	esname "github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/name"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/network"
+	kbname "github.com/elastic/cloud-on-k8s/pkg/controller/kibana/name"
+	kbpod "github.com/elastic/cloud-on-k8s/pkg/controller/kibana/pod"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+const (
+	KindFlag = "kind"
+
+	defaultKind = "elasticsearch"
+)
+
+// resourceKind describes a kind of resource managed by the operator.
+type resourceKind struct {
+	name string
+	// aliases are the short names of the custom resource definition.
+	aliases     []string
+	newObject   func() runtime.Object
+	httpService func(name string) string
+	httpPort    int
+}
+
+var resourceKinds = []resourceKind{
+	{
+		name:        "elasticsearch",
+		aliases:     []string{"es"},
+		newObject:   func() runtime.Object { return &esv1alpha1.Elasticsearch{} },
+		httpService: esname.HTTPService,
+		httpPort:    network.HTTPPort,
+	},
+	{
+		name:        "kibana",
+		aliases:     []string{"kb"},
+		newObject:   func() runtime.Object { return &kbv1alpha1.Kibana{} },
+		httpService: kbname.HTTPService,
+		httpPort:    kbpod.HTTPPort,
+	},
+	{
+		name:        "apmserver",
+		aliases:     []string{"apm"},
+		newObject:   func() runtime.Object { return &apmv1alpha1.ApmServer{} },
+		httpService: apmname.HTTPService,
+		httpPort:    apmconfig.DefaultHTTPPort,
+	},
+}
+
+// lookupKind returns the resource kind with the given name or alias, case insensitive.
+func lookupKind(name string) (resourceKind, error) {
+	name = strings.ToLower(name)
+	names := make([]string, 0, len(resourceKinds))
+	for _, kind := range resourceKinds {
+		if kind.name == name {
+			return kind, nil
+		}
+		for _, alias := range kind.aliases {
+			if alias == name {
+				return kind, nil
+			}
+		}
+		names = append(names, kind.name)
+	}
+	return resourceKind{}, fmt.Errorf("unknown kind %q, expected one of %s", name, strings.Join(names, ", "))
+}
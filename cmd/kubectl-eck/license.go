@@ -0,0 +1,154 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"time"
+
+	commonv1alpha1 "github.com/elastic/cloud-on-k8s/pkg/apis/common/v1alpha1"
+	"github.com/elastic/cloud-on-k8s/pkg/apis/elasticsearch/v1alpha1"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/license"
+	esclient "github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/client"
+	esname "github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/name"
+	"github.com/elastic/cloud-on-k8s/pkg/utils/k8s"
+	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	AllNamespacesFlag = "all-namespaces"
+)
+
+var (
+	licenseCmd = &cobra.Command{
+		Use:   "license",
+		Short: "Show which enterprise license is applied to which Elasticsearch cluster",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ns := ""
+			if !licenseAllNamespaces {
+				var err error
+				if ns, err = targetNamespace(); err != nil {
+					return err
+				}
+			}
+			c, err := newWrappedClient()
+			if err != nil {
+				return err
+			}
+			licenses, err := clusterLicenses(c, ns)
+			if err != nil {
+				return err
+			}
+			return printClusterLicenses(cmd.OutOrStdout(), licenses)
+		},
+	}
+
+	licenseAllNamespaces bool
+)
+
+func init() {
+	licenseCmd.Flags().BoolVarP(&licenseAllNamespaces, AllNamespacesFlag, "A", false, "list the clusters of all namespaces")
+}
+
+// clusterLicense describes the license applied to an Elasticsearch cluster.
+type clusterLicense struct {
+	cluster types.NamespacedName
+	// enterpriseLicense is the secret of the enterprise license the cluster license comes from, if any.
+	enterpriseLicense *types.NamespacedName
+	// license is the cluster license, nil if the cluster runs with a basic license.
+	license *esclient.License
+	applied *commonv1alpha1.Condition
+}
+
+// clusterLicenses returns the license of each Elasticsearch cluster of the given namespace, or of all namespaces
+// if empty, along with the enterprise license it comes from.
+func clusterLicenses(c k8s.Client, namespace string) ([]clusterLicense, error) {
+	enterpriseLicenses, err := enterpriseLicenseSecrets(c)
+	if err != nil {
+		return nil, err
+	}
+	var clusters v1alpha1.ElasticsearchList
+	if err := c.List(&client.ListOptions{Namespace: namespace}, &clusters); err != nil {
+		return nil, err
+	}
+	results := make([]clusterLicense, 0, len(clusters.Items))
+	for _, es := range clusters.Items {
+		result := clusterLicense{
+			cluster: k8s.ExtractNamespacedName(&es),
+			applied: es.Status.Conditions.Get(commonv1alpha1.LicenseAppliedCondition),
+		}
+		var secret corev1.Secret
+		err := c.Get(types.NamespacedName{Namespace: es.Namespace, Name: esname.LicenseSecretName(es.Name)}, &secret)
+		switch {
+		case apierrors.IsNotFound(err):
+			// no cluster license: the cluster runs with a basic license
+			results = append(results, result)
+			continue
+		case err != nil:
+			return nil, err
+		}
+		var esLicense esclient.License
+		if err := json.Unmarshal(secret.Data[license.FileName], &esLicense); err != nil {
+			return nil, fmt.Errorf("unparseable license in secret %s/%s: %v", secret.Namespace, secret.Name, err)
+		}
+		result.license = &esLicense
+		if source, exists := enterpriseLicenses[secret.Labels[license.LicenseLabelName]]; exists {
+			result.enterpriseLicense = &source
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// enterpriseLicenseSecrets returns the secrets of the enterprise licenses, including trial licenses, by license UID.
+func enterpriseLicenseSecrets(c k8s.Client) (map[string]types.NamespacedName, error) {
+	var secrets corev1.SecretList
+	if err := c.List(&client.ListOptions{
+		LabelSelector: labels.SelectorFromSet(map[string]string{common.TypeLabelName: license.Type}),
+	}, &secrets); err != nil {
+		return nil, err
+	}
+	bySourceUID := make(map[string]types.NamespacedName, len(secrets.Items))
+	for _, secret := range secrets.Items {
+		parsed, err := license.ParseEnterpriseLicense(secret.Data)
+		if err != nil || parsed.License.UID == "" {
+			// invalid or not yet initialized trial license, which cannot be the source of a cluster license
+			continue
+		}
+		bySourceUID[parsed.License.UID] = k8s.ExtractNamespacedName(&secret)
+	}
+	return bySourceUID, nil
+}
+
+// printClusterLicenses writes a table of the licenses of the clusters.
+func printClusterLicenses(out io.Writer, licenses []clusterLicense) error {
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "ELASTICSEARCH\tTYPE\tEXPIRY\tENTERPRISE LICENSE\tAPPLIED")
+	for _, l := range licenses {
+		licenseType, expiry, source, applied := "basic", "-", "-", "-"
+		if l.license != nil {
+			licenseType = l.license.Type
+			expiry = l.license.ExpiryTime().UTC().Format(time.RFC3339)
+		}
+		if l.enterpriseLicense != nil {
+			source = l.enterpriseLicense.String()
+		}
+		if l.applied != nil {
+			applied = string(l.applied.Status)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", l.cluster, licenseType, expiry, source, applied)
+	}
+	return w.Flush()
+}
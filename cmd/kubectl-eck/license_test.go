@@ -0,0 +1,94 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/elastic/cloud-on-k8s/pkg/apis"
+	commonv1alpha1 "github.com/elastic/cloud-on-k8s/pkg/apis/common/v1alpha1"
+	"github.com/elastic/cloud-on-k8s/pkg/apis/elasticsearch/v1alpha1"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/license"
+	"github.com/elastic/cloud-on-k8s/pkg/utils/k8s"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func Test_clusterLicenses(t *testing.T) {
+	require.NoError(t, apis.AddToScheme(scheme.Scheme))
+
+	enterpriseLicense := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "elastic-system",
+			Name:      "my-license",
+			Labels:    license.LabelsForType(license.LicenseLabelEnterprise),
+		},
+		Data: map[string][]byte{license.FileName: []byte(`{"license": {"uid": "enterprise-uid", "type": "enterprise"}}`)},
+	}
+	uninitializedTrial := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "elastic-system",
+			Name:      "enterprise-trial",
+			Labels: map[string]string{
+				common.TypeLabelName:     license.Type,
+				license.LicenseLabelType: string(license.LicenseTypeEnterpriseTrial),
+			},
+		},
+	}
+	licensed := &v1alpha1.Elasticsearch{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "licensed"},
+		Status: v1alpha1.ElasticsearchStatus{ReconcilerStatus: commonv1alpha1.ReconcilerStatus{
+			Conditions: commonv1alpha1.Conditions{
+				{Type: commonv1alpha1.LicenseAppliedCondition, Status: corev1.ConditionTrue},
+			},
+		}},
+	}
+	clusterLicenseSecret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "ns",
+			Name:      "licensed-es-license",
+			Labels:    map[string]string{license.LicenseLabelName: "enterprise-uid"},
+		},
+		Data: map[string][]byte{
+			license.FileName: []byte(`{"uid": "cluster-uid", "type": "platinum", "expiry_date_in_millis": 1577836800000}`),
+		},
+	}
+	basic := &v1alpha1.Elasticsearch{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "basic"}}
+	otherNamespace := &v1alpha1.Elasticsearch{ObjectMeta: metav1.ObjectMeta{Namespace: "other-ns", Name: "basic"}}
+
+	c := k8s.WrapClient(fake.NewFakeClient(enterpriseLicense, uninitializedTrial, licensed, clusterLicenseSecret, basic, otherNamespace))
+
+	licenses, err := clusterLicenses(c, "ns")
+	require.NoError(t, err)
+	require.Len(t, licenses, 2)
+	byCluster := make(map[string]clusterLicense)
+	for _, l := range licenses {
+		byCluster[l.cluster.Name] = l
+	}
+
+	require.Nil(t, byCluster["basic"].license)
+	require.Nil(t, byCluster["basic"].enterpriseLicense)
+	require.Nil(t, byCluster["basic"].applied)
+
+	require.Equal(t, "platinum", byCluster["licensed"].license.Type)
+	require.Equal(t, &types.NamespacedName{Namespace: "elastic-system", Name: "my-license"}, byCluster["licensed"].enterpriseLicense)
+	require.True(t, byCluster["licensed"].applied.IsTrue())
+
+	var out bytes.Buffer
+	require.NoError(t, printClusterLicenses(&out, licenses))
+	require.Contains(t, out.String(), "ns/licensed    platinum  2020-01-01T00:00:00Z  elastic-system/my-license  True\n")
+	require.Contains(t, out.String(), "ns/basic       basic     -                     -                          -\n")
+
+	// all namespaces
+	licenses, err = clusterLicenses(c, "")
+	require.NoError(t, err)
+	require.Len(t, licenses, 3)
+}
@@ -0,0 +1,97 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+// kubectl-eck is a kubectl plugin for day-2 operations on the resources managed by the operator.
+// Once the binary is in the PATH, it is available as "kubectl eck".
+package main
+
+import (
+	"os"
+
+	"github.com/elastic/cloud-on-k8s/pkg/apis"
+	"github.com/elastic/cloud-on-k8s/pkg/utils/k8s"
+	"github.com/elastic/cloud-on-k8s/pkg/utils/log"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"k8s.io/client-go/kubernetes/scheme"
+	_ "k8s.io/client-go/plugin/pkg/client/auth/gcp"
+	"k8s.io/client-go/tools/clientcmd"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/config"
+)
+
+const (
+	NamespaceFlag = "namespace"
+)
+
+var (
+	rootCmd = &cobra.Command{
+		Use:          "kubectl-eck",
+		Short:        "Day-2 operations on Elastic resources managed by ECK",
+		SilenceUsage: true,
+	}
+
+	namespace string
+)
+
+func init() {
+	rootCmd.PersistentFlags().StringVarP(
+		&namespace,
+		NamespaceFlag,
+		"n",
+		"",
+		"namespace of the resources, defaults to the namespace of the current kubeconfig context",
+	)
+	log.BindFlags(rootCmd.PersistentFlags())
+	cobra.OnInitialize(func() {
+		log.InitLogger()
+	})
+
+	rootCmd.AddCommand(credentialsCmd, portForwardCmd, statusCmd, pauseCmd, resumeCmd, licenseCmd)
+}
+
+func main() {
+	if err := rootCmd.Execute(); err != nil {
+		os.Exit(1)
+	}
+}
+
+// newClient returns a client for the Kubernetes cluster of the current kubeconfig context.
+func newClient() (client.Client, error) {
+	cfg, err := config.GetConfig()
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to get the Kubernetes client configuration")
+	}
+	if err := apis.AddToScheme(scheme.Scheme); err != nil {
+		return nil, err
+	}
+	c, err := client.New(cfg, client.Options{Scheme: scheme.Scheme})
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to create the Kubernetes client")
+	}
+	return c, nil
+}
+
+// newWrappedClient returns a client for the Kubernetes cluster of the current kubeconfig context,
+// wrapped to use a default timeout.
+func newWrappedClient() (k8s.Client, error) {
+	c, err := newClient()
+	if err != nil {
+		return nil, err
+	}
+	return k8s.WrapClient(c), nil
+}
+
+// targetNamespace returns the namespace set with the namespace flag, or the namespace of the current
+// kubeconfig context.
+func targetNamespace() (string, error) {
+	if namespace != "" {
+		return namespace, nil
+	}
+	ns, _, err := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(
+		clientcmd.NewDefaultClientConfigLoadingRules(),
+		&clientcmd.ConfigOverrides{},
+	).Namespace()
+	return ns, err
+}
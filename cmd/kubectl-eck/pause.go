@@ -0,0 +1,90 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package main
+
+import (
+	"fmt"
+
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common"
+	"github.com/elastic/cloud-on-k8s/pkg/utils/k8s"
+	"github.com/spf13/cobra"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+var (
+	pauseCmd = &cobra.Command{
+		Use:   "pause NAME",
+		Short: "Pause the reconciliation of an Elastic resource by the operator",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runSetPaused(cmd, args[0], true)
+		},
+	}
+
+	resumeCmd = &cobra.Command{
+		Use:   "resume NAME",
+		Short: "Resume the reconciliation of a paused Elastic resource by the operator",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runSetPaused(cmd, args[0], false)
+		},
+	}
+
+	pauseKind string
+)
+
+func init() {
+	for _, cmd := range []*cobra.Command{pauseCmd, resumeCmd} {
+		cmd.Flags().StringVar(&pauseKind, KindFlag, defaultKind, "kind of the resource: elasticsearch, kibana or apmserver")
+	}
+}
+
+func runSetPaused(cmd *cobra.Command, name string, paused bool) error {
+	kind, err := lookupKind(pauseKind)
+	if err != nil {
+		return err
+	}
+	ns, err := targetNamespace()
+	if err != nil {
+		return err
+	}
+	c, err := newWrappedClient()
+	if err != nil {
+		return err
+	}
+	if err := setPaused(c, kind, types.NamespacedName{Namespace: ns, Name: name}, paused); err != nil {
+		return err
+	}
+	state := "resumed"
+	if paused {
+		state = "paused"
+	}
+	_, err = fmt.Fprintf(cmd.OutOrStdout(), "%s %s/%s %s\n", kind.name, ns, name, state)
+	return err
+}
+
+// setPaused sets or removes the pause annotation of the given resource.
+func setPaused(c k8s.Client, kind resourceKind, nsn types.NamespacedName, paused bool) error {
+	obj := kind.newObject()
+	if err := c.Get(nsn, obj); err != nil {
+		return err
+	}
+	accessor, err := meta.Accessor(obj)
+	if err != nil {
+		return err
+	}
+	annotations := accessor.GetAnnotations()
+	if paused {
+		if annotations == nil {
+			annotations = make(map[string]string)
+		}
+		annotations[common.PauseAnnotationName] = "true"
+	} else {
+		delete(annotations, common.PauseAnnotationName)
+	}
+	accessor.SetAnnotations(annotations)
+	return c.Update(obj)
+}
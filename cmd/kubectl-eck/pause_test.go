@@ -0,0 +1,43 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package main
+
+import (
+	"testing"
+
+	"github.com/elastic/cloud-on-k8s/pkg/apis"
+	kbv1alpha1 "github.com/elastic/cloud-on-k8s/pkg/apis/kibana/v1alpha1"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common"
+	"github.com/elastic/cloud-on-k8s/pkg/utils/k8s"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func Test_setPaused(t *testing.T) {
+	require.NoError(t, apis.AddToScheme(scheme.Scheme))
+	kind, err := lookupKind("kb")
+	require.NoError(t, err)
+	nsn := types.NamespacedName{Namespace: "ns", Name: "kb"}
+	c := k8s.WrapClient(fake.NewFakeClient(&kbv1alpha1.Kibana{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "kb", Annotations: map[string]string{"foo": "bar"}},
+	}))
+
+	require.NoError(t, setPaused(c, kind, nsn, true))
+	var kb kbv1alpha1.Kibana
+	require.NoError(t, c.Get(nsn, &kb))
+	require.True(t, common.IsPaused(kb.ObjectMeta))
+	require.Equal(t, "bar", kb.Annotations["foo"])
+
+	require.NoError(t, setPaused(c, kind, nsn, false))
+	require.NoError(t, c.Get(nsn, &kb))
+	require.False(t, common.IsPaused(kb.ObjectMeta))
+	require.Equal(t, map[string]string{"foo": "bar"}, kb.Annotations)
+
+	// unknown resource
+	require.Error(t, setPaused(c, kind, types.NamespacedName{Namespace: "ns", Name: "other"}, true))
+}
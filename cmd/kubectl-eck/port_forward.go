@@ -0,0 +1,134 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"sync"
+
+	"github.com/elastic/cloud-on-k8s/pkg/dev/portforward"
+	"github.com/spf13/cobra"
+	logf "sigs.k8s.io/controller-runtime/pkg/runtime/log"
+	"sigs.k8s.io/controller-runtime/pkg/runtime/signals"
+)
+
+const (
+	AddressFlag = "address"
+	PortFlag    = "port"
+)
+
+var (
+	portForwardCmd = &cobra.Command{
+		Use:   "port-forward NAME",
+		Short: "Forward a local port to the HTTP service of an Elastic resource",
+		Long: `port-forward listens on a local port and forwards each connection to one of the pods
+ backing the HTTP service of the resource, until interrupted.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			kind, err := lookupKind(portForwardKind)
+			if err != nil {
+				return err
+			}
+			ns, err := targetNamespace()
+			if err != nil {
+				return err
+			}
+			c, err := newClient()
+			if err != nil {
+				return err
+			}
+			serviceAddr := fmt.Sprintf("%s.%s.svc:%d", kind.httpService(args[0]), ns, kind.httpPort)
+			forwarder, err := portforward.NewServiceForwarder(c, "tcp", serviceAddr)
+			if err != nil {
+				return err
+			}
+			localPort := portForwardPort
+			if localPort == 0 {
+				localPort = kind.httpPort
+			}
+			listener, err := net.Listen("tcp", net.JoinHostPort(portForwardAddress, strconv.Itoa(localPort)))
+			if err != nil {
+				return err
+			}
+			if _, err := fmt.Fprintf(cmd.OutOrStdout(), "Forwarding from %s to %s\n", listener.Addr(), serviceAddr); err != nil {
+				return err
+			}
+
+			ctx, cancel := context.WithCancel(context.Background())
+			stop := signals.SetupSignalHandler()
+			go func() {
+				<-stop
+				cancel()
+			}()
+			return forward(ctx, listener, forwarder)
+		},
+	}
+
+	portForwardKind    string
+	portForwardAddress string
+	portForwardPort    int
+)
+
+func init() {
+	portForwardCmd.Flags().StringVar(&portForwardKind, KindFlag, defaultKind, "kind of the resource: elasticsearch, kibana or apmserver")
+	portForwardCmd.Flags().StringVar(&portForwardAddress, AddressFlag, "localhost", "local address to listen on")
+	portForwardCmd.Flags().IntVar(&portForwardPort, PortFlag, 0, "local port to listen on, defaults to the port of the service")
+}
+
+// forward accepts connections on the listener and pipes them to connections dialed through the forwarder,
+// until the context is cancelled.
+func forward(ctx context.Context, listener net.Listener, forwarder portforward.Forwarder) error {
+	log := logf.Log.WithName("port-forward")
+
+	go func() {
+		if err := forwarder.Run(ctx); err != nil {
+			log.Error(err, "Forwarder returned with an error")
+		}
+	}()
+	go func() {
+		<-ctx.Done()
+		_ = listener.Close()
+	}()
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				// listener closed on purpose
+				return nil
+			}
+			return err
+		}
+		go func() {
+			defer conn.Close()
+			remote, err := forwarder.DialContext(ctx)
+			if err != nil {
+				log.Error(err, "Failed to dial the forwarded address")
+				return
+			}
+			defer remote.Close()
+			pipe(conn, remote)
+		}()
+	}
+}
+
+// pipe copies data in both directions between the two connections until one of them is closed.
+func pipe(a, b net.Conn) {
+	var wg sync.WaitGroup
+	wg.Add(2)
+	copyAndClose := func(dst, src net.Conn) {
+		defer wg.Done()
+		_, _ = io.Copy(dst, src)
+		// unblock the copy in the other direction
+		_ = dst.Close()
+	}
+	go copyAndClose(a, b)
+	go copyAndClose(b, a)
+	wg.Wait()
+}
@@ -0,0 +1,74 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package main
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net"
+	"testing"
+
+	"github.com/elastic/cloud-on-k8s/pkg/dev/portforward"
+	"github.com/stretchr/testify/require"
+)
+
+// dialingForwarder is a Forwarder dialing a local address.
+type dialingForwarder struct {
+	addr string
+}
+
+var _ portforward.Forwarder = &dialingForwarder{}
+
+func (f *dialingForwarder) Run(ctx context.Context) error {
+	<-ctx.Done()
+	return nil
+}
+
+func (f *dialingForwarder) DialContext(ctx context.Context) (net.Conn, error) {
+	var d net.Dialer
+	return d.DialContext(ctx, "tcp", f.addr)
+}
+
+func Test_forward(t *testing.T) {
+	// echo server standing for the forwarded service
+	echo, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer echo.Close()
+	go func() {
+		for {
+			conn, err := echo.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				_, _ = io.Copy(conn, conn)
+			}()
+		}
+	}()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- forward(ctx, listener, &dialingForwarder{addr: echo.Addr().String()})
+	}()
+
+	for i := 0; i < 2; i++ {
+		conn, err := net.Dial("tcp", listener.Addr().String())
+		require.NoError(t, err)
+		_, err = conn.Write([]byte("ping\n"))
+		require.NoError(t, err)
+		line, err := bufio.NewReader(conn).ReadString('\n')
+		require.NoError(t, err)
+		require.Equal(t, "ping\n", line)
+		require.NoError(t, conn.Close())
+	}
+
+	cancel()
+	require.NoError(t, <-done)
+}
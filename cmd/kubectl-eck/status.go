@@ -0,0 +1,109 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	commonv1alpha1 "github.com/elastic/cloud-on-k8s/pkg/apis/common/v1alpha1"
+	"github.com/elastic/cloud-on-k8s/pkg/apis/elasticsearch/v1alpha1"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common"
+	"github.com/spf13/cobra"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+var statusCmd = &cobra.Command{
+	Use:   "status ELASTICSEARCH_NAME",
+	Short: "Show the status of an Elasticsearch cluster and what the operator is waiting for",
+	Long: `status shows the health and phase of an Elasticsearch cluster, the data migrations delaying the removal
+ of nodes, the predicates delaying a rolling upgrade, and the changes the operator still has to perform.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ns, err := targetNamespace()
+		if err != nil {
+			return err
+		}
+		c, err := newWrappedClient()
+		if err != nil {
+			return err
+		}
+		var es v1alpha1.Elasticsearch
+		if err := c.Get(types.NamespacedName{Namespace: ns, Name: args[0]}, &es); err != nil {
+			return err
+		}
+		return printStatus(cmd.OutOrStdout(), es)
+	},
+}
+
+// printStatus writes a human readable description of the status of the given Elasticsearch cluster.
+func printStatus(out io.Writer, es v1alpha1.Elasticsearch) error {
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(w, "Elasticsearch:\t%s/%s\n", es.Namespace, es.Name)
+	fmt.Fprintf(w, "Version:\t%s\n", es.Spec.Version)
+	fmt.Fprintf(w, "Health:\t%s\n", orNone(string(es.Status.Health)))
+	fmt.Fprintf(w, "Phase:\t%s\n", orNone(string(es.Status.Phase)))
+	fmt.Fprintf(w, "Available nodes:\t%d\n", es.Status.AvailableNodes)
+	fmt.Fprintf(w, "Paused:\t%t\n", common.IsPaused(es.ObjectMeta))
+	if es.Status.ObservedGeneration < es.Generation {
+		fmt.Fprintf(w, "Outdated:\tthe status reflects generation %d of %d\n", es.Status.ObservedGeneration, es.Generation)
+	}
+	fmt.Fprintf(w, "Data migration:\t%s\n", dataMigrationStatus(es.Status))
+	fmt.Fprintf(w, "Upgrade:\t%s\n", conditionMessage(es.Status.Conditions, commonv1alpha1.UpgradeInProgressCondition))
+
+	if len(es.Status.Conditions) > 0 {
+		fmt.Fprintln(w, "\nCONDITION\tSTATUS\tREASON\tMESSAGE")
+		for _, condition := range es.Status.Conditions {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", condition.Type, condition.Status, condition.Reason, condition.Message)
+		}
+	}
+
+	if len(es.Status.PendingChanges) > 0 {
+		fmt.Fprintln(w, "\nSTATEFULSET\tPENDING CHANGE\tNODES")
+		for _, ssetChanges := range es.Status.PendingChanges {
+			for _, change := range ssetChanges.Changes {
+				fmt.Fprintf(w, "%s\t%s\t%s\n", ssetChanges.StatefulSet, change.Type, strings.Join(change.Nodes, ", "))
+			}
+		}
+	}
+	return w.Flush()
+}
+
+// dataMigrationStatus describes the data migration in progress, including the nodes data is migrated away from.
+func dataMigrationStatus(status v1alpha1.ElasticsearchStatus) string {
+	if !status.Conditions.IsTrue(commonv1alpha1.DataMigrationCondition) {
+		return "none"
+	}
+	message := status.Conditions.Get(commonv1alpha1.DataMigrationCondition).Message
+	var nodes []string
+	for _, ssetChanges := range status.PendingChanges {
+		for _, change := range ssetChanges.Changes {
+			if change.Type == v1alpha1.ScaleDownChange || change.Type == v1alpha1.DeleteStatefulSetChange {
+				nodes = append(nodes, change.Nodes...)
+			}
+		}
+	}
+	if len(nodes) == 0 {
+		return message
+	}
+	return fmt.Sprintf("%s: %s", message, strings.Join(nodes, ", "))
+}
+
+// conditionMessage returns the message of the given condition if it is true, or "none".
+func conditionMessage(conditions commonv1alpha1.Conditions, conditionType commonv1alpha1.ConditionType) string {
+	if !conditions.IsTrue(conditionType) {
+		return "none"
+	}
+	return orNone(conditions.Get(conditionType).Message)
+}
+
+func orNone(value string) string {
+	if value == "" {
+		return "none"
+	}
+	return value
+}
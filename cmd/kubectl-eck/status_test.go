@@ -0,0 +1,104 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	commonv1alpha1 "github.com/elastic/cloud-on-k8s/pkg/apis/common/v1alpha1"
+	"github.com/elastic/cloud-on-k8s/pkg/apis/elasticsearch/v1alpha1"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func Test_printStatus(t *testing.T) {
+	es := v1alpha1.Elasticsearch{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "es", Generation: 3},
+		Spec:       v1alpha1.ElasticsearchSpec{Version: "7.2.0"},
+	}
+	tests := []struct {
+		name   string
+		status v1alpha1.ElasticsearchStatus
+		want   []string
+	}{
+		{
+			name: "no status yet",
+			want: []string{
+				"Elasticsearch:    ns/es\n",
+				"Version:          7.2.0\n",
+				"Health:           none\n",
+				"Paused:           false\n",
+				"Outdated:         the status reflects generation 0 of 3\n",
+				"Data migration:   none\n",
+				"Upgrade:          none\n",
+			},
+		},
+		{
+			name: "data migration and upgrade delayed by predicates",
+			status: v1alpha1.ElasticsearchStatus{
+				ReconcilerStatus: commonv1alpha1.ReconcilerStatus{
+					AvailableNodes:     3,
+					ObservedGeneration: 3,
+					Conditions: commonv1alpha1.Conditions{
+						{
+							Type:    commonv1alpha1.DataMigrationCondition,
+							Status:  corev1.ConditionTrue,
+							Reason:  "MigratingData",
+							Message: "Data is being migrated away from the nodes to remove",
+						},
+						{
+							Type:    commonv1alpha1.UpgradeInProgressCondition,
+							Status:  corev1.ConditionTrue,
+							Reason:  "UpgradeDelayed",
+							Message: "1 nodes to restart, delayed by predicates: es-master-0 (require_started_replica)",
+						},
+					},
+				},
+				Health: v1alpha1.ElasticsearchYellowHealth,
+				Phase:  v1alpha1.ElasticsearchMigratingDataPhase,
+				PendingChanges: []v1alpha1.StatefulSetChanges{
+					{
+						StatefulSet: "es-data",
+						Changes: []v1alpha1.Change{
+							{Type: v1alpha1.ScaleDownChange, Nodes: []string{"es-data-2"}},
+						},
+					},
+					{
+						StatefulSet: "es-master",
+						Changes: []v1alpha1.Change{
+							{Type: v1alpha1.RestartNodesChange, Nodes: []string{"es-master-0"}},
+						},
+					},
+				},
+			},
+			want: []string{
+				"Health:           yellow\n",
+				"Phase:            MigratingData\n",
+				"Available nodes:  3\n",
+				"Data migration:   Data is being migrated away from the nodes to remove: es-data-2\n",
+				"Upgrade:          1 nodes to restart, delayed by predicates: es-master-0 (require_started_replica)\n",
+				"UpgradeInProgress  True    UpgradeDelayed",
+				"es-data      ScaleDown       es-data-2\n",
+				"es-master    RestartNodes    es-master-0\n",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			es := es
+			es.Status = tt.status
+			var out bytes.Buffer
+			require.NoError(t, printStatus(&out, es))
+			for _, want := range tt.want {
+				require.Contains(t, out.String(), want)
+			}
+			if tt.status.ObservedGeneration == es.Generation {
+				require.NotContains(t, out.String(), "Outdated")
+			}
+		})
+	}
+}
@@ -6,6 +6,7 @@ package main
 
 import (
 	"github.com/elastic/cloud-on-k8s/cmd/manager"
+	"github.com/elastic/cloud-on-k8s/cmd/plan"
 	"github.com/elastic/cloud-on-k8s/pkg/dev"
 	"github.com/elastic/cloud-on-k8s/pkg/utils/log"
 	"github.com/spf13/cobra"
@@ -16,6 +17,7 @@ import (
 func main() {
 	var rootCmd = &cobra.Command{Use: "elastic-operator"}
 	rootCmd.AddCommand(manager.Cmd)
+	rootCmd.AddCommand(plan.Cmd)
 	// development mode is only available as a command line flag to avoid accidentally enabling it
 	rootCmd.PersistentFlags().BoolVar(&dev.Enabled, "development", false, "turns on development mode")
 	log.BindFlags(rootCmd.PersistentFlags())
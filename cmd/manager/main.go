@@ -14,15 +14,22 @@ import (
 
 	"github.com/elastic/cloud-on-k8s/pkg/about"
 	"github.com/elastic/cloud-on-k8s/pkg/apis"
+	commonv1alpha1 "github.com/elastic/cloud-on-k8s/pkg/apis/common/v1alpha1"
 	"github.com/elastic/cloud-on-k8s/pkg/controller"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/common/certificates"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/heap"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/ingress"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/common/operator"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/nodetuning"
 	"github.com/elastic/cloud-on-k8s/pkg/dev"
 	"github.com/elastic/cloud-on-k8s/pkg/dev/portforward"
 	"github.com/elastic/cloud-on-k8s/pkg/utils/net"
 	"github.com/elastic/cloud-on-k8s/pkg/webhook"
+	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes"
 	"sigs.k8s.io/controller-runtime/pkg/client/config"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
@@ -41,6 +48,8 @@ const (
 	CACertRotateBeforeFlag = "ca-cert-rotate-before"
 	CertValidityFlag       = "cert-validity"
 	CertRotateBeforeFlag   = "cert-rotate-before"
+	CertKeyAlgorithmFlag   = "cert-key-algorithm"
+	CertKeySizeFlag        = "cert-key-size"
 
 	AutoInstallWebhooksFlag = "auto-install-webhooks"
 	OperatorNamespaceFlag   = "operator-namespace"
@@ -48,6 +57,15 @@ const (
 	WebhookPodsLabelFlag    = "webhook-pods-label"
 
 	DebugHTTPServerListenAddressFlag = "debug-http-listen"
+
+	HeapMemoryPercentageFlag = "heap-memory-percentage"
+
+	RestrictedModeFlag      = "restricted-mode"
+	NodeTuningDaemonSetFlag = "node-tuning-daemonset"
+	NodeTuningImageFlag     = "node-tuning-image"
+
+	NetworkPolicyOperatorPodsSelectorFlag      = "network-policy-operator-pods-selector"
+	NetworkPolicyOperatorNamespaceSelectorFlag = "network-policy-operator-namespace-selector"
 )
 
 var (
@@ -108,6 +126,16 @@ func init() {
 		certificates.DefaultRotateBefore,
 		"Duration representing how long before expiration TLS certificates should be reissued",
 	)
+	Cmd.Flags().String(
+		CertKeyAlgorithmFlag,
+		string(certificates.DefaultKeyParams.Algorithm),
+		"Algorithm of the private keys generated for CA and TLS certificates (either RSA or ECDSA)",
+	)
+	Cmd.Flags().Int(
+		CertKeySizeFlag,
+		0,
+		"Size in bits of the private keys generated for CA and TLS certificates (defaults to 2048 for RSA, 256 for ECDSA)",
+	)
 	Cmd.Flags().Bool(
 		AutoInstallWebhooksFlag,
 		true,
@@ -128,6 +156,36 @@ func init() {
 		"",
 		"k8s secret mounted into /tmp/cert to be used for webhook certificates",
 	)
+	Cmd.Flags().Int(
+		HeapMemoryPercentageFlag,
+		heap.DefaultMemoryPercentage,
+		"percentage of the container memory limit used as Elasticsearch and Kibana heap size when not specified, 0 to disable",
+	)
+	Cmd.Flags().Bool(
+		RestrictedModeFlag,
+		false,
+		"never create privileged or root containers in the managed pods",
+	)
+	Cmd.Flags().Bool(
+		NodeTuningDaemonSetFlag,
+		false,
+		"in restricted mode, deploy a privileged DaemonSet in the operator namespace to set vm.max_map_count on each node",
+	)
+	Cmd.Flags().String(
+		NodeTuningImageFlag,
+		nodetuning.DefaultImage,
+		"image of the node tuning DaemonSet",
+	)
+	Cmd.Flags().String(
+		NetworkPolicyOperatorPodsSelectorFlag,
+		"control-plane=elastic-operator",
+		"label selector matching the operator pods, allowed to reach Elasticsearch in the generated NetworkPolicies",
+	)
+	Cmd.Flags().String(
+		NetworkPolicyOperatorNamespaceSelectorFlag,
+		"",
+		"label selector matching the operator namespace in the generated NetworkPolicies, all namespaces if empty",
+	)
 	Cmd.Flags().String(
 		DebugHTTPServerListenAddressFlag,
 		"localhost:6060",
@@ -219,10 +277,21 @@ func execute() {
 		log.Error(err, "unable add APIs to scheme")
 		os.Exit(1)
 	}
+	// OpenShift Routes are handled as unstructured objects
+	ingress.AddRouteToScheme(mgr.GetScheme())
 
 	// Verify cert validity options
 	caCertValidity, caCertRotateBefore := ValidateCertExpirationFlags(CACertValidityFlag, CACertRotateBeforeFlag)
 	certValidity, certRotateBefore := ValidateCertExpirationFlags(CertValidityFlag, CertRotateBeforeFlag)
+	certKeyParams := ValidateCertKeyFlags(CertKeyAlgorithmFlag, CertKeySizeFlag)
+	heapMemoryPercentage := viper.GetInt(HeapMemoryPercentageFlag)
+	if heapMemoryPercentage < 0 || heapMemoryPercentage > 100 {
+		log.Error(fmt.Errorf("%s must be between 0 and 100", HeapMemoryPercentageFlag), "")
+		os.Exit(1)
+	}
+	networkPolicyOperatorPeer := ValidateNetworkPolicyOperatorFlags(
+		NetworkPolicyOperatorPodsSelectorFlag, NetworkPolicyOperatorNamespaceSelectorFlag,
+	)
 	// Setup all Controllers
 	roles := viper.GetStringSlice(operator.RoleFlag)
 	err = operator.ValidateRoles(roles)
@@ -244,6 +313,16 @@ func execute() {
 		os.Exit(1)
 	}
 
+	restrictedMode := operator.RestrictedMode{
+		Enabled:         viper.GetBool(RestrictedModeFlag),
+		NodeTuning:      viper.GetBool(NodeTuningDaemonSetFlag),
+		NodeTuningImage: viper.GetString(NodeTuningImageFlag),
+	}
+	if restrictedMode.NodeTuning && !restrictedMode.Enabled {
+		log.Error(fmt.Errorf("%s requires %s", NodeTuningDaemonSetFlag, RestrictedModeFlag), "")
+		os.Exit(1)
+	}
+
 	log.Info("Setting up controllers", "roles", roles)
 	if err := controller.AddToManager(mgr, roles, operator.Parameters{
 		Dialer:            dialer,
@@ -257,6 +336,10 @@ func execute() {
 			Validity:     certValidity,
 			RotateBefore: certRotateBefore,
 		},
+		CertKeyParams:             certKeyParams,
+		NetworkPolicyOperatorPeer: networkPolicyOperatorPeer,
+		HeapMemoryPercentage:      heapMemoryPercentage,
+		RestrictedMode:            restrictedMode,
 	}); err != nil {
 		log.Error(err, "unable to register controllers to the manager")
 		os.Exit(1)
@@ -306,3 +389,30 @@ func ValidateCertExpirationFlags(validityFlag string, rotateBeforeFlag string) (
 	}
 	return certValidity, certRotateBefore
 }
+
+func ValidateCertKeyFlags(algorithmFlag string, sizeFlag string) certificates.KeyParams {
+	keyParams := certificates.DefaultKeyParams.WithOverrides(&commonv1alpha1.PrivateKeyOptions{
+		Algorithm: commonv1alpha1.PrivateKeyAlgorithm(viper.GetString(algorithmFlag)),
+		Size:      viper.GetInt(sizeFlag),
+	})
+	if err := keyParams.Validate(); err != nil {
+		log.Error(errors.Wrapf(err, "invalid %s or %s", algorithmFlag, sizeFlag), "")
+		os.Exit(1)
+	}
+	return keyParams
+}
+
+// ValidateNetworkPolicyOperatorFlags parses the label selectors of the operator pods and namespace, and exits on error.
+func ValidateNetworkPolicyOperatorFlags(podsSelectorFlag string, namespaceSelectorFlag string) networkingv1.NetworkPolicyPeer {
+	podSelector, err := metav1.ParseToLabelSelector(viper.GetString(podsSelectorFlag))
+	if err != nil {
+		log.Error(errors.Wrapf(err, "invalid %s", podsSelectorFlag), "")
+		os.Exit(1)
+	}
+	namespaceSelector, err := metav1.ParseToLabelSelector(viper.GetString(namespaceSelectorFlag))
+	if err != nil {
+		log.Error(errors.Wrapf(err, "invalid %s", namespaceSelectorFlag), "")
+		os.Exit(1)
+	}
+	return networkingv1.NetworkPolicyPeer{PodSelector: podSelector, NamespaceSelector: namespaceSelector}
+}
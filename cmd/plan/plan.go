@@ -0,0 +1,141 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package plan
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+
+	"github.com/elastic/cloud-on-k8s/pkg/apis"
+	"github.com/elastic/cloud-on-k8s/pkg/apis/elasticsearch/v1alpha1"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/heap"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/operator"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/driver"
+	"github.com/elastic/cloud-on-k8s/pkg/utils/k8s"
+	"github.com/ghodss/yaml"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/config"
+)
+
+const (
+	FileFlag                 = "file"
+	NamespaceFlag            = "namespace"
+	HeapMemoryPercentageFlag = "heap-memory-percentage"
+	RestrictedModeFlag       = "restricted-mode"
+	NodeTuningFlag           = "node-tuning-daemonset"
+
+	defaultNamespace = "default"
+)
+
+var (
+	// Cmd is the cobra command computing the plan of an Elasticsearch manifest.
+	Cmd = &cobra.Command{
+		Use:   "plan",
+		Short: "Show the changes an Elasticsearch manifest would lead to",
+		Long: `plan computes the changes the operator would perform on the StatefulSets of the Elasticsearch resource
+ described in a manifest, against the live resources of the cluster, without applying any change.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return execute(cmd.OutOrStdout())
+		},
+	}
+
+	file                 string
+	namespace            string
+	heapMemoryPercentage int
+	restricted           operator.RestrictedMode
+)
+
+func init() {
+	Cmd.Flags().StringVarP(&file, FileFlag, "f", "", "path to the Elasticsearch manifest, - to read it from the standard input")
+	Cmd.Flags().StringVarP(
+		&namespace,
+		NamespaceFlag,
+		"n",
+		"",
+		"namespace of the Elasticsearch resource, if not specified in the manifest (defaults to default)",
+	)
+	Cmd.Flags().IntVar(
+		&heapMemoryPercentage,
+		HeapMemoryPercentageFlag,
+		heap.DefaultMemoryPercentage,
+		"heap memory percentage the operator is configured with",
+	)
+	Cmd.Flags().BoolVar(&restricted.Enabled, RestrictedModeFlag, false, "whether the operator runs in restricted mode")
+	Cmd.Flags().BoolVar(
+		&restricted.NodeTuning,
+		NodeTuningFlag,
+		false,
+		"whether the operator deploys the node tuning DaemonSet in restricted mode",
+	)
+	_ = Cmd.MarkFlagRequired(FileFlag)
+}
+
+func execute(out io.Writer) error {
+	es, err := readManifest(file)
+	if err != nil {
+		return err
+	}
+	if es.Namespace == "" {
+		es.Namespace = namespace
+	}
+	if es.Namespace == "" {
+		es.Namespace = defaultNamespace
+	}
+
+	cfg, err := config.GetConfig()
+	if err != nil {
+		return errors.Wrap(err, "unable to get the Kubernetes client configuration")
+	}
+	if err := apis.AddToScheme(scheme.Scheme); err != nil {
+		return err
+	}
+	c, err := client.New(cfg, client.Options{Scheme: scheme.Scheme})
+	if err != nil {
+		return errors.Wrap(err, "unable to create the Kubernetes client")
+	}
+
+	changes, err := driver.PlanChanges(k8s.WrapClient(c), es, heapMemoryPercentage, restricted)
+	if err != nil {
+		return err
+	}
+	if len(changes) == 0 {
+		_, err := fmt.Fprintf(out, "No change for Elasticsearch %s/%s\n", es.Namespace, es.Name)
+		return err
+	}
+	bytes, err := yaml.Marshal(changes)
+	if err != nil {
+		return err
+	}
+	_, err = out.Write(bytes)
+	return err
+}
+
+// readManifest reads the Elasticsearch resource from the given file, or from the standard input if "-".
+func readManifest(path string) (v1alpha1.Elasticsearch, error) {
+	var es v1alpha1.Elasticsearch
+	var bytes []byte
+	var err error
+	if path == "-" {
+		bytes, err = ioutil.ReadAll(os.Stdin)
+	} else {
+		bytes, err = ioutil.ReadFile(path)
+	}
+	if err != nil {
+		return es, err
+	}
+	if err := yaml.Unmarshal(bytes, &es); err != nil {
+		return es, errors.Wrapf(err, "unable to parse the Elasticsearch manifest %s", path)
+	}
+	if es.TypeMeta.Kind != es.Kind() {
+		return es, fmt.Errorf("expected an Elasticsearch manifest, got kind %q", es.TypeMeta.Kind)
+	}
+	return es, nil
+}
@@ -32,10 +32,29 @@ type ApmServerSpec struct {
 	// HTTP contains settings for HTTP.
 	HTTP commonv1alpha1.HTTPConfig `json:"http,omitempty"`
 
+	// NetworkPolicy configures the NetworkPolicy restricting the traffic to the pods.
+	// +optional
+	NetworkPolicy commonv1alpha1.NetworkPolicySpec `json:"networkPolicy,omitempty"`
+
 	// ElasticsearchRef references an Elasticsearch resource in the Kubernetes cluster.
 	// If the namespace is not specified, the current resource namespace will be used.
 	ElasticsearchRef commonv1alpha1.ObjectSelector `json:"elasticsearchRef,omitempty"`
 
+	// ExternalElasticsearchRef references an Elasticsearch cluster that is not managed by the operator,
+	// as an alternative to ElasticsearchRef. Both cannot be set at the same time.
+	// +optional
+	ExternalElasticsearchRef *commonv1alpha1.ExternalElasticsearchRef `json:"externalElasticsearchRef,omitempty"`
+
+	// KibanaRef references a Kibana resource in the Kubernetes cluster, used for agent central configuration
+	// and source maps.
+	// If the namespace is not specified, the current resource namespace will be used.
+	// +optional
+	KibanaRef commonv1alpha1.ObjectSelector `json:"kibanaRef,omitempty"`
+
+	// Auth contains settings for the authentication of APM agents.
+	// +optional
+	Auth ApmServerAuth `json:"auth,omitempty"`
+
 	// PodTemplate can be used to propagate configuration to APM Server pods.
 	// This allows specifying custom annotations, labels, environment variables,
 	// affinity, resources, etc. for the pods created from this NodeSpec.
@@ -52,6 +71,22 @@ type ApmServerSpec struct {
 	SecureSettings []commonv1alpha1.SecretSource `json:"secureSettings,omitempty"`
 }
 
+// ApmServerAuth contains settings for the authentication of APM agents.
+// A secret token is always generated: it can be rotated by changing the value of the
+// `apm.k8s.elastic.co/secret-token-rotation` annotation on the ApmServer resource.
+type ApmServerAuth struct {
+	// APIKey contains settings for API key authentication, available in addition to the secret token.
+	// +optional
+	APIKey APIKeyAuth `json:"apiKey,omitempty"`
+}
+
+// APIKeyAuth contains settings for API key authentication.
+type APIKeyAuth struct {
+	// Enabled makes the operator create an API key in the associated Elasticsearch cluster and store it in a Secret
+	// agents can use. Requires APM Server 7.6.0 or later and an association with Elasticsearch.
+	Enabled bool `json:"enabled,omitempty"`
+}
+
 // ApmServerHealth expresses the status of the Apm Server instances.
 type ApmServerHealth string
 
@@ -70,8 +105,18 @@ type ApmServerStatus struct {
 	ExternalService string `json:"service,omitempty"`
 	// SecretTokenSecretName is the name of the Secret that contains the secret token
 	SecretTokenSecretName string `json:"secretTokenSecret,omitempty"`
+	// APIKeySecretName is the name of the Secret that contains the API key agents can use, when API key
+	// authentication is enabled.
+	APIKeySecretName string `json:"apiKeySecret,omitempty"`
 	// Association is the status of any auto-linking to Elasticsearch clusters.
 	Association commonv1alpha1.AssociationStatus
+	// KibanaAssociation is the status of any auto-linking to Kibana.
+	KibanaAssociation commonv1alpha1.AssociationStatus `json:"kibanaAssociationStatus,omitempty"`
+	// UpgradeStatus is the status of the version upgrade, if it is held back.
+	UpgradeStatus commonv1alpha1.UpgradeStatus `json:"upgradeStatus,omitempty"`
+	// Version is the version of the deployed APM Server, which differs from the specified version while its upgrade is
+	// held back.
+	Version string `json:"version,omitempty"`
 }
 
 // IsDegraded returns true if the current status is worse than the previous.
@@ -94,9 +139,10 @@ type ApmServer struct {
 	metav1.TypeMeta   `json:",inline"`
 	metav1.ObjectMeta `json:"metadata,omitempty"`
 
-	Spec      ApmServerSpec   `json:"spec,omitempty"`
-	Status    ApmServerStatus `json:"status,omitempty"`
-	assocConf *commonv1alpha1.AssociationConf
+	Spec        ApmServerSpec   `json:"spec,omitempty"`
+	Status      ApmServerStatus `json:"status,omitempty"`
+	assocConf   *commonv1alpha1.AssociationConf
+	kbAssocConf *commonv1alpha1.AssociationConf
 }
 
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
@@ -121,6 +167,14 @@ func (as *ApmServer) ElasticsearchRef() commonv1alpha1.ObjectSelector {
 	return as.Spec.ElasticsearchRef
 }
 
+func (as *ApmServer) ExternalElasticsearchRef() *commonv1alpha1.ExternalElasticsearchRef {
+	return as.Spec.ExternalElasticsearchRef
+}
+
+func (as *ApmServer) KibanaRef() commonv1alpha1.ObjectSelector {
+	return as.Spec.KibanaRef
+}
+
 func (as *ApmServer) SecureSettings() []commonv1alpha1.SecretSource {
 	return as.Spec.SecureSettings
 }
@@ -138,3 +192,13 @@ func (as *ApmServer) AssociationConf() *commonv1alpha1.AssociationConf {
 func (as *ApmServer) SetAssociationConf(assocConf *commonv1alpha1.AssociationConf) {
 	as.assocConf = assocConf
 }
+
+// KibanaAssociationConf returns the configuration of the association with Kibana.
+func (as *ApmServer) KibanaAssociationConf() *commonv1alpha1.AssociationConf {
+	return as.kbAssocConf
+}
+
+// SetKibanaAssociationConf sets the configuration of the association with Kibana.
+func (as *ApmServer) SetKibanaAssociationConf(assocConf *commonv1alpha1.AssociationConf) {
+	as.kbAssocConf = assocConf
+}
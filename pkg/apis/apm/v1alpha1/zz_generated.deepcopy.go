@@ -13,6 +13,22 @@ import (
 	runtime "k8s.io/apimachinery/pkg/runtime"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *APIKeyAuth) DeepCopyInto(out *APIKeyAuth) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new APIKeyAuth.
+func (in *APIKeyAuth) DeepCopy() *APIKeyAuth {
+	if in == nil {
+		return nil
+	}
+	out := new(APIKeyAuth)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ApmServer) DeepCopyInto(out *ApmServer) {
 	*out = *in
@@ -25,6 +41,11 @@ func (in *ApmServer) DeepCopyInto(out *ApmServer) {
 		*out = new(commonv1alpha1.AssociationConf)
 		**out = **in
 	}
+	if in.kbAssocConf != nil {
+		in, out := &in.kbAssocConf, &out.kbAssocConf
+		*out = new(commonv1alpha1.AssociationConf)
+		**out = **in
+	}
 	return
 }
 
@@ -46,6 +67,23 @@ func (in *ApmServer) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ApmServerAuth) DeepCopyInto(out *ApmServerAuth) {
+	*out = *in
+	out.APIKey = in.APIKey
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ApmServerAuth.
+func (in *ApmServerAuth) DeepCopy() *ApmServerAuth {
+	if in == nil {
+		return nil
+	}
+	out := new(ApmServerAuth)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ApmServerList) DeepCopyInto(out *ApmServerList) {
 	*out = *in
@@ -87,7 +125,15 @@ func (in *ApmServerSpec) DeepCopyInto(out *ApmServerSpec) {
 		*out = (*in).DeepCopy()
 	}
 	in.HTTP.DeepCopyInto(&out.HTTP)
+	in.NetworkPolicy.DeepCopyInto(&out.NetworkPolicy)
 	out.ElasticsearchRef = in.ElasticsearchRef
+	if in.ExternalElasticsearchRef != nil {
+		in, out := &in.ExternalElasticsearchRef, &out.ExternalElasticsearchRef
+		*out = new(commonv1alpha1.ExternalElasticsearchRef)
+		**out = **in
+	}
+	out.KibanaRef = in.KibanaRef
+	out.Auth = in.Auth
 	in.PodTemplate.DeepCopyInto(&out.PodTemplate)
 	if in.SecureSettings != nil {
 		in, out := &in.SecureSettings, &out.SecureSettings
@@ -112,7 +158,7 @@ func (in *ApmServerSpec) DeepCopy() *ApmServerSpec {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ApmServerStatus) DeepCopyInto(out *ApmServerStatus) {
 	*out = *in
-	out.ReconcilerStatus = in.ReconcilerStatus
+	in.ReconcilerStatus.DeepCopyInto(&out.ReconcilerStatus)
 	return
 }
 
@@ -19,6 +19,16 @@ const (
 	AssociationFailed      AssociationStatus = "Failed"
 )
 
+// UpgradeStatus is the status of the version upgrade of an associated resource.
+type UpgradeStatus string
+
+const (
+	UpgradeStatusUnknown UpgradeStatus = ""
+	// WaitingForElasticsearchUpgrade indicates the version upgrade is held back until all the nodes of the associated
+	// Elasticsearch cluster run the same version or a newer one.
+	WaitingForElasticsearchUpgrade UpgradeStatus = "WaitingForElasticsearchUpgrade"
+)
+
 // Associated interface represents a Elastic stack application that is associated with an Elasticsearch cluster.
 // An associated object needs some credentials to establish a connection to the Elasticsearch cluster and usually it
 // offers a keystore which in ECK is represented with an underlying Secret.
@@ -27,6 +37,7 @@ type Associated interface {
 	metav1.Object
 	runtime.Object
 	ElasticsearchRef() ObjectSelector
+	ExternalElasticsearchRef() *ExternalElasticsearchRef
 	AssociationConf() *AssociationConf
 }
 
@@ -37,6 +48,27 @@ type Associator interface {
 	SetAssociationConf(*AssociationConf)
 }
 
+// ExternalElasticsearchRef references an Elasticsearch cluster that is not managed by the operator.
+type ExternalElasticsearchRef struct {
+	// URL of the Elasticsearch HTTP endpoint, for example https://elasticsearch.example.com:9200.
+	URL string `json:"url"`
+
+	// AuthSecret references a secret in the namespace of the associated resource.
+	// It must contain the `username` and `password` entries used to authenticate against Elasticsearch.
+	AuthSecret SecretRef `json:"authSecret"`
+
+	// CASecret optionally references a secret in the namespace of the associated resource.
+	// It must contain the `ca.crt` entry with the certificate authority used to verify the
+	// Elasticsearch HTTP certificates. If not set, the system trust store is used.
+	// +optional
+	CASecret SecretRef `json:"caSecret,omitempty"`
+}
+
+// IsDefined returns true if the external reference is not nil and has a URL.
+func (r *ExternalElasticsearchRef) IsDefined() bool {
+	return r != nil && r.URL != ""
+}
+
 // AssociationConf holds the association configuration of an Elasticsearch cluster.
 type AssociationConf struct {
 	AuthSecretName string `json:"authSecretName"`
@@ -45,9 +77,10 @@ type AssociationConf struct {
 	URL            string `json:"url"`
 }
 
-// IsConfigured returns true if all the fields are set.
+// IsConfigured returns true if the auth and URL fields are set.
+// The CA is optional since an external Elasticsearch cluster may use a publicly trusted certificate.
 func (esac *AssociationConf) IsConfigured() bool {
-	return esac.AuthIsConfigured() && esac.CAIsConfigured() && esac.URLIsConfigured()
+	return esac.AuthIsConfigured() && esac.URLIsConfigured()
 }
 
 // AuthIsConfigured returns true if all the auth fields are set.
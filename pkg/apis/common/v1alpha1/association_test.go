@@ -54,7 +54,7 @@ func TestAssociationConfIsConfigured(t *testing.T) {
 				AuthSecretKey:  "elastic",
 				URL:            "https://my-es.svc",
 			},
-			want: false,
+			want: true,
 		},
 		{
 			name: "correctly configured",
@@ -6,15 +6,21 @@ package v1alpha1
 
 import (
 	v1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
 	"k8s.io/api/policy/v1beta1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/intstr"
 )
 
-// ReconcilerStatus represents status information about desired/available nodes.
+// ReconcilerStatus represents status information about desired/available nodes, and the progress of the
+// reconciliation.
 type ReconcilerStatus struct {
 	AvailableNodes int `json:"availableNodes,omitempty"`
+	// ObservedGeneration is the generation of the specification the status reflects.
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+	// Conditions describe the current state of the resource.
+	Conditions Conditions `json:"conditions,omitempty"`
 }
 
 // SecretRef reference a secret by name.
@@ -48,6 +54,9 @@ type HTTPConfig struct {
 	Service ServiceTemplate `json:"service,omitempty"`
 	// TLS describe additional options to consider when generating HTTP TLS certificates.
 	TLS TLSOptions `json:"tls,omitempty"`
+	// Ingress is a template for a Kubernetes Ingress exposing the service, and an OpenShift Route if that API is
+	// available. The host is added to the SANs of the self-signed HTTP TLS certificate.
+	Ingress *IngressTemplate `json:"ingress,omitempty"`
 }
 
 // Scheme returns the scheme for this HTTP config
@@ -71,6 +80,42 @@ type TLSOptions struct {
 	// - `tls.crt`: The certificate (or a chain).
 	// - `tls.key`: The private key to the first certificate in the certificate chain.
 	Certificate SecretRef `json:"certificate,omitempty"`
+
+	// CertificateAuthority is a reference to a secret that contains the certificate authority used to issue
+	// the certificate, instead of a self-signed certificate authority managed by the operator.
+	// It is ignored if Certificate is specified.
+	//
+	// The secret should have the following content:
+	//
+	// - `tls.crt`: The certificate of the authority, optionally followed by the certificates of its issuers.
+	// - `tls.key`: The private key of the certificate authority.
+	// - `ca.crt`: The certificates of the issuers of the certificate authority, up to the root (optional).
+	CertificateAuthority SecretRef `json:"certificateAuthority,omitempty"`
+
+	// PrivateKey defines the algorithm and size of the private keys generated by the operator
+	// for the self-signed certificate authority and the certificates.
+	// Defaults to the operator settings if not specified.
+	PrivateKey *PrivateKeyOptions `json:"privateKey,omitempty"`
+}
+
+// PrivateKeyAlgorithm is the algorithm of a private key.
+type PrivateKeyAlgorithm string
+
+const (
+	// RSAPrivateKeyAlgorithm is the RSA algorithm.
+	RSAPrivateKeyAlgorithm PrivateKeyAlgorithm = "RSA"
+	// ECDSAPrivateKeyAlgorithm is the ECDSA algorithm.
+	ECDSAPrivateKeyAlgorithm PrivateKeyAlgorithm = "ECDSA"
+)
+
+// PrivateKeyOptions defines the algorithm and size of generated private keys.
+type PrivateKeyOptions struct {
+	// Algorithm is the algorithm of the private keys: RSA or ECDSA.
+	// +kubebuilder:validation:Enum=RSA,ECDSA
+	Algorithm PrivateKeyAlgorithm `json:"algorithm,omitempty"`
+	// Size is the size of the private keys in bits: 2048, 3072 or 4096 for RSA, 256 or 384 for ECDSA.
+	// Defaults to 2048 for RSA and 256 for ECDSA.
+	Size int `json:"size,omitempty"`
 }
 
 // Enabled returns true when TLS is enabled based on this option struct.
@@ -104,6 +149,42 @@ type ServiceTemplate struct {
 	Spec v1.ServiceSpec `json:"spec,omitempty"`
 }
 
+// IngressTemplate describes the data an Ingress or a Route should have when created from a template.
+type IngressTemplate struct {
+	// ObjectMeta is metadata for the Ingress and the Route.
+	// The name and namespace provided here is managed by ECK and will be ignored.
+	// +optional
+	ObjectMeta metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	// Host is the fully qualified domain name the service is exposed on.
+	Host string `json:"host"`
+
+	// Path is the path the service is exposed on. Defaults to "/".
+	// +optional
+	Path string `json:"path,omitempty"`
+
+	// SecretName is the name of a secret containing the certificate and private key presented by the ingress
+	// controller for the host. Defaults to the default certificate of the ingress controller.
+	// +optional
+	SecretName string `json:"secretName,omitempty"`
+}
+
+// DefaultIngressPath is the path a service is exposed on if not specified in the IngressTemplate.
+const DefaultIngressPath = "/"
+
+// NetworkPolicySpec configures the NetworkPolicy restricting the traffic to the pods of a resource.
+type NetworkPolicySpec struct {
+	// Enabled generates a NetworkPolicy restricting the ingress traffic to the pods of the resource.
+	// Only the operator, the pods of associated resources and the sources listed in HTTPFrom are allowed
+	// to reach the HTTP port, depending on the kind of resource.
+	// +optional
+	Enabled bool `json:"enabled,omitempty"`
+
+	// HTTPFrom lists additional sources allowed to reach the HTTP port of the pods, for example an ingress controller.
+	// +optional
+	HTTPFrom []networkingv1.NetworkPolicyPeer `json:"httpFrom,omitempty"`
+}
+
 // DefaultPodDisruptionBudgetMaxUnavailable is the default max unavailable pods in a PDB.
 var DefaultPodDisruptionBudgetMaxUnavailable = intstr.FromInt(1)
 
@@ -0,0 +1,96 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package v1alpha1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ConditionType is the type of a condition of a resource managed by the operator.
+type ConditionType string
+
+const (
+	// ReadyCondition indicates the resource is running at the desired specification and available.
+	ReadyCondition ConditionType = "Ready"
+	// ReconcilingCondition indicates the operator is still working towards the desired specification.
+	ReconcilingCondition ConditionType = "Reconciling"
+	// UpgradeInProgressCondition indicates nodes or pods are being restarted to apply a new specification.
+	UpgradeInProgressCondition ConditionType = "UpgradeInProgress"
+	// DataMigrationCondition indicates data is being migrated away from Elasticsearch nodes before their removal.
+	DataMigrationCondition ConditionType = "DataMigration"
+	// LicenseAppliedCondition indicates the expected license is applied to the Elasticsearch cluster.
+	LicenseAppliedCondition ConditionType = "LicenseApplied"
+	// AssociationReadyCondition indicates the association with Elasticsearch is established.
+	AssociationReadyCondition ConditionType = "AssociationReady"
+	// InvalidCondition indicates the specification of the resource is invalid and cannot be reconciled.
+	InvalidCondition ConditionType = "Invalid"
+)
+
+// Condition describes the state of a resource at a certain point, for one of its aspects.
+type Condition struct {
+	Type   ConditionType          `json:"type"`
+	Status corev1.ConditionStatus `json:"status"`
+	// LastTransitionTime is the last time the status of the condition changed.
+	LastTransitionTime metav1.Time `json:"lastTransitionTime,omitempty"`
+	// Reason is a CamelCase reason for the last transition.
+	Reason string `json:"reason,omitempty"`
+	// Message is a human readable message with details about the last transition.
+	Message string `json:"message,omitempty"`
+}
+
+// NewCondition returns a condition of the given type, with a status depending on the given boolean.
+// Its transition time is set to the current time.
+func NewCondition(conditionType ConditionType, status bool, reason string, message string) Condition {
+	conditionStatus := corev1.ConditionFalse
+	if status {
+		conditionStatus = corev1.ConditionTrue
+	}
+	return Condition{
+		Type:               conditionType,
+		Status:             conditionStatus,
+		LastTransitionTime: metav1.Now(),
+		Reason:             reason,
+		Message:            message,
+	}
+}
+
+// IsTrue returns true if the status of the condition is True.
+func (c Condition) IsTrue() bool {
+	return c.Status == corev1.ConditionTrue
+}
+
+// Conditions is a list of conditions, with at most one condition per type.
+type Conditions []Condition
+
+// Get returns the condition of the given type, or nil if there is none.
+func (c Conditions) Get(conditionType ConditionType) *Condition {
+	for i := range c {
+		if c[i].Type == conditionType {
+			return &c[i]
+		}
+	}
+	return nil
+}
+
+// IsTrue returns true if the condition of the given type exists and its status is True.
+func (c Conditions) IsTrue(conditionType ConditionType) bool {
+	condition := c.Get(conditionType)
+	return condition != nil && condition.IsTrue()
+}
+
+// Set returns the conditions with the given condition added, or replacing the condition of the same type.
+// The transition time of the replaced condition is kept if its status does not change.
+func (c Conditions) Set(condition Condition) Conditions {
+	existing := c.Get(condition.Type)
+	if existing == nil {
+		return append(c, condition)
+	}
+	if existing.Status == condition.Status {
+		condition.LastTransitionTime = existing.LastTransitionTime
+	}
+	*existing = condition
+	return c
+}
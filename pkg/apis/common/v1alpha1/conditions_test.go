@@ -0,0 +1,69 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package v1alpha1
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestConditions_Set(t *testing.T) {
+	before := metav1.NewTime(time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC))
+	now := metav1.NewTime(time.Date(2019, 1, 2, 0, 0, 0, 0, time.UTC))
+	ready := Condition{Type: ReadyCondition, Status: corev1.ConditionTrue, LastTransitionTime: before}
+	reconciling := Condition{Type: ReconcilingCondition, Status: corev1.ConditionFalse, LastTransitionTime: before}
+
+	tests := []struct {
+		name       string
+		conditions Conditions
+		condition  Condition
+		want       Conditions
+	}{
+		{
+			name:       "add a condition",
+			conditions: nil,
+			condition:  Condition{Type: ReadyCondition, Status: corev1.ConditionTrue, LastTransitionTime: now},
+			want:       Conditions{{Type: ReadyCondition, Status: corev1.ConditionTrue, LastTransitionTime: now}},
+		},
+		{
+			name:       "same status: keep the transition time, update the reason and message",
+			conditions: Conditions{reconciling, ready},
+			condition:  Condition{Type: ReadyCondition, Status: corev1.ConditionTrue, LastTransitionTime: now, Reason: "r", Message: "m"},
+			want: Conditions{
+				reconciling,
+				{Type: ReadyCondition, Status: corev1.ConditionTrue, LastTransitionTime: before, Reason: "r", Message: "m"},
+			},
+		},
+		{
+			name:       "status change: update the transition time",
+			conditions: Conditions{reconciling, ready},
+			condition:  Condition{Type: ReadyCondition, Status: corev1.ConditionFalse, LastTransitionTime: now},
+			want: Conditions{
+				reconciling,
+				{Type: ReadyCondition, Status: corev1.ConditionFalse, LastTransitionTime: now},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.conditions.DeepCopy().Set(tt.condition)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestConditions_IsTrue(t *testing.T) {
+	conditions := Conditions{
+		{Type: ReadyCondition, Status: corev1.ConditionTrue},
+		{Type: ReconcilingCondition, Status: corev1.ConditionFalse},
+	}
+	assert.True(t, conditions.IsTrue(ReadyCondition))
+	assert.False(t, conditions.IsTrue(ReconcilingCondition))
+	assert.False(t, conditions.IsTrue(InvalidCondition))
+}
@@ -5,6 +5,9 @@
 package v1alpha1
 
 import (
+	"math"
+	"time"
+
 	commonv1alpha1 "github.com/elastic/cloud-on-k8s/pkg/apis/common/v1alpha1"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -27,11 +30,25 @@ type ElasticsearchSpec struct {
 	// is set according to https://www.elastic.co/guide/en/elasticsearch/reference/current/vm-max-map-count.html.
 	// Setting this to true requires the kubelet to allow running privileged containers.
 	// Defaults to true if not specified. To be disabled, it must be explicitly set to false.
+	// Ignored if the operator runs in restricted mode, where no privileged container is created.
 	SetVMMaxMapCount *bool `json:"setVmMaxMapCount,omitempty"`
 
 	// HTTP contains settings for HTTP.
 	HTTP commonv1alpha1.HTTPConfig `json:"http,omitempty"`
 
+	// ExternalServiceNodeSpecs lists the names of the NodeSpecs whose nodes back the external HTTP Service,
+	// for example to send the client requests to coordinating or ingest nodes only.
+	// All the nodes back the external HTTP Service if not specified.
+	// +optional
+	ExternalServiceNodeSpecs []string `json:"externalServiceNodeSpecs,omitempty"`
+
+	// NetworkPolicy configures the NetworkPolicy restricting the traffic to the pods.
+	// +optional
+	NetworkPolicy commonv1alpha1.NetworkPolicySpec `json:"networkPolicy,omitempty"`
+
+	// Transport contains settings for the transport layer used for communication between nodes.
+	Transport TransportConfig `json:"transport,omitempty"`
+
 	// Nodes represents a list of groups of nodes with the same configuration to be part of the cluster
 	Nodes []NodeSpec `json:"nodes,omitempty"`
 
@@ -40,11 +57,21 @@ type ElasticsearchSpec struct {
 
 	// PodDisruptionBudget allows full control of the default pod disruption budget.
 	//
-	// The default budget selects all cluster pods and sets maxUnavailable to 1.
+	// The default budget selects all cluster pods and adjusts maxUnavailable to the cluster health:
+	// 1 if the cluster is green, 0 otherwise. Setting maxUnavailable or minAvailable disables the adjustment.
 	// To disable it entirely, set to the empty value (`{}` in YAML).
 	// +optional
 	PodDisruptionBudget *commonv1alpha1.PodDisruptionBudgetTemplate `json:"podDisruptionBudget,omitempty"`
 
+	// PodDisruptionBudgetPerNodeSpec creates one default pod disruption budget per NodeSpec instead of a single
+	// budget for the whole cluster, allowing pods of different NodeSpecs to be disrupted at the same time.
+	// +optional
+	PodDisruptionBudgetPerNodeSpec bool `json:"podDisruptionBudgetPerNodeSpec,omitempty"`
+
+	// Auth contains the authentication settings of the cluster, such as SAML, OpenID Connect, LDAP or PKI realms.
+	// Authentication realms require an enterprise license.
+	Auth Auth `json:"auth,omitempty"`
+
 	// SecureSettings references secrets containing secure settings, to be injected
 	// into Elasticsearch keystore on each node.
 	// Each individual key/value entry in the referenced secrets is considered as an
@@ -53,6 +80,37 @@ type ElasticsearchSpec struct {
 	// entries and the `path` field to change the target path of a secret entry key.
 	// The secret must exist in the same namespace as the Elasticsearch resource.
 	SecureSettings []commonv1alpha1.SecretSource `json:"secureSettings,omitempty"`
+
+	// VolumeClaimDeletePolicy sets the policy for the deletion of the PersistentVolumeClaims of the Elasticsearch
+	// nodes: DeleteOnScaledownAndClusterDeletion, DeleteOnScaledownOnly or Retain.
+	// Defaults to Retain if not specified.
+	// +kubebuilder:validation:Enum=DeleteOnScaledownAndClusterDeletion,DeleteOnScaledownOnly,Retain
+	// +optional
+	VolumeClaimDeletePolicy VolumeClaimDeletePolicy `json:"volumeClaimDeletePolicy,omitempty"`
+}
+
+// VolumeClaimDeletePolicy describes the policy for the deletion of the PersistentVolumeClaims of the nodes.
+type VolumeClaimDeletePolicy string
+
+const (
+	// DeleteOnScaledownAndClusterDeletionPolicy deletes the PersistentVolumeClaims of the nodes removed by a downscale,
+	// and all the PersistentVolumeClaims of the cluster when it is deleted.
+	DeleteOnScaledownAndClusterDeletionPolicy VolumeClaimDeletePolicy = "DeleteOnScaledownAndClusterDeletion"
+	// DeleteOnScaledownOnlyPolicy deletes the PersistentVolumeClaims of the nodes removed by a downscale,
+	// but keeps them when the cluster is deleted.
+	DeleteOnScaledownOnlyPolicy VolumeClaimDeletePolicy = "DeleteOnScaledownOnly"
+	// RetainPolicy never deletes the PersistentVolumeClaims.
+	RetainPolicy VolumeClaimDeletePolicy = "Retain"
+)
+
+// DeleteOnScaledown returns true if the PersistentVolumeClaims of the nodes removed by a downscale should be deleted.
+func (p VolumeClaimDeletePolicy) DeleteOnScaledown() bool {
+	return p == DeleteOnScaledownAndClusterDeletionPolicy || p == DeleteOnScaledownOnlyPolicy
+}
+
+// DeleteOnClusterDeletion returns true if the PersistentVolumeClaims should be deleted with the cluster.
+func (p VolumeClaimDeletePolicy) DeleteOnClusterDeletion() bool {
+	return p == DeleteOnScaledownAndClusterDeletionPolicy
 }
 
 // NodeCount returns the total number of nodes of the Elasticsearch cluster
@@ -64,6 +122,43 @@ func (es ElasticsearchSpec) NodeCount() int32 {
 	return count
 }
 
+// TransportConfig configures the transport layer used for communication between nodes.
+type TransportConfig struct {
+	// TLS describe additional options to consider when generating transport TLS certificates.
+	TLS TransportTLSOptions `json:"tls,omitempty"`
+}
+
+// TransportTLSOptions are options to consider when generating transport TLS certificates.
+type TransportTLSOptions struct {
+	// CertificateAuthority is a reference to a secret that contains the certificate authority used to issue
+	// the transport certificates, instead of a self-signed certificate authority managed by the operator.
+	//
+	// The secret should have the following content:
+	//
+	// - `tls.crt`: The certificate of the authority, optionally followed by the certificates of its issuers.
+	// - `tls.key`: The private key of the certificate authority.
+	// - `ca.crt`: The certificates of the issuers of the certificate authority, up to the root (optional).
+	CertificateAuthority commonv1alpha1.SecretRef `json:"certificateAuthority,omitempty"`
+
+	// PrivateKey defines the algorithm and size of the private keys generated by the operator
+	// for the self-signed certificate authority and the transport certificates.
+	// Defaults to the operator settings if not specified.
+	PrivateKey *commonv1alpha1.PrivateKeyOptions `json:"privateKey,omitempty"`
+
+	// TrustRestrictions restricts the transport certificates trusted by the Elasticsearch nodes to the certificates
+	// of the pods currently part of the cluster, and to the given subject names.
+	// If not specified, every certificate issued by the certificate authority is trusted.
+	// +optional
+	TrustRestrictions *TrustRestrictions `json:"trustRestrictions,omitempty"`
+}
+
+// TrustRestrictions restricts the transport certificates trusted by the Elasticsearch nodes.
+type TrustRestrictions struct {
+	// SubjectNames are additional subject names to trust, for example the names of the nodes of remote clusters.
+	// Wildcards are supported, as in `*.node.remote-cluster.default.es.local`.
+	SubjectNames []string `json:"subjectNames,omitempty"`
+}
+
 // NodeSpec defines a common topology for a set of Elasticsearch nodes
 type NodeSpec struct {
 	// Name is a logical name for this set of nodes. Used as a part of the managed Elasticsearch node.name setting.
@@ -91,6 +186,16 @@ type NodeSpec struct {
 	// TODO: define special behavior based on claim metadata.name. (e.g data / logs volumes)
 	// +optional
 	VolumeClaimTemplates []corev1.PersistentVolumeClaim `json:"volumeClaimTemplates,omitempty"`
+
+	// Service is a template for an additional Kubernetes Service selecting only the nodes of this NodeSpec, named
+	// after the cluster and the NodeSpec: <cluster name>-es-<NodeSpec name>-http. No Service is created if not specified.
+	// +optional
+	Service *commonv1alpha1.ServiceTemplate `json:"service,omitempty"`
+
+	// Plugins lists the plugins installed on the nodes before Elasticsearch starts.
+	// Changing this list triggers a rolling restart of the nodes.
+	// +optional
+	Plugins []Plugin `json:"plugins,omitempty"`
 }
 
 // GetESContainerTemplate returns the Elasticsearch container (if set) from the NodeSpec's PodTemplate
@@ -103,8 +208,36 @@ func (n NodeSpec) GetESContainerTemplate() *corev1.Container {
 	return nil
 }
 
+// Plugin is an Elasticsearch plugin, installed either from a remote location or from a bundle stored in a volume.
+type Plugin struct {
+	// Name is the name of an official plugin (e.g. analysis-icu), or the URL of a plugin bundle.
+	// Exactly one of Name or Bundle must be specified.
+	// +optional
+	Name string `json:"name,omitempty"`
+
+	// Bundle references a plugin bundle stored in a volume of the pod template, for offline installations.
+	// +optional
+	Bundle *PluginBundle `json:"bundle,omitempty"`
+}
+
+// PluginBundle references a plugin bundle zip file stored in a volume of the pod template.
+type PluginBundle struct {
+	// VolumeName is the name of the volume of the pod template containing the bundle, e.g. a ConfigMap volume.
+	VolumeName string `json:"volumeName"`
+
+	// Path is the path of the bundle in the volume.
+	Path string `json:"path"`
+}
+
 // UpdateStrategy specifies how updates to the cluster should be performed.
 type UpdateStrategy struct {
+	// Type is the type of update: RollingUpdate restarts the nodes one by one, FullClusterRestart stops all the
+	// nodes to update at once, then restarts them together. A full cluster restart allows version upgrades that
+	// a rolling upgrade does not support. Defaults to RollingUpdate if not specified.
+	// +kubebuilder:validation:Enum=RollingUpdate,FullClusterRestart
+	// +optional
+	Type UpdateStrategyType `json:"type,omitempty"`
+
 	// Groups is a list of groups that should have their cluster mutations considered in a fair manner with a strict
 	// change budget (not allowing any surge or unavailability) before the entire cluster is reconciled with the
 	// full change budget.
@@ -112,8 +245,28 @@ type UpdateStrategy struct {
 
 	// ChangeBudget is the change budget that should be used when performing mutations to the cluster.
 	ChangeBudget *ChangeBudget `json:"changeBudget,omitempty"`
+
+	// Canary restarts a few canary nodes first during a rolling upgrade, then waits for a soak period before
+	// upgrading the other nodes. The upgrade is paused if the cluster health degrades.
+	// +optional
+	Canary *CanaryStrategy `json:"canary,omitempty"`
+}
+
+// FullClusterRestart returns true if the nodes should be restarted all at once.
+func (s UpdateStrategy) FullClusterRestart() bool {
+	return s.Type == FullClusterRestartStrategyType
 }
 
+// UpdateStrategyType is the type of update of the nodes of the cluster.
+type UpdateStrategyType string
+
+const (
+	// RollingUpdateStrategyType restarts the nodes one by one, keeping the cluster available.
+	RollingUpdateStrategyType UpdateStrategyType = "RollingUpdate"
+	// FullClusterRestartStrategyType stops all the nodes to update, then restarts them together.
+	FullClusterRestartStrategyType UpdateStrategyType = "FullClusterRestart"
+)
+
 // ResolveChangeBudget resolves the optional ChangeBudget into the user-provided one or a defaulted one.
 func (s UpdateStrategy) ResolveChangeBudget() ChangeBudget {
 	if s.ChangeBudget != nil {
@@ -123,6 +276,51 @@ func (s UpdateStrategy) ResolveChangeBudget() ChangeBudget {
 	return DefaultChangeBudget
 }
 
+// CanaryStrategy specifies how the canary nodes of a rolling upgrade are restarted and monitored.
+type CanaryStrategy struct {
+	// Nodes is the number of nodes to restart first. Defaults to 1.
+	// +optional
+	Nodes int `json:"nodes,omitempty"`
+
+	// Percent is the percentage of the nodes to upgrade to restart first, rounded up.
+	// Takes precedence over Nodes if specified.
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=100
+	// +optional
+	Percent int `json:"percent,omitempty"`
+
+	// SoakPeriod is the duration to wait for once the canary nodes are restarted, before checking the cluster health
+	// and upgrading the other nodes. Defaults to 10 minutes.
+	// +optional
+	SoakPeriod *metav1.Duration `json:"soakPeriod,omitempty"`
+}
+
+// DefaultCanarySoakPeriod is the soak period used when none is specified.
+var DefaultCanarySoakPeriod = 10 * time.Minute
+
+// CanaryNodes returns the number of canary nodes to restart among the given number of nodes to upgrade.
+func (c CanaryStrategy) CanaryNodes(nodesToUpgrade int) int {
+	canaries := 1
+	switch {
+	case c.Percent > 0:
+		canaries = int(math.Ceil(float64(nodesToUpgrade) * float64(c.Percent) / 100))
+	case c.Nodes > 0:
+		canaries = c.Nodes
+	}
+	if canaries > nodesToUpgrade {
+		return nodesToUpgrade
+	}
+	return canaries
+}
+
+// ResolveSoakPeriod returns the user-provided soak period, or the default one.
+func (c CanaryStrategy) ResolveSoakPeriod() time.Duration {
+	if c.SoakPeriod != nil {
+		return c.SoakPeriod.Duration
+	}
+	return DefaultCanarySoakPeriod
+}
+
 // GroupingDefinition is used to select a group of pods.
 type GroupingDefinition struct {
 	// Selector is the selector used to match pods.
@@ -221,12 +419,45 @@ type ElasticsearchStatus struct {
 	MasterNode      string                          `json:"masterNode,omitempty"`
 	ExternalService string                          `json:"service,omitempty"`
 	ZenDiscovery    ZenDiscoveryStatus              `json:"zenDiscovery,omitempty"`
+	// PendingChanges lists the changes the operator still has to perform on each StatefulSet to reach the
+	// expected topology.
+	PendingChanges []StatefulSetChanges `json:"pendingChanges,omitempty"`
 }
 
 type ZenDiscoveryStatus struct {
 	MinimumMasterNodes int `json:"minimumMasterNodes,omitempty"`
 }
 
+// ChangeType is the type of a change performed by the operator on a StatefulSet.
+type ChangeType string
+
+const (
+	// CreateStatefulSetChange creates a StatefulSet and its nodes.
+	CreateStatefulSetChange ChangeType = "CreateStatefulSet"
+	// ScaleUpChange adds nodes to a StatefulSet.
+	ScaleUpChange ChangeType = "ScaleUp"
+	// ScaleDownChange migrates data away from nodes, then removes them from a StatefulSet.
+	ScaleDownChange ChangeType = "ScaleDown"
+	// DeleteStatefulSetChange migrates data away from all the nodes of a StatefulSet, then deletes it.
+	DeleteStatefulSetChange ChangeType = "DeleteStatefulSet"
+	// RestartNodesChange restarts nodes one by one to apply a new specification.
+	RestartNodesChange ChangeType = "RestartNodes"
+)
+
+// StatefulSetChanges lists the changes to perform on a StatefulSet, in the order the operator performs them.
+type StatefulSetChanges struct {
+	// StatefulSet is the name of the StatefulSet.
+	StatefulSet string   `json:"statefulSet"`
+	Changes     []Change `json:"changes"`
+}
+
+// Change is a change to perform on a StatefulSet.
+type Change struct {
+	Type ChangeType `json:"type"`
+	// Nodes lists the nodes affected by the change.
+	Nodes []string `json:"nodes,omitempty"`
+}
+
 // IsDegraded returns true if the current status is worse than the previous.
 func (es ElasticsearchStatus) IsDegraded(prev ElasticsearchStatus) bool {
 	return es.Health.Less(prev.Health)
@@ -124,3 +124,53 @@ func TestElasticsearchCluster_IsMarkedForDeletion(t *testing.T) {
 		})
 	}
 }
+
+func TestCanaryStrategy_CanaryNodes(t *testing.T) {
+	tests := []struct {
+		name           string
+		canary         CanaryStrategy
+		nodesToUpgrade int
+		want           int
+	}{
+		{
+			name:           "defaults to 1 node",
+			canary:         CanaryStrategy{},
+			nodesToUpgrade: 10,
+			want:           1,
+		},
+		{
+			name:           "number of nodes",
+			canary:         CanaryStrategy{Nodes: 3},
+			nodesToUpgrade: 10,
+			want:           3,
+		},
+		{
+			name:           "percentage of the nodes, rounded up",
+			canary:         CanaryStrategy{Percent: 25},
+			nodesToUpgrade: 10,
+			want:           3,
+		},
+		{
+			name:           "percentage takes precedence over the number of nodes",
+			canary:         CanaryStrategy{Nodes: 5, Percent: 10},
+			nodesToUpgrade: 10,
+			want:           1,
+		},
+		{
+			name:           "no more than the nodes to upgrade",
+			canary:         CanaryStrategy{Nodes: 5},
+			nodesToUpgrade: 2,
+			want:           2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, tt.canary.CanaryNodes(tt.nodesToUpgrade))
+		})
+	}
+}
+
+func TestCanaryStrategy_ResolveSoakPeriod(t *testing.T) {
+	require.Equal(t, DefaultCanarySoakPeriod, CanaryStrategy{}.ResolveSoakPeriod())
+	require.Equal(t, 5*time.Minute, CanaryStrategy{SoakPeriod: &metav1.Duration{Duration: 5 * time.Minute}}.ResolveSoakPeriod())
+}
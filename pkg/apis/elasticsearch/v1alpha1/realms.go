@@ -0,0 +1,175 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package v1alpha1
+
+import (
+	commonv1alpha1 "github.com/elastic/cloud-on-k8s/pkg/apis/common/v1alpha1"
+)
+
+// RealmType is the type of an authentication realm.
+type RealmType string
+
+const (
+	// SAMLRealmType is the type of SAML realms.
+	SAMLRealmType RealmType = "saml"
+	// OIDCRealmType is the type of OpenID Connect realms.
+	OIDCRealmType RealmType = "oidc"
+	// LDAPRealmType is the type of LDAP realms.
+	LDAPRealmType RealmType = "ldap"
+	// PKIRealmType is the type of PKI realms.
+	PKIRealmType RealmType = "pki"
+)
+
+// Auth contains the authentication settings of the cluster.
+type Auth struct {
+	// Realms is the list of authentication realms, in addition to the file and native realms
+	// which are always enabled and consulted first.
+	Realms []Realm `json:"realms,omitempty"`
+
+	// PasswordsSecret is a reference to a secret in the namespace of the cluster containing user-provided passwords,
+	// by user name: the password of the `elastic` user in the `elastic` entry, and optionally the passwords of the
+	// users created for Kibana and APM Server associations in entries named after those users.
+	// Users without an entry get a randomly generated password.
+	PasswordsSecret commonv1alpha1.SecretRef `json:"passwordsSecret,omitempty"`
+}
+
+// Realm configures an authentication realm.
+// Exactly one of the saml, oidc, ldap or pki sections must be specified.
+type Realm struct {
+	// Name of the realm, unique among the realms of the cluster.
+	// +kubebuilder:validation:Pattern=^[a-z0-9]([-a-z0-9]*[a-z0-9])?$
+	// +kubebuilder:validation:MaxLength=40
+	Name string `json:"name"`
+
+	// Order of the realm in the realm chain, unique among the realms of the cluster.
+	// Realms are consulted in ascending order.
+	// +kubebuilder:validation:Minimum=0
+	Order int `json:"order"`
+
+	// Config holds additional settings of the realm, relative to the realm, for example `attributes.groups`.
+	// Settings derived from the other fields of the realm take precedence.
+	Config *commonv1alpha1.Config `json:"config,omitempty"`
+
+	// SAML configures a SAML realm.
+	SAML *SAMLRealm `json:"saml,omitempty"`
+
+	// OIDC configures an OpenID Connect realm.
+	OIDC *OIDCRealm `json:"oidc,omitempty"`
+
+	// LDAP configures an LDAP realm.
+	LDAP *LDAPRealm `json:"ldap,omitempty"`
+
+	// PKI configures a PKI realm.
+	PKI *PKIRealm `json:"pki,omitempty"`
+}
+
+// Type returns the type of the realm, or an empty type if none or several of the type-specific sections
+// are specified.
+func (r Realm) Type() RealmType {
+	var types []RealmType
+	if r.SAML != nil {
+		types = append(types, SAMLRealmType)
+	}
+	if r.OIDC != nil {
+		types = append(types, OIDCRealmType)
+	}
+	if r.LDAP != nil {
+		types = append(types, LDAPRealmType)
+	}
+	if r.PKI != nil {
+		types = append(types, PKIRealmType)
+	}
+	if len(types) != 1 {
+		return ""
+	}
+	return types[0]
+}
+
+// SAMLRealm configures a SAML realm.
+type SAMLRealm struct {
+	// IdPMetadata is a reference to a secret containing the metadata of the identity provider
+	// in the `metadata.xml` entry.
+	IdPMetadata commonv1alpha1.SecretRef `json:"idpMetadata"`
+
+	// IdPEntityID is the entity ID of the identity provider.
+	IdPEntityID string `json:"idpEntityID,omitempty"`
+
+	// SPEntityID is the entity ID of the Kibana service provider.
+	SPEntityID string `json:"spEntityID,omitempty"`
+
+	// SPACS is the URL of the Assertion Consumer Service of Kibana.
+	SPACS string `json:"spACS,omitempty"`
+
+	// SPLogout is the URL of the Single Logout service of Kibana (optional).
+	SPLogout string `json:"spLogout,omitempty"`
+
+	// AttributesPrincipal is the name of the SAML attribute holding the principal of the user.
+	AttributesPrincipal string `json:"attributesPrincipal,omitempty"`
+}
+
+// OIDCRealm configures an OpenID Connect realm.
+type OIDCRealm struct {
+	// ClientSecret is a reference to a secret containing the client secret of the relying party
+	// in the `client_secret` entry.
+	ClientSecret commonv1alpha1.SecretRef `json:"clientSecret"`
+
+	// ClientID is the client identifier of the relying party.
+	ClientID string `json:"clientID,omitempty"`
+
+	// RedirectURI is the redirect URI of Kibana.
+	RedirectURI string `json:"redirectURI,omitempty"`
+
+	// ResponseType is the OAuth 2.0 response type of the authentication flow (optional).
+	ResponseType string `json:"responseType,omitempty"`
+
+	// Issuer is the issuer identifier of the OpenID Connect provider.
+	Issuer string `json:"issuer,omitempty"`
+
+	// AuthorizationEndpoint is the URL of the authorization endpoint of the OpenID Connect provider.
+	AuthorizationEndpoint string `json:"authorizationEndpoint,omitempty"`
+
+	// TokenEndpoint is the URL of the token endpoint of the OpenID Connect provider (optional).
+	TokenEndpoint string `json:"tokenEndpoint,omitempty"`
+
+	// JWKSetURL is the URL of the JSON Web Key Set of the OpenID Connect provider.
+	JWKSetURL string `json:"jwkSetURL,omitempty"`
+
+	// ClaimsPrincipal is the name of the claim holding the principal of the user.
+	ClaimsPrincipal string `json:"claimsPrincipal,omitempty"`
+}
+
+// LDAPRealm configures an LDAP realm.
+type LDAPRealm struct {
+	// URLs of the LDAP servers.
+	URLs []string `json:"urls,omitempty"`
+
+	// BindDN is the distinguished name of the user used to bind to the LDAP servers (optional).
+	BindDN string `json:"bindDN,omitempty"`
+
+	// BindPassword is a reference to a secret containing the password of the bind user
+	// in the `bind_password` entry (optional).
+	BindPassword commonv1alpha1.SecretRef `json:"bindPassword,omitempty"`
+
+	// UserSearchBaseDN is the container distinguished name to search for users (optional).
+	UserSearchBaseDN string `json:"userSearchBaseDN,omitempty"`
+
+	// GroupSearchBaseDN is the container distinguished name to search for groups (optional).
+	GroupSearchBaseDN string `json:"groupSearchBaseDN,omitempty"`
+
+	// CertificateAuthorities is a reference to a secret containing the certificates of the authorities
+	// trusted to verify the certificates of the LDAP servers in the `ca.crt` entry (optional).
+	CertificateAuthorities commonv1alpha1.SecretRef `json:"certificateAuthorities,omitempty"`
+}
+
+// PKIRealm configures a PKI realm, authenticating the users from their client certificate.
+type PKIRealm struct {
+	// CertificateAuthorities is a reference to a secret containing the certificates of the authorities
+	// trusted to verify the client certificates in the `ca.crt` entry.
+	CertificateAuthorities commonv1alpha1.SecretRef `json:"certificateAuthorities"`
+
+	// UsernamePattern is the regular expression extracting the username from the subject of the client
+	// certificate (optional).
+	UsernamePattern string `json:"usernamePattern,omitempty"`
+}
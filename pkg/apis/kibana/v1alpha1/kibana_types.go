@@ -31,12 +31,21 @@ type KibanaSpec struct {
 	// If the namespace is not specified, the current resource namespace will be used.
 	ElasticsearchRef commonv1alpha1.ObjectSelector `json:"elasticsearchRef,omitempty"`
 
+	// ExternalElasticsearchRef references an Elasticsearch cluster that is not managed by the operator,
+	// as an alternative to ElasticsearchRef. Both cannot be set at the same time.
+	// +optional
+	ExternalElasticsearchRef *commonv1alpha1.ExternalElasticsearchRef `json:"externalElasticsearchRef,omitempty"`
+
 	// Config represents Kibana configuration.
 	Config *commonv1alpha1.Config `json:"config,omitempty"`
 
 	// HTTP contains settings for HTTP.
 	HTTP commonv1alpha1.HTTPConfig `json:"http,omitempty"`
 
+	// NetworkPolicy configures the NetworkPolicy restricting the traffic to the pods.
+	// +optional
+	NetworkPolicy commonv1alpha1.NetworkPolicySpec `json:"networkPolicy,omitempty"`
+
 	// PodTemplate can be used to propagate configuration to Kibana pods.
 	// This allows specifying custom annotations, labels, environment variables,
 	// affinity, resources, etc. for the pods created from this NodeSpec.
@@ -68,6 +77,11 @@ type KibanaStatus struct {
 	commonv1alpha1.ReconcilerStatus
 	Health            KibanaHealth                     `json:"health,omitempty"`
 	AssociationStatus commonv1alpha1.AssociationStatus `json:"associationStatus,omitempty"`
+	// UpgradeStatus is the status of the version upgrade, if it is held back.
+	UpgradeStatus commonv1alpha1.UpgradeStatus `json:"upgradeStatus,omitempty"`
+	// Version is the version of the deployed Kibana, which differs from the specified version while its upgrade is
+	// held back.
+	Version string `json:"version,omitempty"`
 }
 
 // IsDegraded returns true if the current status is worse than the previous.
@@ -84,6 +98,10 @@ func (k *Kibana) ElasticsearchRef() commonv1alpha1.ObjectSelector {
 	return k.Spec.ElasticsearchRef
 }
 
+func (k *Kibana) ExternalElasticsearchRef() *commonv1alpha1.ExternalElasticsearchRef {
+	return k.Spec.ExternalElasticsearchRef
+}
+
 func (k *Kibana) SecureSettings() []commonv1alpha1.SecretSource {
 	return k.Spec.SecureSettings
 }
@@ -83,11 +83,17 @@ func (in *KibanaList) DeepCopyObject() runtime.Object {
 func (in *KibanaSpec) DeepCopyInto(out *KibanaSpec) {
 	*out = *in
 	out.ElasticsearchRef = in.ElasticsearchRef
+	if in.ExternalElasticsearchRef != nil {
+		in, out := &in.ExternalElasticsearchRef, &out.ExternalElasticsearchRef
+		*out = new(commonv1alpha1.ExternalElasticsearchRef)
+		**out = **in
+	}
 	if in.Config != nil {
 		in, out := &in.Config, &out.Config
 		*out = (*in).DeepCopy()
 	}
 	in.HTTP.DeepCopyInto(&out.HTTP)
+	in.NetworkPolicy.DeepCopyInto(&out.NetworkPolicy)
 	in.PodTemplate.DeepCopyInto(&out.PodTemplate)
 	if in.SecureSettings != nil {
 		in, out := &in.SecureSettings, &out.SecureSettings
@@ -112,7 +118,7 @@ func (in *KibanaSpec) DeepCopy() *KibanaSpec {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *KibanaStatus) DeepCopyInto(out *KibanaStatus) {
 	*out = *in
-	out.ReconcilerStatus = in.ReconcilerStatus
+	in.ReconcilerStatus.DeepCopyInto(&out.ReconcilerStatus)
 	return
 }
 
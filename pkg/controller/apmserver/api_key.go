@@ -0,0 +1,176 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package apmserver
+
+import (
+	"context"
+	"reflect"
+
+	apmv1alpha1 "github.com/elastic/cloud-on-k8s/pkg/apis/apm/v1alpha1"
+	commonv1alpha1 "github.com/elastic/cloud-on-k8s/pkg/apis/common/v1alpha1"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/apmserver/config"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/apmserver/labels"
+	apmname "github.com/elastic/cloud-on-k8s/pkg/controller/apmserver/name"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/events"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/reconciler"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/version"
+	esclient "github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/client"
+	"github.com/elastic/cloud-on-k8s/pkg/utils/k8s"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+const (
+	// APIKeyIDKey is the key of the API key ID in the API key Secret.
+	APIKeyIDKey = "api-key-id"
+	// APIKeyKey is the key of the base64 encoded API key credentials in the API key Secret, to be used by agents
+	// in the `Authorization: ApiKey <credentials>` header.
+	APIKeyKey = "api-key"
+)
+
+// esClientProvider returns a client for the Elasticsearch cluster associated to the given object.
+type esClientProvider func(c k8s.Client, associated commonv1alpha1.Associated, v version.Version) (esclient.Client, error)
+
+// agentAPIKeyRoleDescriptors restricts the API keys created for agents to the privileges of the APM application
+// agents need: sending events, reading the central configuration and uploading source maps.
+var agentAPIKeyRoleDescriptors = map[string]esclient.APIKeyRoleDescriptor{
+	"apm-agent": {
+		Applications: []esclient.APIKeyApplicationPrivileges{
+			{
+				Application: "apm",
+				Privileges:  []string{"event:write", "config_agent:read", "sourcemap:write"},
+				Resources:   []string{"*"},
+			},
+		},
+	},
+}
+
+// reconcileAPIKeySecret ensures a Secret containing an API key for agents exists if API key authentication is enabled.
+// The API key is created once in the associated Elasticsearch cluster, then re-used as long as the Secret exists.
+// It returns nil if API key authentication is disabled or cannot be set up yet.
+func (r *ReconcileApmServer) reconcileAPIKeySecret(as *apmv1alpha1.ApmServer) (*corev1.Secret, error) {
+	enabled, err := config.APIKeyAuthEnabled(as)
+	if err != nil {
+		return nil, err
+	}
+	if !enabled {
+		if as.Spec.Auth.APIKey.Enabled {
+			r.recorder.Eventf(as, corev1.EventTypeWarning, events.EventReasonValidation,
+				"API key authentication requires APM Server %s or later", config.APIKeyMinVersion)
+		}
+		return nil, r.deleteAPIKeySecret(as)
+	}
+	if !as.AssociationConf().IsConfigured() {
+		log.Info("Waiting for the association with Elasticsearch to create an API key", "namespace", as.Namespace, "as_name", as.Name)
+		return nil, nil
+	}
+
+	var existing corev1.Secret
+	err = r.Get(types.NamespacedName{Namespace: as.Namespace, Name: apmname.APIKey(as.Name)}, &existing)
+	if err != nil && !errors.IsNotFound(err) {
+		return nil, err
+	}
+	if err == nil && len(existing.Data[APIKeyKey]) > 0 {
+		return &existing, nil
+	}
+
+	apiKey, err := r.createAPIKey(as)
+	if err != nil {
+		return nil, err
+	}
+	expected := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: as.Namespace,
+			Name:      apmname.APIKey(as.Name),
+			Labels:    labels.NewLabels(as.Name),
+		},
+		Data: map[string][]byte{
+			APIKeyIDKey: []byte(apiKey.ID),
+			APIKeyKey:   []byte(apiKey.Credentials()),
+		},
+	}
+	reconciled := &corev1.Secret{}
+	return reconciled, reconciler.ReconcileResource(reconciler.Params{
+		Client:     r.Client,
+		Scheme:     r.scheme,
+		Owner:      as,
+		Expected:   expected,
+		Reconciled: reconciled,
+		NeedsUpdate: func() bool {
+			return !reflect.DeepEqual(reconciled.Labels, expected.Labels) ||
+				!reflect.DeepEqual(reconciled.Data, expected.Data)
+		},
+		UpdateReconciled: func() {
+			reconciled.Labels = expected.Labels
+			reconciled.Data = expected.Data
+		},
+		PreCreate: func() {
+			log.Info("Creating apm server API key secret", "namespace", as.Namespace, "secret_name", expected.Name, "as_name", as.Name)
+		},
+	})
+}
+
+// createAPIKey creates an API key for agents in the associated Elasticsearch cluster.
+func (r *ReconcileApmServer) createAPIKey(as *apmv1alpha1.ApmServer) (esclient.APIKeyCreateResponse, error) {
+	client, err := r.newElasticsearchClient(as)
+	if err != nil {
+		return esclient.APIKeyCreateResponse{}, err
+	}
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), esclient.DefaultReqTimeout)
+	defer cancel()
+	return client.CreateAPIKey(ctx, esclient.APIKeyCreateRequest{
+		Name:            as.Namespace + "/" + as.Name,
+		RoleDescriptors: agentAPIKeyRoleDescriptors,
+	})
+}
+
+// deleteAPIKeySecret deletes the API key Secret, if any. The API key is invalidated first if the associated
+// Elasticsearch cluster can be reached.
+func (r *ReconcileApmServer) deleteAPIKeySecret(as *apmv1alpha1.ApmServer) error {
+	var secret corev1.Secret
+	err := r.Get(types.NamespacedName{Namespace: as.Namespace, Name: apmname.APIKey(as.Name)}, &secret)
+	if errors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if id := string(secret.Data[APIKeyIDKey]); id != "" && as.AssociationConf().IsConfigured() {
+		if err := r.invalidateAPIKey(as, id); err != nil && !esclient.IsNotFound(err) {
+			return err
+		}
+	}
+
+	log.Info("Deleting apm server API key secret", "namespace", as.Namespace, "secret_name", secret.Name, "as_name", as.Name)
+	if err := r.Delete(&secret); err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+	return nil
+}
+
+func (r *ReconcileApmServer) invalidateAPIKey(as *apmv1alpha1.ApmServer, id string) error {
+	client, err := r.newElasticsearchClient(as)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), esclient.DefaultReqTimeout)
+	defer cancel()
+	return client.InvalidateAPIKey(ctx, id)
+}
+
+func (r *ReconcileApmServer) newElasticsearchClient(as *apmv1alpha1.ApmServer) (esclient.Client, error) {
+	v, err := version.Parse(as.Spec.Version)
+	if err != nil {
+		return nil, err
+	}
+	return r.esClientProvider(r.Client, as, *v)
+}
@@ -0,0 +1,139 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package apmserver
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	apmv1alpha1 "github.com/elastic/cloud-on-k8s/pkg/apis/apm/v1alpha1"
+	commonv1alpha1 "github.com/elastic/cloud-on-k8s/pkg/apis/common/v1alpha1"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/version"
+	esclient "github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/client"
+	"github.com/elastic/cloud-on-k8s/pkg/utils/k8s"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestReconcileApmServer_reconcileAPIKeySecret(t *testing.T) {
+	require.NoError(t, apmv1alpha1.AddToScheme(scheme.Scheme))
+	apiKeySecretKey := types.NamespacedName{Namespace: "ns", Name: "as-apm-api-key"}
+	apm := func(v string, enabled bool, associated bool) *apmv1alpha1.ApmServer {
+		as := &apmv1alpha1.ApmServer{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "as"},
+			Spec: apmv1alpha1.ApmServerSpec{
+				Version: v,
+				Auth:    apmv1alpha1.ApmServerAuth{APIKey: apmv1alpha1.APIKeyAuth{Enabled: enabled}},
+			},
+		}
+		if associated {
+			as.SetAssociationConf(&commonv1alpha1.AssociationConf{
+				AuthSecretName: "as-elastic-user",
+				AuthSecretKey:  "ns-as-apm-user",
+				URL:            "https://es-es-http.ns.svc:9200",
+			})
+		}
+		return as
+	}
+	apiKeySecret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "as-apm-api-key"},
+		Data: map[string][]byte{
+			APIKeyIDKey: []byte("existing-id"),
+			APIKeyKey:   []byte("existing-credentials"),
+		},
+	}
+	tests := []struct {
+		name           string
+		as             *apmv1alpha1.ApmServer
+		initialObjects []runtime.Object
+		wantRequests   []string
+		wantSecret     bool
+		assertions     func(secret corev1.Secret)
+	}{
+		{
+			name: "API key authentication disabled",
+			as:   apm("7.6.0", false, true),
+		},
+		{
+			name:       "API key authentication not supported by the APM Server version",
+			as:         apm("7.5.0", true, true),
+			wantSecret: false,
+		},
+		{
+			name: "no association with Elasticsearch",
+			as:   apm("7.6.0", true, false),
+		},
+		{
+			name:         "API key is created",
+			as:           apm("7.6.0", true, true),
+			wantRequests: []string{http.MethodPost},
+			wantSecret:   true,
+			assertions: func(secret corev1.Secret) {
+				require.Equal(t, "id", string(secret.Data[APIKeyIDKey]))
+				require.Equal(t, esclient.APIKeyCreateResponse{ID: "id", APIKey: "key"}.Credentials(), string(secret.Data[APIKeyKey]))
+			},
+		},
+		{
+			name:           "existing API key is re-used",
+			as:             apm("7.6.0", true, true),
+			initialObjects: []runtime.Object{apiKeySecret.DeepCopy()},
+			wantSecret:     true,
+			assertions: func(secret corev1.Secret) {
+				require.Equal(t, "existing-id", string(secret.Data[APIKeyIDKey]))
+			},
+		},
+		{
+			name:           "API key is invalidated when API key authentication is disabled",
+			as:             apm("7.6.0", false, true),
+			initialObjects: []runtime.Object{apiKeySecret.DeepCopy()},
+			wantRequests:   []string{http.MethodDelete},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := k8s.WrapClient(fake.NewFakeClient(tt.initialObjects...))
+			var requests []string
+			r := &ReconcileApmServer{
+				Client:   c,
+				scheme:   scheme.Scheme,
+				recorder: record.NewFakeRecorder(10),
+				esClientProvider: func(_ k8s.Client, _ commonv1alpha1.Associated, v version.Version) (esclient.Client, error) {
+					return esclient.NewMockClient(v, func(req *http.Request) *http.Response {
+						requests = append(requests, req.Method)
+						if req.Method == http.MethodPost {
+							var request esclient.APIKeyCreateRequest
+							require.NoError(t, json.NewDecoder(req.Body).Decode(&request))
+							require.Equal(t, "ns/as", request.Name)
+							require.Equal(t, agentAPIKeyRoleDescriptors, request.RoleDescriptors)
+							return esclient.NewMockResponse(200, req, `{"id":"id","name":"ns/as","api_key":"key"}`)
+						}
+						return esclient.NewMockResponse(200, req, `{}`)
+					}), nil
+				},
+			}
+			secret, err := r.reconcileAPIKeySecret(tt.as)
+			require.NoError(t, err)
+			require.Equal(t, tt.wantRequests, requests)
+
+			var reconciled corev1.Secret
+			err = c.Get(apiKeySecretKey, &reconciled)
+			if !tt.wantSecret {
+				require.Nil(t, secret)
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, apiKeySecretKey.Name, secret.Name)
+			tt.assertions(reconciled)
+		})
+	}
+}
@@ -12,6 +12,7 @@ import (
 	"sync/atomic"
 
 	apmv1alpha1 "github.com/elastic/cloud-on-k8s/pkg/apis/apm/v1alpha1"
+	commonv1alpha1 "github.com/elastic/cloud-on-k8s/pkg/apis/common/v1alpha1"
 	apmcerts "github.com/elastic/cloud-on-k8s/pkg/controller/apmserver/certificates"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/apmserver/config"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/apmserver/labels"
@@ -25,21 +26,23 @@ import (
 	"github.com/elastic/cloud-on-k8s/pkg/controller/common/driver"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/common/events"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/common/finalizer"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/ingress"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/common/keystore"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/common/operator"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/common/pod"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/common/reconciler"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/version"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/common/volume"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/common/watches"
 	"github.com/elastic/cloud-on-k8s/pkg/utils/k8s"
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
+	extensionsv1beta1 "k8s.io/api/extensions/v1beta1"
+	networkingv1 "k8s.io/api/networking/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	k8slabels "k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
-	"k8s.io/apimachinery/pkg/util/rand"
 	"k8s.io/client-go/tools/record"
 	"sigs.k8s.io/controller-runtime/pkg/controller"
 	"sigs.k8s.io/controller-runtime/pkg/handler"
@@ -80,19 +83,23 @@ func Add(mgr manager.Manager, params operator.Parameters) error {
 	if err != nil {
 		return err
 	}
-	return addWatches(c, reconciler)
+	if err := addWatches(c, reconciler); err != nil {
+		return err
+	}
+	return ingress.WatchRoutes(c, mgr.GetRESTMapper(), &apmv1alpha1.ApmServer{})
 }
 
 // newReconciler returns a new reconcile.Reconciler
 func newReconciler(mgr manager.Manager, params operator.Parameters) *ReconcileApmServer {
 	client := k8s.WrapClient(mgr.GetClient())
 	return &ReconcileApmServer{
-		Client:         client,
-		scheme:         mgr.GetScheme(),
-		recorder:       mgr.GetRecorder(name),
-		dynamicWatches: watches.NewDynamicWatches(),
-		finalizers:     finalizer.NewHandler(client),
-		Parameters:     params,
+		Client:           client,
+		scheme:           mgr.GetScheme(),
+		recorder:         mgr.GetRecorder(name),
+		dynamicWatches:   watches.NewDynamicWatches(),
+		finalizers:       finalizer.NewHandler(client),
+		esClientProvider: association.NewElasticsearchClient,
+		Parameters:       params,
 	}
 }
 
@@ -119,6 +126,22 @@ func addWatches(c controller.Controller, r *ReconcileApmServer) error {
 		return err
 	}
 
+	// Watch ingresses
+	if err := c.Watch(&source.Kind{Type: &extensionsv1beta1.Ingress{}}, &handler.EnqueueRequestForOwner{
+		IsController: true,
+		OwnerType:    &apmv1alpha1.ApmServer{},
+	}); err != nil {
+		return err
+	}
+
+	// Watch network policies
+	if err := c.Watch(&source.Kind{Type: &networkingv1.NetworkPolicy{}}, &handler.EnqueueRequestForOwner{
+		IsController: true,
+		OwnerType:    &apmv1alpha1.ApmServer{},
+	}); err != nil {
+		return err
+	}
+
 	// Watch secrets
 	if err := c.Watch(&source.Kind{Type: &corev1.Secret{}}, &handler.EnqueueRequestForOwner{
 		IsController: true,
@@ -150,6 +173,8 @@ type ReconcileApmServer struct {
 	recorder       record.EventRecorder
 	dynamicWatches watches.DynamicWatches
 	finalizers     finalizer.Handler
+	// esClientProvider is used to create API keys in the associated Elasticsearch cluster
+	esClientProvider esClientProvider
 	operator.Parameters
 	// iteration is the number of times this controller has run its Reconcile method
 	iteration uint64
@@ -182,6 +207,11 @@ func (r *ReconcileApmServer) Reconcile(request reconcile.Request) (reconcile.Res
 	if ok, err := association.FetchWithAssociation(r.Client, request, &as); !ok {
 		return reconcile.Result{}, err
 	}
+	kbAssocConf, err := association.GetKibanaAssociationConf(&as)
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+	as.SetKibanaAssociationConf(kbAssocConf)
 
 	if common.IsPaused(as.ObjectMeta) {
 		log.Info("Object is paused. Skipping reconciliation", "namespace", as.Namespace, "as_name", as.Name)
@@ -223,15 +253,56 @@ func (r *ReconcileApmServer) isCompatible(as *apmv1alpha1.ApmServer) (bool, erro
 
 func (r *ReconcileApmServer) doReconcile(request reconcile.Request, as *apmv1alpha1.ApmServer) (reconcile.Result, error) {
 	state := NewState(request, as)
+	state.ApmServer.Status.ObservedGeneration = as.Generation
+	results := &reconciler.Results{}
+
+	ver, err := version.Parse(as.Spec.Version)
+	state.UpdateCondition(common.InvalidCondition(err))
+	if err != nil {
+		k8s.EmitErrorEvent(r.recorder, err, as, events.EventReasonValidation, "Invalid version '%s': %v", as.Spec.Version, err)
+		return r.updateStatus(state, results.WithError(err))
+	}
+
+	// a version upgrade must wait for the Elasticsearch cluster to run the new version
+	upgradeStatus, err := association.ReconcileUpgradeStatus(r.Client, r.recorder, as, *ver, as.Status.UpgradeStatus)
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+	state.ApmServer.Status.UpgradeStatus = upgradeStatus
+	reconciledVersion := association.ReconciledVersion(as.Spec.Version, upgradeStatus, as.Status.Version)
+	if reconciledVersion == "" {
+		// APM Server is not deployed yet, there is nothing to reconcile until the upgrade proceeds
+		state.UpdateCondition(association.WaitingForElasticsearchUpgradeCondition(*ver))
+		return r.updateStatus(state, results.WithResult(association.UpgradeRequeue))
+	}
+	upgradeHeldBack := upgradeStatus == commonv1alpha1.WaitingForElasticsearchUpgrade
+	if upgradeHeldBack {
+		// keep reconciling the deployed version while the version change is held back, the status is still
+		// updated through the state
+		state.UpdateCondition(association.WaitingForElasticsearchUpgradeCondition(*ver))
+		results.WithResult(association.UpgradeRequeue)
+		as = as.DeepCopy()
+		as.Spec.Version = reconciledVersion
+	}
+
 	svc, err := common.ReconcileService(r.Client, r.scheme, NewService(*as), as)
 	if err != nil {
 		return reconcile.Result{}, err
 	}
-	results := apmcerts.Reconcile(r, as, []corev1.Service{*svc}, r.CACertRotation)
+	certResults := apmcerts.Reconcile(r, as, []corev1.Service{*svc}, r.CACertRotation, r.CertKeyParams)
+	results.WithResults(&certResults)
 	if results.HasError() {
-		res, err := results.Aggregate()
+		_, err := results.Aggregate()
 		k8s.EmitErrorEvent(r.recorder, err, as, events.EventReconciliationError, "Certificate reconciliation error: %v", err)
-		return res, err
+		return r.updateStatus(state, results)
+	}
+
+	if err := ingress.Reconcile(r.Client, r.scheme, as, apmname.APMNamer, as.Spec.HTTP, *svc, labels.NewLabels(as.Name)); err != nil {
+		return reconcile.Result{}, err
+	}
+
+	if err := reconcileNetworkPolicy(r.Client, r.scheme, *as); err != nil {
+		return reconcile.Result{}, err
 	}
 
 	state, err = r.reconcileApmServerDeployment(state, as)
@@ -241,68 +312,16 @@ func (r *ReconcileApmServer) doReconcile(request reconcile.Request, as *apmv1alp
 			return reconcile.Result{Requeue: true}, nil
 		}
 		k8s.EmitErrorEvent(r.recorder, err, as, events.EventReconciliationError, "Deployment reconciliation error: %v", err)
-		return state.Result, err
+		return r.updateStatus(state, results.WithError(err))
+	}
+	if upgradeHeldBack {
+		// the deployment reports no upgrade in progress
+		state.UpdateCondition(association.WaitingForElasticsearchUpgradeCondition(*ver))
 	}
 
 	state.UpdateApmServerExternalService(*svc)
 
-	return r.updateStatus(state)
-}
-
-func (r *ReconcileApmServer) reconcileApmServerSecret(as *apmv1alpha1.ApmServer) (*corev1.Secret, error) {
-	expectedApmServerSecret := &corev1.Secret{
-		ObjectMeta: metav1.ObjectMeta{
-			Namespace: as.Namespace,
-			Name:      apmname.SecretToken(as.Name),
-			Labels:    labels.NewLabels(as.Name),
-		},
-		Data: map[string][]byte{
-			SecretTokenKey: []byte(rand.String(24)),
-		},
-	}
-	reconciledApmServerSecret := &corev1.Secret{}
-	return reconciledApmServerSecret, reconciler.ReconcileResource(
-		reconciler.Params{
-			Client: r.Client,
-			Scheme: r.scheme,
-
-			Owner:      as,
-			Expected:   expectedApmServerSecret,
-			Reconciled: reconciledApmServerSecret,
-
-			NeedsUpdate: func() bool {
-				if !reflect.DeepEqual(reconciledApmServerSecret.Labels, expectedApmServerSecret.Labels) {
-					return true
-				}
-
-				if reconciledApmServerSecret.Data == nil {
-					return true
-				}
-
-				// re-use the secret token key if it exists
-				existingSecretTokenKey, hasExistingSecretTokenKey := reconciledApmServerSecret.Data[SecretTokenKey]
-				if hasExistingSecretTokenKey {
-					expectedApmServerSecret.Data[SecretTokenKey] = existingSecretTokenKey
-				}
-
-				if !reflect.DeepEqual(reconciledApmServerSecret.Data, expectedApmServerSecret.Data) {
-					return true
-				}
-
-				return false
-			},
-			UpdateReconciled: func() {
-				reconciledApmServerSecret.Labels = expectedApmServerSecret.Labels
-				reconciledApmServerSecret.Data = expectedApmServerSecret.Data
-			},
-			PreCreate: func() {
-				log.Info("Creating apm server secret", "namespace", expectedApmServerSecret.Namespace, "secret_name", expectedApmServerSecret.Name, "as_name", as.Name)
-			},
-			PreUpdate: func() {
-				log.Info("Updating apm server secret", "namespace", expectedApmServerSecret.Namespace, "secret_name", expectedApmServerSecret.Name, "as_name", as.Name)
-			},
-		},
-	)
+	return r.updateStatus(state, results)
 }
 
 func (r *ReconcileApmServer) deploymentParams(
@@ -358,6 +377,27 @@ func (r *ReconcileApmServer) deploymentParams(
 		}
 	}
 
+	if as.KibanaAssociationConf().CAIsConfigured() {
+		kbCASecretName := as.KibanaAssociationConf().GetCASecretName()
+		kbCAVolume := volume.NewSecretVolumeWithMountPath(
+			kbCASecretName,
+			"kibana-certs",
+			filepath.Join(ApmBaseDir, config.KibanaCertificatesDir),
+		)
+
+		// include the Kibana CA in the config checksum, the APM Server must be restarted to use a new CA
+		var kbPublicCASecret corev1.Secret
+		key := types.NamespacedName{Namespace: as.Namespace, Name: kbCASecretName}
+		if err := r.Get(key, &kbPublicCASecret); err != nil {
+			return DeploymentParams{}, err
+		}
+		_, _ = configChecksum.Write(kbPublicCASecret.Data[certificates.CertFileName])
+
+		podSpec.Spec.Volumes = append(podSpec.Spec.Volumes, kbCAVolume.Volume())
+		apmServerContainer := pod.ContainerByName(podSpec.Spec, apmv1alpha1.APMServerContainerName)
+		apmServerContainer.VolumeMounts = append(apmServerContainer.VolumeMounts, kbCAVolume.VolumeMount())
+	}
+
 	if as.Spec.HTTP.TLS.Enabled() {
 		// fetch the secret to calculate the checksum
 		var httpCerts corev1.Secret
@@ -377,8 +417,10 @@ func (r *ReconcileApmServer) deploymentParams(
 		apmServerContainer.VolumeMounts = append(apmServerContainer.VolumeMounts, httpCertsVolume.VolumeMount())
 	}
 
+	// the secret token is read from an environment variable: the APM Server must be restarted when it is rotated
+	_, _ = configChecksum.Write(params.ApmServerSecret.Data[SecretTokenKey])
+
 	podLabels[configChecksumLabelName] = fmt.Sprintf("%x", configChecksum.Sum(nil))
-	// TODO: also need to hash secret token?
 
 	deploymentLabels := labels.NewLabels(as.Name)
 	podSpec.Labels = defaults.SetDefaultLabels(podSpec.Labels, podLabels)
@@ -401,6 +443,10 @@ func (r *ReconcileApmServer) reconcileApmServerDeployment(
 	if err != nil {
 		return state, err
 	}
+	apiKeySecret, err := r.reconcileAPIKeySecret(as)
+	if err != nil {
+		return state, err
+	}
 	reconciledConfigSecret, err := config.Reconcile(r.Client, r.scheme, as)
 	if err != nil {
 		return state, err
@@ -438,14 +484,19 @@ func (r *ReconcileApmServer) reconcileApmServerDeployment(
 	if err != nil {
 		return state, err
 	}
-	state.UpdateApmServerState(result, *reconciledApmServerSecret)
+	state.UpdateApmServerState(result, *reconciledApmServerSecret, apiKeySecret)
+	state.ApmServer.Status.Version = as.Spec.Version
 	return state, nil
 }
 
-func (r *ReconcileApmServer) updateStatus(state State) (reconcile.Result, error) {
+// updateStatus reports the outcome of the reconciliation in the Reconciling condition, updates the status if it
+// changed and returns the aggregated results.
+func (r *ReconcileApmServer) updateStatus(state State, results *reconciler.Results) (reconcile.Result, error) {
+	state.UpdateCondition(results.WithResult(state.Result).ReconcilingCondition())
+
 	current := state.originalApmServer
 	if reflect.DeepEqual(current.Status, state.ApmServer.Status) {
-		return state.Result, nil
+		return results.Aggregate()
 	}
 	if state.ApmServer.Status.IsDegraded(current.Status) {
 		r.recorder.Event(current, corev1.EventTypeWarning, events.EventReasonUnhealthy, "Apm Server health degraded")
@@ -457,12 +508,13 @@ func (r *ReconcileApmServer) updateStatus(state State) (reconcile.Result, error)
 		return reconcile.Result{Requeue: true}, nil
 	}
 
-	return state.Result, err
+	return results.WithError(err).Aggregate()
 }
 
 // finalizersFor returns the list of finalizers applying to a given APM deployment
 func (r *ReconcileApmServer) finalizersFor(as apmv1alpha1.ApmServer) []finalizer.Finalizer {
 	return []finalizer.Finalizer{
 		keystore.Finalizer(k8s.ExtractNamespacedName(&as), r.dynamicWatches, as.Kind()),
+		http.DynamicWatchesFinalizer(r.dynamicWatches, as.Kind(), as.Name, apmname.APMNamer),
 	}
 }
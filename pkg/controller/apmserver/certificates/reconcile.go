@@ -23,6 +23,7 @@ func Reconcile(
 	apm *v1alpha1.ApmServer,
 	services []coverv1.Service,
 	rotation certificates.RotationParams,
+	keyParams certificates.KeyParams,
 ) reconciler.Results {
 	results := reconciler.Results{}
 	selfSignedCert := apm.Spec.HTTP.TLS.SelfSignedCertificate
@@ -30,17 +31,24 @@ func Reconcile(
 		return results
 	}
 
+	keyParams = keyParams.WithOverrides(apm.Spec.HTTP.TLS.PrivateKey)
+	if err := keyParams.Validate(); err != nil {
+		return *results.WithError(err)
+	}
+
 	labels := labels.NewLabels(apm.Name)
 
 	// reconcile CA certs first
-	httpCa, err := certificates.ReconcileCAForOwner(
+	httpCa, err := certificates.ReconcileCA(
 		driver.K8sClient(),
 		driver.Scheme(),
 		name.APMNamer,
 		apm,
 		labels,
 		certificates.HTTPCAType,
+		apm.Spec.HTTP.TLS.CertificateAuthority,
 		rotation,
+		keyParams,
 	)
 	if err != nil {
 		return *results.WithError(err)
@@ -57,10 +65,11 @@ func Reconcile(
 		apm,
 		name.APMNamer,
 		httpCa,
-		apm.Spec.HTTP.TLS,
+		http.TLSOptions(apm.Spec.HTTP),
 		labels,
 		services,
 		rotation, // todo correct rotation
+		keyParams,
 	)
 	if err != nil {
 		return *results.WithError(err)
@@ -15,6 +15,7 @@ import (
 	"github.com/elastic/cloud-on-k8s/pkg/controller/common/certificates"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/common/certificates/http"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/common/settings"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/version"
 	"github.com/elastic/cloud-on-k8s/pkg/utils/k8s"
 )
 
@@ -23,16 +24,27 @@ const (
 	DefaultHTTPPort = 8200
 
 	// Certificates
-	CertificatesDir = "config/elasticsearch-certs"
+	CertificatesDir       = "config/elasticsearch-certs"
+	KibanaCertificatesDir = "config/kibana-certs"
 
-	APMServerHost        = "apm-server.host"
-	APMServerSecretToken = "apm-server.secret_token"
+	APMServerHost          = "apm-server.host"
+	APMServerSecretToken   = "apm-server.secret_token"
+	APMServerAPIKeyEnabled = "apm-server.api_key.enabled"
 
 	APMServerSSLEnabled     = "apm-server.ssl.enabled"
 	APMServerSSLKey         = "apm-server.ssl.key"
 	APMServerSSLCertificate = "apm-server.ssl.certificate"
+
+	APMServerKibanaEnabled                   = "apm-server.kibana.enabled"
+	APMServerKibanaHost                      = "apm-server.kibana.host"
+	APMServerKibanaUsername                  = "apm-server.kibana.username"
+	APMServerKibanaPassword                  = "apm-server.kibana.password"
+	APMServerKibanaSSLCertificateAuthorities = "apm-server.kibana.ssl.certificate_authorities"
 )
 
+// APIKeyMinVersion is the first APM Server version supporting API key authentication.
+var APIKeyMinVersion = version.MustParse("7.6.0")
+
 func NewConfigFromSpec(c k8s.Client, as *v1alpha1.ApmServer) (*settings.CanonicalConfig, error) {
 	specConfig := as.Spec.Config
 	if specConfig == nil {
@@ -51,27 +63,43 @@ func NewConfigFromSpec(c k8s.Client, as *v1alpha1.ApmServer) (*settings.Canonica
 		if err != nil {
 			return nil, err
 		}
-		outputCfg = settings.MustCanonicalConfig(
-			map[string]interface{}{
-				"output.elasticsearch.hosts":                       []string{as.AssociationConf().GetURL()},
-				"output.elasticsearch.username":                    username,
-				"output.elasticsearch.password":                    password,
-				"output.elasticsearch.ssl.certificate_authorities": []string{filepath.Join(CertificatesDir, certificates.CertFileName)},
-			},
-		)
+		output := map[string]interface{}{
+			"output.elasticsearch.hosts":    []string{as.AssociationConf().GetURL()},
+			"output.elasticsearch.username": username,
+			"output.elasticsearch.password": password,
+		}
+		if as.AssociationConf().CAIsConfigured() {
+			output["output.elasticsearch.ssl.certificate_authorities"] = []string{filepath.Join(CertificatesDir, certificates.CertFileName)}
+		}
+		outputCfg = settings.MustCanonicalConfig(output)
+
+	}
+
+	kibanaCfg, err := kibanaSettings(c, as)
+	if err != nil {
+		return nil, err
+	}
 
+	apiKeyEnabled, err := APIKeyAuthEnabled(as)
+	if err != nil {
+		return nil, err
 	}
 
 	// Create a base configuration.
 
-	cfg := settings.MustCanonicalConfig(map[string]interface{}{
+	base := map[string]interface{}{
 		APMServerHost:        fmt.Sprintf(":%d", DefaultHTTPPort),
 		APMServerSecretToken: "${SECRET_TOKEN}",
-	})
+	}
+	if apiKeyEnabled {
+		base[APMServerAPIKeyEnabled] = true
+	}
+	cfg := settings.MustCanonicalConfig(base)
 
 	// Merge the configuration with userSettings last so they take precedence.
 	err = cfg.MergeWith(
 		outputCfg,
+		kibanaCfg,
 		settings.MustCanonicalConfig(tlsSettings(as)),
 		userSettings,
 	)
@@ -82,6 +110,41 @@ func NewConfigFromSpec(c k8s.Client, as *v1alpha1.ApmServer) (*settings.Canonica
 	return cfg, nil
 }
 
+// APIKeyAuthEnabled returns true if API key authentication is requested in the spec and supported by the version of
+// the APM Server.
+func APIKeyAuthEnabled(as *v1alpha1.ApmServer) (bool, error) {
+	if !as.Spec.Auth.APIKey.Enabled {
+		return false, nil
+	}
+	v, err := version.Parse(as.Spec.Version)
+	if err != nil {
+		return false, err
+	}
+	return v.IsSameOrAfter(APIKeyMinVersion), nil
+}
+
+// kibanaSettings returns the settings to connect to the associated Kibana, if any.
+func kibanaSettings(c k8s.Client, as *v1alpha1.ApmServer) (*settings.CanonicalConfig, error) {
+	assocConf := as.KibanaAssociationConf()
+	if !assocConf.IsConfigured() {
+		return nil, nil
+	}
+	username, password, err := association.AuthSettings(c, as.Namespace, assocConf)
+	if err != nil {
+		return nil, err
+	}
+	kibana := map[string]interface{}{
+		APMServerKibanaEnabled:  true,
+		APMServerKibanaHost:     assocConf.GetURL(),
+		APMServerKibanaUsername: username,
+		APMServerKibanaPassword: password,
+	}
+	if assocConf.CAIsConfigured() {
+		kibana[APMServerKibanaSSLCertificateAuthorities] = []string{filepath.Join(KibanaCertificatesDir, certificates.CertFileName)}
+	}
+	return settings.MustCanonicalConfig(kibana), nil
+}
+
 func tlsSettings(as *v1alpha1.ApmServer) map[string]interface{} {
 	if !as.Spec.HTTP.TLS.Enabled() {
 		return nil
@@ -10,6 +10,7 @@ import (
 
 const (
 	secretTokenSuffix = "token"
+	apiKeySuffix      = "api-key"
 	httpServiceSuffix = "http"
 	configSuffix      = "config"
 	deploymentSuffix  = "server"
@@ -22,6 +23,10 @@ func SecretToken(apmName string) string {
 	return APMNamer.Suffix(apmName, secretTokenSuffix)
 }
 
+func APIKey(apmName string) string {
+	return APMNamer.Suffix(apmName, apiKeySuffix)
+}
+
 func HTTPService(apmName string) string {
 	return APMNamer.Suffix(apmName, httpServiceSuffix)
 }
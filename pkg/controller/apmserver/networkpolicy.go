@@ -0,0 +1,29 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package apmserver
+
+import (
+	apmv1alpha1 "github.com/elastic/cloud-on-k8s/pkg/apis/apm/v1alpha1"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/apmserver/labels"
+	apmname "github.com/elastic/cloud-on-k8s/pkg/controller/apmserver/name"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/networkpolicy"
+	"github.com/elastic/cloud-on-k8s/pkg/utils/k8s"
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// reconcileNetworkPolicy ensures the NetworkPolicy of the APM Server exists if enabled in the spec, and is deleted
+// otherwise. The HTTP port is only reachable from the sources specified in the spec.
+func reconcileNetworkPolicy(c k8s.Client, scheme *runtime.Scheme, as apmv1alpha1.ApmServer) error {
+	return networkpolicy.Reconcile(c, scheme, &as, as.Spec.NetworkPolicy.Enabled, newNetworkPolicy(as))
+}
+
+func newNetworkPolicy(as apmv1alpha1.ApmServer) networkingv1.NetworkPolicy {
+	podLabels := labels.NewLabels(as.Name)
+	return networkpolicy.New(
+		apmname.APMNamer, &as, podLabels, podLabels,
+		networkpolicy.PortRule(HTTPPort, as.Spec.NetworkPolicy.HTTPFrom...),
+	)
+}
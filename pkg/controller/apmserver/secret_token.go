@@ -0,0 +1,150 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package apmserver
+
+import (
+	"reflect"
+	"time"
+
+	apmv1alpha1 "github.com/elastic/cloud-on-k8s/pkg/apis/apm/v1alpha1"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/apmserver/labels"
+	apmname "github.com/elastic/cloud-on-k8s/pkg/controller/apmserver/name"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/deployment"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/reconciler"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/rand"
+)
+
+const (
+	// SecretTokenRotationAnnotation triggers the rotation of the secret token when its value changes.
+	SecretTokenRotationAnnotation = "apm.k8s.elastic.co/secret-token-rotation"
+	// SecretTokenRotatedAtAnnotation records when the secret token was last rotated, until the previous token is removed.
+	SecretTokenRotatedAtAnnotation = "apm.k8s.elastic.co/secret-token-rotated-at"
+
+	// PreviousSecretTokenKey holds the token replaced by the last rotation, still accepted by the APM Server instances
+	// not restarted yet, until all the instances have been restarted with the new token.
+	PreviousSecretTokenKey = "previous-secret-token"
+
+	secretTokenLength = 24
+)
+
+func (r *ReconcileApmServer) reconcileApmServerSecret(as *apmv1alpha1.ApmServer) (*corev1.Secret, error) {
+	rolledOut, err := r.isTokenRolledOut(as)
+	if err != nil {
+		return nil, err
+	}
+	rotation := as.Annotations[SecretTokenRotationAnnotation]
+
+	expectedApmServerSecret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: as.Namespace,
+			Name:      apmname.SecretToken(as.Name),
+			Labels:    labels.NewLabels(as.Name),
+		},
+		Data: map[string][]byte{
+			SecretTokenKey: []byte(rand.String(secretTokenLength)),
+		},
+	}
+	if rotation != "" {
+		expectedApmServerSecret.Annotations = map[string]string{SecretTokenRotationAnnotation: rotation}
+	}
+	reconciledApmServerSecret := &corev1.Secret{}
+	return reconciledApmServerSecret, reconciler.ReconcileResource(
+		reconciler.Params{
+			Client: r.Client,
+			Scheme: r.scheme,
+
+			Owner:      as,
+			Expected:   expectedApmServerSecret,
+			Reconciled: reconciledApmServerSecret,
+
+			NeedsUpdate: func() bool {
+				if !reflect.DeepEqual(reconciledApmServerSecret.Labels, expectedApmServerSecret.Labels) {
+					return true
+				}
+
+				expectedApmServerSecret.Data = secretTokenData(
+					reconciledApmServerSecret.Data,
+					reconciledApmServerSecret.Annotations[SecretTokenRotationAnnotation] != rotation,
+					rolledOut,
+				)
+
+				if reconciledApmServerSecret.Annotations[SecretTokenRotationAnnotation] != rotation {
+					return true
+				}
+
+				return !reflect.DeepEqual(reconciledApmServerSecret.Data, expectedApmServerSecret.Data)
+			},
+			UpdateReconciled: func() {
+				reconciledApmServerSecret.Labels = expectedApmServerSecret.Labels
+				reconciledApmServerSecret.Data = expectedApmServerSecret.Data
+				if reconciledApmServerSecret.Annotations == nil {
+					reconciledApmServerSecret.Annotations = map[string]string{}
+				}
+				// record when the token was rotated, for the previous token to be removed once rolled out
+				_, hasPrevious := expectedApmServerSecret.Data[PreviousSecretTokenKey]
+				switch {
+				case !hasPrevious:
+					delete(reconciledApmServerSecret.Annotations, SecretTokenRotatedAtAnnotation)
+				case reconciledApmServerSecret.Annotations[SecretTokenRotationAnnotation] != rotation:
+					reconciledApmServerSecret.Annotations[SecretTokenRotatedAtAnnotation] = time.Now().UTC().Format(time.RFC3339)
+				}
+				// record the rotation the token was generated for
+				if rotation == "" {
+					delete(reconciledApmServerSecret.Annotations, SecretTokenRotationAnnotation)
+				} else {
+					reconciledApmServerSecret.Annotations[SecretTokenRotationAnnotation] = rotation
+				}
+			},
+			PreCreate: func() {
+				log.Info("Creating apm server secret", "namespace", expectedApmServerSecret.Namespace, "secret_name", expectedApmServerSecret.Name, "as_name", as.Name)
+			},
+			PreUpdate: func() {
+				log.Info("Updating apm server secret", "namespace", expectedApmServerSecret.Namespace, "secret_name", expectedApmServerSecret.Name, "as_name", as.Name)
+			},
+		},
+	)
+}
+
+// secretTokenData returns the expected content of the secret token Secret, given its current content.
+// The current token is re-used unless a rotation is requested, in which case a new token is generated and the
+// current one is kept as the previous token. The previous token is removed once all the APM Server instances
+// have been restarted with the new token.
+func secretTokenData(current map[string][]byte, rotate bool, rolledOut bool) map[string][]byte {
+	token, exists := current[SecretTokenKey]
+	if !exists {
+		return map[string][]byte{SecretTokenKey: []byte(rand.String(secretTokenLength))}
+	}
+	if rotate {
+		return map[string][]byte{
+			SecretTokenKey:         []byte(rand.String(secretTokenLength)),
+			PreviousSecretTokenKey: token,
+		}
+	}
+	data := map[string][]byte{SecretTokenKey: token}
+	if previous, exists := current[PreviousSecretTokenKey]; exists && !rolledOut {
+		data[PreviousSecretTokenKey] = previous
+	}
+	return data
+}
+
+// isTokenRolledOut returns true if all the APM Server instances have been restarted since the last rotation of the
+// secret token, and run with the current token.
+func (r *ReconcileApmServer) isTokenRolledOut(as *apmv1alpha1.ApmServer) (bool, error) {
+	var secret corev1.Secret
+	err := r.Get(types.NamespacedName{Namespace: as.Namespace, Name: apmname.SecretToken(as.Name)}, &secret)
+	if errors.IsNotFound(err) {
+		return true, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	// a parsing error leads to a zero time, for the previous token to be removed once the Deployment is rolled out
+	rotatedAt, _ := time.Parse(time.RFC3339, secret.Annotations[SecretTokenRotatedAtAnnotation])
+	return deployment.RolledOutSince(r.Client, types.NamespacedName{Namespace: as.Namespace, Name: apmname.Deployment(as.Name)}, rotatedAt)
+}
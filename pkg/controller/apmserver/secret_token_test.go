@@ -0,0 +1,206 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package apmserver
+
+import (
+	"testing"
+	"time"
+
+	apmv1alpha1 "github.com/elastic/cloud-on-k8s/pkg/apis/apm/v1alpha1"
+	"github.com/elastic/cloud-on-k8s/pkg/utils/k8s"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func Test_secretTokenData(t *testing.T) {
+	tests := []struct {
+		name       string
+		current    map[string][]byte
+		rotate     bool
+		rolledOut  bool
+		assertions func(data map[string][]byte)
+	}{
+		{
+			name: "token is generated",
+			assertions: func(data map[string][]byte) {
+				require.Len(t, data, 1)
+				require.Len(t, data[SecretTokenKey], secretTokenLength)
+			},
+		},
+		{
+			name:    "existing token is preserved",
+			current: map[string][]byte{SecretTokenKey: []byte("token")},
+			assertions: func(data map[string][]byte) {
+				require.Equal(t, map[string][]byte{SecretTokenKey: []byte("token")}, data)
+			},
+		},
+		{
+			name:    "token is rotated, the current one becomes the previous one",
+			current: map[string][]byte{SecretTokenKey: []byte("token"), PreviousSecretTokenKey: []byte("older-token")},
+			rotate:  true,
+			assertions: func(data map[string][]byte) {
+				require.Len(t, data, 2)
+				require.Equal(t, "token", string(data[PreviousSecretTokenKey]))
+				assert.NotEqual(t, "token", string(data[SecretTokenKey]))
+				require.Len(t, data[SecretTokenKey], secretTokenLength)
+			},
+		},
+		{
+			name:    "previous token is kept during the rollout",
+			current: map[string][]byte{SecretTokenKey: []byte("token"), PreviousSecretTokenKey: []byte("previous-token")},
+			assertions: func(data map[string][]byte) {
+				require.Equal(t, map[string][]byte{SecretTokenKey: []byte("token"), PreviousSecretTokenKey: []byte("previous-token")}, data)
+			},
+		},
+		{
+			name:      "previous token is removed once the rollout is over",
+			current:   map[string][]byte{SecretTokenKey: []byte("token"), PreviousSecretTokenKey: []byte("previous-token")},
+			rolledOut: true,
+			assertions: func(data map[string][]byte) {
+				require.Equal(t, map[string][]byte{SecretTokenKey: []byte("token")}, data)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.assertions(secretTokenData(tt.current, tt.rotate, tt.rolledOut))
+		})
+	}
+}
+
+func TestReconcileApmServer_reconcileApmServerSecret(t *testing.T) {
+	require.NoError(t, apmv1alpha1.AddToScheme(scheme.Scheme))
+	apm := func(rotation string) *apmv1alpha1.ApmServer {
+		as := &apmv1alpha1.ApmServer{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "as"}}
+		if rotation != "" {
+			as.Annotations = map[string]string{SecretTokenRotationAnnotation: rotation}
+		}
+		return as
+	}
+	tokenSecret := func(rotation string, data map[string][]byte) *corev1.Secret {
+		s := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "as-apm-token"},
+			Data:       data,
+		}
+		if rotation != "" {
+			s.Annotations = map[string]string{SecretTokenRotationAnnotation: rotation}
+		}
+		return s
+	}
+	tests := []struct {
+		name           string
+		as             *apmv1alpha1.ApmServer
+		initialObjects []runtime.Object
+		assertions     func(secret corev1.Secret)
+	}{
+		{
+			name: "secret token is created",
+			as:   apm(""),
+			assertions: func(secret corev1.Secret) {
+				require.Len(t, secret.Data[SecretTokenKey], secretTokenLength)
+				require.Empty(t, secret.Annotations[SecretTokenRotationAnnotation])
+			},
+		},
+		{
+			name:           "secret token is preserved",
+			as:             apm("1"),
+			initialObjects: []runtime.Object{tokenSecret("1", map[string][]byte{SecretTokenKey: []byte("token")})},
+			assertions: func(secret corev1.Secret) {
+				require.Equal(t, map[string][]byte{SecretTokenKey: []byte("token")}, secret.Data)
+			},
+		},
+		{
+			name:           "secret token is rotated on rotation annotation change",
+			as:             apm("2"),
+			initialObjects: []runtime.Object{tokenSecret("1", map[string][]byte{SecretTokenKey: []byte("token")})},
+			assertions: func(secret corev1.Secret) {
+				require.Equal(t, "2", secret.Annotations[SecretTokenRotationAnnotation])
+				require.NotEmpty(t, secret.Annotations[SecretTokenRotatedAtAnnotation])
+				require.Equal(t, "token", string(secret.Data[PreviousSecretTokenKey]))
+				assert.NotEqual(t, "token", string(secret.Data[SecretTokenKey]))
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := k8s.WrapClient(fake.NewFakeClient(tt.initialObjects...))
+			r := &ReconcileApmServer{Client: c, scheme: scheme.Scheme}
+			_, err := r.reconcileApmServerSecret(tt.as)
+			require.NoError(t, err)
+
+			var secret corev1.Secret
+			require.NoError(t, c.Get(types.NamespacedName{Namespace: "ns", Name: "as-apm-token"}, &secret))
+			tt.assertions(secret)
+		})
+	}
+}
+
+func TestReconcileApmServer_reconcileApmServerSecret_Rotation(t *testing.T) {
+	require.NoError(t, apmv1alpha1.AddToScheme(scheme.Scheme))
+	replicas := int32(2)
+	deployment := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "as-apm-server"},
+		Spec: appsv1.DeploymentSpec{
+			Replicas: &replicas,
+			Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"apm.k8s.elastic.co/name": "as"}},
+		},
+		Status: appsv1.DeploymentStatus{Replicas: 2, UpdatedReplicas: 2, AvailableReplicas: 2},
+	}
+	pod := func(name string, created time.Time) *corev1.Pod {
+		return &corev1.Pod{ObjectMeta: metav1.ObjectMeta{
+			Namespace:         "ns",
+			Name:              name,
+			Labels:            map[string]string{"apm.k8s.elastic.co/name": "as"},
+			CreationTimestamp: metav1.NewTime(created),
+		}}
+	}
+	beforeRotation := time.Now().Add(-time.Hour)
+	c := k8s.WrapClient(fake.NewFakeClient(deployment, pod("pod-1", beforeRotation), pod("pod-2", beforeRotation)))
+	r := &ReconcileApmServer{Client: c, scheme: scheme.Scheme}
+	as := &apmv1alpha1.ApmServer{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "as"}}
+	reconcile := func() corev1.Secret {
+		_, err := r.reconcileApmServerSecret(as)
+		require.NoError(t, err)
+		var secret corev1.Secret
+		require.NoError(t, c.Get(types.NamespacedName{Namespace: "ns", Name: "as-apm-token"}, &secret))
+		return secret
+	}
+
+	token := reconcile().Data[SecretTokenKey]
+
+	// rotation requested: the previous token is kept
+	as.Annotations = map[string]string{SecretTokenRotationAnnotation: "1"}
+	rotated := reconcile()
+	require.Equal(t, token, rotated.Data[PreviousSecretTokenKey])
+	newToken := rotated.Data[SecretTokenKey]
+	require.NotEqual(t, token, newToken)
+
+	// the Deployment is not updated yet, its pods still run with the previous token
+	require.Equal(t, rotated.Data, reconcile().Data)
+
+	// the Deployment is being rolled out
+	deployment.Status.UpdatedReplicas = 1
+	require.NoError(t, c.Update(deployment))
+	require.NoError(t, c.Delete(pod("pod-1", beforeRotation)))
+	require.NoError(t, c.Create(pod("pod-3", time.Now().Add(time.Minute))))
+	require.Equal(t, rotated.Data, reconcile().Data)
+
+	// all the pods run with the new token: the previous one is removed
+	deployment.Status.UpdatedReplicas = 2
+	require.NoError(t, c.Update(deployment))
+	require.NoError(t, c.Delete(pod("pod-2", beforeRotation)))
+	require.NoError(t, c.Create(pod("pod-4", time.Now().Add(time.Minute))))
+	done := reconcile()
+	require.Equal(t, map[string][]byte{SecretTokenKey: newToken}, done.Data)
+	require.NotContains(t, done.Annotations, SecretTokenRotatedAtAnnotation)
+	require.Equal(t, "1", done.Annotations[SecretTokenRotationAnnotation])
+}
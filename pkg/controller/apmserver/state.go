@@ -6,6 +6,8 @@ package apmserver
 
 import (
 	"github.com/elastic/cloud-on-k8s/pkg/apis/apm/v1alpha1"
+	commonv1alpha1 "github.com/elastic/cloud-on-k8s/pkg/apis/common/v1alpha1"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common"
 	v1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
@@ -27,9 +29,14 @@ func NewState(request reconcile.Request, as *v1alpha1.ApmServer) State {
 	return State{Request: request, ApmServer: as, originalApmServer: as.DeepCopy()}
 }
 
-// UpdateApmServerState updates the ApmServer status based on the given deployment.
-func (s State) UpdateApmServerState(deployment v1.Deployment, apmServerSecret corev1.Secret) {
+// UpdateApmServerState updates the ApmServer status based on the given deployment and authentication secrets.
+// The API key secret is nil if API key authentication is not enabled.
+func (s State) UpdateApmServerState(deployment v1.Deployment, apmServerSecret corev1.Secret, apiKeySecret *corev1.Secret) {
 	s.ApmServer.Status.SecretTokenSecretName = apmServerSecret.Name
+	s.ApmServer.Status.APIKeySecretName = ""
+	if apiKeySecret != nil {
+		s.ApmServer.Status.APIKeySecretName = apiKeySecret.Name
+	}
 	s.ApmServer.Status.AvailableNodes = int(deployment.Status.AvailableReplicas) // TODO lossy type conversion
 	s.ApmServer.Status.Health = v1alpha1.ApmServerRed
 	for _, c := range deployment.Status.Conditions {
@@ -37,6 +44,13 @@ func (s State) UpdateApmServerState(deployment v1.Deployment, apmServerSecret co
 			s.ApmServer.Status.Health = v1alpha1.ApmServerGreen
 		}
 	}
+	s.UpdateCondition(common.DeploymentReadyCondition(deployment))
+	s.UpdateCondition(common.DeploymentUpgradeCondition(deployment))
+}
+
+// UpdateCondition sets the given condition in the ApmServer status.
+func (s State) UpdateCondition(condition commonv1alpha1.Condition) {
+	s.ApmServer.Status.Conditions = s.ApmServer.Status.Conditions.Set(condition)
 }
 
 // UpdateApmServerExternalService updates the ApmServer ExternalService status.
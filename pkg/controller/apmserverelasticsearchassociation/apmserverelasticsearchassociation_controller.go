@@ -12,6 +12,7 @@ import (
 	commonv1alpha1 "github.com/elastic/cloud-on-k8s/pkg/apis/common/v1alpha1"
 	estype "github.com/elastic/cloud-on-k8s/pkg/apis/elasticsearch/v1alpha1"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/apmserver/labels"
+	apmname "github.com/elastic/cloud-on-k8s/pkg/controller/apmserver/name"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/common"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/common/annotation"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/common/association"
@@ -20,10 +21,12 @@ import (
 	"github.com/elastic/cloud-on-k8s/pkg/controller/common/finalizer"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/common/operator"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/common/user"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/version"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/common/watches"
 	esname "github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/name"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/services"
 	"github.com/elastic/cloud-on-k8s/pkg/utils/k8s"
+	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
@@ -67,11 +70,12 @@ func Add(mgr manager.Manager, params operator.Parameters) error {
 func newReconciler(mgr manager.Manager, params operator.Parameters) *ReconcileApmServerElasticsearchAssociation {
 	client := k8s.WrapClient(mgr.GetClient())
 	return &ReconcileApmServerElasticsearchAssociation{
-		Client:     client,
-		scheme:     mgr.GetScheme(),
-		watches:    watches.NewDynamicWatches(),
-		recorder:   mgr.GetRecorder(name),
-		Parameters: params,
+		Client:            client,
+		scheme:            mgr.GetScheme(),
+		watches:           watches.NewDynamicWatches(),
+		recorder:          mgr.GetRecorder(name),
+		Parameters:        params,
+		checkConnectivity: association.CheckConnectivity,
 	}
 }
 
@@ -109,6 +113,14 @@ func addWatches(c controller.Controller, r *ReconcileApmServerElasticsearchAssoc
 		return err
 	}
 
+	// Watch Deployments owned by an ApmServer resource, to delete the previous user of a password rotation once rolled out
+	if err := c.Watch(&source.Kind{Type: &appsv1.Deployment{}}, &handler.EnqueueRequestForOwner{
+		OwnerType:    &apmtype.ApmServer{},
+		IsController: true,
+	}); err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -121,6 +133,8 @@ type ReconcileApmServerElasticsearchAssociation struct {
 	recorder record.EventRecorder
 	watches  watches.DynamicWatches
 	operator.Parameters
+	// checkConnectivity verifies that an external Elasticsearch cluster can be reached
+	checkConnectivity association.ConnectivityChecker
 	// iteration is the number of times this controller has run its Reconcile method
 	iteration uint64
 }
@@ -169,6 +183,7 @@ func (r *ReconcileApmServerElasticsearchAssociation) Reconcile(request reconcile
 	oldStatus := apmServer.Status.Association
 	if !reflect.DeepEqual(oldStatus, newStatus) {
 		apmServer.Status.Association = newStatus
+		apmServer.Status.Conditions = apmServer.Status.Conditions.Set(association.ReadyCondition(newStatus))
 		if err := r.Status().Update(&apmServer); err != nil {
 			return defaultRequeue, err
 		}
@@ -192,6 +207,18 @@ func esCAWatchName(apm types.NamespacedName) string {
 	return apm.Namespace + "-" + apm.Name + "-ca-watch"
 }
 
+// providedPasswordsWatchName returns the name of the watch setup on the secret containing the user-provided
+// passwords of the Elasticsearch cluster.
+func providedPasswordsWatchName(apm types.NamespacedName) string {
+	return apm.Namespace + "-" + apm.Name + "-provided-passwords-watch"
+}
+
+// externalSecretsWatchName returns the name of the watch setup on the user-provided secrets
+// of an external Elasticsearch cluster.
+func externalSecretsWatchName(apm types.NamespacedName) string {
+	return apm.Namespace + "-" + apm.Name + "-external-es-watch"
+}
+
 // watchFinalizer ensure that we remove watches for Elasticsearch clusters that we are no longer interested in
 // because the association to the APM server has been deleted.
 func watchFinalizer(assocKey types.NamespacedName, w watches.DynamicWatches) finalizer.Finalizer {
@@ -200,6 +227,8 @@ func watchFinalizer(assocKey types.NamespacedName, w watches.DynamicWatches) fin
 		Execute: func() error {
 			w.ElasticsearchClusters.RemoveHandlerForKey(elasticsearchWatchName(assocKey))
 			w.Secrets.RemoveHandlerForKey(esCAWatchName(assocKey))
+			w.Secrets.RemoveHandlerForKey(providedPasswordsWatchName(assocKey))
+			w.Secrets.RemoveHandlerForKey(externalSecretsWatchName(assocKey))
 			return nil
 		},
 	}
@@ -207,7 +236,7 @@ func watchFinalizer(assocKey types.NamespacedName, w watches.DynamicWatches) fin
 
 func resultFromStatus(status commonv1alpha1.AssociationStatus) reconcile.Result {
 	switch status {
-	case commonv1alpha1.AssociationPending:
+	case commonv1alpha1.AssociationPending, commonv1alpha1.AssociationFailed:
 		return defaultRequeue // retry
 	default:
 		return reconcile.Result{} // we are done or there is not much we can do
@@ -224,8 +253,25 @@ func (r *ReconcileApmServerElasticsearchAssociation) isCompatible(apmServer *apm
 }
 
 func (r *ReconcileApmServerElasticsearchAssociation) reconcileInternal(apmServer *apmtype.ApmServer) (commonv1alpha1.AssociationStatus, error) {
-	// no auto-association nothing to do
+	assocKey := k8s.ExtractNamespacedName(apmServer)
 	elasticsearchRef := apmServer.Spec.ElasticsearchRef
+
+	if apmServer.Spec.ExternalElasticsearchRef.IsDefined() {
+		if elasticsearchRef.IsDefined() {
+			r.recorder.Event(apmServer, corev1.EventTypeWarning, events.EventAssociationError,
+				"elasticsearchRef and externalElasticsearchRef cannot be set at the same time")
+			return commonv1alpha1.AssociationFailed, nil
+		}
+		// stop watching any ES cluster previously referenced for this APM server
+		r.watches.ElasticsearchClusters.RemoveHandlerForKey(elasticsearchWatchName(assocKey))
+		r.watches.Secrets.RemoveHandlerForKey(esCAWatchName(assocKey))
+		r.watches.Secrets.RemoveHandlerForKey(providedPasswordsWatchName(assocKey))
+		return r.reconcileExternal(apmServer)
+	}
+	// stop watching the secrets of any external ES cluster previously referenced
+	r.watches.Secrets.RemoveHandlerForKey(externalSecretsWatchName(assocKey))
+
+	// no auto-association nothing to do
 	if !elasticsearchRef.IsDefined() {
 		return commonv1alpha1.AssociationUnknown, nil
 	}
@@ -233,7 +279,6 @@ func (r *ReconcileApmServerElasticsearchAssociation) reconcileInternal(apmServer
 		// no namespace provided: default to the APM server namespace
 		elasticsearchRef.Namespace = apmServer.Namespace
 	}
-	assocKey := k8s.ExtractNamespacedName(apmServer)
 	// Make sure we see events from Elasticsearch using a dynamic watch
 	// will become more relevant once we refactor user handling to CRDs and implement
 	// syncing of user credentials across namespaces
@@ -263,7 +308,12 @@ func (r *ReconcileApmServerElasticsearchAssociation) reconcileInternal(apmServer
 		return commonv1alpha1.AssociationFailed, err
 	}
 
-	if err := association.ReconcileEsUser(
+	// watch the user-provided passwords of the ES cluster, which may contain the password of the APM user
+	if err := r.reconcileProvidedPasswordsWatch(assocKey, es); err != nil {
+		return commonv1alpha1.AssociationFailed, err
+	}
+
+	authSecretRef, err := association.ReconcileEsUser(
 		r.Client,
 		r.scheme,
 		apmServer,
@@ -274,7 +324,9 @@ func (r *ReconcileApmServerElasticsearchAssociation) reconcileInternal(apmServer
 		"superuser",
 		apmUserSuffix,
 		es,
-	); err != nil { // TODO distinguish conflicts and non-recoverable errors here
+		types.NamespacedName{Namespace: apmServer.Namespace, Name: apmname.Deployment(apmServer.Name)},
+	)
+	if err != nil { // TODO distinguish conflicts and non-recoverable errors here
 		return commonv1alpha1.AssociationPending, err
 	}
 
@@ -282,9 +334,12 @@ func (r *ReconcileApmServerElasticsearchAssociation) reconcileInternal(apmServer
 	if err != nil {
 		return commonv1alpha1.AssociationPending, err // maybe not created yet
 	}
+	if caSecretName == "" {
+		// ES CA not created yet, we'll be notified to reconcile later
+		return commonv1alpha1.AssociationPending, nil
+	}
 
 	// construct the expected ES output configuration
-	authSecretRef := association.ClearTextSecretKeySelector(apmServer, apmUserSuffix)
 	expectedAssocConf := &commonv1alpha1.AssociationConf{
 		AuthSecretName: authSecretRef.Name,
 		AuthSecretKey:  authSecretRef.Key,
@@ -292,25 +347,120 @@ func (r *ReconcileApmServerElasticsearchAssociation) reconcileInternal(apmServer
 		URL:            services.ExternalServiceURL(es),
 	}
 
-	if !reflect.DeepEqual(expectedAssocConf, apmServer.AssociationConf()) {
-		log.Info("Updating APMServer spec with Elasticsearch association configuration", "namespace", apmServer.Namespace, "name", apmServer.Name)
-		if err := association.UpdateAssociationConf(r.Client, apmServer, expectedAssocConf); err != nil {
-			if errors.IsConflict(err) {
-				return commonv1alpha1.AssociationPending, nil
-			}
-			log.Error(err, "Failed to update APMServer association configuration", "namespace", apmServer.Namespace, "name", apmServer.Name)
-			return commonv1alpha1.AssociationPending, err
+	if updated, err := r.updateAssociationConf(apmServer, expectedAssocConf); !updated {
+		return commonv1alpha1.AssociationPending, err
+	}
+
+	if err := deleteOrphanedResources(r, apmServer); err != nil {
+		log.Error(err, "Error while trying to delete orphaned resources. Continuing.", "namespace", apmServer.Namespace, "as_name", apmServer.Name)
+	}
+
+	return commonv1alpha1.AssociationEstablished, nil
+}
+
+// reconcileExternal sets up the association with an Elasticsearch cluster not managed by the operator.
+func (r *ReconcileApmServerElasticsearchAssociation) reconcileExternal(apmServer *apmtype.ApmServer) (commonv1alpha1.AssociationStatus, error) {
+	assocKey := k8s.ExtractNamespacedName(apmServer)
+	externalRef := apmServer.Spec.ExternalElasticsearchRef
+
+	// watch the user-provided secrets to reconcile on any change
+	if err := r.watches.Secrets.AddHandler(watches.NamedWatch{
+		Name:    externalSecretsWatchName(assocKey),
+		Watched: association.ExternalSecretKeys(apmServer),
+		Watcher: assocKey,
+	}); err != nil {
+		return commonv1alpha1.AssociationFailed, err
+	}
+
+	secretLabels := labels.NewLabels(apmServer.Name)
+	secretLabels[AssociationLabelName] = apmServer.Name
+	secretLabels[AssociationLabelNamespace] = apmServer.Namespace
+
+	authSecret, err := association.ReconcileExternalUserSecret(r.Client, r.scheme, apmServer, secretLabels, apmUserSuffix)
+	if err != nil {
+		k8s.EmitErrorEvent(r.recorder, err, apmServer, events.EventAssociationError, "Failed to reconcile external Elasticsearch credentials: %v", err)
+		if apierrors.IsNotFound(err) {
+			// we'll be notified to reconcile once the secret is created
+			return commonv1alpha1.AssociationPending, nil
 		}
-		apmServer.SetAssociationConf(expectedAssocConf)
+		return commonv1alpha1.AssociationFailed, nil
+	}
+
+	caSecretName, err := association.ReconcileExternalCASecret(r.Client, r.scheme, apmServer, secretLabels, elasticsearchCASecretSuffix)
+	if err != nil {
+		k8s.EmitErrorEvent(r.recorder, err, apmServer, events.EventAssociationError, "Failed to reconcile external Elasticsearch CA: %v", err)
+		if apierrors.IsNotFound(err) {
+			return commonv1alpha1.AssociationPending, nil
+		}
+		return commonv1alpha1.AssociationFailed, nil
+	}
+
+	expectedAssocConf := &commonv1alpha1.AssociationConf{
+		AuthSecretName: authSecret.Name,
+		AuthSecretKey:  authSecret.Key,
+		CASecretName:   caSecretName,
+		URL:            externalRef.URL,
+	}
+	if updated, err := r.updateAssociationConf(apmServer, expectedAssocConf); !updated {
+		return commonv1alpha1.AssociationPending, err
 	}
 
 	if err := deleteOrphanedResources(r, apmServer); err != nil {
 		log.Error(err, "Error while trying to delete orphaned resources. Continuing.", "namespace", apmServer.Namespace, "as_name", apmServer.Name)
 	}
 
+	ver, err := version.Parse(apmServer.Spec.Version)
+	if err != nil {
+		return commonv1alpha1.AssociationFailed, err
+	}
+	if err := r.checkConnectivity(r.Client, apmServer, *ver); err != nil {
+		k8s.EmitErrorEvent(r.recorder, err, apmServer, events.EventAssociationError,
+			"Failed to connect to external Elasticsearch cluster %s: %v", externalRef.URL, err)
+		return commonv1alpha1.AssociationFailed, nil
+	}
+
 	return commonv1alpha1.AssociationEstablished, nil
 }
 
+// updateAssociationConf updates the association configuration if necessary.
+// It returns false if the configuration could not be updated yet.
+func (r *ReconcileApmServerElasticsearchAssociation) updateAssociationConf(
+	apmServer *apmtype.ApmServer,
+	expected *commonv1alpha1.AssociationConf,
+) (bool, error) {
+	if reflect.DeepEqual(expected, apmServer.AssociationConf()) {
+		return true, nil
+	}
+	log.Info("Updating APMServer spec with Elasticsearch association configuration", "namespace", apmServer.Namespace, "name", apmServer.Name)
+	if err := association.UpdateAssociationConf(r.Client, apmServer, expected); err != nil {
+		if errors.IsConflict(err) {
+			return false, nil
+		}
+		log.Error(err, "Failed to update APMServer association configuration", "namespace", apmServer.Namespace, "name", apmServer.Name)
+		return false, err
+	}
+	apmServer.SetAssociationConf(expected)
+	return true, nil
+}
+
+// reconcileProvidedPasswordsWatch sets a watch on the secret containing the user-provided passwords of the given
+// Elasticsearch cluster, or removes it if there is none.
+func (r *ReconcileApmServerElasticsearchAssociation) reconcileProvidedPasswordsWatch(
+	assocKey types.NamespacedName,
+	es estype.Elasticsearch,
+) error {
+	secretKey, exists := user.ProvidedPasswordsSecretKey(es)
+	if !exists {
+		r.watches.Secrets.RemoveHandlerForKey(providedPasswordsWatchName(assocKey))
+		return nil
+	}
+	return r.watches.Secrets.AddHandler(watches.NamedWatch{
+		Name:    providedPasswordsWatchName(assocKey),
+		Watched: []types.NamespacedName{secretKey},
+		Watcher: assocKey,
+	})
+}
+
 func (r *ReconcileApmServerElasticsearchAssociation) reconcileElasticsearchCA(apm *apmtype.ApmServer, es types.NamespacedName) (string, error) {
 	apmKey := k8s.ExtractNamespacedName(apm)
 	// watch ES CA secret to reconcile on any change
@@ -345,13 +495,22 @@ func deleteOrphanedResources(c k8s.Client, apm *apmtype.ApmServer) error {
 		return err
 	}
 
+	externalRef := apm.Spec.ExternalElasticsearchRef
 	for _, s := range secrets.Items {
 		controlledBy := metav1.IsControlledBy(&s, apm)
-		if controlledBy && !apm.Spec.ElasticsearchRef.IsDefined() {
+		unusedCA := externalRef.IsDefined() && externalRef.CASecret.SecretName == "" &&
+			s.Name == association.ElasticsearchCACertSecretName(apm, elasticsearchCASecretSuffix)
+		if controlledBy && ((!apm.Spec.ElasticsearchRef.IsDefined() && !externalRef.IsDefined()) || unusedCA) {
 			log.Info("Deleting secret", "namespace", s.Namespace, "secret_name", s.Name, "as_name", apm.Name)
 			if err := c.Delete(&s); err != nil {
 				return err
 			}
+		} else if value, ok := s.Labels[common.TypeLabelName]; ok && value == user.UserType && externalRef.IsDefined() {
+			// the Elasticsearch user is not needed with an external cluster
+			log.Info("Deleting secret", "namespace", s.Namespace, "secret_name", s.Name, "as_name", apm.Name)
+			if err := c.Delete(&s); err != nil && !apierrors.IsNotFound(err) {
+				return err
+			}
 		}
 	}
 	return nil
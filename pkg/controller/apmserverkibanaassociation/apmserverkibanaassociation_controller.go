@@ -0,0 +1,455 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package apmserverkibanaassociation
+
+import (
+	"reflect"
+	"time"
+
+	apmtype "github.com/elastic/cloud-on-k8s/pkg/apis/apm/v1alpha1"
+	commonv1alpha1 "github.com/elastic/cloud-on-k8s/pkg/apis/common/v1alpha1"
+	estype "github.com/elastic/cloud-on-k8s/pkg/apis/elasticsearch/v1alpha1"
+	kbtype "github.com/elastic/cloud-on-k8s/pkg/apis/kibana/v1alpha1"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/apmserver/labels"
+	apmname "github.com/elastic/cloud-on-k8s/pkg/controller/apmserver/name"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/annotation"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/association"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/certificates/http"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/events"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/finalizer"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/operator"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/user"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/watches"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/kibana"
+	kbname "github.com/elastic/cloud-on-k8s/pkg/controller/kibana/name"
+	"github.com/elastic/cloud-on-k8s/pkg/utils/k8s"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8slabels "k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	logf "sigs.k8s.io/controller-runtime/pkg/runtime/log"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+)
+
+const (
+	name                 = "apm-kb-association-controller"
+	kibanaUserSuffix     = "apm-kb-user"
+	kibanaCASecretSuffix = "apm-kb-ca" // nolint
+
+	// kibanaUserRole is the role of the user used by the APM Server to access the Kibana APIs
+	// for agent central configuration and source maps.
+	kibanaUserRole = "kibana_user"
+)
+
+var (
+	log            = logf.Log.WithName(name)
+	defaultRequeue = reconcile.Result{Requeue: true, RequeueAfter: 10 * time.Second}
+)
+
+// Add creates a new ApmServerKibanaAssociation Controller and adds it to the Manager with default RBAC. The Manager will set fields on the Controller
+// and Start it when the Manager is Started.
+func Add(mgr manager.Manager, params operator.Parameters) error {
+	r := newReconciler(mgr, params)
+	c, err := add(mgr, r)
+	if err != nil {
+		return err
+	}
+	return addWatches(c, r)
+}
+
+// newReconciler returns a new reconcile.Reconciler
+func newReconciler(mgr manager.Manager, params operator.Parameters) *ReconcileApmServerKibanaAssociation {
+	client := k8s.WrapClient(mgr.GetClient())
+	return &ReconcileApmServerKibanaAssociation{
+		Client:     client,
+		scheme:     mgr.GetScheme(),
+		watches:    watches.NewDynamicWatches(),
+		recorder:   mgr.GetRecorder(name),
+		Parameters: params,
+	}
+}
+
+// add adds a new Controller to mgr with r as the reconcile.Reconciler
+func add(mgr manager.Manager, r reconcile.Reconciler) (controller.Controller, error) {
+	// Create a new controller
+	c, err := controller.New(name, mgr, controller.Options{Reconciler: r})
+	if err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func addWatches(c controller.Controller, r *ReconcileApmServerKibanaAssociation) error {
+	// Watch for changes to ApmServers
+	if err := c.Watch(&source.Kind{Type: &apmtype.ApmServer{}}, &handler.EnqueueRequestForObject{}); err != nil {
+		return err
+	}
+
+	// Watch Kibana objects
+	if err := c.Watch(&source.Kind{Type: &kbtype.Kibana{}}, r.watches.Kibanas); err != nil {
+		return err
+	}
+
+	// Watch the Elasticsearch clusters Kibana is associated with
+	if err := c.Watch(&source.Kind{Type: &estype.Elasticsearch{}}, r.watches.ElasticsearchClusters); err != nil {
+		return err
+	}
+
+	// Dynamically watch Kibana public CA secrets for referenced Kibana instances
+	if err := c.Watch(&source.Kind{Type: &corev1.Secret{}}, r.watches.Secrets); err != nil {
+		return err
+	}
+
+	// Watch Secrets owned by an ApmServer resource
+	if err := c.Watch(&source.Kind{Type: &corev1.Secret{}}, &handler.EnqueueRequestForOwner{
+		OwnerType:    &apmtype.ApmServer{},
+		IsController: true,
+	}); err != nil {
+		return err
+	}
+
+	// Watch Deployments owned by an ApmServer resource, to delete the previous user of a password rotation once rolled out
+	if err := c.Watch(&source.Kind{Type: &appsv1.Deployment{}}, &handler.EnqueueRequestForOwner{
+		OwnerType:    &apmtype.ApmServer{},
+		IsController: true,
+	}); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+var _ reconcile.Reconciler = &ReconcileApmServerKibanaAssociation{}
+
+// ReconcileApmServerKibanaAssociation reconciles the association between an ApmServer and Kibana
+type ReconcileApmServerKibanaAssociation struct {
+	k8s.Client
+	scheme   *runtime.Scheme
+	recorder record.EventRecorder
+	watches  watches.DynamicWatches
+	operator.Parameters
+	// iteration is the number of times this controller has run its Reconcile method
+	iteration uint64
+}
+
+// Reconcile reads that state of the cluster for an ApmServer object and sets up its association with Kibana,
+// as specified in the ApmServer.Spec.KibanaRef
+func (r *ReconcileApmServerKibanaAssociation) Reconcile(request reconcile.Request) (reconcile.Result, error) {
+	defer common.LogReconciliationRun(log, request, &r.iteration)()
+
+	var apmServer apmtype.ApmServer
+	if err := r.Get(request.NamespacedName, &apmServer); err != nil {
+		if apierrors.IsNotFound(err) {
+			return reconcile.Result{}, nil
+		}
+		return reconcile.Result{}, err
+	}
+	kbAssocConf, err := association.GetKibanaAssociationConf(&apmServer)
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+	apmServer.SetKibanaAssociationConf(kbAssocConf)
+
+	if common.IsPaused(apmServer.ObjectMeta) {
+		log.Info("Object is paused. Skipping reconciliation", "namespace", apmServer.Namespace, "as_name", apmServer.Name)
+		return common.PauseRequeue, nil
+	}
+
+	handler := finalizer.NewHandler(r)
+	apmName := k8s.ExtractNamespacedName(&apmServer)
+	err = handler.Handle(
+		&apmServer,
+		watchFinalizer(apmName, r.watches),
+		userFinalizer(r.Client, apmName),
+	)
+	if err != nil {
+		// failed to prepare finalizer or run finalizer: retry
+		return defaultRequeue, err
+	}
+
+	// ApmServer is being deleted short-circuit reconciliation
+	if !apmServer.DeletionTimestamp.IsZero() {
+		return reconcile.Result{}, nil
+	}
+
+	if compatible, err := r.isCompatible(&apmServer); err != nil || !compatible {
+		return reconcile.Result{}, err
+	}
+
+	newStatus, err := r.reconcileInternal(&apmServer)
+	oldStatus := apmServer.Status.KibanaAssociation
+	if !reflect.DeepEqual(oldStatus, newStatus) {
+		apmServer.Status.KibanaAssociation = newStatus
+		if err := r.Status().Update(&apmServer); err != nil {
+			return defaultRequeue, err
+		}
+		r.recorder.AnnotatedEventf(&apmServer,
+			annotation.ForAssociationStatusChange(oldStatus, newStatus),
+			corev1.EventTypeNormal,
+			events.EventAssociationStatusChange,
+			"Kibana association status changed from [%s] to [%s]", oldStatus, newStatus)
+
+	}
+	return resultFromStatus(newStatus), err
+}
+
+func kibanaWatchName(assocKey types.NamespacedName) string {
+	return assocKey.Namespace + "-" + assocKey.Name + "-kb-watch"
+}
+
+// elasticsearchWatchName returns the name of the watch setup on the Elasticsearch cluster Kibana is associated with.
+func elasticsearchWatchName(assocKey types.NamespacedName) string {
+	return assocKey.Namespace + "-" + assocKey.Name + "-kb-es-watch"
+}
+
+// kibanaCAWatchName returns the name of the watch setup on the secret that
+// contains the HTTP certificate chain of Kibana.
+func kibanaCAWatchName(apm types.NamespacedName) string {
+	return apm.Namespace + "-" + apm.Name + "-kb-ca-watch"
+}
+
+// watchFinalizer ensure that we remove watches for Kibana instances that we are no longer interested in
+// because the association to the APM server has been deleted.
+func watchFinalizer(assocKey types.NamespacedName, w watches.DynamicWatches) finalizer.Finalizer {
+	return finalizer.Finalizer{
+		Name: "finalizer.association.apmserver.k8s.elastic.co/kibana",
+		Execute: func() error {
+			removeWatches(assocKey, w)
+			return nil
+		},
+	}
+}
+
+// userFinalizer deletes the Elasticsearch user of the APM server for Kibana.
+// It is named after the association, to not conflict with the finalizer of the Elasticsearch user of the APM server.
+func userFinalizer(c k8s.Client, assocKey types.NamespacedName) finalizer.Finalizer {
+	f := user.UserFinalizer(c, NewUserLabelSelector(assocKey), apmtype.Kind)
+	f.Name = "finalizer.association.apmserver.k8s.elastic.co/kibana-user"
+	return f
+}
+
+func removeWatches(assocKey types.NamespacedName, w watches.DynamicWatches) {
+	w.Kibanas.RemoveHandlerForKey(kibanaWatchName(assocKey))
+	w.ElasticsearchClusters.RemoveHandlerForKey(elasticsearchWatchName(assocKey))
+	w.Secrets.RemoveHandlerForKey(kibanaCAWatchName(assocKey))
+}
+
+func resultFromStatus(status commonv1alpha1.AssociationStatus) reconcile.Result {
+	switch status {
+	case commonv1alpha1.AssociationPending, commonv1alpha1.AssociationFailed:
+		return defaultRequeue // retry
+	default:
+		return reconcile.Result{} // we are done or there is not much we can do
+	}
+}
+
+func (r *ReconcileApmServerKibanaAssociation) isCompatible(apmServer *apmtype.ApmServer) (bool, error) {
+	selector := k8slabels.Set(map[string]string{labels.ApmServerNameLabelName: apmServer.Name}).AsSelector()
+	compat, err := annotation.ReconcileCompatibility(r.Client, apmServer, selector, r.OperatorInfo.BuildInfo.Version)
+	if err != nil {
+		k8s.EmitErrorEvent(r.recorder, err, apmServer, events.EventCompatCheckError, "Error during compatibility check: %v", err)
+	}
+	return compat, err
+}
+
+func (r *ReconcileApmServerKibanaAssociation) reconcileInternal(apmServer *apmtype.ApmServer) (commonv1alpha1.AssociationStatus, error) {
+	assocKey := k8s.ExtractNamespacedName(apmServer)
+	kibanaRef := apmServer.Spec.KibanaRef
+
+	// no association with Kibana: clean up any previous association
+	if !kibanaRef.IsDefined() {
+		removeWatches(assocKey, r.watches)
+		if err := association.RemoveKibanaAssociationConf(r.Client, apmServer); err != nil && !apierrors.IsConflict(err) {
+			return commonv1alpha1.AssociationUnknown, err
+		}
+		apmServer.SetKibanaAssociationConf(nil)
+		if err := deleteOrphanedResources(r, apmServer); err != nil {
+			log.Error(err, "Error while trying to delete orphaned resources. Continuing.", "namespace", apmServer.Namespace, "as_name", apmServer.Name)
+		}
+		return commonv1alpha1.AssociationUnknown, nil
+	}
+	if kibanaRef.Namespace == "" {
+		// no namespace provided: default to the APM server namespace
+		kibanaRef.Namespace = apmServer.Namespace
+	}
+
+	if err := r.watches.Kibanas.AddHandler(watches.NamedWatch{
+		Name:    kibanaWatchName(assocKey),
+		Watched: []types.NamespacedName{kibanaRef.NamespacedName()},
+		Watcher: assocKey,
+	}); err != nil {
+		return commonv1alpha1.AssociationFailed, err
+	}
+
+	var kb kbtype.Kibana
+	if err := r.Get(kibanaRef.NamespacedName(), &kb); err != nil {
+		k8s.EmitErrorEvent(r.recorder, err, apmServer, events.EventAssociationError,
+			"Failed to find referenced Kibana %s: %v", kibanaRef.NamespacedName(), err)
+		if apierrors.IsNotFound(err) {
+			// Kibana is not found, remove any existing Kibana configuration and retry in a bit.
+			if err := association.RemoveKibanaAssociationConf(r.Client, apmServer); err != nil && !apierrors.IsConflict(err) {
+				log.Error(err, "Failed to remove Kibana configuration from APMServer object", "namespace", apmServer.Namespace, "name", apmServer.Name)
+				return commonv1alpha1.AssociationPending, err
+			}
+			return commonv1alpha1.AssociationPending, nil
+		}
+		return commonv1alpha1.AssociationFailed, err
+	}
+
+	// the APM server authenticates against Kibana with a user of the Elasticsearch cluster Kibana is associated with
+	esRef := kb.Spec.ElasticsearchRef
+	if !esRef.IsDefined() {
+		r.recorder.Eventf(apmServer, corev1.EventTypeWarning, events.EventAssociationError,
+			"Kibana %s is not associated with an Elasticsearch cluster managed by the operator", kibanaRef.NamespacedName())
+		return commonv1alpha1.AssociationPending, nil
+	}
+	if esRef.Namespace == "" {
+		esRef.Namespace = kb.Namespace
+	}
+	if err := r.watches.ElasticsearchClusters.AddHandler(watches.NamedWatch{
+		Name:    elasticsearchWatchName(assocKey),
+		Watched: []types.NamespacedName{esRef.NamespacedName()},
+		Watcher: assocKey,
+	}); err != nil {
+		return commonv1alpha1.AssociationFailed, err
+	}
+	var es estype.Elasticsearch
+	if err := r.Get(esRef.NamespacedName(), &es); err != nil {
+		k8s.EmitErrorEvent(r.recorder, err, apmServer, events.EventAssociationError,
+			"Failed to find the Elasticsearch cluster %s of Kibana %s: %v", esRef.NamespacedName(), kibanaRef.NamespacedName(), err)
+		if apierrors.IsNotFound(err) {
+			return commonv1alpha1.AssociationPending, nil
+		}
+		return commonv1alpha1.AssociationFailed, err
+	}
+
+	authSecretRef, err := association.ReconcileEsUser(
+		r.Client,
+		r.scheme,
+		apmServer,
+		map[string]string{
+			AssociationLabelName:      apmServer.Name,
+			AssociationLabelNamespace: apmServer.Namespace,
+		},
+		kibanaUserRole,
+		kibanaUserSuffix,
+		es,
+		types.NamespacedName{Namespace: apmServer.Namespace, Name: apmname.Deployment(apmServer.Name)},
+	)
+	if err != nil {
+		return commonv1alpha1.AssociationPending, err
+	}
+
+	var caSecretName string
+	if kb.Spec.HTTP.TLS.Enabled() {
+		var err error
+		caSecretName, err = r.reconcileKibanaCA(apmServer, kibanaRef.NamespacedName())
+		if err != nil {
+			return commonv1alpha1.AssociationPending, err
+		}
+		if caSecretName == "" {
+			// Kibana CA not created yet, we'll be notified to reconcile later
+			return commonv1alpha1.AssociationPending, nil
+		}
+	} else {
+		r.watches.Secrets.RemoveHandlerForKey(kibanaCAWatchName(assocKey))
+	}
+
+	expectedAssocConf := &commonv1alpha1.AssociationConf{
+		AuthSecretName: authSecretRef.Name,
+		AuthSecretKey:  authSecretRef.Key,
+		CASecretName:   caSecretName,
+		URL:            kibana.ExternalServiceURL(kb),
+	}
+	if updated, err := r.updateAssociationConf(apmServer, expectedAssocConf); !updated {
+		return commonv1alpha1.AssociationPending, err
+	}
+
+	if err := deleteOrphanedResources(r, apmServer); err != nil {
+		log.Error(err, "Error while trying to delete orphaned resources. Continuing.", "namespace", apmServer.Namespace, "as_name", apmServer.Name)
+	}
+
+	return commonv1alpha1.AssociationEstablished, nil
+}
+
+// updateAssociationConf updates the Kibana association configuration if necessary.
+// It returns false if the configuration could not be updated yet.
+func (r *ReconcileApmServerKibanaAssociation) updateAssociationConf(
+	apmServer *apmtype.ApmServer,
+	expected *commonv1alpha1.AssociationConf,
+) (bool, error) {
+	if reflect.DeepEqual(expected, apmServer.KibanaAssociationConf()) {
+		return true, nil
+	}
+	log.Info("Updating APMServer spec with Kibana association configuration", "namespace", apmServer.Namespace, "name", apmServer.Name)
+	if err := association.UpdateKibanaAssociationConf(r.Client, apmServer, expected); err != nil {
+		if apierrors.IsConflict(err) {
+			return false, nil
+		}
+		log.Error(err, "Failed to update APMServer Kibana association configuration", "namespace", apmServer.Namespace, "name", apmServer.Name)
+		return false, err
+	}
+	apmServer.SetKibanaAssociationConf(expected)
+	return true, nil
+}
+
+func (r *ReconcileApmServerKibanaAssociation) reconcileKibanaCA(apm *apmtype.ApmServer, kb types.NamespacedName) (string, error) {
+	apmKey := k8s.ExtractNamespacedName(apm)
+	// watch Kibana CA secret to reconcile on any change
+	if err := r.watches.Secrets.AddHandler(watches.NamedWatch{
+		Name:    kibanaCAWatchName(apmKey),
+		Watched: []types.NamespacedName{http.PublicCertsSecretRef(kbname.KBNamer, kb)},
+		Watcher: apmKey,
+	}); err != nil {
+		return "", err
+	}
+	// Build the labels applied on the secret
+	labels := labels.NewLabels(apm.Name)
+	labels[AssociationLabelName] = apm.Name
+	return association.ReconcilePublicCertsSecret(
+		r.Client,
+		r.scheme,
+		apm,
+		kbname.KBNamer,
+		kb,
+		labels,
+		kibanaCASecretSuffix,
+	)
+}
+
+// deleteOrphanedResources deletes resources created by this association that are not needed anymore:
+// all of them if the Kibana reference was removed, the CA secret if Kibana does not use TLS anymore.
+func deleteOrphanedResources(c k8s.Client, apm *apmtype.ApmServer) error {
+	var secrets corev1.SecretList
+	selector := NewResourceSelector(apm.Name)
+	if err := c.List(&client.ListOptions{LabelSelector: selector}, &secrets); err != nil {
+		return err
+	}
+
+	for _, s := range secrets.Items {
+		controlledBy := metav1.IsControlledBy(&s, apm)
+		unusedCA := s.Name == association.ElasticsearchCACertSecretName(apm, kibanaCASecretSuffix) &&
+			!apm.KibanaAssociationConf().CAIsConfigured()
+		isUser := s.Labels[common.TypeLabelName] == user.UserType && s.Labels[AssociationLabelNamespace] == apm.Namespace
+		if (controlledBy && (!apm.Spec.KibanaRef.IsDefined() || unusedCA)) ||
+			(isUser && !apm.Spec.KibanaRef.IsDefined()) {
+			log.Info("Deleting secret", "namespace", s.Namespace, "secret_name", s.Name, "as_name", apm.Name)
+			if err := c.Delete(&s); err != nil && !apierrors.IsNotFound(err) {
+				return err
+			}
+		}
+	}
+	return nil
+}
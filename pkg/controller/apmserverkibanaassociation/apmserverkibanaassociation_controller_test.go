@@ -0,0 +1,309 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package apmserverkibanaassociation
+
+import (
+	"testing"
+
+	apmtype "github.com/elastic/cloud-on-k8s/pkg/apis/apm/v1alpha1"
+	commonv1alpha1 "github.com/elastic/cloud-on-k8s/pkg/apis/common/v1alpha1"
+	estype "github.com/elastic/cloud-on-k8s/pkg/apis/elasticsearch/v1alpha1"
+	kbtype "github.com/elastic/cloud-on-k8s/pkg/apis/kibana/v1alpha1"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/association"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/certificates"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/certificates/http"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/user"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/watches"
+	kbname "github.com/elastic/cloud-on-k8s/pkg/controller/kibana/name"
+	"github.com/elastic/cloud-on-k8s/pkg/utils/k8s"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+const (
+	esUserName     = "default-as-apm-kb-user"
+	userSecretName = "as-apm-kb-user"
+	caSecretName   = "as-apm-kb-ca"
+)
+
+var t = true
+var ownerRefFixture = metav1.OwnerReference{
+	APIVersion:         "apm.k8s.elastic.co/v1alpha1",
+	Kind:               "ApmServer",
+	Name:               "as",
+	UID:                "",
+	Controller:         &t,
+	BlockOwnerDeletion: &t,
+}
+
+// apmFixture is a shared test fixture
+var apmFixture = apmtype.ApmServer{
+	ObjectMeta: metav1.ObjectMeta{
+		Name:      "as",
+		Namespace: "default",
+	},
+	Spec: apmtype.ApmServerSpec{
+		KibanaRef: commonv1alpha1.ObjectSelector{
+			Name:      "kb",
+			Namespace: "kb-ns",
+		},
+	},
+}
+
+var kibanaFixture = kbtype.Kibana{
+	ObjectMeta: metav1.ObjectMeta{
+		Name:      "kb",
+		Namespace: "kb-ns",
+	},
+	Spec: kbtype.KibanaSpec{
+		ElasticsearchRef: commonv1alpha1.ObjectSelector{
+			Name: "es",
+		},
+	},
+}
+
+var esFixture = estype.Elasticsearch{
+	ObjectMeta: metav1.ObjectMeta{
+		Name:      "es",
+		Namespace: "kb-ns",
+	},
+}
+
+func setupScheme(t *testing.T) *runtime.Scheme {
+	sc := scheme.Scheme
+	if err := apmtype.SchemeBuilder.AddToScheme(sc); err != nil {
+		assert.Fail(t, "failed to add apm types")
+	}
+	if err := kbtype.SchemeBuilder.AddToScheme(sc); err != nil {
+		assert.Fail(t, "failed to add Kibana types")
+	}
+	if err := estype.SchemeBuilder.AddToScheme(sc); err != nil {
+		assert.Fail(t, "failed to add Es types")
+	}
+	return sc
+}
+
+func kibanaPublicCertsFixture() *corev1.Secret {
+	nsn := http.PublicCertsSecretRef(kbname.KBNamer, k8s.ExtractNamespacedName(&kibanaFixture))
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      nsn.Name,
+			Namespace: nsn.Namespace,
+		},
+		Data: map[string][]byte{
+			certificates.CertFileName: []byte("kibana-ca"),
+		},
+	}
+}
+
+func TestReconcileApmServerKibanaAssociation_reconcileInternal(t *testing.T) {
+	s := setupScheme(t)
+	tests := []struct {
+		name           string
+		kibana         func() *kbtype.Kibana
+		initialObjects []runtime.Object
+		wantStatus     commonv1alpha1.AssociationStatus
+		assertions     func(c k8s.Client, apm apmtype.ApmServer)
+	}{
+		{
+			name:       "Kibana not found",
+			wantStatus: commonv1alpha1.AssociationPending,
+		},
+		{
+			name:       "Elasticsearch cluster of Kibana not found",
+			kibana:     kibanaFixture.DeepCopy,
+			wantStatus: commonv1alpha1.AssociationPending,
+		},
+		{
+			name: "Kibana without Elasticsearch reference",
+			kibana: func() *kbtype.Kibana {
+				kb := kibanaFixture.DeepCopy()
+				kb.Spec.ElasticsearchRef = commonv1alpha1.ObjectSelector{}
+				return kb
+			},
+			initialObjects: []runtime.Object{esFixture.DeepCopy()},
+			wantStatus:     commonv1alpha1.AssociationPending,
+		},
+		{
+			name:           "Kibana CA not created yet",
+			kibana:         kibanaFixture.DeepCopy,
+			initialObjects: []runtime.Object{esFixture.DeepCopy()},
+			wantStatus:     commonv1alpha1.AssociationPending,
+		},
+		{
+			name:           "association established with TLS",
+			kibana:         kibanaFixture.DeepCopy,
+			initialObjects: []runtime.Object{esFixture.DeepCopy(), kibanaPublicCertsFixture()},
+			wantStatus:     commonv1alpha1.AssociationEstablished,
+			assertions: func(c k8s.Client, apm apmtype.ApmServer) {
+				require.Equal(t, &commonv1alpha1.AssociationConf{
+					AuthSecretName: userSecretName,
+					AuthSecretKey:  esUserName,
+					CASecretName:   caSecretName,
+					URL:            "https://kb-kb-http.kb-ns.svc:5601",
+				}, apm.KibanaAssociationConf())
+				// the association configuration is persisted
+				var updated apmtype.ApmServer
+				require.NoError(t, c.Get(k8s.ExtractNamespacedName(&apm), &updated))
+				conf, err := association.GetKibanaAssociationConf(&updated)
+				require.NoError(t, err)
+				require.Equal(t, apm.KibanaAssociationConf(), conf)
+				// the user is created in the namespace of the Elasticsearch cluster of Kibana
+				var esUser corev1.Secret
+				require.NoError(t, c.Get(types.NamespacedName{Namespace: "kb-ns", Name: esUserName}, &esUser))
+				require.Equal(t, kibanaUserRole, string(esUser.Data[user.UserRoles]))
+				// the Kibana CA is copied in the APM server namespace
+				var ca corev1.Secret
+				require.NoError(t, c.Get(types.NamespacedName{Namespace: "default", Name: caSecretName}, &ca))
+				require.Equal(t, "kibana-ca", string(ca.Data[certificates.CertFileName]))
+			},
+		},
+		{
+			name: "association established without TLS",
+			kibana: func() *kbtype.Kibana {
+				kb := kibanaFixture.DeepCopy()
+				kb.Spec.HTTP.TLS.SelfSignedCertificate = &commonv1alpha1.SelfSignedCertificate{Disabled: true}
+				return kb
+			},
+			initialObjects: []runtime.Object{esFixture.DeepCopy()},
+			wantStatus:     commonv1alpha1.AssociationEstablished,
+			assertions: func(c k8s.Client, apm apmtype.ApmServer) {
+				require.Equal(t, "http://kb-kb-http.kb-ns.svc:5601", apm.KibanaAssociationConf().GetURL())
+				require.False(t, apm.KibanaAssociationConf().CAIsConfigured())
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			apm := apmFixture.DeepCopy()
+			objs := append([]runtime.Object{apm.DeepCopy()}, tt.initialObjects...)
+			if tt.kibana != nil {
+				objs = append(objs, tt.kibana())
+			}
+			c := k8s.WrapClient(fake.NewFakeClientWithScheme(s, objs...))
+			r := &ReconcileApmServerKibanaAssociation{
+				Client:   c,
+				scheme:   s,
+				watches:  watches.NewDynamicWatches(),
+				recorder: record.NewFakeRecorder(10),
+			}
+			status, err := r.reconcileInternal(apm)
+			require.NoError(t, err)
+			require.Equal(t, tt.wantStatus, status)
+			if tt.assertions != nil {
+				tt.assertions(c, *apm)
+			}
+		})
+	}
+}
+
+func Test_deleteOrphanedResources(t *testing.T) {
+	s := setupScheme(t)
+	userSecret := func() *corev1.Secret {
+		return &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      userSecretName,
+				Namespace: apmFixture.Namespace,
+				Labels: map[string]string{
+					AssociationLabelName: apmFixture.Name,
+				},
+				OwnerReferences: []metav1.OwnerReference{ownerRefFixture},
+			},
+		}
+	}
+	esUser := func() *corev1.Secret {
+		return &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      esUserName,
+				Namespace: "kb-ns",
+				Labels: map[string]string{
+					AssociationLabelName:      apmFixture.Name,
+					AssociationLabelNamespace: apmFixture.Namespace,
+					common.TypeLabelName:      user.UserType,
+				},
+			},
+		}
+	}
+	caSecret := func() *corev1.Secret {
+		return &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      caSecretName,
+				Namespace: apmFixture.Namespace,
+				Labels: map[string]string{
+					AssociationLabelName: apmFixture.Name,
+				},
+				OwnerReferences: []metav1.OwnerReference{ownerRefFixture},
+			},
+		}
+	}
+	tests := []struct {
+		name          string
+		apm           func() *apmtype.ApmServer
+		wantExist     []types.NamespacedName
+		wantNotExist  []types.NamespacedName
+		initialObject []runtime.Object
+	}{
+		{
+			name: "nothing to delete",
+			apm: func() *apmtype.ApmServer {
+				apm := apmFixture.DeepCopy()
+				apm.SetKibanaAssociationConf(&commonv1alpha1.AssociationConf{CASecretName: caSecretName})
+				return apm
+			},
+			initialObject: []runtime.Object{userSecret(), esUser(), caSecret()},
+			wantExist: []types.NamespacedName{
+				{Namespace: apmFixture.Namespace, Name: userSecretName},
+				{Namespace: "kb-ns", Name: esUserName},
+				{Namespace: apmFixture.Namespace, Name: caSecretName},
+			},
+		},
+		{
+			name:          "Kibana does not use TLS anymore",
+			apm:           apmFixture.DeepCopy,
+			initialObject: []runtime.Object{userSecret(), esUser(), caSecret()},
+			wantExist: []types.NamespacedName{
+				{Namespace: apmFixture.Namespace, Name: userSecretName},
+				{Namespace: "kb-ns", Name: esUserName},
+			},
+			wantNotExist: []types.NamespacedName{
+				{Namespace: apmFixture.Namespace, Name: caSecretName},
+			},
+		},
+		{
+			name: "Kibana reference removed",
+			apm: func() *apmtype.ApmServer {
+				apm := apmFixture.DeepCopy()
+				apm.Spec.KibanaRef = commonv1alpha1.ObjectSelector{}
+				return apm
+			},
+			initialObject: []runtime.Object{userSecret(), esUser(), caSecret()},
+			wantNotExist: []types.NamespacedName{
+				{Namespace: apmFixture.Namespace, Name: userSecretName},
+				{Namespace: "kb-ns", Name: esUserName},
+				{Namespace: apmFixture.Namespace, Name: caSecretName},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := k8s.WrapClient(fake.NewFakeClientWithScheme(s, tt.initialObject...))
+			require.NoError(t, deleteOrphanedResources(c, tt.apm()))
+			for _, nsn := range tt.wantExist {
+				require.NoError(t, c.Get(nsn, &corev1.Secret{}))
+			}
+			for _, nsn := range tt.wantNotExist {
+				require.Error(t, c.Get(nsn, &corev1.Secret{}))
+			}
+		})
+	}
+}
@@ -0,0 +1,37 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package apmserverkibanaassociation
+
+import (
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/user"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+const (
+	// AssociationLabelName marks resources created by this controller for easier retrieval.
+	AssociationLabelName = "apmkibanaassociation.k8s.elastic.co/name"
+	// AssociationLabelNamespace marks resources created by this controller for easier retrieval.
+	AssociationLabelNamespace = "apmkibanaassociation.k8s.elastic.co/namespace"
+)
+
+// NewResourceSelector selects resources labeled as related to the named association.
+func NewResourceSelector(name string) labels.Selector {
+	return labels.Set(map[string]string{
+		AssociationLabelName: name,
+	}).AsSelector()
+}
+
+func NewUserLabelSelector(
+	namespacedName types.NamespacedName,
+) labels.Selector {
+	return labels.SelectorFromSet(
+		map[string]string{
+			AssociationLabelName:      namespacedName.Name,
+			AssociationLabelNamespace: namespacedName.Namespace,
+			common.TypeLabelName:      user.UserType,
+		})
+}
@@ -16,6 +16,8 @@ const (
 	PrevAssocStatusAnnotation = "association.k8s.elastic.co/previous-status"
 	// AssociationConfAnnotation is the annotation used to define the config for associated Elasticsearch cluster.
 	AssociationConfAnnotation = "association.k8s.elastic.co/es-conf"
+	// KibanaAssociationConfAnnotation is the annotation used to define the config for associated Kibana.
+	KibanaAssociationConfAnnotation = "association.k8s.elastic.co/kb-conf"
 )
 
 // ForAssociationStatusChange constructs the annotation map for an association status change event.
@@ -17,15 +17,37 @@ func ElasticsearchAuthSettings(
 	c k8s.Client,
 	associated v1alpha1.Associated,
 ) (username, password string, err error) {
-	assocConf := associated.AssociationConf()
+	return AuthSettings(c, associated.GetNamespace(), associated.AssociationConf())
+}
+
+// AuthSettings returns the user and the password referenced by the given association configuration,
+// whose secret lives in the given namespace.
+func AuthSettings(
+	c k8s.Client,
+	namespace string,
+	assocConf *v1alpha1.AssociationConf,
+) (username, password string, err error) {
 	if !assocConf.AuthIsConfigured() {
 		return "", "", nil
 	}
 
-	secretObjKey := types.NamespacedName{Namespace: associated.GetNamespace(), Name: assocConf.AuthSecretName}
+	secretObjKey := types.NamespacedName{Namespace: namespace, Name: assocConf.AuthSecretName}
 	var secret v1.Secret
 	if err := c.Get(secretObjKey, &secret); err != nil {
 		return "", "", err
 	}
 	return assocConf.AuthSecretKey, string(secret.Data[assocConf.AuthSecretKey]), nil
 }
+
+// ReadyCondition returns the AssociationReady condition matching the given status of the association with
+// Elasticsearch.
+func ReadyCondition(status v1alpha1.AssociationStatus) v1alpha1.Condition {
+	switch status {
+	case v1alpha1.AssociationUnknown:
+		return v1alpha1.NewCondition(v1alpha1.AssociationReadyCondition, false, "AssociationNotConfigured", "")
+	default:
+		return v1alpha1.NewCondition(
+			v1alpha1.AssociationReadyCondition, status == v1alpha1.AssociationEstablished, "Association"+string(status), "",
+		)
+	}
+}
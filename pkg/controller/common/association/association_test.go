@@ -10,6 +10,7 @@ import (
 	"github.com/elastic/cloud-on-k8s/pkg/apis/apm/v1alpha1"
 	commonv1alpha1 "github.com/elastic/cloud-on-k8s/pkg/apis/common/v1alpha1"
 	"github.com/elastic/cloud-on-k8s/pkg/utils/k8s"
+	"github.com/stretchr/testify/assert"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"sigs.k8s.io/controller-runtime/pkg/client/fake"
@@ -104,3 +105,24 @@ func TestGetCredentials(t *testing.T) {
 		})
 	}
 }
+
+func TestReadyCondition(t *testing.T) {
+	tests := []struct {
+		status     commonv1alpha1.AssociationStatus
+		wantStatus corev1.ConditionStatus
+		wantReason string
+	}{
+		{status: commonv1alpha1.AssociationUnknown, wantStatus: corev1.ConditionFalse, wantReason: "AssociationNotConfigured"},
+		{status: commonv1alpha1.AssociationPending, wantStatus: corev1.ConditionFalse, wantReason: "AssociationPending"},
+		{status: commonv1alpha1.AssociationFailed, wantStatus: corev1.ConditionFalse, wantReason: "AssociationFailed"},
+		{status: commonv1alpha1.AssociationEstablished, wantStatus: corev1.ConditionTrue, wantReason: "AssociationEstablished"},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.status), func(t *testing.T) {
+			got := ReadyCondition(tt.status)
+			assert.Equal(t, commonv1alpha1.AssociationReadyCondition, got.Type)
+			assert.Equal(t, tt.wantStatus, got.Status)
+			assert.Equal(t, tt.wantReason, got.Reason)
+		})
+	}
+}
@@ -9,6 +9,7 @@ import (
 
 	"github.com/elastic/cloud-on-k8s/pkg/apis/common/v1alpha1"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/common/certificates/http"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/name"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/common/reconciler"
 	esname "github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/name"
 	"github.com/elastic/cloud-on-k8s/pkg/utils/k8s"
@@ -35,11 +36,26 @@ func ReconcileCASecret(
 	labels map[string]string,
 	suffix string,
 ) (string, error) {
-	publicESHTTPCertificatesNSN := http.PublicCertsSecretRef(esname.ESNamer, es)
+	return ReconcilePublicCertsSecret(client, scheme, associated, esname.ESNamer, es, labels, suffix)
+}
+
+// ReconcilePublicCertsSecret keeps in sync a copy of the public HTTP certificates of the source resource,
+// identified by the given namer and namespaced name, in the namespace of the associated resource.
+// It is the responsibility of the controller to set a watch on the public HTTP certificates.
+func ReconcilePublicCertsSecret(
+	client k8s.Client,
+	scheme *runtime.Scheme,
+	associated v1alpha1.Associated,
+	namer name.Namer,
+	source types.NamespacedName,
+	labels map[string]string,
+	suffix string,
+) (string, error) {
+	publicHTTPCertificatesNSN := http.PublicCertsSecretRef(namer, source)
 
-	// retrieve the HTTP certificates from ES namespace
-	var publicESHTTPCertificatesSecret corev1.Secret
-	if err := client.Get(publicESHTTPCertificatesNSN, &publicESHTTPCertificatesSecret); err != nil {
+	// retrieve the HTTP certificates from the source namespace
+	var publicHTTPCertificatesSecret corev1.Secret
+	if err := client.Get(publicHTTPCertificatesNSN, &publicHTTPCertificatesSecret); err != nil {
 		if errors.IsNotFound(err) {
 			return "", nil // probably not created yet, we'll be notified to reconcile later
 		}
@@ -53,7 +69,7 @@ func ReconcileCASecret(
 			Name:      ElasticsearchCACertSecretName(associated, suffix),
 			Labels:    labels,
 		},
-		Data: publicESHTTPCertificatesSecret.Data,
+		Data: publicHTTPCertificatesSecret.Data,
 	}
 	var reconciledSecret corev1.Secret
 	if err := reconciler.ReconcileResource(reconciler.Params{
@@ -39,22 +39,31 @@ func FetchWithAssociation(client k8s.Client, request reconcile.Request, obj comm
 
 // GetAssociationConf extracts the association configuration from the given object by reading the annotations.
 func GetAssociationConf(obj runtime.Object) (*commonv1alpha1.AssociationConf, error) {
+	return getAssociationConf(obj, annotation.AssociationConfAnnotation)
+}
+
+// GetKibanaAssociationConf extracts the Kibana association configuration from the given object by reading the annotations.
+func GetKibanaAssociationConf(obj runtime.Object) (*commonv1alpha1.AssociationConf, error) {
+	return getAssociationConf(obj, annotation.KibanaAssociationConfAnnotation)
+}
+
+func getAssociationConf(obj runtime.Object, annotationName string) (*commonv1alpha1.AssociationConf, error) {
 	accessor := meta.NewAccessor()
 	annotations, err := accessor.Annotations(obj)
 	if err != nil {
 		return nil, err
 	}
 
-	return extractAssociationConf(annotations)
+	return extractAssociationConf(annotations, annotationName)
 }
 
-func extractAssociationConf(annotations map[string]string) (*commonv1alpha1.AssociationConf, error) {
+func extractAssociationConf(annotations map[string]string, annotationName string) (*commonv1alpha1.AssociationConf, error) {
 	if len(annotations) == 0 {
 		return nil, nil
 	}
 
 	var assocConf commonv1alpha1.AssociationConf
-	serializedConf, exists := annotations[annotation.AssociationConfAnnotation]
+	serializedConf, exists := annotations[annotationName]
 	if !exists || serializedConf == "" {
 		return nil, nil
 	}
@@ -68,6 +77,15 @@ func extractAssociationConf(annotations map[string]string) (*commonv1alpha1.Asso
 
 // RemoveAssociationConf removes the association configuration annotation.
 func RemoveAssociationConf(client k8s.Client, obj runtime.Object) error {
+	return removeAssociationConf(client, obj, annotation.AssociationConfAnnotation)
+}
+
+// RemoveKibanaAssociationConf removes the Kibana association configuration annotation.
+func RemoveKibanaAssociationConf(client k8s.Client, obj runtime.Object) error {
+	return removeAssociationConf(client, obj, annotation.KibanaAssociationConfAnnotation)
+}
+
+func removeAssociationConf(client k8s.Client, obj runtime.Object, annotationName string) error {
 	accessor := meta.NewAccessor()
 	annotations, err := accessor.Annotations(obj)
 	if err != nil {
@@ -78,11 +96,11 @@ func RemoveAssociationConf(client k8s.Client, obj runtime.Object) error {
 		return nil
 	}
 
-	if _, exists := annotations[annotation.AssociationConfAnnotation]; !exists {
+	if _, exists := annotations[annotationName]; !exists {
 		return nil
 	}
 
-	delete(annotations, annotation.AssociationConfAnnotation)
+	delete(annotations, annotationName)
 	if err := accessor.SetAnnotations(obj, annotations); err != nil {
 		return err
 	}
@@ -92,6 +110,15 @@ func RemoveAssociationConf(client k8s.Client, obj runtime.Object) error {
 
 // UpdateAssociationConf updates the association configuration annotation.
 func UpdateAssociationConf(client k8s.Client, obj runtime.Object, wantConf *commonv1alpha1.AssociationConf) error {
+	return updateAssociationConf(client, obj, annotation.AssociationConfAnnotation, wantConf)
+}
+
+// UpdateKibanaAssociationConf updates the Kibana association configuration annotation.
+func UpdateKibanaAssociationConf(client k8s.Client, obj runtime.Object, wantConf *commonv1alpha1.AssociationConf) error {
+	return updateAssociationConf(client, obj, annotation.KibanaAssociationConfAnnotation, wantConf)
+}
+
+func updateAssociationConf(client k8s.Client, obj runtime.Object, annotationName string, wantConf *commonv1alpha1.AssociationConf) error {
 	accessor := meta.NewAccessor()
 	annotations, err := accessor.Annotations(obj)
 	if err != nil {
@@ -108,7 +135,7 @@ func UpdateAssociationConf(client k8s.Client, obj runtime.Object, wantConf *comm
 		annotations = make(map[string]string)
 	}
 
-	annotations[annotation.AssociationConfAnnotation] = unsafeBytesToString(serializedConf)
+	annotations[annotationName] = unsafeBytesToString(serializedConf)
 	if err := accessor.SetAnnotations(obj, annotations); err != nil {
 		return err
 	}
@@ -0,0 +1,211 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package association
+
+import (
+	"context"
+	"crypto/x509"
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/elastic/cloud-on-k8s/pkg/apis/common/v1alpha1"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/certificates"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/reconciler"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/version"
+	esclient "github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/client"
+	"github.com/elastic/cloud-on-k8s/pkg/utils/k8s"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+const (
+	// ExternalAuthSecretUsernameKey is the key of the username in the user-provided external credentials secret.
+	ExternalAuthSecretUsernameKey = corev1.BasicAuthUsernameKey
+	// ExternalAuthSecretPasswordKey is the key of the password in the user-provided external credentials secret.
+	ExternalAuthSecretPasswordKey = corev1.BasicAuthPasswordKey
+
+	// connectivityCheckTimeout is the maximum duration of a request checking that an external cluster is reachable.
+	connectivityCheckTimeout = 10 * time.Second
+)
+
+// ExternalSecretKeys returns the namespaced names of the user-provided secrets referenced by an external
+// Elasticsearch reference, so they can be watched by the association controller.
+func ExternalSecretKeys(associated v1alpha1.Associated) []types.NamespacedName {
+	ref := associated.ExternalElasticsearchRef()
+	if !ref.IsDefined() {
+		return nil
+	}
+	keys := []types.NamespacedName{{Namespace: associated.GetNamespace(), Name: ref.AuthSecret.SecretName}}
+	if ref.CASecret.SecretName != "" {
+		keys = append(keys, types.NamespacedName{Namespace: associated.GetNamespace(), Name: ref.CASecret.SecretName})
+	}
+	return keys
+}
+
+// ReconcileExternalUserSecret copies the credentials of an external Elasticsearch cluster, provided by the user
+// as a `username`/`password` secret, into a secret following the format expected by the association configuration
+// (the username as key, the password as value). It returns the selector of the resulting secret entry.
+func ReconcileExternalUserSecret(
+	c k8s.Client,
+	s *runtime.Scheme,
+	associated v1alpha1.Associated,
+	labels map[string]string,
+	userSuffix string,
+) (*corev1.SecretKeySelector, error) {
+	ref := associated.ExternalElasticsearchRef()
+	var userSecret corev1.Secret
+	if err := c.Get(types.NamespacedName{Namespace: associated.GetNamespace(), Name: ref.AuthSecret.SecretName}, &userSecret); err != nil {
+		return nil, err
+	}
+	username := string(userSecret.Data[ExternalAuthSecretUsernameKey])
+	password, hasPassword := userSecret.Data[ExternalAuthSecretPasswordKey]
+	if username == "" || !hasPassword {
+		return nil, fmt.Errorf(
+			"secret %s/%s must contain the %s and %s entries",
+			userSecret.Namespace, userSecret.Name, ExternalAuthSecretUsernameKey, ExternalAuthSecretPasswordKey,
+		)
+	}
+
+	secKey := secretKey(associated, userSuffix)
+	expectedSecret := corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      secKey.Name,
+			Namespace: secKey.Namespace,
+			Labels:    labels,
+		},
+		Data: map[string][]byte{
+			username: password,
+		},
+	}
+
+	reconciledSecret := corev1.Secret{}
+	if err := reconciler.ReconcileResource(reconciler.Params{
+		Client:     c,
+		Scheme:     s,
+		Owner:      associated,
+		Expected:   &expectedSecret,
+		Reconciled: &reconciledSecret,
+		NeedsUpdate: func() bool {
+			return !reflect.DeepEqual(expectedSecret.Data, reconciledSecret.Data) ||
+				!hasExpectedLabels(&expectedSecret, &reconciledSecret)
+		},
+		UpdateReconciled: func() {
+			setExpectedLabels(&expectedSecret, &reconciledSecret)
+			reconciledSecret.Data = expectedSecret.Data
+		},
+	}); err != nil {
+		return nil, err
+	}
+
+	return &corev1.SecretKeySelector{
+		LocalObjectReference: corev1.LocalObjectReference{Name: secKey.Name},
+		Key:                  username,
+	}, nil
+}
+
+// ReconcileExternalCASecret keeps in sync a copy of the certificate authority of an external Elasticsearch cluster,
+// provided by the user in the `ca.crt` entry of a secret. The copy has the same format as the one reconciled
+// for a managed Elasticsearch cluster. It returns an empty name if no CA secret is referenced.
+func ReconcileExternalCASecret(
+	c k8s.Client,
+	s *runtime.Scheme,
+	associated v1alpha1.Associated,
+	labels map[string]string,
+	suffix string,
+) (string, error) {
+	ref := associated.ExternalElasticsearchRef()
+	if ref.CASecret.SecretName == "" {
+		return "", nil
+	}
+
+	var userCASecret corev1.Secret
+	if err := c.Get(types.NamespacedName{Namespace: associated.GetNamespace(), Name: ref.CASecret.SecretName}, &userCASecret); err != nil {
+		return "", err
+	}
+	caPem, exists := userCASecret.Data[certificates.CAFileName]
+	if !exists {
+		return "", fmt.Errorf("secret %s/%s must contain the %s entry", userCASecret.Namespace, userCASecret.Name, certificates.CAFileName)
+	}
+	if certs, err := certificates.ParsePEMCerts(caPem); err != nil || len(certs) == 0 {
+		return "", fmt.Errorf("secret %s/%s does not contain a valid PEM certificate", userCASecret.Namespace, userCASecret.Name)
+	}
+
+	expectedSecret := corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: associated.GetNamespace(),
+			Name:      ElasticsearchCACertSecretName(associated, suffix),
+			Labels:    labels,
+		},
+		Data: map[string][]byte{
+			certificates.CAFileName:   caPem,
+			certificates.CertFileName: caPem,
+		},
+	}
+	var reconciledSecret corev1.Secret
+	if err := reconciler.ReconcileResource(reconciler.Params{
+		Client:     c,
+		Scheme:     s,
+		Owner:      associated,
+		Expected:   &expectedSecret,
+		Reconciled: &reconciledSecret,
+		NeedsUpdate: func() bool {
+			return !reflect.DeepEqual(expectedSecret.Data, reconciledSecret.Data)
+		},
+		UpdateReconciled: func() {
+			reconciledSecret.Data = expectedSecret.Data
+		},
+	}); err != nil {
+		return "", err
+	}
+
+	return expectedSecret.Name, nil
+}
+
+// ConnectivityChecker verifies that the Elasticsearch cluster described by the association configuration
+// of the given object can be reached with the configured credentials.
+type ConnectivityChecker func(c k8s.Client, associated v1alpha1.Associated, v version.Version) error
+
+// CheckConnectivity is the default ConnectivityChecker. It retrieves the cluster information using the
+// URL, credentials and CA of the association configuration.
+func CheckConnectivity(c k8s.Client, associated v1alpha1.Associated, v version.Version) error {
+	client, err := NewElasticsearchClient(c, associated, v)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), connectivityCheckTimeout)
+	defer cancel()
+	_, err = client.GetClusterInfo(ctx)
+	return err
+}
+
+// NewElasticsearchClient returns a client for the Elasticsearch cluster described by the association configuration
+// of the given object, authenticated with the credentials of the association.
+func NewElasticsearchClient(c k8s.Client, associated v1alpha1.Associated, v version.Version) (esclient.Client, error) {
+	assocConf := associated.AssociationConf()
+	username, password, err := ElasticsearchAuthSettings(c, associated)
+	if err != nil {
+		return nil, err
+	}
+
+	var caCerts []*x509.Certificate
+	if assocConf.CAIsConfigured() {
+		var caSecret corev1.Secret
+		if err := c.Get(types.NamespacedName{Namespace: associated.GetNamespace(), Name: assocConf.GetCASecretName()}, &caSecret); err != nil {
+			return nil, err
+		}
+		caCerts, err = certificates.ParsePEMCerts(caSecret.Data[certificates.CertFileName])
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// the operator dialer is not used here: it only knows how to reach resources inside the Kubernetes cluster
+	return esclient.NewElasticsearchClient(nil, assocConf.GetURL(), esclient.UserAuth{Name: username, Password: password}, v, caCerts), nil
+}
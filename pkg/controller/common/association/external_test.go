@@ -0,0 +1,172 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package association
+
+import (
+	"testing"
+
+	commonv1alpha1 "github.com/elastic/cloud-on-k8s/pkg/apis/common/v1alpha1"
+	kbtype "github.com/elastic/cloud-on-k8s/pkg/apis/kibana/v1alpha1"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/certificates"
+	"github.com/elastic/cloud-on-k8s/pkg/utils/k8s"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+var externalKibanaFixture = kbtype.Kibana{
+	ObjectMeta: kibanaFixtureObjectMeta,
+	Spec: kbtype.KibanaSpec{
+		ExternalElasticsearchRef: &commonv1alpha1.ExternalElasticsearchRef{
+			URL:        "https://elasticsearch.example.com:9200",
+			AuthSecret: commonv1alpha1.SecretRef{SecretName: "external-credentials"},
+			CASecret:   commonv1alpha1.SecretRef{SecretName: "external-ca"},
+		},
+	},
+}
+
+func externalSecret(name string, data map[string][]byte) *corev1.Secret {
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: kibanaFixture.Namespace, Name: name},
+		Data:       data,
+	}
+}
+
+func TestExternalSecretKeys(t *testing.T) {
+	require.Nil(t, ExternalSecretKeys(&kibanaFixture))
+	require.Equal(t, []types.NamespacedName{
+		{Namespace: "default", Name: "external-credentials"},
+		{Namespace: "default", Name: "external-ca"},
+	}, ExternalSecretKeys(&externalKibanaFixture))
+}
+
+func TestReconcileExternalUserSecret(t *testing.T) {
+	tests := []struct {
+		name         string
+		initialObjs  []runtime.Object
+		wantErr      bool
+		wantSelector *corev1.SecretKeySelector
+		wantData     map[string][]byte
+	}{
+		{
+			name:    "user secret does not exist",
+			wantErr: true,
+		},
+		{
+			name: "user secret is missing the password",
+			initialObjs: []runtime.Object{
+				externalSecret("external-credentials", map[string][]byte{"username": []byte("kibana")}),
+			},
+			wantErr: true,
+		},
+		{
+			name: "user secret is copied with the username as key",
+			initialObjs: []runtime.Object{
+				externalSecret("external-credentials", map[string][]byte{"username": []byte("kibana"), "password": []byte("secret")}),
+			},
+			wantSelector: &corev1.SecretKeySelector{
+				LocalObjectReference: corev1.LocalObjectReference{Name: "kibana-foo-kibana-user"},
+				Key:                  "kibana",
+			},
+			wantData: map[string][]byte{"kibana": []byte("secret")},
+		},
+		{
+			name: "existing copy is updated",
+			initialObjs: []runtime.Object{
+				externalSecret("external-credentials", map[string][]byte{"username": []byte("kibana"), "password": []byte("new")}),
+				externalSecret("kibana-foo-kibana-user", map[string][]byte{"kibana": []byte("old")}),
+			},
+			wantSelector: &corev1.SecretKeySelector{
+				LocalObjectReference: corev1.LocalObjectReference{Name: "kibana-foo-kibana-user"},
+				Key:                  "kibana",
+			},
+			wantData: map[string][]byte{"kibana": []byte("new")},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := k8s.WrapClient(fake.NewFakeClientWithScheme(setupScheme(t), tt.initialObjs...))
+			got, err := ReconcileExternalUserSecret(c, setupScheme(t), &externalKibanaFixture, map[string]string{}, "kibana-user")
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.wantSelector, got)
+
+			var copied corev1.Secret
+			require.NoError(t, c.Get(types.NamespacedName{Namespace: "default", Name: got.Name}, &copied))
+			require.Equal(t, tt.wantData, copied.Data)
+		})
+	}
+}
+
+func TestReconcileExternalCASecret(t *testing.T) {
+	ca, err := certificates.NewSelfSignedCA(certificates.CABuilderOptions{})
+	require.NoError(t, err)
+	caPem := certificates.EncodePEMCert(ca.Cert.Raw)
+
+	tests := []struct {
+		name        string
+		kibana      kbtype.Kibana
+		initialObjs []runtime.Object
+		want        string
+		wantErr     bool
+	}{
+		{
+			name: "no CA secret referenced",
+			kibana: func() kbtype.Kibana {
+				kb := *externalKibanaFixture.DeepCopy()
+				kb.Spec.ExternalElasticsearchRef.CASecret = commonv1alpha1.SecretRef{}
+				return kb
+			}(),
+			want: "",
+		},
+		{
+			name:    "CA secret does not exist",
+			kibana:  externalKibanaFixture,
+			wantErr: true,
+		},
+		{
+			name:   "CA secret does not contain a certificate",
+			kibana: externalKibanaFixture,
+			initialObjs: []runtime.Object{
+				externalSecret("external-ca", map[string][]byte{certificates.CAFileName: []byte("not a certificate")}),
+			},
+			wantErr: true,
+		},
+		{
+			name:   "CA secret is copied",
+			kibana: externalKibanaFixture,
+			initialObjs: []runtime.Object{
+				externalSecret("external-ca", map[string][]byte{certificates.CAFileName: caPem}),
+			},
+			want: ElasticsearchCACertSecretName(&externalKibanaFixture, ElasticsearchCASecretSuffix),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := k8s.WrapClient(fake.NewFakeClientWithScheme(setupScheme(t), tt.initialObjs...))
+			got, err := ReconcileExternalCASecret(c, setupScheme(t), &tt.kibana, map[string]string{}, ElasticsearchCASecretSuffix)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.want, got)
+			if tt.want == "" {
+				return
+			}
+
+			var copied corev1.Secret
+			require.NoError(t, c.Get(types.NamespacedName{Namespace: "default", Name: got}, &copied))
+			require.Equal(t, caPem, copied.Data[certificates.CertFileName])
+			require.Equal(t, caPem, copied.Data[certificates.CAFileName])
+		})
+	}
+}
@@ -6,17 +6,38 @@ package association
 
 import (
 	"bytes"
+	"reflect"
+	"time"
 
 	commonv1alpha1 "github.com/elastic/cloud-on-k8s/pkg/apis/common/v1alpha1"
 	"github.com/elastic/cloud-on-k8s/pkg/apis/elasticsearch/v1alpha1"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/deployment"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/common/reconciler"
 	commonuser "github.com/elastic/cloud-on-k8s/pkg/controller/common/user"
 	"github.com/elastic/cloud-on-k8s/pkg/utils/k8s"
+	"github.com/elastic/cloud-on-k8s/pkg/utils/maps"
 	"golang.org/x/crypto/bcrypt"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
+	logf "sigs.k8s.io/controller-runtime/pkg/runtime/log"
+)
+
+var log = logf.Log.WithName("association")
+
+const (
+	// UserNameAnnotationName is the annotation of the secret holding the credentials of an associated object,
+	// recording the name of the Elasticsearch user in use.
+	UserNameAnnotationName = "association.k8s.elastic.co/es-user"
+	// UserSwitchedAnnotationName is the annotation of the secret holding the credentials of an associated object,
+	// recording when it was switched to a new user by a password rotation. It is removed along with the previous user.
+	UserSwitchedAnnotationName = "association.k8s.elastic.co/es-user-switched-at"
+
+	// rotatedUserSuffix is the suffix of the name of the user the associated object switches to on every other
+	// password rotation.
+	rotatedUserSuffix = "-rotated"
 )
 
 // elasticsearchUserName identifies the associated user in Elasticsearch namespace.
@@ -66,7 +87,39 @@ func ClearTextSecretKeySelector(associated commonv1alpha1.Associated, userSuffix
 	}
 }
 
-// ReconcileEsUser creates a User resource and a corresponding secret or updates those as appropriate.
+// UserKeys are the namespaced names of the user resources the controller may create for the associated object: the
+// associated object switches between them on each password rotation.
+func UserKeys(associated commonv1alpha1.Associated, userSuffix string) []types.NamespacedName {
+	key := UserKey(associated, userSuffix)
+	return []types.NamespacedName{key, {Namespace: key.Namespace, Name: alternateUserName(key.Name, key.Name)}}
+}
+
+// alternateUserName returns the name of the user to switch to on the next password rotation, given the name of the
+// user currently in use and the name of the first user of the associated object.
+func alternateUserName(userName string, current string) string {
+	if current == userName {
+		return userName + rotatedUserSuffix
+	}
+	return userName
+}
+
+// currentUserName returns the name of the user currently in use, as recorded in the given secret.
+func currentUserName(userName string, secret corev1.Secret) string {
+	if current, exists := secret.Annotations[UserNameAnnotationName]; exists {
+		return current
+	}
+	return userName
+}
+
+// ReconcileEsUser creates a User resource and a corresponding secret or updates those as appropriate. It returns the
+// key of the secret holding the password of the user the associated object must use, whose name is also the user name.
+//
+// The password is rotated when requested with the PasswordRotationAnnotationName annotation of the Elasticsearch resource,
+// unless it is provided by the user in the secret referenced by the Elasticsearch resource. A rotation creates a second
+// user with a new password, next to the current one: the associated object is switched to the new user once the
+// Elasticsearch nodes accept it, and the previous user is deleted once the given Deployment of the associated object
+// is rolled out with the new credentials. Both users are valid in the meantime.
+// It is the responsibility of the controller to set a watch on the secret containing the user-provided passwords.
 func ReconcileEsUser(
 	c k8s.Client,
 	s *runtime.Scheme,
@@ -75,44 +128,166 @@ func ReconcileEsUser(
 	userRoles string,
 	userObjectSuffix string,
 	es v1alpha1.Elasticsearch,
-) error {
-	pw := commonuser.RandomPasswordBytes()
-
+	deploymentKey types.NamespacedName,
+) (*corev1.SecretKeySelector, error) {
 	secKey := secretKey(associated, userObjectSuffix)
-	usrKey := UserKey(associated, userObjectSuffix)
+	// the user lives in the namespace of the Elasticsearch cluster, which may not be the one referenced by
+	// ElasticsearchRef if the user is meant for another associated resource, for example Kibana
+	usrKey := types.NamespacedName{Namespace: es.Namespace, Name: elasticsearchUserName(associated, userObjectSuffix)}
+
+	providedPasswords, err := commonuser.ProvidedPasswords(c, es)
+	if err != nil {
+		return nil, err
+	}
+	pw, provided := providedPasswords[usrKey.Name]
+	if !provided {
+		pw = commonuser.RandomPasswordBytes()
+	}
 	expectedSecret := corev1.Secret{
 		ObjectMeta: metav1.ObjectMeta{
-			Name:      secKey.Name,
-			Namespace: secKey.Namespace,
-			Labels:    labels,
+			Name:        secKey.Name,
+			Namespace:   secKey.Namespace,
+			Labels:      labels,
+			Annotations: map[string]string{UserNameAnnotationName: usrKey.Name},
 		},
 		Data: map[string][]byte{
 			usrKey.Name: pw,
 		},
 	}
+	commonuser.SetPasswordRotation(es, &expectedSecret)
 
 	reconciledSecret := corev1.Secret{}
-	err := reconciler.ReconcileResource(reconciler.Params{
+	err = reconciler.ReconcileResource(reconciler.Params{
 		Client:     c,
 		Scheme:     s,
 		Owner:      associated,
 		Expected:   &expectedSecret,
 		Reconciled: &reconciledSecret,
 		NeedsUpdate: func() bool {
-			_, ok := reconciledSecret.Data[usrKey.Name]
-			return !ok || !hasExpectedLabels(&expectedSecret, &reconciledSecret)
+			return !hasExpectedLabels(&expectedSecret, &reconciledSecret) || !hasCurrentUser(usrKey.Name, reconciledSecret) ||
+				provided && !reflect.DeepEqual(reconciledSecret.Data, expectedSecret.Data)
 		},
 		UpdateReconciled: func() {
 			setExpectedLabels(&expectedSecret, &reconciledSecret)
+			if hasCurrentUser(usrKey.Name, reconciledSecret) && !provided {
+				// keep the generated passwords in use
+				return
+			}
+			reconciledSecret.Annotations = maps.Merge(reconciledSecret.Annotations, expectedSecret.Annotations)
+			delete(reconciledSecret.Annotations, UserSwitchedAnnotationName)
 			reconciledSecret.Data = expectedSecret.Data
 		},
 	})
 	if err != nil {
-		return err
+		return nil, err
+	}
+
+	rotation := es.Annotations[commonuser.PasswordRotationAnnotationName]
+	if !provided {
+		if err := rotateEsUser(c, es, usrKey.Name, deploymentKey, &reconciledSecret); err != nil {
+			return nil, err
+		}
+		if commonuser.PasswordRotationRequested(es, reconciledSecret) && !newUserReady(usrKey.Name, reconciledSecret) {
+			// the Elasticsearch cluster must not rotate its own users before the new user is part of the file realm
+			rotation = reconciledSecret.Annotations[commonuser.PasswordRotationAnnotationName]
+		}
+	}
+
+	for _, name := range []string{usrKey.Name, alternateUserName(usrKey.Name, usrKey.Name)} {
+		password, exists := reconciledSecret.Data[name]
+		if !exists {
+			if err := deleteEsUser(c, types.NamespacedName{Namespace: es.Namespace, Name: name}); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if err := reconcileEsUserSecret(c, s, es, name, password, labels, userRoles, rotation); err != nil {
+			return nil, err
+		}
+	}
+
+	return &corev1.SecretKeySelector{
+		LocalObjectReference: corev1.LocalObjectReference{Name: secKey.Name},
+		Key:                  currentUserName(usrKey.Name, reconciledSecret),
+	}, nil
+}
+
+// hasCurrentUser returns true if the given secret holds the password of the user currently in use.
+func hasCurrentUser(userName string, secret corev1.Secret) bool {
+	current := currentUserName(userName, secret)
+	if current != userName && current != alternateUserName(userName, userName) {
+		return false
+	}
+	_, exists := secret.Data[current]
+	return exists
+}
+
+// newUserReady returns true if the given secret holds the user to switch to on the next password rotation.
+func newUserReady(userName string, secret corev1.Secret) bool {
+	if _, switching := secret.Annotations[UserSwitchedAnnotationName]; switching {
+		return false
 	}
+	_, exists := secret.Data[alternateUserName(userName, currentUserName(userName, secret))]
+	return exists
+}
 
-	reconciledPw := reconciledSecret.Data[usrKey.Name] // make sure we don't constantly update the password
-	bcryptHash, err := bcrypt.GenerateFromPassword(reconciledPw, bcrypt.DefaultCost)
+// rotateEsUser moves the rotation of the user of the associated object one step forward:
+//   - a new user is created next to the current one when a rotation is requested,
+//   - the associated object is switched to the new user once the rotation is propagated to the Elasticsearch nodes,
+//   - the previous user is deleted once the given Deployment is rolled out with the new credentials.
+func rotateEsUser(
+	c k8s.Client,
+	es v1alpha1.Elasticsearch,
+	userName string,
+	deploymentKey types.NamespacedName,
+	secret *corev1.Secret,
+) error {
+	current := currentUserName(userName, *secret)
+	other := alternateUserName(userName, current)
+	_, otherExists := secret.Data[other]
+	switchedAt, switching := secret.Annotations[UserSwitchedAnnotationName]
+	switch {
+	case switching:
+		// a parsing error leads to a zero time, for the previous user to be deleted once the Deployment is rolled out
+		since, _ := time.Parse(time.RFC3339, switchedAt)
+		rolledOut, err := deployment.RolledOutSince(c, deploymentKey, since)
+		if err != nil || !rolledOut {
+			return err
+		}
+		log.Info("Deleting previous user", "namespace", es.Namespace, "user", other)
+		delete(secret.Data, other)
+		delete(secret.Annotations, UserSwitchedAnnotationName)
+	case !commonuser.PasswordRotationRequested(es, *secret):
+		return nil
+	case !otherExists:
+		secret.Data[other] = commonuser.RandomPasswordBytes()
+	case commonuser.PasswordRotationPropagated(es):
+		log.Info("Switching to rotated user", "namespace", es.Namespace, "user", other)
+		secret.Annotations[UserNameAnnotationName] = other
+		secret.Annotations[UserSwitchedAnnotationName] = time.Now().UTC().Format(time.RFC3339)
+		commonuser.SetPasswordRotation(es, secret)
+	default:
+		// wait for the Elasticsearch nodes to accept the new user
+		return nil
+	}
+	return c.Update(secret)
+}
+
+// reconcileEsUserSecret reconciles the secret representing the given user on the Elasticsearch side of the association.
+// rotation is the password rotation to record in the secret, that the Elasticsearch cluster waits for before rotating
+// its own users.
+func reconcileEsUserSecret(
+	c k8s.Client,
+	s *runtime.Scheme,
+	es v1alpha1.Elasticsearch,
+	userName string,
+	password []byte,
+	labels map[string]string,
+	userRoles string,
+	rotation string,
+) error {
+	// make sure we don't constantly update the password
+	bcryptHash, err := bcrypt.GenerateFromPassword(password, bcrypt.DefaultCost)
 	if err != nil {
 		return err
 	}
@@ -129,16 +304,19 @@ func ReconcileEsUser(
 
 	expectedEsUser := &corev1.Secret{
 		ObjectMeta: metav1.ObjectMeta{
-			Name:      usrKey.Name,
-			Namespace: usrKey.Namespace,
+			Name:      userName,
+			Namespace: es.Namespace,
 			Labels:    userLabels,
 		},
 		Data: map[string][]byte{
-			commonuser.UserName:     []byte(usrKey.Name),
+			commonuser.UserName:     []byte(userName),
 			commonuser.PasswordHash: bcryptHash,
 			commonuser.UserRoles:    []byte(userRoles),
 		},
 	}
+	if rotation != "" {
+		expectedEsUser.Annotations = map[string]string{commonuser.PasswordRotationAnnotationName: rotation}
+	}
 
 	reconciledEsSecret := corev1.Secret{}
 	return reconciler.ReconcileResource(reconciler.Params{
@@ -149,17 +327,40 @@ func ReconcileEsUser(
 		Reconciled: &reconciledEsSecret,
 		NeedsUpdate: func() bool {
 			return !hasExpectedLabels(expectedEsUser, &reconciledEsSecret) ||
+				reconciledEsSecret.Annotations[commonuser.PasswordRotationAnnotationName] != rotation ||
 				!bytes.Equal(expectedEsUser.Data[commonuser.UserName], reconciledEsSecret.Data[commonuser.UserName]) ||
 				!bytes.Equal(expectedEsUser.Data[commonuser.UserRoles], reconciledEsSecret.Data[commonuser.UserRoles]) ||
-				bcrypt.CompareHashAndPassword(reconciledEsSecret.Data[commonuser.PasswordHash], reconciledPw) != nil
+				bcrypt.CompareHashAndPassword(reconciledEsSecret.Data[commonuser.PasswordHash], password) != nil
 		},
 		UpdateReconciled: func() {
 			setExpectedLabels(expectedEsUser, &reconciledEsSecret)
+			if rotation == "" {
+				delete(reconciledEsSecret.Annotations, commonuser.PasswordRotationAnnotationName)
+			} else {
+				reconciledEsSecret.Annotations = maps.Merge(reconciledEsSecret.Annotations, expectedEsUser.Annotations)
+			}
 			reconciledEsSecret.Data = expectedEsUser.Data
 		},
 	})
 }
 
+// deleteEsUser deletes the secret representing the given user on the Elasticsearch side of the association, if it exists.
+func deleteEsUser(c k8s.Client, key types.NamespacedName) error {
+	var secret corev1.Secret
+	err := c.Get(key, &secret)
+	if errors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	log.Info("Deleting user", "namespace", key.Namespace, "user", key.Name)
+	if err := c.Delete(&secret); err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+	return nil
+}
+
 // hasExpectedLabels does a left-biased comparison ensuring all key/value pairs in expected exist in actual.
 func hasExpectedLabels(expected, actual metav1.Object) bool {
 	actualLabels := actual.GetLabels()
@@ -5,6 +5,7 @@
 package association
 
 import (
+	"strings"
 	"testing"
 
 	commonv1alpha1 "github.com/elastic/cloud-on-k8s/pkg/apis/common/v1alpha1"
@@ -12,6 +13,8 @@ import (
 	kbtype "github.com/elastic/cloud-on-k8s/pkg/apis/kibana/v1alpha1"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/common"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/common/user"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/watches"
+	esclient "github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/client"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/label"
 	elasticsearchuser "github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/user"
 	esuser "github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/user"
@@ -19,7 +22,10 @@ import (
 	"github.com/elastic/cloud-on-k8s/pkg/utils/k8s"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
@@ -30,6 +36,7 @@ import (
 
 const (
 	userName                  = "default-kibana-foo-kibana-user"
+	rotatedUserName           = "default-kibana-foo-kibana-user-rotated"
 	userSecretName            = "kibana-foo-kibana-user" // nolint
 	associationLabelName      = "association.k8s.elastic.co/name"
 	associationLabelNamespace = "association.k8s.elastic.co/namespace"
@@ -43,6 +50,8 @@ var esFixture = estype.Elasticsearch{
 	},
 }
 
+var deploymentKey = types.NamespacedName{Namespace: "default", Name: "kibana-foo-kb"}
+
 var kibanaFixtureUID types.UID = "82257b19-8862-11e9-896d-08002703f062"
 
 var kibanaFixtureObjectMeta = metav1.ObjectMeta{
@@ -230,6 +239,115 @@ func Test_reconcileEsUser(t *testing.T) {
 				require.Equal(t, "$2a$10$mE3yo/AkZgR4eVW9kbA1TeIQ40Jv6WaWU494rx4C6EhLvuY0BSg4e", string(userSecret.Data[user.PasswordHash]))
 			},
 		},
+		{
+			name: "Reconcile rotates the password on request",
+			args: args{
+				initialObjects: []runtime.Object{
+					&corev1.Secret{
+						ObjectMeta: metav1.ObjectMeta{
+							Namespace: "default",
+							Name:      userSecretName,
+							Labels: map[string]string{
+								kblabel.KibanaNameLabelName: kibanaFixture.Name,
+								common.TypeLabelName:        kblabel.Type,
+								associationLabelName:        kibanaFixture.Name,
+								associationLabelNamespace:   kibanaFixture.Namespace,
+							},
+						},
+						Data: map[string][]byte{
+							userName: []byte("my-secret-pw"),
+						},
+					},
+				},
+				kibana: kibanaFixture,
+				es: estype.Elasticsearch{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:        esFixture.Name,
+						Namespace:   esFixture.Namespace,
+						UID:         esFixture.UID,
+						Annotations: map[string]string{user.PasswordRotationAnnotationName: "1"},
+					},
+				},
+			},
+			wantErr: false,
+			postCondition: func(c k8s.Client) {
+				var secret corev1.Secret
+				assert.NoError(t, c.Get(types.NamespacedName{Name: userSecretName, Namespace: "default"}, &secret))
+				// the current user is kept until the new one is propagated to the Elasticsearch nodes
+				require.Equal(t, userName, secret.Annotations[UserNameAnnotationName])
+				require.Equal(t, "my-secret-pw", string(secret.Data[userName]))
+				require.NotEmpty(t, secret.Data[rotatedUserName])
+				// both users exist in the Elasticsearch namespace
+				var esUser, rotatedEsUser corev1.Secret
+				assert.NoError(t, c.Get(types.NamespacedName{Name: userName, Namespace: "default"}, &esUser))
+				require.Equal(t, "1", esUser.Annotations[user.PasswordRotationAnnotationName])
+				require.NoError(t, bcrypt.CompareHashAndPassword(esUser.Data[user.PasswordHash], []byte("my-secret-pw")))
+				assert.NoError(t, c.Get(types.NamespacedName{Name: rotatedUserName, Namespace: "default"}, &rotatedEsUser))
+				require.Equal(t, "1", rotatedEsUser.Annotations[user.PasswordRotationAnnotationName])
+				require.NoError(t, bcrypt.CompareHashAndPassword(rotatedEsUser.Data[user.PasswordHash], secret.Data[rotatedUserName]))
+			},
+		},
+		{
+			name: "Reconcile uses the user-provided password",
+			args: args{
+				initialObjects: []runtime.Object{
+					&corev1.Secret{
+						ObjectMeta: metav1.ObjectMeta{
+							Namespace: "default",
+							Name:      userSecretName,
+							Labels: map[string]string{
+								kblabel.KibanaNameLabelName: kibanaFixture.Name,
+								common.TypeLabelName:        kblabel.Type,
+								associationLabelName:        kibanaFixture.Name,
+								associationLabelNamespace:   kibanaFixture.Namespace,
+							},
+						},
+						Data: map[string][]byte{
+							userName: []byte("my-secret-pw"),
+						},
+					},
+					&corev1.Secret{
+						ObjectMeta: metav1.ObjectMeta{
+							Namespace: esFixture.Namespace,
+							Name:      "es-passwords",
+						},
+						Data: map[string][]byte{
+							userName: []byte("provided-pw"),
+						},
+					},
+				},
+				kibana: kibanaFixture,
+				es: estype.Elasticsearch{
+					ObjectMeta: esFixture.ObjectMeta,
+					Spec: estype.ElasticsearchSpec{
+						Auth: estype.Auth{PasswordsSecret: commonv1alpha1.SecretRef{SecretName: "es-passwords"}},
+					},
+				},
+			},
+			wantErr: false,
+			postCondition: func(c k8s.Client) {
+				var secret corev1.Secret
+				assert.NoError(t, c.Get(types.NamespacedName{Name: userSecretName, Namespace: "default"}, &secret))
+				require.Equal(t, "provided-pw", string(secret.Data[userName]))
+				var esUser corev1.Secret
+				assert.NoError(t, c.Get(types.NamespacedName{Name: userName, Namespace: "default"}, &esUser))
+				require.NoError(t, bcrypt.CompareHashAndPassword(esUser.Data[user.PasswordHash], []byte("provided-pw")))
+			},
+		},
+		{
+			name: "Reconcile fails if the passwords secret does not exist",
+			args: args{
+				kibana: kibanaFixture,
+				es: estype.Elasticsearch{
+					ObjectMeta: esFixture.ObjectMeta,
+					Spec: estype.ElasticsearchSpec{
+						Auth: estype.Auth{PasswordsSecret: commonv1alpha1.SecretRef{SecretName: "es-passwords"}},
+					},
+				},
+			},
+			wantErr:       true,
+			postCondition: func(c k8s.Client) {},
+		},
 		{
 			name: "Reconcile is namespace aware",
 			args: args{
@@ -266,7 +384,7 @@ func Test_reconcileEsUser(t *testing.T) {
 	for _, tt := range tests {
 		c := k8s.WrapClient(fake.NewFakeClient(tt.args.initialObjects...))
 		t.Run(tt.name, func(t *testing.T) {
-			if err := ReconcileEsUser(
+			if _, err := ReconcileEsUser(
 				c,
 				sc,
 				&tt.args.kibana,
@@ -277,6 +395,7 @@ func Test_reconcileEsUser(t *testing.T) {
 				elasticsearchuser.KibanaSystemUserBuiltinRole,
 				"kibana-user",
 				tt.args.es,
+				deploymentKey,
 			); (err != nil) != tt.wantErr {
 				t.Errorf("reconcileEsUser() error = %v, wantErr %v", err, tt.wantErr)
 			}
@@ -284,3 +403,245 @@ func Test_reconcileEsUser(t *testing.T) {
 		})
 	}
 }
+
+func Test_reconcileEsUser_PasswordRotation(t *testing.T) {
+	sc := setupScheme(t)
+	c := k8s.WrapClient(fake.NewFakeClient())
+	reconcileEsUser := func(es estype.Elasticsearch) (string, corev1.Secret) {
+		selector, err := ReconcileEsUser(
+			c,
+			sc,
+			&kibanaFixture,
+			map[string]string{
+				associationLabelName:      kibanaFixture.Name,
+				associationLabelNamespace: kibanaFixture.Namespace,
+			},
+			elasticsearchuser.KibanaSystemUserBuiltinRole,
+			"kibana-user",
+			es,
+			deploymentKey,
+		)
+		require.NoError(t, err)
+		require.Equal(t, userSecretName, selector.Name)
+		var secret corev1.Secret
+		require.NoError(t, c.Get(types.NamespacedName{Name: userSecretName, Namespace: "default"}, &secret))
+		return selector.Key, secret
+	}
+	esUser := func(name string) corev1.Secret {
+		var secret corev1.Secret
+		require.NoError(t, c.Get(types.NamespacedName{Name: name, Namespace: "default"}, &secret))
+		return secret
+	}
+
+	es := *esFixture.DeepCopy()
+	current, initial := reconcileEsUser(es)
+	require.Equal(t, userName, current)
+	password := initial.Data[userName]
+
+	// rotation requested: a new user is created next to the current one
+	es.Annotations = map[string]string{user.PasswordRotationAnnotationName: "1"}
+	current, staged := reconcileEsUser(es)
+	require.Equal(t, userName, current)
+	require.Equal(t, password, staged.Data[userName])
+	newPassword := staged.Data[rotatedUserName]
+	require.NotEmpty(t, newPassword)
+	require.NotEqual(t, password, newPassword)
+	for name, pw := range map[string][]byte{userName: password, rotatedUserName: newPassword} {
+		u := esUser(name)
+		require.NoError(t, bcrypt.CompareHashAndPassword(u.Data[user.PasswordHash], pw))
+		// the Elasticsearch cluster can rotate its own users
+		require.Equal(t, "1", u.Annotations[user.PasswordRotationAnnotationName])
+	}
+
+	// the current user is kept while the rotation is not propagated to the Elasticsearch nodes
+	current, waiting := reconcileEsUser(es)
+	require.Equal(t, userName, current)
+	require.Equal(t, staged.Data, waiting.Data)
+
+	// once propagated, the associated object switches to the new user
+	es.Annotations[user.PasswordsRotatedAnnotationName] = "1"
+	current, switched := reconcileEsUser(es)
+	require.Equal(t, rotatedUserName, current)
+	require.Equal(t, rotatedUserName, switched.Annotations[UserNameAnnotationName])
+	require.Contains(t, switched.Annotations, UserSwitchedAnnotationName)
+	require.Equal(t, "1", switched.Annotations[user.PasswordRotationAnnotationName])
+
+	// the previous user is kept until the Deployment is rolled out with the new credentials
+	replicas := int32(1)
+	deployment := appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Namespace: deploymentKey.Namespace, Name: deploymentKey.Name},
+		Spec: appsv1.DeploymentSpec{
+			Replicas: &replicas,
+			Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "kb"}},
+		},
+		Status: appsv1.DeploymentStatus{Replicas: 2, UpdatedReplicas: 1, AvailableReplicas: 2},
+	}
+	require.NoError(t, c.Create(&deployment))
+	current, rolling := reconcileEsUser(es)
+	require.Equal(t, rotatedUserName, current)
+	require.Equal(t, switched.Data, rolling.Data)
+	require.NoError(t, bcrypt.CompareHashAndPassword(esUser(userName).Data[user.PasswordHash], password))
+
+	deployment.Status = appsv1.DeploymentStatus{Replicas: 1, UpdatedReplicas: 1, AvailableReplicas: 1}
+	require.NoError(t, c.Update(&deployment))
+	current, rotated := reconcileEsUser(es)
+	require.Equal(t, rotatedUserName, current)
+	require.Equal(t, map[string][]byte{rotatedUserName: newPassword}, rotated.Data)
+	require.NotContains(t, rotated.Annotations, UserSwitchedAnnotationName)
+	require.True(t, apierrors.IsNotFound(c.Get(types.NamespacedName{Name: userName, Namespace: "default"}, &corev1.Secret{})))
+
+	// the password is not rotated again for the same request
+	current, again := reconcileEsUser(es)
+	require.Equal(t, rotatedUserName, current)
+	require.Equal(t, rotated.Data, again.Data)
+
+	// the next rotation switches back to the first user name
+	es.Annotations = map[string]string{user.PasswordRotationAnnotationName: "2"}
+	current, staged = reconcileEsUser(es)
+	require.Equal(t, rotatedUserName, current)
+	require.NotEmpty(t, staged.Data[userName])
+	require.Equal(t, newPassword, staged.Data[rotatedUserName])
+}
+
+// node is an Elasticsearch node, with the files of the file realm and probe user secret volumes, and the users of the
+// file realm it loaded from them.
+type node struct {
+	realmVolume map[string][]byte
+	probeVolume map[string][]byte
+	loadedUsers map[string][]byte
+}
+
+// mount updates the volumes of the node with the current content of the secrets.
+func (n *node) mount(t *testing.T, c k8s.Client, es estype.Elasticsearch) {
+	var realm, internalUsers corev1.Secret
+	require.NoError(t, c.Get(types.NamespacedName{Namespace: es.Namespace, Name: esuser.XPackFileRealmSecretName(es.Name)}, &realm))
+	require.NoError(t, c.Get(types.NamespacedName{Namespace: es.Namespace, Name: esuser.ElasticInternalUsersSecretName(es.Name)}, &internalUsers))
+	n.realmVolume = realm.Data
+	n.probeVolume = internalUsers.Data
+}
+
+// load reloads the users of the file realm from the mounted volume.
+func (n *node) load() {
+	n.loadedUsers = make(map[string][]byte)
+	for _, line := range strings.Split(string(n.realmVolume[esuser.ElasticUsersFile]), "\n") {
+		userHash := strings.SplitN(line, ":", 2)
+		n.loadedUsers[userHash[0]] = []byte(userHash[1])
+	}
+}
+
+// authenticates returns true if the loaded file realm accepts the given credentials.
+func (n *node) authenticates(name string, password []byte) bool {
+	hash, exists := n.loadedUsers[name]
+	return exists && bcrypt.CompareHashAndPassword(hash, password) == nil
+}
+
+// probeSucceeds returns true if one of the passwords the readiness probe tries is accepted.
+func (n *node) probeSucceeds() bool {
+	for _, volume := range []map[string][]byte{n.realmVolume, n.probeVolume} {
+		if password, exists := volume[esuser.InternalProbeUserName]; exists && n.authenticates(esuser.InternalProbeUserName, password) {
+			return true
+		}
+	}
+	return false
+}
+
+func TestPasswordRotation_NodesReloadRealmOneAtATime(t *testing.T) {
+	sc := setupScheme(t)
+	es := *esFixture.DeepCopy()
+	replicas := int32(1)
+	deployment := appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Namespace: deploymentKey.Namespace, Name: deploymentKey.Name},
+		Spec: appsv1.DeploymentSpec{
+			Replicas: &replicas,
+			Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "kb"}},
+		},
+		Status: appsv1.DeploymentStatus{Replicas: 1, UpdatedReplicas: 1, AvailableReplicas: 1},
+	}
+	c := k8s.WrapClient(fake.NewFakeClient(&es, &deployment))
+
+	// kibanaAuth is the user and password Kibana is running with
+	var kibanaAuth esclient.UserAuth
+	reconcile := func() *corev1.SecretKeySelector {
+		require.NoError(t, c.Get(k8s.ExtractNamespacedName(&es), &es))
+		selector, err := ReconcileEsUser(
+			c,
+			sc,
+			&kibanaFixture,
+			map[string]string{
+				associationLabelName:      kibanaFixture.Name,
+				associationLabelNamespace: kibanaFixture.Namespace,
+			},
+			elasticsearchuser.KibanaSystemUserBuiltinRole,
+			"kibana-user",
+			es,
+			deploymentKey,
+		)
+		require.NoError(t, err)
+		_, err = esuser.ReconcileUsers(c, sc, es, watches.NewDynamicWatches())
+		require.NoError(t, err)
+		return selector
+	}
+	kibanaConf := func(selector *corev1.SecretKeySelector) esclient.UserAuth {
+		var secret corev1.Secret
+		require.NoError(t, c.Get(types.NamespacedName{Namespace: kibanaFixture.Namespace, Name: selector.Name}, &secret))
+		return esclient.UserAuth{Name: selector.Key, Password: string(secret.Data[selector.Key])}
+	}
+	nodes := []*node{{}, {}, {}}
+	requireCredentialsWork := func() {
+		for i, n := range nodes {
+			require.True(t, n.probeSucceeds(), "probe of node %d", i)
+			require.True(t, n.authenticates(kibanaAuth.Name, []byte(kibanaAuth.Password)), "Kibana on node %d", i)
+		}
+	}
+	// reloadOneAtATime updates the volumes of the nodes, then their file realm, one node at a time
+	reloadOneAtATime := func() {
+		for _, n := range nodes {
+			n.mount(t, c, es)
+			requireCredentialsWork()
+			n.load()
+			requireCredentialsWork()
+		}
+	}
+
+	kibanaAuth = kibanaConf(reconcile())
+	reloadOneAtATime()
+	initialAuth := kibanaAuth
+
+	// request a rotation: the new users are added to the file realm, next to the current ones
+	es.Annotations = map[string]string{user.PasswordRotationAnnotationName: "1"}
+	require.NoError(t, c.Update(&es))
+	require.Equal(t, initialAuth, kibanaConf(reconcile()))
+	requireCredentialsWork()
+	reloadOneAtATime()
+
+	// the operator completes the rotation once all the nodes accept the new password of the controller user
+	var internalUsers corev1.Secret
+	require.NoError(t, c.Get(types.NamespacedName{Namespace: es.Namespace, Name: esuser.ElasticInternalUsersSecretName(es.Name)}, &internalUsers))
+	pending := internalUsers.Data[user.PendingPasswordKey(esuser.InternalControllerUserName)]
+	require.NotEmpty(t, pending)
+	for _, n := range nodes {
+		require.True(t, n.authenticates(esuser.InternalControllerUserName, pending))
+	}
+	require.NoError(t, esuser.CompletePasswordRotation(c, es))
+
+	// Kibana is switched to the new user, and rolled out
+	selector := reconcile()
+	require.Equal(t, rotatedUserName, selector.Key)
+	deployment.Status.UpdatedReplicas = 0
+	require.NoError(t, c.Update(&deployment))
+	reloadOneAtATime()
+	require.Equal(t, selector, reconcile())
+	reloadOneAtATime()
+	deployment.Status.UpdatedReplicas = 1
+	require.NoError(t, c.Update(&deployment))
+	kibanaAuth = kibanaConf(selector)
+	require.NotEqual(t, initialAuth, kibanaAuth)
+	requireCredentialsWork()
+
+	// the previous user is removed from the file realm
+	reconcile()
+	reloadOneAtATime()
+	for _, n := range nodes {
+		require.False(t, n.authenticates(initialAuth.Name, []byte(initialAuth.Password)))
+	}
+}
@@ -0,0 +1,120 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package association
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/elastic/cloud-on-k8s/pkg/apis/common/v1alpha1"
+	estype "github.com/elastic/cloud-on-k8s/pkg/apis/elasticsearch/v1alpha1"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/events"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/version"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/sset"
+	esversion "github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/version"
+	"github.com/elastic/cloud-on-k8s/pkg/utils/k8s"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// UpgradeRequeue is the result requeuing the reconciliation of a resource whose upgrade is held back.
+var UpgradeRequeue = reconcile.Result{Requeue: true, RequeueAfter: 10 * time.Second}
+
+// referencedElasticsearch returns the Elasticsearch resource referenced by the associated resource, if it exists.
+// The namespace of the reference defaults to the namespace of the associated resource.
+func referencedElasticsearch(c k8s.Client, associated v1alpha1.Associated) (*estype.Elasticsearch, error) {
+	ref := associated.ElasticsearchRef()
+	if !ref.IsDefined() {
+		return nil, nil
+	}
+	nsn := ref.NamespacedName()
+	if nsn.Namespace == "" {
+		nsn.Namespace = associated.GetNamespace()
+	}
+	var es estype.Elasticsearch
+	if err := c.Get(nsn, &es); err != nil {
+		if errors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &es, nil
+}
+
+// ElasticsearchVersion returns the version specified in the Elasticsearch resource referenced by the associated
+// resource, or nil if there is no such resource.
+func ElasticsearchVersion(c k8s.Client, associated v1alpha1.Associated) (*version.Version, error) {
+	es, err := referencedElasticsearch(c, associated)
+	if err != nil || es == nil {
+		return nil, err
+	}
+	return version.Parse(es.Spec.Version)
+}
+
+// PendingElasticsearchUpgrade returns true if the Elasticsearch cluster referenced by the associated resource still
+// runs nodes with a version lower than the given one, along with the lowest version running.
+// An associated resource must not run a version newer than its Elasticsearch cluster: its own upgrade should wait
+// for this one.
+func PendingElasticsearchUpgrade(c k8s.Client, associated v1alpha1.Associated, v version.Version) (bool, *version.Version, error) {
+	es, err := referencedElasticsearch(c, associated)
+	if err != nil || es == nil {
+		return false, nil, err
+	}
+	pods, err := sset.GetActualPodsForCluster(c, *es)
+	if err != nil {
+		return false, nil, err
+	}
+	minVersion, err := esversion.MinVersion(pods)
+	if err != nil || minVersion == nil {
+		return false, nil, err
+	}
+	return !minVersion.IsSameOrAfter(v), minVersion, nil
+}
+
+// WaitingForElasticsearchUpgradeCondition returns the UpgradeInProgress condition of an associated resource whose
+// upgrade to the given version is held back.
+func WaitingForElasticsearchUpgradeCondition(v version.Version) v1alpha1.Condition {
+	return v1alpha1.NewCondition(
+		v1alpha1.UpgradeInProgressCondition, true, string(v1alpha1.WaitingForElasticsearchUpgrade),
+		fmt.Sprintf("Waiting for all Elasticsearch nodes to run version %s", v),
+	)
+}
+
+// ReconciledVersion returns the version an associated resource must be reconciled with, given its specified version,
+// the status of its upgrade and the version it runs. Only the version change waits for the Elasticsearch upgrade: in
+// the meantime, the resource keeps being reconciled with the version it runs. An empty string is returned if it does
+// not run any version yet, in which case there is nothing to reconcile until the upgrade proceeds.
+func ReconciledVersion(specVersion string, upgradeStatus v1alpha1.UpgradeStatus, deployedVersion string) string {
+	if upgradeStatus != v1alpha1.WaitingForElasticsearchUpgrade {
+		return specVersion
+	}
+	return deployedVersion
+}
+
+// ReconcileUpgradeStatus returns the upgrade status of the associated resource specifying the given version, given its
+// current status: its reconciliation must be held back while the Elasticsearch cluster it references runs nodes with a
+// lower version. An event is emitted when the upgrade starts being held back.
+func ReconcileUpgradeStatus(
+	c k8s.Client,
+	recorder record.EventRecorder,
+	associated v1alpha1.Associated,
+	v version.Version,
+	current v1alpha1.UpgradeStatus,
+) (v1alpha1.UpgradeStatus, error) {
+	pending, esVersion, err := PendingElasticsearchUpgrade(c, associated, v)
+	if err != nil {
+		return current, err
+	}
+	if !pending {
+		return v1alpha1.UpgradeStatusUnknown, nil
+	}
+	if current != v1alpha1.WaitingForElasticsearchUpgrade {
+		recorder.Eventf(associated, corev1.EventTypeNormal, events.EventReasonDelayed,
+			"Upgrade to version %s delayed until all Elasticsearch nodes run it, lowest version running is %s", v, esVersion)
+	}
+	return v1alpha1.WaitingForElasticsearchUpgrade, nil
+}
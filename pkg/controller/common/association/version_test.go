@@ -0,0 +1,151 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package association
+
+import (
+	"testing"
+
+	commonv1alpha1 "github.com/elastic/cloud-on-k8s/pkg/apis/common/v1alpha1"
+	estype "github.com/elastic/cloud-on-k8s/pkg/apis/elasticsearch/v1alpha1"
+	kbtype "github.com/elastic/cloud-on-k8s/pkg/apis/kibana/v1alpha1"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/version"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/label"
+	"github.com/elastic/cloud-on-k8s/pkg/utils/k8s"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func esPod(name string, v string) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "ns",
+			Name:      name,
+			Labels: map[string]string{
+				label.ClusterNameLabelName: "es",
+				label.VersionLabelName:     v,
+			},
+		},
+	}
+}
+
+func TestReconcileUpgradeStatus(t *testing.T) {
+	require.NoError(t, estype.AddToScheme(scheme.Scheme))
+
+	es := &estype.Elasticsearch{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "es"},
+		Spec:       estype.ElasticsearchSpec{Version: "7.3.0"},
+	}
+	kb := kbtype.Kibana{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "kb"},
+		Spec: kbtype.KibanaSpec{
+			Version:          "7.3.0",
+			ElasticsearchRef: commonv1alpha1.ObjectSelector{Name: "es"},
+		},
+	}
+	standaloneKb := *kb.DeepCopy()
+	standaloneKb.Spec.ElasticsearchRef = commonv1alpha1.ObjectSelector{}
+
+	tests := []struct {
+		name       string
+		kb         kbtype.Kibana
+		initial    []runtime.Object
+		current    commonv1alpha1.UpgradeStatus
+		want       commonv1alpha1.UpgradeStatus
+		wantEvents int
+	}{
+		{
+			name:    "no Elasticsearch reference",
+			kb:      standaloneKb,
+			initial: []runtime.Object{es, esPod("es-0", "7.2.0")},
+			want:    commonv1alpha1.UpgradeStatusUnknown,
+		},
+		{
+			name:    "referenced Elasticsearch does not exist",
+			kb:      kb,
+			initial: []runtime.Object{esPod("es-0", "7.2.0")},
+			want:    commonv1alpha1.UpgradeStatusUnknown,
+		},
+		{
+			name:    "no Elasticsearch node yet",
+			kb:      kb,
+			initial: []runtime.Object{es},
+			want:    commonv1alpha1.UpgradeStatusUnknown,
+		},
+		{
+			name:    "all Elasticsearch nodes upgraded",
+			kb:      kb,
+			initial: []runtime.Object{es, esPod("es-0", "7.3.0"), esPod("es-1", "7.3.0")},
+			current: commonv1alpha1.WaitingForElasticsearchUpgrade,
+			want:    commonv1alpha1.UpgradeStatusUnknown,
+		},
+		{
+			name:       "Elasticsearch upgrade in progress: wait",
+			kb:         kb,
+			initial:    []runtime.Object{es, esPod("es-0", "7.3.0"), esPod("es-1", "7.2.0")},
+			want:       commonv1alpha1.WaitingForElasticsearchUpgrade,
+			wantEvents: 1,
+		},
+		{
+			name:    "Elasticsearch upgrade still in progress: no new event",
+			kb:      kb,
+			initial: []runtime.Object{es, esPod("es-0", "7.3.0"), esPod("es-1", "7.2.0")},
+			current: commonv1alpha1.WaitingForElasticsearchUpgrade,
+			want:    commonv1alpha1.WaitingForElasticsearchUpgrade,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := k8s.WrapClient(fake.NewFakeClient(tt.initial...))
+			recorder := record.NewFakeRecorder(10)
+			got, err := ReconcileUpgradeStatus(c, recorder, &tt.kb, version.MustParse(tt.kb.Spec.Version), tt.current)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+			assert.Len(t, recorder.Events, tt.wantEvents)
+		})
+	}
+}
+
+func TestReconciledVersion(t *testing.T) {
+	tests := []struct {
+		name            string
+		upgradeStatus   commonv1alpha1.UpgradeStatus
+		deployedVersion string
+		want            string
+	}{
+		{
+			name:            "upgrade not held back: reconcile the specified version",
+			upgradeStatus:   commonv1alpha1.UpgradeStatusUnknown,
+			deployedVersion: "7.2.0",
+			want:            "7.3.0",
+		},
+		{
+			name:          "nothing deployed yet: reconcile the specified version",
+			upgradeStatus: commonv1alpha1.UpgradeStatusUnknown,
+			want:          "7.3.0",
+		},
+		{
+			name:            "upgrade held back: keep reconciling the deployed version",
+			upgradeStatus:   commonv1alpha1.WaitingForElasticsearchUpgrade,
+			deployedVersion: "7.2.0",
+			want:            "7.2.0",
+		},
+		{
+			name:          "upgrade held back and nothing deployed yet: nothing to reconcile",
+			upgradeStatus: commonv1alpha1.WaitingForElasticsearchUpgrade,
+			want:          "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, ReconciledVersion("7.3.0", tt.upgradeStatus, tt.deployedVersion))
+		})
+	}
+}
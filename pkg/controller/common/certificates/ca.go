@@ -5,8 +5,8 @@
 package certificates
 
 import (
+	"crypto"
 	cryptorand "crypto/rand"
-	"crypto/rsa"
 	"crypto/x509"
 	"crypto/x509/pkix"
 	"math/big"
@@ -23,9 +23,12 @@ var (
 // CA is a simple certificate authority
 type CA struct {
 	// PrivateKey is the CA private key
-	PrivateKey *rsa.PrivateKey
+	PrivateKey crypto.Signer
 	// Cert is the certificate used to issue new certificates
 	Cert *x509.Certificate
+	// Chain contains the certificates of the authorities that issued Cert, up to the root authority.
+	// It is empty for a self-signed CA.
+	Chain []*x509.Certificate
 }
 
 // ValidatedCertificateTemplate is a type alias used to convey that the certificate template has been validated and
@@ -33,19 +36,42 @@ type CA struct {
 type ValidatedCertificateTemplate x509.Certificate
 
 // NewCA returns a ca with the given private key and cert
-func NewCA(privateKey *rsa.PrivateKey, cert *x509.Certificate) *CA {
+func NewCA(privateKey crypto.Signer, cert *x509.Certificate) *CA {
 	return &CA{
 		PrivateKey: privateKey,
 		Cert:       cert,
 	}
 }
 
+// PEMChain returns the PEM-encoded CA certificate, followed by the certificates of the authorities that issued it.
+func (c *CA) PEMChain() []byte {
+	blocks := make([][]byte, 0, len(c.Chain)+1)
+	blocks = append(blocks, c.Cert.Raw)
+	for _, cert := range c.Chain {
+		blocks = append(blocks, cert.Raw)
+	}
+	return EncodePEMCert(blocks...)
+}
+
+// CertPool returns a pool containing the CA certificate and the certificates of the authorities that issued it,
+// to be used when verifying the certificates issued by this CA.
+func (c *CA) CertPool() *x509.CertPool {
+	pool := x509.NewCertPool()
+	pool.AddCert(c.Cert)
+	for _, cert := range c.Chain {
+		pool.AddCert(cert)
+	}
+	return pool
+}
+
 // CABuilderOptions are options to build a self-signed CA
 type CABuilderOptions struct {
 	// Subject of the CA to build.
 	Subject pkix.Name
 	// PrivateKey to be used for signing certificates (auto-generated if not provided).
-	PrivateKey *rsa.PrivateKey
+	PrivateKey crypto.Signer
+	// KeyParams defines how to generate the private key if not provided (defaults to DefaultKeyParams).
+	KeyParams *KeyParams
 	// ExpireIn defines in how much time will the CA expire (defaults to DefaultCertValidity if not provided).
 	ExpireIn *time.Duration
 }
@@ -60,7 +86,11 @@ func NewSelfSignedCA(options CABuilderOptions) (*CA, error) {
 
 	privateKey := options.PrivateKey
 	if privateKey == nil {
-		privateKey, err = rsa.GenerateKey(cryptorand.Reader, 2048)
+		keyParams := DefaultKeyParams
+		if options.KeyParams != nil {
+			keyParams = *options.KeyParams
+		}
+		privateKey, err = keyParams.GenerateKey()
 		if err != nil {
 			return nil, errors.Wrap(err, "unable to generate the private key")
 		}
@@ -76,7 +106,7 @@ func NewSelfSignedCA(options CABuilderOptions) (*CA, error) {
 		Subject:               options.Subject,
 		NotBefore:             time.Now().Add(-1 * time.Minute),
 		NotAfter:              notAfter,
-		SignatureAlgorithm:    x509.SHA256WithRSA,
+		SignatureAlgorithm:    signatureAlgorithm(privateKey.Public()),
 		IsCA:                  true,
 		BasicConstraintsValid: true,
 		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
@@ -111,6 +141,8 @@ func (c *CA) CreateCertificate(
 	}
 	validatedCertificateTemplate.SerialNumber = serial
 	validatedCertificateTemplate.Issuer = c.Cert.Issuer
+	// the certificate is signed with the CA private key, whatever the algorithm of the certificate public key
+	validatedCertificateTemplate.SignatureAlgorithm = signatureAlgorithm(c.PrivateKey.Public())
 
 	certTemplate := x509.Certificate(validatedCertificateTemplate)
 
@@ -43,7 +43,8 @@ func CAInternalSecretName(namer name.Namer, ownerName string, caType CAType) str
 // The CA is persisted across operator restarts in the apiserver as a Secret for the CA certificate and private key:
 // `<clusterName>-<caType>-ca-internal`
 //
-// The CA cert and private key are rotated if they become invalid (or soon to expire).
+// The CA cert and private key are rotated if they become invalid (or soon to expire), or if the private key was not
+// generated with the given key params.
 func ReconcileCAForOwner(
 	cl k8s.Client,
 	scheme *runtime.Scheme,
@@ -52,6 +53,7 @@ func ReconcileCAForOwner(
 	labels map[string]string,
 	caType CAType,
 	rotationParams RotationParams,
+	keyParams KeyParams,
 ) (*CA, error) {
 
 	// retrieve current CA secret
@@ -66,20 +68,20 @@ func ReconcileCAForOwner(
 	}
 	if apierrors.IsNotFound(err) {
 		log.Info("No internal CA certificate Secret found, creating a new one", "owner_namespace", owner.GetNamespace(), "owner_name", owner.GetName(), "ca_type", caType)
-		return renewCA(cl, namer, owner, labels, rotationParams.Validity, scheme, caType)
+		return renewCA(cl, namer, owner, labels, rotationParams.Validity, keyParams, scheme, caType)
 	}
 
 	// build CA
 	ca := buildCAFromSecret(caInternalSecret)
 	if ca == nil {
 		log.Info("Cannot build CA from secret, creating a new one", "owner_namespace", owner.GetNamespace(), "owner_name", owner.GetName(), "ca_type", caType)
-		return renewCA(cl, namer, owner, labels, rotationParams.Validity, scheme, caType)
+		return renewCA(cl, namer, owner, labels, rotationParams.Validity, keyParams, scheme, caType)
 	}
 
 	// renew if cannot reuse
-	if !canReuseCA(ca, rotationParams.RotateBefore) {
+	if !canReuseCA(ca, rotationParams.RotateBefore, keyParams) {
 		log.Info("Cannot reuse existing CA, creating a new one", "owner_namespace", owner.GetNamespace(), "owner_name", owner.GetName(), "ca_type", caType)
-		return renewCA(cl, namer, owner, labels, rotationParams.Validity, scheme, caType)
+		return renewCA(cl, namer, owner, labels, rotationParams.Validity, keyParams, scheme, caType)
 	}
 
 	// reuse existing CA
@@ -93,6 +95,7 @@ func renewCA(
 	owner v1.Object,
 	labels map[string]string,
 	expireIn time.Duration,
+	keyParams KeyParams,
 	scheme *runtime.Scheme,
 	caType CAType,
 ) (*CA, error) {
@@ -101,12 +104,16 @@ func renewCA(
 			CommonName:         owner.GetName() + "-" + string(caType),
 			OrganizationalUnit: []string{owner.GetName()},
 		},
-		ExpireIn: &expireIn,
+		ExpireIn:  &expireIn,
+		KeyParams: &keyParams,
 	})
 	if err != nil {
 		return nil, err
 	}
-	caInternalSecret := internalSecretForCA(ca, namer, owner, labels, caType)
+	caInternalSecret, err := internalSecretForCA(ca, namer, owner, labels, caType)
+	if err != nil {
+		return nil, err
+	}
 
 	// create or update internal secret
 	reconciledCAInternalSecret := corev1.Secret{}
@@ -126,8 +133,12 @@ func renewCA(
 }
 
 // canReuseCA returns true if the given CA is valid for reuse
-func canReuseCA(ca *CA, expirationSafetyMargin time.Duration) bool {
-	return PrivateMatchesPublicKey(ca.Cert.PublicKey, *ca.PrivateKey) && certIsValid(*ca.Cert, expirationSafetyMargin)
+func canReuseCA(ca *CA, expirationSafetyMargin time.Duration, keyParams KeyParams) bool {
+	if !keyParams.Matches(ca.Cert.PublicKey) {
+		log.Info("CA private key algorithm or size does not match the expected one", "subject", ca.Cert.Subject)
+		return false
+	}
+	return PrivateMatchesPublicKey(ca.Cert.PublicKey, ca.PrivateKey) && certIsValid(*ca.Cert, expirationSafetyMargin)
 }
 
 // certIsValid returns true if the given cert is valid,
@@ -152,7 +163,11 @@ func internalSecretForCA(
 	owner v1.Object,
 	labels map[string]string,
 	caType CAType,
-) corev1.Secret {
+) (corev1.Secret, error) {
+	privateKeyData, err := EncodePEMPrivateKey(ca.PrivateKey)
+	if err != nil {
+		return corev1.Secret{}, err
+	}
 	return corev1.Secret{
 		ObjectMeta: v1.ObjectMeta{
 			Namespace: owner.GetNamespace(),
@@ -161,9 +176,9 @@ func internalSecretForCA(
 		},
 		Data: map[string][]byte{
 			CertFileName: EncodePEMCert(ca.Cert.Raw),
-			KeyFileName:  EncodePEMPrivateKey(*ca.PrivateKey),
+			KeyFileName:  privateKeyData,
 		},
-	}
+	}, nil
 }
 
 // buildCAFromSecret parses the given secret into a CA.
@@ -12,6 +12,7 @@ import (
 	"testing"
 	"time"
 
+	commonv1alpha1 "github.com/elastic/cloud-on-k8s/pkg/apis/common/v1alpha1"
 	"github.com/elastic/cloud-on-k8s/pkg/apis/elasticsearch/v1alpha1"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/common/name"
 	"github.com/elastic/cloud-on-k8s/pkg/utils/k8s"
@@ -132,10 +133,30 @@ func Test_canReuseCA(t *testing.T) {
 			},
 			want: false,
 		},
+		{
+			name: "key algorithm mismatch",
+			ca: func() *CA {
+				ecdsaKeyParams := KeyParams{Algorithm: commonv1alpha1.ECDSAPrivateKeyAlgorithm, Size: DefaultECDSAKeySize}
+				testCa, err := NewSelfSignedCA(CABuilderOptions{KeyParams: &ecdsaKeyParams})
+				require.NoError(t, err)
+				return testCa
+			},
+			want: false,
+		},
+		{
+			name: "key size mismatch",
+			ca: func() *CA {
+				rsaKeyParams := KeyParams{Algorithm: commonv1alpha1.RSAPrivateKeyAlgorithm, Size: 3072}
+				testCa, err := NewSelfSignedCA(CABuilderOptions{KeyParams: &rsaKeyParams})
+				require.NoError(t, err)
+				return testCa
+			},
+			want: false,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if got := canReuseCA(tt.ca(), DefaultRotateBefore); got != tt.want {
+			if got := canReuseCA(tt.ca(), DefaultRotateBefore, DefaultKeyParams); got != tt.want {
 				t.Errorf("canReuseCA() = %v, want %v", got, tt.want)
 			}
 		})
@@ -162,8 +183,7 @@ func checkCASecrets(
 	// if an expected Ca was passed, it should match ca
 	if expectedCa != nil {
 		require.True(t, ca.Cert.Equal(expectedCa.Cert))
-		require.Equal(t, ca.PrivateKey.E, expectedCa.PrivateKey.E)
-		require.Equal(t, ca.PrivateKey.N, expectedCa.PrivateKey.N)
+		require.True(t, PrivateMatchesPublicKey(expectedCa.Cert.PublicKey, ca.PrivateKey))
 	}
 
 	// if a not expected Ca was passed, it should not match ca
@@ -186,14 +206,14 @@ func checkCASecrets(
 	require.NotNil(t, parsedCa)
 	// and return the ca
 	require.True(t, ca.Cert.Equal(parsedCa.Cert))
-	require.Equal(t, ca.PrivateKey.E, parsedCa.PrivateKey.E)
-	require.Equal(t, ca.PrivateKey.N, parsedCa.PrivateKey.N)
+	require.True(t, PrivateMatchesPublicKey(ca.Cert.PublicKey, parsedCa.PrivateKey))
 }
 
 func Test_renewCA(t *testing.T) {
 	testCa, err := NewSelfSignedCA(CABuilderOptions{})
 	require.NoError(t, err)
-	internalCASecret := internalSecretForCA(testCa, testNamer, &testCluster, nil, TransportCAType)
+	internalCASecret, err := internalSecretForCA(testCa, testNamer, &testCluster, nil, TransportCAType)
+	require.NoError(t, err)
 
 	err = v1alpha1.AddToScheme(scheme.Scheme)
 	require.NoError(t, err)
@@ -218,7 +238,7 @@ func Test_renewCA(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			ca, err := renewCA(tt.client, testNamer, &testCluster, nil, tt.expireIn, scheme.Scheme, TransportCAType)
+			ca, err := renewCA(tt.client, testNamer, &testCluster, nil, tt.expireIn, DefaultKeyParams, scheme.Scheme, TransportCAType)
 			require.NoError(t, err)
 			require.NotNil(t, ca)
 			assert.Equal(t, ca.Cert.Issuer.CommonName, testName+"-"+string(TransportCAType))
@@ -233,7 +253,8 @@ func TestReconcileCAForCluster(t *testing.T) {
 
 	validCa, err := NewSelfSignedCA(CABuilderOptions{})
 	require.NoError(t, err)
-	internalCASecret := internalSecretForCA(validCa, testNamer, &testCluster, nil, TransportCAType)
+	internalCASecret, err := internalSecretForCA(validCa, testNamer, &testCluster, nil, TransportCAType)
+	require.NoError(t, err)
 
 	internalCASecretWithoutPrivateKey := internalCASecret.DeepCopy()
 	delete(internalCASecretWithoutPrivateKey.Data, KeyFileName)
@@ -246,14 +267,18 @@ func TestReconcileCAForCluster(t *testing.T) {
 		ExpireIn: &soonToExpire,
 	})
 	require.NoError(t, err)
-	soonToExpireInternalCASecret := internalSecretForCA(
+	soonToExpireInternalCASecret, err := internalSecretForCA(
 		soonToExpireCa, testNamer, &testCluster, nil, TransportCAType,
 	)
+	require.NoError(t, err)
+
+	ecdsaKeyParams := KeyParams{Algorithm: commonv1alpha1.ECDSAPrivateKeyAlgorithm, Size: DefaultECDSAKeySize}
 
 	tests := []struct {
 		name             string
 		cl               k8s.Client
 		caCertValidity   time.Duration
+		keyParams        *KeyParams
 		shouldReuseCa    *CA // ca that should be reused
 		shouldNotReuseCa *CA // ca that should not be reused
 	}{
@@ -288,17 +313,31 @@ func TestReconcileCAForCluster(t *testing.T) {
 			shouldReuseCa:    nil,            // should create a new one
 			shouldNotReuseCa: soonToExpireCa, // and not reuse existing one
 		},
+		{
+			name:             "existing internal cert has a different key algorithm",
+			cl:               k8s.WrapClient(fake.NewFakeClient(&internalCASecret)),
+			caCertValidity:   DefaultCertValidity,
+			keyParams:        &ecdsaKeyParams,
+			shouldReuseCa:    nil,     // should create a new one
+			shouldNotReuseCa: validCa, // and not reuse existing one
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
+			keyParams := DefaultKeyParams
+			if tt.keyParams != nil {
+				keyParams = *tt.keyParams
+			}
 			ca, err := ReconcileCAForOwner(
 				tt.cl, scheme.Scheme, testNamer, &testCluster, nil, TransportCAType, RotationParams{
 					Validity:     tt.caCertValidity,
 					RotateBefore: DefaultRotateBefore,
 				},
+				keyParams,
 			)
 			require.NoError(t, err)
 			require.NotNil(t, ca)
+			require.True(t, keyParams.Matches(ca.Cert.PublicKey))
 			checkCASecrets(
 				t, tt.cl, testCluster, TransportCAType, ca, tt.shouldReuseCa, tt.shouldNotReuseCa, tt.caCertValidity,
 			)
@@ -312,7 +351,8 @@ func Test_internalSecretForCA(t *testing.T) {
 
 	labels := map[string]string{"foo": "bar"}
 
-	internalSecret := internalSecretForCA(testCa, testNamer, &testCluster, labels, TransportCAType)
+	internalSecret, err := internalSecretForCA(testCa, testNamer, &testCluster, labels, TransportCAType)
+	require.NoError(t, err)
 
 	assert.Equal(t, testNamespace, internalSecret.Namespace)
 	assert.Equal(t, testName+"-test-transport-ca-internal", internalSecret.Name)
@@ -328,7 +368,8 @@ func Test_buildCAFromSecret(t *testing.T) {
 	testCa, err := NewSelfSignedCA(CABuilderOptions{})
 	require.NoError(t, err)
 
-	internalSecret := internalSecretForCA(testCa, testNamer, &testCluster, nil, TransportCAType)
+	internalSecret, err := internalSecretForCA(testCa, testNamer, &testCluster, nil, TransportCAType)
+	require.NoError(t, err)
 
 	internalSecretMissingCert := internalSecret.DeepCopy()
 	delete(internalSecretMissingCert.Data, CertFileName)
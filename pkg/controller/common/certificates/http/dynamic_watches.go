@@ -19,6 +19,12 @@ func httpCertificateWatchKey(namer name.Namer, ownerName string) string {
 	return namer.Suffix(ownerName, "http-certificate")
 }
 
+// httpCertificateAuthorityWatchKey returns the key used by the dynamic watch registration for the user-provided
+// certificate authority issuing http certificates
+func httpCertificateAuthorityWatchKey(namer name.Namer, ownerName string) string {
+	return namer.Suffix(ownerName, "http-certificate-authority")
+}
+
 // reconcileDynamicWatches reconciles the dynamic watches needed by the HTTP certificates.
 func reconcileDynamicWatches(dynamicWatches watches.DynamicWatches, owner types.NamespacedName, namer name.Namer, tls v1alpha1.TLSOptions) error {
 	// watch the Secret specified in es.Spec.HTTP.TLS.Certificate because if it changes we should reconcile the new
@@ -41,6 +47,26 @@ func reconcileDynamicWatches(dynamicWatches watches.DynamicWatches, owner types.
 		dynamicWatches.Secrets.RemoveHandlerForKey(httpCertificateWatch.Key())
 	}
 
+	// watch the Secret specified in es.Spec.HTTP.TLS.CertificateAuthority because if it changes we should issue
+	// new certificates.
+	httpCertificateAuthorityWatch := watches.NamedWatch{
+		Name: httpCertificateAuthorityWatchKey(namer, owner.Name),
+		Watched: []types.NamespacedName{{
+			Namespace: owner.Namespace,
+			Name:      tls.CertificateAuthority.SecretName,
+		}},
+		Watcher: owner,
+	}
+
+	if tls.CertificateAuthority.SecretName != "" {
+		if err := dynamicWatches.Secrets.AddHandler(httpCertificateAuthorityWatch); err != nil {
+			return err
+		}
+	} else {
+		// remove the watch if no longer configured.
+		dynamicWatches.Secrets.RemoveHandlerForKey(httpCertificateAuthorityWatch.Key())
+	}
+
 	return nil
 }
 
@@ -51,6 +77,7 @@ func DynamicWatchesFinalizer(dynamicWatches watches.DynamicWatches, kind string,
 		Execute: func() error {
 			// es resource is being finalized, so we no longer need the dynamic watch
 			dynamicWatches.Secrets.RemoveHandlerForKey(httpCertificateWatchKey(namer, ownerName))
+			dynamicWatches.Secrets.RemoveHandlerForKey(httpCertificateAuthorityWatchKey(namer, ownerName))
 			return nil
 		},
 	}
@@ -50,6 +50,7 @@ func TestReconcileHTTPCertsPublicSecret(t *testing.T) {
 		wantSecret := &corev1.Secret{
 			ObjectMeta: k8s.ToObjectMeta(namespacedSecretName),
 			Data: map[string][]byte{
+				certificates.CAFileName:   ca,
 				certificates.CertFileName: tls,
 			},
 		}
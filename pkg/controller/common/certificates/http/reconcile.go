@@ -5,8 +5,9 @@
 package http
 
 import (
+	"bytes"
+	"crypto"
 	cryptorand "crypto/rand"
-	"crypto/rsa"
 	"crypto/x509"
 	"crypto/x509/pkix"
 	"net"
@@ -43,6 +44,7 @@ func ReconcileHTTPCertificates(
 	labels map[string]string,
 	services []corev1.Service,
 	rotationParams certificates.RotationParams,
+	keyParams certificates.KeyParams,
 ) (*CertificatesSecret, error) {
 	ownerNSN := k8s.ExtractNamespacedName(owner)
 	customCertificates, err := GetCustomCertificates(driver.K8sClient(), ownerNSN, tls)
@@ -55,7 +57,7 @@ func ReconcileHTTPCertificates(
 	}
 
 	internalCerts, err := reconcileHTTPInternalCertificatesSecret(
-		driver.K8sClient(), driver.Scheme(), owner, namer, tls, labels, services, customCertificates, ca, rotationParams,
+		driver.K8sClient(), driver.Scheme(), owner, namer, tls, labels, services, customCertificates, ca, rotationParams, keyParams,
 	)
 	if err != nil {
 		return nil, err
@@ -64,6 +66,28 @@ func ReconcileHTTPCertificates(
 	return internalCerts, nil
 }
 
+// TLSOptions returns the TLS options of the given HTTP configuration, with the host of the ingress, if any, added to
+// the SANs of the self-signed certificate.
+func TLSOptions(config v1alpha1.HTTPConfig) v1alpha1.TLSOptions {
+	tls := *config.TLS.DeepCopy()
+	if config.Ingress == nil || config.Ingress.Host == "" {
+		return tls
+	}
+	if tls.SelfSignedCertificate == nil {
+		tls.SelfSignedCertificate = &v1alpha1.SelfSignedCertificate{}
+	}
+	for _, san := range tls.SelfSignedCertificate.SubjectAlternativeNames {
+		if san.DNS == config.Ingress.Host {
+			return tls
+		}
+	}
+	tls.SelfSignedCertificate.SubjectAlternativeNames = append(
+		tls.SelfSignedCertificate.SubjectAlternativeNames,
+		v1alpha1.SubjectAlternativeName{DNS: config.Ingress.Host},
+	)
+	return tls
+}
+
 // reconcileHTTPInternalCertificatesSecret ensures that the internal HTTP certificate secret has the correct content.
 func reconcileHTTPInternalCertificatesSecret(
 	c k8s.Client,
@@ -76,6 +100,7 @@ func reconcileHTTPInternalCertificatesSecret(
 	customCertificates *CertificatesSecret,
 	ca *certificates.CA,
 	rotationParams certificates.RotationParams,
+	keyParams certificates.KeyParams,
 ) (*CertificatesSecret, error) {
 	secret := corev1.Secret{
 		ObjectMeta: metav1.ObjectMeta{
@@ -122,6 +147,9 @@ func reconcileHTTPInternalCertificatesSecret(
 		expectedSecretData := make(map[string][]byte)
 		expectedSecretData[certificates.CertFileName] = customCertificates.CertChain()
 		expectedSecretData[certificates.KeyFileName] = customCertificates.KeyPem()
+		if caPem := customCertificates.CAPem(); len(caPem) > 0 {
+			expectedSecretData[certificates.CAFileName] = caPem
+		}
 
 		if !reflect.DeepEqual(secret.Data, expectedSecretData) {
 			needsUpdate = true
@@ -129,7 +157,7 @@ func reconcileHTTPInternalCertificatesSecret(
 		}
 	} else {
 		selfSignedNeedsUpdate, err := ensureInternalSelfSignedCertificateSecretContents(
-			&secret, k8s.ExtractNamespacedName(owner), namer, tls, svcs, ca, rotationParams,
+			&secret, k8s.ExtractNamespacedName(owner), namer, tls, svcs, ca, rotationParams, keyParams,
 		)
 		if err != nil {
 			return nil, err
@@ -167,17 +195,21 @@ func ensureInternalSelfSignedCertificateSecretContents(
 	svcs []corev1.Service,
 	ca *certificates.CA,
 	rotationParam certificates.RotationParams,
+	keyParams certificates.KeyParams,
 ) (bool, error) {
 	secretWasChanged := false
 
-	// verify that the secret contains a parsable private key, create if it does not exist
-	var privateKey *rsa.PrivateKey
+	// verify that the secret contains a parsable private key generated with the expected params, create if it does not exist
+	var privateKey crypto.Signer
 	needsNewPrivateKey := true
 	if privateKeyData, ok := secret.Data[certificates.KeyFileName]; ok {
 		storedPrivateKey, err := certificates.ParsePEMPrivateKey(privateKeyData)
-		if err != nil {
+		switch {
+		case err != nil:
 			log.Error(err, "Unable to parse stored private key", "namespace", secret.Namespace, "secret_name", secret.Name)
-		} else {
+		case !keyParams.Matches(storedPrivateKey.Public()):
+			log.Info("Stored private key algorithm or size does not match the expected one", "namespace", secret.Namespace, "secret_name", secret.Name)
+		default:
 			needsNewPrivateKey = false
 			privateKey = storedPrivateKey
 		}
@@ -185,18 +217,22 @@ func ensureInternalSelfSignedCertificateSecretContents(
 
 	// if we need a new private key, generate it
 	if needsNewPrivateKey {
-		generatedPrivateKey, err := rsa.GenerateKey(cryptorand.Reader, 2048)
+		generatedPrivateKey, err := keyParams.GenerateKey()
+		if err != nil {
+			return secretWasChanged, err
+		}
+		privateKeyData, err := certificates.EncodePEMPrivateKey(generatedPrivateKey)
 		if err != nil {
 			return secretWasChanged, err
 		}
 
 		privateKey = generatedPrivateKey
 		secretWasChanged = true
-		secret.Data[certificates.KeyFileName] = certificates.EncodePEMPrivateKey(*privateKey)
+		secret.Data[certificates.KeyFileName] = privateKeyData
 	}
 
-	// check if the existing cert should be re-issued
-	if shouldIssueNewHTTPCertificate(owner, namer, tls, secret, svcs, ca, rotationParam.RotateBefore) {
+	// check if the existing cert should be re-issued, a new private key always requires a new certificate
+	if needsNewPrivateKey || shouldIssueNewHTTPCertificate(owner, namer, tls, secret, svcs, ca, rotationParam.RotateBefore) {
 		log.Info(
 			"Issuing new HTTP certificate",
 			"namespace", secret.Namespace,
@@ -228,7 +264,13 @@ func ensureInternalSelfSignedCertificateSecretContents(
 
 		secretWasChanged = true
 		// store certificate and signed certificate in a secret mounted into the pod
-		secret.Data[certificates.CertFileName] = certificates.EncodePEMCert(certData, ca.Cert.Raw)
+		secret.Data[certificates.CertFileName] = append(certificates.EncodePEMCert(certData), ca.PEMChain()...)
+	}
+
+	// store the CA alongside the certificate, for clients that need to verify it
+	if caPem := ca.PEMChain(); !bytes.Equal(secret.Data[certificates.CAFileName], caPem) {
+		secretWasChanged = true
+		secret.Data[certificates.CAFileName] = caPem
 	}
 
 	return secretWasChanged, nil
@@ -242,6 +284,7 @@ func ensureInternalSelfSignedCertificateSecretContents(
 //   - certificate has the wrong format
 //   - certificate is invalid according to the CA or expired
 //   - certificate SAN and IP does not match the expected ones
+//   - certificate chain does not match the CA chain
 func shouldIssueNewHTTPCertificate(
 	owner types.NamespacedName,
 	namer name.Namer,
@@ -279,8 +322,7 @@ func shouldIssueNewHTTPCertificate(
 		return true
 	}
 
-	pool := x509.NewCertPool()
-	pool.AddCert(ca.Cert)
+	pool := ca.CertPool()
 	verifyOpts := x509.VerifyOptions{
 		DNSName:       validatedTemplate.Subject.CommonName,
 		Roots:         pool,
@@ -317,6 +359,12 @@ func shouldIssueNewHTTPCertificate(
 		return true
 	}
 
+	// the CA certificate may have been renewed with the same key, or its chain may have changed
+	if !bytes.HasSuffix(certData, ca.PEMChain()) {
+		log.Info("Certificate chain does not match the CA chain, should issue new", "namespace", secret.Namespace, "secret_name", secret.Name)
+		return true
+	}
+
 	return false
 }
 
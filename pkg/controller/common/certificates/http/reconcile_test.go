@@ -5,6 +5,7 @@
 package http
 
 import (
+	"crypto/ecdsa"
 	cryptorand "crypto/rand"
 	"crypto/rsa"
 	"crypto/x509"
@@ -107,10 +108,11 @@ func TestReconcileHTTPCertificates(t *testing.T) {
 	key := loadFileBytes("tls.key")
 
 	type args struct {
-		c        k8s.Client
-		es       v1alpha1.Elasticsearch
-		ca       *certificates.CA
-		services []corev1.Service
+		c         k8s.Client
+		es        v1alpha1.Elasticsearch
+		ca        *certificates.CA
+		services  []corev1.Service
+		keyParams *certificates.KeyParams
 	}
 	tests := []struct {
 		name    string
@@ -128,6 +130,23 @@ func TestReconcileHTTPCertificates(t *testing.T) {
 			want: func(t *testing.T, cs *CertificatesSecret) {
 				assert.Contains(t, cs.Data, certificates.KeyFileName)
 				assert.Contains(t, cs.Data, certificates.CertFileName)
+				assert.Equal(t, testCA.PEMChain(), cs.Data[certificates.CAFileName])
+			},
+		},
+		{
+			name: "should generate a private key with the given algorithm",
+			args: args{
+				c:         k8s.WrapClient(fake.NewFakeClient()),
+				es:        testES,
+				ca:        testCA,
+				keyParams: &certificates.KeyParams{Algorithm: commonv1alpha1.ECDSAPrivateKeyAlgorithm, Size: 384},
+			},
+			want: func(t *testing.T, cs *CertificatesSecret) {
+				privateKey, err := certificates.ParsePEMPrivateKey(cs.Data[certificates.KeyFileName])
+				require.NoError(t, err)
+				ecdsaKey, ok := privateKey.(*ecdsa.PrivateKey)
+				require.True(t, ok)
+				assert.Equal(t, 384, ecdsaKey.Curve.Params().BitSize)
 			},
 		},
 		{
@@ -170,12 +189,17 @@ func TestReconcileHTTPCertificates(t *testing.T) {
 				Watches:       w,
 			}
 
+			keyParams := certificates.DefaultKeyParams
+			if tt.args.keyParams != nil {
+				keyParams = *tt.args.keyParams
+			}
 			got, err := ReconcileHTTPCertificates(
 				testDriver, &tt.args.es, name.ESNamer, tt.args.ca, tt.args.es.Spec.HTTP.TLS, map[string]string{}, tt.args.services,
 				certificates.RotationParams{
 					Validity:     certificates.DefaultCertValidity,
 					RotateBefore: certificates.DefaultRotateBefore,
 				},
+				keyParams,
 			)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("ReconcileHTTPCertificates() error = %v, wantErr %v", err, tt.wantErr)
@@ -186,6 +210,85 @@ func TestReconcileHTTPCertificates(t *testing.T) {
 	}
 }
 
+func TestTLSOptions(t *testing.T) {
+	ingress := &commonv1alpha1.IngressTemplate{Host: "kibana.example.com"}
+	tests := []struct {
+		name   string
+		config commonv1alpha1.HTTPConfig
+		want   commonv1alpha1.TLSOptions
+	}{
+		{
+			name:   "no ingress",
+			config: commonv1alpha1.HTTPConfig{},
+			want:   commonv1alpha1.TLSOptions{},
+		},
+		{
+			name:   "ingress host added to the SANs",
+			config: commonv1alpha1.HTTPConfig{Ingress: ingress},
+			want: commonv1alpha1.TLSOptions{
+				SelfSignedCertificate: &commonv1alpha1.SelfSignedCertificate{
+					SubjectAlternativeNames: []commonv1alpha1.SubjectAlternativeName{{DNS: "kibana.example.com"}},
+				},
+			},
+		},
+		{
+			name: "ingress host appended to the user-provided SANs",
+			config: commonv1alpha1.HTTPConfig{
+				Ingress: ingress,
+				TLS: commonv1alpha1.TLSOptions{
+					SelfSignedCertificate: &commonv1alpha1.SelfSignedCertificate{
+						SubjectAlternativeNames: []commonv1alpha1.SubjectAlternativeName{{IP: "4.4.6.7"}},
+					},
+				},
+			},
+			want: commonv1alpha1.TLSOptions{
+				SelfSignedCertificate: &commonv1alpha1.SelfSignedCertificate{
+					SubjectAlternativeNames: []commonv1alpha1.SubjectAlternativeName{{IP: "4.4.6.7"}, {DNS: "kibana.example.com"}},
+				},
+			},
+		},
+		{
+			name: "ingress host already in the SANs",
+			config: commonv1alpha1.HTTPConfig{
+				Ingress: ingress,
+				TLS: commonv1alpha1.TLSOptions{
+					SelfSignedCertificate: &commonv1alpha1.SelfSignedCertificate{
+						SubjectAlternativeNames: []commonv1alpha1.SubjectAlternativeName{{DNS: "kibana.example.com"}},
+					},
+				},
+			},
+			want: commonv1alpha1.TLSOptions{
+				SelfSignedCertificate: &commonv1alpha1.SelfSignedCertificate{
+					SubjectAlternativeNames: []commonv1alpha1.SubjectAlternativeName{{DNS: "kibana.example.com"}},
+				},
+			},
+		},
+		{
+			name: "self-signed certificate disabled",
+			config: commonv1alpha1.HTTPConfig{
+				Ingress: ingress,
+				TLS: commonv1alpha1.TLSOptions{
+					SelfSignedCertificate: &commonv1alpha1.SelfSignedCertificate{Disabled: true},
+				},
+			},
+			want: commonv1alpha1.TLSOptions{
+				SelfSignedCertificate: &commonv1alpha1.SelfSignedCertificate{
+					SubjectAlternativeNames: []commonv1alpha1.SubjectAlternativeName{{DNS: "kibana.example.com"}},
+					Disabled:                true,
+				},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			original := tt.config.DeepCopy()
+			require.Equal(t, tt.want, TLSOptions(tt.config))
+			// the configuration must not be modified
+			require.Equal(t, original, &tt.config)
+		})
+	}
+}
+
 func Test_createValidatedHTTPCertificateTemplate(t *testing.T) {
 	sanDNS1 := "my.dns.com"
 	sanDNS2 := "my.second.dns.com"
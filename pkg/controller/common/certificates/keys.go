@@ -5,21 +5,141 @@
 package certificates
 
 import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	cryptorand "crypto/rand"
 	"crypto/rsa"
+	"crypto/x509"
+	"fmt"
 
+	"github.com/elastic/cloud-on-k8s/pkg/apis/common/v1alpha1"
 	"github.com/pkg/errors"
 )
 
-// PrivateMatchesPublicKey returns true if the public and private keys correspond to each other.
-func PrivateMatchesPublicKey(publicKey interface{}, privateKey rsa.PrivateKey) bool {
-	pubKey, ok := publicKey.(*rsa.PublicKey)
-	if !ok {
-		log.Error(errors.New("Public key is not an RSA public key"), "")
+const (
+	// DefaultRSAKeySize is the size of RSA keys if not specified.
+	DefaultRSAKeySize = 2048
+	// DefaultECDSAKeySize is the size of ECDSA keys if not specified.
+	DefaultECDSAKeySize = 256
+)
+
+// DefaultKeyParams are the default parameters of the private keys generated by the operator.
+var DefaultKeyParams = KeyParams{Algorithm: v1alpha1.RSAPrivateKeyAlgorithm, Size: DefaultRSAKeySize}
+
+// KeyParams defines the algorithm and size of generated private keys.
+type KeyParams struct {
+	// Algorithm is the algorithm of the private keys.
+	Algorithm v1alpha1.PrivateKeyAlgorithm
+	// Size is the size of the private keys in bits.
+	Size int
+}
+
+// WithOverrides returns the key params resulting from the given user-provided options applied on top of these params.
+// The size defaults to the default size of the algorithm if only the algorithm is overridden.
+func (p KeyParams) WithOverrides(options *v1alpha1.PrivateKeyOptions) KeyParams {
+	if options == nil {
+		return p
+	}
+	result := p
+	if options.Algorithm != "" && options.Algorithm != p.Algorithm {
+		result = KeyParams{Algorithm: options.Algorithm, Size: defaultKeySize(options.Algorithm)}
+	}
+	if options.Size != 0 {
+		result.Size = options.Size
+	}
+	return result
+}
+
+// Validate returns an error if private keys cannot be generated with these params.
+func (p KeyParams) Validate() error {
+	switch p.Algorithm {
+	case v1alpha1.RSAPrivateKeyAlgorithm:
+		switch p.Size {
+		case 2048, 3072, 4096:
+			return nil
+		}
+	case v1alpha1.ECDSAPrivateKeyAlgorithm:
+		if _, err := ellipticCurve(p.Size); err == nil {
+			return nil
+		}
+	default:
+		return fmt.Errorf("unsupported private key algorithm %s", p.Algorithm)
+	}
+	return fmt.Errorf("unsupported private key size %d for algorithm %s", p.Size, p.Algorithm)
+}
+
+// GenerateKey generates a new private key according to these params.
+func (p KeyParams) GenerateKey() (crypto.Signer, error) {
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+	if p.Algorithm == v1alpha1.ECDSAPrivateKeyAlgorithm {
+		curve, err := ellipticCurve(p.Size)
+		if err != nil {
+			return nil, err
+		}
+		return ecdsa.GenerateKey(curve, cryptorand.Reader)
+	}
+	return rsa.GenerateKey(cryptorand.Reader, p.Size)
+}
+
+// Matches returns true if the given public key was generated with these params.
+func (p KeyParams) Matches(publicKey crypto.PublicKey) bool {
+	switch key := publicKey.(type) {
+	case *rsa.PublicKey:
+		return p.Algorithm == v1alpha1.RSAPrivateKeyAlgorithm && key.N.BitLen() == p.Size
+	case *ecdsa.PublicKey:
+		return p.Algorithm == v1alpha1.ECDSAPrivateKeyAlgorithm && key.Curve.Params().BitSize == p.Size
+	default:
 		return false
 	}
-	// check that public and private keys share the same modulus and exponent
-	if pubKey.N.Cmp(privateKey.N) != 0 || pubKey.E != privateKey.E {
+}
+
+func defaultKeySize(algorithm v1alpha1.PrivateKeyAlgorithm) int {
+	if algorithm == v1alpha1.ECDSAPrivateKeyAlgorithm {
+		return DefaultECDSAKeySize
+	}
+	return DefaultRSAKeySize
+}
+
+func ellipticCurve(size int) (elliptic.Curve, error) {
+	switch size {
+	case 256:
+		return elliptic.P256(), nil
+	case 384:
+		return elliptic.P384(), nil
+	default:
+		return nil, fmt.Errorf("unsupported elliptic curve size %d", size)
+	}
+}
+
+// signatureAlgorithm returns the algorithm to use to sign certificates with the private key of the given public key.
+func signatureAlgorithm(publicKey crypto.PublicKey) x509.SignatureAlgorithm {
+	key, ok := publicKey.(*ecdsa.PublicKey)
+	switch {
+	case !ok:
+		return x509.SHA256WithRSA
+	case key.Curve.Params().BitSize > 256:
+		return x509.ECDSAWithSHA384
+	default:
+		return x509.ECDSAWithSHA256
+	}
+}
+
+// PrivateMatchesPublicKey returns true if the public and private keys correspond to each other.
+func PrivateMatchesPublicKey(publicKey interface{}, privateKey crypto.Signer) bool {
+	switch pubKey := publicKey.(type) {
+	case *rsa.PublicKey:
+		privKey, ok := privateKey.(*rsa.PrivateKey)
+		// check that public and private keys share the same modulus and exponent
+		return ok && pubKey.N.Cmp(privKey.N) == 0 && pubKey.E == privKey.E
+	case *ecdsa.PublicKey:
+		privKey, ok := privateKey.(*ecdsa.PrivateKey)
+		// check that public and private keys share the same curve and point
+		return ok && pubKey.Curve == privKey.Curve && pubKey.X.Cmp(privKey.X) == 0 && pubKey.Y.Cmp(privKey.Y) == 0
+	default:
+		log.Error(errors.New("Public key is neither an RSA nor an ECDSA public key"), "")
 		return false
 	}
-	return true
 }
@@ -7,10 +7,14 @@
 package certificates
 
 import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
 	cryptorand "crypto/rand"
 	"crypto/rsa"
 	"testing"
 
+	"github.com/elastic/cloud-on-k8s/pkg/apis/common/v1alpha1"
 	"github.com/stretchr/testify/require"
 )
 
@@ -19,22 +23,44 @@ func Test_PrivateMatchesPublicKey(t *testing.T) {
 	require.NoError(t, err)
 	privateKey2, err := rsa.GenerateKey(cryptorand.Reader, 2048)
 	require.NoError(t, err)
+	ecdsaPrivateKey1, err := ecdsa.GenerateKey(elliptic.P256(), cryptorand.Reader)
+	require.NoError(t, err)
+	ecdsaPrivateKey2, err := ecdsa.GenerateKey(elliptic.P256(), cryptorand.Reader)
+	require.NoError(t, err)
 	tests := []struct {
 		name       string
 		publicKey  interface{}
-		privateKey rsa.PrivateKey
+		privateKey crypto.Signer
 		want       bool
 	}{
 		{
 			name:       "with matching public and private keys",
 			publicKey:  privateKey1.Public(),
-			privateKey: *privateKey1,
+			privateKey: privateKey1,
 			want:       true,
 		},
 		{
 			name:       "with non-matching public and private keys",
 			publicKey:  privateKey1.Public(),
-			privateKey: *privateKey2,
+			privateKey: privateKey2,
+			want:       false,
+		},
+		{
+			name:       "with matching ECDSA public and private keys",
+			publicKey:  ecdsaPrivateKey1.Public(),
+			privateKey: ecdsaPrivateKey1,
+			want:       true,
+		},
+		{
+			name:       "with non-matching ECDSA public and private keys",
+			publicKey:  ecdsaPrivateKey1.Public(),
+			privateKey: ecdsaPrivateKey2,
+			want:       false,
+		},
+		{
+			name:       "with public and private keys of different algorithms",
+			publicKey:  privateKey1.Public(),
+			privateKey: ecdsaPrivateKey1,
 			want:       false,
 		},
 	}
@@ -46,3 +72,103 @@ func Test_PrivateMatchesPublicKey(t *testing.T) {
 		})
 	}
 }
+
+func TestKeyParams_WithOverrides(t *testing.T) {
+	tests := []struct {
+		name    string
+		params  KeyParams
+		options *v1alpha1.PrivateKeyOptions
+		want    KeyParams
+	}{
+		{
+			name:    "no options",
+			params:  DefaultKeyParams,
+			options: nil,
+			want:    DefaultKeyParams,
+		},
+		{
+			name:    "size override",
+			params:  DefaultKeyParams,
+			options: &v1alpha1.PrivateKeyOptions{Size: 4096},
+			want:    KeyParams{Algorithm: v1alpha1.RSAPrivateKeyAlgorithm, Size: 4096},
+		},
+		{
+			name:    "algorithm override defaults to the size of the algorithm",
+			params:  KeyParams{Algorithm: v1alpha1.RSAPrivateKeyAlgorithm, Size: 4096},
+			options: &v1alpha1.PrivateKeyOptions{Algorithm: v1alpha1.ECDSAPrivateKeyAlgorithm},
+			want:    KeyParams{Algorithm: v1alpha1.ECDSAPrivateKeyAlgorithm, Size: DefaultECDSAKeySize},
+		},
+		{
+			name:    "same algorithm keeps the size",
+			params:  KeyParams{Algorithm: v1alpha1.RSAPrivateKeyAlgorithm, Size: 4096},
+			options: &v1alpha1.PrivateKeyOptions{Algorithm: v1alpha1.RSAPrivateKeyAlgorithm},
+			want:    KeyParams{Algorithm: v1alpha1.RSAPrivateKeyAlgorithm, Size: 4096},
+		},
+		{
+			name:    "algorithm and size override",
+			params:  DefaultKeyParams,
+			options: &v1alpha1.PrivateKeyOptions{Algorithm: v1alpha1.ECDSAPrivateKeyAlgorithm, Size: 384},
+			want:    KeyParams{Algorithm: v1alpha1.ECDSAPrivateKeyAlgorithm, Size: 384},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, tt.params.WithOverrides(tt.options))
+		})
+	}
+}
+
+func TestKeyParams_GenerateKey(t *testing.T) {
+	tests := []struct {
+		name    string
+		params  KeyParams
+		wantErr bool
+	}{
+		{
+			name:   "default RSA key",
+			params: DefaultKeyParams,
+		},
+		{
+			name:   "ECDSA P-256 key",
+			params: KeyParams{Algorithm: v1alpha1.ECDSAPrivateKeyAlgorithm, Size: 256},
+		},
+		{
+			name:   "ECDSA P-384 key",
+			params: KeyParams{Algorithm: v1alpha1.ECDSAPrivateKeyAlgorithm, Size: 384},
+		},
+		{
+			name:    "unsupported RSA size",
+			params:  KeyParams{Algorithm: v1alpha1.RSAPrivateKeyAlgorithm, Size: 1024},
+			wantErr: true,
+		},
+		{
+			name:    "unsupported ECDSA size",
+			params:  KeyParams{Algorithm: v1alpha1.ECDSAPrivateKeyAlgorithm, Size: 2048},
+			wantErr: true,
+		},
+		{
+			name:    "unsupported algorithm",
+			params:  KeyParams{Algorithm: "DSA", Size: 2048},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key, err := tt.params.GenerateKey()
+			if tt.wantErr {
+				require.Error(t, err)
+				require.Error(t, tt.params.Validate())
+				return
+			}
+			require.NoError(t, err)
+			require.True(t, tt.params.Matches(key.Public()))
+
+			// the key can be encoded and parsed back
+			pemKey, err := EncodePEMPrivateKey(key)
+			require.NoError(t, err)
+			parsed, err := ParsePEMPrivateKey(pemKey)
+			require.NoError(t, err)
+			require.True(t, PrivateMatchesPublicKey(key.Public(), parsed))
+		})
+	}
+}
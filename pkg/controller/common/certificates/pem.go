@@ -6,6 +6,8 @@ package certificates
 
 import (
 	"bytes"
+	"crypto"
+	"crypto/ecdsa"
 	"crypto/rsa"
 	"crypto/x509"
 	"encoding/pem"
@@ -48,22 +50,59 @@ func EncodePEMCert(certBlocks ...[]byte) []byte {
 	return buf.Bytes()
 }
 
-// EncodePEMPrivateKey encodes the given private key in the PEM format
-func EncodePEMPrivateKey(privateKey rsa.PrivateKey) []byte {
-	return pem.EncodeToMemory(&pem.Block{
-		Type:  "RSA PRIVATE KEY",
-		Bytes: x509.MarshalPKCS1PrivateKey(&privateKey),
-	})
+// EncodePEMPrivateKey encodes the given RSA or ECDSA private key in the PEM format
+func EncodePEMPrivateKey(privateKey crypto.Signer) ([]byte, error) {
+	switch key := privateKey.(type) {
+	case *rsa.PrivateKey:
+		return pem.EncodeToMemory(&pem.Block{
+			Type:  "RSA PRIVATE KEY",
+			Bytes: x509.MarshalPKCS1PrivateKey(key),
+		}), nil
+	case *ecdsa.PrivateKey:
+		keyBytes, err := x509.MarshalECPrivateKey(key)
+		if err != nil {
+			return nil, err
+		}
+		return pem.EncodeToMemory(&pem.Block{
+			Type:  "EC PRIVATE KEY",
+			Bytes: keyBytes,
+		}), nil
+	default:
+		return nil, errors.New("private key is neither an RSA nor an ECDSA private key")
+	}
 }
 
-// ParsePEMPrivateKey parses the given private key in the PEM format
-func ParsePEMPrivateKey(pemData []byte) (*rsa.PrivateKey, error) {
+// ParsePEMPrivateKey parses the given RSA or ECDSA private key in the PEM format, either PKCS#1, SEC 1 or PKCS#8 encoded
+func ParsePEMPrivateKey(pemData []byte) (crypto.Signer, error) {
 	block, _ := pem.Decode(pemData)
 	if block == nil {
 		return nil, errors.New("can't decode pem block")
 	}
-	if block.Type != "RSA PRIVATE KEY" || len(block.Headers) != 0 {
-		return nil, errors.New("pem block is not an RSA private key")
+	if len(block.Headers) != 0 {
+		return nil, errors.New("pem block is not a private key")
+	}
+	var key interface{}
+	var err error
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		key, err = x509.ParseECPrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	default:
+		return nil, errors.New("pem block is neither an RSA nor an ECDSA private key")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	switch key := key.(type) {
+	case *rsa.PrivateKey:
+		return key, nil
+	case *ecdsa.PrivateKey:
+		return key, nil
+	default:
+		return nil, errors.New("pem block is neither an RSA nor an ECDSA private key")
 	}
-	return x509.ParsePKCS1PrivateKey(block.Bytes)
 }
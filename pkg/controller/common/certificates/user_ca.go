@@ -0,0 +1,143 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package certificates
+
+import (
+	"crypto/x509"
+	"fmt"
+
+	"github.com/elastic/cloud-on-k8s/pkg/apis/common/v1alpha1"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/name"
+	"github.com/elastic/cloud-on-k8s/pkg/utils/k8s"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// ReconcileCA returns the CA to use to issue certificates of the given CAType for the owner.
+//
+// If caRef references a secret, the CA provided by the user in this secret is validated and returned. The secret is
+// expected to be watched by the caller, so that the certificates are re-issued when it changes.
+// Otherwise, a self-signed CA managed by the operator is reconciled (see ReconcileCAForOwner).
+// The key params only apply to the self-signed CA: the private key of a user-provided CA is used as is.
+func ReconcileCA(
+	cl k8s.Client,
+	scheme *runtime.Scheme,
+	namer name.Namer,
+	owner v1.Object,
+	labels map[string]string,
+	caType CAType,
+	caRef v1alpha1.SecretRef,
+	rotationParams RotationParams,
+	keyParams KeyParams,
+) (*CA, error) {
+	if caRef.SecretName == "" {
+		return ReconcileCAForOwner(cl, scheme, namer, owner, labels, caType, rotationParams, keyParams)
+	}
+
+	var userCASecret corev1.Secret
+	if err := cl.Get(types.NamespacedName{Namespace: owner.GetNamespace(), Name: caRef.SecretName}, &userCASecret); err != nil {
+		return nil, err
+	}
+	ca, err := BuildCAFromUserSecret(userCASecret)
+	if err != nil {
+		return nil, err
+	}
+	if !certIsValid(*ca.Cert, rotationParams.RotateBefore) {
+		log.Info(
+			"User-provided CA should be renewed",
+			"namespace", userCASecret.Namespace,
+			"secret_name", userCASecret.Name,
+			"ca_type", caType,
+		)
+	}
+	return ca, nil
+}
+
+// BuildCAFromUserSecret parses and validates the CA provided by the user in the given secret.
+//
+// The secret is expected to contain:
+//
+// - `tls.crt`: the CA certificate, optionally followed by the certificates of the authorities that issued it.
+// - `tls.key`: the CA private key.
+// - `ca.crt`: the certificates of the authorities that issued the CA certificate, up to the root (optional).
+func BuildCAFromUserSecret(secret corev1.Secret) (*CA, error) {
+	certs, err := ParsePEMCerts(secret.Data[CertFileName])
+	if err != nil {
+		return nil, errors.Wrapf(err, "cannot parse %s in secret %s/%s", CertFileName, secret.Namespace, secret.Name)
+	}
+	if len(certs) == 0 {
+		return nil, fmt.Errorf("can't find a certificate %s in %s/%s", CertFileName, secret.Namespace, secret.Name)
+	}
+
+	privateKeyBytes, exists := secret.Data[KeyFileName]
+	if !exists {
+		return nil, fmt.Errorf("can't find private key %s in %s/%s", KeyFileName, secret.Namespace, secret.Name)
+	}
+	privateKey, err := ParsePEMPrivateKey(privateKeyBytes)
+	if err != nil {
+		return nil, errors.Wrapf(err, "cannot parse %s in secret %s/%s", KeyFileName, secret.Namespace, secret.Name)
+	}
+
+	chain := certs[1:]
+	if caBytes, exists := secret.Data[CAFileName]; exists {
+		issuers, err := ParsePEMCerts(caBytes)
+		if err != nil {
+			return nil, errors.Wrapf(err, "cannot parse %s in secret %s/%s", CAFileName, secret.Namespace, secret.Name)
+		}
+		chain = appendMissingCerts(chain, issuers)
+	}
+
+	ca := NewCA(privateKey, certs[0])
+	ca.Chain = chain
+	if err := validateCA(ca); err != nil {
+		return nil, errors.Wrapf(err, "invalid certificate authority in secret %s/%s", secret.Namespace, secret.Name)
+	}
+	return ca, nil
+}
+
+// appendMissingCerts appends to certs the certificates from others that it does not already contain.
+func appendMissingCerts(certs []*x509.Certificate, others []*x509.Certificate) []*x509.Certificate {
+	for _, other := range others {
+		found := false
+		for _, cert := range certs {
+			if cert.Equal(other) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			certs = append(certs, other)
+		}
+	}
+	return certs
+}
+
+// validateCA returns an error if the given CA cannot be used to issue certificates.
+func validateCA(ca *CA) error {
+	if !ca.Cert.BasicConstraintsValid || !ca.Cert.IsCA {
+		return errors.New("certificate is not a certificate authority")
+	}
+	if ca.Cert.KeyUsage != 0 && ca.Cert.KeyUsage&x509.KeyUsageCertSign == 0 {
+		return errors.New("certificate is not allowed to sign certificates")
+	}
+	if !PrivateMatchesPublicKey(ca.Cert.PublicKey, ca.PrivateKey) {
+		return errors.New("private key does not match the certificate")
+	}
+	if !certIsValid(*ca.Cert, 0) {
+		return fmt.Errorf("certificate is expired or not valid yet (valid from %s to %s)", ca.Cert.NotBefore, ca.Cert.NotAfter)
+	}
+	// each certificate of the chain must be issued by the next one
+	issued := ca.Cert
+	for _, issuer := range ca.Chain {
+		if err := issued.CheckSignatureFrom(issuer); err != nil {
+			return errors.Wrapf(err, "certificate %s is not issued by %s", issued.Subject, issuer.Subject)
+		}
+		issued = issuer
+	}
+	return nil
+}
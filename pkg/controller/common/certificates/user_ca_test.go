@@ -0,0 +1,282 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package certificates
+
+import (
+	"crypto"
+	cryptorand "crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"testing"
+	"time"
+
+	commonv1alpha1 "github.com/elastic/cloud-on-k8s/pkg/apis/common/v1alpha1"
+	"github.com/elastic/cloud-on-k8s/pkg/apis/elasticsearch/v1alpha1"
+	"github.com/elastic/cloud-on-k8s/pkg/utils/k8s"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+// newTestIntermediateCA returns a CA issued by the given parent CA.
+func newTestIntermediateCA(t *testing.T, parent *CA, notAfter time.Time, keyUsage x509.KeyUsage) *CA {
+	privateKey, err := rsa.GenerateKey(cryptorand.Reader, 2048)
+	require.NoError(t, err)
+	certData, err := parent.CreateCertificate(ValidatedCertificateTemplate{
+		Subject:               pkix.Name{CommonName: "intermediate"},
+		NotBefore:             time.Now().Add(-1 * time.Minute),
+		NotAfter:              notAfter,
+		PublicKey:             privateKey.Public(),
+		SignatureAlgorithm:    x509.SHA256WithRSA,
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              keyUsage,
+	})
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(certData)
+	require.NoError(t, err)
+	ca := NewCA(privateKey, cert)
+	ca.Chain = append([]*x509.Certificate{parent.Cert}, parent.Chain...)
+	return ca
+}
+
+func mustEncodePEMPrivateKey(t *testing.T, privateKey crypto.Signer) []byte {
+	privateKeyData, err := EncodePEMPrivateKey(privateKey)
+	require.NoError(t, err)
+	return privateKeyData
+}
+
+func userCASecret(data map[string][]byte) corev1.Secret {
+	return corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: "user-ca"},
+		Data:       data,
+	}
+}
+
+func TestBuildCAFromUserSecret(t *testing.T) {
+	root, err := NewSelfSignedCA(CABuilderOptions{Subject: pkix.Name{CommonName: "root"}})
+	require.NoError(t, err)
+	otherRoot, err := NewSelfSignedCA(CABuilderOptions{Subject: pkix.Name{CommonName: "other-root"}})
+	require.NoError(t, err)
+	ecdsaKeyParams := KeyParams{Algorithm: commonv1alpha1.ECDSAPrivateKeyAlgorithm, Size: DefaultECDSAKeySize}
+	ecdsaRoot, err := NewSelfSignedCA(CABuilderOptions{Subject: pkix.Name{CommonName: "ecdsa-root"}, KeyParams: &ecdsaKeyParams})
+	require.NoError(t, err)
+	intermediate := newTestIntermediateCA(t, root, time.Now().Add(24*time.Hour), x509.KeyUsageCertSign)
+	expiredIntermediate := newTestIntermediateCA(t, root, time.Now().Add(-1*time.Second), x509.KeyUsageCertSign)
+	nonSigningIntermediate := newTestIntermediateCA(t, root, time.Now().Add(24*time.Hour), x509.KeyUsageDigitalSignature)
+
+	leafKey, err := rsa.GenerateKey(cryptorand.Reader, 2048)
+	require.NoError(t, err)
+	leafData, err := root.CreateCertificate(ValidatedCertificateTemplate{
+		Subject:            pkix.Name{CommonName: "leaf"},
+		NotBefore:          time.Now().Add(-1 * time.Minute),
+		NotAfter:           time.Now().Add(24 * time.Hour),
+		PublicKey:          leafKey.Public(),
+		SignatureAlgorithm: x509.SHA256WithRSA,
+	})
+	require.NoError(t, err)
+
+	pkcs8Key, err := x509.MarshalPKCS8PrivateKey(intermediate.PrivateKey)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name      string
+		data      map[string][]byte
+		wantErr   bool
+		wantCert  *x509.Certificate
+		wantChain []*x509.Certificate
+	}{
+		{
+			name:    "no certificate",
+			data:    map[string][]byte{KeyFileName: mustEncodePEMPrivateKey(t, root.PrivateKey)},
+			wantErr: true,
+		},
+		{
+			name:    "no private key",
+			data:    map[string][]byte{CertFileName: EncodePEMCert(root.Cert.Raw)},
+			wantErr: true,
+		},
+		{
+			name: "self-signed CA",
+			data: map[string][]byte{
+				CertFileName: EncodePEMCert(root.Cert.Raw),
+				KeyFileName:  mustEncodePEMPrivateKey(t, root.PrivateKey),
+			},
+			wantCert:  root.Cert,
+			wantChain: []*x509.Certificate{},
+		},
+		{
+			name: "self-signed ECDSA CA",
+			data: map[string][]byte{
+				CertFileName: EncodePEMCert(ecdsaRoot.Cert.Raw),
+				KeyFileName:  mustEncodePEMPrivateKey(t, ecdsaRoot.PrivateKey),
+			},
+			wantCert:  ecdsaRoot.Cert,
+			wantChain: []*x509.Certificate{},
+		},
+		{
+			name: "intermediate CA with the chain in tls.crt",
+			data: map[string][]byte{
+				CertFileName: EncodePEMCert(intermediate.Cert.Raw, root.Cert.Raw),
+				KeyFileName:  mustEncodePEMPrivateKey(t, intermediate.PrivateKey),
+			},
+			wantCert:  intermediate.Cert,
+			wantChain: []*x509.Certificate{root.Cert},
+		},
+		{
+			name: "intermediate CA with the chain in ca.crt and a PKCS#8 private key",
+			data: map[string][]byte{
+				CertFileName: EncodePEMCert(intermediate.Cert.Raw),
+				KeyFileName:  pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: pkcs8Key}),
+				CAFileName:   EncodePEMCert(root.Cert.Raw),
+			},
+			wantCert:  intermediate.Cert,
+			wantChain: []*x509.Certificate{root.Cert},
+		},
+		{
+			name: "chain duplicated in tls.crt and ca.crt",
+			data: map[string][]byte{
+				CertFileName: EncodePEMCert(intermediate.Cert.Raw, root.Cert.Raw),
+				KeyFileName:  mustEncodePEMPrivateKey(t, intermediate.PrivateKey),
+				CAFileName:   EncodePEMCert(root.Cert.Raw),
+			},
+			wantCert:  intermediate.Cert,
+			wantChain: []*x509.Certificate{root.Cert},
+		},
+		{
+			name: "chain does not match the CA",
+			data: map[string][]byte{
+				CertFileName: EncodePEMCert(intermediate.Cert.Raw),
+				KeyFileName:  mustEncodePEMPrivateKey(t, intermediate.PrivateKey),
+				CAFileName:   EncodePEMCert(otherRoot.Cert.Raw),
+			},
+			wantErr: true,
+		},
+		{
+			name: "private key does not match the CA",
+			data: map[string][]byte{
+				CertFileName: EncodePEMCert(intermediate.Cert.Raw),
+				KeyFileName:  mustEncodePEMPrivateKey(t, root.PrivateKey),
+			},
+			wantErr: true,
+		},
+		{
+			name: "certificate is not a CA",
+			data: map[string][]byte{
+				CertFileName: EncodePEMCert(leafData),
+				KeyFileName:  mustEncodePEMPrivateKey(t, leafKey),
+			},
+			wantErr: true,
+		},
+		{
+			name: "CA is not allowed to sign certificates",
+			data: map[string][]byte{
+				CertFileName: EncodePEMCert(nonSigningIntermediate.Cert.Raw),
+				KeyFileName:  mustEncodePEMPrivateKey(t, nonSigningIntermediate.PrivateKey),
+			},
+			wantErr: true,
+		},
+		{
+			name: "CA is expired",
+			data: map[string][]byte{
+				CertFileName: EncodePEMCert(expiredIntermediate.Cert.Raw),
+				KeyFileName:  mustEncodePEMPrivateKey(t, expiredIntermediate.PrivateKey),
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ca, err := BuildCAFromUserSecret(userCASecret(tt.data))
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.True(t, tt.wantCert.Equal(ca.Cert))
+			require.Equal(t, tt.wantChain, ca.Chain)
+		})
+	}
+}
+
+func TestCA_PEMChain(t *testing.T) {
+	root, err := NewSelfSignedCA(CABuilderOptions{Subject: pkix.Name{CommonName: "root"}})
+	require.NoError(t, err)
+	require.Equal(t, EncodePEMCert(root.Cert.Raw), root.PEMChain())
+
+	intermediate := newTestIntermediateCA(t, root, time.Now().Add(24*time.Hour), x509.KeyUsageCertSign)
+	require.Equal(t, EncodePEMCert(intermediate.Cert.Raw, root.Cert.Raw), intermediate.PEMChain())
+}
+
+func TestReconcileCA(t *testing.T) {
+	require.NoError(t, v1alpha1.AddToScheme(scheme.Scheme))
+
+	root, err := NewSelfSignedCA(CABuilderOptions{Subject: pkix.Name{CommonName: "root"}})
+	require.NoError(t, err)
+	intermediate := newTestIntermediateCA(t, root, time.Now().Add(24*time.Hour), x509.KeyUsageCertSign)
+	validSecret := userCASecret(map[string][]byte{
+		CertFileName: EncodePEMCert(intermediate.Cert.Raw),
+		KeyFileName:  mustEncodePEMPrivateKey(t, intermediate.PrivateKey),
+		CAFileName:   EncodePEMCert(root.Cert.Raw),
+	})
+	invalidSecret := userCASecret(map[string][]byte{
+		CertFileName: EncodePEMCert(intermediate.Cert.Raw),
+		KeyFileName:  mustEncodePEMPrivateKey(t, root.PrivateKey),
+	})
+	rotation := RotationParams{Validity: DefaultCertValidity, RotateBefore: DefaultRotateBefore}
+
+	tests := []struct {
+		name    string
+		cl      k8s.Client
+		caRef   commonv1alpha1.SecretRef
+		wantCA  *CA
+		wantErr bool
+	}{
+		{
+			name:  "no user-provided CA: a self-signed CA is reconciled",
+			cl:    k8s.WrapClient(fake.NewFakeClient()),
+			caRef: commonv1alpha1.SecretRef{},
+		},
+		{
+			name:    "user-provided CA secret does not exist",
+			cl:      k8s.WrapClient(fake.NewFakeClient()),
+			caRef:   commonv1alpha1.SecretRef{SecretName: "user-ca"},
+			wantErr: true,
+		},
+		{
+			name:    "user-provided CA is invalid",
+			cl:      k8s.WrapClient(fake.NewFakeClient(&invalidSecret)),
+			caRef:   commonv1alpha1.SecretRef{SecretName: "user-ca"},
+			wantErr: true,
+		},
+		{
+			name:   "user-provided CA is valid",
+			cl:     k8s.WrapClient(fake.NewFakeClient(&validSecret)),
+			caRef:  commonv1alpha1.SecretRef{SecretName: "user-ca"},
+			wantCA: intermediate,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ca, err := ReconcileCA(tt.cl, scheme.Scheme, testNamer, &testCluster, nil, TransportCAType, tt.caRef, rotation, DefaultKeyParams)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			if tt.wantCA == nil {
+				// the self-signed CA should be persisted in the internal secret
+				checkCASecrets(t, tt.cl, testCluster, TransportCAType, ca, nil, nil, DefaultCertValidity)
+				return
+			}
+			require.True(t, tt.wantCA.Cert.Equal(ca.Cert))
+			require.Equal(t, tt.wantCA.Chain, ca.Chain)
+		})
+	}
+}
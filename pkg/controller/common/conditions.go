@@ -0,0 +1,71 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package common
+
+import (
+	"fmt"
+
+	commonv1alpha1 "github.com/elastic/cloud-on-k8s/pkg/apis/common/v1alpha1"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+const (
+	// DeploymentAvailableReason is the reason of the Ready condition when the Deployment is available.
+	DeploymentAvailableReason = "DeploymentAvailable"
+	// DeploymentUnavailableReason is the reason of the Ready condition when the Deployment is not available.
+	DeploymentUnavailableReason = "DeploymentUnavailable"
+	// PodsRestartingReason is the reason of the UpgradeInProgress condition when the pods of the Deployment are
+	// being replaced.
+	PodsRestartingReason = "PodsRestarting"
+	// UpgradeCompletedReason is the reason of the UpgradeInProgress condition when all the pods are up-to-date.
+	UpgradeCompletedReason = "UpgradeCompleted"
+	// ValidationFailedReason is the reason of the Invalid condition when the specification is invalid.
+	ValidationFailedReason = "ValidationFailed"
+	// ValidationPassedReason is the reason of the Invalid condition when the specification is valid.
+	ValidationPassedReason = "ValidationPassed"
+)
+
+// DeploymentReadyCondition returns the Ready condition of a resource running the pods of the given Deployment.
+func DeploymentReadyCondition(deployment appsv1.Deployment) commonv1alpha1.Condition {
+	for _, c := range deployment.Status.Conditions {
+		if c.Type == appsv1.DeploymentAvailable && c.Status == corev1.ConditionTrue {
+			return commonv1alpha1.NewCondition(commonv1alpha1.ReadyCondition, true, DeploymentAvailableReason, "")
+		}
+	}
+	return commonv1alpha1.NewCondition(
+		commonv1alpha1.ReadyCondition, false, DeploymentUnavailableReason,
+		fmt.Sprintf("%d available pods", deployment.Status.AvailableReplicas),
+	)
+}
+
+// DeploymentUpgradeCondition returns the UpgradeInProgress condition of a resource running the pods of the given
+// Deployment: an upgrade is in progress until the Deployment controller observed the latest specification and
+// replaced all the pods.
+func DeploymentUpgradeCondition(deployment appsv1.Deployment) commonv1alpha1.Condition {
+	replicas := int32(1)
+	if deployment.Spec.Replicas != nil {
+		replicas = *deployment.Spec.Replicas
+	}
+	status := deployment.Status
+	if status.ObservedGeneration < deployment.Generation ||
+		status.UpdatedReplicas < replicas ||
+		status.Replicas > status.UpdatedReplicas {
+		return commonv1alpha1.NewCondition(
+			commonv1alpha1.UpgradeInProgressCondition, true, PodsRestartingReason,
+			fmt.Sprintf("%d/%d pods updated", status.UpdatedReplicas, replicas),
+		)
+	}
+	return commonv1alpha1.NewCondition(commonv1alpha1.UpgradeInProgressCondition, false, UpgradeCompletedReason, "")
+}
+
+// InvalidCondition returns the Invalid condition matching the given validation error, which is nil if the
+// specification is valid.
+func InvalidCondition(err error) commonv1alpha1.Condition {
+	if err != nil {
+		return commonv1alpha1.NewCondition(commonv1alpha1.InvalidCondition, true, ValidationFailedReason, err.Error())
+	}
+	return commonv1alpha1.NewCondition(commonv1alpha1.InvalidCondition, false, ValidationPassedReason, "")
+}
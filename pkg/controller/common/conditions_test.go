@@ -0,0 +1,99 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package common
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func int32Ptr(i int32) *int32 {
+	return &i
+}
+
+func TestDeploymentReadyCondition(t *testing.T) {
+	tests := []struct {
+		name       string
+		deployment appsv1.Deployment
+		want       corev1.ConditionStatus
+	}{
+		{
+			name: "available",
+			deployment: appsv1.Deployment{Status: appsv1.DeploymentStatus{
+				Conditions: []appsv1.DeploymentCondition{{Type: appsv1.DeploymentAvailable, Status: corev1.ConditionTrue}},
+			}},
+			want: corev1.ConditionTrue,
+		},
+		{
+			name: "not available",
+			deployment: appsv1.Deployment{Status: appsv1.DeploymentStatus{
+				Conditions: []appsv1.DeploymentCondition{{Type: appsv1.DeploymentAvailable, Status: corev1.ConditionFalse}},
+			}},
+			want: corev1.ConditionFalse,
+		},
+		{
+			name:       "no condition yet",
+			deployment: appsv1.Deployment{},
+			want:       corev1.ConditionFalse,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, DeploymentReadyCondition(tt.deployment).Status)
+		})
+	}
+}
+
+func TestDeploymentUpgradeCondition(t *testing.T) {
+	tests := []struct {
+		name       string
+		deployment appsv1.Deployment
+		want       corev1.ConditionStatus
+	}{
+		{
+			name: "all pods updated",
+			deployment: appsv1.Deployment{
+				ObjectMeta: metav1.ObjectMeta{Generation: 2},
+				Spec:       appsv1.DeploymentSpec{Replicas: int32Ptr(2)},
+				Status:     appsv1.DeploymentStatus{ObservedGeneration: 2, Replicas: 2, UpdatedReplicas: 2},
+			},
+			want: corev1.ConditionFalse,
+		},
+		{
+			name: "new specification not observed yet",
+			deployment: appsv1.Deployment{
+				ObjectMeta: metav1.ObjectMeta{Generation: 3},
+				Spec:       appsv1.DeploymentSpec{Replicas: int32Ptr(2)},
+				Status:     appsv1.DeploymentStatus{ObservedGeneration: 2, Replicas: 2, UpdatedReplicas: 2},
+			},
+			want: corev1.ConditionTrue,
+		},
+		{
+			name: "pods being replaced",
+			deployment: appsv1.Deployment{
+				ObjectMeta: metav1.ObjectMeta{Generation: 2},
+				Spec:       appsv1.DeploymentSpec{Replicas: int32Ptr(2)},
+				Status:     appsv1.DeploymentStatus{ObservedGeneration: 2, Replicas: 3, UpdatedReplicas: 2},
+			},
+			want: corev1.ConditionTrue,
+		},
+		{
+			name: "default replicas",
+			deployment: appsv1.Deployment{
+				Status: appsv1.DeploymentStatus{Replicas: 1, UpdatedReplicas: 1},
+			},
+			want: corev1.ConditionFalse,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, DeploymentUpgradeCondition(tt.deployment).Status)
+		})
+	}
+}
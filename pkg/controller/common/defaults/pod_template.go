@@ -9,6 +9,7 @@ import (
 
 	corev1 "k8s.io/api/core/v1"
 
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/heap"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/settings"
 )
 
@@ -211,6 +212,13 @@ func (b *PodTemplateBuilder) WithEnv(vars ...corev1.EnvVar) *PodTemplateBuilder
 	return b
 }
 
+// WithHeapSize sets the heap size of the main container to the given percentage of its memory limit, capped to maxMi
+// if positive, unless the heap size is already specified.
+func (b *PodTemplateBuilder) WithHeapSize(opts heap.Options, percentage int, maxMi int64) *PodTemplateBuilder {
+	heap.SetDefault(b.Container, opts, percentage, maxMi)
+	return b
+}
+
 // WithTerminationGracePeriod sets the given termination grace period if not already specified in the template.
 func (b *PodTemplateBuilder) WithTerminationGracePeriod(period int64) *PodTemplateBuilder {
 	if b.PodTemplate.Spec.TerminationGracePeriodSeconds == nil {
@@ -219,6 +227,14 @@ func (b *PodTemplateBuilder) WithTerminationGracePeriod(period int64) *PodTempla
 	return b
 }
 
+// WithSecurityContext sets the given pod security context if not already specified in the template.
+func (b *PodTemplateBuilder) WithSecurityContext(securityContext *corev1.PodSecurityContext) *PodTemplateBuilder {
+	if b.PodTemplate.Spec.SecurityContext == nil {
+		b.PodTemplate.Spec.SecurityContext = securityContext
+	}
+	return b
+}
+
 // findVolumeMountByNameOrMountPath attempts to find a volume mount with the given name or mount path in the mounts
 // Returns the index of the volume mount or -1 if no volume mount by that name was found.
 func (b *PodTemplateBuilder) findVolumeMountByNameOrMountPath(
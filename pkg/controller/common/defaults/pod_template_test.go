@@ -314,6 +314,44 @@ func TestPodTemplateBuilder_WithAffinity(t *testing.T) {
 	}
 }
 
+func TestPodTemplateBuilder_WithSecurityContext(t *testing.T) {
+	runAsUser := int64(1000)
+	defaultSecurityContext := &corev1.PodSecurityContext{RunAsUser: &runAsUser}
+
+	containerName := "mycontainer"
+	tests := []struct {
+		name            string
+		PodTemplate     corev1.PodTemplateSpec
+		securityContext *corev1.PodSecurityContext
+		want            *corev1.PodSecurityContext
+	}{
+		{
+			name:            "set default security context",
+			PodTemplate:     corev1.PodTemplateSpec{},
+			securityContext: defaultSecurityContext,
+			want:            defaultSecurityContext,
+		},
+		{
+			name: "don't override user-provided security context",
+			PodTemplate: corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					SecurityContext: &corev1.PodSecurityContext{},
+				},
+			},
+			securityContext: defaultSecurityContext,
+			want:            &corev1.PodSecurityContext{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := NewPodTemplateBuilder(tt.PodTemplate, containerName)
+			if got := b.WithSecurityContext(tt.securityContext).PodTemplate.Spec.SecurityContext; !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("PodTemplateBuilder.WithSecurityContext() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
 func TestPodTemplateBuilder_WithPorts(t *testing.T) {
 	containerName := "mycontainer"
 	tests := []struct {
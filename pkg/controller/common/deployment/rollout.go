@@ -0,0 +1,58 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package deployment
+
+import (
+	"time"
+
+	"github.com/elastic/cloud-on-k8s/pkg/utils/k8s"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// RolledOutSince returns true if all the pods of the given Deployment run its latest pod template, and were created
+// at or after the given time. Checking the creation time of the pods makes sure a change made at that time is
+// rolled out, even if the Deployment is not updated yet. A Deployment that does not exist is considered rolled out.
+func RolledOutSince(c k8s.Client, key types.NamespacedName, since time.Time) (bool, error) {
+	var deployment appsv1.Deployment
+	err := c.Get(key, &deployment)
+	if errors.IsNotFound(err) {
+		return true, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	replicas := int32(1)
+	if deployment.Spec.Replicas != nil {
+		replicas = *deployment.Spec.Replicas
+	}
+	if deployment.Status.ObservedGeneration < deployment.Generation ||
+		deployment.Status.Replicas != replicas ||
+		deployment.Status.UpdatedReplicas != replicas ||
+		deployment.Status.AvailableReplicas != replicas {
+		return false, nil
+	}
+
+	selector, err := metav1.LabelSelectorAsSelector(deployment.Spec.Selector)
+	if err != nil {
+		return false, err
+	}
+	var pods corev1.PodList
+	if err := c.List(&client.ListOptions{Namespace: key.Namespace, LabelSelector: selector}, &pods); err != nil {
+		return false, err
+	}
+	for _, pod := range pods.Items {
+		// terminating pods are on their way out
+		if pod.DeletionTimestamp == nil && pod.CreationTimestamp.Time.Before(since) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
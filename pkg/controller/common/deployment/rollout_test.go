@@ -0,0 +1,97 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package deployment
+
+import (
+	"testing"
+	"time"
+
+	"github.com/elastic/cloud-on-k8s/pkg/utils/k8s"
+	"github.com/stretchr/testify/require"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestRolledOutSince(t *testing.T) {
+	since := time.Date(2019, 10, 1, 12, 0, 0, 0, time.UTC)
+	replicas := int32(2)
+	deployment := func(updatedReplicas int32) *appsv1.Deployment {
+		return &appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "kb"},
+			Spec: appsv1.DeploymentSpec{
+				Replicas: &replicas,
+				Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "kb"}},
+			},
+			Status: appsv1.DeploymentStatus{
+				Replicas:          replicas,
+				UpdatedReplicas:   updatedReplicas,
+				AvailableReplicas: replicas,
+			},
+		}
+	}
+	pod := func(name string, created time.Time, terminating bool) *corev1.Pod {
+		p := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{
+			Namespace:         "ns",
+			Name:              name,
+			Labels:            map[string]string{"app": "kb"},
+			CreationTimestamp: metav1.NewTime(created),
+		}}
+		if terminating {
+			p.DeletionTimestamp = &metav1.Time{Time: since}
+		}
+		return p
+	}
+
+	tests := []struct {
+		name           string
+		initialObjects []runtime.Object
+		want           bool
+	}{
+		{
+			name: "no deployment",
+			want: true,
+		},
+		{
+			name: "rollout in progress",
+			initialObjects: []runtime.Object{
+				deployment(1),
+				pod("new", since.Add(time.Minute), false),
+				pod("old", since.Add(-time.Minute), false),
+			},
+			want: false,
+		},
+		{
+			name: "deployment not updated yet: pods older than the change",
+			initialObjects: []runtime.Object{
+				deployment(2),
+				pod("old-1", since.Add(-time.Minute), false),
+				pod("old-2", since.Add(-time.Minute), false),
+			},
+			want: false,
+		},
+		{
+			name: "rolled out, terminating pods are ignored",
+			initialObjects: []runtime.Object{
+				deployment(2),
+				pod("new-1", since.Add(time.Minute), false),
+				pod("new-2", since, false),
+				pod("old", since.Add(-time.Minute), true),
+			},
+			want: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := k8s.WrapClient(fake.NewFakeClient(tt.initialObjects...))
+			got, err := RolledOutSince(c, types.NamespacedName{Namespace: "ns", Name: "kb"}, since)
+			require.NoError(t, err)
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
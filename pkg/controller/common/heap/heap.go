@@ -0,0 +1,100 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package heap
+
+import (
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+const (
+	// DefaultMemoryPercentage is the default percentage of the container memory limit used as heap size. The heap size
+	// is not derived from the memory limit by default: doing so would change the pods of existing clusters, and restart
+	// them when the operator is upgraded.
+	DefaultMemoryPercentage = 0
+
+	mebibyte = 1024 * 1024
+)
+
+// MemoryLimitMi returns the memory limit of the container in MiB, or 0 if not set.
+func MemoryLimitMi(c corev1.Container) int64 {
+	limit, exists := c.Resources.Limits[corev1.ResourceMemory]
+	if !exists {
+		return 0
+	}
+	return limit.Value() / mebibyte
+}
+
+// FromMemoryLimit returns the given percentage of the memory limit of the container in MiB, capped to maxMi if positive.
+// It returns 0 if the container has no memory limit or if the percentage is not positive.
+func FromMemoryLimit(c corev1.Container, percentage int, maxMi int64) int64 {
+	limitMi := MemoryLimitMi(c)
+	if limitMi == 0 || percentage <= 0 {
+		return 0
+	}
+	sizeMi := limitMi * int64(percentage) / 100
+	if maxMi > 0 && sizeMi > maxMi {
+		sizeMi = maxMi
+	}
+	return sizeMi
+}
+
+// Options describes how the heap size is specified in an environment variable of the container.
+type Options struct {
+	// EnvVarName is the name of the environment variable containing the heap size options.
+	EnvVarName string
+	// Parse returns the maximum heap size in MiB specified in the value of the environment variable, if any.
+	Parse func(value string) (int64, bool)
+	// Format returns the options setting the given heap size in MiB.
+	Format func(sizeMi int64) string
+}
+
+// SetDefault sets the heap size of the container to the given percentage of its memory limit, capped to maxMi if
+// positive. The options are appended to the environment variable, unless it already specifies a heap size, comes from
+// a source the operator cannot inspect, or the container has no memory limit.
+func SetDefault(c *corev1.Container, opts Options, percentage int, maxMi int64) {
+	sizeMi := FromMemoryLimit(*c, percentage, maxMi)
+	if sizeMi == 0 {
+		return
+	}
+	for i, env := range c.Env {
+		if env.Name != opts.EnvVarName {
+			continue
+		}
+		if env.ValueFrom != nil {
+			return
+		}
+		if _, specified := opts.Parse(env.Value); specified {
+			return
+		}
+		c.Env[i].Value = strings.TrimSpace(strings.Join([]string{env.Value, opts.Format(sizeMi)}, " "))
+		return
+	}
+	c.Env = append(c.Env, corev1.EnvVar{Name: opts.EnvVarName, Value: opts.Format(sizeMi)})
+}
+
+// ExceedsMemoryLimit returns a message describing the issue if the heap size specified in the environment variable of
+// the container is not below its memory limit, leaving no room for the rest of the process, or an empty string otherwise.
+func ExceedsMemoryLimit(c corev1.Container, opts Options) string {
+	limitMi := MemoryLimitMi(c)
+	if limitMi == 0 {
+		return ""
+	}
+	for _, env := range c.Env {
+		if env.Name != opts.EnvVarName {
+			continue
+		}
+		sizeMi, specified := opts.Parse(env.Value)
+		if specified && sizeMi >= limitMi {
+			return fmt.Sprintf(
+				"Heap size of %dMi set in %s of container %s does not fit in its memory limit of %dMi",
+				sizeMi, opts.EnvVarName, c.Name, limitMi,
+			)
+		}
+	}
+	return ""
+}
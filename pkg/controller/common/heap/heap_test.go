@@ -0,0 +1,167 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package heap
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// testOptions specify the heap size as "heap=<size>"
+var testOptions = Options{
+	EnvVarName: "OPTS",
+	Parse: func(value string) (int64, bool) {
+		for _, opt := range strings.Fields(value) {
+			if strings.HasPrefix(opt, "heap=") {
+				size, _ := strconv.ParseInt(strings.TrimPrefix(opt, "heap="), 10, 64)
+				return size, true
+			}
+		}
+		return 0, false
+	},
+	Format: func(sizeMi int64) string {
+		return fmt.Sprintf("heap=%d", sizeMi)
+	},
+}
+
+func containerWithLimit(limit string, env ...corev1.EnvVar) corev1.Container {
+	c := corev1.Container{Name: "main", Env: env}
+	if limit != "" {
+		c.Resources.Limits = corev1.ResourceList{corev1.ResourceMemory: resource.MustParse(limit)}
+	}
+	return c
+}
+
+func TestFromMemoryLimit(t *testing.T) {
+	tests := []struct {
+		name       string
+		container  corev1.Container
+		percentage int
+		maxMi      int64
+		want       int64
+	}{
+		{
+			name:       "no memory limit",
+			container:  containerWithLimit(""),
+			percentage: 50,
+			want:       0,
+		},
+		{
+			name:       "percentage disabled",
+			container:  containerWithLimit("4Gi"),
+			percentage: 0,
+			want:       0,
+		},
+		{
+			name:       "percentage of the memory limit",
+			container:  containerWithLimit("4Gi"),
+			percentage: 50,
+			want:       2048,
+		},
+		{
+			name:       "decimal memory limit",
+			container:  containerWithLimit("2G"),
+			percentage: 50,
+			want:       953,
+		},
+		{
+			name:       "capped",
+			container:  containerWithLimit("128Gi"),
+			percentage: 50,
+			maxMi:      31 * 1024,
+			want:       31 * 1024,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, FromMemoryLimit(tt.container, tt.percentage, tt.maxMi))
+		})
+	}
+}
+
+func TestSetDefault(t *testing.T) {
+	tests := []struct {
+		name      string
+		container corev1.Container
+		wantEnv   []corev1.EnvVar
+	}{
+		{
+			name:      "no memory limit: no heap size",
+			container: containerWithLimit(""),
+		},
+		{
+			name:      "memory limit: add the env var",
+			container: containerWithLimit("1Gi", corev1.EnvVar{Name: "OTHER", Value: "value"}),
+			wantEnv:   []corev1.EnvVar{{Name: "OTHER", Value: "value"}, {Name: "OPTS", Value: "heap=512"}},
+		},
+		{
+			name:      "memory limit and other options: append the heap size",
+			container: containerWithLimit("1Gi", corev1.EnvVar{Name: "OPTS", Value: "other=true"}),
+			wantEnv:   []corev1.EnvVar{{Name: "OPTS", Value: "other=true heap=512"}},
+		},
+		{
+			name:      "heap size specified by the user: unchanged",
+			container: containerWithLimit("1Gi", corev1.EnvVar{Name: "OPTS", Value: "heap=100"}),
+			wantEnv:   []corev1.EnvVar{{Name: "OPTS", Value: "heap=100"}},
+		},
+		{
+			name: "env var from a source: unchanged",
+			container: containerWithLimit("1Gi", corev1.EnvVar{Name: "OPTS", ValueFrom: &corev1.EnvVarSource{
+				ConfigMapKeyRef: &corev1.ConfigMapKeySelector{Key: "opts"},
+			}}),
+			wantEnv: []corev1.EnvVar{{Name: "OPTS", ValueFrom: &corev1.EnvVarSource{
+				ConfigMapKeyRef: &corev1.ConfigMapKeySelector{Key: "opts"},
+			}}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			SetDefault(&tt.container, testOptions, 50, 0)
+			assert.Equal(t, tt.wantEnv, tt.container.Env)
+		})
+	}
+}
+
+func TestExceedsMemoryLimit(t *testing.T) {
+	tests := []struct {
+		name      string
+		container corev1.Container
+		want      string
+	}{
+		{
+			name:      "no memory limit",
+			container: containerWithLimit("", corev1.EnvVar{Name: "OPTS", Value: "heap=4096"}),
+		},
+		{
+			name:      "no heap size",
+			container: containerWithLimit("1Gi"),
+		},
+		{
+			name:      "heap size below the memory limit",
+			container: containerWithLimit("1Gi", corev1.EnvVar{Name: "OPTS", Value: "heap=512"}),
+		},
+		{
+			name:      "heap size equal to the memory limit",
+			container: containerWithLimit("1Gi", corev1.EnvVar{Name: "OPTS", Value: "heap=1024"}),
+			want:      "Heap size of 1024Mi set in OPTS of container main does not fit in its memory limit of 1024Mi",
+		},
+		{
+			name:      "heap size above the memory limit",
+			container: containerWithLimit("1Gi", corev1.EnvVar{Name: "OPTS", Value: "heap=2048"}),
+			want:      "Heap size of 2048Mi set in OPTS of container main does not fit in its memory limit of 1024Mi",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, ExceedsMemoryLimit(tt.container, testOptions))
+		})
+	}
+}
@@ -0,0 +1,176 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package ingress
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/elastic/cloud-on-k8s/pkg/apis/common/v1alpha1"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/certificates"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/certificates/http"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/defaults"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/name"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/reconciler"
+	"github.com/elastic/cloud-on-k8s/pkg/utils/k8s"
+	"github.com/elastic/cloud-on-k8s/pkg/utils/maps"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/api/extensions/v1beta1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	logf "sigs.k8s.io/controller-runtime/pkg/runtime/log"
+)
+
+var log = logf.Log.WithName("ingress")
+
+const (
+	// BackendProtocolAnnotation is the NGINX ingress controller annotation setting the protocol used to reach the service.
+	BackendProtocolAnnotation = "nginx.ingress.kubernetes.io/backend-protocol"
+	// ProxySSLSecretAnnotation is the NGINX ingress controller annotation referencing the secret containing the CA
+	// used to verify the certificate of the service.
+	ProxySSLSecretAnnotation = "nginx.ingress.kubernetes.io/proxy-ssl-secret"
+	// ProxySSLVerifyAnnotation is the NGINX ingress controller annotation enabling the verification of the
+	// certificate of the service.
+	ProxySSLVerifyAnnotation = "nginx.ingress.kubernetes.io/proxy-ssl-verify"
+	// ProxySSLNameAnnotation is the NGINX ingress controller annotation setting the name used to verify the
+	// certificate of the service.
+	ProxySSLNameAnnotation = "nginx.ingress.kubernetes.io/proxy-ssl-name"
+)
+
+// Reconcile ensures an Ingress exposing the given service exists if the HTTP configuration specifies one, as well as
+// an OpenShift Route if that API is available. Both are deleted if the HTTP configuration does not specify an ingress.
+//
+// If TLS is enabled, traffic is re-encrypted between the ingress controller and the service, and the certificate of
+// the service is verified against the CA of the public HTTP certificates secret.
+func Reconcile(
+	c k8s.Client,
+	scheme *runtime.Scheme,
+	owner metav1.Object,
+	namer name.Namer,
+	config v1alpha1.HTTPConfig,
+	svc corev1.Service,
+	labels map[string]string,
+) error {
+	if config.Ingress == nil {
+		return deleteIngressAndRoute(c, owner, svc)
+	}
+
+	if err := reconcileIngress(c, scheme, owner, newIngress(owner, namer, config, svc, labels)); err != nil {
+		return err
+	}
+
+	var caCert []byte
+	if config.TLS.Enabled() {
+		var publicCerts corev1.Secret
+		err := c.Get(http.PublicCertsSecretRef(namer, k8s.ExtractNamespacedName(owner)), &publicCerts)
+		if err != nil && !errors.IsNotFound(err) {
+			return err
+		}
+		caCert = publicCerts.Data[certificates.CAFileName]
+	}
+	return reconcileRoute(c, scheme, owner, newRoute(config, svc, labels, caCert))
+}
+
+// newIngress returns the Ingress routing the host and path of the ingress template to the given service.
+func newIngress(
+	owner metav1.Object,
+	namer name.Namer,
+	config v1alpha1.HTTPConfig,
+	svc corev1.Service,
+	labels map[string]string,
+) *v1beta1.Ingress {
+	template := config.Ingress.DeepCopy()
+	objectMeta := template.ObjectMeta
+	objectMeta.Name = svc.Name
+	objectMeta.Namespace = svc.Namespace
+	objectMeta.Labels = defaults.SetDefaultLabels(objectMeta.Labels, labels)
+
+	if config.TLS.Enabled() {
+		publicCerts := http.PublicCertsSecretRef(namer, k8s.ExtractNamespacedName(owner))
+		// user provided annotations take precedence over the defaults
+		objectMeta.Annotations = maps.Merge(map[string]string{
+			BackendProtocolAnnotation: "HTTPS",
+			ProxySSLSecretAnnotation:  fmt.Sprintf("%s/%s", publicCerts.Namespace, publicCerts.Name),
+			ProxySSLVerifyAnnotation:  "on",
+			ProxySSLNameAnnotation:    k8s.GetServiceDNSName(svc)[0],
+		}, objectMeta.Annotations)
+	}
+
+	path := template.Path
+	if path == "" {
+		path = v1alpha1.DefaultIngressPath
+	}
+
+	return &v1beta1.Ingress{
+		ObjectMeta: objectMeta,
+		Spec: v1beta1.IngressSpec{
+			TLS: []v1beta1.IngressTLS{
+				{
+					Hosts:      []string{template.Host},
+					SecretName: template.SecretName,
+				},
+			},
+			Rules: []v1beta1.IngressRule{
+				{
+					Host: template.Host,
+					IngressRuleValue: v1beta1.IngressRuleValue{
+						HTTP: &v1beta1.HTTPIngressRuleValue{
+							Paths: []v1beta1.HTTPIngressPath{
+								{
+									Path: path,
+									Backend: v1beta1.IngressBackend{
+										ServiceName: svc.Name,
+										ServicePort: intstr.FromInt(int(svc.Spec.Ports[0].Port)),
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func reconcileIngress(c k8s.Client, scheme *runtime.Scheme, owner metav1.Object, expected *v1beta1.Ingress) error {
+	reconciled := &v1beta1.Ingress{}
+	return reconciler.ReconcileResource(reconciler.Params{
+		Client:     c,
+		Scheme:     scheme,
+		Owner:      owner,
+		Expected:   expected,
+		Reconciled: reconciled,
+		NeedsUpdate: func() bool {
+			return !maps.IsSubset(expected.Labels, reconciled.Labels) ||
+				!maps.IsSubset(expected.Annotations, reconciled.Annotations) ||
+				!reflect.DeepEqual(expected.Spec, reconciled.Spec)
+		},
+		UpdateReconciled: func() {
+			reconciled.Labels = maps.Merge(reconciled.Labels, expected.Labels)
+			reconciled.Annotations = maps.Merge(reconciled.Annotations, expected.Annotations)
+			reconciled.Spec = expected.Spec
+		},
+	})
+}
+
+// deleteIngressAndRoute deletes the Ingress and the Route of the given service, if they are controlled by the owner.
+func deleteIngressAndRoute(c k8s.Client, owner metav1.Object, svc corev1.Service) error {
+	nsn := types.NamespacedName{Namespace: svc.Namespace, Name: svc.Name}
+	var ingress v1beta1.Ingress
+	err := c.Get(nsn, &ingress)
+	if err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+	if err == nil && metav1.IsControlledBy(&ingress, owner) {
+		log.Info("Deleting ingress", "namespace", ingress.Namespace, "ingress_name", ingress.Name)
+		if err := c.Delete(&ingress); err != nil && !errors.IsNotFound(err) {
+			return err
+		}
+	}
+	return deleteRoute(c, owner, nsn)
+}
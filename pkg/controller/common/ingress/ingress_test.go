@@ -0,0 +1,384 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package ingress
+
+import (
+	"testing"
+
+	commonv1alpha1 "github.com/elastic/cloud-on-k8s/pkg/apis/common/v1alpha1"
+	"github.com/elastic/cloud-on-k8s/pkg/apis/kibana/v1alpha1"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/name"
+	"github.com/elastic/cloud-on-k8s/pkg/utils/k8s"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/api/extensions/v1beta1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+var (
+	testNamer = name.NewNamer("kb")
+	testOwner = v1alpha1.Kibana{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "kb", UID: "uid"},
+	}
+	testService = corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "kb-kb-http"},
+		Spec: corev1.ServiceSpec{
+			Ports: []corev1.ServicePort{{Port: 5601}},
+		},
+	}
+	testLabels = map[string]string{"kibana.k8s.elastic.co/name": "kb"}
+)
+
+func tlsDisabled() commonv1alpha1.TLSOptions {
+	return commonv1alpha1.TLSOptions{SelfSignedCertificate: &commonv1alpha1.SelfSignedCertificate{Disabled: true}}
+}
+
+func Test_newIngress(t *testing.T) {
+	tests := []struct {
+		name            string
+		config          commonv1alpha1.HTTPConfig
+		wantAnnotations map[string]string
+		wantLabels      map[string]string
+		wantPath        string
+		wantSecretName  string
+	}{
+		{
+			name: "TLS enabled: re-encrypt to the service",
+			config: commonv1alpha1.HTTPConfig{
+				Ingress: &commonv1alpha1.IngressTemplate{Host: "kibana.example.com"},
+			},
+			wantAnnotations: map[string]string{
+				BackendProtocolAnnotation: "HTTPS",
+				ProxySSLSecretAnnotation:  "ns/kb-kb-http-certs-public",
+				ProxySSLVerifyAnnotation:  "on",
+				ProxySSLNameAnnotation:    "kb-kb-http.ns.svc",
+			},
+			wantLabels: testLabels,
+			wantPath:   "/",
+		},
+		{
+			name: "TLS disabled: plain HTTP to the service",
+			config: commonv1alpha1.HTTPConfig{
+				Ingress: &commonv1alpha1.IngressTemplate{Host: "kibana.example.com"},
+				TLS:     tlsDisabled(),
+			},
+			wantLabels: testLabels,
+			wantPath:   "/",
+		},
+		{
+			name: "user-provided metadata, path and secret",
+			config: commonv1alpha1.HTTPConfig{
+				Ingress: &commonv1alpha1.IngressTemplate{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:        "ignored",
+						Labels:      map[string]string{"a": "b"},
+						Annotations: map[string]string{ProxySSLVerifyAnnotation: "off", "c": "d"},
+					},
+					Host:       "kibana.example.com",
+					Path:       "/kibana",
+					SecretName: "my-cert",
+				},
+			},
+			wantAnnotations: map[string]string{
+				BackendProtocolAnnotation: "HTTPS",
+				ProxySSLSecretAnnotation:  "ns/kb-kb-http-certs-public",
+				ProxySSLVerifyAnnotation:  "off",
+				ProxySSLNameAnnotation:    "kb-kb-http.ns.svc",
+				"c":                       "d",
+			},
+			wantLabels:     map[string]string{"a": "b", "kibana.k8s.elastic.co/name": "kb"},
+			wantPath:       "/kibana",
+			wantSecretName: "my-cert",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := newIngress(&testOwner, testNamer, tt.config, testService, testLabels)
+			assert.Equal(t, "kb-kb-http", got.Name)
+			assert.Equal(t, "ns", got.Namespace)
+			assert.Equal(t, tt.wantLabels, got.Labels)
+			assert.Equal(t, tt.wantAnnotations, got.Annotations)
+			assert.Equal(t, []v1beta1.IngressTLS{{Hosts: []string{"kibana.example.com"}, SecretName: tt.wantSecretName}}, got.Spec.TLS)
+			require.Len(t, got.Spec.Rules, 1)
+			assert.Equal(t, "kibana.example.com", got.Spec.Rules[0].Host)
+			assert.Equal(t, []v1beta1.HTTPIngressPath{
+				{
+					Path: tt.wantPath,
+					Backend: v1beta1.IngressBackend{
+						ServiceName: "kb-kb-http",
+						ServicePort: intstr.FromInt(5601),
+					},
+				},
+			}, got.Spec.Rules[0].HTTP.Paths)
+		})
+	}
+}
+
+func Test_reconcileIngress(t *testing.T) {
+	require.NoError(t, v1alpha1.AddToScheme(scheme.Scheme))
+	config := commonv1alpha1.HTTPConfig{
+		Ingress: &commonv1alpha1.IngressTemplate{Host: "kibana.example.com"},
+	}
+	expected := newIngress(&testOwner, testNamer, config, testService, testLabels)
+
+	existing := expected.DeepCopy()
+	existing.Spec.Rules[0].Host = "old.example.com"
+	existing.Annotations = map[string]string{"user": "annotation"}
+
+	tests := []struct {
+		name    string
+		initial []runtime.Object
+	}{
+		{
+			name: "create the ingress",
+		},
+		{
+			name:    "update the ingress, preserving existing annotations",
+			initial: []runtime.Object{existing},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := k8s.WrapClient(fake.NewFakeClient(tt.initial...))
+			require.NoError(t, reconcileIngress(c, scheme.Scheme, &testOwner, expected.DeepCopy()))
+
+			var got v1beta1.Ingress
+			require.NoError(t, c.Get(types.NamespacedName{Namespace: "ns", Name: "kb-kb-http"}, &got))
+			assert.Equal(t, expected.Spec, got.Spec)
+			for k, v := range expected.Annotations {
+				assert.Equal(t, v, got.Annotations[k])
+			}
+			if len(tt.initial) > 0 {
+				assert.Equal(t, "annotation", got.Annotations["user"])
+			}
+		})
+	}
+}
+
+func Test_deleteIngressAndRoute(t *testing.T) {
+	owned := newIngress(&testOwner, testNamer, commonv1alpha1.HTTPConfig{
+		Ingress: &commonv1alpha1.IngressTemplate{Host: "kibana.example.com"},
+	}, testService, testLabels)
+	owned.OwnerReferences = []metav1.OwnerReference{
+		{APIVersion: "kibana.k8s.elastic.co/v1alpha1", Kind: "Kibana", Name: "kb", UID: "uid", Controller: &[]bool{true}[0]},
+	}
+	notOwned := owned.DeepCopy()
+	notOwned.OwnerReferences = nil
+
+	tests := []struct {
+		name        string
+		ingress     *v1beta1.Ingress
+		wantDeleted bool
+	}{
+		{
+			name:        "delete the ingress controlled by the owner",
+			ingress:     owned,
+			wantDeleted: true,
+		},
+		{
+			name:        "keep an ingress not controlled by the owner",
+			ingress:     notOwned,
+			wantDeleted: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := k8s.WrapClient(fake.NewFakeClient(tt.ingress))
+			require.NoError(t, deleteIngressAndRoute(c, &testOwner, testService))
+
+			var ingress v1beta1.Ingress
+			err := c.Get(types.NamespacedName{Namespace: "ns", Name: "kb-kb-http"}, &ingress)
+			assert.Equal(t, tt.wantDeleted, errors.IsNotFound(err))
+		})
+	}
+}
+
+func Test_newRoute(t *testing.T) {
+	caCert := []byte("ca")
+	tests := []struct {
+		name           string
+		config         commonv1alpha1.HTTPConfig
+		svc            corev1.Service
+		caCert         []byte
+		wantTLS        map[string]interface{}
+		wantTargetPort interface{}
+	}{
+		{
+			name: "TLS enabled: re-encrypt with the CA",
+			config: commonv1alpha1.HTTPConfig{
+				Ingress: &commonv1alpha1.IngressTemplate{Host: "kibana.example.com"},
+			},
+			svc:    testService,
+			caCert: caCert,
+			wantTLS: map[string]interface{}{
+				"termination":                   "reencrypt",
+				"insecureEdgeTerminationPolicy": "Redirect",
+				"destinationCACertificate":      "ca",
+			},
+			wantTargetPort: int64(5601),
+		},
+		{
+			name: "TLS enabled without CA",
+			config: commonv1alpha1.HTTPConfig{
+				Ingress: &commonv1alpha1.IngressTemplate{Host: "kibana.example.com"},
+			},
+			svc: corev1.Service{
+				ObjectMeta: testService.ObjectMeta,
+				Spec: corev1.ServiceSpec{
+					Ports: []corev1.ServicePort{{Name: "https", Port: 443, TargetPort: intstr.FromInt(5601)}},
+				},
+			},
+			wantTLS: map[string]interface{}{
+				"termination":                   "reencrypt",
+				"insecureEdgeTerminationPolicy": "Redirect",
+			},
+			wantTargetPort: "https",
+		},
+		{
+			name: "TLS disabled: edge termination",
+			config: commonv1alpha1.HTTPConfig{
+				Ingress: &commonv1alpha1.IngressTemplate{Host: "kibana.example.com"},
+				TLS:     tlsDisabled(),
+			},
+			svc:    testService,
+			caCert: caCert,
+			wantTLS: map[string]interface{}{
+				"termination":                   "edge",
+				"insecureEdgeTerminationPolicy": "Redirect",
+			},
+			wantTargetPort: int64(5601),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := newRoute(tt.config, tt.svc, testLabels, tt.caCert)
+			assert.Equal(t, RouteGroupVersionKind, got.GroupVersionKind())
+			assert.Equal(t, "kb-kb-http", got.GetName())
+			assert.Equal(t, "ns", got.GetNamespace())
+			assert.Equal(t, testLabels, got.GetLabels())
+
+			host, _, err := unstructured.NestedString(got.Object, "spec", "host")
+			require.NoError(t, err)
+			assert.Equal(t, "kibana.example.com", host)
+			path, _, err := unstructured.NestedString(got.Object, "spec", "path")
+			require.NoError(t, err)
+			assert.Equal(t, "/", path)
+			service, _, err := unstructured.NestedString(got.Object, "spec", "to", "name")
+			require.NoError(t, err)
+			assert.Equal(t, "kb-kb-http", service)
+			tls, _, err := unstructured.NestedMap(got.Object, "spec", "tls")
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantTLS, tls)
+			targetPort, _, err := unstructured.NestedFieldCopy(got.Object, "spec", "port", "targetPort")
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantTargetPort, targetPort)
+		})
+	}
+}
+
+func Test_isRouteAPIUnavailable(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{
+			name: "no error",
+			want: false,
+		},
+		{
+			name: "Route kind not served by the API server",
+			err:  &meta.NoKindMatchError{GroupKind: RouteGroupVersionKind.GroupKind(), SearchedVersions: []string{"v1"}},
+			want: true,
+		},
+		{
+			name: "Route kind not registered in the scheme",
+			err:  runtime.NewNotRegisteredErrForKind("scheme", RouteGroupVersionKind),
+			want: false,
+		},
+		{
+			name: "other error",
+			err:  errors.NewNotFound(schema.GroupResource{Group: "route.openshift.io", Resource: "routes"}, "kb-kb-http"),
+			want: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, isRouteAPIUnavailable(tt.err))
+		})
+	}
+}
+
+func TestAddRouteToScheme(t *testing.T) {
+	s := runtime.NewScheme()
+	AddRouteToScheme(s)
+	for _, kind := range []string{"Route", "RouteList"} {
+		obj, err := s.New(RouteGroupVersionKind.GroupVersion().WithKind(kind))
+		require.NoError(t, err)
+		_, isUnstructured := obj.(runtime.Unstructured)
+		assert.True(t, isUnstructured)
+	}
+}
+
+func Test_keepGeneratedHost(t *testing.T) {
+	route := func(host string, generated bool) *unstructured.Unstructured {
+		r := newRoute(commonv1alpha1.HTTPConfig{
+			Ingress: &commonv1alpha1.IngressTemplate{Host: host},
+		}, testService, testLabels, nil)
+		if generated {
+			r.SetAnnotations(map[string]string{routeHostGeneratedAnnotation: "true"})
+		}
+		return r
+	}
+	tests := []struct {
+		name       string
+		expected   *unstructured.Unstructured
+		reconciled *unstructured.Unstructured
+		wantHost   string
+	}{
+		{
+			name:       "no host specified: keep the generated host",
+			expected:   route("", false),
+			reconciled: route("kb-kb-http-ns.apps.example.com", true),
+			wantHost:   "kb-kb-http-ns.apps.example.com",
+		},
+		{
+			name:       "no host specified: reset a host that was not generated",
+			expected:   route("", false),
+			reconciled: route("kibana.example.com", false),
+			wantHost:   "",
+		},
+		{
+			name:       "host specified: replace the generated host",
+			expected:   route("kibana.example.com", false),
+			reconciled: route("kb-kb-http-ns.apps.example.com", true),
+			wantHost:   "kibana.example.com",
+		},
+		{
+			name:       "host specified: replace another host",
+			expected:   route("kibana.example.com", false),
+			reconciled: route("old.example.com", false),
+			wantHost:   "kibana.example.com",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			keepGeneratedHost(tt.expected, tt.reconciled)
+			host, _, err := unstructured.NestedString(tt.expected.Object, "spec", "host")
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantHost, host)
+		})
+	}
+}
@@ -0,0 +1,193 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package ingress
+
+import (
+	"reflect"
+
+	"github.com/elastic/cloud-on-k8s/pkg/apis/common/v1alpha1"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/defaults"
+	"github.com/elastic/cloud-on-k8s/pkg/utils/k8s"
+	"github.com/elastic/cloud-on-k8s/pkg/utils/maps"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+)
+
+// RouteGroupVersionKind is the kind of the OpenShift Route. Routes are handled as unstructured objects to avoid a
+// dependency on the OpenShift API, which is not available on all clusters.
+var RouteGroupVersionKind = schema.GroupVersionKind{Group: "route.openshift.io", Version: "v1", Kind: "Route"}
+
+// AddRouteToScheme registers the Route kind as an unstructured object in the given scheme, for Routes to be read and
+// watched through the cache of the manager.
+func AddRouteToScheme(s *runtime.Scheme) {
+	s.AddKnownTypeWithName(RouteGroupVersionKind, &unstructured.Unstructured{})
+	s.AddKnownTypeWithName(RouteGroupVersionKind.GroupVersion().WithKind(RouteGroupVersionKind.Kind+"List"), &unstructured.UnstructuredList{})
+}
+
+// WatchRoutes adds a watch on the Routes controlled by resources of the given type to the controller.
+// It does nothing if the Route API is not available.
+func WatchRoutes(c controller.Controller, mapper meta.RESTMapper, ownerType runtime.Object) error {
+	_, err := mapper.RESTMapping(RouteGroupVersionKind.GroupKind(), RouteGroupVersionKind.Version)
+	if isRouteAPIUnavailable(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	route := &unstructured.Unstructured{}
+	route.SetGroupVersionKind(RouteGroupVersionKind)
+	return c.Watch(&source.Kind{Type: route}, &handler.EnqueueRequestForOwner{
+		IsController: true,
+		OwnerType:    ownerType,
+	})
+}
+
+const (
+	// routeHostGeneratedAnnotation is set by OpenShift on the Routes whose host it generated, because none was
+	// specified.
+	routeHostGeneratedAnnotation = "openshift.io/host.generated"
+	// reencryptTermination terminates TLS at the router and re-encrypts the traffic to the service.
+	reencryptTermination = "reencrypt"
+	// edgeTermination terminates TLS at the router and sends plain HTTP to the service.
+	edgeTermination = "edge"
+)
+
+// newRoute returns the Route routing the host and path of the ingress template to the given service.
+// If caCert is not empty, traffic is re-encrypted and the certificate of the service is verified against it.
+func newRoute(config v1alpha1.HTTPConfig, svc corev1.Service, labels map[string]string, caCert []byte) *unstructured.Unstructured {
+	template := config.Ingress
+	path := template.Path
+	if path == "" {
+		path = v1alpha1.DefaultIngressPath
+	}
+
+	tls := map[string]interface{}{
+		"termination":                   edgeTermination,
+		"insecureEdgeTerminationPolicy": "Redirect",
+	}
+	if config.TLS.Enabled() {
+		tls["termination"] = reencryptTermination
+		if len(caCert) > 0 {
+			tls["destinationCACertificate"] = string(caCert)
+		}
+	}
+
+	// weight and wildcardPolicy are set to the values defaulted by the API server, to compare with the existing Route
+	route := &unstructured.Unstructured{Object: map[string]interface{}{
+		"spec": map[string]interface{}{
+			"host": template.Host,
+			"path": path,
+			"to": map[string]interface{}{
+				"kind":   "Service",
+				"name":   svc.Name,
+				"weight": int64(100),
+			},
+			"wildcardPolicy": "None",
+			"port": map[string]interface{}{
+				"targetPort": routeTargetPort(svc.Spec.Ports[0]),
+			},
+			"tls": tls,
+		},
+	}}
+	route.SetGroupVersionKind(RouteGroupVersionKind)
+	route.SetNamespace(svc.Namespace)
+	route.SetName(svc.Name)
+	route.SetLabels(defaults.SetDefaultLabels(maps.Merge(nil, template.ObjectMeta.Labels), labels))
+	route.SetAnnotations(maps.Merge(nil, template.ObjectMeta.Annotations))
+	return route
+}
+
+// routeTargetPort returns the target port of the service port, as expected by the Route: its name if any, its number
+// otherwise. Numbers are int64 to keep the unstructured content JSON compatible.
+func routeTargetPort(port corev1.ServicePort) interface{} {
+	if port.Name != "" {
+		return port.Name
+	}
+	if port.TargetPort.IntValue() != 0 {
+		return int64(port.TargetPort.IntValue())
+	}
+	return int64(port.Port)
+}
+
+// reconcileRoute creates or updates the given Route. It does nothing if the Route API is not available.
+func reconcileRoute(c k8s.Client, scheme *runtime.Scheme, owner metav1.Object, expected *unstructured.Unstructured) error {
+	reconciled := &unstructured.Unstructured{}
+	reconciled.SetGroupVersionKind(RouteGroupVersionKind)
+	err := c.Get(types.NamespacedName{Namespace: expected.GetNamespace(), Name: expected.GetName()}, reconciled)
+	if isRouteAPIUnavailable(err) {
+		return nil
+	}
+	if errors.IsNotFound(err) {
+		if err := controllerutil.SetControllerReference(owner, expected, scheme); err != nil {
+			return err
+		}
+		log.Info("Creating route", "namespace", expected.GetNamespace(), "route_name", expected.GetName())
+		return c.Create(expected)
+	}
+	if err != nil {
+		return err
+	}
+
+	keepGeneratedHost(expected, reconciled)
+	if maps.IsSubset(expected.GetLabels(), reconciled.GetLabels()) &&
+		maps.IsSubset(expected.GetAnnotations(), reconciled.GetAnnotations()) &&
+		reflect.DeepEqual(expected.Object["spec"], reconciled.Object["spec"]) {
+		return nil
+	}
+	reconciled.SetLabels(maps.Merge(reconciled.GetLabels(), expected.GetLabels()))
+	reconciled.SetAnnotations(maps.Merge(reconciled.GetAnnotations(), expected.GetAnnotations()))
+	reconciled.Object["spec"] = expected.Object["spec"]
+	log.Info("Updating route", "namespace", reconciled.GetNamespace(), "route_name", reconciled.GetName())
+	return c.Update(reconciled)
+}
+
+// keepGeneratedHost sets the host generated by OpenShift for the reconciled Route in the expected Route, if the
+// expected Route does not specify any host, so that the generated host is not reset on every reconciliation.
+func keepGeneratedHost(expected, reconciled *unstructured.Unstructured) {
+	if host, _, _ := unstructured.NestedString(expected.Object, "spec", "host"); host != "" {
+		return
+	}
+	if reconciled.GetAnnotations()[routeHostGeneratedAnnotation] != "true" {
+		return
+	}
+	generated, _, _ := unstructured.NestedString(reconciled.Object, "spec", "host")
+	_ = unstructured.SetNestedField(expected.Object, generated, "spec", "host")
+}
+
+// deleteRoute deletes the given Route if it is controlled by the owner. It does nothing if the Route API is not available.
+func deleteRoute(c k8s.Client, owner metav1.Object, nsn types.NamespacedName) error {
+	route := &unstructured.Unstructured{}
+	route.SetGroupVersionKind(RouteGroupVersionKind)
+	err := c.Get(nsn, route)
+	if errors.IsNotFound(err) || isRouteAPIUnavailable(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if !metav1.IsControlledBy(route, owner) {
+		return nil
+	}
+	log.Info("Deleting route", "namespace", nsn.Namespace, "route_name", nsn.Name)
+	if err := c.Delete(route); err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+	return nil
+}
+
+// isRouteAPIUnavailable returns true if the error indicates the Route API is not served by the API server.
+func isRouteAPIUnavailable(err error) bool {
+	return err != nil && meta.IsNoMatchError(err)
+}
@@ -5,14 +5,19 @@
 package keystore
 
 import (
+	"reflect"
 	"strings"
 
 	commonv1alpha1 "github.com/elastic/cloud-on-k8s/pkg/apis/common/v1alpha1"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/common/driver"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/common/name"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/volume"
+	"github.com/elastic/cloud-on-k8s/pkg/utils/k8s"
 	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
 	logf "sigs.k8s.io/controller-runtime/pkg/runtime/log"
 )
 
@@ -58,10 +63,52 @@ func NewResources(
 		// nothing to do
 		return nil, nil
 	}
+	return newResources(*secretVolume, version, hasKeystore, initContainerParams)
+}
+
+// ExpectedResources returns the volume and init container NewResources would return, without creating, updating or
+// watching any resource. It is meant to compute the expected pods without applying any change.
+// If the operator-managed secret does not contain the user-provided secure settings yet, its version is unknown and
+// left empty.
+func ExpectedResources(
+	c k8s.Client,
+	hasKeystore HasKeystore,
+	namer name.Namer,
+	initContainerParams InitContainerParameters,
+) (*Resources, error) {
+	userSecrets, err := retrieveUserSecrets(c, nil, hasKeystore)
+	if err != nil {
+		return nil, err
+	}
+	aggregatedData := aggregateSecureSettings(userSecrets)
+	if len(aggregatedData) == 0 {
+		return nil, nil
+	}
+
+	var secret corev1.Secret
+	secretName := secureSettingsSecretName(namer, hasKeystore)
+	err = c.Get(types.NamespacedName{Namespace: hasKeystore.GetNamespace(), Name: secretName}, &secret)
+	if err != nil && !apierrors.IsNotFound(err) {
+		return nil, err
+	}
+	version := ""
+	if err == nil && reflect.DeepEqual(secret.Data, aggregatedData) {
+		version = secret.GetResourceVersion()
+	}
 
+	secretVolume := volume.NewSecretVolumeWithMountPath(secretName, SecureSettingsVolumeName, SecureSettingsVolumeMountPath)
+	return newResources(secretVolume, version, hasKeystore, initContainerParams)
+}
+
+func newResources(
+	secretVolume volume.SecretVolume,
+	version string,
+	hasKeystore HasKeystore,
+	initContainerParams InitContainerParameters,
+) (*Resources, error) {
 	// build an init container to create the keystore from the secure settings volume
 	initContainer, err := initContainer(
-		*secretVolume,
+		secretVolume,
 		strings.ToLower(hasKeystore.Kind()),
 		initContainerParams,
 	)
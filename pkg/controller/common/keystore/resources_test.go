@@ -163,3 +163,72 @@ echo "Keystore initialization successful."
 		})
 	}
 }
+
+func TestExpectedResources(t *testing.T) {
+	managedSecret := corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "namespace",
+			Name:      secureSettingsSecretName(name.KBNamer, &testKibanaWithSecureSettings),
+		},
+		Data: testSecureSettingsSecret.Data,
+	}
+	outdatedManagedSecret := *managedSecret.DeepCopy()
+	outdatedManagedSecret.Data = map[string][]byte{"key1": []byte("outdated")}
+
+	tests := []struct {
+		name               string
+		client             k8s.Client
+		kb                 v1alpha1.Kibana
+		wantNil            bool
+		wantManagedVersion bool
+	}{
+		{
+			name:    "no secure settings specified: no resources",
+			client:  k8s.WrapClient(fake.NewFakeClient()),
+			kb:      testKibana,
+			wantNil: true,
+		},
+		{
+			name:    "secure settings specified but secret not there: no resources",
+			client:  k8s.WrapClient(fake.NewFakeClient()),
+			kb:      testKibanaWithSecureSettings,
+			wantNil: true,
+		},
+		{
+			name:               "managed secret up-to-date: version of the managed secret",
+			client:             k8s.WrapClient(fake.NewFakeClient(&testSecureSettingsSecret, &managedSecret)),
+			kb:                 testKibanaWithSecureSettings,
+			wantManagedVersion: true,
+		},
+		{
+			name:   "managed secret outdated: unknown version",
+			client: k8s.WrapClient(fake.NewFakeClient(&testSecureSettingsSecret, &outdatedManagedSecret)),
+			kb:     testKibanaWithSecureSettings,
+		},
+		{
+			name:   "managed secret not there: unknown version",
+			client: k8s.WrapClient(fake.NewFakeClient(&testSecureSettingsSecret)),
+			kb:     testKibanaWithSecureSettings,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resources, err := ExpectedResources(tt.client, &tt.kb, name.KBNamer, initContainersParameters)
+			require.NoError(t, err)
+			if tt.wantNil {
+				require.Nil(t, resources)
+				return
+			}
+			require.NotNil(t, resources)
+			assert.Equal(t, resources.InitContainer.Name, "elastic-internal-init-keystore")
+			assert.Equal(t, resources.Volume.Secret.SecretName, managedSecret.Name)
+			wantVersion := ""
+			if tt.wantManagedVersion {
+				var secret corev1.Secret
+				require.NoError(t, tt.client.Get(k8s.ExtractNamespacedName(&managedSecret), &secret))
+				wantVersion = secret.ResourceVersion
+			}
+			assert.Equal(t, resources.Version, wantVersion)
+		})
+	}
+}
@@ -81,13 +81,7 @@ func reconcileSecureSettings(
 	userSecrets []corev1.Secret,
 	namer name.Namer,
 	labels map[string]string) (*corev1.Secret, error) {
-	aggregatedData := map[string][]byte{}
-
-	for _, s := range userSecrets {
-		for k, v := range s.Data {
-			aggregatedData[k] = v
-		}
-	}
+	aggregatedData := aggregateSecureSettings(userSecrets)
 
 	// reconcile our managed secret with the user-provided secret content
 	expected := corev1.Secret{
@@ -124,6 +118,19 @@ func reconcileSecureSettings(
 	})
 }
 
+// aggregateSecureSettings merges the entries of the given secrets, the last secret taking precedence.
+func aggregateSecureSettings(userSecrets []corev1.Secret) map[string][]byte {
+	aggregatedData := map[string][]byte{}
+	for _, s := range userSecrets {
+		for k, v := range s.Data {
+			aggregatedData[k] = v
+		}
+	}
+	return aggregatedData
+}
+
+// retrieveUserSecrets returns the user-provided secure settings secrets. Missing secrets are skipped, and reported
+// through an event if a recorder is given.
 func retrieveUserSecrets(c k8s.Client, recorder record.EventRecorder, hasKeystore HasKeystore) ([]corev1.Secret, error) {
 	userSecrets := make([]corev1.Secret, 0, len(hasKeystore.SecureSettings()))
 	for _, userSecretsRef := range hasKeystore.SecureSettings() {
@@ -150,7 +157,9 @@ func retrieveUserSecret(c k8s.Client, recorder record.EventRecorder, hasKeystore
 	if err != nil && apierrors.IsNotFound(err) {
 		msg := "Secure settings secret not found"
 		log.Info(msg, "namespace", namespace, "secret_name", secretName)
-		recorder.Event(hasKeystore, corev1.EventTypeWarning, events.EventReasonUnexpected, msg+": "+secretName)
+		if recorder != nil {
+			recorder.Event(hasKeystore, corev1.EventTypeWarning, events.EventReasonUnexpected, msg+": "+secretName)
+		}
 		return nil, false, nil
 	} else if err != nil {
 		return nil, false, err
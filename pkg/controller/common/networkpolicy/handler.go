@@ -0,0 +1,54 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package networkpolicy
+
+import (
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/util/workqueue"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// ReferenceHandler is an EventHandler enqueuing the resources referenced by the watched objects, to update their
+// NetworkPolicy when the associations change. On updates, the resources referenced by the previous version of the
+// object are enqueued along with the ones referenced by the new version, so that the NetworkPolicy of a resource the
+// object is dissociated from stops allowing its pods.
+type ReferenceHandler struct {
+	// References returns the requests to reconcile the resources referenced by the given object.
+	References func(object runtime.Object) []reconcile.Request
+}
+
+var _ handler.EventHandler = &ReferenceHandler{}
+
+// Create enqueues the resources referenced by the created object.
+func (h *ReferenceHandler) Create(evt event.CreateEvent, q workqueue.RateLimitingInterface) {
+	h.enqueue(evt.Object, q)
+}
+
+// Update enqueues the resources referenced by both the previous and the new version of the updated object.
+func (h *ReferenceHandler) Update(evt event.UpdateEvent, q workqueue.RateLimitingInterface) {
+	h.enqueue(evt.ObjectOld, q)
+	h.enqueue(evt.ObjectNew, q)
+}
+
+// Delete enqueues the resources referenced by the deleted object.
+func (h *ReferenceHandler) Delete(evt event.DeleteEvent, q workqueue.RateLimitingInterface) {
+	h.enqueue(evt.Object, q)
+}
+
+// Generic enqueues the resources referenced by the object.
+func (h *ReferenceHandler) Generic(evt event.GenericEvent, q workqueue.RateLimitingInterface) {
+	h.enqueue(evt.Object, q)
+}
+
+func (h *ReferenceHandler) enqueue(object runtime.Object, q workqueue.RateLimitingInterface) {
+	if object == nil {
+		return
+	}
+	for _, req := range h.References(object) {
+		q.Add(req)
+	}
+}
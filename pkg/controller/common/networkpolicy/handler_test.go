@@ -0,0 +1,84 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package networkpolicy
+
+import (
+	"testing"
+
+	commonv1alpha1 "github.com/elastic/cloud-on-k8s/pkg/apis/common/v1alpha1"
+	"github.com/elastic/cloud-on-k8s/pkg/apis/kibana/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/workqueue"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func TestReferenceHandler(t *testing.T) {
+	h := &ReferenceHandler{References: func(object runtime.Object) []reconcile.Request {
+		kb := object.(*v1alpha1.Kibana)
+		return ReferencedRequests(kb.Spec.ElasticsearchRef, kb.Namespace)
+	}}
+	kibana := func(esName string) *v1alpha1.Kibana {
+		kb := testOwner.DeepCopy()
+		kb.Spec.ElasticsearchRef = commonv1alpha1.ObjectSelector{Name: esName}
+		return kb
+	}
+	requests := func(q workqueue.RateLimitingInterface) []reconcile.Request {
+		var got []reconcile.Request
+		for q.Len() > 0 {
+			item, _ := q.Get()
+			got = append(got, item.(reconcile.Request))
+			q.Done(item)
+		}
+		return got
+	}
+	request := func(name string) reconcile.Request {
+		return reconcile.Request{NamespacedName: types.NamespacedName{Namespace: "ns", Name: name}}
+	}
+
+	tests := []struct {
+		name  string
+		event func(h *ReferenceHandler, q workqueue.RateLimitingInterface)
+		want  []reconcile.Request
+	}{
+		{
+			name: "create",
+			event: func(h *ReferenceHandler, q workqueue.RateLimitingInterface) {
+				h.Create(event.CreateEvent{Object: kibana("es")}, q)
+			},
+			want: []reconcile.Request{request("es")},
+		},
+		{
+			name: "reference changed: both the previous and the new cluster are enqueued",
+			event: func(h *ReferenceHandler, q workqueue.RateLimitingInterface) {
+				h.Update(event.UpdateEvent{ObjectOld: kibana("es"), ObjectNew: kibana("other-es")}, q)
+			},
+			want: []reconcile.Request{request("es"), request("other-es")},
+		},
+		{
+			name: "reference removed: the previous cluster is enqueued",
+			event: func(h *ReferenceHandler, q workqueue.RateLimitingInterface) {
+				h.Update(event.UpdateEvent{ObjectOld: kibana("es"), ObjectNew: kibana("")}, q)
+			},
+			want: []reconcile.Request{request("es")},
+		},
+		{
+			name: "delete",
+			event: func(h *ReferenceHandler, q workqueue.RateLimitingInterface) {
+				h.Delete(event.DeleteEvent{Object: kibana("es")}, q)
+			},
+			want: []reconcile.Request{request("es")},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter())
+			tt.event(h, q)
+			assert.Equal(t, tt.want, requests(q))
+		})
+	}
+}
@@ -0,0 +1,169 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package networkpolicy
+
+import (
+	"reflect"
+
+	commonv1alpha1 "github.com/elastic/cloud-on-k8s/pkg/apis/common/v1alpha1"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/name"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/reconciler"
+	"github.com/elastic/cloud-on-k8s/pkg/utils/k8s"
+	"github.com/elastic/cloud-on-k8s/pkg/utils/maps"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	logf "sigs.k8s.io/controller-runtime/pkg/runtime/log"
+)
+
+var log = logf.Log.WithName("networkpolicy")
+
+const (
+	suffix = "network-policy"
+
+	// NamespaceNameLabelName is the label holding the name of a namespace, used to select the namespace of pods in
+	// another namespace than the NetworkPolicy. It is set automatically starting with Kubernetes 1.21.
+	NamespaceNameLabelName = "kubernetes.io/metadata.name"
+)
+
+// Name returns the name of the NetworkPolicy of the given owner.
+func Name(namer name.Namer, ownerName string) string {
+	return namer.Suffix(ownerName, suffix)
+}
+
+// New returns a NetworkPolicy restricting the ingress traffic to the pods matching the given labels to the given rules.
+// Rules without peers are omitted, since they would allow all sources: the port of such a rule is not reachable.
+func New(
+	namer name.Namer,
+	owner metav1.Object,
+	labels map[string]string,
+	podLabels map[string]string,
+	rules ...networkingv1.NetworkPolicyIngressRule,
+) networkingv1.NetworkPolicy {
+	ingress := make([]networkingv1.NetworkPolicyIngressRule, 0, len(rules))
+	for _, rule := range rules {
+		if len(rule.From) > 0 {
+			ingress = append(ingress, rule)
+		}
+	}
+	return networkingv1.NetworkPolicy{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: owner.GetNamespace(),
+			Name:      Name(namer, owner.GetName()),
+			Labels:    labels,
+		},
+		Spec: networkingv1.NetworkPolicySpec{
+			PodSelector: metav1.LabelSelector{MatchLabels: podLabels},
+			Ingress:     ingress,
+			PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeIngress},
+		},
+	}
+}
+
+// PortRule returns a rule allowing the given peers to reach the given TCP port.
+func PortRule(port int, peers ...networkingv1.NetworkPolicyPeer) networkingv1.NetworkPolicyIngressRule {
+	protocol := corev1.ProtocolTCP
+	portValue := intstr.FromInt(port)
+	return networkingv1.NetworkPolicyIngressRule{
+		Ports: []networkingv1.NetworkPolicyPort{{Protocol: &protocol, Port: &portValue}},
+		From:  peers,
+	}
+}
+
+// PodsPeer returns a peer matching the pods with the given labels in the given namespace, as seen from a NetworkPolicy
+// in the target namespace. The namespace of pods in another namespace is selected by its NamespaceNameLabelName label.
+func PodsPeer(targetNamespace string, namespace string, labels map[string]string) networkingv1.NetworkPolicyPeer {
+	peer := networkingv1.NetworkPolicyPeer{
+		PodSelector: &metav1.LabelSelector{MatchLabels: labels},
+	}
+	if namespace != targetNamespace {
+		peer.NamespaceSelector = &metav1.LabelSelector{MatchLabels: map[string]string{NamespaceNameLabelName: namespace}}
+	}
+	return peer
+}
+
+// Reconcile ensures the expected NetworkPolicy exists if enabled, and deletes the NetworkPolicy of the owner otherwise.
+func Reconcile(
+	c k8s.Client,
+	scheme *runtime.Scheme,
+	owner metav1.Object,
+	enabled bool,
+	expected networkingv1.NetworkPolicy,
+) error {
+	if !enabled {
+		return deleteNetworkPolicy(c, owner, types.NamespacedName{Namespace: expected.Namespace, Name: expected.Name})
+	}
+
+	reconciled := &networkingv1.NetworkPolicy{}
+	return reconciler.ReconcileResource(reconciler.Params{
+		Client:     c,
+		Scheme:     scheme,
+		Owner:      owner,
+		Expected:   &expected,
+		Reconciled: reconciled,
+		NeedsUpdate: func() bool {
+			return !maps.IsSubset(expected.Labels, reconciled.Labels) ||
+				!reflect.DeepEqual(expected.Spec, reconciled.Spec)
+		},
+		UpdateReconciled: func() {
+			reconciled.Labels = maps.Merge(reconciled.Labels, expected.Labels)
+			reconciled.Spec = expected.Spec
+		},
+		PreCreate: func() {
+			log.Info("Creating network policy", "namespace", expected.Namespace, "network_policy_name", expected.Name)
+		},
+		PreUpdate: func() {
+			log.Info("Updating network policy", "namespace", expected.Namespace, "network_policy_name", expected.Name)
+		},
+	})
+}
+
+// deleteNetworkPolicy deletes the given NetworkPolicy if it exists and is controlled by the owner.
+func deleteNetworkPolicy(c k8s.Client, owner metav1.Object, nsn types.NamespacedName) error {
+	var policy networkingv1.NetworkPolicy
+	err := c.Get(nsn, &policy)
+	if errors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if !metav1.IsControlledBy(&policy, owner) {
+		return nil
+	}
+	log.Info("Deleting network policy", "namespace", nsn.Namespace, "network_policy_name", nsn.Name)
+	if err := c.Delete(&policy); err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+	return nil
+}
+
+// References returns true if the given reference, specified in a resource of the given namespace, points to the target.
+// The namespace of the reference defaults to the namespace of the resource.
+func References(ref commonv1alpha1.ObjectSelector, namespace string, target types.NamespacedName) bool {
+	return ref.IsDefined() && referencedName(ref, namespace) == target
+}
+
+// ReferencedRequests returns a request to reconcile the resource pointed to by the given reference, specified in a
+// resource of the given namespace. It is used to update the NetworkPolicy of a resource when its associations change.
+func ReferencedRequests(ref commonv1alpha1.ObjectSelector, namespace string) []reconcile.Request {
+	if !ref.IsDefined() {
+		return nil
+	}
+	return []reconcile.Request{{NamespacedName: referencedName(ref, namespace)}}
+}
+
+func referencedName(ref commonv1alpha1.ObjectSelector, namespace string) types.NamespacedName {
+	nsn := ref.NamespacedName()
+	if nsn.Namespace == "" {
+		nsn.Namespace = namespace
+	}
+	return nsn
+}
@@ -0,0 +1,188 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package networkpolicy
+
+import (
+	"testing"
+
+	commonv1alpha1 "github.com/elastic/cloud-on-k8s/pkg/apis/common/v1alpha1"
+	"github.com/elastic/cloud-on-k8s/pkg/apis/kibana/v1alpha1"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/name"
+	"github.com/elastic/cloud-on-k8s/pkg/utils/k8s"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+var (
+	testNamer = name.NewNamer("kb")
+	testOwner = v1alpha1.Kibana{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "kb", UID: "uid"},
+	}
+	testLabels = map[string]string{"kibana.k8s.elastic.co/name": "kb"}
+	testPeer   = networkingv1.NetworkPolicyPeer{PodSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"a": "b"}}}
+)
+
+func TestNew(t *testing.T) {
+	rule := PortRule(5601, testPeer)
+	got := New(testNamer, &testOwner, testLabels, testLabels, rule, PortRule(9200))
+	assert.Equal(t, "kb-kb-network-policy", got.Name)
+	assert.Equal(t, "ns", got.Namespace)
+	assert.Equal(t, testLabels, got.Labels)
+	assert.Equal(t, metav1.LabelSelector{MatchLabels: testLabels}, got.Spec.PodSelector)
+	// the rule without peers is omitted
+	assert.Equal(t, []networkingv1.NetworkPolicyIngressRule{rule}, got.Spec.Ingress)
+	assert.Equal(t, []networkingv1.PolicyType{networkingv1.PolicyTypeIngress}, got.Spec.PolicyTypes)
+}
+
+func TestPodsPeer(t *testing.T) {
+	tests := []struct {
+		name      string
+		namespace string
+		want      networkingv1.NetworkPolicyPeer
+	}{
+		{
+			name:      "same namespace",
+			namespace: "ns",
+			want:      networkingv1.NetworkPolicyPeer{PodSelector: &metav1.LabelSelector{MatchLabels: testLabels}},
+		},
+		{
+			name:      "other namespace",
+			namespace: "other",
+			want: networkingv1.NetworkPolicyPeer{
+				PodSelector:       &metav1.LabelSelector{MatchLabels: testLabels},
+				NamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{NamespaceNameLabelName: "other"}},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, PodsPeer("ns", tt.namespace, testLabels))
+		})
+	}
+}
+
+func TestReconcile(t *testing.T) {
+	require.NoError(t, v1alpha1.AddToScheme(scheme.Scheme))
+	expected := New(testNamer, &testOwner, testLabels, testLabels, PortRule(5601, testPeer))
+
+	outdated := expected.DeepCopy()
+	outdated.Spec.Ingress = nil
+	outdated.OwnerReferences = []metav1.OwnerReference{
+		{APIVersion: "kibana.k8s.elastic.co/v1alpha1", Kind: "Kibana", Name: "kb", UID: "uid", Controller: &[]bool{true}[0]},
+	}
+	notOwned := expected.DeepCopy()
+
+	tests := []struct {
+		name        string
+		initial     []runtime.Object
+		enabled     bool
+		wantDeleted bool
+	}{
+		{
+			name:    "create the network policy",
+			enabled: true,
+		},
+		{
+			name:    "update the network policy",
+			initial: []runtime.Object{outdated},
+			enabled: true,
+		},
+		{
+			name:        "delete the network policy controlled by the owner when disabled",
+			initial:     []runtime.Object{outdated},
+			wantDeleted: true,
+		},
+		{
+			name:    "keep a network policy not controlled by the owner",
+			initial: []runtime.Object{notOwned},
+		},
+		{
+			name:        "nothing to delete",
+			wantDeleted: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := k8s.WrapClient(fake.NewFakeClient(tt.initial...))
+			require.NoError(t, Reconcile(c, scheme.Scheme, &testOwner, tt.enabled, expected))
+
+			var got networkingv1.NetworkPolicy
+			err := c.Get(types.NamespacedName{Namespace: "ns", Name: "kb-kb-network-policy"}, &got)
+			if tt.wantDeleted {
+				assert.True(t, errors.IsNotFound(err))
+				return
+			}
+			require.NoError(t, err)
+			if tt.enabled {
+				assert.Equal(t, expected.Spec, got.Spec)
+			}
+		})
+	}
+}
+
+func TestReferences(t *testing.T) {
+	target := types.NamespacedName{Namespace: "ns", Name: "es"}
+	tests := []struct {
+		name      string
+		ref       commonv1alpha1.ObjectSelector
+		namespace string
+		want      bool
+	}{
+		{
+			name:      "undefined reference",
+			namespace: "ns",
+			want:      false,
+		},
+		{
+			name:      "reference in the same namespace",
+			ref:       commonv1alpha1.ObjectSelector{Name: "es"},
+			namespace: "ns",
+			want:      true,
+		},
+		{
+			name:      "reference in the namespace of another resource",
+			ref:       commonv1alpha1.ObjectSelector{Name: "es"},
+			namespace: "other",
+			want:      false,
+		},
+		{
+			name:      "reference with an explicit namespace",
+			ref:       commonv1alpha1.ObjectSelector{Name: "es", Namespace: "ns"},
+			namespace: "other",
+			want:      true,
+		},
+		{
+			name:      "reference to another resource",
+			ref:       commonv1alpha1.ObjectSelector{Name: "other"},
+			namespace: "ns",
+			want:      false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, References(tt.ref, tt.namespace, target))
+		})
+	}
+}
+
+func TestReferencedRequests(t *testing.T) {
+	assert.Nil(t, ReferencedRequests(commonv1alpha1.ObjectSelector{}, "ns"))
+	assert.Equal(t,
+		[]reconcile.Request{{NamespacedName: types.NamespacedName{Namespace: "ns", Name: "es"}}},
+		ReferencedRequests(commonv1alpha1.ObjectSelector{Name: "es"}, "ns"),
+	)
+	assert.Equal(t,
+		[]reconcile.Request{{NamespacedName: types.NamespacedName{Namespace: "other", Name: "es"}}},
+		ReferencedRequests(commonv1alpha1.ObjectSelector{Name: "es", Namespace: "other"}, "ns"),
+	)
+}
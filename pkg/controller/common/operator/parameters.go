@@ -8,6 +8,7 @@ import (
 	"github.com/elastic/cloud-on-k8s/pkg/about"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/common/certificates"
 	"github.com/elastic/cloud-on-k8s/pkg/utils/net"
+	networkingv1 "k8s.io/api/networking/v1"
 )
 
 // Parameters contain parameters to create new operators.
@@ -22,4 +23,28 @@ type Parameters struct {
 	CACertRotation certificates.RotationParams
 	// CertRotation defines the rotation params for non-CA certificates.
 	CertRotation certificates.RotationParams
+	// CertKeyParams defines the algorithm and size of the private keys generated for CA and non-CA certificates.
+	CertKeyParams certificates.KeyParams
+	// NetworkPolicyOperatorPeer selects the operator pods in the NetworkPolicies of the managed resources.
+	NetworkPolicyOperatorPeer networkingv1.NetworkPolicyPeer
+	// HeapMemoryPercentage is the percentage of the container memory limit used as heap size when not specified.
+	HeapMemoryPercentage int
+	// RestrictedMode prevents the creation of privileged or root containers in the managed pods.
+	RestrictedMode RestrictedMode
+}
+
+// RestrictedMode defines how the operator runs the managed pods when privileged or root containers are forbidden.
+type RestrictedMode struct {
+	// Enabled prevents the creation of privileged or root containers in the managed pods.
+	Enabled bool
+	// NodeTuning indicates a DaemonSet sets vm.max_map_count on each Kubernetes node.
+	NodeTuning bool
+	// NodeTuningImage is the image of the node tuning DaemonSet pods.
+	NodeTuningImage string
+}
+
+// MaxMapCountGuaranteed returns true if vm.max_map_count is expected to be large enough on the Kubernetes nodes
+// for Elasticsearch to memory-map its index files.
+func (r RestrictedMode) MaxMapCountGuaranteed() bool {
+	return !r.Enabled || r.NodeTuning
 }
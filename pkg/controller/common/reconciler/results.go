@@ -5,10 +5,20 @@
 package reconciler
 
 import (
+	commonv1alpha1 "github.com/elastic/cloud-on-k8s/pkg/apis/common/v1alpha1"
 	k8serrors "k8s.io/apimachinery/pkg/util/errors"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 )
 
+const (
+	// ReconciliationErrorReason is the reason of the Reconciling condition when an error occurred.
+	ReconciliationErrorReason = "ReconciliationError"
+	// ReconciliationInProgressReason is the reason of the Reconciling condition when a requeue is requested.
+	ReconciliationInProgressReason = "ReconciliationInProgress"
+	// ReconciledReason is the reason of the Reconciling condition when the reconciliation is over.
+	ReconciledReason = "Reconciled"
+)
+
 // Results collects intermediate results of a reconciliation run and any errors that occurred.
 type Results struct {
 	results []reconcile.Result
@@ -80,3 +90,17 @@ func nextResultTakesPrecedence(current, next reconcile.Result) bool {
 	}
 	return false // default case
 }
+
+// ReconcilingCondition returns the Reconciling condition matching the collected results: the reconciliation is still
+// in progress if an error occurred or a requeue is requested.
+func (r *Results) ReconcilingCondition() commonv1alpha1.Condition {
+	result, err := r.Aggregate()
+	switch {
+	case err != nil:
+		return commonv1alpha1.NewCondition(commonv1alpha1.ReconcilingCondition, true, ReconciliationErrorReason, err.Error())
+	case result.Requeue || result.RequeueAfter > 0:
+		return commonv1alpha1.NewCondition(commonv1alpha1.ReconcilingCondition, true, ReconciliationInProgressReason, "")
+	default:
+		return commonv1alpha1.NewCondition(commonv1alpha1.ReconcilingCondition, false, ReconciledReason, "")
+	}
+}
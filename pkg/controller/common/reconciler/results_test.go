@@ -9,7 +9,9 @@ import (
 	"testing"
 	"time"
 
+	commonv1alpha1 "github.com/elastic/cloud-on-k8s/pkg/apis/common/v1alpha1"
 	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 )
 
@@ -192,3 +194,39 @@ func TestResults_WithResults(t *testing.T) {
 		})
 	}
 }
+
+func TestResults_ReconcilingCondition(t *testing.T) {
+	tests := []struct {
+		name       string
+		results    *Results
+		wantStatus corev1.ConditionStatus
+		wantReason string
+	}{
+		{
+			name:       "no requeue",
+			results:    &Results{results: []reconcile.Result{{}}},
+			wantStatus: corev1.ConditionFalse,
+			wantReason: ReconciledReason,
+		},
+		{
+			name:       "requeue",
+			results:    &Results{results: []reconcile.Result{{}, {RequeueAfter: 1 * time.Second}}},
+			wantStatus: corev1.ConditionTrue,
+			wantReason: ReconciliationInProgressReason,
+		},
+		{
+			name:       "error",
+			results:    &Results{results: []reconcile.Result{{}}, errors: []error{errors.New("test")}},
+			wantStatus: corev1.ConditionTrue,
+			wantReason: ReconciliationErrorReason,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.results.ReconcilingCondition()
+			if got.Type != commonv1alpha1.ReconcilingCondition || got.Status != tt.wantStatus || got.Reason != tt.wantReason {
+				t.Errorf("Results.ReconcilingCondition() = %v, want status %s and reason %s", got, tt.wantStatus, tt.wantReason)
+			}
+		})
+	}
+}
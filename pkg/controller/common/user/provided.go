@@ -0,0 +1,33 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package user
+
+import (
+	"github.com/elastic/cloud-on-k8s/pkg/apis/elasticsearch/v1alpha1"
+	"github.com/elastic/cloud-on-k8s/pkg/utils/k8s"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// ProvidedPasswordsSecretKey returns the namespaced name of the secret containing the user-provided passwords
+// of the given Elasticsearch cluster, and false if there is none.
+func ProvidedPasswordsSecretKey(es v1alpha1.Elasticsearch) (types.NamespacedName, bool) {
+	secretName := es.Spec.Auth.PasswordsSecret.SecretName
+	return types.NamespacedName{Namespace: es.Namespace, Name: secretName}, secretName != ""
+}
+
+// ProvidedPasswords returns the user-provided passwords of the given Elasticsearch cluster, by user name.
+// It returns an error if the referenced secret does not exist.
+func ProvidedPasswords(c k8s.Client, es v1alpha1.Elasticsearch) (map[string][]byte, error) {
+	key, exists := ProvidedPasswordsSecretKey(es)
+	if !exists {
+		return nil, nil
+	}
+	var secret corev1.Secret
+	if err := c.Get(key, &secret); err != nil {
+		return nil, err
+	}
+	return secret.Data, nil
+}
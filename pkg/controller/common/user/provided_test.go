@@ -0,0 +1,69 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package user
+
+import (
+	"testing"
+
+	commonv1alpha1 "github.com/elastic/cloud-on-k8s/pkg/apis/common/v1alpha1"
+	"github.com/elastic/cloud-on-k8s/pkg/apis/elasticsearch/v1alpha1"
+	"github.com/elastic/cloud-on-k8s/pkg/utils/k8s"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestProvidedPasswords(t *testing.T) {
+	passwordsSecret := corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "passwords"},
+		Data:       map[string][]byte{"elastic": []byte("changeme")},
+	}
+	esWithPasswordsSecret := func(secretName string) v1alpha1.Elasticsearch {
+		return v1alpha1.Elasticsearch{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "es"},
+			Spec: v1alpha1.ElasticsearchSpec{
+				Auth: v1alpha1.Auth{PasswordsSecret: commonv1alpha1.SecretRef{SecretName: secretName}},
+			},
+		}
+	}
+	tests := []struct {
+		name           string
+		es             v1alpha1.Elasticsearch
+		initialObjects []runtime.Object
+		want           map[string][]byte
+		wantErr        bool
+	}{
+		{
+			name: "no passwords secret",
+			es:   esWithPasswordsSecret(""),
+			want: nil,
+		},
+		{
+			name:           "passwords secret",
+			es:             esWithPasswordsSecret("passwords"),
+			initialObjects: []runtime.Object{&passwordsSecret},
+			want:           map[string][]byte{"elastic": []byte("changeme")},
+		},
+		{
+			name:    "passwords secret not found",
+			es:      esWithPasswordsSecret("passwords"),
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := k8s.WrapClient(fake.NewFakeClient(tt.initialObjects...))
+			got, err := ProvidedPasswords(c, tt.es)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -0,0 +1,55 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package user
+
+import (
+	"github.com/elastic/cloud-on-k8s/pkg/apis/elasticsearch/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+const (
+	// PasswordRotationAnnotationName is the annotation of the Elasticsearch resource requesting the rotation of the
+	// passwords managed by the operator. Its value is an arbitrary token: changing it requests a new rotation.
+	// Secrets whose passwords were rotated record the last token in an annotation of the same name.
+	PasswordRotationAnnotationName = "elasticsearch.k8s.elastic.co/rotate-passwords"
+	// PasswordsRotatedAnnotationName is the annotation set by the operator on the Elasticsearch resource to record
+	// the token of the last password rotation whose new passwords are accepted by all the Elasticsearch nodes.
+	PasswordsRotatedAnnotationName = "elasticsearch.k8s.elastic.co/passwords-rotated"
+
+	// PendingPasswordSuffix is the suffix of the secret keys storing the new passwords of a rotation in progress.
+	// They are only used by Elasticsearch until the rotation is completed, at which point they replace the current
+	// passwords.
+	PendingPasswordSuffix = ".pending"
+)
+
+// PasswordRotationRequested returns true if the passwords stored in the given secret must be rotated.
+func PasswordRotationRequested(es v1alpha1.Elasticsearch, secret corev1.Secret) bool {
+	rotation := es.Annotations[PasswordRotationAnnotationName]
+	return rotation != "" && rotation != secret.Annotations[PasswordRotationAnnotationName]
+}
+
+// PasswordRotationPropagated returns true if the new passwords of the rotation requested on the given Elasticsearch
+// resource are accepted by all the Elasticsearch nodes.
+func PasswordRotationPropagated(es v1alpha1.Elasticsearch) bool {
+	rotation := es.Annotations[PasswordRotationAnnotationName]
+	return rotation != "" && rotation == es.Annotations[PasswordsRotatedAnnotationName]
+}
+
+// PendingPasswordKey returns the secret key storing the pending password of the given user during a rotation.
+func PendingPasswordKey(userName string) string {
+	return userName + PendingPasswordSuffix
+}
+
+// SetPasswordRotation records in the given secret the password rotation requested on the Elasticsearch resource.
+func SetPasswordRotation(es v1alpha1.Elasticsearch, secret *corev1.Secret) {
+	rotation, exists := es.Annotations[PasswordRotationAnnotationName]
+	if !exists {
+		return
+	}
+	if secret.Annotations == nil {
+		secret.Annotations = make(map[string]string)
+	}
+	secret.Annotations[PasswordRotationAnnotationName] = rotation
+}
@@ -0,0 +1,125 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package user
+
+import (
+	"testing"
+
+	"github.com/elastic/cloud-on-k8s/pkg/apis/elasticsearch/v1alpha1"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func esWithRotation(rotation string) v1alpha1.Elasticsearch {
+	es := v1alpha1.Elasticsearch{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "es"}}
+	if rotation != "" {
+		es.Annotations = map[string]string{PasswordRotationAnnotationName: rotation}
+	}
+	return es
+}
+
+func secretWithRotation(rotation string) corev1.Secret {
+	secret := corev1.Secret{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "secret"}}
+	if rotation != "" {
+		secret.Annotations = map[string]string{PasswordRotationAnnotationName: rotation}
+	}
+	return secret
+}
+
+func TestPasswordRotationRequested(t *testing.T) {
+	tests := []struct {
+		name   string
+		es     v1alpha1.Elasticsearch
+		secret corev1.Secret
+		want   bool
+	}{
+		{
+			name:   "no rotation requested",
+			es:     esWithRotation(""),
+			secret: secretWithRotation(""),
+			want:   false,
+		},
+		{
+			name:   "no rotation requested, secret rotated before",
+			es:     esWithRotation(""),
+			secret: secretWithRotation("1"),
+			want:   false,
+		},
+		{
+			name:   "first rotation requested",
+			es:     esWithRotation("1"),
+			secret: secretWithRotation(""),
+			want:   true,
+		},
+		{
+			name:   "rotation already done",
+			es:     esWithRotation("1"),
+			secret: secretWithRotation("1"),
+			want:   false,
+		},
+		{
+			name:   "new rotation requested",
+			es:     esWithRotation("2"),
+			secret: secretWithRotation("1"),
+			want:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, PasswordRotationRequested(tt.es, tt.secret))
+		})
+	}
+}
+
+func TestSetPasswordRotation(t *testing.T) {
+	secret := secretWithRotation("")
+	SetPasswordRotation(esWithRotation(""), &secret)
+	require.Nil(t, secret.Annotations)
+
+	SetPasswordRotation(esWithRotation("1"), &secret)
+	require.Equal(t, "1", secret.Annotations[PasswordRotationAnnotationName])
+	require.False(t, PasswordRotationRequested(esWithRotation("1"), secret))
+}
+
+func TestPasswordRotationPropagated(t *testing.T) {
+	tests := []struct {
+		name    string
+		rotated string
+		es      v1alpha1.Elasticsearch
+		want    bool
+	}{
+		{
+			name: "no rotation requested",
+			es:   esWithRotation(""),
+			want: false,
+		},
+		{
+			name: "rotation in progress",
+			es:   esWithRotation("1"),
+			want: false,
+		},
+		{
+			name:    "previous rotation propagated",
+			es:      esWithRotation("2"),
+			rotated: "1",
+			want:    false,
+		},
+		{
+			name:    "rotation propagated",
+			es:      esWithRotation("2"),
+			rotated: "2",
+			want:    true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.rotated != "" {
+				tt.es.Annotations[PasswordsRotatedAnnotationName] = tt.rotated
+			}
+			require.Equal(t, tt.want, PasswordRotationPropagated(tt.es))
+		})
+	}
+}
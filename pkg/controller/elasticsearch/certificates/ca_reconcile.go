@@ -36,19 +36,31 @@ func Reconcile(
 	services []corev1.Service,
 	caRotation certificates.RotationParams,
 	certRotation certificates.RotationParams,
+	keyParams certificates.KeyParams,
 ) (*CertificateResources, *reconciler.Results) {
 	results := &reconciler.Results{}
 
+	httpKeyParams := keyParams.WithOverrides(es.Spec.HTTP.TLS.PrivateKey)
+	if err := httpKeyParams.Validate(); err != nil {
+		return nil, results.WithError(err)
+	}
+	transportKeyParams := keyParams.WithOverrides(es.Spec.Transport.TLS.PrivateKey)
+	if err := transportKeyParams.Validate(); err != nil {
+		return nil, results.WithError(err)
+	}
+
 	labels := label.NewLabels(k8s.ExtractNamespacedName(&es))
 
-	httpCA, err := certificates.ReconcileCAForOwner(
+	httpCA, err := certificates.ReconcileCA(
 		driver.K8sClient(),
 		driver.Scheme(),
 		name.ESNamer,
 		&es,
 		labels,
 		certificates.HTTPCAType,
+		es.Spec.HTTP.TLS.CertificateAuthority,
 		caRotation,
+		httpKeyParams,
 	)
 	if err != nil {
 		return nil, results.WithError(err)
@@ -65,10 +77,11 @@ func Reconcile(
 		&es,
 		name.ESNamer,
 		httpCA,
-		es.Spec.HTTP.TLS,
+		http.TLSOptions(es.Spec.HTTP),
 		labels,
 		services,
 		caRotation,
+		httpKeyParams,
 	)
 	if err != nil {
 		return nil, results.WithError(err)
@@ -79,14 +92,20 @@ func Reconcile(
 		return nil, results.WithError(err)
 	}
 
-	transportCA, err := certificates.ReconcileCAForOwner(
+	if err := reconcileTransportDynamicWatches(driver.DynamicWatches(), es); err != nil {
+		return nil, results.WithError(err)
+	}
+
+	transportCA, err := certificates.ReconcileCA(
 		driver.K8sClient(),
 		driver.Scheme(),
 		name.ESNamer,
 		&es,
 		labels,
 		certificates.TransportCAType,
+		es.Spec.Transport.TLS.CertificateAuthority,
 		caRotation,
+		transportKeyParams,
 	)
 	if err != nil {
 		return nil, results.WithError(err)
@@ -108,6 +127,7 @@ func Reconcile(
 		transportCA,
 		es,
 		certRotation,
+		transportKeyParams,
 	)
 	if results.WithResult(result).WithError(err).HasError() {
 		return nil, results
@@ -0,0 +1,54 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package certificates
+
+import (
+	"github.com/elastic/cloud-on-k8s/pkg/apis/elasticsearch/v1alpha1"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/finalizer"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/watches"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/name"
+	"github.com/elastic/cloud-on-k8s/pkg/utils/k8s"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// transportCertificateAuthorityWatchKey returns the key used by the dynamic watch registration for the
+// user-provided certificate authority issuing transport certificates
+func transportCertificateAuthorityWatchKey(esName string) string {
+	return name.ESNamer.Suffix(esName, "transport-certificate-authority")
+}
+
+// reconcileTransportDynamicWatches reconciles the dynamic watches needed by the transport certificates.
+func reconcileTransportDynamicWatches(dynamicWatches watches.DynamicWatches, es v1alpha1.Elasticsearch) error {
+	// watch the Secret specified in es.Spec.Transport.TLS.CertificateAuthority because if it changes we should
+	// issue new certificates.
+	secretName := es.Spec.Transport.TLS.CertificateAuthority.SecretName
+	watch := watches.NamedWatch{
+		Name: transportCertificateAuthorityWatchKey(es.Name),
+		Watched: []types.NamespacedName{{
+			Namespace: es.Namespace,
+			Name:      secretName,
+		}},
+		Watcher: k8s.ExtractNamespacedName(&es),
+	}
+
+	if secretName != "" {
+		return dynamicWatches.Secrets.AddHandler(watch)
+	}
+	// remove the watch if no longer configured.
+	dynamicWatches.Secrets.RemoveHandlerForKey(watch.Key())
+	return nil
+}
+
+// DynamicWatchesFinalizer returns a Finalizer for dynamic watches related to transport certificates
+func DynamicWatchesFinalizer(dynamicWatches watches.DynamicWatches, es v1alpha1.Elasticsearch) finalizer.Finalizer {
+	return finalizer.Finalizer{
+		Name: "finalizer.elasticsearch.k8s.elastic.co/transport-certificates-secret",
+		Execute: func() error {
+			// es resource is being finalized, so we no longer need the dynamic watch
+			dynamicWatches.Secrets.RemoveHandlerForKey(transportCertificateAuthorityWatchKey(es.Name))
+			return nil
+		},
+	}
+}
@@ -5,6 +5,9 @@
 package transport
 
 import (
+	"bytes"
+	"crypto"
+	cryptorand "crypto/rand"
 	"crypto/x509"
 	"crypto/x509/pkix"
 	"fmt"
@@ -18,13 +21,44 @@ import (
 	corev1 "k8s.io/api/core/v1"
 )
 
+// createCSR creates a certificate signing request for the given pod, signed with the given private key.
+// The CSR subject and subject alternative names are the ones expected for the pod certificate.
+func createCSR(privateKey crypto.Signer, pod corev1.Pod, cluster v1alpha1.Elasticsearch) ([]byte, error) {
+	generalNames, err := buildGeneralNames(cluster, pod)
+	if err != nil {
+		return nil, err
+	}
+	generalNamesBytes, err := certificates.MarshalToSubjectAlternativeNamesData(generalNames)
+	if err != nil {
+		return nil, err
+	}
+
+	return x509.CreateCertificateRequest(cryptorand.Reader, &x509.CertificateRequest{
+		Subject: pkix.Name{
+			CommonName: buildCertificateCommonName(pod, cluster.Name, cluster.Namespace),
+		},
+		ExtraExtensions: []pkix.Extension{
+			{Id: certificates.SubjectAlternativeNamesObjectIdentifier, Value: generalNamesBytes},
+		},
+	}, privateKey)
+}
+
 // createValidatedCertificateTemplate validates a CSR and creates a certificate template.
+//
+// The CSR must be signed by the private key of its public key, and must request the subject and subject alternative
+// names expected for the pod. The pod UID is set as the subject serial number of the certificate, to bind it to this
+// specific pod: a pod re-created with the same name does not get the certificate of its predecessor.
 func createValidatedCertificateTemplate(
 	pod corev1.Pod,
 	cluster v1alpha1.Elasticsearch,
 	csr *x509.CertificateRequest,
 	certValidity time.Duration,
 ) (*certificates.ValidatedCertificateTemplate, error) {
+	// make sure the requester owns the private key of the public key to certify
+	if err := csr.CheckSignature(); err != nil {
+		return nil, errors.Wrap(err, "invalid CSR signature")
+	}
+
 	generalNames, err := buildGeneralNames(cluster, pod)
 	if err != nil {
 		return nil, err
@@ -35,11 +69,16 @@ func createValidatedCertificateTemplate(
 		return nil, err
 	}
 
-	// TODO: csr signature is not checked
+	commonName := buildCertificateCommonName(pod, cluster.Name, cluster.Namespace)
+	if err := validateCSRIdentity(csr, commonName, generalNamesBytes); err != nil {
+		return nil, err
+	}
+
 	certificateTemplate := certificates.ValidatedCertificateTemplate(x509.Certificate{
 		Subject: pkix.Name{
-			CommonName:         buildCertificateCommonName(pod, cluster.Name, cluster.Namespace),
+			CommonName:         commonName,
 			OrganizationalUnit: []string{cluster.Name},
+			SerialNumber:       string(pod.UID),
 		},
 
 		ExtraExtensions: []pkix.Extension{
@@ -51,9 +90,6 @@ func createValidatedCertificateTemplate(
 		PublicKeyAlgorithm: csr.PublicKeyAlgorithm,
 		PublicKey:          csr.PublicKey,
 
-		Signature:          csr.Signature,
-		SignatureAlgorithm: csr.SignatureAlgorithm,
-
 		KeyUsage:    x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
 		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
 	})
@@ -61,6 +97,31 @@ func createValidatedCertificateTemplate(
 	return &certificateTemplate, nil
 }
 
+// validateCSRIdentity returns an error if the subject or the subject alternative names requested by the CSR
+// are not the expected ones.
+func validateCSRIdentity(csr *x509.CertificateRequest, commonName string, generalNamesBytes []byte) error {
+	if csr.Subject.CommonName != commonName {
+		return fmt.Errorf("CSR subject common name %s does not match the expected %s", csr.Subject.CommonName, commonName)
+	}
+	sanFound := false
+	for _, ext := range csr.Extensions {
+		if !ext.Id.Equal(certificates.SubjectAlternativeNamesObjectIdentifier) {
+			continue
+		}
+		if sanFound {
+			return errors.New("CSR has more than one subject alternative names extension")
+		}
+		sanFound = true
+		if !bytes.Equal(ext.Value, generalNamesBytes) {
+			return fmt.Errorf("CSR subject alternative names do not match the expected ones for %s", commonName)
+		}
+	}
+	if !sanFound {
+		return errors.New("CSR has no subject alternative names extension")
+	}
+	return nil
+}
+
 func buildGeneralNames(
 	cluster v1alpha1.Elasticsearch,
 	pod corev1.Pod,
@@ -5,7 +5,9 @@
 package transport
 
 import (
+	cryptorand "crypto/rand"
 	"crypto/x509"
+	"crypto/x509/pkix"
 	"net"
 	"testing"
 
@@ -14,6 +16,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
 )
 
 // roundTripSerialize does a serialization round-trip of the certificate in order to make sure any extra extensions
@@ -64,6 +67,74 @@ func Test_createValidatedCertificateTemplate(t *testing.T) {
 	assert.Contains(t, otherNames, certificates.GeneralName{OtherName: *otherName})
 }
 
+func Test_createValidatedCertificateTemplate_podIdentity(t *testing.T) {
+	pod := testPod.DeepCopy()
+	pod.UID = types.UID("test-pod-uid")
+
+	validatedCert, err := createValidatedCertificateTemplate(*pod, testES, testCSR, certificates.DefaultCertValidity)
+	require.NoError(t, err)
+	certRT, err := roundTripSerialize(validatedCert)
+	require.NoError(t, err)
+
+	// the certificate is bound to the pod UID
+	assert.Equal(t, "test-pod-uid", certRT.Subject.SerialNumber)
+}
+
+func Test_createValidatedCertificateTemplate_invalidCSR(t *testing.T) {
+	parseCSR := func(csrBytes []byte, err error) *x509.CertificateRequest {
+		require.NoError(t, err)
+		csr, err := x509.ParseCertificateRequest(csrBytes)
+		require.NoError(t, err)
+		return csr
+	}
+
+	otherPod := testPod.DeepCopy()
+	otherPod.Name = "other-pod-name"
+	otherIPPod := testPod.DeepCopy()
+	otherIPPod.Status.PodIP = "5.6.7.8"
+
+	tests := []struct {
+		name string
+		csr  func() *x509.CertificateRequest
+	}{
+		{
+			name: "invalid signature",
+			csr: func() *x509.CertificateRequest {
+				csr := *testCSR
+				csr.Signature = append([]byte{}, testCSR.Signature...)
+				csr.Signature[0] ^= 0xff
+				return &csr
+			},
+		},
+		{
+			name: "subject does not match the pod",
+			csr: func() *x509.CertificateRequest {
+				return parseCSR(createCSR(testRSAPrivateKey, *otherPod, testES))
+			},
+		},
+		{
+			name: "subject alternative names do not match the pod",
+			csr: func() *x509.CertificateRequest {
+				return parseCSR(createCSR(testRSAPrivateKey, *otherIPPod, testES))
+			},
+		},
+		{
+			name: "no subject alternative names",
+			csr: func() *x509.CertificateRequest {
+				return parseCSR(x509.CreateCertificateRequest(cryptorand.Reader, &x509.CertificateRequest{
+					Subject: pkix.Name{CommonName: buildCertificateCommonName(testPod, testES.Name, testES.Namespace)},
+				}, testRSAPrivateKey))
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := createValidatedCertificateTemplate(testPod, testES, tt.csr(), certificates.DefaultCertValidity)
+			require.Error(t, err)
+		})
+	}
+}
+
 func Test_buildGeneralNames(t *testing.T) {
 	expectedCommonName := "test-pod-name.node.test-es-name.test-namespace.es.local"
 	otherName, err := (&certificates.UTF8StringValuedOtherName{
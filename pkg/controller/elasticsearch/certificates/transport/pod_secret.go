@@ -5,12 +5,14 @@
 package transport
 
 import (
-	cryptorand "crypto/rand"
-	"crypto/rsa"
+	"bytes"
+	"crypto"
 	"crypto/x509"
 	"errors"
 	"fmt"
 	"reflect"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/elastic/cloud-on-k8s/pkg/apis/elasticsearch/v1alpha1"
@@ -28,6 +30,40 @@ func PodCertFileName(podName string) string {
 	return fmt.Sprintf("%s.%s", podName, certificates.CertFileName)
 }
 
+// podsWithReusedKeys returns the names of the pods whose private key in the secret is also the private key of
+// another pod. The first pod in alphabetical order keeps its key, the others are returned.
+func podsWithReusedKeys(secret corev1.Secret) []string {
+	podsByPublicKey := make(map[string][]string)
+	keyFileSuffix := "." + certificates.KeyFileName
+	for secretDataKey, privateKeyData := range secret.Data {
+		if !strings.HasSuffix(secretDataKey, keyFileSuffix) {
+			continue
+		}
+		privateKey, err := certificates.ParsePEMPrivateKey(privateKeyData)
+		if err != nil {
+			// the key will be replaced by a new one anyway
+			continue
+		}
+		publicKey, err := x509.MarshalPKIXPublicKey(privateKey.Public())
+		if err != nil {
+			continue
+		}
+		podName := strings.TrimSuffix(secretDataKey, keyFileSuffix)
+		podsByPublicKey[string(publicKey)] = append(podsByPublicKey[string(publicKey)], podName)
+	}
+
+	var reused []string
+	for _, podNames := range podsByPublicKey {
+		if len(podNames) < 2 {
+			continue
+		}
+		sort.Strings(podNames)
+		reused = append(reused, podNames[1:]...)
+	}
+	sort.Strings(reused)
+	return reused
+}
+
 // ensureTransportCertificatesSecretContentsForPod ensures that the transport certificates secret has the correct
 // content for a specific pod
 func ensureTransportCertificatesSecretContentsForPod(
@@ -36,16 +72,23 @@ func ensureTransportCertificatesSecretContentsForPod(
 	pod corev1.Pod,
 	ca *certificates.CA,
 	rotationParams certificates.RotationParams,
+	keyParams certificates.KeyParams,
 ) error {
-	// verify that the secret contains a parsable private key, create if it does not exist
-	var privateKey *rsa.PrivateKey
+	rotatePreviousPodEntries(es, secret, pod)
+
+	// verify that the secret contains a parsable private key generated with the expected params, create if it does not exist
+	var privateKey crypto.Signer
 	needsNewPrivateKey := true
 	if privateKeyData, ok := secret.Data[PodKeyFileName(pod.Name)]; ok {
 		storedPrivateKey, err := certificates.ParsePEMPrivateKey(privateKeyData)
-		if err != nil {
+		switch {
+		case err != nil:
 			log.Error(err, "Unable to parse stored private key",
 				"namespace", pod.Namespace, "pod_name", pod.Name)
-		} else {
+		case !keyParams.Matches(storedPrivateKey.Public()):
+			log.Info("Stored private key algorithm or size does not match the expected one",
+				"namespace", pod.Namespace, "pod_name", pod.Name)
+		default:
 			needsNewPrivateKey = false
 			privateKey = storedPrivateKey
 		}
@@ -53,13 +96,17 @@ func ensureTransportCertificatesSecretContentsForPod(
 
 	// if we need a new private key, generate it
 	if needsNewPrivateKey {
-		generatedPrivateKey, err := rsa.GenerateKey(cryptorand.Reader, 2048)
+		generatedPrivateKey, err := keyParams.GenerateKey()
+		if err != nil {
+			return err
+		}
+		privateKeyData, err := certificates.EncodePEMPrivateKey(generatedPrivateKey)
 		if err != nil {
 			return err
 		}
 
 		privateKey = generatedPrivateKey
-		secret.Data[PodKeyFileName(pod.Name)] = certificates.EncodePEMPrivateKey(*privateKey)
+		secret.Data[PodKeyFileName(pod.Name)] = privateKeyData
 	}
 
 	if shouldIssueNewCertificate(es, *secret, pod, privateKey, ca, rotationParams.RotateBefore) {
@@ -68,7 +115,7 @@ func ensureTransportCertificatesSecretContentsForPod(
 			"pod_name", pod.Name,
 		)
 
-		csr, err := x509.CreateCertificateRequest(cryptorand.Reader, &x509.CertificateRequest{}, privateKey)
+		csr, err := createCSR(privateKey, pod, es)
 		if err != nil {
 			return err
 		}
@@ -92,25 +139,43 @@ func ensureTransportCertificatesSecretContentsForPod(
 		}
 
 		// store the issued certificate in a secret mounted into the pod
-		secret.Data[PodCertFileName(pod.Name)] = certificates.EncodePEMCert(certData, ca.Cert.Raw)
+		secret.Data[PodCertFileName(pod.Name)] = append(certificates.EncodePEMCert(certData), ca.PEMChain()...)
 	}
 
 	return nil
 }
 
+// rotatePreviousPodEntries removes the private key and certificate of the given pod from the secret if the
+// certificate was issued to a previous pod with the same name, so that new ones are generated for this pod.
+func rotatePreviousPodEntries(es v1alpha1.Elasticsearch, secret *corev1.Secret, pod corev1.Pod) {
+	if _, exists := secret.Data[PodCertFileName(pod.Name)]; !exists {
+		return
+	}
+	cert := extractTransportCert(*secret, pod, buildCertificateCommonName(pod, es.Name, es.Namespace))
+	if cert == nil || cert.Subject.SerialNumber == string(pod.UID) {
+		return
+	}
+	log.Info("Certificate was issued to a previous pod with the same name, rotating the private key and certificate",
+		"namespace", pod.Namespace, "pod_name", pod.Name, "pod_uid", pod.UID, "cert_pod_uid", cert.Subject.SerialNumber)
+	delete(secret.Data, PodKeyFileName(pod.Name))
+	delete(secret.Data, PodCertFileName(pod.Name))
+}
+
 // shouldIssueNewCertificate returns true if we should issue a new certificate.
 //
 // Reasons for reissuing a certificate:
 // - no certificate yet
 // - certificate has the wrong format
+// - certificate was issued to a different pod
 // - certificate is invalid or expired
 // - certificate has no SAN extra extension
 // - certificate SAN and IP does not match pod SAN and IP
+// - certificate chain does not match the CA chain
 func shouldIssueNewCertificate(
 	es v1alpha1.Elasticsearch,
 	secret corev1.Secret,
 	pod corev1.Pod,
-	privateKey *rsa.PrivateKey,
+	privateKey crypto.Signer,
 	ca *certificates.CA,
 	certReconcileBefore time.Duration,
 ) bool {
@@ -128,8 +193,13 @@ func shouldIssueNewCertificate(
 		return true
 	}
 
-	publicKey, publicKeyOk := cert.PublicKey.(*rsa.PublicKey)
-	if !publicKeyOk || publicKey.N.Cmp(privateKey.PublicKey.N) != 0 || publicKey.E != privateKey.PublicKey.E {
+	if cert.Subject.SerialNumber != string(pod.UID) {
+		log.Info("Certificate was issued to a different pod, should issue new",
+			"namespace", pod.Namespace, "pod_name", pod.Name, "pod_uid", pod.UID)
+		return true
+	}
+
+	if !certificates.PrivateMatchesPublicKey(cert.PublicKey, privateKey) {
 		log.Info(
 			"Certificate belongs do a different public key, should issue new",
 			"namespace", pod.Namespace,
@@ -141,8 +211,7 @@ func shouldIssueNewCertificate(
 		return true
 	}
 
-	pool := x509.NewCertPool()
-	pool.AddCert(ca.Cert)
+	pool := ca.CertPool()
 	verifyOpts := x509.VerifyOptions{
 		DNSName:       certCommonName,
 		Roots:         pool,
@@ -192,6 +261,13 @@ func shouldIssueNewCertificate(
 		return true
 	}
 
+	// the CA certificate may have been renewed with the same key, or its chain may have changed
+	if !bytes.HasSuffix(secret.Data[PodCertFileName(pod.Name)], ca.PEMChain()) {
+		log.Info("Certificate chain does not match the CA chain, should issue new",
+			"namespace", pod.Namespace, "pod_name", pod.Name)
+		return true
+	}
+
 	return false
 }
 
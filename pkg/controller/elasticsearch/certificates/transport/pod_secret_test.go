@@ -5,14 +5,17 @@
 package transport
 
 import (
+	"crypto/ecdsa"
 	"testing"
 	"time"
 
+	commonv1alpha1 "github.com/elastic/cloud-on-k8s/pkg/apis/common/v1alpha1"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/common/certificates"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
 )
 
 func Test_shouldIssueNewCertificate(t *testing.T) {
@@ -71,6 +74,23 @@ func Test_shouldIssueNewCertificate(t *testing.T) {
 			},
 			want: false,
 		},
+		{
+			name: "cert issued to a previous pod with the same name",
+			args: args{
+				secret: corev1.Secret{
+					Data: map[string][]byte{
+						PodCertFileName(testPod.Name): pemCert,
+					},
+				},
+				pod: func() *corev1.Pod {
+					pod := testPod.DeepCopy()
+					pod.UID = types.UID("new-pod-uid")
+					return pod
+				}(),
+				rotateBefore: certificates.DefaultRotateBefore,
+			},
+			want: true,
+		},
 		{
 			name: "should be rotated soon",
 			args: args{
@@ -83,6 +103,18 @@ func Test_shouldIssueNewCertificate(t *testing.T) {
 			},
 			want: true,
 		},
+		{
+			name: "cert chain does not include the CA",
+			args: args{
+				secret: corev1.Secret{
+					Data: map[string][]byte{
+						PodCertFileName(testPod.Name): certificates.EncodePEMCert(certData),
+					},
+				},
+				rotateBefore: certificates.DefaultRotateBefore,
+			},
+			want: true,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -105,10 +137,14 @@ func Test_shouldIssueNewCertificate(t *testing.T) {
 }
 
 func Test_ensureTransportCertificatesSecretContentsForPod(t *testing.T) {
+	pemPrivateKey, err := certificates.EncodePEMPrivateKey(testRSAPrivateKey)
+	require.NoError(t, err)
+
 	tests := []struct {
 		name       string
 		secret     *corev1.Secret
 		pod        *corev1.Pod
+		keyParams  *certificates.KeyParams
 		assertions func(t *testing.T, before corev1.Secret, after corev1.Secret)
 		wantErr    func(t *testing.T, err error)
 	}{
@@ -131,7 +167,7 @@ func Test_ensureTransportCertificatesSecretContentsForPod(t *testing.T) {
 			name: "no cert in the secret",
 			secret: &corev1.Secret{
 				Data: map[string][]byte{
-					PodKeyFileName(testPod.Name): certificates.EncodePEMPrivateKey(*testRSAPrivateKey),
+					PodKeyFileName(testPod.Name): pemPrivateKey,
 				},
 			},
 			assertions: func(t *testing.T, before corev1.Secret, after corev1.Secret) {
@@ -146,7 +182,7 @@ func Test_ensureTransportCertificatesSecretContentsForPod(t *testing.T) {
 			name: "cert does not belong to the key in the secret",
 			secret: &corev1.Secret{
 				Data: map[string][]byte{
-					PodKeyFileName(testPod.Name):  certificates.EncodePEMPrivateKey(*testRSAPrivateKey),
+					PodKeyFileName(testPod.Name):  pemPrivateKey,
 					PodCertFileName(testPod.Name): certificates.EncodePEMCert(testCA.Cert.Raw),
 				},
 			},
@@ -163,7 +199,7 @@ func Test_ensureTransportCertificatesSecretContentsForPod(t *testing.T) {
 			name: "invalid cert in the secret",
 			secret: &corev1.Secret{
 				Data: map[string][]byte{
-					PodKeyFileName(testPod.Name):  certificates.EncodePEMPrivateKey(*testRSAPrivateKey),
+					PodKeyFileName(testPod.Name):  pemPrivateKey,
 					PodCertFileName(testPod.Name): []byte("invalid"),
 				},
 			},
@@ -176,11 +212,57 @@ func Test_ensureTransportCertificatesSecretContentsForPod(t *testing.T) {
 				assert.NotEqual(t, after.Data[PodCertFileName(testPod.Name)], before.Data[PodCertFileName(testPod.Name)])
 			},
 		},
+		{
+			name: "key does not match the expected algorithm",
+			secret: &corev1.Secret{
+				Data: map[string][]byte{
+					PodKeyFileName(testPod.Name):  pemPrivateKey,
+					PodCertFileName(testPod.Name): pemCert,
+				},
+			},
+			keyParams: &certificates.KeyParams{Algorithm: commonv1alpha1.ECDSAPrivateKeyAlgorithm, Size: 256},
+			assertions: func(t *testing.T, before corev1.Secret, after corev1.Secret) {
+				// key should be re-generated with the expected algorithm
+				assert.NotEqual(t, before.Data[PodKeyFileName(testPod.Name)], after.Data[PodKeyFileName(testPod.Name)])
+				privateKey, err := certificates.ParsePEMPrivateKey(after.Data[PodKeyFileName(testPod.Name)])
+				require.NoError(t, err)
+				_, isECDSA := privateKey.(*ecdsa.PrivateKey)
+				assert.True(t, isECDSA)
+
+				// and the cert re-issued for the new key
+				assert.NotEqual(t, before.Data[PodCertFileName(testPod.Name)], after.Data[PodCertFileName(testPod.Name)])
+				certs, err := certificates.ParsePEMCerts(after.Data[PodCertFileName(testPod.Name)])
+				require.NoError(t, err)
+				assert.True(t, certificates.PrivateMatchesPublicKey(certs[0].PublicKey, privateKey))
+			},
+		},
+		{
+			name: "cert issued to a previous pod with the same name",
+			secret: &corev1.Secret{
+				Data: map[string][]byte{
+					PodKeyFileName(testPod.Name):  pemPrivateKey,
+					PodCertFileName(testPod.Name): pemCert,
+				},
+			},
+			pod: func() *corev1.Pod {
+				pod := testPod.DeepCopy()
+				pod.UID = types.UID("new-pod-uid")
+				return pod
+			}(),
+			assertions: func(t *testing.T, before corev1.Secret, after corev1.Secret) {
+				// both key and cert should be rotated
+				assert.NotEqual(t, before.Data[PodKeyFileName(testPod.Name)], after.Data[PodKeyFileName(testPod.Name)])
+				assert.NotEqual(t, before.Data[PodCertFileName(testPod.Name)], after.Data[PodCertFileName(testPod.Name)])
+				certs, err := certificates.ParsePEMCerts(after.Data[PodCertFileName(testPod.Name)])
+				require.NoError(t, err)
+				assert.Equal(t, "new-pod-uid", certs[0].Subject.SerialNumber)
+			},
+		},
 		{
 			name: "valid data should not require updating",
 			secret: &corev1.Secret{
 				Data: map[string][]byte{
-					PodKeyFileName(testPod.Name):  certificates.EncodePEMPrivateKey(*testRSAPrivateKey),
+					PodKeyFileName(testPod.Name):  pemPrivateKey,
 					PodCertFileName(testPod.Name): pemCert,
 				},
 			},
@@ -198,6 +280,10 @@ func Test_ensureTransportCertificatesSecretContentsForPod(t *testing.T) {
 				tt.pod = testPod.DeepCopy()
 			}
 
+			if tt.keyParams == nil {
+				tt.keyParams = &certificates.DefaultKeyParams
+			}
+
 			beforeSecret := tt.secret.DeepCopy()
 
 			err := ensureTransportCertificatesSecretContentsForPod(
@@ -209,6 +295,7 @@ func Test_ensureTransportCertificatesSecretContentsForPod(t *testing.T) {
 					Validity:     certificates.DefaultCertValidity,
 					RotateBefore: certificates.DefaultRotateBefore,
 				},
+				*tt.keyParams,
 			)
 			if tt.wantErr != nil {
 				tt.wantErr(t, err)
@@ -220,3 +307,56 @@ func Test_ensureTransportCertificatesSecretContentsForPod(t *testing.T) {
 		})
 	}
 }
+
+func Test_podsWithReusedKeys(t *testing.T) {
+	pemPrivateKey, err := certificates.EncodePEMPrivateKey(testRSAPrivateKey)
+	require.NoError(t, err)
+	otherPrivateKey, err := certificates.DefaultKeyParams.GenerateKey()
+	require.NoError(t, err)
+	otherPemPrivateKey, err := certificates.EncodePEMPrivateKey(otherPrivateKey)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name string
+		data map[string][]byte
+		want []string
+	}{
+		{
+			name: "no keys",
+			data: map[string][]byte{certificates.CAFileName: testCA.PEMChain()},
+			want: nil,
+		},
+		{
+			name: "distinct keys",
+			data: map[string][]byte{
+				PodKeyFileName("pod-a"):  pemPrivateKey,
+				PodCertFileName("pod-a"): pemCert,
+				PodKeyFileName("pod-b"):  otherPemPrivateKey,
+			},
+			want: nil,
+		},
+		{
+			name: "key reused by several pods",
+			data: map[string][]byte{
+				PodKeyFileName("pod-c"): pemPrivateKey,
+				PodKeyFileName("pod-a"): pemPrivateKey,
+				PodKeyFileName("pod-b"): otherPemPrivateKey,
+				PodKeyFileName("pod-d"): pemPrivateKey,
+			},
+			want: []string{"pod-c", "pod-d"},
+		},
+		{
+			name: "invalid keys are ignored",
+			data: map[string][]byte{
+				PodKeyFileName("pod-a"): []byte("invalid"),
+				PodKeyFileName("pod-b"): []byte("invalid"),
+			},
+			want: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, podsWithReusedKeys(corev1.Secret{Data: tt.data}))
+		})
+	}
+}
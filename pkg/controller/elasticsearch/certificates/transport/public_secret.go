@@ -34,7 +34,7 @@ func ReconcileTransportCertsPublicSecret(
 	expected := &corev1.Secret{
 		ObjectMeta: meta,
 		Data: map[string][]byte{
-			certificates.CAFileName: certificates.EncodePEMCert(ca.Cert.Raw),
+			certificates.CAFileName: ca.PEMChain(),
 		},
 	}
 	reconciled := &corev1.Secret{}
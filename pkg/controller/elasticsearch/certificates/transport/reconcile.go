@@ -15,6 +15,7 @@ import (
 	"github.com/elastic/cloud-on-k8s/pkg/controller/common/reconciler"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/label"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/name"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/volume"
 	"github.com/elastic/cloud-on-k8s/pkg/utils/k8s"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -27,13 +28,14 @@ import (
 var log = logf.Log.WithName("transport")
 
 // ReconcileTransportCertificatesSecrets reconciles the secret containing transport certificates for all nodes in the
-// cluster.
+// cluster, along with the trust restrictions file listing the nodes whose certificates are trusted.
 func ReconcileTransportCertificatesSecrets(
 	c k8s.Client,
 	scheme *runtime.Scheme,
 	ca *certificates.CA,
 	es v1alpha1.Elasticsearch,
 	rotationParams certificates.RotationParams,
+	keyParams certificates.KeyParams,
 ) (reconcile.Result, error) {
 	var pods corev1.PodList
 	if err := c.List(&client.ListOptions{
@@ -50,6 +52,15 @@ func ReconcileTransportCertificatesSecrets(
 	// defensive copy of the current secret so we can check whether we need to update later on
 	currentTransportCertificatesSecret := secret.DeepCopy()
 
+	// private keys must not be shared between pods: rotate the private key and certificate of pods reusing the key
+	// of another pod
+	for _, podName := range podsWithReusedKeys(*secret) {
+		log.Info("Private key is reused by another pod, rotating the private key and certificate",
+			"namespace", es.Namespace, "pod_name", podName)
+		delete(secret.Data, PodKeyFileName(podName))
+		delete(secret.Data, PodCertFileName(podName))
+	}
+
 	for _, pod := range pods.Items {
 		if pod.Status.PodIP == "" {
 			log.Info("Skipping pod because it has no IP yet", "namespace", pod.Namespace, "pod_name", pod.Name)
@@ -57,7 +68,7 @@ func ReconcileTransportCertificatesSecrets(
 		}
 
 		if err := ensureTransportCertificatesSecretContentsForPod(
-			es, secret, pod, ca, rotationParams,
+			es, secret, pod, ca, rotationParams, keyParams,
 		); err != nil {
 			return reconcile.Result{}, err
 		}
@@ -67,8 +78,8 @@ func ReconcileTransportCertificatesSecrets(
 	podsByName := k8s.PodsByName(pods.Items)
 	keysToPrune := make([]string, 0)
 	for secretDataKey := range secret.Data {
-		if secretDataKey == certificates.CAFileName {
-			// never remove the CA file
+		if secretDataKey == certificates.CAFileName || secretDataKey == volume.TransportTrustRestrictionsFile {
+			// never remove the CA and trust restrictions files
 			continue
 		}
 
@@ -88,13 +99,16 @@ func ReconcileTransportCertificatesSecrets(
 		}
 	}
 
-	caBytes := certificates.EncodePEMCert(ca.Cert.Raw)
+	caBytes := ca.PEMChain()
 
 	// compare with current trusted CA certs.
 	if !bytes.Equal(caBytes, secret.Data[certificates.CAFileName]) {
 		secret.Data[certificates.CAFileName] = caBytes
 	}
 
+	// only trust the certificates of the current pods
+	secret.Data[volume.TransportTrustRestrictionsFile] = buildTrustRestrictions(es, *secret, pods.Items)
+
 	if !reflect.DeepEqual(secret, currentTransportCertificatesSecret) {
 		if err := c.Update(secret); err != nil {
 			return reconcile.Result{}, err
@@ -8,11 +8,14 @@ import (
 	"testing"
 
 	"github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/name"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/volume"
 
 	"github.com/elastic/cloud-on-k8s/pkg/apis/elasticsearch/v1alpha1"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/certificates"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/label"
 	"github.com/elastic/cloud-on-k8s/pkg/utils/k8s"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
@@ -109,3 +112,51 @@ func Test_ensureTransportCertificateSecretExists(t *testing.T) {
 		})
 	}
 }
+
+func TestReconcileTransportCertificatesSecrets_TrustRestrictions(t *testing.T) {
+	es := *testES.DeepCopy()
+	es.Spec.Transport.TLS.TrustRestrictions = &v1alpha1.TrustRestrictions{
+		SubjectNames: []string{"*.node.remote.default.es.local"},
+	}
+	pod := corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "pod-a",
+			Namespace: es.Namespace,
+			Labels:    map[string]string{label.ClusterNameLabelName: es.Name},
+		},
+		Status: corev1.PodStatus{PodIP: testIP},
+	}
+	c := k8s.WrapClient(fake.NewFakeClient(&pod))
+	reconcileTrustRestrictions := func() string {
+		_, err := ReconcileTransportCertificatesSecrets(
+			c,
+			scheme.Scheme,
+			testCA,
+			es,
+			certificates.RotationParams{
+				Validity:     certificates.DefaultCertValidity,
+				RotateBefore: certificates.DefaultRotateBefore,
+			},
+			certificates.DefaultKeyParams,
+		)
+		require.NoError(t, err)
+		var secret corev1.Secret
+		require.NoError(t, c.Get(types.NamespacedName{
+			Namespace: es.Namespace,
+			Name:      name.TransportCertificatesSecret(es.Name),
+		}, &secret))
+		return string(secret.Data[volume.TransportTrustRestrictionsFile])
+	}
+
+	// the remote subject names are trusted along with the pods of the cluster
+	require.Equal(t, "trust.subject_name:\n"+
+		"  - \"*.node.remote.default.es.local\"\n"+
+		"  - \"pod-a.node.test-es-name.test-namespace.es.local\"\n",
+		reconcileTrustRestrictions())
+
+	// and are still trusted on subsequent reconciliations, once the pod is removed
+	require.NoError(t, c.Delete(&pod))
+	require.Equal(t, "trust.subject_name:\n"+
+		"  - \"*.node.remote.default.es.local\"\n",
+		reconcileTrustRestrictions())
+}
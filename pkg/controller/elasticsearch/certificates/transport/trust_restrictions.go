@@ -0,0 +1,51 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package transport
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+
+	"github.com/elastic/cloud-on-k8s/pkg/apis/elasticsearch/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// buildTrustRestrictions returns the content of the trust restrictions file of the cluster.
+//
+// Elasticsearch nodes only trust the transport certificates whose ES othername is listed in this file, which is
+// reloaded when it changes. Only the pods that currently have a certificate in the transport certificates secret
+// are listed: the certificates of pods removed from the cluster, although still signed by the CA, are not trusted
+// anymore. The subject names specified by the user, such as the names of the nodes of remote clusters, are always
+// listed.
+func buildTrustRestrictions(es v1alpha1.Elasticsearch, secret corev1.Secret, pods []corev1.Pod) []byte {
+	uniqueNames := make(map[string]struct{})
+	if restrictions := es.Spec.Transport.TLS.TrustRestrictions; restrictions != nil {
+		for _, subjectName := range restrictions.SubjectNames {
+			uniqueNames[subjectName] = struct{}{}
+		}
+	}
+	for _, pod := range pods {
+		if _, exists := secret.Data[PodCertFileName(pod.Name)]; !exists {
+			continue
+		}
+		uniqueNames[buildCertificateCommonName(pod, es.Name, es.Namespace)] = struct{}{}
+	}
+	subjectNames := make([]string, 0, len(uniqueNames))
+	for subjectName := range uniqueNames {
+		subjectNames = append(subjectNames, subjectName)
+	}
+	sort.Strings(subjectNames)
+
+	if len(subjectNames) == 0 {
+		return []byte("trust.subject_name: []\n")
+	}
+	var buf bytes.Buffer
+	buf.WriteString("trust.subject_name:\n")
+	for _, subjectName := range subjectNames {
+		buf.WriteString(fmt.Sprintf("  - %q\n", subjectName))
+	}
+	return buf.Bytes()
+}
@@ -0,0 +1,94 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package transport
+
+import (
+	"testing"
+
+	"github.com/elastic/cloud-on-k8s/pkg/apis/elasticsearch/v1alpha1"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func Test_buildTrustRestrictions(t *testing.T) {
+	podA := corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod-a"}}
+	podB := corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod-b"}}
+	remoteES := *testES.DeepCopy()
+	remoteES.Spec.Transport.TLS.TrustRestrictions = &v1alpha1.TrustRestrictions{
+		SubjectNames: []string{"*.node.remote.default.es.local", "pod-a.node.test-es-name.test-namespace.es.local"},
+	}
+	tests := []struct {
+		name   string
+		es     v1alpha1.Elasticsearch
+		secret corev1.Secret
+		pods   []corev1.Pod
+		want   string
+	}{
+		{
+			name:   "no pods",
+			es:     testES,
+			secret: corev1.Secret{},
+			pods:   nil,
+			want:   "trust.subject_name: []\n",
+		},
+		{
+			name:   "pods without certificates are not trusted",
+			es:     testES,
+			secret: corev1.Secret{Data: map[string][]byte{PodKeyFileName(podA.Name): []byte("key")}},
+			pods:   []corev1.Pod{podA},
+			want:   "trust.subject_name: []\n",
+		},
+		{
+			name: "pods with certificates are trusted in a stable order",
+			es:   testES,
+			secret: corev1.Secret{Data: map[string][]byte{
+				PodCertFileName(podA.Name): []byte("cert"),
+				PodCertFileName(podB.Name): []byte("cert"),
+			}},
+			pods: []corev1.Pod{podB, podA},
+			want: "trust.subject_name:\n" +
+				"  - \"pod-a.node.test-es-name.test-namespace.es.local\"\n" +
+				"  - \"pod-b.node.test-es-name.test-namespace.es.local\"\n",
+		},
+		{
+			name: "certificates of removed pods are not trusted",
+			es:   testES,
+			secret: corev1.Secret{Data: map[string][]byte{
+				PodCertFileName(podA.Name): []byte("cert"),
+				PodCertFileName("removed"): []byte("cert"),
+			}},
+			pods: []corev1.Pod{podA},
+			want: "trust.subject_name:\n" +
+				"  - \"pod-a.node.test-es-name.test-namespace.es.local\"\n",
+		},
+		{
+			name:   "user subject names are trusted without pods",
+			es:     remoteES,
+			secret: corev1.Secret{},
+			want: "trust.subject_name:\n" +
+				"  - \"*.node.remote.default.es.local\"\n" +
+				"  - \"pod-a.node.test-es-name.test-namespace.es.local\"\n",
+		},
+		{
+			name: "user subject names are merged with the names of the pods",
+			es:   remoteES,
+			secret: corev1.Secret{Data: map[string][]byte{
+				PodCertFileName(podA.Name): []byte("cert"),
+				PodCertFileName(podB.Name): []byte("cert"),
+			}},
+			pods: []corev1.Pod{podA, podB},
+			want: "trust.subject_name:\n" +
+				"  - \"*.node.remote.default.es.local\"\n" +
+				"  - \"pod-a.node.test-es-name.test-namespace.es.local\"\n" +
+				"  - \"pod-b.node.test-es-name.test-namespace.es.local\"\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, string(buildTrustRestrictions(tt.es, tt.secret, tt.pods)))
+		})
+	}
+}
@@ -102,6 +102,14 @@ type Client interface {
 	//
 	// Introduced in: Elasticsearch 7.0.0
 	DeleteVotingConfigExclusions(ctx context.Context, waitForRemoval bool) error
+	// CreateAPIKey creates an API key owned by the authenticated user.
+	//
+	// Introduced in: Elasticsearch 6.7.0
+	CreateAPIKey(ctx context.Context, request APIKeyCreateRequest) (APIKeyCreateResponse, error)
+	// InvalidateAPIKey invalidates the API key with the given ID.
+	//
+	// Introduced in: Elasticsearch 6.7.0
+	InvalidateAPIKey(ctx context.Context, id string) error
 	// Request exposes a low level interface to the underlying HTTP client e.g. for testing purposes.
 	// The Elasticsearch endpoint will be added automatically to the request URL which should therefore just be the path
 	// with a leading /
@@ -113,6 +121,12 @@ type Client interface {
 // If dialer is not nil, it will be used to create new TCP connections
 func NewElasticsearchClient(dialer net.Dialer, esURL string, esUser UserAuth, v version.Version, caCerts []*x509.Certificate) Client {
 	certPool := x509.NewCertPool()
+	if len(caCerts) == 0 {
+		// no CA provided (e.g. an external cluster using a publicly trusted certificate): rely on the system trust store
+		if systemPool, err := x509.SystemCertPool(); err == nil {
+			certPool = systemPool
+		}
+	}
 	for _, c := range caCerts {
 		certPool.AddCert(c)
 	}
@@ -572,6 +572,34 @@ func TestClient_DeleteVotingConfigExclusions(t *testing.T) {
 	}
 }
 
+func TestClient_CreateAPIKey(t *testing.T) {
+	client := NewMockClient(version.MustParse("7.6.0"), func(req *http.Request) *http.Response {
+		require.Equal(t, http.MethodPost, req.Method)
+		require.Equal(t, "/_security/api_key", req.URL.Path)
+		var request APIKeyCreateRequest
+		require.NoError(t, json.NewDecoder(req.Body).Decode(&request))
+		require.Equal(t, "my-key", request.Name)
+		return NewMockResponse(200, req, `{"id":"VuaCfGcBCdbkQm-e5aOx","name":"my-key","api_key":"ui2lp2axTNmsyakw9tvNnw"}`)
+	})
+	response, err := client.CreateAPIKey(context.Background(), APIKeyCreateRequest{Name: "my-key"})
+	require.NoError(t, err)
+	require.Equal(t, "VuaCfGcBCdbkQm-e5aOx", response.ID)
+	// base64 of VuaCfGcBCdbkQm-e5aOx:ui2lp2axTNmsyakw9tvNnw
+	require.Equal(t, "VnVhQ2ZHY0JDZGJrUW0tZTVhT3g6dWkybHAyYXhUTm1zeWFrdzl0dk5udw==", response.Credentials())
+}
+
+func TestClient_InvalidateAPIKey(t *testing.T) {
+	client := NewMockClient(version.MustParse("7.6.0"), func(req *http.Request) *http.Response {
+		require.Equal(t, http.MethodDelete, req.Method)
+		require.Equal(t, "/_security/api_key", req.URL.Path)
+		var request APIKeyInvalidateRequest
+		require.NoError(t, json.NewDecoder(req.Body).Decode(&request))
+		require.Equal(t, "VuaCfGcBCdbkQm-e5aOx", request.ID)
+		return NewMockResponse(200, req, `{}`)
+	})
+	require.NoError(t, client.InvalidateAPIKey(context.Background(), "VuaCfGcBCdbkQm-e5aOx"))
+}
+
 func TestClient_SetMinimumMasterNodes(t *testing.T) {
 	tests := []struct {
 		name         string
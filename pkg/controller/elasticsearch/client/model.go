@@ -5,6 +5,7 @@
 package client
 
 import (
+	"encoding/base64"
 	"encoding/json"
 	"strconv"
 	"time"
@@ -372,3 +373,41 @@ type SearchResults struct {
 	Shards json.RawMessage            // model when needed
 	Aggs   map[string]json.RawMessage // model when needed
 }
+
+// APIKeyApplicationPrivileges are privileges granted on an application to an API key.
+type APIKeyApplicationPrivileges struct {
+	Application string   `json:"application"`
+	Privileges  []string `json:"privileges"`
+	Resources   []string `json:"resources"`
+}
+
+// APIKeyRoleDescriptor limits the privileges of an API key.
+type APIKeyRoleDescriptor struct {
+	Cluster      []string                      `json:"cluster,omitempty"`
+	Applications []APIKeyApplicationPrivileges `json:"applications,omitempty"`
+}
+
+// APIKeyCreateRequest is the request to create an API key.
+type APIKeyCreateRequest struct {
+	Name            string                          `json:"name"`
+	Expiration      string                          `json:"expiration,omitempty"`
+	RoleDescriptors map[string]APIKeyRoleDescriptor `json:"role_descriptors,omitempty"`
+}
+
+// APIKeyCreateResponse is the response to an API key creation request.
+type APIKeyCreateResponse struct {
+	ID     string `json:"id"`
+	Name   string `json:"name"`
+	APIKey string `json:"api_key"`
+}
+
+// Credentials returns the base64 encoded credentials of the API key, as expected in the
+// `Authorization: ApiKey <credentials>` header.
+func (r APIKeyCreateResponse) Credentials() string {
+	return base64.StdEncoding.EncodeToString([]byte(r.ID + ":" + r.APIKey))
+}
+
+// APIKeyInvalidateRequest is the request to invalidate an API key.
+type APIKeyInvalidateRequest struct {
+	ID string `json:"id"`
+}
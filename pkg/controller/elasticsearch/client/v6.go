@@ -128,6 +128,15 @@ func (c *clientV6) DeleteVotingConfigExclusions(ctx context.Context, waitForRemo
 	return errors.New("Not supported in Elasticsearch 6.x")
 }
 
+func (c *clientV6) CreateAPIKey(ctx context.Context, request APIKeyCreateRequest) (APIKeyCreateResponse, error) {
+	var response APIKeyCreateResponse
+	return response, c.post(ctx, "/_security/api_key", request, &response)
+}
+
+func (c *clientV6) InvalidateAPIKey(ctx context.Context, id string) error {
+	return c.delete(ctx, "/_security/api_key", APIKeyInvalidateRequest{ID: id}, nil)
+}
+
 func (c *clientV6) Request(ctx context.Context, r *http.Request) (*http.Response, error) {
 	newURL, err := url.Parse(stringsutil.Concat(c.Endpoint, r.URL.String()))
 	if err != nil {
@@ -60,6 +60,8 @@ func removeUUIDAnnotation(client k8s.Client, es *v1alpha1.Elasticsearch) error {
 
 // clusterNeedsReBootstrap is true if we are updating a single master cluster from 6.x to 7.x
 // because we lose the 'cluster' when rolling the single master node.
+// This is also the case when updating from 6.x to 7.x with a full cluster restart, since all the master nodes
+// are restarted at once.
 // Invariant: no grow and shrink
 func clusterNeedsReBootstrap(client k8s.Client, es *v1alpha1.Elasticsearch) (bool, error) {
 	initialZen2Upgrade, err := zen2.IsInitialZen2Upgrade(client, *es)
@@ -70,7 +72,7 @@ func clusterNeedsReBootstrap(client k8s.Client, es *v1alpha1.Elasticsearch) (boo
 	if err != nil {
 		return false, err
 	}
-	return len(currentMasters) == 1 && initialZen2Upgrade, nil
+	return (len(currentMasters) == 1 || es.Spec.UpdateStrategy.FullClusterRestart()) && initialZen2Upgrade, nil
 }
 
 // clusterIsBootstrapped returns true if the cluster has formed and has a UUID.
@@ -47,6 +47,11 @@ func reBootstrappingES() *v1alpha1.Elasticsearch {
 	}
 }
 
+func fullClusterRestartES(es *v1alpha1.Elasticsearch) *v1alpha1.Elasticsearch {
+	es.Spec.UpdateStrategy.Type = v1alpha1.FullClusterRestartStrategyType
+	return es
+}
+
 func TestAnnotatedForBootstrap(t *testing.T) {
 	require.True(t, AnnotatedForBootstrap(*bootstrappedES()))
 	require.False(t, AnnotatedForBootstrap(*notBootstrappedES()))
@@ -118,6 +123,48 @@ func TestReconcileClusterUUID(t *testing.T) {
 			observedState: observer.State{ClusterState: &client.ClusterState{ClusterUUID: "uuid"}},
 			wantCluster:   reBootstrappingES(),
 		},
+		{
+			name: "annotated, bootstrapped, rolling upgrade of multiple master nodes",
+			c: k8s.WrapClient(fake.NewFakeClient(
+				bootstrappedES(),
+				sset.TestPod{
+					Name:        "master-0",
+					ClusterName: "cluster",
+					Version:     "6.8.0",
+					Master:      true,
+				}.BuildPtr(),
+				sset.TestPod{
+					Name:        "master-1",
+					ClusterName: "cluster",
+					Version:     "6.8.0",
+					Master:      true,
+				}.BuildPtr(),
+			)),
+			cluster:       bootstrappedES(),
+			observedState: observer.State{ClusterState: &client.ClusterState{ClusterUUID: "uuid"}},
+			wantCluster:   bootstrappedES(),
+		},
+		{
+			name: "annotated, bootstrapped, but needs re-bootstrapping due to full cluster restart of multiple master nodes",
+			c: k8s.WrapClient(fake.NewFakeClient(
+				fullClusterRestartES(bootstrappedES()),
+				sset.TestPod{
+					Name:        "master-0",
+					ClusterName: "cluster",
+					Version:     "6.8.0",
+					Master:      true,
+				}.BuildPtr(),
+				sset.TestPod{
+					Name:        "master-1",
+					ClusterName: "cluster",
+					Version:     "6.8.0",
+					Master:      true,
+				}.BuildPtr(),
+			)),
+			cluster:       fullClusterRestartES(bootstrappedES()),
+			observedState: observer.State{ClusterState: &client.ClusterState{ClusterUUID: "uuid"}},
+			wantCluster:   fullClusterRestartES(reBootstrappingES()),
+		},
 		{
 			name: "not annotated, bootstrapped, but still on pre-upgrade version",
 			c: k8s.WrapClient(fake.NewFakeClient(
@@ -0,0 +1,243 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package driver
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	commonv1alpha1 "github.com/elastic/cloud-on-k8s/pkg/apis/common/v1alpha1"
+	"github.com/elastic/cloud-on-k8s/pkg/apis/elasticsearch/v1alpha1"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/events"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/reconciler"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/observer"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/sset"
+	"github.com/elastic/cloud-on-k8s/pkg/utils/k8s"
+	"github.com/elastic/cloud-on-k8s/pkg/utils/stringsutil"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+const (
+	// CanaryUpgradeAnnotationName stores the state of the canary upgrade in progress.
+	CanaryUpgradeAnnotationName = "elasticsearch.k8s.elastic.co/canary-upgrade"
+
+	// CanaryUpgradeReason is the reason of the UpgradeInProgress condition while the canary nodes are restarted
+	// and monitored.
+	CanaryUpgradeReason = "CanaryUpgrade"
+	// CanaryRegressionReason is the reason of the UpgradeInProgress condition when the upgrade is paused because
+	// the cluster regressed once the canary nodes were restarted.
+	CanaryRegressionReason = "CanaryRegression"
+)
+
+// canaryUpgradeState is the state of a canary upgrade, persisted in an annotation of the Elasticsearch resource.
+type canaryUpgradeState struct {
+	// UpdateRevisions are the revisions of the StatefulSets being upgraded, by StatefulSet name.
+	UpdateRevisions map[string]string `json:"updateRevisions"`
+	// Canaries are the names of the nodes restarted first.
+	Canaries []string `json:"canaries"`
+	// Health is the health of the cluster before the canaries were restarted.
+	Health v1alpha1.ElasticsearchHealth `json:"health"`
+	// SoakStartTime is the time at which all the canaries were restarted.
+	SoakStartTime *metav1.Time `json:"soakStartTime,omitempty"`
+	// Paused is true if the reconciliation was paused because of a regression.
+	Paused bool `json:"paused,omitempty"`
+	// Approved is true if the other nodes can be upgraded, either because no regression was observed during the
+	// soak period, or because the reconciliation was resumed after a regression.
+	Approved bool `json:"approved,omitempty"`
+}
+
+// sameUpgrade returns true if the StatefulSets to update still target the revisions of the canary upgrade.
+// StatefulSets fully upgraded are not part of the given revisions anymore.
+func (s canaryUpgradeState) sameUpgrade(updateRevisions map[string]string) bool {
+	for name, revision := range updateRevisions {
+		if s.UpdateRevisions[name] != revision {
+			return false
+		}
+	}
+	return true
+}
+
+// getCanaryUpgradeState returns the state of the canary upgrade in progress, or nil if there is none.
+func getCanaryUpgradeState(es v1alpha1.Elasticsearch) *canaryUpgradeState {
+	serialized, exists := es.Annotations[CanaryUpgradeAnnotationName]
+	if !exists {
+		return nil
+	}
+	var state canaryUpgradeState
+	if err := json.Unmarshal([]byte(serialized), &state); err != nil {
+		log.Error(err, "Cannot parse the canary upgrade annotation, ignoring it", "namespace", es.Namespace, "es_name", es.Name)
+		return nil
+	}
+	return &state
+}
+
+// setCanaryUpgradeState updates the annotation storing the state of the canary upgrade, or removes it if state is nil.
+func setCanaryUpgradeState(c k8s.Client, es *v1alpha1.Elasticsearch, state *canaryUpgradeState) error {
+	if state == nil {
+		if _, exists := es.Annotations[CanaryUpgradeAnnotationName]; !exists {
+			return nil
+		}
+		delete(es.Annotations, CanaryUpgradeAnnotationName)
+		return c.Update(es)
+	}
+	serialized, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	if es.Annotations == nil {
+		es.Annotations = make(map[string]string)
+	}
+	es.Annotations[CanaryUpgradeAnnotationName] = string(serialized)
+	return c.Update(es)
+}
+
+// reconcileCanaryUpgrade restricts a rolling upgrade to a few canary nodes, then waits for a soak period once they
+// are restarted. If the canaries did not join the cluster or the cluster health degraded at the end of the soak
+// period, the reconciliation is paused until the user resumes it, which approves the upgrade of the other nodes.
+// It returns the names of the nodes the rolling upgrade is restricted to, or nil if all the nodes can be upgraded.
+func (d *defaultDriver) reconcileCanaryUpgrade(
+	observedState observer.State,
+	statefulSets sset.StatefulSetList,
+	podsToUpgrade []corev1.Pod,
+	now time.Time,
+) ([]string, *reconciler.Results) {
+	results := &reconciler.Results{}
+	canary := d.ES.Spec.UpdateStrategy.Canary
+	if canary == nil || len(podsToUpgrade) == 0 {
+		// no canary upgrade, or the upgrade is over
+		return nil, results.WithError(setCanaryUpgradeState(d.Client, &d.ES, nil))
+	}
+
+	updateRevisions := make(map[string]string)
+	for _, statefulSet := range statefulSets.ToUpdate() {
+		updateRevisions[statefulSet.Name] = statefulSet.Status.UpdateRevision
+	}
+	state := getCanaryUpgradeState(d.ES)
+	if state == nil || !state.sameUpgrade(updateRevisions) {
+		state = &canaryUpgradeState{
+			UpdateRevisions: updateRevisions,
+			Canaries:        selectCanaries(podsToUpgrade, canary.CanaryNodes(len(podsToUpgrade))),
+			Health:          observedHealth(observedState),
+		}
+		log.Info("Starting canary upgrade", "namespace", d.ES.Namespace, "es_name", d.ES.Name, "canaries", state.Canaries)
+		if err := setCanaryUpgradeState(d.Client, &d.ES, state); err != nil {
+			return nil, results.WithError(err)
+		}
+	}
+
+	if state.Approved {
+		return nil, results
+	}
+	if state.Paused {
+		// the reconciliation was resumed after the upgrade was paused
+		log.Info("Canary upgrade approved", "namespace", d.ES.Namespace, "es_name", d.ES.Name)
+		state.Approved = true
+		return nil, results.WithError(setCanaryUpgradeState(d.Client, &d.ES, state))
+	}
+
+	// wait for the canaries to be restarted
+	var restartingCanaries []string
+	for _, pod := range podsToUpgrade {
+		if stringsutil.StringInSlice(pod.Name, state.Canaries) {
+			restartingCanaries = append(restartingCanaries, pod.Name)
+		}
+	}
+	if len(restartingCanaries) > 0 {
+		d.ReconcileState.ReportCondition(commonv1alpha1.NewCondition(
+			commonv1alpha1.UpgradeInProgressCondition, true, CanaryUpgradeReason,
+			fmt.Sprintf("Restarting canary nodes: %s", strings.Join(restartingCanaries, ", ")),
+		))
+		return state.Canaries, results
+	}
+
+	// wait for the soak period to be over
+	if state.SoakStartTime == nil {
+		state.SoakStartTime = &metav1.Time{Time: now}
+		if err := setCanaryUpgradeState(d.Client, &d.ES, state); err != nil {
+			return state.Canaries, results.WithError(err)
+		}
+	}
+	soakEnd := state.SoakStartTime.Add(canary.ResolveSoakPeriod())
+	if now.Before(soakEnd) {
+		d.ReconcileState.ReportCondition(commonv1alpha1.NewCondition(
+			commonv1alpha1.UpgradeInProgressCondition, true, CanaryUpgradeReason,
+			fmt.Sprintf("Canary nodes restarted, soak period until %s", soakEnd.UTC().Format(time.RFC3339)),
+		))
+		return state.Canaries, results.WithResult(reconcile.Result{RequeueAfter: soakEnd.Sub(now)})
+	}
+
+	regression := canaryRegression(*state, observedState)
+	if regression == "" {
+		log.Info("Canary upgrade successful", "namespace", d.ES.Namespace, "es_name", d.ES.Name)
+		state.Approved = true
+		return nil, results.WithError(setCanaryUpgradeState(d.Client, &d.ES, state))
+	}
+
+	d.ReconcileState.ReportCondition(commonv1alpha1.NewCondition(
+		commonv1alpha1.UpgradeInProgressCondition, true, CanaryRegressionReason, regression,
+	))
+	if existing := d.ES.Status.Conditions.Get(commonv1alpha1.UpgradeInProgressCondition); existing == nil ||
+		existing.Reason != CanaryRegressionReason {
+		// make sure the regression is visible in the status before pausing the reconciliation
+		return state.Canaries, results.WithResult(defaultRequeue)
+	}
+
+	log.Info("Pausing canary upgrade", "namespace", d.ES.Namespace, "es_name", d.ES.Name, "regression", regression)
+	d.ReconcileState.AddEvent(corev1.EventTypeWarning, events.EventReasonUnhealthy,
+		fmt.Sprintf("Canary upgrade paused: %s", regression),
+	)
+	state.Paused = true
+	if d.ES.Annotations == nil {
+		d.ES.Annotations = make(map[string]string)
+	}
+	d.ES.Annotations[common.PauseAnnotationName] = "true"
+	return state.Canaries, results.WithError(setCanaryUpgradeState(d.Client, &d.ES, state))
+}
+
+// selectCanaries returns the names of the first Pods to upgrade, in the order of a rolling upgrade.
+func selectCanaries(podsToUpgrade []corev1.Pod, count int) []string {
+	candidates := make([]corev1.Pod, len(podsToUpgrade))
+	copy(candidates, podsToUpgrade)
+	sortCandidates(candidates)
+	canaries := k8s.PodNames(candidates[:count])
+	sort.Strings(canaries)
+	return canaries
+}
+
+// observedHealth returns the observed health of the cluster, red if unknown.
+func observedHealth(observedState observer.State) v1alpha1.ElasticsearchHealth {
+	if observedState.ClusterHealth == nil || observedState.ClusterHealth.Status == "" {
+		return v1alpha1.ElasticsearchRedHealth
+	}
+	return v1alpha1.ElasticsearchHealth(observedState.ClusterHealth.Status)
+}
+
+// canaryRegression returns a description of the regression observed once the canaries are restarted, if any.
+func canaryRegression(state canaryUpgradeState, observedState observer.State) string {
+	var missing []string
+	for _, canary := range state.Canaries {
+		if observedState.ClusterState == nil {
+			missing = append(missing, canary)
+			continue
+		}
+		if _, exists := observedState.ClusterState.NodesByNodeName()[canary]; !exists {
+			missing = append(missing, canary)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Sprintf("canary nodes not in the cluster: %s", strings.Join(missing, ", "))
+	}
+	current := v1alpha1.ElasticsearchStatus{Health: observedHealth(observedState)}
+	if current.IsDegraded(v1alpha1.ElasticsearchStatus{Health: state.Health}) {
+		return fmt.Sprintf("cluster health degraded from %s to %s", state.Health, current.Health)
+	}
+	return ""
+}
@@ -0,0 +1,303 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package driver
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	commonv1alpha1 "github.com/elastic/cloud-on-k8s/pkg/apis/common/v1alpha1"
+	"github.com/elastic/cloud-on-k8s/pkg/apis/elasticsearch/v1alpha1"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/expectations"
+	esclient "github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/client"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/observer"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/reconcile"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/sset"
+	"github.com/elastic/cloud-on-k8s/pkg/utils/k8s"
+	"github.com/stretchr/testify/require"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+var canaryNow = time.Date(2019, 10, 1, 12, 0, 0, 0, time.UTC)
+
+func canaryES(state *canaryUpgradeState, upgradeCondition string) v1alpha1.Elasticsearch {
+	es := v1alpha1.Elasticsearch{
+		ObjectMeta: metav1.ObjectMeta{Name: "es", Namespace: testNamespace},
+		Spec: v1alpha1.ElasticsearchSpec{
+			Version: "7.3.0",
+			UpdateStrategy: v1alpha1.UpdateStrategy{
+				Canary: &v1alpha1.CanaryStrategy{Nodes: 1, SoakPeriod: &metav1.Duration{Duration: 10 * time.Minute}},
+			},
+		},
+	}
+	if state != nil {
+		serialized, err := json.Marshal(state)
+		if err != nil {
+			panic(err)
+		}
+		es.Annotations = map[string]string{CanaryUpgradeAnnotationName: string(serialized)}
+	}
+	if upgradeCondition != "" {
+		es.Status.Conditions = es.Status.Conditions.Set(
+			commonv1alpha1.NewCondition(commonv1alpha1.UpgradeInProgressCondition, true, upgradeCondition, ""),
+		)
+	}
+	return es
+}
+
+func canaryPods(names ...string) []corev1.Pod {
+	pods := make([]corev1.Pod, 0, len(names))
+	for _, name := range names {
+		pods = append(pods, sset.TestPod{Namespace: testNamespace, Name: name, StatefulSetName: "nodes", Data: true}.Build())
+	}
+	return pods
+}
+
+func canaryObservedState(health v1alpha1.ElasticsearchHealth, nodes ...string) observer.State {
+	clusterState := esclient.ClusterState{Nodes: map[string]esclient.ClusterStateNode{}}
+	for _, node := range nodes {
+		clusterState.Nodes[node+"-id"] = esclient.ClusterStateNode{Name: node}
+	}
+	return observer.State{
+		ClusterState:  &clusterState,
+		ClusterHealth: &esclient.Health{Status: string(health)},
+	}
+}
+
+func Test_defaultDriver_reconcileCanaryUpgrade(t *testing.T) {
+	require.NoError(t, v1alpha1.AddToScheme(scheme.Scheme))
+	statefulSets := sset.StatefulSetList{
+		sset.TestSset{
+			Namespace: testNamespace, Name: "nodes", Replicas: 3, Data: true,
+			Status: appsv1.StatefulSetStatus{CurrentRevision: "rev-a", UpdateRevision: "rev-b", UpdatedReplicas: 1, Replicas: 3},
+		}.Build(),
+	}
+	revisions := map[string]string{"nodes": "rev-b"}
+	soakStarted := func(ago time.Duration) *metav1.Time {
+		return &metav1.Time{Time: canaryNow.Add(-ago)}
+	}
+
+	tests := []struct {
+		name          string
+		es            v1alpha1.Elasticsearch
+		podsToUpgrade []corev1.Pod
+		observedState observer.State
+		wantCanaries  []string
+		wantState     *canaryUpgradeState
+		wantPaused    bool
+		wantCondition string
+		wantRequeue   bool
+	}{
+		{
+			name:          "no canary strategy",
+			es:            v1alpha1.Elasticsearch{ObjectMeta: metav1.ObjectMeta{Name: "es", Namespace: testNamespace}},
+			podsToUpgrade: canaryPods("nodes-0", "nodes-1", "nodes-2"),
+			observedState: canaryObservedState(v1alpha1.ElasticsearchGreenHealth, "nodes-0", "nodes-1", "nodes-2"),
+			wantCanaries:  nil,
+		},
+		{
+			name:          "start a canary upgrade",
+			es:            canaryES(nil, ""),
+			podsToUpgrade: canaryPods("nodes-0", "nodes-1", "nodes-2"),
+			observedState: canaryObservedState(v1alpha1.ElasticsearchGreenHealth, "nodes-0", "nodes-1", "nodes-2"),
+			wantCanaries:  []string{"nodes-2"},
+			wantState: &canaryUpgradeState{
+				UpdateRevisions: revisions, Canaries: []string{"nodes-2"}, Health: v1alpha1.ElasticsearchGreenHealth,
+			},
+			wantCondition: CanaryUpgradeReason,
+		},
+		{
+			name: "start a new canary upgrade if the revisions changed",
+			es: canaryES(&canaryUpgradeState{
+				UpdateRevisions: map[string]string{"nodes": "rev-0"}, Canaries: []string{"nodes-1"},
+				Health: v1alpha1.ElasticsearchGreenHealth, Approved: true,
+			}, ""),
+			podsToUpgrade: canaryPods("nodes-0", "nodes-1", "nodes-2"),
+			observedState: canaryObservedState(v1alpha1.ElasticsearchGreenHealth, "nodes-0", "nodes-1", "nodes-2"),
+			wantCanaries:  []string{"nodes-2"},
+			wantState: &canaryUpgradeState{
+				UpdateRevisions: revisions, Canaries: []string{"nodes-2"}, Health: v1alpha1.ElasticsearchGreenHealth,
+			},
+			wantCondition: CanaryUpgradeReason,
+		},
+		{
+			name: "canaries restarted: start the soak period",
+			es: canaryES(&canaryUpgradeState{
+				UpdateRevisions: revisions, Canaries: []string{"nodes-2"}, Health: v1alpha1.ElasticsearchGreenHealth,
+			}, ""),
+			podsToUpgrade: canaryPods("nodes-0", "nodes-1"),
+			observedState: canaryObservedState(v1alpha1.ElasticsearchYellowHealth, "nodes-0", "nodes-1"),
+			wantCanaries:  []string{"nodes-2"},
+			wantState: &canaryUpgradeState{
+				UpdateRevisions: revisions, Canaries: []string{"nodes-2"}, Health: v1alpha1.ElasticsearchGreenHealth,
+				SoakStartTime: soakStarted(0),
+			},
+			wantCondition: CanaryUpgradeReason,
+			wantRequeue:   true,
+		},
+		{
+			name: "soak period over without regression: upgrade the other nodes",
+			es: canaryES(&canaryUpgradeState{
+				UpdateRevisions: revisions, Canaries: []string{"nodes-2"}, Health: v1alpha1.ElasticsearchGreenHealth,
+				SoakStartTime: soakStarted(11 * time.Minute),
+			}, CanaryUpgradeReason),
+			podsToUpgrade: canaryPods("nodes-0", "nodes-1"),
+			observedState: canaryObservedState(v1alpha1.ElasticsearchGreenHealth, "nodes-0", "nodes-1", "nodes-2"),
+			wantCanaries:  nil,
+			wantState: &canaryUpgradeState{
+				UpdateRevisions: revisions, Canaries: []string{"nodes-2"}, Health: v1alpha1.ElasticsearchGreenHealth,
+				SoakStartTime: soakStarted(11 * time.Minute), Approved: true,
+			},
+		},
+		{
+			name: "soak period over with a canary not in the cluster: report the regression",
+			es: canaryES(&canaryUpgradeState{
+				UpdateRevisions: revisions, Canaries: []string{"nodes-2"}, Health: v1alpha1.ElasticsearchGreenHealth,
+				SoakStartTime: soakStarted(11 * time.Minute),
+			}, CanaryUpgradeReason),
+			podsToUpgrade: canaryPods("nodes-0", "nodes-1"),
+			observedState: canaryObservedState(v1alpha1.ElasticsearchGreenHealth, "nodes-0", "nodes-1"),
+			wantCanaries:  []string{"nodes-2"},
+			wantState: &canaryUpgradeState{
+				UpdateRevisions: revisions, Canaries: []string{"nodes-2"}, Health: v1alpha1.ElasticsearchGreenHealth,
+				SoakStartTime: soakStarted(11 * time.Minute),
+			},
+			wantCondition: CanaryRegressionReason,
+			wantRequeue:   true,
+		},
+		{
+			name: "soak period over with a degraded health reported in the status: pause the upgrade",
+			es: canaryES(&canaryUpgradeState{
+				UpdateRevisions: revisions, Canaries: []string{"nodes-2"}, Health: v1alpha1.ElasticsearchGreenHealth,
+				SoakStartTime: soakStarted(11 * time.Minute),
+			}, CanaryRegressionReason),
+			podsToUpgrade: canaryPods("nodes-0", "nodes-1"),
+			observedState: canaryObservedState(v1alpha1.ElasticsearchYellowHealth, "nodes-0", "nodes-1", "nodes-2"),
+			wantCanaries:  []string{"nodes-2"},
+			wantState: &canaryUpgradeState{
+				UpdateRevisions: revisions, Canaries: []string{"nodes-2"}, Health: v1alpha1.ElasticsearchGreenHealth,
+				SoakStartTime: soakStarted(11 * time.Minute), Paused: true,
+			},
+			wantPaused:    true,
+			wantCondition: CanaryRegressionReason,
+		},
+		{
+			name: "reconciliation resumed after a pause: approve the upgrade",
+			es: canaryES(&canaryUpgradeState{
+				UpdateRevisions: revisions, Canaries: []string{"nodes-2"}, Health: v1alpha1.ElasticsearchGreenHealth,
+				SoakStartTime: soakStarted(20 * time.Minute), Paused: true,
+			}, CanaryRegressionReason),
+			podsToUpgrade: canaryPods("nodes-0", "nodes-1"),
+			observedState: canaryObservedState(v1alpha1.ElasticsearchYellowHealth, "nodes-0", "nodes-1", "nodes-2"),
+			wantCanaries:  nil,
+			wantState: &canaryUpgradeState{
+				UpdateRevisions: revisions, Canaries: []string{"nodes-2"}, Health: v1alpha1.ElasticsearchGreenHealth,
+				SoakStartTime: soakStarted(20 * time.Minute), Paused: true, Approved: true,
+			},
+		},
+		{
+			name: "upgrade over: remove the canary upgrade state",
+			es: canaryES(&canaryUpgradeState{
+				UpdateRevisions: revisions, Canaries: []string{"nodes-2"}, Health: v1alpha1.ElasticsearchGreenHealth,
+				SoakStartTime: soakStarted(20 * time.Minute), Approved: true,
+			}, ""),
+			podsToUpgrade: nil,
+			observedState: canaryObservedState(v1alpha1.ElasticsearchGreenHealth, "nodes-0", "nodes-1", "nodes-2"),
+			wantCanaries:  nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			es := tt.es
+			k8sClient := k8s.WrapClient(fake.NewFakeClient(&es))
+			reconcileState := reconcile.NewState(es)
+			d := &defaultDriver{DefaultDriverParameters{
+				ES:             es,
+				Client:         k8sClient,
+				ReconcileState: reconcileState,
+				Expectations:   expectations.NewExpectations(),
+			}}
+
+			canaries, results := d.reconcileCanaryUpgrade(tt.observedState, statefulSets, tt.podsToUpgrade, canaryNow)
+			require.False(t, results.HasError())
+			require.Equal(t, tt.wantCanaries, canaries)
+			res, _ := results.Aggregate()
+			require.Equal(t, tt.wantRequeue, res.Requeue || res.RequeueAfter > 0)
+
+			var updatedES v1alpha1.Elasticsearch
+			require.NoError(t, k8sClient.Get(k8s.ExtractNamespacedName(&es), &updatedES))
+			require.Equal(t, tt.wantPaused, common.IsPaused(updatedES.ObjectMeta))
+			state := getCanaryUpgradeState(updatedES)
+			if tt.wantState == nil {
+				require.Nil(t, state)
+			} else {
+				require.NotNil(t, state)
+				require.Equal(t, tt.wantState.UpdateRevisions, state.UpdateRevisions)
+				require.Equal(t, tt.wantState.Canaries, state.Canaries)
+				require.Equal(t, tt.wantState.Health, state.Health)
+				require.Equal(t, tt.wantState.Paused, state.Paused)
+				require.Equal(t, tt.wantState.Approved, state.Approved)
+				if tt.wantState.SoakStartTime == nil {
+					require.Nil(t, state.SoakStartTime)
+				} else {
+					require.NotNil(t, state.SoakStartTime)
+					require.True(t, tt.wantState.SoakStartTime.Equal(state.SoakStartTime))
+				}
+			}
+
+			_, withStatus := reconcileState.Apply()
+			if tt.wantCondition == "" {
+				// no condition reported by the canary upgrade
+				require.Nil(t, withStatus)
+				return
+			}
+			require.NotNil(t, withStatus)
+			condition := withStatus.Status.Conditions.Get(commonv1alpha1.UpgradeInProgressCondition)
+			require.NotNil(t, condition)
+			require.Equal(t, tt.wantCondition, condition.Reason)
+		})
+	}
+}
+
+func Test_canaryRegression(t *testing.T) {
+	state := canaryUpgradeState{Canaries: []string{"nodes-2"}, Health: v1alpha1.ElasticsearchGreenHealth}
+	tests := []struct {
+		name          string
+		observedState observer.State
+		want          string
+	}{
+		{
+			name:          "no regression",
+			observedState: canaryObservedState(v1alpha1.ElasticsearchGreenHealth, "nodes-0", "nodes-1", "nodes-2"),
+			want:          "",
+		},
+		{
+			name:          "canary not in the cluster",
+			observedState: canaryObservedState(v1alpha1.ElasticsearchGreenHealth, "nodes-0", "nodes-1"),
+			want:          "canary nodes not in the cluster: nodes-2",
+		},
+		{
+			name:          "cluster state unknown",
+			observedState: observer.State{},
+			want:          "canary nodes not in the cluster: nodes-2",
+		},
+		{
+			name:          "health degraded",
+			observedState: canaryObservedState(v1alpha1.ElasticsearchYellowHealth, "nodes-0", "nodes-1", "nodes-2"),
+			want:          "cluster health degraded from green to yellow",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, canaryRegression(state, tt.observedState))
+		})
+	}
+}
@@ -5,10 +5,13 @@
 package driver
 
 import (
+	"fmt"
+
 	appsv1 "k8s.io/api/apps/v1"
 	v1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 
+	commonv1alpha1 "github.com/elastic/cloud-on-k8s/pkg/apis/common/v1alpha1"
 	"github.com/elastic/cloud-on-k8s/pkg/apis/elasticsearch/v1alpha1"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/common/events"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/common/reconciler"
@@ -21,6 +24,7 @@ import (
 	"github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/version/zen1"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/version/zen2"
 	"github.com/elastic/cloud-on-k8s/pkg/utils/k8s"
+	"github.com/elastic/cloud-on-k8s/pkg/utils/set"
 )
 
 // HandleDownscale attempts to downscale actual StatefulSets towards expected ones.
@@ -46,9 +50,23 @@ func HandleDownscale(
 		return results.WithError(err)
 	}
 
+	// delete the volume claims of the nodes already removed, before their StatefulSet may be deleted
+	pendingClaims, err := deleteScaledDownVolumeClaims(downscaleCtx, actualStatefulSets)
+	if err != nil {
+		return results.WithError(err)
+	}
+	if pendingClaims.Count() > 0 {
+		results.WithResult(defaultRequeue)
+	}
+
+	// no data migration in progress unless reported otherwise while attempting the downscales
+	downscaleCtx.reconcileState.ReportCondition(commonv1alpha1.NewCondition(
+		commonv1alpha1.DataMigrationCondition, false, NoDataMigrationReason, "",
+	))
+
 	for _, downscale := range downscales {
 		// attempt the StatefulSet downscale (may or may not remove nodes)
-		requeue, err := attemptDownscale(downscaleCtx, downscale, downscaleState, leavingNodes, actualStatefulSets)
+		requeue, err := attemptDownscale(downscaleCtx, downscale, downscaleState, leavingNodes, actualStatefulSets, pendingClaims)
 		if err != nil {
 			return results.WithError(err)
 		}
@@ -95,7 +113,8 @@ func scheduleDataMigrations(esClient esclient.Client, leavingNodes []string) err
 
 // attemptDownscale attempts to decrement the number of replicas of the given StatefulSet,
 // or deletes the StatefulSet entirely if it should not contain any replica.
-// Nodes whose data migration is not over will not be removed.
+// Nodes whose data migration is not over will not be removed, and StatefulSets whose volume claims are pending
+// deletion will not be deleted.
 // A boolean is returned to indicate if a requeue should be scheduled if the entire downscale could not be performed.
 func attemptDownscale(
 	ctx downscaleContext,
@@ -103,9 +122,15 @@ func attemptDownscale(
 	state *downscaleState,
 	allLeavingNodes []string,
 	statefulSets sset.StatefulSetList,
+	pendingClaims set.StringSet,
 ) (bool, error) {
 	switch {
 	case downscale.isRemoval():
+		if pendingClaims.Has(downscale.statefulSet.Name) {
+			// the claims are retrieved through the StatefulSet, keep it until they are deleted
+			ssetLogger(downscale.statefulSet).V(1).Info("Volume claims pending deletion, skipping StatefulSet deletion")
+			return true, nil
+		}
 		return false, removeStatefulSetResources(ctx.k8sClient, ctx.es, downscale.statefulSet)
 
 	case downscale.isReplicaDecrease():
@@ -162,6 +187,10 @@ func calculatePerformableDownscale(
 	for _, node := range downscale.leavingNodeNames() {
 		if canDownscale, reason := checkDownscaleInvariants(*state, downscale.statefulSet); !canDownscale {
 			ssetLogger(downscale.statefulSet).V(1).Info("Cannot downscale StatefulSet", "node", node, "reason", reason)
+			ctx.reconcileState.ReportCondition(commonv1alpha1.NewCondition(
+				commonv1alpha1.ReconcilingCondition, true, DownscaleDelayedReason,
+				fmt.Sprintf("Cannot remove node %s: %s", node, reason),
+			))
 			return performableDownscale
 		}
 		if migration.IsMigratingData(ctx.observedState, node, allLeavingNodes) {
@@ -16,6 +16,11 @@ import (
 const (
 	OneMasterAtATimeInvariant        = "A master node is already in the process of being removed"
 	AtLeastOneRunningMasterInvariant = "Cannot remove the last running master node"
+
+	// DownscaleDelayedReason is the reason of the Reconciling condition when a downscale is delayed by an invariant.
+	DownscaleDelayedReason = "DownscaleDelayed"
+	// NoDataMigrationReason is the reason of the DataMigration condition when no data is being migrated.
+	NoDataMigrationReason = "NoDataMigration"
 )
 
 // checkDownscaleInvariants returns true if the given state state allows downscaling the given StatefulSet.
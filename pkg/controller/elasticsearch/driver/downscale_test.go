@@ -30,6 +30,7 @@ import (
 	"github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/settings"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/sset"
 	"github.com/elastic/cloud-on-k8s/pkg/utils/k8s"
+	"github.com/elastic/cloud-on-k8s/pkg/utils/set"
 )
 
 // Sample StatefulSets to use in tests
@@ -557,6 +558,8 @@ func Test_attemptDownscale(t *testing.T) {
 		downscale            ssetDownscale
 		state                *downscaleState
 		statefulSets         sset.StatefulSetList
+		pendingClaims        set.StringSet
+		expectedRequeue      bool
 		expectedStatefulSets []appsv1.StatefulSet
 	}{
 		{
@@ -575,6 +578,25 @@ func Test_attemptDownscale(t *testing.T) {
 				sset.TestSset{Name: "should-stay", Version: "7.1.0", Replicas: 2, Master: true, Data: true}.Build(),
 			},
 		},
+		{
+			name: "statefulset removal delayed while its volume claims are pending deletion",
+			downscale: ssetDownscale{
+				statefulSet:     sset.TestSset{Name: "should-be-removed", Version: "7.1.0", Replicas: 0, Master: true, Data: true}.Build(),
+				initialReplicas: 0,
+				targetReplicas:  0,
+			},
+			state: &downscaleState{runningMasters: 2, masterRemovalInProgress: false},
+			statefulSets: sset.StatefulSetList{
+				sset.TestSset{Name: "should-be-removed", Version: "7.1.0", Replicas: 0, Master: true, Data: true}.Build(),
+				sset.TestSset{Name: "should-stay", Version: "7.1.0", Replicas: 2, Master: true, Data: true}.Build(),
+			},
+			pendingClaims:   set.Make("should-be-removed"),
+			expectedRequeue: true,
+			expectedStatefulSets: []appsv1.StatefulSet{
+				sset.TestSset{Name: "should-be-removed", Version: "7.1.0", Replicas: 0, Master: true, Data: true}.Build(),
+				sset.TestSset{Name: "should-stay", Version: "7.1.0", Replicas: 2, Master: true, Data: true}.Build(),
+			},
+		},
 		{
 			name: "target replicas == initial replicas",
 			downscale: ssetDownscale{
@@ -641,8 +663,9 @@ func Test_attemptDownscale(t *testing.T) {
 				esClient: &fakeESClient{},
 			}
 			// do the downscale
-			_, err := attemptDownscale(downscaleCtx, tt.downscale, tt.state, nil, tt.statefulSets)
+			requeue, err := attemptDownscale(downscaleCtx, tt.downscale, tt.state, nil, tt.statefulSets, tt.pendingClaims)
 			require.NoError(t, err)
+			require.Equal(t, tt.expectedRequeue, requeue)
 			// retrieve statefulsets
 			var ssets appsv1.StatefulSetList
 			err = k8sClient.List(&client.ListOptions{}, &ssets)
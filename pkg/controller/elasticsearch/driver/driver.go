@@ -9,11 +9,14 @@ import (
 	"fmt"
 	"time"
 
+	commonv1alpha1 "github.com/elastic/cloud-on-k8s/pkg/apis/common/v1alpha1"
 	"github.com/elastic/cloud-on-k8s/pkg/apis/elasticsearch/v1alpha1"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/common"
 	commondriver "github.com/elastic/cloud-on-k8s/pkg/controller/common/driver"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/common/events"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/common/expectations"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/heap"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/ingress"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/common/keystore"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/common/operator"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/common/reconciler"
@@ -27,6 +30,8 @@ import (
 	"github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/label"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/license"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/name"
+	esnetworkpolicy "github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/networkpolicy"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/nodespec"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/observer"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/pdb"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/reconcile"
@@ -45,6 +50,13 @@ var (
 	defaultRequeue = controller.Result{Requeue: true, RequeueAfter: 10 * time.Second}
 )
 
+const (
+	// LicenseAppliedReason is the reason of the LicenseApplied condition when the expected license is applied.
+	LicenseAppliedReason = "LicenseApplied"
+	// LicenseUpdateFailedReason is the reason of the LicenseApplied condition when the license cannot be updated.
+	LicenseUpdateFailedReason = "LicenseUpdateFailed"
+)
+
 // Driver orchestrates the reconciliation of an Elasticsearch resource.
 // Its lifecycle is bound to a single reconciliation attempt.
 type Driver interface {
@@ -121,23 +133,44 @@ func (d *defaultDriver) Reconcile() *reconciler.Results {
 		return results.WithError(err)
 	}
 
+	// label the pods backing the external service before it may select them
+	if err := services.ReconcileExternalServiceLabels(d.Client, d.ES); err != nil {
+		return results.WithError(err)
+	}
+
 	externalService, err := common.ReconcileService(d.Client, d.Scheme(), services.NewExternalService(d.ES), &d.ES)
 	if err != nil {
 		return results.WithError(err)
 	}
 
+	nodeSpecServices, err := services.ReconcileNodeSpecServices(d.Client, d.Scheme(), d.ES)
+	if err != nil {
+		return results.WithError(err)
+	}
+
 	certificateResources, res := certificates.Reconcile(
 		d,
 		d.ES,
-		[]corev1.Service{*externalService},
+		append([]corev1.Service{*externalService}, nodeSpecServices...),
 		d.OperatorParameters.CACertRotation,
 		d.OperatorParameters.CertRotation,
+		d.OperatorParameters.CertKeyParams,
 	)
 	if results.WithResults(res).HasError() {
 		return results
 	}
 
-	internalUsers, err := user.ReconcileUsers(d.Client, d.Scheme(), d.ES)
+	if err := ingress.Reconcile(
+		d.Client, d.Scheme(), &d.ES, name.ESNamer, d.ES.Spec.HTTP, *externalService, label.NewLabels(k8s.ExtractNamespacedName(&d.ES)),
+	); err != nil {
+		return results.WithError(err)
+	}
+
+	if err := esnetworkpolicy.Reconcile(d.Client, d.Scheme(), d.ES, d.OperatorParameters.NetworkPolicyOperatorPeer); err != nil {
+		return results.WithError(err)
+	}
+
+	internalUsers, err := user.ReconcileUsers(d.Client, d.Scheme(), d.ES, d.DynamicWatches())
 	if err != nil {
 		return results.WithError(err)
 	}
@@ -155,12 +188,28 @@ func (d *defaultDriver) Reconcile() *reconciler.Results {
 	}
 
 	warnUnsupportedDistro(resourcesState.AllPods, d.ReconcileState.Recorder)
+	warnHeapSizeExceedsMemoryLimit(d.ES, d.ReconcileState.Recorder)
+
+	// keep using the current password of the controller user until a password rotation is propagated to all nodes
+	controllerUser, res := d.reconcilePasswordRotation(
+		*internalUsers,
+		resourcesState.CurrentPodsByPhase[corev1.PodRunning],
+		func(url string, u user.User) esclient.Client {
+			return esclient.NewElasticsearchClient(
+				d.OperatorParameters.Dialer, url, u.Auth(), *min,
+				certificateResources.TrustedHTTPCertificates,
+			)
+		},
+	)
+	if results.WithResults(res).HasError() {
+		return results
+	}
 
 	observedState := d.Observers.ObservedStateResolver(
 		k8s.ExtractNamespacedName(&d.ES),
 		d.newElasticsearchClient(
 			resourcesState,
-			internalUsers.ControllerUser,
+			controllerUser,
 			*min,
 			certificateResources.TrustedHTTPCertificates,
 		))
@@ -170,7 +219,7 @@ func (d *defaultDriver) Reconcile() *reconciler.Results {
 		d.ReconcileState.UpdateElasticsearchState(*resourcesState, observedState)
 	}
 
-	if err := pdb.Reconcile(d.Client, d.Scheme(), d.ES); err != nil {
+	if err := pdb.Reconcile(d.Client, d.Scheme(), d.ES, observedState.ClusterHealth); err != nil {
 		return results.WithError(err)
 	}
 
@@ -181,7 +230,7 @@ func (d *defaultDriver) Reconcile() *reconciler.Results {
 	// TODO: support user-supplied certificate (non-ca)
 	esClient := d.newElasticsearchClient(
 		resourcesState,
-		internalUsers.ControllerUser,
+		controllerUser,
 		*min,
 		certificateResources.TrustedHTTPCertificates,
 	)
@@ -207,8 +256,17 @@ func (d *defaultDriver) Reconcile() *reconciler.Results {
 					events.EventReasonUnexpected,
 					fmt.Sprintf("Could not update cluster license: %s", err.Error()),
 				)
+				d.ReconcileState.ReportCondition(commonv1alpha1.NewCondition(
+					commonv1alpha1.LicenseAppliedCondition, false, LicenseUpdateFailedReason, err.Error(),
+				))
 				return defaultRequeue, err
 			}
+			if err == nil && observedState.ClusterLicense != nil {
+				d.ReconcileState.ReportCondition(commonv1alpha1.NewCondition(
+					commonv1alpha1.LicenseAppliedCondition, true, LicenseAppliedReason,
+					fmt.Sprintf("Elasticsearch runs a %s license", observedState.ClusterLicense.Type),
+				))
+			}
 			return controller.Result{}, err
 		},
 	)
@@ -219,9 +277,10 @@ func (d *defaultDriver) Reconcile() *reconciler.Results {
 	}
 
 	// setup a keystore with secure settings in an init container, if specified by the user
+	// or required by the authentication realms
 	keystoreResources, err := keystore.NewResources(
 		d,
-		&d.ES,
+		keystoreElasticsearch(d.ES, d.Version),
 		name.ESNamer,
 		label.NewLabels(k8s.ExtractNamespacedName(&d.ES)),
 		initcontainer.KeystoreParams,
@@ -246,6 +305,17 @@ func (d *defaultDriver) Reconcile() *reconciler.Results {
 	return results
 }
 
+// keystoreElasticsearch returns a copy of the given Elasticsearch resource with the secure settings required by the
+// authentication realms added to the ones specified by the user.
+func keystoreElasticsearch(es v1alpha1.Elasticsearch, v version.Version) *v1alpha1.Elasticsearch {
+	keystoreES := es.DeepCopy()
+	keystoreES.Spec.SecureSettings = append(
+		keystoreES.Spec.SecureSettings,
+		settings.RealmsSecureSettings(v, es.Spec.Auth)...,
+	)
+	return keystoreES
+}
+
 // newElasticsearchClient creates a new Elasticsearch HTTP client for this cluster using the provided user
 func (d *defaultDriver) newElasticsearchClient(
 	state *reconcile.ResourcesState,
@@ -271,3 +341,22 @@ func warnUnsupportedDistro(pods []corev1.Pod, recorder *events.Recorder) {
 		}
 	}
 }
+
+// warnHeapSizeExceedsMemoryLimit emits a warning event for each node spec specifying a heap size that does not fit in
+// the memory limit of the Elasticsearch container. Events are only emitted when the specification changed since the
+// last reconciliation, not on every reconciliation.
+func warnHeapSizeExceedsMemoryLimit(es v1alpha1.Elasticsearch, recorder *events.Recorder) {
+	if es.Generation == es.Status.ObservedGeneration {
+		return
+	}
+	for _, nodeSpec := range es.Spec.Nodes {
+		container := nodeSpec.GetESContainerTemplate()
+		if container == nil {
+			continue
+		}
+		if msg := heap.ExceedsMemoryLimit(*container, nodespec.HeapOptions); msg != "" {
+			recorder.AddEvent(corev1.EventTypeWarning, events.EventReasonValidation,
+				fmt.Sprintf("Node spec %s: %s", nodeSpec.Name, msg))
+		}
+	}
+}
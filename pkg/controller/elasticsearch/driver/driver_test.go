@@ -0,0 +1,66 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package driver
+
+import (
+	"testing"
+
+	commonv1alpha1 "github.com/elastic/cloud-on-k8s/pkg/apis/common/v1alpha1"
+	"github.com/elastic/cloud-on-k8s/pkg/apis/elasticsearch/v1alpha1"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/events"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func Test_warnHeapSizeExceedsMemoryLimit(t *testing.T) {
+	es := func(generation, observedGeneration int64, heapOpts string) v1alpha1.Elasticsearch {
+		return v1alpha1.Elasticsearch{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "es", Generation: generation},
+			Spec: v1alpha1.ElasticsearchSpec{Nodes: []v1alpha1.NodeSpec{{
+				Name: "default",
+				PodTemplate: corev1.PodTemplateSpec{Spec: corev1.PodSpec{Containers: []corev1.Container{{
+					Name: v1alpha1.ElasticsearchContainerName,
+					Env:  []corev1.EnvVar{{Name: "ES_JAVA_OPTS", Value: heapOpts}},
+					Resources: corev1.ResourceRequirements{
+						Limits: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("2Gi")},
+					},
+				}}}},
+			}}},
+			Status: v1alpha1.ElasticsearchStatus{
+				ReconcilerStatus: commonv1alpha1.ReconcilerStatus{ObservedGeneration: observedGeneration},
+			},
+		}
+	}
+	tests := []struct {
+		name       string
+		es         v1alpha1.Elasticsearch
+		wantEvents int
+	}{
+		{
+			name:       "heap size fits in the memory limit",
+			es:         es(2, 1, "-Xms1g -Xmx1g"),
+			wantEvents: 0,
+		},
+		{
+			name:       "heap size exceeds the memory limit of a new specification",
+			es:         es(2, 1, "-Xms4g -Xmx4g"),
+			wantEvents: 1,
+		},
+		{
+			name:       "heap size exceeds the memory limit of an already observed specification",
+			es:         es(2, 2, "-Xms4g -Xmx4g"),
+			wantEvents: 0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := events.NewRecorder()
+			warnHeapSizeExceedsMemoryLimit(tt.es, recorder)
+			assert.Len(t, recorder.Events(), tt.wantEvents)
+		})
+	}
+}
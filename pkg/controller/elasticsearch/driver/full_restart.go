@@ -0,0 +1,153 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package driver
+
+import (
+	"fmt"
+
+	commonv1alpha1 "github.com/elastic/cloud-on-k8s/pkg/apis/common/v1alpha1"
+	"github.com/elastic/cloud-on-k8s/pkg/apis/elasticsearch/v1alpha1"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/reconciler"
+	esclient "github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/client"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/sset"
+	"github.com/elastic/cloud-on-k8s/pkg/utils/k8s"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+)
+
+const (
+	// FullClusterRestartAnnotationName marks a cluster whose nodes are being restarted all at once.
+	// It is set once the cluster is prepared for the restart, and removed once all the nodes are restarted.
+	FullClusterRestartAnnotationName = "elasticsearch.k8s.elastic.co/full-cluster-restart"
+
+	// FullClusterRestartReason is the reason of the UpgradeInProgress condition when all the nodes to update
+	// are restarted at once.
+	FullClusterRestartReason = "FullClusterRestart"
+)
+
+// fullClusterRestartInProgress returns true if the nodes of the cluster are being restarted all at once.
+func fullClusterRestartInProgress(es v1alpha1.Elasticsearch) bool {
+	_, inProgress := es.Annotations[FullClusterRestartAnnotationName]
+	return inProgress
+}
+
+// setFullClusterRestartInProgress sets or removes the full cluster restart annotation.
+func setFullClusterRestartInProgress(c k8s.Client, es *v1alpha1.Elasticsearch, inProgress bool) error {
+	if fullClusterRestartInProgress(*es) == inProgress {
+		return nil
+	}
+	if inProgress {
+		if es.Annotations == nil {
+			es.Annotations = make(map[string]string)
+		}
+		es.Annotations[FullClusterRestartAnnotationName] = "true"
+	} else {
+		delete(es.Annotations, FullClusterRestartAnnotationName)
+	}
+	return c.Update(es)
+}
+
+// handleFullClusterRestart updates the nodes of a cluster with the FullClusterRestart update strategy.
+// Shards allocation is disabled and a synced flush requested, then all the Pods to update are deleted at once,
+// to be recreated with the new specification by the StatefulSet controller.
+// Shards allocation is enabled again once all the nodes are back in the cluster.
+func (d *defaultDriver) handleFullClusterRestart(
+	esClient esclient.Client,
+	esState ESState,
+	statefulSets sset.StatefulSetList,
+) *reconciler.Results {
+	results := &reconciler.Results{}
+
+	ok, err := d.expectationsMet(statefulSets)
+	if err != nil {
+		return results.WithError(err)
+	}
+	if !ok {
+		return results.WithResult(defaultRequeue)
+	}
+
+	podsToUpgrade, err := podsToUpgrade(d.Client, statefulSets)
+	if err != nil {
+		return results.WithError(err)
+	}
+
+	if len(podsToUpgrade) == 0 {
+		d.ReconcileState.ReportCondition(upgradeInProgressCondition(nil, nil, nil))
+		if err := setFullClusterRestartInProgress(d.Client, &d.ES, false); err != nil {
+			return results.WithError(err)
+		}
+		// Maybe re-enable shards allocation if the cluster is formed again with all its nodes.
+		return results.WithResults(d.MaybeEnableShardsAllocation(esClient, esState, statefulSets))
+	}
+
+	if !fullClusterRestartInProgress(d.ES) {
+		log.Info("Preparing the cluster for a full restart", "namespace", d.ES.Namespace, "es_name", d.ES.Name)
+		if err := prepareClusterForNodeRestart(d.ES, esClient, esState); err != nil {
+			return results.WithError(err)
+		}
+		if err := setFullClusterRestartInProgress(d.Client, &d.ES, true); err != nil {
+			return results.WithError(err)
+		}
+	}
+
+	return results.WithResults(d.restartAllNodes(podsToUpgrade))
+}
+
+// resumeFullClusterRestart restarts the remaining nodes of a full cluster restart in progress.
+// It does not request Elasticsearch, which is expected to be unavailable while its nodes are down.
+func (d *defaultDriver) resumeFullClusterRestart(statefulSets sset.StatefulSetList) *reconciler.Results {
+	results := &reconciler.Results{}
+	if !fullClusterRestartInProgress(d.ES) {
+		// a full cluster restart can only start once Elasticsearch is prepared for it
+		return results
+	}
+
+	ok, err := d.expectationsMet(statefulSets)
+	if err != nil {
+		return results.WithError(err)
+	}
+	if !ok {
+		return results.WithResult(defaultRequeue)
+	}
+
+	podsToUpgrade, err := podsToUpgrade(d.Client, statefulSets)
+	if err != nil {
+		return results.WithError(err)
+	}
+	if len(podsToUpgrade) == 0 {
+		// all the nodes are restarted, waiting for the cluster to be formed again
+		return results
+	}
+	return results.WithResults(d.restartAllNodes(podsToUpgrade))
+}
+
+// restartAllNodes deletes all the given Pods at once.
+func (d *defaultDriver) restartAllNodes(podsToUpgrade []corev1.Pod) *reconciler.Results {
+	results := &reconciler.Results{}
+	for _, pod := range podsToUpgrade {
+		if !pod.DeletionTimestamp.IsZero() {
+			// already stopping
+			continue
+		}
+		log.Info("Deleting pod for full cluster restart",
+			"es_name", d.ES.Name, "namespace", d.ES.Namespace, "pod_name", pod.Name, "pod_uid", pod.UID,
+		)
+		pod := pod
+		d.Expectations.ExpectDeletion(pod)
+		if err := deletePodWithUIDPrecondition(d.Client, &pod); err != nil {
+			d.Expectations.CancelExpectedDeletion(pod)
+			if errors.IsConflict(err) || errors.IsNotFound(err) {
+				// Cache is not up to date or Pod has been deleted by someone else
+				continue
+			}
+			return results.WithError(err)
+		}
+	}
+	d.ReconcileState.ReportCondition(commonv1alpha1.NewCondition(
+		commonv1alpha1.UpgradeInProgressCondition, true, FullClusterRestartReason,
+		fmt.Sprintf("%d nodes to restart all at once", len(podsToUpgrade)),
+	))
+	return results.WithResult(defaultRequeue)
+}
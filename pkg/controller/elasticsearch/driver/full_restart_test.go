@@ -0,0 +1,209 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package driver
+
+import (
+	"testing"
+
+	commonv1alpha1 "github.com/elastic/cloud-on-k8s/pkg/apis/common/v1alpha1"
+	"github.com/elastic/cloud-on-k8s/pkg/apis/elasticsearch/v1alpha1"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/expectations"
+	esclient "github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/client"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/reconcile"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/sset"
+	"github.com/elastic/cloud-on-k8s/pkg/utils/k8s"
+	"github.com/stretchr/testify/require"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func fullRestartES(inProgress bool) v1alpha1.Elasticsearch {
+	es := v1alpha1.Elasticsearch{
+		ObjectMeta: metav1.ObjectMeta{Name: "es", Namespace: testNamespace},
+		Spec: v1alpha1.ElasticsearchSpec{
+			Version:        "7.2.0",
+			UpdateStrategy: v1alpha1.UpdateStrategy{Type: v1alpha1.FullClusterRestartStrategyType},
+		},
+	}
+	if inProgress {
+		es.Annotations = map[string]string{FullClusterRestartAnnotationName: "true"}
+	}
+	return es
+}
+
+func fullRestartSset(updatedReplicas int32) appsv1.StatefulSet {
+	return sset.TestSset{
+		Namespace: testNamespace, Name: "nodes", Replicas: 3, Master: true, Data: true,
+		Status: appsv1.StatefulSetStatus{CurrentRevision: "rev-a", UpdateRevision: "rev-b", UpdatedReplicas: updatedReplicas, Replicas: 3},
+	}.Build()
+}
+
+func fullRestartPod(name, revision string) *corev1.Pod {
+	return sset.TestPod{Namespace: testNamespace, Name: name, StatefulSetName: "nodes", Revision: revision}.BuildPtr()
+}
+
+func Test_defaultDriver_handleFullClusterRestart(t *testing.T) {
+	require.NoError(t, v1alpha1.AddToScheme(scheme.Scheme))
+	allocationDisabled := esclient.ClusterRoutingAllocation{}
+	allocationDisabled.Transient.Cluster.Routing.Allocation.Enable = "none"
+	nodesInCluster := esclient.Nodes{Nodes: map[string]esclient.Node{
+		"nodes-0": {Name: "nodes-0"}, "nodes-1": {Name: "nodes-1"}, "nodes-2": {Name: "nodes-2"},
+	}}
+
+	tests := []struct {
+		name                    string
+		es                      v1alpha1.Elasticsearch
+		statefulSet             appsv1.StatefulSet
+		pods                    []runtime.Object
+		esClient                *fakeESClient
+		wantPrepared            bool
+		wantAllocationEnabled   bool
+		wantRemainingPods       []string
+		wantInProgress          bool
+		wantUpgradeCondition    bool
+		wantUpgradeConditionWhy string
+		wantRequeue             bool
+	}{
+		{
+			name:        "no node to restart",
+			es:          fullRestartES(false),
+			statefulSet: fullRestartSset(3),
+			pods: []runtime.Object{
+				fullRestartPod("nodes-0", "rev-b"), fullRestartPod("nodes-1", "rev-b"), fullRestartPod("nodes-2", "rev-b"),
+			},
+			esClient:                &fakeESClient{nodes: nodesInCluster},
+			wantRemainingPods:       []string{"nodes-0", "nodes-1", "nodes-2"},
+			wantUpgradeConditionWhy: common.UpgradeCompletedReason,
+		},
+		{
+			name:        "prepare the cluster and restart all the nodes",
+			es:          fullRestartES(false),
+			statefulSet: fullRestartSset(0),
+			pods: []runtime.Object{
+				fullRestartPod("nodes-0", "rev-a"), fullRestartPod("nodes-1", "rev-a"), fullRestartPod("nodes-2", "rev-a"),
+			},
+			esClient:                &fakeESClient{nodes: nodesInCluster},
+			wantPrepared:            true,
+			wantRemainingPods:       nil,
+			wantInProgress:          true,
+			wantUpgradeCondition:    true,
+			wantUpgradeConditionWhy: FullClusterRestartReason,
+			wantRequeue:             true,
+		},
+		{
+			name:        "restart in progress: restart the remaining nodes",
+			es:          fullRestartES(true),
+			statefulSet: fullRestartSset(1),
+			pods: []runtime.Object{
+				fullRestartPod("nodes-0", "rev-a"), fullRestartPod("nodes-1", "rev-a"), fullRestartPod("nodes-2", "rev-b"),
+			},
+			esClient:                &fakeESClient{nodes: nodesInCluster, clusterRoutingAllocation: allocationDisabled},
+			wantRemainingPods:       []string{"nodes-2"},
+			wantInProgress:          true,
+			wantUpgradeCondition:    true,
+			wantUpgradeConditionWhy: FullClusterRestartReason,
+			wantRequeue:             true,
+		},
+		{
+			name:        "all the nodes are restarted: enable shards allocation",
+			es:          fullRestartES(true),
+			statefulSet: fullRestartSset(3),
+			pods: []runtime.Object{
+				fullRestartPod("nodes-0", "rev-b"), fullRestartPod("nodes-1", "rev-b"), fullRestartPod("nodes-2", "rev-b"),
+			},
+			esClient:                &fakeESClient{nodes: nodesInCluster, clusterRoutingAllocation: allocationDisabled},
+			wantAllocationEnabled:   true,
+			wantRemainingPods:       []string{"nodes-0", "nodes-1", "nodes-2"},
+			wantUpgradeConditionWhy: common.UpgradeCompletedReason,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			es := tt.es
+			objects := append([]runtime.Object{&es}, tt.pods...)
+			k8sClient := k8s.WrapClient(fake.NewFakeClient(objects...))
+			reconcileState := reconcile.NewState(es)
+			d := &defaultDriver{DefaultDriverParameters{
+				ES:             es,
+				Client:         k8sClient,
+				ReconcileState: reconcileState,
+				Expectations:   expectations.NewExpectations(),
+			}}
+
+			results := d.handleFullClusterRestart(tt.esClient, NewMemoizingESState(tt.esClient), sset.StatefulSetList{tt.statefulSet})
+			require.False(t, results.HasError())
+			res, _ := results.Aggregate()
+			require.Equal(t, tt.wantRequeue, res.Requeue || res.RequeueAfter > 0)
+
+			require.Equal(t, tt.wantPrepared, tt.esClient.DisableReplicaShardsAllocationCalled)
+			require.Equal(t, tt.wantPrepared, tt.esClient.SyncedFlushCalled)
+			require.Equal(t, tt.wantAllocationEnabled, tt.esClient.EnableShardAllocationCalled)
+
+			var pods corev1.PodList
+			require.NoError(t, k8sClient.List(nil, &pods))
+			require.ElementsMatch(t, tt.wantRemainingPods, names(pods.Items))
+
+			var updatedES v1alpha1.Elasticsearch
+			require.NoError(t, k8sClient.Get(k8s.ExtractNamespacedName(&es), &updatedES))
+			require.Equal(t, tt.wantInProgress, fullClusterRestartInProgress(updatedES))
+
+			_, withStatus := reconcileState.Apply()
+			require.NotNil(t, withStatus)
+			condition := withStatus.Status.Conditions.Get(commonv1alpha1.UpgradeInProgressCondition)
+			require.NotNil(t, condition)
+			require.Equal(t, tt.wantUpgradeCondition, condition.Status == corev1.ConditionTrue)
+			require.Equal(t, tt.wantUpgradeConditionWhy, condition.Reason)
+		})
+	}
+}
+
+func Test_defaultDriver_resumeFullClusterRestart(t *testing.T) {
+	tests := []struct {
+		name              string
+		es                v1alpha1.Elasticsearch
+		pods              []runtime.Object
+		wantRemainingPods []string
+	}{
+		{
+			name: "no full cluster restart in progress: do nothing",
+			es:   fullRestartES(false),
+			pods: []runtime.Object{
+				fullRestartPod("nodes-0", "rev-a"), fullRestartPod("nodes-1", "rev-a"), fullRestartPod("nodes-2", "rev-b"),
+			},
+			wantRemainingPods: []string{"nodes-0", "nodes-1", "nodes-2"},
+		},
+		{
+			name: "full cluster restart in progress: restart the remaining nodes",
+			es:   fullRestartES(true),
+			pods: []runtime.Object{
+				fullRestartPod("nodes-0", "rev-a"), fullRestartPod("nodes-1", "rev-a"), fullRestartPod("nodes-2", "rev-b"),
+			},
+			wantRemainingPods: []string{"nodes-2"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			k8sClient := k8s.WrapClient(fake.NewFakeClient(tt.pods...))
+			d := &defaultDriver{DefaultDriverParameters{
+				ES:             tt.es,
+				Client:         k8sClient,
+				ReconcileState: reconcile.NewState(tt.es),
+				Expectations:   expectations.NewExpectations(),
+			}}
+
+			results := d.resumeFullClusterRestart(sset.StatefulSetList{fullRestartSset(1)})
+			require.False(t, results.HasError())
+
+			var pods corev1.PodList
+			require.NoError(t, k8sClient.List(nil, &pods))
+			require.ElementsMatch(t, tt.wantRemainingPods, names(pods.Items))
+		})
+	}
+}
@@ -0,0 +1,400 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package driver
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/elastic/cloud-on-k8s/pkg/apis/elasticsearch/v1alpha1"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/events"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/expectations"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/reconciler"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/migration"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/observer"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/reconcile"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/sset"
+	"github.com/elastic/cloud-on-k8s/pkg/utils/k8s"
+	"github.com/elastic/cloud-on-k8s/pkg/utils/stringsutil"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// ReplaceNodeAnnotation can be set to "true" on a Pending Elasticsearch pod to delete its PersistentVolumeClaims,
+	// so that the pod is recreated with new volumes, possibly on another Kubernetes node.
+	ReplaceNodeAnnotation = "elasticsearch.k8s.elastic.co/replace-node"
+	// AutoReplaceNodesAfterAnnotation can be set on the Elasticsearch resource to a duration (eg. "15m") after which
+	// Pending pods bound to volumes of unavailable Kubernetes nodes are replaced automatically.
+	AutoReplaceNodesAfterAnnotation = "elasticsearch.k8s.elastic.co/auto-replace-nodes-after"
+	// ReplacingNodesAnnotation records on the Elasticsearch resource the pods being replaced, along with the time their
+	// replacement started, so that a replacement is completed even if the pod is recreated in the meantime.
+	ReplacingNodesAnnotation = "elasticsearch.k8s.elastic.co/replacing-nodes"
+)
+
+// nodeReplacementContext holds the parameters required to replace Elasticsearch nodes whose pod cannot be scheduled
+// anymore, because its volumes are local to a Kubernetes node that is gone.
+type nodeReplacementContext struct {
+	k8sClient      k8s.Client
+	es             v1alpha1.Elasticsearch
+	observedState  observer.State
+	reconcileState *reconcile.State
+	expectations   *expectations.Expectations
+	now            time.Time
+}
+
+// replaceUnavailableNodes deletes the PersistentVolumeClaims and the pod of the Pending Elasticsearch nodes
+// requested for replacement with the ReplaceNodeAnnotation, or pinned to an unavailable Kubernetes node for
+// longer than the duration of the AutoReplaceNodesAfterAnnotation. The StatefulSet controller then recreates
+// the pod with new volumes. A node is never replaced if it may hold the last copy of a shard.
+func replaceUnavailableNodes(ctx nodeReplacementContext, statefulSets sset.StatefulSetList) *reconciler.Results {
+	results := &reconciler.Results{}
+
+	autoReplaceAfter, err := autoReplaceNodesAfter(ctx.es)
+	if err != nil {
+		ctx.reconcileState.AddEvent(corev1.EventTypeWarning, events.EventReasonValidation, err.Error())
+	}
+	replacing, err := replacingNodes(ctx.es)
+	if err != nil {
+		// the replacements in progress are not resumed
+		log.Error(err, "Ignoring invalid annotation", "namespace", ctx.es.Namespace, "es_name", ctx.es.Name, "annotation", ReplacingNodesAnnotation)
+	}
+
+	for _, statefulSet := range statefulSets {
+		pods, err := sset.GetActualPodsForStatefulSet(ctx.k8sClient, statefulSet)
+		if err != nil {
+			return results.WithError(err)
+		}
+		for _, pod := range pods {
+			claims := podClaimNames(statefulSet, pod)
+			if len(claims) == 0 {
+				continue
+			}
+			if startedAt, exists := replacing[pod.Name]; exists {
+				results.WithResults(completeNodeReplacement(&ctx, replacing, pod, claims, startedAt))
+				continue
+			}
+			if pod.Status.Phase != corev1.PodPending || pod.DeletionTimestamp != nil {
+				continue
+			}
+			if pod.Annotations[ReplaceNodeAnnotation] != "true" {
+				if autoReplaceAfter <= 0 || ctx.now.Sub(pendingSince(pod)) < autoReplaceAfter {
+					continue
+				}
+				unavailable, err := volumesOnUnavailableNodes(ctx.k8sClient, pod.Namespace, claims)
+				if err != nil {
+					return results.WithError(err)
+				}
+				if !unavailable {
+					continue
+				}
+			}
+			results.WithResults(replaceNode(&ctx, replacing, pod, claims))
+		}
+	}
+	return results
+}
+
+// replaceNode starts the replacement of the given node, if its data is replicated elsewhere. The replacement is
+// recorded in the ReplacingNodesAnnotation of the Elasticsearch resource before any claim is deleted.
+func replaceNode(ctx *nodeReplacementContext, replacing map[string]time.Time, pod corev1.Pod, claims []string) *reconciler.Results {
+	results := &reconciler.Results{}
+	if !migration.IsDataReplicated(ctx.observedState, pod.Name) {
+		ctx.reconcileState.AddEvent(
+			corev1.EventTypeWarning,
+			events.EventReasonDelayed,
+			fmt.Sprintf("Cannot replace node %s: it may hold the last copy of a shard", pod.Name),
+		)
+		return results.WithResult(defaultRequeue)
+	}
+
+	log.Info("Replacing node", "namespace", pod.Namespace, "es_name", ctx.es.Name, "pod_name", pod.Name)
+	ctx.reconcileState.AddEvent(
+		corev1.EventTypeNormal,
+		events.EventReasonStateChange,
+		fmt.Sprintf("Replacing node %s with new volumes, its data is replicated on other nodes", pod.Name),
+	)
+	// claims and pods are only compared to the start time with a precision of one second
+	startedAt := ctx.now.UTC().Truncate(time.Second)
+	replacing[pod.Name] = startedAt
+	if err := setReplacingNodes(ctx, replacing); err != nil {
+		delete(replacing, pod.Name)
+		return results.WithError(err)
+	}
+	return completeNodeReplacement(ctx, replacing, pod, claims, startedAt)
+}
+
+// completeNodeReplacement deletes the given PersistentVolumeClaims of a node being replaced since the given time, then
+// its pod once the claims are gone, for the pod to be recreated with new claims. Claims and pods created after the
+// replacement started are kept: they are the replacements.
+func completeNodeReplacement(
+	ctx *nodeReplacementContext,
+	replacing map[string]time.Time,
+	pod corev1.Pod,
+	claims []string,
+	startedAt time.Time,
+) *reconciler.Results {
+	results := &reconciler.Results{}
+	remaining := false
+	for _, claim := range claims {
+		var pvc corev1.PersistentVolumeClaim
+		err := ctx.k8sClient.Get(types.NamespacedName{Namespace: pod.Namespace, Name: claim}, &pvc)
+		if apierrors.IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return results.WithError(err)
+		}
+		if !pvc.CreationTimestamp.Time.Before(startedAt) {
+			continue
+		}
+		remaining = true
+		if pvc.DeletionTimestamp != nil {
+			continue
+		}
+		if err := ctx.k8sClient.Delete(&pvc); err != nil && !apierrors.IsNotFound(err) {
+			return results.WithError(err)
+		}
+		ctx.reconcileState.AddEvent(
+			corev1.EventTypeNormal,
+			events.EventReasonDeleted,
+			fmt.Sprintf("Deleted PersistentVolumeClaim %s of node %s", claim, pod.Name),
+		)
+	}
+	if remaining {
+		// the pod must not be recreated before the claims are gone, or it would be bound to them again
+		log.V(1).Info("Waiting for the volume claims to be deleted", "namespace", pod.Namespace, "es_name", ctx.es.Name, "pod_name", pod.Name)
+		return results.WithResult(defaultRequeue)
+	}
+
+	if pod.CreationTimestamp.Time.Before(startedAt) && pod.DeletionTimestamp == nil {
+		if err := deleteReplacedPod(ctx, pod); err != nil {
+			return results.WithError(err)
+		}
+	}
+
+	log.Info("Node replaced", "namespace", pod.Namespace, "es_name", ctx.es.Name, "pod_name", pod.Name)
+	delete(replacing, pod.Name)
+	if err := setReplacingNodes(ctx, replacing); err != nil {
+		return results.WithError(err)
+	}
+	return results
+}
+
+// deleteReplacedPod deletes the pod of a node whose claims are deleted.
+func deleteReplacedPod(ctx *nodeReplacementContext, pod corev1.Pod) error {
+	ctx.expectations.ExpectDeletion(pod)
+	uid := pod.UID
+	if err := ctx.k8sClient.Delete(&pod, func(options *client.DeleteOptions) {
+		if options.Preconditions == nil {
+			options.Preconditions = &metav1.Preconditions{}
+		}
+		// make sure we do not delete a pod already recreated by the StatefulSet controller
+		options.Preconditions.UID = &uid
+	}); err != nil && !apierrors.IsNotFound(err) {
+		ctx.expectations.CancelExpectedDeletion(pod)
+		return err
+	}
+	ctx.reconcileState.AddEvent(
+		corev1.EventTypeNormal,
+		events.EventReasonDeleted,
+		fmt.Sprintf("Deleted pod %s, to be recreated with new volumes", pod.Name),
+	)
+	return nil
+}
+
+// replacingNodes returns the pods being replaced, with the time their replacement started, from the
+// ReplacingNodesAnnotation of the Elasticsearch resource.
+func replacingNodes(es v1alpha1.Elasticsearch) (map[string]time.Time, error) {
+	replacing := make(map[string]time.Time)
+	value, exists := es.Annotations[ReplacingNodesAnnotation]
+	if !exists {
+		return replacing, nil
+	}
+	if err := json.Unmarshal([]byte(value), &replacing); err != nil {
+		return make(map[string]time.Time), err
+	}
+	return replacing, nil
+}
+
+// setReplacingNodes records the given pods being replaced in the ReplacingNodesAnnotation of the Elasticsearch resource.
+func setReplacingNodes(ctx *nodeReplacementContext, replacing map[string]time.Time) error {
+	es := ctx.es.DeepCopy()
+	if len(replacing) == 0 {
+		if _, exists := es.Annotations[ReplacingNodesAnnotation]; !exists {
+			return nil
+		}
+		delete(es.Annotations, ReplacingNodesAnnotation)
+	} else {
+		value, err := json.Marshal(replacing)
+		if err != nil {
+			return err
+		}
+		if es.Annotations == nil {
+			es.Annotations = make(map[string]string)
+		}
+		es.Annotations[ReplacingNodesAnnotation] = string(value)
+	}
+	if err := ctx.k8sClient.Update(es); err != nil {
+		return err
+	}
+	ctx.es = *es
+	return nil
+}
+
+// autoReplaceNodesAfter returns the duration set in the AutoReplaceNodesAfterAnnotation, or 0 if not set.
+func autoReplaceNodesAfter(es v1alpha1.Elasticsearch) (time.Duration, error) {
+	value, exists := es.Annotations[AutoReplaceNodesAfterAnnotation]
+	if !exists {
+		return 0, nil
+	}
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid duration in annotation %s: %s", AutoReplaceNodesAfterAnnotation, value)
+	}
+	return duration, nil
+}
+
+// podClaimNames returns the names of the PersistentVolumeClaims of the given StatefulSet pod.
+func podClaimNames(statefulSet appsv1.StatefulSet, pod corev1.Pod) []string {
+	claims := make([]string, 0, len(statefulSet.Spec.VolumeClaimTemplates))
+	for _, template := range statefulSet.Spec.VolumeClaimTemplates {
+		claims = append(claims, template.Name+"-"+pod.Name)
+	}
+	return claims
+}
+
+// pendingSince returns the time since which the pod could not be scheduled, or its creation time.
+func pendingSince(pod corev1.Pod) time.Time {
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == corev1.PodScheduled && condition.Status == corev1.ConditionFalse {
+			return condition.LastTransitionTime.Time
+		}
+	}
+	return pod.CreationTimestamp.Time
+}
+
+// volumesOnUnavailableNodes returns true if at least one of the given claims is bound to a volume that can only be
+// accessed from Kubernetes nodes that are gone or not ready, such as a local volume.
+func volumesOnUnavailableNodes(c k8s.Client, namespace string, claims []string) (bool, error) {
+	var nodes corev1.NodeList
+	if err := c.List(&client.ListOptions{}, &nodes); err != nil {
+		return false, err
+	}
+	for _, claim := range claims {
+		var pvc corev1.PersistentVolumeClaim
+		err := c.Get(types.NamespacedName{Namespace: namespace, Name: claim}, &pvc)
+		if apierrors.IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return false, err
+		}
+		if pvc.Spec.VolumeName == "" {
+			continue
+		}
+		var pv corev1.PersistentVolume
+		err = c.Get(types.NamespacedName{Name: pvc.Spec.VolumeName}, &pv)
+		if apierrors.IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return false, err
+		}
+		if pv.Spec.NodeAffinity == nil || pv.Spec.NodeAffinity.Required == nil {
+			continue
+		}
+		if !readyNodeMatches(nodes.Items, *pv.Spec.NodeAffinity.Required) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// readyNodeMatches returns true if at least one of the given nodes is ready and matches the node selector.
+func readyNodeMatches(nodes []corev1.Node, selector corev1.NodeSelector) bool {
+	for _, node := range nodes {
+		if nodeIsReady(node) && nodeMatches(node, selector) {
+			return true
+		}
+	}
+	return false
+}
+
+func nodeIsReady(node corev1.Node) bool {
+	for _, condition := range node.Status.Conditions {
+		if condition.Type == corev1.NodeReady {
+			return condition.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
+
+// nodeMatches returns true if the node matches at least one of the terms of the selector.
+func nodeMatches(node corev1.Node, selector corev1.NodeSelector) bool {
+	for _, term := range selector.NodeSelectorTerms {
+		if nodeMatchesTerm(node, term) {
+			return true
+		}
+	}
+	return false
+}
+
+// nodeMatchesTerm returns true if the node matches all the requirements of the term. An empty term matches no node.
+func nodeMatchesTerm(node corev1.Node, term corev1.NodeSelectorTerm) bool {
+	if len(term.MatchExpressions) == 0 && len(term.MatchFields) == 0 {
+		return false
+	}
+	for _, requirement := range term.MatchExpressions {
+		if !requirementMatches(requirement, node.Labels) {
+			return false
+		}
+	}
+	for _, requirement := range term.MatchFields {
+		// metadata.name is the only supported field
+		if !requirementMatches(requirement, map[string]string{"metadata.name": node.Name}) {
+			return false
+		}
+	}
+	return true
+}
+
+func requirementMatches(requirement corev1.NodeSelectorRequirement, values map[string]string) bool {
+	value, exists := values[requirement.Key]
+	switch requirement.Operator {
+	case corev1.NodeSelectorOpIn:
+		return exists && stringsutil.StringInSlice(value, requirement.Values)
+	case corev1.NodeSelectorOpNotIn:
+		return !exists || !stringsutil.StringInSlice(value, requirement.Values)
+	case corev1.NodeSelectorOpExists:
+		return exists
+	case corev1.NodeSelectorOpDoesNotExist:
+		return !exists
+	case corev1.NodeSelectorOpGt, corev1.NodeSelectorOpLt:
+		if !exists || len(requirement.Values) != 1 {
+			return false
+		}
+		actual, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return false
+		}
+		expected, err := strconv.ParseInt(requirement.Values[0], 10, 64)
+		if err != nil {
+			return false
+		}
+		if requirement.Operator == corev1.NodeSelectorOpGt {
+			return actual > expected
+		}
+		return actual < expected
+	default:
+		return false
+	}
+}
@@ -0,0 +1,426 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package driver
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/elastic/cloud-on-k8s/pkg/apis/elasticsearch/v1alpha1"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/events"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/expectations"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/reconciler"
+	esclient "github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/client"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/observer"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/reconcile"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/sset"
+	"github.com/elastic/cloud-on-k8s/pkg/utils/k8s"
+)
+
+func Test_requirementMatches(t *testing.T) {
+	labels := map[string]string{"zone": "a", "cores": "8"}
+	tests := []struct {
+		name        string
+		requirement corev1.NodeSelectorRequirement
+		want        bool
+	}{
+		{
+			name:        "In: match",
+			requirement: corev1.NodeSelectorRequirement{Key: "zone", Operator: corev1.NodeSelectorOpIn, Values: []string{"a", "b"}},
+			want:        true,
+		},
+		{
+			name:        "In: no match",
+			requirement: corev1.NodeSelectorRequirement{Key: "zone", Operator: corev1.NodeSelectorOpIn, Values: []string{"b"}},
+			want:        false,
+		},
+		{
+			name:        "In: missing label",
+			requirement: corev1.NodeSelectorRequirement{Key: "rack", Operator: corev1.NodeSelectorOpIn, Values: []string{"a"}},
+			want:        false,
+		},
+		{
+			name:        "NotIn: match",
+			requirement: corev1.NodeSelectorRequirement{Key: "zone", Operator: corev1.NodeSelectorOpNotIn, Values: []string{"b"}},
+			want:        true,
+		},
+		{
+			name:        "NotIn: missing label",
+			requirement: corev1.NodeSelectorRequirement{Key: "rack", Operator: corev1.NodeSelectorOpNotIn, Values: []string{"a"}},
+			want:        true,
+		},
+		{
+			name:        "NotIn: no match",
+			requirement: corev1.NodeSelectorRequirement{Key: "zone", Operator: corev1.NodeSelectorOpNotIn, Values: []string{"a"}},
+			want:        false,
+		},
+		{
+			name:        "Exists",
+			requirement: corev1.NodeSelectorRequirement{Key: "zone", Operator: corev1.NodeSelectorOpExists},
+			want:        true,
+		},
+		{
+			name:        "DoesNotExist",
+			requirement: corev1.NodeSelectorRequirement{Key: "zone", Operator: corev1.NodeSelectorOpDoesNotExist},
+			want:        false,
+		},
+		{
+			name:        "Gt",
+			requirement: corev1.NodeSelectorRequirement{Key: "cores", Operator: corev1.NodeSelectorOpGt, Values: []string{"4"}},
+			want:        true,
+		},
+		{
+			name:        "Lt",
+			requirement: corev1.NodeSelectorRequirement{Key: "cores", Operator: corev1.NodeSelectorOpLt, Values: []string{"4"}},
+			want:        false,
+		},
+		{
+			name:        "Gt: not an integer",
+			requirement: corev1.NodeSelectorRequirement{Key: "zone", Operator: corev1.NodeSelectorOpGt, Values: []string{"4"}},
+			want:        false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, requirementMatches(tt.requirement, labels))
+		})
+	}
+}
+
+func Test_nodeMatches(t *testing.T) {
+	node := corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-1", Labels: map[string]string{"zone": "a"}}}
+	tests := []struct {
+		name     string
+		selector corev1.NodeSelector
+		want     bool
+	}{
+		{
+			name:     "no term",
+			selector: corev1.NodeSelector{},
+			want:     false,
+		},
+		{
+			name:     "empty term",
+			selector: corev1.NodeSelector{NodeSelectorTerms: []corev1.NodeSelectorTerm{{}}},
+			want:     false,
+		},
+		{
+			name: "match on the node name",
+			selector: corev1.NodeSelector{NodeSelectorTerms: []corev1.NodeSelectorTerm{{
+				MatchFields: []corev1.NodeSelectorRequirement{{Key: "metadata.name", Operator: corev1.NodeSelectorOpIn, Values: []string{"node-1"}}},
+			}}},
+			want: true,
+		},
+		{
+			name: "all requirements of a term must match",
+			selector: corev1.NodeSelector{NodeSelectorTerms: []corev1.NodeSelectorTerm{{
+				MatchExpressions: []corev1.NodeSelectorRequirement{{Key: "zone", Operator: corev1.NodeSelectorOpIn, Values: []string{"a"}}},
+				MatchFields:      []corev1.NodeSelectorRequirement{{Key: "metadata.name", Operator: corev1.NodeSelectorOpIn, Values: []string{"node-2"}}},
+			}}},
+			want: false,
+		},
+		{
+			name: "one term must match",
+			selector: corev1.NodeSelector{NodeSelectorTerms: []corev1.NodeSelectorTerm{
+				{MatchExpressions: []corev1.NodeSelectorRequirement{{Key: "zone", Operator: corev1.NodeSelectorOpIn, Values: []string{"b"}}}},
+				{MatchExpressions: []corev1.NodeSelectorRequirement{{Key: "zone", Operator: corev1.NodeSelectorOpIn, Values: []string{"a"}}}},
+			}},
+			want: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, nodeMatches(node, tt.selector))
+		})
+	}
+}
+
+// Fixtures for the node replacement tests: a pod of the StatefulSet "sset" bound to a local volume on "node-1".
+var (
+	replacementSset = sset.TestSset{Namespace: "ns", Name: "sset", ClusterName: "cluster", Version: "7.2.0", Replicas: 1, Data: true}
+	replacementNode = func(name string, ready corev1.ConditionStatus) *corev1.Node {
+		return &corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Status:     corev1.NodeStatus{Conditions: []corev1.NodeCondition{{Type: corev1.NodeReady, Status: ready}}},
+		}
+	}
+	replacementPV = &corev1.PersistentVolume{
+		ObjectMeta: metav1.ObjectMeta{Name: "local-pv"},
+		Spec: corev1.PersistentVolumeSpec{
+			NodeAffinity: &corev1.VolumeNodeAffinity{Required: &corev1.NodeSelector{
+				NodeSelectorTerms: []corev1.NodeSelectorTerm{{MatchExpressions: []corev1.NodeSelectorRequirement{
+					{Key: "kubernetes.io/hostname", Operator: corev1.NodeSelectorOpIn, Values: []string{"node-1"}},
+				}}},
+			}},
+		},
+	}
+	replacementPVC = func() *corev1.PersistentVolumeClaim {
+		return &corev1.PersistentVolumeClaim{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "elasticsearch-data-sset-0"},
+			Spec:       corev1.PersistentVolumeClaimSpec{VolumeName: "local-pv"},
+		}
+	}
+	replacementPod = func(annotations map[string]string, pendingSince time.Time) *corev1.Pod {
+		pod := sset.TestPod{
+			Namespace:       "ns",
+			Name:            "sset-0",
+			ClusterName:     "cluster",
+			StatefulSetName: "sset",
+			Version:         "7.2.0",
+			Data:            true,
+			Status: corev1.PodStatus{
+				Phase: corev1.PodPending,
+				Conditions: []corev1.PodCondition{{
+					Type:               corev1.PodScheduled,
+					Status:             corev1.ConditionFalse,
+					LastTransitionTime: metav1.NewTime(pendingSince),
+				}},
+			},
+		}.BuildPtr()
+		pod.Annotations = annotations
+		return pod
+	}
+	replicatedState = observer.State{ClusterState: &esclient.ClusterState{
+		ClusterName: "cluster",
+		RoutingTable: esclient.RoutingTable{Indices: map[string]esclient.Shards{
+			"index-1": {Shards: map[string][]esclient.Shard{"0": {
+				{Index: "index-1", Shard: 0, State: esclient.STARTED, Node: "sset-1"},
+			}}},
+		}},
+	}}
+	lastCopyState = observer.State{ClusterState: &esclient.ClusterState{
+		ClusterName: "cluster",
+		RoutingTable: esclient.RoutingTable{Indices: map[string]esclient.Shards{
+			"index-1": {Shards: map[string][]esclient.Shard{"0": {
+				{Index: "index-1", Shard: 0, State: esclient.STARTED, Node: "sset-0"},
+			}}},
+		}},
+	}}
+)
+
+func Test_replaceUnavailableNodes(t *testing.T) {
+	require.NoError(t, v1alpha1.AddToScheme(scheme.Scheme))
+	now := time.Date(2019, 8, 1, 12, 0, 0, 0, time.UTC)
+	statefulSet := replacementSset.Build()
+	statefulSet.Spec.VolumeClaimTemplates = []corev1.PersistentVolumeClaim{
+		{ObjectMeta: metav1.ObjectMeta{Name: "elasticsearch-data"}},
+	}
+	autoReplace := map[string]string{AutoReplaceNodesAfterAnnotation: "10m"}
+	replacing := map[string]string{ReplacingNodesAnnotation: `{"sset-0":"2019-08-01T11:00:00Z"}`}
+	recreatedPod := func() *corev1.Pod {
+		pod := sset.TestPod{Namespace: "ns", Name: "sset-0", StatefulSetName: "sset", Status: corev1.PodStatus{Phase: corev1.PodRunning}}.BuildPtr()
+		pod.CreationTimestamp = metav1.NewTime(now.Add(-time.Minute))
+		return pod
+	}
+	recreatedPVC := func() *corev1.PersistentVolumeClaim {
+		pvc := replacementPVC()
+		pvc.CreationTimestamp = metav1.NewTime(now.Add(-time.Minute))
+		return pvc
+	}
+
+	tests := []struct {
+		name             string
+		esAnnotations    map[string]string
+		runtimeObjs      []runtime.Object
+		observedState    observer.State
+		wantReplaced     bool
+		podRecreated     bool
+		wantResults      *reconciler.Results
+		wantEventReasons []string
+	}{
+		{
+			name: "running pod: nothing to do",
+			runtimeObjs: []runtime.Object{
+				sset.TestPod{Namespace: "ns", Name: "sset-0", StatefulSetName: "sset", Status: corev1.PodStatus{Phase: corev1.PodRunning}}.BuildPtr(),
+				replacementPVC(),
+			},
+			observedState: replicatedState,
+			wantReplaced:  false,
+			wantResults:   &reconciler.Results{},
+		},
+		{
+			name:          "pending pod without annotation: nothing to do",
+			runtimeObjs:   []runtime.Object{replacementPod(nil, now.Add(-time.Hour)), replacementPVC(), replacementPV},
+			observedState: replicatedState,
+			wantReplaced:  false,
+			wantResults:   &reconciler.Results{},
+		},
+		{
+			name: "pending pod with the replace annotation: replace",
+			runtimeObjs: []runtime.Object{
+				replacementPod(map[string]string{ReplaceNodeAnnotation: "true"}, now), replacementPVC(),
+			},
+			observedState:    replicatedState,
+			wantReplaced:     true,
+			wantResults:      (&reconciler.Results{}).WithResult(defaultRequeue),
+			wantEventReasons: []string{events.EventReasonStateChange, events.EventReasonDeleted},
+		},
+		{
+			name: "pending pod with the replace annotation holding the last copy of a shard: refuse",
+			runtimeObjs: []runtime.Object{
+				replacementPod(map[string]string{ReplaceNodeAnnotation: "true"}, now), replacementPVC(),
+			},
+			observedState:    lastCopyState,
+			wantReplaced:     false,
+			wantResults:      (&reconciler.Results{}).WithResult(defaultRequeue),
+			wantEventReasons: []string{events.EventReasonDelayed},
+		},
+		{
+			name: "pending pod with the replace annotation and unknown cluster state: refuse",
+			runtimeObjs: []runtime.Object{
+				replacementPod(map[string]string{ReplaceNodeAnnotation: "true"}, now), replacementPVC(),
+			},
+			observedState:    observer.State{},
+			wantReplaced:     false,
+			wantResults:      (&reconciler.Results{}).WithResult(defaultRequeue),
+			wantEventReasons: []string{events.EventReasonDelayed},
+		},
+		{
+			name:          "auto replacement: Kubernetes node gone for long enough: replace",
+			esAnnotations: autoReplace,
+			runtimeObjs: []runtime.Object{
+				replacementPod(nil, now.Add(-time.Hour)), replacementPVC(), replacementPV,
+				replacementNode("node-2", corev1.ConditionTrue),
+			},
+			observedState:    replicatedState,
+			wantReplaced:     true,
+			wantResults:      (&reconciler.Results{}).WithResult(defaultRequeue),
+			wantEventReasons: []string{events.EventReasonStateChange, events.EventReasonDeleted},
+		},
+		{
+			name:          "auto replacement: Kubernetes node not ready for long enough: replace",
+			esAnnotations: autoReplace,
+			runtimeObjs: []runtime.Object{
+				replacementPod(nil, now.Add(-time.Hour)), replacementPVC(), replacementPV,
+				replacementNode("node-1", corev1.ConditionFalse),
+			},
+			observedState:    replicatedState,
+			wantReplaced:     true,
+			wantResults:      (&reconciler.Results{}).WithResult(defaultRequeue),
+			wantEventReasons: []string{events.EventReasonStateChange, events.EventReasonDeleted},
+		},
+		{
+			name:          "auto replacement: Kubernetes node gone, but not for long enough: nothing to do",
+			esAnnotations: autoReplace,
+			runtimeObjs: []runtime.Object{
+				replacementPod(nil, now.Add(-time.Minute)), replacementPVC(), replacementPV,
+			},
+			observedState: replicatedState,
+			wantReplaced:  false,
+			wantResults:   &reconciler.Results{},
+		},
+		{
+			name:          "auto replacement: Kubernetes node available: nothing to do",
+			esAnnotations: autoReplace,
+			runtimeObjs: []runtime.Object{
+				replacementPod(nil, now.Add(-time.Hour)), replacementPVC(), replacementPV,
+				replacementNode("node-1", corev1.ConditionTrue),
+			},
+			observedState: replicatedState,
+			wantReplaced:  false,
+			wantResults:   &reconciler.Results{},
+		},
+		{
+			name:             "replacement in progress of a recreated pod: the previous claim is deleted",
+			esAnnotations:    replacing,
+			runtimeObjs:      []runtime.Object{recreatedPod(), replacementPVC()},
+			observedState:    lastCopyState,
+			wantReplaced:     true,
+			podRecreated:     true,
+			wantResults:      (&reconciler.Results{}).WithResult(defaultRequeue),
+			wantEventReasons: []string{events.EventReasonDeleted},
+		},
+		{
+			name:          "replacement in progress: the new claim is kept",
+			esAnnotations: replacing,
+			runtimeObjs:   []runtime.Object{recreatedPod(), recreatedPVC()},
+			observedState: lastCopyState,
+			wantReplaced:  false,
+			wantResults:   &reconciler.Results{},
+		},
+		{
+			name:          "invalid auto replacement annotation: ignored",
+			esAnnotations: map[string]string{AutoReplaceNodesAfterAnnotation: "ten minutes"},
+			runtimeObjs: []runtime.Object{
+				replacementPod(nil, now.Add(-time.Hour)), replacementPVC(), replacementPV,
+			},
+			observedState:    replicatedState,
+			wantReplaced:     false,
+			wantResults:      &reconciler.Results{},
+			wantEventReasons: []string{events.EventReasonValidation},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			es := v1alpha1.Elasticsearch{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "cluster", Annotations: tt.esAnnotations}}
+			k8sClient := k8s.WrapClient(fake.NewFakeClient(append(tt.runtimeObjs, &es)...))
+			newContext := func() nodeReplacementContext {
+				var current v1alpha1.Elasticsearch
+				require.NoError(t, k8sClient.Get(k8s.ExtractNamespacedName(&es), &current))
+				return nodeReplacementContext{
+					k8sClient:      k8sClient,
+					es:             current,
+					observedState:  tt.observedState,
+					reconcileState: reconcile.NewState(current),
+					expectations:   expectations.NewExpectations(),
+					now:            now,
+				}
+			}
+			eventReasons := func(ctx nodeReplacementContext) []string {
+				reasons := make([]string, 0, len(ctx.reconcileState.Events()))
+				for _, event := range ctx.reconcileState.Events() {
+					reasons = append(reasons, event.Reason)
+				}
+				return reasons
+			}
+
+			ctx := newContext()
+			results := replaceUnavailableNodes(ctx, sset.StatefulSetList{statefulSet})
+			require.Equal(t, tt.wantResults, results)
+			if len(tt.wantEventReasons) == 0 {
+				require.Empty(t, eventReasons(ctx))
+			} else {
+				require.Equal(t, tt.wantEventReasons, eventReasons(ctx))
+			}
+
+			var pvc corev1.PersistentVolumeClaim
+			pvcErr := k8sClient.Get(types.NamespacedName{Namespace: "ns", Name: "elasticsearch-data-sset-0"}, &pvc)
+			var pod corev1.Pod
+			podErr := k8sClient.Get(types.NamespacedName{Namespace: "ns", Name: "sset-0"}, &pod)
+			// the pod is only deleted once its claims are gone
+			require.NoError(t, podErr)
+			if !tt.wantReplaced {
+				require.NoError(t, pvcErr)
+				require.NotContains(t, newContext().es.Annotations, ReplacingNodesAnnotation)
+				return
+			}
+			require.True(t, apierrors.IsNotFound(pvcErr))
+			// the replacement is recorded, in case the pod is recreated in the meantime
+			require.Contains(t, newContext().es.Annotations[ReplacingNodesAnnotation], "sset-0")
+
+			// the claims are gone: the pod is deleted, unless recreated since the replacement started
+			ctx = newContext()
+			require.Equal(t, &reconciler.Results{}, replaceUnavailableNodes(ctx, sset.StatefulSetList{statefulSet}))
+			podErr = k8sClient.Get(types.NamespacedName{Namespace: "ns", Name: "sset-0"}, &pod)
+			if tt.podRecreated {
+				require.NoError(t, podErr)
+				require.Empty(t, eventReasons(ctx))
+			} else {
+				require.True(t, apierrors.IsNotFound(podErr))
+				require.Equal(t, []string{events.EventReasonDeleted}, eventReasons(ctx))
+			}
+			require.NotContains(t, newContext().es.Annotations, ReplacingNodesAnnotation)
+		})
+	}
+}
@@ -5,6 +5,8 @@
 package driver
 
 import (
+	"time"
+
 	"github.com/elastic/cloud-on-k8s/pkg/controller/common/keystore"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/common/reconciler"
 	esclient "github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/client"
@@ -41,10 +43,19 @@ func (d *defaultDriver) reconcileNodeSpecs(
 		return results.WithResult(defaultRequeue)
 	}
 
-	expectedResources, err := nodespec.BuildExpectedResources(d.ES, keystoreResources)
+	expectedResources, err := nodespec.BuildExpectedResources(
+		d.ES, d.Version, keystoreResources, d.OperatorParameters.HeapMemoryPercentage, d.OperatorParameters.RestrictedMode,
+	)
+	if err != nil {
+		return results.WithError(err)
+	}
+
+	// record the changes still to perform in the status
+	pendingChanges, err := planChanges(d.Client, expectedResources.StatefulSets(), actualStatefulSets)
 	if err != nil {
 		return results.WithError(err)
 	}
+	reconcileState.UpdatePendingChanges(pendingChanges)
 
 	esState := NewMemoizingESState(esClient)
 
@@ -65,6 +76,10 @@ func (d *defaultDriver) reconcileNodeSpecs(
 		// Cannot perform next operations if we cannot request Elasticsearch.
 		log.Info("ES external service not ready yet for further reconciliation, re-queuing.", "namespace", d.ES.Namespace, "es_name", d.ES.Name)
 		reconcileState.UpdateElasticsearchPending(resourcesState.CurrentPods)
+		if d.ES.Spec.UpdateStrategy.FullClusterRestart() {
+			// Nodes stopped for a full cluster restart cannot wait for Elasticsearch to be available.
+			results.WithResults(d.resumeFullClusterRestart(actualStatefulSets))
+		}
 		return results.WithResult(defaultRequeue)
 	}
 
@@ -85,7 +100,22 @@ func (d *defaultDriver) reconcileNodeSpecs(
 		results.WithResult(defaultRequeue)
 	}
 
-	// Phase 2: handle sset scale down.
+	// Phase 2: replace nodes stuck Pending because their volumes are bound to unavailable Kubernetes nodes.
+	replacementCtx := nodeReplacementContext{
+		k8sClient:      d.Client,
+		es:             d.ES,
+		observedState:  observedState,
+		reconcileState: reconcileState,
+		expectations:   d.Expectations,
+		now:            time.Now(),
+	}
+	replacementRes := replaceUnavailableNodes(replacementCtx, actualStatefulSets)
+	results.WithResults(replacementRes)
+	if replacementRes.HasError() {
+		return results
+	}
+
+	// Phase 3: handle sset scale down.
 	// We want to safely remove nodes from the cluster, either because the sset requires less replicas,
 	// or because it should be removed entirely.
 	downscaleCtx := downscaleContext{
@@ -103,8 +133,13 @@ func (d *defaultDriver) reconcileNodeSpecs(
 		return results
 	}
 
-	// Phase 3: handle rolling upgrades.
-	rollingUpgradesRes := d.handleRollingUpgrades(esClient, esState, actualStatefulSets, expectedResources.MasterNodesNames())
+	// Phase 4: handle rolling upgrades, or restart all the nodes at once.
+	var rollingUpgradesRes *reconciler.Results
+	if d.ES.Spec.UpdateStrategy.FullClusterRestart() {
+		rollingUpgradesRes = d.handleFullClusterRestart(esClient, esState, actualStatefulSets)
+	} else {
+		rollingUpgradesRes = d.handleRollingUpgrades(esClient, esState, actualStatefulSets, expectedResources.MasterNodesNames(), observedState)
+	}
 	results.WithResults(rollingUpgradesRes)
 	if rollingUpgradesRes.HasError() {
 		return results
@@ -0,0 +1,76 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package driver
+
+import (
+	"context"
+
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/events"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/reconciler"
+	commonuser "github.com/elastic/cloud-on-k8s/pkg/controller/common/user"
+	esclient "github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/client"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/services"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/user"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// reconcilePasswordRotation returns the user the operator must use to interact with Elasticsearch.
+// While a password rotation is in progress, the current password of the controller user is used until the pending one
+// is accepted by all the running nodes, at which point the rotation is completed: the pending passwords replace the
+// current ones.
+// newClient returns a client for the given URL, authenticated with the given user.
+func (d *defaultDriver) reconcilePasswordRotation(
+	internalUsers user.InternalUsers,
+	runningPods []corev1.Pod,
+	newClient func(url string, u user.User) esclient.Client,
+) (user.User, *reconciler.Results) {
+	results := &reconciler.Results{}
+	if internalUsers.PendingControllerUser == nil {
+		_, rotationRequested := d.ES.Annotations[commonuser.PasswordRotationAnnotationName]
+		if rotationRequested && !commonuser.PasswordRotationPropagated(d.ES) {
+			// record a rotation whose passwords were replaced, in case it could not be recorded when completed
+			if err := user.CompletePasswordRotation(d.Client, d.ES); err != nil {
+				return internalUsers.ControllerUser, results.WithError(err)
+			}
+		}
+		return internalUsers.ControllerUser, results
+	}
+
+	pending := *internalUsers.PendingControllerUser
+	if !passwordPropagated(runningPods, func(url string) esclient.Client { return newClient(url, pending) }) {
+		log.Info("Waiting for the new passwords to be propagated to all nodes", "namespace", d.ES.Namespace, "es_name", d.ES.Name)
+		return internalUsers.ControllerUser, results.WithResult(defaultRequeue)
+	}
+
+	if err := user.CompletePasswordRotation(d.Client, d.ES); err != nil {
+		return pending, results.WithError(err)
+	}
+	d.ReconcileState.AddEvent(corev1.EventTypeNormal, events.EventReasonStateChange, "Passwords rotated")
+	return pending, results
+}
+
+// passwordPropagated returns true if all the given Pods accept the credentials of the clients returned by newClient.
+func passwordPropagated(pods []corev1.Pod, newClient func(url string) esclient.Client) bool {
+	for _, pod := range pods {
+		url := services.ElasticsearchPodURL(pod)
+		if url == "" {
+			return false
+		}
+		if !acceptsCredentials(newClient(url)) {
+			log.V(1).Info("New password not propagated yet", "namespace", pod.Namespace, "pod_name", pod.Name)
+			return false
+		}
+	}
+	return true
+}
+
+// acceptsCredentials returns true if the given client can successfully request Elasticsearch.
+func acceptsCredentials(c esclient.Client) bool {
+	defer c.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), esclient.DefaultReqTimeout)
+	defer cancel()
+	_, err := c.GetClusterInfo(ctx)
+	return err == nil
+}
@@ -0,0 +1,205 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package driver
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/elastic/cloud-on-k8s/pkg/apis/elasticsearch/v1alpha1"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/expectations"
+	commonuser "github.com/elastic/cloud-on-k8s/pkg/controller/common/user"
+	esclient "github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/client"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/label"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/reconcile"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/user"
+	"github.com/elastic/cloud-on-k8s/pkg/utils/k8s"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+// credentialsCheckESClient mocks an ES client whose credentials are rejected by some nodes.
+type credentialsCheckESClient struct {
+	esclient.Client
+	rejected bool
+}
+
+func (c *credentialsCheckESClient) GetClusterInfo(_ context.Context) (esclient.Info, error) {
+	if c.rejected {
+		return esclient.Info{}, errors.New("401 Unauthorized")
+	}
+	return esclient.Info{}, nil
+}
+
+func (c *credentialsCheckESClient) Close() {}
+
+// newCredentialsCheckESClient returns a client factory for which the given URLs reject the credentials.
+func newCredentialsCheckESClient(rejectedURLs ...string) func(url string) esclient.Client {
+	return func(url string) esclient.Client {
+		for _, rejected := range rejectedURLs {
+			if url == rejected {
+				return &credentialsCheckESClient{rejected: true}
+			}
+		}
+		return &credentialsCheckESClient{}
+	}
+}
+
+func runningESPod(name string) corev1.Pod {
+	return corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "ns",
+			Name:      name,
+			Labels: map[string]string{
+				label.HTTPSchemeLabelName:      "https",
+				label.StatefulSetNameLabelName: "sset",
+			},
+		},
+	}
+}
+
+func Test_passwordPropagated(t *testing.T) {
+	pods := []corev1.Pod{runningESPod("sset-0"), runningESPod("sset-1")}
+	tests := []struct {
+		name      string
+		pods      []corev1.Pod
+		newClient func(url string) esclient.Client
+		want      bool
+	}{
+		{
+			name:      "no pods",
+			newClient: newCredentialsCheckESClient(),
+			want:      true,
+		},
+		{
+			name:      "all pods accept the new credentials",
+			pods:      pods,
+			newClient: newCredentialsCheckESClient(),
+			want:      true,
+		},
+		{
+			name:      "a pod rejects the new credentials",
+			pods:      pods,
+			newClient: newCredentialsCheckESClient("https://sset-1.sset.ns:9200"),
+			want:      false,
+		},
+		{
+			name:      "pod URL unknown",
+			pods:      []corev1.Pod{{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "sset-0"}}},
+			newClient: newCredentialsCheckESClient(),
+			want:      false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, passwordPropagated(tt.pods, tt.newClient))
+		})
+	}
+}
+
+func Test_defaultDriver_reconcilePasswordRotation(t *testing.T) {
+	require.NoError(t, v1alpha1.AddToScheme(scheme.Scheme))
+	es := v1alpha1.Elasticsearch{ObjectMeta: metav1.ObjectMeta{
+		Namespace:   "ns",
+		Name:        "es",
+		Annotations: map[string]string{commonuser.PasswordRotationAnnotationName: "1"},
+	}}
+	controllerUser := user.New(user.InternalControllerUserName, user.Password("current"))
+	pendingUser := user.New(user.InternalControllerUserName, user.Password("pending"))
+	pods := []corev1.Pod{runningESPod("sset-0"), runningESPod("sset-1")}
+	usersSecret := func(name string, data map[string][]byte) *corev1.Secret {
+		return &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace:   "ns",
+				Name:        name,
+				Annotations: map[string]string{commonuser.PasswordRotationAnnotationName: "1"},
+			},
+			Data: data,
+		}
+	}
+
+	tests := []struct {
+		name              string
+		internalUsers     user.InternalUsers
+		internalData      map[string][]byte
+		newClient         func(url string) esclient.Client
+		wantUser          user.User
+		wantRequeue       bool
+		wantSecretEntries int
+		wantPropagated    bool
+	}{
+		{
+			name:          "no rotation in progress, rotation already recorded",
+			internalUsers: user.InternalUsers{ControllerUser: controllerUser},
+			internalData: map[string][]byte{
+				user.InternalControllerUserName: []byte("current"),
+			},
+			newClient:         newCredentialsCheckESClient(),
+			wantUser:          controllerUser,
+			wantSecretEntries: 1,
+			wantPropagated:    true,
+		},
+		{
+			name:          "new password not propagated yet: use the current one",
+			internalUsers: user.InternalUsers{ControllerUser: controllerUser, PendingControllerUser: &pendingUser},
+			internalData: map[string][]byte{
+				user.InternalControllerUserName:                                []byte("current"),
+				commonuser.PendingPasswordKey(user.InternalControllerUserName): []byte("pending"),
+			},
+			newClient:         newCredentialsCheckESClient("https://sset-0.sset.ns:9200"),
+			wantUser:          controllerUser,
+			wantRequeue:       true,
+			wantSecretEntries: 2,
+			wantPropagated:    false,
+		},
+		{
+			name:          "new password propagated: complete the rotation",
+			internalUsers: user.InternalUsers{ControllerUser: controllerUser, PendingControllerUser: &pendingUser},
+			internalData: map[string][]byte{
+				user.InternalControllerUserName:                                []byte("current"),
+				commonuser.PendingPasswordKey(user.InternalControllerUserName): []byte("pending"),
+			},
+			newClient:         newCredentialsCheckESClient(),
+			wantUser:          pendingUser,
+			wantSecretEntries: 1,
+			wantPropagated:    true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			k8sClient := k8s.WrapClient(fake.NewFakeClient(
+				es.DeepCopy(),
+				usersSecret(user.ElasticInternalUsersSecretName(es.Name), tt.internalData),
+				usersSecret(user.ElasticExternalUsersSecretName(es.Name), map[string][]byte{user.ExternalUserName: []byte("elastic")}),
+			))
+			d := &defaultDriver{DefaultDriverParameters{
+				ES:             es,
+				Client:         k8sClient,
+				ReconcileState: reconcile.NewState(es),
+				Expectations:   expectations.NewExpectations(),
+			}}
+
+			u, results := d.reconcilePasswordRotation(tt.internalUsers, pods, func(url string, _ user.User) esclient.Client {
+				return tt.newClient(url)
+			})
+			require.False(t, results.HasError())
+			require.Equal(t, tt.wantUser, u)
+			res, _ := results.Aggregate()
+			require.Equal(t, tt.wantRequeue, res.Requeue || res.RequeueAfter > 0)
+
+			var secret corev1.Secret
+			require.NoError(t, k8sClient.Get(types.NamespacedName{Namespace: "ns", Name: user.ElasticInternalUsersSecretName(es.Name)}, &secret))
+			require.Len(t, secret.Data, tt.wantSecretEntries)
+			var updatedES v1alpha1.Elasticsearch
+			require.NoError(t, k8sClient.Get(types.NamespacedName{Namespace: "ns", Name: es.Name}, &updatedES))
+			require.Equal(t, tt.wantPropagated, commonuser.PasswordRotationPropagated(updatedES))
+		})
+	}
+}
@@ -0,0 +1,158 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package driver
+
+import (
+	"sort"
+
+	"github.com/elastic/cloud-on-k8s/pkg/apis/elasticsearch/v1alpha1"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/hash"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/keystore"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/operator"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/version"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/initcontainer"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/name"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/nodespec"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/sset"
+	"github.com/elastic/cloud-on-k8s/pkg/utils/k8s"
+	appsv1 "k8s.io/api/apps/v1"
+)
+
+// PlanChanges computes the changes the operator would perform on the StatefulSets of the given Elasticsearch resource
+// to reach its specification from the live resources, without applying any change.
+func PlanChanges(
+	c k8s.Client,
+	es v1alpha1.Elasticsearch,
+	heapMemoryPercentage int,
+	restricted operator.RestrictedMode,
+) ([]v1alpha1.StatefulSetChanges, error) {
+	v, err := version.Parse(es.Spec.Version)
+	if err != nil {
+		return nil, err
+	}
+	keystoreResources, err := keystore.ExpectedResources(c, keystoreElasticsearch(es, *v), name.ESNamer, initcontainer.KeystoreParams)
+	if err != nil {
+		return nil, err
+	}
+	expectedResources, err := nodespec.BuildExpectedResources(es, *v, keystoreResources, heapMemoryPercentage, restricted)
+	if err != nil {
+		return nil, err
+	}
+	actualStatefulSets, err := sset.RetrieveActualStatefulSets(c, k8s.ExtractNamespacedName(&es))
+	if err != nil {
+		return nil, err
+	}
+	return planChanges(c, expectedResources.StatefulSets(), actualStatefulSets)
+}
+
+// planChanges compares expected and actual StatefulSets to list the changes to perform on each of them, in the order
+// they are performed by the driver: creations and upscales first, then downscales, then rolling upgrades.
+// StatefulSets are sorted by name, and omitted if there is no change to perform.
+func planChanges(
+	c k8s.Client,
+	expectedStatefulSets sset.StatefulSetList,
+	actualStatefulSets sset.StatefulSetList,
+) ([]v1alpha1.StatefulSetChanges, error) {
+	changes := map[string][]v1alpha1.Change{}
+	addChange := func(ssetName string, changeType v1alpha1.ChangeType, nodes []string) {
+		changes[ssetName] = append(changes[ssetName], v1alpha1.Change{Type: changeType, Nodes: nodes})
+	}
+
+	// Phase 1: creations and upscales
+	for _, expected := range expectedStatefulSets {
+		actual, exists := actualStatefulSets.GetByName(expected.Name)
+		switch {
+		case !exists:
+			addChange(expected.Name, v1alpha1.CreateStatefulSetChange, sset.PodNames(expected))
+		case isReplicaIncrease(actual, expected):
+			addChange(expected.Name, v1alpha1.ScaleUpChange, podNamesInRange(expected.Name, sset.GetReplicas(actual), sset.GetReplicas(expected)))
+		}
+	}
+
+	// Phase 2: downscales and removals
+	for _, downscale := range calculateDownscales(expectedStatefulSets, actualStatefulSets) {
+		_, shouldExist := expectedStatefulSets.GetByName(downscale.statefulSet.Name)
+		switch {
+		case !shouldExist:
+			addChange(downscale.statefulSet.Name, v1alpha1.DeleteStatefulSetChange, downscale.leavingNodeNames())
+		case downscale.isReplicaDecrease():
+			addChange(downscale.statefulSet.Name, v1alpha1.ScaleDownChange, downscale.leavingNodeNames())
+		}
+	}
+
+	// Phase 3: rolling upgrades
+	for _, expected := range expectedStatefulSets {
+		actual, exists := actualStatefulSets.GetByName(expected.Name)
+		if !exists {
+			continue
+		}
+		nodes, err := nodesToRestart(c, actual, expected)
+		if err != nil {
+			return nil, err
+		}
+		if len(nodes) > 0 {
+			addChange(expected.Name, v1alpha1.RestartNodesChange, nodes)
+		}
+	}
+
+	if len(changes) == 0 {
+		return nil, nil
+	}
+	result := make([]v1alpha1.StatefulSetChanges, 0, len(changes))
+	for ssetName, ssetChanges := range changes {
+		result = append(result, v1alpha1.StatefulSetChanges{StatefulSet: ssetName, Changes: ssetChanges})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].StatefulSet < result[j].StatefulSet
+	})
+	return result, nil
+}
+
+// nodesToRestart returns the names of the nodes of the actual StatefulSet that must be restarted, highest ordinal first.
+// If the expected specification differs from the actual one, all the nodes kept after any downscale must be restarted.
+// Otherwise, the nodes not running the update revision of the StatefulSet yet must be.
+func nodesToRestart(c k8s.Client, actual appsv1.StatefulSet, expected appsv1.StatefulSet) ([]string, error) {
+	kept := sset.GetReplicas(actual)
+	if sset.GetReplicas(expected) < kept {
+		kept = sset.GetReplicas(expected)
+	}
+
+	// replicas changes are handled by upscales and downscales, compare the specifications with the same replicas
+	expected = *expected.DeepCopy()
+	nodespec.UpdateReplicas(&expected, actual.Spec.Replicas)
+	if expected.Labels[hash.TemplateHashLabelName] != actual.Labels[hash.TemplateHashLabelName] {
+		names := podNamesInRange(actual.Name, 0, kept)
+		// reverse the order to restart the highest ordinal first
+		for i, j := 0, len(names)-1; i < j; i, j = i+1, j-1 {
+			names[i], names[j] = names[j], names[i]
+		}
+		return names, nil
+	}
+
+	pods, err := podsToUpgrade(c, sset.StatefulSetList{actual})
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, pod := range pods {
+		_, ordinal, err := sset.StatefulSetName(pod.Name)
+		if err != nil {
+			return nil, err
+		}
+		if ordinal < kept {
+			names = append(names, pod.Name)
+		}
+	}
+	return names, nil
+}
+
+// podNamesInRange returns the names of the pods of the given StatefulSet with an ordinal in [from, to).
+func podNamesInRange(ssetName string, from int32, to int32) []string {
+	var names []string
+	for i := from; i < to; i++ {
+		names = append(names, sset.PodName(ssetName, i))
+	}
+	return names
+}
@@ -0,0 +1,147 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package driver
+
+import (
+	"testing"
+
+	"github.com/elastic/cloud-on-k8s/pkg/apis/elasticsearch/v1alpha1"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/sset"
+	"github.com/elastic/cloud-on-k8s/pkg/utils/k8s"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func Test_planChanges(t *testing.T) {
+	ssetV72 := func(name string, replicas int32) appsv1.StatefulSet {
+		return sset.TestSset{Namespace: testNamespace, Name: name, Version: "7.2.0", Replicas: replicas}.Build()
+	}
+	ssetV73 := func(name string, replicas int32) appsv1.StatefulSet {
+		return sset.TestSset{Namespace: testNamespace, Name: name, Version: "7.3.0", Replicas: replicas}.Build()
+	}
+	upgrading := sset.TestSset{
+		Namespace: testNamespace, Name: "a", Version: "7.2.0", Replicas: 3,
+		Status: appsv1.StatefulSetStatus{CurrentRevision: "rev-a", UpdateRevision: "rev-b", UpdatedReplicas: 1, Replicas: 3},
+	}.Build()
+
+	tests := []struct {
+		name     string
+		expected sset.StatefulSetList
+		actual   sset.StatefulSetList
+		pods     []runtime.Object
+		want     []v1alpha1.StatefulSetChanges
+	}{
+		{
+			name:     "no change",
+			expected: sset.StatefulSetList{ssetV72("a", 3)},
+			actual:   sset.StatefulSetList{ssetV72("a", 3)},
+			want:     nil,
+		},
+		{
+			name:     "new StatefulSet",
+			expected: sset.StatefulSetList{ssetV72("a", 3)},
+			actual:   sset.StatefulSetList{},
+			want: []v1alpha1.StatefulSetChanges{
+				{StatefulSet: "a", Changes: []v1alpha1.Change{
+					{Type: v1alpha1.CreateStatefulSetChange, Nodes: []string{"a-0", "a-1", "a-2"}},
+				}},
+			},
+		},
+		{
+			name:     "upscale",
+			expected: sset.StatefulSetList{ssetV72("a", 3)},
+			actual:   sset.StatefulSetList{ssetV72("a", 1)},
+			want: []v1alpha1.StatefulSetChanges{
+				{StatefulSet: "a", Changes: []v1alpha1.Change{
+					{Type: v1alpha1.ScaleUpChange, Nodes: []string{"a-1", "a-2"}},
+				}},
+			},
+		},
+		{
+			name:     "downscale",
+			expected: sset.StatefulSetList{ssetV72("a", 1)},
+			actual:   sset.StatefulSetList{ssetV72("a", 3)},
+			want: []v1alpha1.StatefulSetChanges{
+				{StatefulSet: "a", Changes: []v1alpha1.Change{
+					{Type: v1alpha1.ScaleDownChange, Nodes: []string{"a-2", "a-1"}},
+				}},
+			},
+		},
+		{
+			name:     "StatefulSet removal",
+			expected: sset.StatefulSetList{ssetV72("b", 1)},
+			actual:   sset.StatefulSetList{ssetV72("a", 2), ssetV72("b", 1)},
+			want: []v1alpha1.StatefulSetChanges{
+				{StatefulSet: "a", Changes: []v1alpha1.Change{
+					{Type: v1alpha1.DeleteStatefulSetChange, Nodes: []string{"a-1", "a-0"}},
+				}},
+			},
+		},
+		{
+			name:     "spec change and upscale: restart existing nodes",
+			expected: sset.StatefulSetList{ssetV73("a", 2)},
+			actual:   sset.StatefulSetList{ssetV72("a", 1)},
+			want: []v1alpha1.StatefulSetChanges{
+				{StatefulSet: "a", Changes: []v1alpha1.Change{
+					{Type: v1alpha1.ScaleUpChange, Nodes: []string{"a-1"}},
+					{Type: v1alpha1.RestartNodesChange, Nodes: []string{"a-0"}},
+				}},
+			},
+		},
+		{
+			name:     "spec change and downscale: restart remaining nodes",
+			expected: sset.StatefulSetList{ssetV73("a", 2)},
+			actual:   sset.StatefulSetList{ssetV72("a", 3)},
+			want: []v1alpha1.StatefulSetChanges{
+				{StatefulSet: "a", Changes: []v1alpha1.Change{
+					{Type: v1alpha1.ScaleDownChange, Nodes: []string{"a-2"}},
+					{Type: v1alpha1.RestartNodesChange, Nodes: []string{"a-1", "a-0"}},
+				}},
+			},
+		},
+		{
+			name:     "rolling upgrade in progress: restart nodes not upgraded yet",
+			expected: sset.StatefulSetList{ssetV72("a", 3)},
+			actual:   sset.StatefulSetList{upgrading},
+			pods: []runtime.Object{
+				podWithRevision("a-0", "rev-a"),
+				podWithRevision("a-1", "rev-a"),
+				podWithRevision("a-2", "rev-b"),
+			},
+			want: []v1alpha1.StatefulSetChanges{
+				{StatefulSet: "a", Changes: []v1alpha1.Change{
+					{Type: v1alpha1.RestartNodesChange, Nodes: []string{"a-1", "a-0"}},
+				}},
+			},
+		},
+		{
+			name:     "changes are sorted by StatefulSet",
+			expected: sset.StatefulSetList{ssetV72("c", 1), ssetV72("b", 1)},
+			actual:   sset.StatefulSetList{ssetV72("a", 1), ssetV72("b", 2)},
+			want: []v1alpha1.StatefulSetChanges{
+				{StatefulSet: "a", Changes: []v1alpha1.Change{
+					{Type: v1alpha1.DeleteStatefulSetChange, Nodes: []string{"a-0"}},
+				}},
+				{StatefulSet: "b", Changes: []v1alpha1.Change{
+					{Type: v1alpha1.ScaleDownChange, Nodes: []string{"b-1"}},
+				}},
+				{StatefulSet: "c", Changes: []v1alpha1.Change{
+					{Type: v1alpha1.CreateStatefulSetChange, Nodes: []string{"c-0"}},
+				}},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := k8s.WrapClient(fake.NewFakeClient(tt.pods...))
+			got, err := planChanges(c, tt.expected, tt.actual)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
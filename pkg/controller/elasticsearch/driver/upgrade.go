@@ -6,11 +6,16 @@ package driver
 
 import (
 	"context"
+	"fmt"
+	"time"
 
+	commonv1alpha1 "github.com/elastic/cloud-on-k8s/pkg/apis/common/v1alpha1"
 	"github.com/elastic/cloud-on-k8s/pkg/apis/elasticsearch/v1alpha1"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/common/expectations"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/common/reconciler"
 	esclient "github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/client"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/observer"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/sset"
 	"github.com/elastic/cloud-on-k8s/pkg/utils/k8s"
 	corev1 "k8s.io/api/core/v1"
@@ -23,6 +28,7 @@ func (d *defaultDriver) handleRollingUpgrades(
 	esState ESState,
 	statefulSets sset.StatefulSetList,
 	expectedMaster []string,
+	observedState observer.State,
 ) *reconciler.Results {
 	results := &reconciler.Results{}
 
@@ -49,8 +55,15 @@ func (d *defaultDriver) handleRollingUpgrades(
 		return results.WithError(err)
 	}
 
+	// Maybe restrict the upgrade to some canary nodes first.
+	canaries, canaryResults := d.reconcileCanaryUpgrade(observedState, statefulSets, podsToUpgrade, time.Now())
+	results.WithResults(canaryResults)
+	if canaryResults.HasError() {
+		return results
+	}
+
 	// Maybe upgrade some of the nodes.
-	deletedPods, err := newRollingUpgrade(
+	deletedPods, failed, err := newRollingUpgrade(
 		d,
 		statefulSets,
 		esClient,
@@ -58,10 +71,15 @@ func (d *defaultDriver) handleRollingUpgrades(
 		expectedMaster,
 		podsToUpgrade,
 		healthyPods,
+		canaries,
 	).run()
 	if err != nil {
 		return results.WithError(err)
 	}
+	if canaries == nil {
+		// the canary upgrade reports its own progress
+		d.ReconcileState.ReportCondition(upgradeInProgressCondition(podsToUpgrade, deletedPods, failed))
+	}
 	if len(deletedPods) > 0 {
 		// Some Pods have just been deleted, we don't need to try to enable shards allocation.
 		return results.WithResult(defaultRequeue)
@@ -78,6 +96,29 @@ func (d *defaultDriver) handleRollingUpgrades(
 	return results
 }
 
+const (
+	// NodesRestartingReason is the reason of the UpgradeInProgress condition when nodes are being restarted.
+	NodesRestartingReason = "NodesRestarting"
+	// UpgradeDelayedReason is the reason of the UpgradeInProgress condition when no node can be restarted for now.
+	UpgradeDelayedReason = "UpgradeDelayed"
+)
+
+// upgradeInProgressCondition returns the UpgradeInProgress condition matching the outcome of a rolling upgrade step.
+func upgradeInProgressCondition(podsToUpgrade []corev1.Pod, deletedPods []corev1.Pod, failed failedPredicates) commonv1alpha1.Condition {
+	if len(podsToUpgrade) == 0 {
+		return commonv1alpha1.NewCondition(commonv1alpha1.UpgradeInProgressCondition, false, common.UpgradeCompletedReason, "")
+	}
+	message := fmt.Sprintf("%d nodes to restart", len(podsToUpgrade))
+	if len(failed) > 0 {
+		message = fmt.Sprintf("%s, delayed by predicates: %s", message, failed.message())
+	}
+	reason := NodesRestartingReason
+	if len(deletedPods) == 0 {
+		reason = UpgradeDelayedReason
+	}
+	return commonv1alpha1.NewCondition(commonv1alpha1.UpgradeInProgressCondition, true, reason, message)
+}
+
 type rollingUpgradeCtx struct {
 	client          k8s.Client
 	ES              v1alpha1.Elasticsearch
@@ -88,6 +129,8 @@ type rollingUpgradeCtx struct {
 	expectedMasters []string
 	podsToUpgrade   []corev1.Pod
 	healthyPods     map[string]corev1.Pod
+	// canaries, if not nil, restricts the Pods that can be deleted to the given canary nodes.
+	canaries []string
 }
 
 func newRollingUpgrade(
@@ -98,6 +141,7 @@ func newRollingUpgrade(
 	expectedMaster []string,
 	podsToUpgrade []corev1.Pod,
 	healthyPods map[string]corev1.Pod,
+	canaries []string,
 ) rollingUpgradeCtx {
 	return rollingUpgradeCtx{
 		client:          d.Client,
@@ -109,21 +153,22 @@ func newRollingUpgrade(
 		expectedMasters: expectedMaster,
 		podsToUpgrade:   podsToUpgrade,
 		healthyPods:     healthyPods,
+		canaries:        canaries,
 	}
 }
 
-func (ctx rollingUpgradeCtx) run() ([]corev1.Pod, error) {
-	deletedPods, err := ctx.Delete()
+func (ctx rollingUpgradeCtx) run() ([]corev1.Pod, failedPredicates, error) {
+	deletedPods, failed, err := ctx.Delete()
 	if errors.IsConflict(err) || errors.IsNotFound(err) {
 		// Cache is not up to date or Pod has been deleted by someone else
 		// (could be the statefulset controller)
 		// TODO: should we at least log this one in debug mode ?
-		return deletedPods, nil
+		return deletedPods, failed, nil
 	}
 	if err != nil {
-		return deletedPods, err
+		return deletedPods, failed, err
 	}
-	return deletedPods, nil
+	return deletedPods, failed, nil
 }
 
 func healthyPods(
@@ -282,21 +327,21 @@ func (d *defaultDriver) MaybeEnableShardsAllocation(
 	return results
 }
 
-func (ctx *rollingUpgradeCtx) prepareClusterForNodeRestart(esClient esclient.Client, esState ESState) error {
+func prepareClusterForNodeRestart(es v1alpha1.Elasticsearch, esClient esclient.Client, esState ESState) error {
 	// Disable shard allocations to avoid shards moving around while the node is temporarily down
 	shardsAllocationEnabled, err := esState.ShardAllocationsEnabled()
 	if err != nil {
 		return err
 	}
 	if shardsAllocationEnabled {
-		log.Info("Disabling shards allocation", "es_name", ctx.ES.Name, "namespace", ctx.ES.Namespace)
+		log.Info("Disabling shards allocation", "es_name", es.Name, "namespace", es.Namespace)
 		if err := disableShardsAllocation(esClient); err != nil {
 			return err
 		}
 	}
 
 	// Request a sync flush to optimize indices recovery when the node restarts.
-	if err := doSyncFlush(ctx.ES, esClient); err != nil {
+	if err := doSyncFlush(es, esClient); err != nil {
 		return err
 	}
 
@@ -9,24 +9,33 @@ import (
 
 	"github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/label"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/sset"
+	"github.com/elastic/cloud-on-k8s/pkg/utils/k8s"
+	"github.com/elastic/cloud-on-k8s/pkg/utils/stringsutil"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
 // Delete runs through a list of potential candidates and select the ones that can be deleted.
+// It also returns the predicates preventing the other candidates from being deleted.
 // Do not run this function unless driver expectations are met.
-func (ctx *rollingUpgradeCtx) Delete() ([]corev1.Pod, error) {
+func (ctx *rollingUpgradeCtx) Delete() ([]corev1.Pod, failedPredicates, error) {
 	if len(ctx.podsToUpgrade) == 0 {
-		return nil, nil
+		return nil, nil, nil
 	}
 
 	// Get allowed deletions and check if maxUnavailable has been reached.
 	allowedDeletions, maxUnavailableReached := ctx.getAllowedDeletions()
 
 	// Step 1. Sort the Pods to get the ones with the higher priority
-	candidates := make([]corev1.Pod, len(ctx.podsToUpgrade)) // work on a copy in order to have no side effect
-	copy(candidates, ctx.podsToUpgrade)
+	candidates := make([]corev1.Pod, 0, len(ctx.podsToUpgrade)) // work on a copy in order to have no side effect
+	for _, pod := range ctx.podsToUpgrade {
+		if ctx.canaries != nil && !stringsutil.StringInSlice(pod.Name, ctx.canaries) {
+			// only canary nodes can be restarted for now
+			continue
+		}
+		candidates = append(candidates, pod)
+	}
 	sortCandidates(candidates)
 
 	// Step 2: Apply predicates
@@ -40,9 +49,9 @@ func (ctx *rollingUpgradeCtx) Delete() ([]corev1.Pod, error) {
 		"maxUnavailableReached", maxUnavailableReached,
 		"allowedDeletions", allowedDeletions,
 	)
-	podsToDelete, err := applyPredicates(predicateContext, candidates, maxUnavailableReached, allowedDeletions)
+	podsToDelete, failed, err := applyPredicates(predicateContext, candidates, maxUnavailableReached, allowedDeletions)
 	if err != nil {
-		return podsToDelete, err
+		return podsToDelete, failed, err
 	}
 
 	if len(podsToDelete) == 0 {
@@ -51,12 +60,12 @@ func (ctx *rollingUpgradeCtx) Delete() ([]corev1.Pod, error) {
 			"es_name", ctx.ES.Name,
 			"namespace", ctx.ES.Namespace,
 		)
-		return podsToDelete, nil
+		return podsToDelete, failed, nil
 	}
 
 	// Disable shard allocation
-	if err := ctx.prepareClusterForNodeRestart(ctx.esClient, ctx.esState); err != nil {
-		return podsToDelete, err
+	if err := prepareClusterForNodeRestart(ctx.ES, ctx.esClient, ctx.esState); err != nil {
+		return podsToDelete, failed, err
 	}
 	// TODO: If master is changed into a data node (or the opposite) it must be excluded or we should update m_m_n
 	deletedPods := []corev1.Pod{}
@@ -65,11 +74,11 @@ func (ctx *rollingUpgradeCtx) Delete() ([]corev1.Pod, error) {
 		err := ctx.delete(&podToDelete)
 		if err != nil {
 			ctx.expectations.CancelExpectedDeletion(podToDelete)
-			return deletedPods, err
+			return deletedPods, failed, err
 		}
 		deletedPods = append(deletedPods, podToDelete)
 	}
-	return deletedPods, nil
+	return deletedPods, failed, nil
 }
 
 // getAllowedDeletions returns the number of deletions that can be done and if maxUnavailable has been reached.
@@ -121,9 +130,14 @@ func sortCandidates(allPods []corev1.Pod) {
 }
 
 func (ctx *rollingUpgradeCtx) delete(pod *corev1.Pod) error {
-	uid := pod.UID
 	log.Info("Deleting pod for rolling upgrade", "es_name", ctx.ES.Name, "namespace", ctx.ES.Namespace, "pod_name", pod.Name, "pod_uid", pod.UID)
-	return ctx.client.Delete(pod, func(options *client.DeleteOptions) {
+	return deletePodWithUIDPrecondition(ctx.client, pod)
+}
+
+// deletePodWithUIDPrecondition deletes the given Pod, only if it is still the same Pod.
+func deletePodWithUIDPrecondition(c k8s.Client, pod *corev1.Pod) error {
+	uid := pod.UID
+	return c.Delete(pod, func(options *client.DeleteOptions) {
 		if options.Preconditions == nil {
 			options.Preconditions = &metav1.Preconditions{}
 		}
@@ -134,23 +148,24 @@ func (ctx *rollingUpgradeCtx) delete(pod *corev1.Pod) error {
 	})
 }
 
+// runPredicates returns the first predicate preventing the candidate from being deleted, or nil if all predicates pass.
 func runPredicates(
 	ctx PredicateContext,
 	candidate corev1.Pod,
 	deletedPods []corev1.Pod,
 	maxUnavailableReached bool,
-) (bool, error) {
+) (*failedPredicate, error) {
 	for _, predicate := range predicates {
 		canDelete, err := predicate.fn(ctx, candidate, deletedPods, maxUnavailableReached)
 		if err != nil {
-			return false, err
+			return nil, err
 		}
 		if !canDelete {
 			log.V(1).Info("Predicate failed", "pod_name", candidate.Name, "predicate_name", predicate.name)
 			// Skip this Pod, it can't be deleted for the moment
-			return false, nil
+			return &failedPredicate{pod: candidate.Name, predicate: predicate.name}, nil
 		}
 	}
 	// All predicates passed!
-	return true, nil
+	return nil, nil
 }
@@ -5,6 +5,9 @@
 package driver
 
 import (
+	"fmt"
+	"strings"
+
 	"github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/client"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/label"
 	corev1 "k8s.io/api/core/v1"
@@ -37,23 +40,49 @@ func NewPredicateContext(
 	}
 }
 
-func applyPredicates(ctx PredicateContext, candidates []corev1.Pod, maxUnavailableReached bool, allowedDeletions int) (deletedPods []corev1.Pod, err error) {
+// failedPredicate records the predicate preventing a Pod from being deleted.
+type failedPredicate struct {
+	pod       string
+	predicate string
+}
+
+type failedPredicates []failedPredicate
+
+// message returns a human readable description of the failed predicates.
+func (f failedPredicates) message() string {
+	descriptions := make([]string, len(f))
+	for i, failed := range f {
+		descriptions[i] = fmt.Sprintf("%s (%s)", failed.pod, failed.predicate)
+	}
+	return strings.Join(descriptions, ", ")
+}
+
+func applyPredicates(
+	ctx PredicateContext,
+	candidates []corev1.Pod,
+	maxUnavailableReached bool,
+	allowedDeletions int,
+) (deletedPods []corev1.Pod, failed failedPredicates, err error) {
 	for _, candidate := range candidates {
-		if ok, err := runPredicates(ctx, candidate, deletedPods, maxUnavailableReached); err != nil {
-			return deletedPods, err
-		} else if ok {
-			candidate := candidate
-			// Remove from healthy nodes if it was there
-			delete(ctx.healthyPods, candidate.Name)
-			// Append to the deletedPods list
-			deletedPods = append(deletedPods, candidate)
-			allowedDeletions--
-			if allowedDeletions <= 0 {
-				break
-			}
+		failedPredicate, err := runPredicates(ctx, candidate, deletedPods, maxUnavailableReached)
+		if err != nil {
+			return deletedPods, failed, err
+		}
+		if failedPredicate != nil {
+			failed = append(failed, *failedPredicate)
+			continue
+		}
+		candidate := candidate
+		// Remove from healthy nodes if it was there
+		delete(ctx.healthyPods, candidate.Name)
+		// Append to the deletedPods list
+		deletedPods = append(deletedPods, candidate)
+		allowedDeletions--
+		if allowedDeletions <= 0 {
+			break
 		}
 	}
-	return deletedPods, nil
+	return deletedPods, failed, nil
 }
 
 var predicates = [...]Predicate{
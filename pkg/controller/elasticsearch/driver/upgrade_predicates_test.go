@@ -21,6 +21,7 @@ func TestUpgradePodsDeletion_Delete(t *testing.T) {
 		green           bool
 		maxUnavailable  int
 		podFilter       filter
+		canaries        []string
 	}
 	tests := []struct {
 		name                         string
@@ -46,6 +47,24 @@ func TestUpgradePodsDeletion_Delete(t *testing.T) {
 			wantErr:                      false,
 			wantShardsAllocationDisabled: true,
 		},
+		{
+			name: "Only delete canary nodes",
+			fields: fields{
+				upgradeTestPods: newUpgradeTestPods(
+					newTestPod("master-0").isMaster(true).isData(false).isHealthy(true).needsUpgrade(true).isInCluster(true),
+					newTestPod("node-0").isMaster(false).isData(true).isHealthy(true).needsUpgrade(true).isInCluster(true),
+					newTestPod("node-1").isMaster(false).isData(true).isHealthy(true).needsUpgrade(true).isInCluster(true),
+					newTestPod("node-2").isMaster(false).isData(true).isHealthy(true).needsUpgrade(true).isInCluster(true),
+				),
+				maxUnavailable: 2,
+				green:          true,
+				podFilter:      nothing,
+				canaries:       []string{"node-0"},
+			},
+			deleted:                      []string{"node-0"},
+			wantErr:                      false,
+			wantShardsAllocationDisabled: true,
+		},
 		{
 			name: "All Pods are upgraded",
 			fields: fields{
@@ -249,9 +268,10 @@ func TestUpgradePodsDeletion_Delete(t *testing.T) {
 			expectedMasters: tt.fields.upgradeTestPods.toMasters(),
 			podsToUpgrade:   tt.fields.upgradeTestPods.toUpgrade(),
 			healthyPods:     tt.fields.upgradeTestPods.toHealthyPods(),
+			canaries:        tt.fields.canaries,
 		}
 
-		deleted, err := ctx.Delete()
+		deleted, _, err := ctx.Delete()
 		if (err != nil) != tt.wantErr {
 			t.Errorf("runPredicates error = %v, wantErr %v", err, tt.wantErr)
 			return
@@ -8,6 +8,7 @@ import (
 	"reflect"
 	"testing"
 
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/sset"
 	"github.com/elastic/cloud-on-k8s/pkg/utils/k8s"
 	"github.com/stretchr/testify/assert"
@@ -173,3 +174,53 @@ func Test_healthyPods(t *testing.T) {
 		})
 	}
 }
+
+func Test_upgradeInProgressCondition(t *testing.T) {
+	pod := func(name string) corev1.Pod {
+		return corev1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: name}}
+	}
+	tests := []struct {
+		name          string
+		podsToUpgrade []corev1.Pod
+		deletedPods   []corev1.Pod
+		failed        failedPredicates
+		wantStatus    corev1.ConditionStatus
+		wantReason    string
+		wantMessage   string
+	}{
+		{
+			name:       "no pod to upgrade",
+			wantStatus: corev1.ConditionFalse,
+			wantReason: common.UpgradeCompletedReason,
+		},
+		{
+			name:          "pods restarting",
+			podsToUpgrade: []corev1.Pod{pod("a-0"), pod("a-1")},
+			deletedPods:   []corev1.Pod{pod("a-1")},
+			failed:        failedPredicates{{pod: "a-0", predicate: "one_master_at_a_time"}},
+			wantStatus:    corev1.ConditionTrue,
+			wantReason:    NodesRestartingReason,
+			wantMessage:   "2 nodes to restart, delayed by predicates: a-0 (one_master_at_a_time)",
+		},
+		{
+			name:          "upgrade delayed by predicates",
+			podsToUpgrade: []corev1.Pod{pod("a-0"), pod("a-1")},
+			failed: failedPredicates{
+				{pod: "a-1", predicate: "do_not_restart_healthy_node_if_not_green"},
+				{pod: "a-0", predicate: "do_not_restart_healthy_node_if_not_green"},
+			},
+			wantStatus: corev1.ConditionTrue,
+			wantReason: UpgradeDelayedReason,
+			wantMessage: "2 nodes to restart, delayed by predicates: a-1 (do_not_restart_healthy_node_if_not_green), " +
+				"a-0 (do_not_restart_healthy_node_if_not_green)",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := upgradeInProgressCondition(tt.podsToUpgrade, tt.deletedPods, tt.failed)
+			assert.Equal(t, tt.wantStatus, got.Status)
+			assert.Equal(t, tt.wantReason, got.Reason)
+			assert.Equal(t, tt.wantMessage, got.Message)
+		})
+	}
+}
@@ -0,0 +1,99 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package driver
+
+import (
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+
+	"github.com/elastic/cloud-on-k8s/pkg/apis/elasticsearch/v1alpha1"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/events"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/finalizer"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/migration"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/sset"
+	"github.com/elastic/cloud-on-k8s/pkg/utils/k8s"
+	"github.com/elastic/cloud-on-k8s/pkg/utils/set"
+)
+
+// VolumeClaimsFinalizerName is the name of the finalizer deleting the PersistentVolumeClaims of a deleted cluster.
+const VolumeClaimsFinalizerName = "finalizer.elasticsearch.k8s.elastic.co/volume-claims"
+
+// deleteScaledDownVolumeClaims deletes the PersistentVolumeClaims of the nodes removed by a downscale,
+// including all the claims of the StatefulSets with no replica left, if allowed by the VolumeClaimDeletePolicy.
+// The claims of a node are kept as long as its data may not have been migrated to other nodes.
+// It returns the names of the StatefulSets whose claims could not be deleted yet: they must not be removed before,
+// since their claims could not be retrieved anymore.
+func deleteScaledDownVolumeClaims(ctx downscaleContext, actualStatefulSets sset.StatefulSetList) (set.StringSet, error) {
+	pending := set.StringSet{}
+	if !ctx.es.Spec.VolumeClaimDeletePolicy.DeleteOnScaledown() {
+		return pending, nil
+	}
+	for _, statefulSet := range actualStatefulSets {
+		claims, err := sset.GetActualVolumeClaimsForStatefulSet(ctx.k8sClient, statefulSet)
+		if err != nil {
+			return nil, err
+		}
+		for _, claim := range claims {
+			ordinal, _ := sset.VolumeClaimOrdinal(statefulSet, claim.Name)
+			if ordinal < sset.GetReplicas(statefulSet) {
+				// the node is still expected in the cluster
+				continue
+			}
+			podName := sset.PodName(statefulSet.Name, ordinal)
+			if migration.IsMigratingData(ctx.observedState, podName, nil) {
+				ssetLogger(statefulSet).V(1).Info(
+					"Data migration not over yet, skipping volume claim deletion", "node", podName, "claim", claim.Name,
+				)
+				pending.Add(statefulSet.Name)
+				continue
+			}
+			ssetLogger(statefulSet).Info("Deleting volume claim of removed node", "node", podName, "claim", claim.Name)
+			claim := claim
+			if err := ctx.k8sClient.Delete(&claim); err != nil && !apierrors.IsNotFound(err) {
+				return nil, err
+			}
+			ctx.reconcileState.AddEvent(
+				corev1.EventTypeNormal,
+				events.EventReasonDeleted,
+				fmt.Sprintf("Deleted PersistentVolumeClaim %s of removed node %s", claim.Name, podName),
+			)
+		}
+	}
+	return pending, nil
+}
+
+// VolumeClaimsFinalizer returns a Finalizer deleting all the PersistentVolumeClaims of the cluster when it is deleted,
+// if allowed by the VolumeClaimDeletePolicy.
+// The finalizer is registered whatever the policy, so that a policy change before the deletion is taken into account.
+func VolumeClaimsFinalizer(c k8s.Client, es v1alpha1.Elasticsearch) finalizer.Finalizer {
+	return finalizer.Finalizer{
+		Name: VolumeClaimsFinalizerName,
+		Execute: func() error {
+			if !es.Spec.VolumeClaimDeletePolicy.DeleteOnClusterDeletion() {
+				return nil
+			}
+			statefulSets, err := sset.RetrieveActualStatefulSets(c, k8s.ExtractNamespacedName(&es))
+			if err != nil {
+				return err
+			}
+			for _, statefulSet := range statefulSets {
+				claims, err := sset.GetActualVolumeClaimsForStatefulSet(c, statefulSet)
+				if err != nil {
+					return err
+				}
+				for _, claim := range claims {
+					ssetLogger(statefulSet).Info("Deleting volume claim of deleted cluster", "claim", claim.Name)
+					claim := claim
+					if err := c.Delete(&claim); err != nil && !apierrors.IsNotFound(err) {
+						return err
+					}
+				}
+			}
+			return nil
+		},
+	}
+}
@@ -0,0 +1,214 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package driver
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/elastic/cloud-on-k8s/pkg/apis/elasticsearch/v1alpha1"
+	esclient "github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/client"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/label"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/observer"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/reconcile"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/sset"
+	"github.com/elastic/cloud-on-k8s/pkg/utils/k8s"
+)
+
+// volumeClaimsSset has 1 replica, and volume claims for 3 pods.
+var volumeClaimsSset = sset.TestSset{Namespace: "ns", Name: "sset", ClusterName: "cluster", Version: "7.2.0", Replicas: 1, Data: true}
+
+func volumeClaimsObjects() []runtime.Object {
+	claim := func(name string) runtime.Object {
+		return &corev1.PersistentVolumeClaim{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: name}}
+	}
+	return []runtime.Object{
+		claim("elasticsearch-data-sset-0"),
+		claim("elasticsearch-data-sset-1"),
+		claim("elasticsearch-data-sset-2"),
+		// claim of another StatefulSet
+		claim("elasticsearch-data-other-sset-1"),
+	}
+}
+
+func volumeClaimNames(t *testing.T, c k8s.Client) []string {
+	var claims corev1.PersistentVolumeClaimList
+	require.NoError(t, c.List(&client.ListOptions{}, &claims))
+	names := make([]string, 0, len(claims.Items))
+	for _, claim := range claims.Items {
+		names = append(names, claim.Name)
+	}
+	return names
+}
+
+func Test_deleteScaledDownVolumeClaims(t *testing.T) {
+	statefulSet := volumeClaimsSset.Build()
+	statefulSet.Spec.VolumeClaimTemplates = []corev1.PersistentVolumeClaim{
+		{ObjectMeta: metav1.ObjectMeta{Name: "elasticsearch-data"}},
+	}
+	removedStatefulSet := statefulSet
+	removedStatefulSet.Spec.Replicas = new(int32)
+
+	clusterState := &esclient.ClusterState{
+		ClusterName: "cluster",
+		RoutingTable: esclient.RoutingTable{Indices: map[string]esclient.Shards{
+			"index-1": {Shards: map[string][]esclient.Shard{"0": {
+				{Index: "index-1", Shard: 0, State: esclient.STARTED, Node: "sset-0"},
+			}}},
+		}},
+	}
+	migratingClusterState := &esclient.ClusterState{
+		ClusterName: "cluster",
+		RoutingTable: esclient.RoutingTable{Indices: map[string]esclient.Shards{
+			"index-1": {Shards: map[string][]esclient.Shard{"0": {
+				{Index: "index-1", Shard: 0, State: esclient.STARTED, Node: "sset-2"},
+			}}},
+		}},
+	}
+
+	tests := []struct {
+		name         string
+		policy       v1alpha1.VolumeClaimDeletePolicy
+		clusterState *esclient.ClusterState
+		wantPending  []string
+		wantClaims   []string
+	}{
+		{
+			name:         "default policy: retain all claims",
+			policy:       "",
+			clusterState: clusterState,
+			wantClaims: []string{
+				"elasticsearch-data-sset-0", "elasticsearch-data-sset-1", "elasticsearch-data-sset-2", "elasticsearch-data-other-sset-1",
+			},
+		},
+		{
+			name:         "Retain: retain all claims",
+			policy:       v1alpha1.RetainPolicy,
+			clusterState: clusterState,
+			wantClaims: []string{
+				"elasticsearch-data-sset-0", "elasticsearch-data-sset-1", "elasticsearch-data-sset-2", "elasticsearch-data-other-sset-1",
+			},
+		},
+		{
+			name:         "DeleteOnScaledownOnly: delete the claims of the removed nodes",
+			policy:       v1alpha1.DeleteOnScaledownOnlyPolicy,
+			clusterState: clusterState,
+			wantClaims:   []string{"elasticsearch-data-sset-0", "elasticsearch-data-other-sset-1"},
+		},
+		{
+			name:         "DeleteOnScaledownAndClusterDeletion: delete the claims of the removed nodes",
+			policy:       v1alpha1.DeleteOnScaledownAndClusterDeletionPolicy,
+			clusterState: clusterState,
+			wantClaims:   []string{"elasticsearch-data-sset-0", "elasticsearch-data-other-sset-1"},
+		},
+		{
+			name:         "data migration not over: keep the claims of the migrating node",
+			policy:       v1alpha1.DeleteOnScaledownOnlyPolicy,
+			clusterState: migratingClusterState,
+			wantPending:  []string{"sset"},
+			wantClaims:   []string{"elasticsearch-data-sset-0", "elasticsearch-data-sset-2", "elasticsearch-data-other-sset-1"},
+		},
+		{
+			name:         "unknown cluster state: keep all claims",
+			policy:       v1alpha1.DeleteOnScaledownOnlyPolicy,
+			clusterState: nil,
+			wantPending:  []string{"sset"},
+			wantClaims: []string{
+				"elasticsearch-data-sset-0", "elasticsearch-data-sset-1", "elasticsearch-data-sset-2", "elasticsearch-data-other-sset-1",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			k8sClient := k8s.WrapClient(fake.NewFakeClient(volumeClaimsObjects()...))
+			es := v1alpha1.Elasticsearch{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "cluster"},
+				Spec:       v1alpha1.ElasticsearchSpec{VolumeClaimDeletePolicy: tt.policy},
+			}
+			ctx := downscaleContext{
+				k8sClient:      k8sClient,
+				es:             es,
+				observedState:  observer.State{ClusterState: tt.clusterState},
+				reconcileState: reconcile.NewState(es),
+			}
+			pending, err := deleteScaledDownVolumeClaims(ctx, sset.StatefulSetList{statefulSet})
+			require.NoError(t, err)
+			require.ElementsMatch(t, tt.wantPending, pending.AsSlice())
+			require.ElementsMatch(t, tt.wantClaims, volumeClaimNames(t, k8sClient))
+		})
+	}
+
+	t.Run("StatefulSet removal: delete all its claims", func(t *testing.T) {
+		k8sClient := k8s.WrapClient(fake.NewFakeClient(volumeClaimsObjects()...))
+		es := v1alpha1.Elasticsearch{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "cluster"},
+			Spec:       v1alpha1.ElasticsearchSpec{VolumeClaimDeletePolicy: v1alpha1.DeleteOnScaledownOnlyPolicy},
+		}
+		ctx := downscaleContext{
+			k8sClient:      k8sClient,
+			es:             es,
+			observedState:  observer.State{ClusterState: &esclient.ClusterState{ClusterName: "cluster"}},
+			reconcileState: reconcile.NewState(es),
+		}
+		pending, err := deleteScaledDownVolumeClaims(ctx, sset.StatefulSetList{removedStatefulSet})
+		require.NoError(t, err)
+		require.Zero(t, pending.Count())
+		require.ElementsMatch(t, []string{"elasticsearch-data-other-sset-1"}, volumeClaimNames(t, k8sClient))
+		require.Len(t, ctx.reconcileState.Events(), 3)
+	})
+}
+
+func TestVolumeClaimsFinalizer(t *testing.T) {
+	statefulSet := volumeClaimsSset.Build()
+	statefulSet.Labels = map[string]string{label.ClusterNameLabelName: "cluster"}
+	statefulSet.Spec.VolumeClaimTemplates = []corev1.PersistentVolumeClaim{
+		{ObjectMeta: metav1.ObjectMeta{Name: "elasticsearch-data"}},
+	}
+
+	tests := []struct {
+		name       string
+		policy     v1alpha1.VolumeClaimDeletePolicy
+		wantClaims []string
+	}{
+		{
+			name:   "default policy: retain all claims",
+			policy: "",
+			wantClaims: []string{
+				"elasticsearch-data-sset-0", "elasticsearch-data-sset-1", "elasticsearch-data-sset-2", "elasticsearch-data-other-sset-1",
+			},
+		},
+		{
+			name:   "DeleteOnScaledownOnly: retain all claims",
+			policy: v1alpha1.DeleteOnScaledownOnlyPolicy,
+			wantClaims: []string{
+				"elasticsearch-data-sset-0", "elasticsearch-data-sset-1", "elasticsearch-data-sset-2", "elasticsearch-data-other-sset-1",
+			},
+		},
+		{
+			name:       "DeleteOnScaledownAndClusterDeletion: delete all the claims of the cluster",
+			policy:     v1alpha1.DeleteOnScaledownAndClusterDeletionPolicy,
+			wantClaims: []string{"elasticsearch-data-other-sset-1"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			k8sClient := k8s.WrapClient(fake.NewFakeClient(append(volumeClaimsObjects(), &statefulSet)...))
+			es := v1alpha1.Elasticsearch{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "cluster"},
+				Spec:       v1alpha1.ElasticsearchSpec{VolumeClaimDeletePolicy: tt.policy},
+			}
+			finalizer := VolumeClaimsFinalizer(k8sClient, es)
+			require.Equal(t, VolumeClaimsFinalizerName, finalizer.Name)
+			require.NoError(t, finalizer.Execute())
+			require.ElementsMatch(t, tt.wantClaims, volumeClaimNames(t, k8sClient))
+		})
+	}
+}
@@ -8,28 +8,37 @@ import (
 	"fmt"
 	"sync/atomic"
 
+	apmv1alpha1 "github.com/elastic/cloud-on-k8s/pkg/apis/apm/v1alpha1"
 	elasticsearchv1alpha1 "github.com/elastic/cloud-on-k8s/pkg/apis/elasticsearch/v1alpha1"
+	kbv1alpha1 "github.com/elastic/cloud-on-k8s/pkg/apis/kibana/v1alpha1"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/common"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/common/annotation"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/common/certificates/http"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/common/events"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/common/expectations"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/common/finalizer"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/ingress"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/common/keystore"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/license"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/networkpolicy"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/common/operator"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/common/reconciler"
 	commonversion "github.com/elastic/cloud-on-k8s/pkg/controller/common/version"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/common/watches"
+	escerts "github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/certificates"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/driver"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/label"
 	esname "github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/name"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/observer"
 	esreconcile "github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/reconcile"
+	esuser "github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/user"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/validation"
 	esversion "github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/version"
 	"github.com/elastic/cloud-on-k8s/pkg/utils/k8s"
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
+	extensionsv1beta1 "k8s.io/api/extensions/v1beta1"
+	networkingv1 "k8s.io/api/networking/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
@@ -55,7 +64,10 @@ func Add(mgr manager.Manager, params operator.Parameters) error {
 	if err != nil {
 		return err
 	}
-	return addWatches(c, reconciler)
+	if err := addWatches(c, reconciler); err != nil {
+		return err
+	}
+	return ingress.WatchRoutes(c, mgr.GetRESTMapper(), &elasticsearchv1alpha1.Elasticsearch{})
 }
 
 // newReconciler returns a new reconcile.Reconciler
@@ -71,6 +83,7 @@ func newReconciler(mgr manager.Manager, params operator.Parameters) *ReconcileEl
 		finalizers:     finalizer.NewHandler(client),
 		dynamicWatches: watches.NewDynamicWatches(),
 		expectations:   expectations.NewExpectations(),
+		licenseChecker: license.NewLicenseChecker(client, params.OperatorNamespace),
 
 		Parameters: params,
 	}
@@ -131,6 +144,47 @@ func addWatches(c controller.Controller, r *ReconcileElasticsearch) error {
 		return err
 	}
 
+	// Watch ingresses
+	if err := c.Watch(&source.Kind{Type: &extensionsv1beta1.Ingress{}}, &handler.EnqueueRequestForOwner{
+		IsController: true,
+		OwnerType:    &elasticsearchv1alpha1.Elasticsearch{},
+	}); err != nil {
+		return err
+	}
+
+	// Watch network policies
+	if err := c.Watch(&source.Kind{Type: &networkingv1.NetworkPolicy{}}, &handler.EnqueueRequestForOwner{
+		IsController: true,
+		OwnerType:    &elasticsearchv1alpha1.Elasticsearch{},
+	}); err != nil {
+		return err
+	}
+
+	// Watch Kibana and APM Server resources, to update the network policy of the clusters they are associated to, or
+	// dissociated from
+	if err := c.Watch(&source.Kind{Type: &kbv1alpha1.Kibana{}}, &networkpolicy.ReferenceHandler{
+		References: func(object runtime.Object) []reconcile.Request {
+			kb, ok := object.(*kbv1alpha1.Kibana)
+			if !ok {
+				return nil
+			}
+			return networkpolicy.ReferencedRequests(kb.Spec.ElasticsearchRef, kb.Namespace)
+		},
+	}); err != nil {
+		return err
+	}
+	if err := c.Watch(&source.Kind{Type: &apmv1alpha1.ApmServer{}}, &networkpolicy.ReferenceHandler{
+		References: func(object runtime.Object) []reconcile.Request {
+			as, ok := object.(*apmv1alpha1.ApmServer)
+			if !ok {
+				return nil
+			}
+			return networkpolicy.ReferencedRequests(as.Spec.ElasticsearchRef, as.Namespace)
+		},
+	}); err != nil {
+		return err
+	}
+
 	// Watch secrets
 	if err := c.Watch(&source.Kind{Type: &corev1.Secret{}}, r.dynamicWatches.Secrets); err != nil {
 		return err
@@ -171,6 +225,9 @@ type ReconcileElasticsearch struct {
 	// by marking resources updates as expected, and skipping some operations if the cache is not up-to-date.
 	expectations *expectations.Expectations
 
+	// licenseChecker checks whether the features requiring an enterprise license can be used
+	licenseChecker license.Checker
+
 	// iteration is the number of times this controller has run its Reconcile method
 	iteration uint64
 }
@@ -216,6 +273,8 @@ func (r *ReconcileElasticsearch) Reconcile(request reconcile.Request) (reconcile
 
 	state := esreconcile.NewState(es)
 	results := r.internalReconcile(es, state)
+	state.UpdateReconciling(results.ReconcilingCondition())
+	state.UpdateReady()
 	err = r.updateStatus(es, state)
 	if err != nil {
 		if apierrors.IsConflict(err) {
@@ -252,11 +311,25 @@ func (r *ReconcileElasticsearch) internalReconcile(
 		return results
 	}
 
+	licenseViolations, err := validation.ValidateLicense(es, r.licenseChecker)
+	if err != nil {
+		return results.WithError(err)
+	}
+	if len(licenseViolations) > 0 {
+		reconcileState.UpdateElasticsearchInvalid(licenseViolations)
+		return results
+	}
+	reconcileState.UpdateElasticsearchValid()
+
 	ver, err := commonversion.Parse(es.Spec.Version)
 	if err != nil {
 		return results.WithError(err)
 	}
 	supported := esversion.SupportedVersions(*ver)
+	if es.Spec.UpdateStrategy.FullClusterRestart() {
+		// nodes of different versions never run together
+		supported = esversion.FullClusterRestartSupportedVersions(*ver)
+	}
 	if supported == nil {
 		return results.WithError(fmt.Errorf("unsupported version: %s", ver))
 	}
@@ -301,5 +374,8 @@ func (r *ReconcileElasticsearch) finalizersFor(
 		r.esObservers.Finalizer(clusterName),
 		keystore.Finalizer(k8s.ExtractNamespacedName(&es), r.dynamicWatches, es.Kind()),
 		http.DynamicWatchesFinalizer(r.dynamicWatches, es.Kind(), es.Name, esname.ESNamer),
+		escerts.DynamicWatchesFinalizer(r.dynamicWatches, es),
+		esuser.DynamicWatchesFinalizer(r.dynamicWatches, es),
+		driver.VolumeClaimsFinalizer(r.Client, es),
 	}
 }
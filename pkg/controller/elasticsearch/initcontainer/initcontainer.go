@@ -5,6 +5,7 @@
 package initcontainer
 
 import (
+	"github.com/elastic/cloud-on-k8s/pkg/apis/elasticsearch/v1alpha1"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/common/keystore"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/common/volume"
 	corev1 "k8s.io/api/core/v1"
@@ -23,26 +24,35 @@ const (
 // NewInitContainers creates init containers according to the given parameters
 func NewInitContainers(
 	elasticsearchImage string,
-	setVMMaxMapCount *bool,
+	setVMMaxMapCount bool,
+	checkVMMaxMapCount bool,
 	transportCertificatesVolume volume.SecretVolume,
 	clusterName string,
+	plugins []v1alpha1.Plugin,
 	keystoreResources *keystore.Resources,
 ) ([]corev1.Container, error) {
 	var containers []corev1.Container
-	// create the privileged init container if not explicitly disabled by the user
-	if setVMMaxMapCount == nil || *setVMMaxMapCount {
+	// create the privileged init container if not disabled by the user or the restricted mode
+	if setVMMaxMapCount {
 		osSettingsContainer, err := NewOSSettingsInitContainer(elasticsearchImage)
 		if err != nil {
 			return nil, err
 		}
 		containers = append(containers, osSettingsContainer)
 	}
-	prepareFsContainer, err := NewPrepareFSInitContainer(elasticsearchImage, transportCertificatesVolume, clusterName)
+	prepareFsContainer, err := NewPrepareFSInitContainer(
+		elasticsearchImage, transportCertificatesVolume, clusterName, checkVMMaxMapCount,
+	)
 	if err != nil {
 		return nil, err
 	}
 	containers = append(containers, prepareFsContainer)
 
+	// plugins are installed once the prepare-fs init container populated the shared volumes
+	if len(plugins) > 0 {
+		containers = append(containers, NewInstallPluginsInitContainer(elasticsearchImage, plugins))
+	}
+
 	if keystoreResources != nil {
 		containers = append(containers, keystoreResources.InitContainer)
 	}
@@ -7,18 +7,19 @@ package initcontainer
 import (
 	"testing"
 
+	"github.com/elastic/cloud-on-k8s/pkg/apis/elasticsearch/v1alpha1"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/common/keystore"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/common/volume"
 	"github.com/stretchr/testify/assert"
 )
 
 func TestNewInitContainers(t *testing.T) {
-	varTrue := true
-	varFalse := false
 	type args struct {
 		elasticsearchImage string
 		operatorImage      string
-		SetVMMaxMapCount   *bool
+		SetVMMaxMapCount   bool
+		CheckVMMaxMapCount bool
+		plugins            []v1alpha1.Plugin
 		keystoreResources  *keystore.Resources
 	}
 	tests := []struct {
@@ -31,25 +32,26 @@ func TestNewInitContainers(t *testing.T) {
 			args: args{
 				elasticsearchImage: "es-image",
 				operatorImage:      "op-image",
-				SetVMMaxMapCount:   &varTrue,
+				SetVMMaxMapCount:   true,
 			},
 			expectedNumberOfContainers: 2,
 		},
 		{
-			name: "with SetVMMaxMapCount unspecified",
+			name: "with SetVMMaxMapCount disabled and CheckVMMaxMapCount enabled",
 			args: args{
 				elasticsearchImage: "es-image",
 				operatorImage:      "op-image",
-				SetVMMaxMapCount:   nil,
+				SetVMMaxMapCount:   false,
+				CheckVMMaxMapCount: true,
 			},
-			expectedNumberOfContainers: 2,
+			expectedNumberOfContainers: 1,
 		},
 		{
 			name: "with SetVMMaxMapCount disabled",
 			args: args{
 				elasticsearchImage: "es-image",
 				operatorImage:      "op-image",
-				SetVMMaxMapCount:   &varFalse,
+				SetVMMaxMapCount:   false,
 			},
 			expectedNumberOfContainers: 1,
 		},
@@ -58,19 +60,31 @@ func TestNewInitContainers(t *testing.T) {
 			args: args{
 				elasticsearchImage: "es-image",
 				operatorImage:      "op-image",
-				SetVMMaxMapCount:   nil,
+				SetVMMaxMapCount:   true,
 				keystoreResources:  &keystore.Resources{},
 			},
 			expectedNumberOfContainers: 3,
 		},
+		{
+			name: "with plugins",
+			args: args{
+				elasticsearchImage: "es-image",
+				operatorImage:      "op-image",
+				SetVMMaxMapCount:   true,
+				plugins:            []v1alpha1.Plugin{{Name: "analysis-icu"}},
+			},
+			expectedNumberOfContainers: 3,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			containers, err := NewInitContainers(
 				tt.args.elasticsearchImage,
 				tt.args.SetVMMaxMapCount,
+				tt.args.CheckVMMaxMapCount,
 				volume.SecretVolume{},
 				"clustername",
+				tt.args.plugins,
 				tt.args.keystoreResources,
 			)
 			assert.NoError(t, err)
@@ -14,6 +14,10 @@ import (
 // https://www.elastic.co/guide/en/elasticsearch/reference/current/docker.html#docker-cli-run-prod-mode
 const VMMaxMapCount = 262144
 
+// MinVMMaxMapCountEnvVar is the environment variable set in the prepare-fs init container
+// to check vm.max_map_count is at least the given value.
+const MinVMMaxMapCountEnvVar = "MIN_VM_MAX_MAP_COUNT"
+
 // NewOSSettingsInitContainer creates an init container to handle OS settings tweaks
 // It needs to be privileged.
 func NewOSSettingsInitContainer(imageName string) (corev1.Container, error) {
@@ -0,0 +1,104 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package initcontainer
+
+import (
+	"path"
+
+	"github.com/elastic/cloud-on-k8s/pkg/apis/elasticsearch/v1alpha1"
+	"github.com/elastic/cloud-on-k8s/pkg/utils/stringsutil"
+	corev1 "k8s.io/api/core/v1"
+)
+
+const (
+	// installPluginsContainerName is the name of the container that installs the plugins
+	installPluginsContainerName = "elastic-internal-install-plugins"
+
+	// PluginBundlesMountPath is the directory where the volumes containing plugin bundles are mounted
+	// in the install plugins init container.
+	PluginBundlesMountPath = "/mnt/elastic-internal/plugin-bundles"
+
+	pluginBinPath = "/usr/share/elasticsearch/bin/elasticsearch-plugin"
+)
+
+// installedPluginsFile records the plugins already installed in the shared volumes, which outlive the init
+// container if it is restarted.
+var installedPluginsFile = path.Join(EsConfigSharedVolume.EsContainerMountPath, ".elastic-internal-installed-plugins")
+
+// installPluginsScript installs the plugins given as arguments, skipping the ones already installed.
+var installPluginsScript = `#!/usr/bin/env bash
+
+set -eu
+
+for plugin in "$@"; do
+	if [[ -f ` + installedPluginsFile + ` ]] && grep -Fxq "$plugin" ` + installedPluginsFile + `; then
+		echo "Plugin $plugin already installed"
+		continue
+	fi
+	echo "Installing plugin $plugin"
+	` + pluginBinPath + ` install --batch "$plugin"
+	echo "$plugin" >> ` + installedPluginsFile + `
+done
+`
+
+// NewInstallPluginsInitContainer creates an init container installing the given plugins in the shared volumes
+// populated by the prepare-fs init container, so they can be loaded by the Elasticsearch container.
+// Volumes containing plugin bundles are mounted read-only under PluginBundlesMountPath.
+func NewInstallPluginsInitContainer(imageName string, plugins []v1alpha1.Plugin) corev1.Container {
+	var volumeMounts []corev1.VolumeMount
+	for _, v := range PluginBundleVolumeNames(plugins) {
+		volumeMounts = append(volumeMounts, corev1.VolumeMount{
+			Name:      v,
+			MountPath: path.Join(PluginBundlesMountPath, v),
+			ReadOnly:  true,
+		})
+	}
+	// the shared volumes are mounted at the location of the Elasticsearch directories
+	// so the plugins are installed as if they were part of the image
+	volumeMounts = append(volumeMounts, PluginVolumes.EsContainerVolumeMounts()...)
+
+	privileged := false
+	return corev1.Container{
+		Image:           imageName,
+		ImagePullPolicy: corev1.PullIfNotPresent,
+		Name:            installPluginsContainerName,
+		SecurityContext: &corev1.SecurityContext{
+			Privileged: &privileged,
+		},
+		Command:      append([]string{"bash", "-c", installPluginsScript, installPluginsContainerName}, PluginSources(plugins)...),
+		VolumeMounts: volumeMounts,
+	}
+}
+
+// PluginSources returns the arguments given to the plugin install command for the given plugins:
+// the name or URL of remote plugins, and a file URL for plugin bundles.
+func PluginSources(plugins []v1alpha1.Plugin) []string {
+	sources := make([]string, 0, len(plugins))
+	for _, p := range plugins {
+		if p.Bundle != nil {
+			sources = append(sources, stringsutil.Concat("file://", path.Join(PluginBundlesMountPath, p.Bundle.VolumeName, p.Bundle.Path)))
+			continue
+		}
+		sources = append(sources, p.Name)
+	}
+	return sources
+}
+
+// PluginBundleVolumeNames returns the names of the volumes containing the given plugin bundles, without duplicates.
+func PluginBundleVolumeNames(plugins []v1alpha1.Plugin) []string {
+	var names []string
+	seen := map[string]struct{}{}
+	for _, p := range plugins {
+		if p.Bundle == nil {
+			continue
+		}
+		if _, exists := seen[p.Bundle.VolumeName]; exists {
+			continue
+		}
+		seen[p.Bundle.VolumeName] = struct{}{}
+		names = append(names, p.Bundle.VolumeName)
+	}
+	return names
+}
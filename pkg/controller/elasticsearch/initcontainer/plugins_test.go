@@ -0,0 +1,39 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package initcontainer
+
+import (
+	"testing"
+
+	"github.com/elastic/cloud-on-k8s/pkg/apis/elasticsearch/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestNewInstallPluginsInitContainer(t *testing.T) {
+	plugins := []v1alpha1.Plugin{
+		{Name: "analysis-icu"},
+		{Name: "https://example.com/plugin.zip"},
+		{Bundle: &v1alpha1.PluginBundle{VolumeName: "bundles", Path: "a/plugin-a.zip"}},
+		{Bundle: &v1alpha1.PluginBundle{VolumeName: "bundles", Path: "plugin-b.zip"}},
+	}
+
+	container := NewInstallPluginsInitContainer("es-image", plugins)
+
+	assert.Equal(t, "es-image", container.Image)
+	assert.Equal(t, []string{
+		"bash", "-c", installPluginsScript, installPluginsContainerName,
+		"analysis-icu",
+		"https://example.com/plugin.zip",
+		"file:///mnt/elastic-internal/plugin-bundles/bundles/a/plugin-a.zip",
+		"file:///mnt/elastic-internal/plugin-bundles/bundles/plugin-b.zip",
+	}, container.Command)
+	assert.Equal(t, []corev1.VolumeMount{
+		{Name: "bundles", MountPath: "/mnt/elastic-internal/plugin-bundles/bundles", ReadOnly: true},
+		{Name: "elastic-internal-elasticsearch-config-local", MountPath: "/usr/share/elasticsearch/config"},
+		{Name: "elastic-internal-elasticsearch-plugins-local", MountPath: "/usr/share/elasticsearch/plugins"},
+		{Name: "elastic-internal-elasticsearch-bin-local", MountPath: "/usr/share/elasticsearch/bin"},
+	}, container.VolumeMounts)
+}
@@ -6,6 +6,7 @@ package initcontainer
 
 import (
 	"path"
+	"strconv"
 
 	"github.com/elastic/cloud-on-k8s/pkg/controller/common/defaults"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/common/volume"
@@ -81,12 +82,14 @@ var (
 
 // NewPrepareFSInitContainer creates an init container to handle things such as:
 // - configuration changes
+// - checking vm.max_map_count is large enough, if checkVMMaxMapCount is true
 // Modified directories and files are meant to be persisted for reuse in the actual ES container.
 // This container does not need to be privileged.
 func NewPrepareFSInitContainer(
 	imageName string,
 	transportCertificatesVolume volume.SecretVolume,
 	clusterName string,
+	checkVMMaxMapCount bool,
 ) (corev1.Container, error) {
 	// we mount the certificates to a location outside of the default config directory because the prepare-fs script
 	// will attempt to move all the files under the configuration directory to a different volume, and it should not
@@ -100,6 +103,11 @@ func NewPrepareFSInitContainer(
 		esvolume.ScriptsVolumeMountPath,
 		0755)
 
+	env := append([]corev1.EnvVar{}, defaults.PodDownwardEnvVars...)
+	if checkVMMaxMapCount {
+		env = append(env, corev1.EnvVar{Name: MinVMMaxMapCountEnvVar, Value: strconv.Itoa(VMMaxMapCount)})
+	}
+
 	privileged := false
 	container := corev1.Container{
 		Image:           imageName,
@@ -108,7 +116,7 @@ func NewPrepareFSInitContainer(
 		SecurityContext: &corev1.SecurityContext{
 			Privileged: &privileged,
 		},
-		Env:     defaults.PodDownwardEnvVars,
+		Env:     env,
 		Command: []string{"bash", "-c", path.Join(esvolume.ScriptsVolumeMountPath, PrepareFsScriptConfigKey)},
 		VolumeMounts: append(
 			PluginVolumes.InitContainerVolumeMounts(),
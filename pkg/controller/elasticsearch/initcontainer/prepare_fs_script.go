@@ -47,6 +47,9 @@ func RenderScriptTemplate(params TemplateParams) (string, error) {
 const (
 	PrepareFsScriptConfigKey  = "prepare-fs.sh"
 	UnsupportedDistroExitCode = 42
+	// InsufficientVMMaxMapCountExitCode is the exit code of the script when vm.max_map_count is too low
+	// for Elasticsearch to memory-map its index files.
+	InsufficientVMMaxMapCountExitCode = 43
 )
 
 // scriptTemplate is the main script to be run
@@ -63,6 +66,15 @@ var scriptTemplate = template.Must(template.New("").Parse(
 		exit ` + fmt.Sprintf("%d", UnsupportedDistroExitCode) + `
 	fi
 
+	# check vm.max_map_count when it is not set by a privileged init container
+	if [[ -n "${` + MinVMMaxMapCountEnvVar + `:-}" ]]; then
+		max_map_count=$(cat /proc/sys/vm/max_map_count)
+		if [[ $max_map_count -lt $` + MinVMMaxMapCountEnvVar + ` ]]; then
+			>&2 echo "vm.max_map_count is $max_map_count, at least $` + MinVMMaxMapCountEnvVar + ` is required"
+			exit ` + fmt.Sprintf("%d", InsufficientVMMaxMapCountExitCode) + `
+		fi
+	fi
+
 	# compute time in seconds since the given start time
 	function duration() {
 		local start=$1
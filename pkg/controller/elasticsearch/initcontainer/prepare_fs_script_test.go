@@ -32,6 +32,7 @@ func TestRenderScriptTemplate(t *testing.T) {
 				"cp -av /usr/share/elasticsearch/bin/* /mnt/elastic-internal/elasticsearch-bin-local/",
 				"cp -av /usr/share/elasticsearch/plugins/* /mnt/elastic-internal/elasticsearch-plugins-local/",
 				"ln -sf /secrets/users /usr/share/elasticsearch/users",
+				"max_map_count=$(cat /proc/sys/vm/max_map_count)",
 			},
 		},
 	}
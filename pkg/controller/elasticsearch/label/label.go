@@ -45,8 +45,15 @@ const (
 	NodeTypesMLLabelName common.TrueFalseLabel = "elasticsearch.k8s.elastic.co/node-ml"
 
 	ConfigTemplateHashLabelName = "elasticsearch.k8s.elastic.co/config-template-hash"
+	// PluginsHashLabelName is a label to store a hash of the plugins installed on the node
+	PluginsHashLabelName = "elasticsearch.k8s.elastic.co/plugins-hash"
 
 	HTTPSchemeLabelName = "elasticsearch.k8s.elastic.co/http-scheme"
+	// ExternalServiceLabelName is a label set to true on the pods backing the external HTTP service, when only
+	// some of the NodeSpecs back the external HTTP service. It is set on the existing pods, not in the pod template.
+	ExternalServiceLabelName = "elasticsearch.k8s.elastic.co/external-service"
+	// NodeSpecServiceLabelName is a label set on the per-NodeSpec HTTP services to store the name of the NodeSpec
+	NodeSpecServiceLabelName = "elasticsearch.k8s.elastic.co/nodespec-service"
 
 	// Type represents the Elasticsearch type
 	Type = "elasticsearch"
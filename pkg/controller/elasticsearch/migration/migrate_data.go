@@ -76,6 +76,35 @@ func IsMigratingData(state observer.State, podName string, exclusions []string)
 	return nodeIsMigratingData(podName, clusterState.GetShards(), excludedNodes)
 }
 
+// nodeDataIsReplicated is the core of IsDataReplicated just with any I/O
+// removed to facilitate testing. See IsDataReplicated for a high-level description.
+func nodeDataIsReplicated(nodeName string, shards []client.Shard) bool {
+	// shard keys mapped to whether they have a started copy outside of the node
+	startedElsewhere := make(map[string]bool)
+	for _, shard := range shards {
+		key := shard.Key()
+		startedElsewhere[key] = startedElsewhere[key] || (shard.Node != nodeName && shard.IsStarted())
+	}
+	for _, started := range startedElsewhere {
+		if !started {
+			return false
+		}
+	}
+	return true
+}
+
+// IsDataReplicated checks that every shard of the cluster has at least one started copy
+// on another node than the given one, so that the node and its data can be removed
+// without losing the last copy of a shard. This also holds if the node already left
+// the cluster: the shards it held are then either started elsewhere or unassigned.
+func IsDataReplicated(state observer.State, podName string) bool {
+	clusterState := state.ClusterState
+	if clusterState == nil || clusterState.IsEmpty() {
+		return false // we don't know if the request timed out or the cluster has not formed yet
+	}
+	return nodeDataIsReplicated(podName, clusterState.GetShards())
+}
+
 // AllocationSettings captures Elasticsearch API calls around allocation filtering.
 type AllocationSettings interface {
 	ExcludeFromShardAllocation(context context.Context, nodes string) error
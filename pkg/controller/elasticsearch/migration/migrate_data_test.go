@@ -178,3 +178,59 @@ func TestIsMigratingData(t *testing.T) {
 		})
 	}
 }
+
+func TestNodeDataIsReplicated(t *testing.T) {
+	tests := []struct {
+		name   string
+		shards []client.Shard
+		want   bool
+	}{
+		{
+			name: "no shards",
+			want: true,
+		},
+		{
+			name: "node still in the cluster, copies started elsewhere",
+			shards: []client.Shard{
+				{Index: "index-1", Shard: 0, State: client.STARTED, Node: "A"},
+				{Index: "index-1", Shard: 0, State: client.STARTED, Node: "B"},
+				{Index: "index-1", Shard: 1, State: client.STARTED, Node: "C"},
+			},
+			want: true,
+		},
+		{
+			name: "node still in the cluster with the only copy",
+			shards: []client.Shard{
+				{Index: "index-1", Shard: 0, State: client.STARTED, Node: "A"},
+				{Index: "index-1", Shard: 0, State: client.INITIALIZING, Node: "B"},
+			},
+			want: false,
+		},
+		{
+			name: "node left the cluster, replica unassigned",
+			shards: []client.Shard{
+				{Index: "index-1", Shard: 0, Primary: true, State: client.STARTED, Node: "B"},
+				{Index: "index-1", Shard: 0, State: client.UNASSIGNED, Node: ""},
+			},
+			want: true,
+		},
+		{
+			name: "node left the cluster with the last copy",
+			shards: []client.Shard{
+				{Index: "index-1", Shard: 0, Primary: true, State: client.STARTED, Node: "B"},
+				{Index: "index-1", Shard: 1, Primary: true, State: client.UNASSIGNED, Node: ""},
+			},
+			want: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, nodeDataIsReplicated("A", tt.shards))
+		})
+	}
+}
+
+func TestIsDataReplicated(t *testing.T) {
+	assert.False(t, IsDataReplicated(observer.State{}, "A"), "unknown cluster state")
+	assert.False(t, IsDataReplicated(observer.State{ClusterState: &client.ClusterState{}}, "A"), "empty cluster state")
+}
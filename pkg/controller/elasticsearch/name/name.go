@@ -68,6 +68,12 @@ func Validate(es v1alpha1.Elasticsearch) error {
 			return errors.Wrapf(err, "error generating StatefulSet name for nodeSpec: '%s'", nodeSpec.Name)
 		}
 
+		if nodeSpec.Service != nil {
+			if _, err := ESNamer.SafeSuffix(esName, nodeSpec.Name, httpServiceSuffix); err != nil {
+				return errors.Wrapf(err, "error generating Service name for nodeSpec: '%s'", nodeSpec.Name)
+			}
+		}
+
 		// length of the ordinal suffix that will be added to the pods of this sset (dash + ordinal)
 		podOrdinalSuffixLen := len(strconv.FormatInt(int64(nodeSpec.NodeCount), 10)) + 1
 		// there should be enough space for the ordinal suffix
@@ -109,6 +115,11 @@ func HTTPService(esName string) string {
 	return ESNamer.Suffix(esName, httpServiceSuffix)
 }
 
+// NodeSpecHTTPService returns the name of the HTTP service selecting only the nodes of the given NodeSpec.
+func NodeSpecHTTPService(esName string, nodeSpecName string) string {
+	return ESNamer.Suffix(esName, nodeSpecName, httpServiceSuffix)
+}
+
 func ElasticUserSecret(esName string) string {
 	return ESNamer.Suffix(esName, elasticUserSecretSuffix)
 }
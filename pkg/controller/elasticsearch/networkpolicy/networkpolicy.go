@@ -0,0 +1,89 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package networkpolicy
+
+import (
+	"sort"
+
+	apmv1alpha1 "github.com/elastic/cloud-on-k8s/pkg/apis/apm/v1alpha1"
+	"github.com/elastic/cloud-on-k8s/pkg/apis/elasticsearch/v1alpha1"
+	kbv1alpha1 "github.com/elastic/cloud-on-k8s/pkg/apis/kibana/v1alpha1"
+	apmlabels "github.com/elastic/cloud-on-k8s/pkg/controller/apmserver/labels"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/networkpolicy"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/label"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/name"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/network"
+	kblabel "github.com/elastic/cloud-on-k8s/pkg/controller/kibana/label"
+	"github.com/elastic/cloud-on-k8s/pkg/utils/k8s"
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Reconcile ensures the NetworkPolicy of the cluster exists if enabled in the spec, and is deleted otherwise.
+//
+// The transport port is only reachable from the pods of the cluster. The HTTP port is only reachable from the operator,
+// the pods of the Kibana and APM Server resources associated to the cluster, and the sources specified in the spec.
+func Reconcile(c k8s.Client, scheme *runtime.Scheme, es v1alpha1.Elasticsearch, operatorPeer networkingv1.NetworkPolicyPeer) error {
+	if !es.Spec.NetworkPolicy.Enabled {
+		return networkpolicy.Reconcile(c, scheme, &es, false, newNetworkPolicy(es, nil))
+	}
+
+	httpPeers := []networkingv1.NetworkPolicyPeer{operatorPeer}
+	associated, err := associatedPeers(c, es)
+	if err != nil {
+		return err
+	}
+	httpPeers = append(httpPeers, associated...)
+	httpPeers = append(httpPeers, es.Spec.NetworkPolicy.HTTPFrom...)
+
+	return networkpolicy.Reconcile(c, scheme, &es, true, newNetworkPolicy(es, httpPeers))
+}
+
+func newNetworkPolicy(es v1alpha1.Elasticsearch, httpPeers []networkingv1.NetworkPolicyPeer) networkingv1.NetworkPolicy {
+	labels := label.NewLabels(k8s.ExtractNamespacedName(&es))
+	return networkpolicy.New(
+		name.ESNamer,
+		&es,
+		labels,
+		labels,
+		networkpolicy.PortRule(network.TransportPort, networkpolicy.PodsPeer(es.Namespace, es.Namespace, labels)),
+		networkpolicy.PortRule(network.HTTPPort, httpPeers...),
+	)
+}
+
+// associatedPeers returns peers matching the pods of the Kibana and APM Server resources referencing the cluster.
+// Items are listed in a stable order, to avoid updating the NetworkPolicy needlessly.
+func associatedPeers(c k8s.Client, es v1alpha1.Elasticsearch) ([]networkingv1.NetworkPolicyPeer, error) {
+	esNSN := k8s.ExtractNamespacedName(&es)
+	var peers []networkingv1.NetworkPolicyPeer
+
+	var kibanas kbv1alpha1.KibanaList
+	if err := c.List(&client.ListOptions{}, &kibanas); err != nil {
+		return nil, err
+	}
+	sort.Slice(kibanas.Items, func(i, j int) bool {
+		return k8s.LessNamespacedName(&kibanas.Items[i], &kibanas.Items[j])
+	})
+	for _, kb := range kibanas.Items {
+		if networkpolicy.References(kb.Spec.ElasticsearchRef, kb.Namespace, esNSN) {
+			peers = append(peers, networkpolicy.PodsPeer(es.Namespace, kb.Namespace, kblabel.NewLabels(kb.Name)))
+		}
+	}
+
+	var apmServers apmv1alpha1.ApmServerList
+	if err := c.List(&client.ListOptions{}, &apmServers); err != nil {
+		return nil, err
+	}
+	sort.Slice(apmServers.Items, func(i, j int) bool {
+		return k8s.LessNamespacedName(&apmServers.Items[i], &apmServers.Items[j])
+	})
+	for _, as := range apmServers.Items {
+		if networkpolicy.References(as.Spec.ElasticsearchRef, as.Namespace, esNSN) {
+			peers = append(peers, networkpolicy.PodsPeer(es.Namespace, as.Namespace, apmlabels.NewLabels(as.Name)))
+		}
+	}
+	return peers, nil
+}
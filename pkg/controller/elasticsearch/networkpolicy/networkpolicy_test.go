@@ -0,0 +1,148 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package networkpolicy
+
+import (
+	"testing"
+
+	apmv1alpha1 "github.com/elastic/cloud-on-k8s/pkg/apis/apm/v1alpha1"
+	commonv1alpha1 "github.com/elastic/cloud-on-k8s/pkg/apis/common/v1alpha1"
+	"github.com/elastic/cloud-on-k8s/pkg/apis/elasticsearch/v1alpha1"
+	kbv1alpha1 "github.com/elastic/cloud-on-k8s/pkg/apis/kibana/v1alpha1"
+	"github.com/elastic/cloud-on-k8s/pkg/utils/k8s"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+var (
+	operatorPeer = networkingv1.NetworkPolicyPeer{
+		PodSelector:       &metav1.LabelSelector{MatchLabels: map[string]string{"control-plane": "elastic-operator"}},
+		NamespaceSelector: &metav1.LabelSelector{},
+	}
+	userPeer = networkingv1.NetworkPolicyPeer{
+		PodSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "ingress-nginx"}},
+	}
+	clusterLabels = map[string]string{
+		"common.k8s.elastic.co/type":                "elasticsearch",
+		"elasticsearch.k8s.elastic.co/cluster-name": "es",
+	}
+)
+
+func newES(enabled bool) v1alpha1.Elasticsearch {
+	return v1alpha1.Elasticsearch{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "es", UID: "uid"},
+		Spec: v1alpha1.ElasticsearchSpec{
+			NetworkPolicy: commonv1alpha1.NetworkPolicySpec{
+				Enabled:  enabled,
+				HTTPFrom: []networkingv1.NetworkPolicyPeer{userPeer},
+			},
+		},
+	}
+}
+
+func newKibana(namespace, name string, ref commonv1alpha1.ObjectSelector) *kbv1alpha1.Kibana {
+	return &kbv1alpha1.Kibana{
+		ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name},
+		Spec:       kbv1alpha1.KibanaSpec{ElasticsearchRef: ref},
+	}
+}
+
+func newApmServer(namespace, name string, ref commonv1alpha1.ObjectSelector) *apmv1alpha1.ApmServer {
+	return &apmv1alpha1.ApmServer{
+		ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name},
+		Spec:       apmv1alpha1.ApmServerSpec{ElasticsearchRef: ref},
+	}
+}
+
+func podsPeer(labels map[string]string, namespace string) networkingv1.NetworkPolicyPeer {
+	peer := networkingv1.NetworkPolicyPeer{PodSelector: &metav1.LabelSelector{MatchLabels: labels}}
+	if namespace != "ns" {
+		peer.NamespaceSelector = &metav1.LabelSelector{MatchLabels: map[string]string{"kubernetes.io/metadata.name": namespace}}
+	}
+	return peer
+}
+
+func TestReconcile(t *testing.T) {
+	require.NoError(t, v1alpha1.AddToScheme(scheme.Scheme))
+	require.NoError(t, kbv1alpha1.AddToScheme(scheme.Scheme))
+	require.NoError(t, apmv1alpha1.AddToScheme(scheme.Scheme))
+
+	tcp := corev1.ProtocolTCP
+	transportPort := intstr.FromInt(9300)
+	httpPort := intstr.FromInt(9200)
+
+	tests := []struct {
+		name        string
+		es          v1alpha1.Elasticsearch
+		initial     []runtime.Object
+		wantDeleted bool
+		wantHTTP    []networkingv1.NetworkPolicyPeer
+	}{
+		{
+			name:        "network policy disabled",
+			es:          newES(false),
+			wantDeleted: true,
+		},
+		{
+			name:     "no associated resources",
+			es:       newES(true),
+			wantHTTP: []networkingv1.NetworkPolicyPeer{operatorPeer, userPeer},
+		},
+		{
+			name: "associated Kibana and APM Server resources, sorted by namespace and name",
+			es:   newES(true),
+			initial: []runtime.Object{
+				newKibana("ns", "kb2", commonv1alpha1.ObjectSelector{Name: "es"}),
+				newKibana("ns", "kb1", commonv1alpha1.ObjectSelector{Name: "es"}),
+				newKibana("other", "kb", commonv1alpha1.ObjectSelector{Name: "es", Namespace: "ns"}),
+				newKibana("other", "unrelated", commonv1alpha1.ObjectSelector{Name: "es"}),
+				newApmServer("ns", "apm", commonv1alpha1.ObjectSelector{Name: "es"}),
+				newApmServer("ns", "unrelated", commonv1alpha1.ObjectSelector{Name: "other"}),
+			},
+			wantHTTP: []networkingv1.NetworkPolicyPeer{
+				operatorPeer,
+				podsPeer(map[string]string{"common.k8s.elastic.co/type": "kibana", "kibana.k8s.elastic.co/name": "kb1"}, "ns"),
+				podsPeer(map[string]string{"common.k8s.elastic.co/type": "kibana", "kibana.k8s.elastic.co/name": "kb2"}, "ns"),
+				podsPeer(map[string]string{"common.k8s.elastic.co/type": "kibana", "kibana.k8s.elastic.co/name": "kb"}, "other"),
+				podsPeer(map[string]string{"common.k8s.elastic.co/type": "apm-server", "apm.k8s.elastic.co/name": "apm"}, "ns"),
+				userPeer,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := k8s.WrapClient(fake.NewFakeClient(tt.initial...))
+			require.NoError(t, Reconcile(c, scheme.Scheme, tt.es, operatorPeer))
+
+			var got networkingv1.NetworkPolicy
+			err := c.Get(types.NamespacedName{Namespace: "ns", Name: "es-es-network-policy"}, &got)
+			if tt.wantDeleted {
+				assert.True(t, errors.IsNotFound(err))
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, metav1.LabelSelector{MatchLabels: clusterLabels}, got.Spec.PodSelector)
+			assert.Equal(t, []networkingv1.NetworkPolicyIngressRule{
+				{
+					Ports: []networkingv1.NetworkPolicyPort{{Protocol: &tcp, Port: &transportPort}},
+					From:  []networkingv1.NetworkPolicyPeer{podsPeer(clusterLabels, "ns")},
+				},
+				{
+					Ports: []networkingv1.NetworkPolicyPort{{Protocol: &tcp, Port: &httpPort}},
+					From:  tt.wantHTTP,
+				},
+			}, got.Spec.Ingress)
+		})
+	}
+}
@@ -26,6 +26,9 @@ const (
 
 	// DefaultTerminationGracePeriodSeconds is the termination grace period for the Elasticsearch containers
 	DefaultTerminationGracePeriodSeconds int64 = 120
+
+	// ElasticsearchUserID is the id of the elasticsearch user and group in the Elasticsearch Docker image
+	ElasticsearchUserID int64 = 1000
 )
 
 var (
@@ -70,6 +73,37 @@ func DefaultEnvVars(httpCfg v1alpha1.HTTPConfig) []corev1.EnvVar {
 	)
 }
 
+// RestrictedSecurityContext returns the pod security context used in restricted mode: all containers run as the
+// elasticsearch user, and the volumes are writable by its group.
+func RestrictedSecurityContext() *corev1.PodSecurityContext {
+	runAsNonRoot := true
+	userID := ElasticsearchUserID
+	return &corev1.PodSecurityContext{
+		RunAsNonRoot: &runAsNonRoot,
+		RunAsUser:    &userID,
+		FSGroup:      &userID,
+	}
+}
+
+// WithRestrictedSecurityContext merges the restricted mode requirements into the given pod security context:
+// containers never run as root, and the elasticsearch user and group are used unless others are specified.
+// The user-provided security context is not modified.
+func WithRestrictedSecurityContext(securityContext *corev1.PodSecurityContext) *corev1.PodSecurityContext {
+	restricted := RestrictedSecurityContext()
+	if securityContext == nil {
+		return restricted
+	}
+	merged := securityContext.DeepCopy()
+	merged.RunAsNonRoot = restricted.RunAsNonRoot
+	if merged.RunAsUser == nil {
+		merged.RunAsUser = restricted.RunAsUser
+	}
+	if merged.FSGroup == nil {
+		merged.FSGroup = restricted.FSGroup
+	}
+	return merged
+}
+
 // DefaultAffinity returns the default affinity for pods in a cluster.
 func DefaultAffinity(esName string) *corev1.Affinity {
 	return &corev1.Affinity{
@@ -0,0 +1,71 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package nodespec
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/heap"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/settings"
+)
+
+// MaxHeapSizeMi is the maximum heap size set by the operator, below the threshold above which the JVM cannot use
+// compressed ordinary object pointers anymore.
+const MaxHeapSizeMi = 31 * 1024
+
+var (
+	// jvmHeapRegexp matches the JVM options setting the initial or maximum heap size
+	jvmHeapRegexp = regexp.MustCompile(`^-Xm[sx]`)
+	// jvmMaxHeapRegexp matches the JVM option setting the maximum heap size, capturing its value and unit
+	jvmMaxHeapRegexp = regexp.MustCompile(`^-Xmx(\d+)([kKmMgGtT]?)$`)
+
+	// HeapOptions describes how the heap size of Elasticsearch is specified.
+	HeapOptions = heap.Options{
+		EnvVarName: settings.EnvEsJavaOpts,
+		Parse:      parseJVMHeapSizeMi,
+		Format: func(sizeMi int64) string {
+			return fmt.Sprintf("-Xms%dm -Xmx%dm", sizeMi, sizeMi)
+		},
+	}
+)
+
+// parseJVMHeapSizeMi returns the maximum heap size in MiB specified in the given JVM options. A heap size is considered
+// specified as soon as the initial or the maximum heap size is, even if the maximum heap size cannot be determined.
+func parseJVMHeapSizeMi(opts string) (int64, bool) {
+	specified := false
+	var match []string
+	for _, opt := range strings.Fields(opts) {
+		if !jvmHeapRegexp.MatchString(opt) {
+			continue
+		}
+		specified = true
+		// the last occurrence takes precedence
+		if m := jvmMaxHeapRegexp.FindStringSubmatch(opt); m != nil {
+			match = m
+		}
+	}
+	if match == nil {
+		return 0, specified
+	}
+	size, err := strconv.ParseInt(match[1], 10, 64)
+	if err != nil {
+		return 0, true
+	}
+	switch strings.ToLower(match[2]) {
+	case "":
+		return size / (1024 * 1024), true
+	case "k":
+		return size / 1024, true
+	case "m":
+		return size, true
+	case "g":
+		return size * 1024, true
+	default: // "t"
+		return size * 1024 * 1024, true
+	}
+}
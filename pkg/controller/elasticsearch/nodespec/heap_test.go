@@ -0,0 +1,37 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package nodespec
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_parseJVMHeapSizeMi(t *testing.T) {
+	tests := []struct {
+		opts          string
+		wantSizeMi    int64
+		wantSpecified bool
+	}{
+		{opts: "", wantSizeMi: 0, wantSpecified: false},
+		{opts: "-Dfoo=bar -XX:+UseG1GC", wantSizeMi: 0, wantSpecified: false},
+		{opts: "-Xms1g -Xmx1g", wantSizeMi: 1024, wantSpecified: true},
+		{opts: "-Xmx512m", wantSizeMi: 512, wantSpecified: true},
+		{opts: "-Xmx2G -Dfoo=bar", wantSizeMi: 2048, wantSpecified: true},
+		{opts: "-Xmx1048576k", wantSizeMi: 1024, wantSpecified: true},
+		{opts: "-Xmx1073741824", wantSizeMi: 1024, wantSpecified: true},
+		{opts: "-Xmx1t", wantSizeMi: 1024 * 1024, wantSpecified: true},
+		{opts: "-Xmx1g -Xmx4g", wantSizeMi: 4096, wantSpecified: true},
+		{opts: "-Xms1g", wantSizeMi: 0, wantSpecified: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.opts, func(t *testing.T) {
+			sizeMi, specified := parseJVMHeapSizeMi(tt.opts)
+			assert.Equal(t, tt.wantSizeMi, sizeMi)
+			assert.Equal(t, tt.wantSpecified, specified)
+		})
+	}
+}
@@ -14,6 +14,7 @@ import (
 	"github.com/elastic/cloud-on-k8s/pkg/controller/common/defaults"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/common/hash"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/common/keystore"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/operator"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/common/version"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/common/volume"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/initcontainer"
@@ -31,8 +32,10 @@ func BuildPodTemplateSpec(
 	nodeSpec v1alpha1.NodeSpec,
 	cfg settings.CanonicalConfig,
 	keystoreResources *keystore.Resources,
+	heapMemoryPercentage int,
+	restricted operator.RestrictedMode,
 ) (corev1.PodTemplateSpec, error) {
-	volumes, volumeMounts := buildVolumes(es.Name, nodeSpec, keystoreResources)
+	volumes, volumeMounts := buildVolumes(es.Name, nodeSpec, es.Spec.Auth, keystoreResources)
 	labels, err := buildLabels(es, cfg, nodeSpec, keystoreResources)
 	if err != nil {
 		return corev1.PodTemplateSpec{}, err
@@ -41,11 +44,26 @@ func BuildPodTemplateSpec(
 	builder := defaults.NewPodTemplateBuilder(nodeSpec.PodTemplate, v1alpha1.ElasticsearchContainerName).
 		WithDockerImage(es.Spec.Image, stringsutil.Concat(DefaultImageRepository, ":", es.Spec.Version))
 
+	// the privileged init container is never created in restricted mode, where vm.max_map_count is checked instead
+	setVMMaxMapCount := !restricted.Enabled && (es.Spec.SetVMMaxMapCount == nil || *es.Spec.SetVMMaxMapCount)
+	checkVMMaxMapCount := false
+	if restricted.Enabled {
+		ver, err := version.Parse(es.Spec.Version)
+		if err != nil {
+			return corev1.PodTemplateSpec{}, err
+		}
+		if checkVMMaxMapCount, err = cfg.MmapEnabled(*ver); err != nil {
+			return corev1.PodTemplateSpec{}, err
+		}
+	}
+
 	initContainers, err := initcontainer.NewInitContainers(
 		builder.Container.Image,
-		es.Spec.SetVMMaxMapCount,
+		setVMMaxMapCount,
+		checkVMMaxMapCount,
 		transportCertificatesVolume(es.Name),
 		es.Name,
+		nodeSpec.Plugins,
 		keystoreResources,
 	)
 	if err != nil {
@@ -53,6 +71,7 @@ func BuildPodTemplateSpec(
 	}
 
 	builder = builder.
+		WithHeapSize(HeapOptions, heapMemoryPercentage, MaxHeapSizeMi).
 		WithResources(DefaultResources).
 		WithTerminationGracePeriod(DefaultTerminationGracePeriodSeconds).
 		WithPorts(DefaultContainerPorts).
@@ -65,6 +84,10 @@ func BuildPodTemplateSpec(
 		WithInitContainers(initContainers...).
 		WithInitContainerDefaults()
 
+	if restricted.Enabled {
+		builder.PodTemplate.Spec.SecurityContext = WithRestrictedSecurityContext(builder.PodTemplate.Spec.SecurityContext)
+	}
+
 	return builder.PodTemplate, nil
 }
 
@@ -105,6 +128,11 @@ func buildLabels(
 		return nil, err
 	}
 
+	if len(nodeSpec.Plugins) > 0 {
+		// label with a hash of the plugins to rotate the pod on plugins change
+		podLabels[label.PluginsHashLabelName] = hash.HashObject(nodeSpec.Plugins)
+	}
+
 	if keystoreResources != nil {
 		// label with a checksum of the secure settings to rotate the pod on secure settings change
 		// TODO: use hash.HashObject instead && fix the config checksum label name?
@@ -11,6 +11,9 @@ import (
 	commonv1alpha1 "github.com/elastic/cloud-on-k8s/pkg/apis/common/v1alpha1"
 	"github.com/elastic/cloud-on-k8s/pkg/apis/elasticsearch/v1alpha1"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/common/defaults"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/heap"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/operator"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/version"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/initcontainer"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/settings"
 	"github.com/go-test/deep"
@@ -87,10 +90,19 @@ var sampleES = v1alpha1.Elasticsearch{
 
 func TestBuildPodTemplateSpec(t *testing.T) {
 	nodeSpec := sampleES.Spec.Nodes[0]
-	cfg, err := settings.NewMergedESConfig(sampleES.Name, sampleES.Spec.HTTP, *nodeSpec.Config)
+	cfg, err := settings.NewMergedESConfig(
+		sampleES.Name,
+		version.MustParse(sampleES.Spec.Version),
+		sampleES.Spec.HTTP,
+		sampleES.Spec.Transport,
+		sampleES.Spec.Auth,
+		*nodeSpec.Config,
+	)
 	require.NoError(t, err)
 
-	actual, err := BuildPodTemplateSpec(sampleES, sampleES.Spec.Nodes[0], cfg, nil)
+	actual, err := BuildPodTemplateSpec(
+		sampleES, sampleES.Spec.Nodes[0], cfg, nil, heap.DefaultMemoryPercentage, operator.RestrictedMode{},
+	)
 	require.NoError(t, err)
 
 	// build expected PodTemplateSpec
@@ -98,16 +110,18 @@ func TestBuildPodTemplateSpec(t *testing.T) {
 	terminationGracePeriodSeconds := DefaultTerminationGracePeriodSeconds
 	varFalse := false
 
-	volumes, volumeMounts := buildVolumes(sampleES.Name, nodeSpec, nil)
+	volumes, volumeMounts := buildVolumes(sampleES.Name, nodeSpec, sampleES.Spec.Auth, nil)
 	// should be sorted
 	sort.Slice(volumes, func(i, j int) bool { return volumes[i].Name < volumes[j].Name })
 	sort.Slice(volumeMounts, func(i, j int) bool { return volumeMounts[i].Name < volumeMounts[j].Name })
 
 	initContainers, err := initcontainer.NewInitContainers(
 		"docker.elastic.co/elasticsearch/elasticsearch:7.2.0",
-		nil,
+		true,
+		false,
 		transportCertificatesVolume(sampleES.Name),
 		sampleES.Name,
+		nodeSpec.Plugins,
 		nil,
 	)
 	require.NoError(t, err)
@@ -190,3 +204,95 @@ func TestBuildPodTemplateSpec(t *testing.T) {
 	deep.MaxDepth = 25
 	require.Nil(t, deep.Equal(expected, actual))
 }
+
+func TestBuildPodTemplateSpec_RestrictedMode(t *testing.T) {
+	nodeSpec := sampleES.Spec.Nodes[0]
+	cfg, err := settings.NewMergedESConfig(
+		sampleES.Name,
+		version.MustParse(sampleES.Spec.Version),
+		sampleES.Spec.HTTP,
+		sampleES.Spec.Transport,
+		sampleES.Spec.Auth,
+		*nodeSpec.Config,
+	)
+	require.NoError(t, err)
+	mmapDisabledCfg, err := settings.NewMergedESConfig(
+		sampleES.Name,
+		version.MustParse(sampleES.Spec.Version),
+		sampleES.Spec.HTTP,
+		sampleES.Spec.Transport,
+		sampleES.Spec.Auth,
+		*nodeSpec.Config,
+	)
+	require.NoError(t, err)
+	require.NoError(t, mmapDisabledCfg.DisableMmap(version.MustParse(sampleES.Spec.Version)))
+	fsGroup := int64(2000)
+
+	tests := []struct {
+		name                   string
+		cfg                    settings.CanonicalConfig
+		restricted             operator.RestrictedMode
+		wantOSSettings         bool
+		podSecurityContext     *corev1.PodSecurityContext
+		wantVMMaxMapCountCheck bool
+		wantSecurityContext    *corev1.PodSecurityContext
+	}{
+		{
+			name:           "not restricted",
+			cfg:            cfg,
+			restricted:     operator.RestrictedMode{},
+			wantOSSettings: true,
+		},
+		{
+			name:                "restricted, mmap disabled",
+			cfg:                 mmapDisabledCfg,
+			restricted:          operator.RestrictedMode{Enabled: true},
+			wantSecurityContext: RestrictedSecurityContext(),
+		},
+		{
+			name:                   "restricted with node tuning, mmap enabled",
+			cfg:                    cfg,
+			restricted:             operator.RestrictedMode{Enabled: true, NodeTuning: true},
+			wantVMMaxMapCountCheck: true,
+			wantSecurityContext:    RestrictedSecurityContext(),
+		},
+		{
+			name:               "restricted with a user-provided security context",
+			cfg:                mmapDisabledCfg,
+			restricted:         operator.RestrictedMode{Enabled: true},
+			podSecurityContext: &corev1.PodSecurityContext{FSGroup: &fsGroup},
+			wantSecurityContext: &corev1.PodSecurityContext{
+				RunAsNonRoot: RestrictedSecurityContext().RunAsNonRoot,
+				RunAsUser:    RestrictedSecurityContext().RunAsUser,
+				FSGroup:      &fsGroup,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			nodeSpec := *nodeSpec.DeepCopy()
+			nodeSpec.PodTemplate.Spec.SecurityContext = tt.podSecurityContext
+			actual, err := BuildPodTemplateSpec(sampleES, nodeSpec, tt.cfg, nil, heap.DefaultMemoryPercentage, tt.restricted)
+			require.NoError(t, err)
+
+			hasOSSettings := false
+			hasVMMaxMapCountCheck := false
+			for _, c := range actual.Spec.InitContainers {
+				if c.SecurityContext != nil && c.SecurityContext.Privileged != nil && *c.SecurityContext.Privileged {
+					hasOSSettings = true
+				}
+				if c.Name != initcontainer.PrepareFilesystemContainerName {
+					continue
+				}
+				for _, env := range c.Env {
+					if env.Name == initcontainer.MinVMMaxMapCountEnvVar {
+						hasVMMaxMapCountCheck = true
+					}
+				}
+			}
+			require.Equal(t, tt.wantOSSettings, hasOSSettings)
+			require.Equal(t, tt.wantVMMaxMapCountCheck, hasVMMaxMapCountCheck)
+			require.Equal(t, tt.wantSecurityContext, actual.Spec.SecurityContext)
+		})
+	}
+}
@@ -27,21 +27,33 @@ func NewReadinessProbe() *corev1.Probe {
 }
 
 const ReadinessProbeScriptConfigKey = "readiness-probe-script.sh"
+
+// ReadinessProbeScript requests Elasticsearch with the password of the probe user stored in the file realm first:
+// it is updated along with the file realm users, so that the probe keeps working while a password rotation is being
+// propagated to the node. It falls back to the password of the probe user secret.
 const ReadinessProbeScript string = `
 #!/usr/bin/env bash
 # Consider a node to be healthy if it responds to a simple GET on "/"
 CURL_TIMEOUT=3
 
+# request Elasticsearch with the given basic auth options
+request() {
+  curl -o /dev/null -w "%{http_code}" --max-time $CURL_TIMEOUT -XGET -s -k "$@" ${READINESS_PROBE_PROTOCOL:-https}://127.0.0.1:9200
+}
+
+REALM_PASSWORD_FILE="` + volume.XPackFileRealmVolumeMountPath + `/${PROBE_USERNAME}"
+
 # setup basic auth if credentials are available
-if [ -n "${PROBE_USERNAME}" ] && [ -f "${PROBE_PASSWORD_FILE}" ]; then
-  PROBE_PASSWORD=$(<$PROBE_PASSWORD_FILE)
-  BASIC_AUTH="-u ${PROBE_USERNAME}:${PROBE_PASSWORD}"
-else
-  BASIC_AUTH=''
+if [ -n "${PROBE_USERNAME}" ] && [ -f "${REALM_PASSWORD_FILE}" ]; then
+  status=$(request -u "${PROBE_USERNAME}:$(<$REALM_PASSWORD_FILE)")
+fi
+if [[ $status != "200" ]]; then
+  if [ -n "${PROBE_USERNAME}" ] && [ -f "${PROBE_PASSWORD_FILE}" ]; then
+    status=$(request -u "${PROBE_USERNAME}:$(<$PROBE_PASSWORD_FILE)")
+  else
+    status=$(request)
+  fi
 fi
-
-# request Elasticsearch
-status=$(curl -o /dev/null -w "%{http_code}" --max-time $CURL_TIMEOUT -XGET -s -k ${BASIC_AUTH} ${READINESS_PROBE_PROTOCOL:-https}://127.0.0.1:9200)
 
 # ready if status code 200
 if [[ $status == "200" ]]; then
@@ -11,6 +11,8 @@ import (
 	commonv1alpha1 "github.com/elastic/cloud-on-k8s/pkg/apis/common/v1alpha1"
 	"github.com/elastic/cloud-on-k8s/pkg/apis/elasticsearch/v1alpha1"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/common/keystore"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/operator"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/version"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/label"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/settings"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/sset"
@@ -34,7 +36,13 @@ func (l ResourcesList) StatefulSets() sset.StatefulSetList {
 	return ssetList
 }
 
-func BuildExpectedResources(es v1alpha1.Elasticsearch, keystoreResources *keystore.Resources) (ResourcesList, error) {
+func BuildExpectedResources(
+	es v1alpha1.Elasticsearch,
+	ver version.Version,
+	keystoreResources *keystore.Resources,
+	heapMemoryPercentage int,
+	restricted operator.RestrictedMode,
+) (ResourcesList, error) {
 	nodesResources := make(ResourcesList, 0, len(es.Spec.Nodes))
 
 	for _, nodeSpec := range es.Spec.Nodes {
@@ -43,13 +51,19 @@ func BuildExpectedResources(es v1alpha1.Elasticsearch, keystoreResources *keysto
 		if nodeSpec.Config != nil {
 			userCfg = *nodeSpec.Config
 		}
-		cfg, err := settings.NewMergedESConfig(es.Name, es.Spec.HTTP, userCfg)
+		cfg, err := settings.NewMergedESConfig(es.Name, ver, es.Spec.HTTP, es.Spec.Transport, es.Spec.Auth, userCfg)
 		if err != nil {
 			return nil, err
 		}
+		// prevent Elasticsearch from memory-mapping its files if vm.max_map_count may be too low
+		if !restricted.MaxMapCountGuaranteed() {
+			if err := cfg.DisableMmap(ver); err != nil {
+				return nil, err
+			}
+		}
 
 		// build stateful set and associated headless service
-		statefulSet, err := BuildStatefulSet(es, nodeSpec, cfg, keystoreResources)
+		statefulSet, err := BuildStatefulSet(es, nodeSpec, cfg, keystoreResources, heapMemoryPercentage, restricted)
 		if err != nil {
 			return nil, err
 		}
@@ -9,6 +9,7 @@ import (
 	"github.com/elastic/cloud-on-k8s/pkg/controller/common/defaults"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/common/hash"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/common/keystore"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/operator"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/label"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/name"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/settings"
@@ -48,6 +49,8 @@ func BuildStatefulSet(
 	nodeSpec v1alpha1.NodeSpec,
 	cfg settings.CanonicalConfig,
 	keystoreResources *keystore.Resources,
+	heapMemoryPercentage int,
+	restricted operator.RestrictedMode,
 ) (appsv1.StatefulSet, error) {
 	statefulSetName := name.StatefulSet(es.Name, nodeSpec.Name)
 
@@ -59,7 +62,9 @@ func BuildStatefulSet(
 		nodeSpec.VolumeClaimTemplates, nodeSpec.PodTemplate.Spec, esvolume.DefaultVolumeClaimTemplates...,
 	)
 	// build pod template
-	podTemplate, err := BuildPodTemplateSpec(es, nodeSpec, cfg, keystoreResources)
+	podTemplate, err := BuildPodTemplateSpec(
+		es, nodeSpec, cfg, keystoreResources, heapMemoryPercentage, restricted,
+	)
 	if err != nil {
 		return appsv1.StatefulSet{}, err
 	}
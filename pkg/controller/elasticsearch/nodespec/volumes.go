@@ -5,6 +5,8 @@
 package nodespec
 
 import (
+	"path"
+
 	corev1 "k8s.io/api/core/v1"
 
 	"github.com/elastic/cloud-on-k8s/pkg/apis/elasticsearch/v1alpha1"
@@ -18,7 +20,12 @@ import (
 	esvolume "github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/volume"
 )
 
-func buildVolumes(esName string, nodeSpec v1alpha1.NodeSpec, keystoreResources *keystore.Resources) ([]corev1.Volume, []corev1.VolumeMount) {
+func buildVolumes(
+	esName string,
+	nodeSpec v1alpha1.NodeSpec,
+	auth v1alpha1.Auth,
+	keystoreResources *keystore.Resources,
+) ([]corev1.Volume, []corev1.VolumeMount) {
 
 	configVolume := settings.ConfigSecretVolume(name.StatefulSet(esName, nodeSpec.Name))
 	probeSecret := volume.NewSelectiveSecretVolumeWithMountPath(
@@ -89,5 +96,17 @@ func buildVolumes(esName string, nodeSpec v1alpha1.NodeSpec, keystoreResources *
 		configVolume.VolumeMount(),
 	)
 
+	// mount the files referenced by the authentication realms
+	for _, file := range settings.RealmSecretFiles(auth) {
+		realmVolume := volume.NewSelectiveSecretVolumeWithMountPath(
+			file.SecretName,
+			esvolume.RealmVolumeNamePrefix+file.RealmName,
+			path.Dir(file.Path()),
+			[]string{file.Key},
+		)
+		volumes = append(volumes, realmVolume.Volume())
+		volumeMounts = append(volumeMounts, realmVolume.VolumeMount())
+	}
+
 	return volumes, volumeMounts
 }
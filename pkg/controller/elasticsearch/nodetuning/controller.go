@@ -0,0 +1,92 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package nodetuning
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/operator"
+	"github.com/elastic/cloud-on-k8s/pkg/utils/k8s"
+	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+)
+
+const name = "node-tuning-controller"
+
+// ReconcileNodeTuning reconciles the node tuning DaemonSet in the operator namespace.
+type ReconcileNodeTuning struct {
+	k8s.Client
+	namespace string
+	image     string
+	// iteration is the number of times this controller has run its Reconcile method.
+	iteration int64
+}
+
+// Reconcile creates the node tuning DaemonSet, or restores it if it was modified or deleted.
+func (r *ReconcileNodeTuning) Reconcile(request reconcile.Request) (reconcile.Result, error) {
+	// atomically update the iteration to support concurrent runs.
+	currentIteration := atomic.AddInt64(&r.iteration, 1)
+	iterationStartTime := time.Now()
+	log.Info("Start reconcile iteration", "iteration", currentIteration, "namespace", request.Namespace, "daemonset_name", request.Name)
+	defer func() {
+		log.Info("End reconcile iteration", "iteration", currentIteration, "took", time.Since(iterationStartTime), "namespace", request.Namespace, "daemonset_name", request.Name)
+	}()
+
+	return reconcile.Result{}, ReconcileDaemonSet(r, r.namespace, r.image)
+}
+
+// Add creates a new node tuning controller and adds it to the Manager if the node tuning DaemonSet is enabled.
+func Add(mgr manager.Manager, params operator.Parameters) error {
+	if !params.RestrictedMode.NodeTuning {
+		return nil
+	}
+	// the manager cache may be restricted to a managed namespace that is not the operator namespace:
+	// read and write the DaemonSet directly through the API server
+	c, err := client.New(mgr.GetConfig(), client.Options{Scheme: mgr.GetScheme()})
+	if err != nil {
+		return err
+	}
+	r := &ReconcileNodeTuning{
+		Client:    k8s.WrapClient(c),
+		namespace: params.OperatorNamespace,
+		image:     params.RestrictedMode.NodeTuningImage,
+	}
+	return add(mgr, r)
+}
+
+func add(mgr manager.Manager, r *ReconcileNodeTuning) error {
+	c, err := controller.New(name, mgr, controller.Options{Reconciler: r})
+	if err != nil {
+		return err
+	}
+
+	// Watch the node tuning DaemonSet to restore it if it is modified or deleted
+	if err := c.Watch(&source.Kind{Type: &appsv1.DaemonSet{}}, &handler.EnqueueRequestsFromMapFunc{
+		ToRequests: handler.ToRequestsFunc(func(obj handler.MapObject) []reconcile.Request {
+			if obj.Meta.GetNamespace() != r.namespace || obj.Meta.GetName() != DaemonSetName {
+				return nil
+			}
+			return []reconcile.Request{{NamespacedName: types.NamespacedName{Namespace: r.namespace, Name: DaemonSetName}}}
+		}),
+	}); err != nil {
+		return err
+	}
+
+	// Trigger a first reconciliation at startup, the DaemonSet may not exist yet
+	expected := NewDaemonSet(r.namespace, r.image)
+	evtChan := make(chan event.GenericEvent, 1)
+	evtChan <- event.GenericEvent{Meta: &expected.ObjectMeta, Object: &expected}
+	return c.Watch(&source.Channel{Source: evtChan}, &handler.EnqueueRequestForObject{})
+}
+
+var _ reconcile.Reconciler = &ReconcileNodeTuning{}
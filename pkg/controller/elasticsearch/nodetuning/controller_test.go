@@ -0,0 +1,54 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package nodetuning
+
+import (
+	"testing"
+
+	"github.com/elastic/cloud-on-k8s/pkg/utils/k8s"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func TestReconcileNodeTuning_Reconcile(t *testing.T) {
+	r := &ReconcileNodeTuning{
+		Client:    k8s.WrapClient(fake.NewFakeClient()),
+		namespace: testNamespace,
+		image:     "my-image",
+	}
+	request := reconcile.Request{NamespacedName: types.NamespacedName{Namespace: testNamespace, Name: DaemonSetName}}
+	expected := NewDaemonSet(testNamespace, "my-image")
+	requireExpected := func() appsv1.DaemonSet {
+		var actual appsv1.DaemonSet
+		require.NoError(t, r.Get(request.NamespacedName, &actual))
+		assert.Equal(t, expected.Labels, actual.Labels)
+		assert.Equal(t, expected.Spec, actual.Spec)
+		return actual
+	}
+
+	// the DaemonSet is created
+	_, err := r.Reconcile(request)
+	require.NoError(t, err)
+	actual := requireExpected()
+
+	// the DaemonSet is restored if modified
+	modified := NewDaemonSet(testNamespace, "other-image")
+	actual.Labels = modified.Labels
+	actual.Spec = modified.Spec
+	require.NoError(t, r.Update(&actual))
+	_, err = r.Reconcile(request)
+	require.NoError(t, err)
+	actual = requireExpected()
+
+	// the DaemonSet is recreated if deleted
+	require.NoError(t, r.Delete(&actual))
+	_, err = r.Reconcile(request)
+	require.NoError(t, err)
+	requireExpected()
+}
@@ -0,0 +1,111 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package nodetuning
+
+import (
+	"fmt"
+
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/hash"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/initcontainer"
+	"github.com/elastic/cloud-on-k8s/pkg/utils/k8s"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	logf "sigs.k8s.io/controller-runtime/pkg/runtime/log"
+)
+
+const (
+	// DaemonSetName is the name of the DaemonSet tuning the Kubernetes nodes.
+	DaemonSetName = "elastic-node-tuning"
+	// Type is the value of the type label of the node tuning pods.
+	Type = "node-tuning"
+	// DefaultImage is the default image of the node tuning pods.
+	DefaultImage = "busybox:1.31"
+
+	sysctlContainerName = "sysctl"
+	sleepContainerName  = "sleep"
+)
+
+var log = logf.Log.WithName("node-tuning")
+
+// NewDaemonSet returns a DaemonSet running one pod per Kubernetes node, setting vm.max_map_count to the value
+// recommended for Elasticsearch in a privileged init container, then sleeping.
+// It allows the Elasticsearch pods to memory-map their index files without running privileged containers themselves.
+func NewDaemonSet(namespace string, image string) appsv1.DaemonSet {
+	labels := map[string]string{common.TypeLabelName: Type}
+	privileged := true
+	runAsUser := int64(0)
+	cpu := resource.MustParse("10m")
+	memory := resource.MustParse("16Mi")
+	resources := corev1.ResourceRequirements{
+		Requests: corev1.ResourceList{corev1.ResourceCPU: cpu, corev1.ResourceMemory: memory},
+		Limits:   corev1.ResourceList{corev1.ResourceCPU: cpu, corev1.ResourceMemory: memory},
+	}
+	template := corev1.PodTemplateSpec{
+		ObjectMeta: metav1.ObjectMeta{Labels: labels},
+		Spec: corev1.PodSpec{
+			InitContainers: []corev1.Container{{
+				Name:  sysctlContainerName,
+				Image: image,
+				// only raise the value, to not interfere with a larger value set on purpose
+				Command: []string{"sh", "-c", fmt.Sprintf(
+					"[ $(sysctl -n vm.max_map_count) -ge %[1]d ] || sysctl -w vm.max_map_count=%[1]d",
+					initcontainer.VMMaxMapCount,
+				)},
+				SecurityContext: &corev1.SecurityContext{
+					Privileged: &privileged,
+					RunAsUser:  &runAsUser,
+				},
+				Resources: resources,
+			}},
+			Containers: []corev1.Container{{
+				Name:      sleepContainerName,
+				Image:     image,
+				Command:   []string{"sh", "-c", "trap exit TERM; while true; do sleep 3600 & wait; done"},
+				Resources: resources,
+			}},
+			// run on all the Kubernetes nodes Elasticsearch pods may be scheduled on
+			Tolerations: []corev1.Toleration{{Operator: corev1.TolerationOpExists}},
+		},
+	}
+	return appsv1.DaemonSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: namespace,
+			Name:      DaemonSetName,
+			Labels:    hash.SetTemplateHashLabel(map[string]string{common.TypeLabelName: Type}, template),
+		},
+		Spec: appsv1.DaemonSetSpec{
+			Selector: &metav1.LabelSelector{MatchLabels: labels},
+			Template: template,
+		},
+	}
+}
+
+// ReconcileDaemonSet creates the node tuning DaemonSet in the given namespace, or updates it if it does not match
+// the expected specification.
+func ReconcileDaemonSet(c k8s.Client, namespace string, image string) error {
+	expected := NewDaemonSet(namespace, image)
+
+	var actual appsv1.DaemonSet
+	err := c.Get(types.NamespacedName{Namespace: namespace, Name: expected.Name}, &actual)
+	if err != nil && errors.IsNotFound(err) {
+		log.Info("Creating node tuning DaemonSet", "namespace", namespace, "name", expected.Name)
+		return c.Create(&expected)
+	} else if err != nil {
+		return err
+	}
+
+	if hash.GetTemplateHashLabel(actual.Labels) == hash.GetTemplateHashLabel(expected.Labels) {
+		return nil
+	}
+	log.Info("Updating node tuning DaemonSet", "namespace", namespace, "name", expected.Name)
+	actual.Labels = expected.Labels
+	actual.Spec = expected.Spec
+	return c.Update(&actual)
+}
@@ -0,0 +1,65 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package nodetuning
+
+import (
+	"testing"
+
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/hash"
+	"github.com/elastic/cloud-on-k8s/pkg/utils/k8s"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+const testNamespace = "elastic-system"
+
+func TestNewDaemonSet(t *testing.T) {
+	ds := NewDaemonSet(testNamespace, "my-image")
+	// the selector must match the pods, and not include the template hash
+	assert.Equal(t, ds.Spec.Template.Labels, ds.Spec.Selector.MatchLabels)
+	assert.NotContains(t, ds.Spec.Selector.MatchLabels, hash.TemplateHashLabelName)
+	assert.NotEmpty(t, hash.GetTemplateHashLabel(ds.Labels))
+	// only the init container is privileged
+	require.Len(t, ds.Spec.Template.Spec.InitContainers, 1)
+	assert.True(t, *ds.Spec.Template.Spec.InitContainers[0].SecurityContext.Privileged)
+	assert.Contains(t, ds.Spec.Template.Spec.InitContainers[0].Command[2], "sysctl -w vm.max_map_count=262144")
+	require.Len(t, ds.Spec.Template.Spec.Containers, 1)
+	assert.Nil(t, ds.Spec.Template.Spec.Containers[0].SecurityContext)
+	assert.Equal(t, "my-image", ds.Spec.Template.Spec.Containers[0].Image)
+}
+
+func TestReconcileDaemonSet(t *testing.T) {
+	outdated := NewDaemonSet(testNamespace, "old-image")
+	tests := []struct {
+		name     string
+		initObjs []runtime.Object
+	}{
+		{
+			name: "create the DaemonSet",
+		},
+		{
+			name:     "update the DaemonSet",
+			initObjs: []runtime.Object{&outdated},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := k8s.WrapClient(fake.NewFakeClient(tt.initObjs...))
+			require.NoError(t, ReconcileDaemonSet(c, testNamespace, "my-image"))
+			// reconciling again is a no-op
+			require.NoError(t, ReconcileDaemonSet(c, testNamespace, "my-image"))
+
+			var actual appsv1.DaemonSet
+			require.NoError(t, c.Get(types.NamespacedName{Namespace: testNamespace, Name: DaemonSetName}, &actual))
+			expected := NewDaemonSet(testNamespace, "my-image")
+			assert.Equal(t, expected.Labels, actual.Labels)
+			assert.Equal(t, expected.Spec, actual.Spec)
+		})
+	}
+}
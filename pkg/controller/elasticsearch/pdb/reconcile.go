@@ -11,6 +11,7 @@ import (
 	"github.com/elastic/cloud-on-k8s/pkg/apis/elasticsearch/v1alpha1"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/common/defaults"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/common/reconciler"
+	esclient "github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/client"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/label"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/name"
 	"github.com/elastic/cloud-on-k8s/pkg/utils/k8s"
@@ -18,75 +19,116 @@ import (
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
-// Reconcile ensures that a PodDisruptionBudget exists for this cluster according to the spec.
+// noDisruption is the max unavailable pods of a default budget when the cluster is not green.
+var noDisruption = intstr.FromInt(0)
+
+// Reconcile ensures that the PodDisruptionBudgets of this cluster exist according to the spec.
+//
+// Unless maxUnavailable or minAvailable are specified, the default budgets allow a single disruption if the
+// cluster is green, none otherwise: the health is not known yet, or a disruption may make the last copy of a
+// shard unavailable. Health changes trigger a reconciliation, so the budgets follow the observed health.
 //
-// If the spec has disabled the default PDB, it will ensure it does not exist
+// If the spec has disabled the default PDB, it will ensure it does not exist.
 func Reconcile(
 	c k8s.Client,
 	scheme *runtime.Scheme,
 	es v1alpha1.Elasticsearch,
+	health *esclient.Health,
 ) error {
-	disabled := false
+	expected := expectedBudgets(es, health)
+	for i := range expected {
+		if err := reconcileBudget(c, scheme, es, expected[i]); err != nil {
+			return err
+		}
+	}
+	return deleteUnexpectedBudgets(c, es, expected)
+}
 
+// expectedBudgets returns the PodDisruptionBudgets expected for the given cluster: none if the default PDB is
+// disabled, one per NodeSpec if requested, a single one for the whole cluster otherwise.
+func expectedBudgets(es v1alpha1.Elasticsearch, health *esclient.Health) []v1beta1.PodDisruptionBudget {
 	template := es.Spec.PodDisruptionBudget
 	if template != nil {
 		// clone to avoid accidentally overwriting template fields
 		template = template.DeepCopy()
-
 		emptyTemplate := commonv1alpha1.PodDisruptionBudgetTemplate{}
 		if reflect.DeepEqual(&emptyTemplate, template) {
-			disabled = true
+			// disabled
+			return nil
 		}
 	} else {
 		template = &commonv1alpha1.PodDisruptionBudgetTemplate{}
 	}
 
-	var objectMeta metav1.ObjectMeta
-	if template != nil {
-		objectMeta = *template.ObjectMeta.DeepCopy()
+	if !es.Spec.PodDisruptionBudgetPerNodeSpec {
+		return []v1beta1.PodDisruptionBudget{
+			newBudget(es, *template, health, name.DefaultPodDisruptionBudget(es.Name), nil),
+		}
 	}
-	objectMeta.Name = name.DefaultPodDisruptionBudget(es.Name)
-	objectMeta.Namespace = es.Namespace
-	objectMeta.Labels = defaults.SetDefaultLabels(objectMeta.Labels, label.NewLabels(k8s.ExtractNamespacedName(&es)))
 
-	expected := v1beta1.PodDisruptionBudget{
-		ObjectMeta: objectMeta,
-		Spec:       template.Spec,
+	budgets := make([]v1beta1.PodDisruptionBudget, 0, len(es.Spec.Nodes))
+	for _, nodeSpec := range es.Spec.Nodes {
+		ssetName := name.StatefulSet(es.Name, nodeSpec.Name)
+		budget := newBudget(es, *template, health, ssetName, map[string]string{
+			label.StatefulSetNameLabelName: ssetName,
+		})
+		// the selector of the template would select the pods of other NodeSpecs
+		budget.Spec.Selector = &metav1.LabelSelector{
+			MatchLabels: map[string]string{
+				label.ClusterNameLabelName:     es.Name,
+				label.StatefulSetNameLabelName: ssetName,
+			},
+		}
+		budgets = append(budgets, budget)
 	}
+	return budgets
+}
 
-	if disabled {
-		// delete the default budget if it exists.
-		//
-		// we do this by getting first because that is a local cache read,
-		// versus a Delete call, which would hit the API.
-
-		if err := c.Get(k8s.ExtractNamespacedName(&expected), &expected); err != nil && !errors.IsNotFound(err) {
-			return err
-		} else if errors.IsNotFound(err) {
-			// already deleted, which is fine
-			return nil
-		}
+// newBudget builds a PodDisruptionBudget from the template, with defaults adjusted to the cluster health.
+func newBudget(
+	es v1alpha1.Elasticsearch,
+	template commonv1alpha1.PodDisruptionBudgetTemplate,
+	health *esclient.Health,
+	budgetName string,
+	extraLabels map[string]string,
+) v1beta1.PodDisruptionBudget {
+	objectMeta := *template.ObjectMeta.DeepCopy()
+	objectMeta.Name = budgetName
+	objectMeta.Namespace = es.Namespace
+	objectMeta.Labels = defaults.SetDefaultLabels(objectMeta.Labels, label.NewLabels(k8s.ExtractNamespacedName(&es)))
+	for k, v := range extraLabels {
+		objectMeta.Labels[k] = v
+	}
 
-		if err := c.Delete(&expected); err != nil && !errors.IsNotFound(err) {
-			return err
-		}
-		return nil
+	budget := v1beta1.PodDisruptionBudget{
+		ObjectMeta: objectMeta,
+		Spec:       *template.Spec.DeepCopy(),
 	}
 
 	// set our defaults
-	if expected.Spec.MaxUnavailable == nil {
-		expected.Spec.MaxUnavailable = &commonv1alpha1.DefaultPodDisruptionBudgetMaxUnavailable
+	if budget.Spec.MaxUnavailable == nil && budget.Spec.MinAvailable == nil {
+		maxUnavailable := noDisruption
+		if health != nil && v1alpha1.ElasticsearchHealth(health.Status) == v1alpha1.ElasticsearchGreenHealth {
+			maxUnavailable = commonv1alpha1.DefaultPodDisruptionBudgetMaxUnavailable
+		}
+		budget.Spec.MaxUnavailable = &maxUnavailable
 	}
-	if expected.Spec.Selector == nil {
-		expected.Spec.Selector = &metav1.LabelSelector{
+	if budget.Spec.Selector == nil {
+		budget.Spec.Selector = &metav1.LabelSelector{
 			MatchLabels: map[string]string{
 				label.ClusterNameLabelName: es.Name,
 			},
 		}
 	}
+	return budget
+}
 
+func reconcileBudget(c k8s.Client, scheme *runtime.Scheme, es v1alpha1.Elasticsearch, expected v1beta1.PodDisruptionBudget) error {
 	var reconciled v1beta1.PodDisruptionBudget
 	return reconciler.ReconcileResource(reconciler.Params{
 		Client:     c,
@@ -100,10 +142,12 @@ func Reconcile(
 					return true
 				}
 			}
-
 			return !reflect.DeepEqual(expected.Spec, reconciled.Spec)
 		},
 		UpdateReconciled: func() {
+			if reconciled.Labels == nil {
+				reconciled.Labels = map[string]string{}
+			}
 			for k, v := range expected.Labels {
 				reconciled.Labels[k] = v
 			}
@@ -111,3 +155,47 @@ func Reconcile(
 		},
 	})
 }
+
+// deleteUnexpectedBudgets deletes the default budget and the per-NodeSpec budgets of the cluster that are not expected
+// anymore, for example because the default PDB was disabled or a NodeSpec was removed.
+func deleteUnexpectedBudgets(c k8s.Client, es v1alpha1.Elasticsearch, expected []v1beta1.PodDisruptionBudget) error {
+	isExpected := make(map[string]bool, len(expected))
+	for _, budget := range expected {
+		isExpected[budget.Name] = true
+	}
+
+	var candidates []v1beta1.PodDisruptionBudget
+	// get the default budget first: it may not carry the cluster labels
+	defaultBudgetName := name.DefaultPodDisruptionBudget(es.Name)
+	var defaultBudget v1beta1.PodDisruptionBudget
+	err := c.Get(types.NamespacedName{Namespace: es.Namespace, Name: defaultBudgetName}, &defaultBudget)
+	if err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+	if err == nil {
+		candidates = append(candidates, defaultBudget)
+	}
+	// per-NodeSpec budgets are the ones controlled by the cluster
+	var budgets v1beta1.PodDisruptionBudgetList
+	if err := c.List(&client.ListOptions{
+		Namespace:     es.Namespace,
+		LabelSelector: label.NewLabelSelectorForElasticsearch(es),
+	}, &budgets); err != nil {
+		return err
+	}
+	for i := range budgets.Items {
+		if budgets.Items[i].Name != defaultBudgetName && metav1.IsControlledBy(&budgets.Items[i], &es) {
+			candidates = append(candidates, budgets.Items[i])
+		}
+	}
+
+	for i := range candidates {
+		if isExpected[candidates[i].Name] {
+			continue
+		}
+		if err := c.Delete(&candidates[i]); err != nil && !errors.IsNotFound(err) {
+			return err
+		}
+	}
+	return nil
+}
@@ -10,6 +10,7 @@ import (
 	commonv1alpha1 "github.com/elastic/cloud-on-k8s/pkg/apis/common/v1alpha1"
 	"github.com/elastic/cloud-on-k8s/pkg/apis/elasticsearch/v1alpha1"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/common/defaults"
+	esclient "github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/client"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/label"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/name"
 	"github.com/elastic/cloud-on-k8s/pkg/utils/k8s"
@@ -35,9 +36,30 @@ func TestReconcile(t *testing.T) {
 		return &i
 	}
 
+	green := &esclient.Health{Status: string(v1alpha1.ElasticsearchGreenHealth)}
+	yellow := &esclient.Health{Status: string(v1alpha1.ElasticsearchYellowHealth)}
+
+	defaultBudget := func(maxUnavailable int) *v1beta1.PodDisruptionBudget {
+		return &v1beta1.PodDisruptionBudget{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: name.DefaultPodDisruptionBudget(esMeta.Name), Namespace: esMeta.Namespace,
+				Labels: label.NewLabels(k8s.ExtractNamespacedName(&esMeta)),
+			},
+			Spec: v1beta1.PodDisruptionBudgetSpec{
+				Selector: &metav1.LabelSelector{
+					MatchLabels: map[string]string{
+						label.ClusterNameLabelName: esMeta.Name,
+					},
+				},
+				MaxUnavailable: intStrRef(intstr.FromInt(maxUnavailable)),
+			},
+		}
+	}
+
 	type args struct {
-		c  k8s.Client
-		es v1alpha1.Elasticsearch
+		c      k8s.Client
+		es     v1alpha1.Elasticsearch
+		health *esclient.Health
 	}
 	tests := []struct {
 		name    string
@@ -46,27 +68,47 @@ func TestReconcile(t *testing.T) {
 		wantErr bool
 	}{
 		{
-			name: "default",
+			name: "default with a green cluster: allow one disruption",
 			args: args{
 				c: k8s.WrapClient(fake.NewFakeClient()),
 				es: v1alpha1.Elasticsearch{
 					ObjectMeta: esMeta,
 				},
+				health: green,
 			},
-			want: &v1beta1.PodDisruptionBudget{
-				ObjectMeta: metav1.ObjectMeta{
-					Name: name.DefaultPodDisruptionBudget(esMeta.Name), Namespace: esMeta.Namespace,
-					Labels: label.NewLabels(k8s.ExtractNamespacedName(&esMeta)),
+			want: defaultBudget(1),
+		},
+		{
+			name: "default with a yellow cluster: allow no disruption",
+			args: args{
+				c: k8s.WrapClient(fake.NewFakeClient()),
+				es: v1alpha1.Elasticsearch{
+					ObjectMeta: esMeta,
 				},
-				Spec: v1beta1.PodDisruptionBudgetSpec{
-					Selector: &metav1.LabelSelector{
-						MatchLabels: map[string]string{
-							label.ClusterNameLabelName: esMeta.Name,
-						},
-					},
-					MaxUnavailable: intStrRef(intstr.FromInt(1)),
+				health: yellow,
+			},
+			want: defaultBudget(0),
+		},
+		{
+			name: "default with an unknown health: allow no disruption",
+			args: args{
+				c: k8s.WrapClient(fake.NewFakeClient()),
+				es: v1alpha1.Elasticsearch{
+					ObjectMeta: esMeta,
 				},
 			},
+			want: defaultBudget(0),
+		},
+		{
+			name: "default budget is updated when the cluster becomes green",
+			args: args{
+				c: k8s.WrapClient(fake.NewFakeClient(defaultBudget(0))),
+				es: v1alpha1.Elasticsearch{
+					ObjectMeta: esMeta,
+				},
+				health: green,
+			},
+			want: defaultBudget(1),
 		},
 		{
 			name: "custom pod disruption budget template",
@@ -96,6 +138,8 @@ func TestReconcile(t *testing.T) {
 						},
 					},
 				},
+				// the health does not affect a custom budget
+				health: yellow,
 			},
 			want: &v1beta1.PodDisruptionBudget{
 				ObjectMeta: metav1.ObjectMeta{
@@ -152,7 +196,7 @@ func TestReconcile(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			err := Reconcile(tt.args.c, scheme.Scheme, tt.args.es)
+			err := Reconcile(tt.args.c, scheme.Scheme, tt.args.es, tt.args.health)
 
 			if (err != nil) != tt.wantErr {
 				t.Errorf("Reconcile() error = %v, wantErr %v", err, tt.wantErr)
@@ -176,3 +220,68 @@ func TestReconcile(t *testing.T) {
 		})
 	}
 }
+
+func TestReconcile_PerNodeSpec(t *testing.T) {
+	require.NoError(t, v1alpha1.AddToScheme(scheme.Scheme))
+	es := v1alpha1.Elasticsearch{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "my-cluster",
+			Namespace: "my-namespace",
+		},
+		Spec: v1alpha1.ElasticsearchSpec{
+			Nodes: []v1alpha1.NodeSpec{
+				{Name: "masters"},
+				{Name: "data"},
+			},
+			PodDisruptionBudgetPerNodeSpec: true,
+		},
+	}
+	controllerRef := metav1.NewControllerRef(&es, v1alpha1.SchemeGroupVersion.WithKind("Elasticsearch"))
+	// the default budget created before per-NodeSpec budgets were requested
+	defaultBudget := &v1beta1.PodDisruptionBudget{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: name.DefaultPodDisruptionBudget(es.Name), Namespace: es.Namespace,
+		},
+	}
+	// the budget of a NodeSpec that does not exist anymore
+	removedNodeSpecBudget := &v1beta1.PodDisruptionBudget{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: name.StatefulSet(es.Name, "removed"), Namespace: es.Namespace,
+			Labels:          label.NewLabels(k8s.ExtractNamespacedName(&es)),
+			OwnerReferences: []metav1.OwnerReference{*controllerRef},
+		},
+	}
+	// a budget labeled with the cluster name, but not managed by the operator
+	userBudget := &v1beta1.PodDisruptionBudget{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "user-budget", Namespace: es.Namespace,
+			Labels: label.NewLabels(k8s.ExtractNamespacedName(&es)),
+		},
+	}
+	c := k8s.WrapClient(fake.NewFakeClient(defaultBudget, removedNodeSpecBudget, userBudget))
+
+	yellow := &esclient.Health{Status: string(v1alpha1.ElasticsearchYellowHealth)}
+	require.NoError(t, Reconcile(c, scheme.Scheme, es, yellow))
+
+	var pdbs v1beta1.PodDisruptionBudgetList
+	require.NoError(t, c.List(&client.ListOptions{}, &pdbs))
+	budgets := make(map[string]v1beta1.PodDisruptionBudget, len(pdbs.Items))
+	for _, pdb := range pdbs.Items {
+		budgets[pdb.Name] = pdb
+	}
+	require.Len(t, budgets, 3)
+	require.Contains(t, budgets, "user-budget")
+	for _, nodeSpec := range es.Spec.Nodes {
+		ssetName := name.StatefulSet(es.Name, nodeSpec.Name)
+		budget, exists := budgets[ssetName]
+		require.True(t, exists)
+		require.Equal(t, ssetName, budget.Labels[label.StatefulSetNameLabelName])
+		require.Equal(t, &metav1.LabelSelector{
+			MatchLabels: map[string]string{
+				label.ClusterNameLabelName:     es.Name,
+				label.StatefulSetNameLabelName: ssetName,
+			},
+		}, budget.Spec.Selector)
+		require.Equal(t, intstr.FromInt(0), *budget.Spec.MaxUnavailable)
+	}
+}
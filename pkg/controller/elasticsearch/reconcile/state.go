@@ -7,9 +7,14 @@ package reconcile
 import (
 	"fmt"
 	"reflect"
+	"sort"
+	"strings"
 
+	commonv1alpha1 "github.com/elastic/cloud-on-k8s/pkg/apis/common/v1alpha1"
 	"github.com/elastic/cloud-on-k8s/pkg/apis/elasticsearch/v1alpha1"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/common/events"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/reconciler"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/common/validation"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/observer"
 	"github.com/elastic/cloud-on-k8s/pkg/utils/k8s"
@@ -22,11 +27,67 @@ type State struct {
 	*events.Recorder
 	cluster v1alpha1.Elasticsearch
 	status  v1alpha1.ElasticsearchStatus
+	// conditions reported during the reconciliation, applied to the status conditions on Apply
+	conditions map[commonv1alpha1.ConditionType]commonv1alpha1.Condition
 }
 
+const (
+	// MigratingDataReason is the reason of the DataMigration condition when data is migrated away from leaving nodes.
+	MigratingDataReason = "MigratingData"
+	// ClusterReadyReason is the reason of the Ready condition when the cluster is ready.
+	ClusterReadyReason = "ClusterReady"
+	// ClusterNotReadyReason is the reason of the Ready condition when the cluster is not ready.
+	ClusterNotReadyReason = "ClusterNotReady"
+)
+
 // NewState creates a new reconcile state based on the given cluster
 func NewState(c v1alpha1.Elasticsearch) *State {
-	return &State{Recorder: events.NewRecorder(), cluster: c, status: *c.Status.DeepCopy()}
+	return &State{
+		Recorder:   events.NewRecorder(),
+		cluster:    c,
+		status:     *c.Status.DeepCopy(),
+		conditions: map[commonv1alpha1.ConditionType]commonv1alpha1.Condition{},
+	}
+}
+
+// ReportCondition records the given condition in the state. If a condition of the same type was already reported
+// during this reconciliation, it is replaced. Conditions not reported keep their current value.
+func (s *State) ReportCondition(condition commonv1alpha1.Condition) {
+	s.conditions[condition.Type] = condition
+}
+
+// UpdateReconciling reports the given Reconciling condition, unless a more specific reason to still be reconciling
+// was reported during this reconciliation and no error occurred.
+func (s *State) UpdateReconciling(condition commonv1alpha1.Condition) {
+	reported, exists := s.conditions[commonv1alpha1.ReconcilingCondition]
+	if exists && reported.IsTrue() && condition.Reason != reconciler.ReconciliationErrorReason {
+		return
+	}
+	s.ReportCondition(condition)
+}
+
+// UpdateReady reports the Ready condition: the cluster is ready if its health is green and no change remains to be
+// performed on its StatefulSets.
+func (s *State) UpdateReady() {
+	switch {
+	case s.conditions[commonv1alpha1.InvalidCondition].IsTrue():
+		s.ReportCondition(commonv1alpha1.NewCondition(
+			commonv1alpha1.ReadyCondition, false, ClusterNotReadyReason, "The Elasticsearch specification is invalid",
+		))
+	case s.status.Health != v1alpha1.ElasticsearchGreenHealth:
+		s.ReportCondition(commonv1alpha1.NewCondition(
+			commonv1alpha1.ReadyCondition, false, ClusterNotReadyReason,
+			fmt.Sprintf("Elasticsearch cluster health is %s", s.status.Health),
+		))
+	case len(s.status.PendingChanges) > 0:
+		s.ReportCondition(commonv1alpha1.NewCondition(
+			commonv1alpha1.ReadyCondition, false, ClusterNotReadyReason, "Changes are pending on the StatefulSets",
+		))
+	default:
+		s.ReportCondition(commonv1alpha1.NewCondition(
+			commonv1alpha1.ReadyCondition, true, ClusterReadyReason, "Elasticsearch cluster health is green",
+		))
+	}
 }
 
 // AvailableElasticsearchNodes filters a slice of pods for the ones that are ready.
@@ -96,6 +157,10 @@ func (s *State) UpdateElasticsearchMigrating(
 		events.EventReasonDelayed,
 		"Requested topology change delayed by data migration",
 	)
+	s.ReportCondition(commonv1alpha1.NewCondition(
+		commonv1alpha1.DataMigrationCondition, true, MigratingDataReason,
+		"Data is being migrated away from the nodes to remove",
+	))
 	return s.updateWithPhase(v1alpha1.ElasticsearchMigratingDataPhase, resourcesState, observedState)
 }
 
@@ -106,6 +171,11 @@ func (s *State) UpdateZen1MinimumMasterNodes(value int) {
 	}
 }
 
+// UpdatePendingChanges records the changes still to perform on the StatefulSets in the state.
+func (s *State) UpdatePendingChanges(changes []v1alpha1.StatefulSetChanges) {
+	s.status.PendingChanges = changes
+}
+
 // GetZen1MinimumMasterNodes returns the current minimum master nodes as it is stored in the state.
 func (s *State) GetZen1MinimumMasterNodes() int {
 	return s.status.ZenDiscovery.MinimumMasterNodes
@@ -117,6 +187,8 @@ func (s *State) GetZen1MinimumMasterNodes() int {
 func (s *State) Apply() ([]events.Event, *v1alpha1.Elasticsearch) {
 	previous := s.cluster.Status
 	current := s.status
+	current.ObservedGeneration = s.cluster.Generation
+	current.Conditions = s.applyConditions(current.Conditions)
 	if reflect.DeepEqual(previous, current) {
 		return s.Events(), nil
 	}
@@ -151,9 +223,35 @@ func (s *State) Apply() ([]events.Event, *v1alpha1.Elasticsearch) {
 	return s.Events(), &s.cluster
 }
 
+// applyConditions returns the given conditions updated with the conditions reported during the reconciliation,
+// sorted by type for a stable order.
+func (s *State) applyConditions(conditions commonv1alpha1.Conditions) commonv1alpha1.Conditions {
+	conditions = conditions.DeepCopy()
+	types := make([]string, 0, len(s.conditions))
+	for conditionType := range s.conditions {
+		types = append(types, string(conditionType))
+	}
+	sort.Strings(types)
+	for _, conditionType := range types {
+		conditions = conditions.Set(s.conditions[commonv1alpha1.ConditionType(conditionType)])
+	}
+	return conditions
+}
+
+// UpdateElasticsearchInvalid marks Elasticsearch as invalid in the resource status, and emits an event per violation.
 func (s *State) UpdateElasticsearchInvalid(results []validation.Result) {
 	s.status.Phase = v1alpha1.ElasticsearchResourceInvalid
+	reasons := make([]string, 0, len(results))
 	for _, r := range results {
 		s.AddEvent(corev1.EventTypeWarning, events.EventReasonValidation, r.Reason)
+		reasons = append(reasons, r.Reason)
 	}
+	s.ReportCondition(commonv1alpha1.NewCondition(
+		commonv1alpha1.InvalidCondition, true, common.ValidationFailedReason, strings.Join(reasons, ", "),
+	))
+}
+
+// UpdateElasticsearchValid reports the specification of Elasticsearch as valid.
+func (s *State) UpdateElasticsearchValid() {
+	s.ReportCondition(commonv1alpha1.NewCondition(commonv1alpha1.InvalidCondition, false, common.ValidationPassedReason, ""))
 }
@@ -12,10 +12,13 @@ import (
 	v1alpha12 "github.com/elastic/cloud-on-k8s/pkg/apis/common/v1alpha1"
 	"github.com/elastic/cloud-on-k8s/pkg/apis/elasticsearch/v1alpha1"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/common/events"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/reconciler"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/validation"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/client"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/observer"
 	"github.com/stretchr/testify/assert"
 	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 )
 
@@ -454,3 +457,101 @@ func TestState_UpdateElasticsearchMigrating(t *testing.T) {
 		})
 	}
 }
+
+func TestState_Conditions(t *testing.T) {
+	transitionTime := metav1.NewTime(time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC))
+	cluster := v1alpha1.Elasticsearch{
+		ObjectMeta: metav1.ObjectMeta{Generation: 3},
+		Status: v1alpha1.ElasticsearchStatus{
+			ReconcilerStatus: v1alpha12.ReconcilerStatus{
+				ObservedGeneration: 2,
+				Conditions: v1alpha12.Conditions{
+					{Type: v1alpha12.ReadyCondition, Status: corev1.ConditionTrue, LastTransitionTime: transitionTime},
+					{Type: v1alpha12.DataMigrationCondition, Status: corev1.ConditionFalse, LastTransitionTime: transitionTime},
+				},
+			},
+			Health: v1alpha1.ElasticsearchGreenHealth,
+		},
+	}
+
+	s := NewState(cluster)
+	s.UpdateElasticsearchValid()
+	s.ReportCondition(v1alpha12.NewCondition(v1alpha12.ReconcilingCondition, true, "DownscaleDelayed", "reason"))
+	s.UpdateReconciling(v1alpha12.NewCondition(v1alpha12.ReconcilingCondition, true, reconciler.ReconciliationInProgressReason, ""))
+	s.UpdateElasticsearchMigrating(ResourcesState{}, observer.State{
+		ClusterHealth: &client.Health{Status: "green"},
+	})
+	s.UpdateReady()
+	_, updated := s.Apply()
+
+	assert.NotNil(t, updated)
+	assert.Equal(t, int64(3), updated.Status.ObservedGeneration)
+	conditions := updated.Status.Conditions
+	// existing conditions keep their order, new ones are appended sorted by type
+	var types []v1alpha12.ConditionType
+	for _, c := range conditions {
+		types = append(types, c.Type)
+	}
+	assert.Equal(t, []v1alpha12.ConditionType{
+		v1alpha12.ReadyCondition, v1alpha12.DataMigrationCondition, v1alpha12.InvalidCondition, v1alpha12.ReconcilingCondition,
+	}, types)
+	// status unchanged: transition time preserved
+	assert.Equal(t, transitionTime, conditions.Get(v1alpha12.ReadyCondition).LastTransitionTime)
+	// status changed: new transition time
+	assert.True(t, conditions.IsTrue(v1alpha12.DataMigrationCondition))
+	assert.NotEqual(t, transitionTime, conditions.Get(v1alpha12.DataMigrationCondition).LastTransitionTime)
+	// the more specific Reconciling reason is kept
+	assert.Equal(t, "DownscaleDelayed", conditions.Get(v1alpha12.ReconcilingCondition).Reason)
+	assert.False(t, conditions.IsTrue(v1alpha12.InvalidCondition))
+
+	// an error takes precedence over the reported reason
+	s = NewState(cluster)
+	s.ReportCondition(v1alpha12.NewCondition(v1alpha12.ReconcilingCondition, true, "DownscaleDelayed", "reason"))
+	s.UpdateReconciling(v1alpha12.NewCondition(v1alpha12.ReconcilingCondition, true, reconciler.ReconciliationErrorReason, "error"))
+	_, updated = s.Apply()
+	assert.Equal(t, reconciler.ReconciliationErrorReason, updated.Status.Conditions.Get(v1alpha12.ReconcilingCondition).Reason)
+}
+
+func TestState_UpdateReady(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  v1alpha1.ElasticsearchStatus
+		invalid bool
+		want    corev1.ConditionStatus
+	}{
+		{
+			name:   "green health and no pending change",
+			status: v1alpha1.ElasticsearchStatus{Health: v1alpha1.ElasticsearchGreenHealth},
+			want:   corev1.ConditionTrue,
+		},
+		{
+			name:   "yellow health",
+			status: v1alpha1.ElasticsearchStatus{Health: v1alpha1.ElasticsearchYellowHealth},
+			want:   corev1.ConditionFalse,
+		},
+		{
+			name: "pending changes",
+			status: v1alpha1.ElasticsearchStatus{
+				Health:         v1alpha1.ElasticsearchGreenHealth,
+				PendingChanges: []v1alpha1.StatefulSetChanges{{StatefulSet: "a"}},
+			},
+			want: corev1.ConditionFalse,
+		},
+		{
+			name:    "invalid specification",
+			status:  v1alpha1.ElasticsearchStatus{Health: v1alpha1.ElasticsearchGreenHealth},
+			invalid: true,
+			want:    corev1.ConditionFalse,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewState(v1alpha1.Elasticsearch{Status: tt.status})
+			if tt.invalid {
+				s.UpdateElasticsearchInvalid([]validation.Result{{Reason: "invalid"}})
+			}
+			s.UpdateReady()
+			assert.Equal(t, tt.want, s.conditions[v1alpha12.ReadyCondition].Status)
+		})
+	}
+}
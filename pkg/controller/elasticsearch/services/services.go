@@ -10,9 +10,14 @@ import (
 	"strconv"
 
 	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/runtime/log"
 
 	"github.com/elastic/cloud-on-k8s/pkg/apis/elasticsearch/v1alpha1"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/common/defaults"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/label"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/name"
@@ -25,6 +30,8 @@ const (
 	globalServiceSuffix = ".svc"
 )
 
+var log = logf.Log.WithName("services")
+
 // ExternalServiceName returns the name for the external service
 // associated to this cluster
 func ExternalServiceName(esName string) string {
@@ -37,7 +44,8 @@ func ExternalServiceURL(es v1alpha1.Elasticsearch) string {
 }
 
 // NewExternalService returns the external service associated to the given cluster
-// It is used by users to perform requests against one of the cluster nodes.
+// It is used by users to perform requests against one of the cluster nodes, or against one of the nodes
+// of the NodeSpecs listed in ExternalServiceNodeSpecs.
 func NewExternalService(es v1alpha1.Elasticsearch) *corev1.Service {
 	nsn := k8s.ExtractNamespacedName(&es)
 
@@ -50,15 +58,135 @@ func NewExternalService(es v1alpha1.Elasticsearch) *corev1.Service {
 	svc.ObjectMeta.Name = ExternalServiceName(es.Name)
 
 	labels := label.NewLabels(nsn)
-	ports := []corev1.ServicePort{
+	selector := label.NewLabels(nsn)
+	if len(es.Spec.ExternalServiceNodeSpecs) > 0 {
+		selector[label.ExternalServiceLabelName] = "true"
+	}
+
+	return defaults.SetServiceDefaults(&svc, labels, selector, httpPorts())
+}
+
+// NewNodeSpecService returns the HTTP service selecting only the nodes of the given NodeSpec,
+// or nil if the NodeSpec does not specify any.
+func NewNodeSpecService(es v1alpha1.Elasticsearch, nodeSpec v1alpha1.NodeSpec) *corev1.Service {
+	if nodeSpec.Service == nil {
+		return nil
+	}
+	nsn := k8s.ExtractNamespacedName(&es)
+
+	svc := corev1.Service{
+		ObjectMeta: *nodeSpec.Service.ObjectMeta.DeepCopy(),
+		Spec:       *nodeSpec.Service.Spec.DeepCopy(),
+	}
+
+	svc.ObjectMeta.Namespace = es.Namespace
+	svc.ObjectMeta.Name = name.NodeSpecHTTPService(es.Name, nodeSpec.Name)
+
+	labels := label.NewLabels(nsn)
+	labels[label.NodeSpecServiceLabelName] = nodeSpec.Name
+	selector := label.NewStatefulSetLabels(nsn, name.StatefulSet(es.Name, nodeSpec.Name))
+
+	return defaults.SetServiceDefaults(&svc, labels, selector, httpPorts())
+}
+
+// NewNodeSpecServices returns the HTTP services of the NodeSpecs specifying one.
+func NewNodeSpecServices(es v1alpha1.Elasticsearch) []corev1.Service {
+	var svcs []corev1.Service
+	for _, nodeSpec := range es.Spec.Nodes {
+		if svc := NewNodeSpecService(es, nodeSpec); svc != nil {
+			svcs = append(svcs, *svc)
+		}
+	}
+	return svcs
+}
+
+func httpPorts() []corev1.ServicePort {
+	return []corev1.ServicePort{
 		{
 			Name:     "https",
 			Protocol: corev1.ProtocolTCP,
 			Port:     network.HTTPPort,
 		},
 	}
+}
+
+// ReconcileNodeSpecServices creates or updates the HTTP services of the NodeSpecs specifying one, and deletes
+// the services of the NodeSpecs that do not anymore. It returns the reconciled services.
+func ReconcileNodeSpecServices(c k8s.Client, scheme *runtime.Scheme, es v1alpha1.Elasticsearch) ([]corev1.Service, error) {
+	expected := NewNodeSpecServices(es)
+	reconciled := make([]corev1.Service, 0, len(expected))
+	expectedNames := make([]string, 0, len(expected))
+	for i := range expected {
+		svc, err := common.ReconcileService(c, scheme, &expected[i], &es)
+		if err != nil {
+			return nil, err
+		}
+		reconciled = append(reconciled, *svc)
+		expectedNames = append(expectedNames, svc.Name)
+	}
 
-	return defaults.SetServiceDefaults(&svc, labels, labels, ports)
+	var actual corev1.ServiceList
+	if err := c.List(&client.ListOptions{
+		Namespace:     es.Namespace,
+		LabelSelector: label.NewLabelSelectorForElasticsearch(es),
+	}, &actual); err != nil {
+		return nil, err
+	}
+	for i := range actual.Items {
+		svc := actual.Items[i]
+		if _, isNodeSpecService := svc.Labels[label.NodeSpecServiceLabelName]; !isNodeSpecService ||
+			stringsutil.StringInSlice(svc.Name, expectedNames) {
+			continue
+		}
+		log.Info("Deleting NodeSpec service", "namespace", svc.Namespace, "name", svc.Name)
+		if err := c.Delete(&svc); err != nil && !apierrors.IsNotFound(err) {
+			return nil, err
+		}
+	}
+	return reconciled, nil
+}
+
+// ReconcileExternalServiceLabels sets the ExternalServiceLabelName label on the pods of the NodeSpecs backing the
+// external HTTP service, and removes it from the other pods. The pods are labeled in place rather than through the
+// StatefulSets pod template: the external service selects the labeled pods as soon as the NodeSpecs backing it
+// change, without waiting for a rolling restart which cannot happen while the service has no endpoint.
+func ReconcileExternalServiceLabels(c k8s.Client, es v1alpha1.Elasticsearch) error {
+	var pods corev1.PodList
+	if err := c.List(&client.ListOptions{
+		Namespace:     es.Namespace,
+		LabelSelector: label.NewLabelSelectorForElasticsearch(es),
+	}, &pods); err != nil {
+		return err
+	}
+	for i := range pods.Items {
+		pod := pods.Items[i]
+		labeled := pod.Labels[label.ExternalServiceLabelName] == "true"
+		backing := backsExternalService(es, pod)
+		if labeled == backing {
+			continue
+		}
+		if backing {
+			pod.Labels[label.ExternalServiceLabelName] = "true"
+		} else {
+			delete(pod.Labels, label.ExternalServiceLabelName)
+		}
+		log.V(1).Info("Updating external service label", "namespace", pod.Namespace, "pod_name", pod.Name, "backing", backing)
+		if err := c.Update(&pod); err != nil && !apierrors.IsNotFound(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// backsExternalService returns true if the given pod belongs to one of the NodeSpecs listed in
+// ExternalServiceNodeSpecs.
+func backsExternalService(es v1alpha1.Elasticsearch, pod corev1.Pod) bool {
+	for _, nodeSpecName := range es.Spec.ExternalServiceNodeSpecs {
+		if pod.Labels[label.StatefulSetNameLabelName] == name.StatefulSet(es.Name, nodeSpecName) {
+			return true
+		}
+	}
+	return false
 }
 
 // IsServiceReady checks if a service has one or more ready endpoints.
@@ -107,12 +235,37 @@ func ElasticsearchURL(es v1alpha1.Elasticsearch, pods []corev1.Pod) string {
 	}
 	if schemeChange {
 		// switch to sending requests directly to a random pod instead of going through the service
-		randomPod := pods[rand.Intn(len(pods))]
-		scheme, hasScheme := randomPod.Labels[label.HTTPSchemeLabelName]
-		sset, hasSset := randomPod.Labels[label.StatefulSetNameLabelName]
-		if hasScheme && hasSset {
-			return fmt.Sprintf("%s://%s.%s.%s:%d", scheme, randomPod.Name, sset, randomPod.Namespace, network.HTTPPort)
+		if backing := podsBackingExternalService(es, pods); len(backing) > 0 {
+			pods = backing
+		}
+		if url := ElasticsearchPodURL(pods[rand.Intn(len(pods))]); url != "" {
+			return url
 		}
 	}
 	return ExternalServiceURL(es)
 }
+
+// ElasticsearchPodURL returns the URL to request the given Elasticsearch Pod directly, through the headless service
+// of its StatefulSet. It returns an empty string if the Pod does not have the labels required to build the URL.
+func ElasticsearchPodURL(pod corev1.Pod) string {
+	scheme, hasScheme := pod.Labels[label.HTTPSchemeLabelName]
+	sset, hasSset := pod.Labels[label.StatefulSetNameLabelName]
+	if !hasScheme || !hasSset {
+		return ""
+	}
+	return fmt.Sprintf("%s://%s.%s.%s:%d", scheme, pod.Name, sset, pod.Namespace, network.HTTPPort)
+}
+
+// podsBackingExternalService filters the given pods to the ones backing the external service.
+func podsBackingExternalService(es v1alpha1.Elasticsearch, pods []corev1.Pod) []corev1.Pod {
+	if len(es.Spec.ExternalServiceNodeSpecs) == 0 {
+		return pods
+	}
+	backing := make([]corev1.Pod, 0, len(pods))
+	for _, p := range pods {
+		if p.Labels[label.ExternalServiceLabelName] == "true" {
+			backing = append(backing, p)
+		}
+	}
+	return backing
+}
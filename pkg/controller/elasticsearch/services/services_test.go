@@ -7,11 +7,19 @@ package services
 import (
 	"testing"
 
+	commonv1alpha1 "github.com/elastic/cloud-on-k8s/pkg/apis/common/v1alpha1"
 	"github.com/elastic/cloud-on-k8s/pkg/apis/elasticsearch/v1alpha1"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/label"
+	"github.com/elastic/cloud-on-k8s/pkg/utils/k8s"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 )
 
 func TestExternalServiceURL(t *testing.T) {
@@ -152,3 +160,240 @@ func TestElasticsearchURL(t *testing.T) {
 		})
 	}
 }
+
+func TestElasticsearchPodURL(t *testing.T) {
+	tests := []struct {
+		name string
+		pod  corev1.Pod
+		want string
+	}{
+		{
+			name: "pod address through the StatefulSet headless service",
+			pod: corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "my-sset-0",
+					Namespace: "my-ns",
+					Labels: map[string]string{
+						label.HTTPSchemeLabelName:      "https",
+						label.StatefulSetNameLabelName: "my-sset",
+					},
+				},
+			},
+			want: "https://my-sset-0.my-sset.my-ns:9200",
+		},
+		{
+			name: "missing StatefulSet label",
+			pod: corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "my-sset-0",
+					Namespace: "my-ns",
+					Labels: map[string]string{
+						label.HTTPSchemeLabelName: "https",
+					},
+				},
+			},
+			want: "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, ElasticsearchPodURL(tt.pod))
+		})
+	}
+}
+
+func TestNewExternalService(t *testing.T) {
+	es := v1alpha1.Elasticsearch{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "es"}}
+	svc := NewExternalService(es)
+	require.Equal(t, "es-es-http", svc.Name)
+	require.Equal(t, label.NewLabels(k8s.ExtractNamespacedName(&es)), svc.Spec.Selector)
+
+	// only select the pods of the given NodeSpecs
+	es.Spec.ExternalServiceNodeSpecs = []string{"coordinating"}
+	svc = NewExternalService(es)
+	expectedSelector := label.NewLabels(k8s.ExtractNamespacedName(&es))
+	expectedSelector[label.ExternalServiceLabelName] = "true"
+	require.Equal(t, expectedSelector, svc.Spec.Selector)
+	// the selector label is not used for the service itself
+	require.NotContains(t, svc.Labels, label.ExternalServiceLabelName)
+}
+
+func TestNewNodeSpecServices(t *testing.T) {
+	es := v1alpha1.Elasticsearch{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "es"},
+		Spec: v1alpha1.ElasticsearchSpec{
+			Nodes: []v1alpha1.NodeSpec{
+				{Name: "master", NodeCount: 3},
+				{
+					Name:      "coordinating",
+					NodeCount: 2,
+					Service: &commonv1alpha1.ServiceTemplate{
+						ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{"foo": "bar"}},
+						Spec:       corev1.ServiceSpec{Type: corev1.ServiceTypeLoadBalancer},
+					},
+				},
+			},
+		},
+	}
+	svcs := NewNodeSpecServices(es)
+	require.Len(t, svcs, 1)
+	svc := svcs[0]
+	require.Equal(t, "ns", svc.Namespace)
+	require.Equal(t, "es-es-coordinating-http", svc.Name)
+	require.Equal(t, map[string]string{"foo": "bar"}, svc.Annotations)
+	require.Equal(t, "coordinating", svc.Labels[label.NodeSpecServiceLabelName])
+	require.Equal(t, corev1.ServiceTypeLoadBalancer, svc.Spec.Type)
+	require.Equal(t, "es-es-coordinating", svc.Spec.Selector[label.StatefulSetNameLabelName])
+	require.Equal(t, "es", svc.Spec.Selector[label.ClusterNameLabelName])
+	// the template in the spec is not modified
+	require.Empty(t, es.Spec.Nodes[1].Service.ObjectMeta.Labels)
+}
+
+func TestReconcileNodeSpecServices(t *testing.T) {
+	require.NoError(t, v1alpha1.AddToScheme(scheme.Scheme))
+	es := v1alpha1.Elasticsearch{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "es"},
+		Spec: v1alpha1.ElasticsearchSpec{
+			Nodes: []v1alpha1.NodeSpec{
+				{Name: "master", NodeCount: 3},
+				{Name: "coordinating", NodeCount: 2, Service: &commonv1alpha1.ServiceTemplate{}},
+			},
+		},
+	}
+	// service of a NodeSpec which does not specify one anymore
+	obsolete := NewNodeSpecService(es, v1alpha1.NodeSpec{Name: "ingest", Service: &commonv1alpha1.ServiceTemplate{}})
+	// services that are not NodeSpec services
+	external := NewExternalService(es)
+	other := &corev1.Service{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "other"}}
+	c := k8s.WrapClient(fake.NewFakeClient(obsolete, external, other))
+
+	reconciled, err := ReconcileNodeSpecServices(c, scheme.Scheme, es)
+	require.NoError(t, err)
+	require.Len(t, reconciled, 1)
+	require.Equal(t, "es-es-coordinating-http", reconciled[0].Name)
+
+	var svcs corev1.ServiceList
+	require.NoError(t, c.List(&client.ListOptions{}, &svcs))
+	names := make([]string, 0, len(svcs.Items))
+	for _, svc := range svcs.Items {
+		names = append(names, svc.Name)
+	}
+	require.ElementsMatch(t, []string{"es-es-http", "es-es-coordinating-http", "other"}, names)
+}
+
+func TestReconcileExternalServiceLabels(t *testing.T) {
+	pod := func(name, ssetName string, labeled bool) runtime.Object {
+		p := corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: "ns",
+				Name:      name,
+				Labels: map[string]string{
+					label.ClusterNameLabelName:     "es",
+					label.StatefulSetNameLabelName: ssetName,
+				},
+			},
+		}
+		if labeled {
+			p.Labels[label.ExternalServiceLabelName] = "true"
+		}
+		return &p
+	}
+	tests := []struct {
+		name                     string
+		externalServiceNodeSpecs []string
+		pods                     []runtime.Object
+		wantLabeled              []string
+		wantSelected             []string
+	}{
+		{
+			name:                     "restrict the external service of a running cluster to a NodeSpec",
+			externalServiceNodeSpecs: []string{"coordinating"},
+			pods: []runtime.Object{
+				pod("es-es-master-0", "es-es-master", false),
+				pod("es-es-coordinating-0", "es-es-coordinating", false),
+				pod("es-es-coordinating-1", "es-es-coordinating", false),
+			},
+			wantLabeled:  []string{"es-es-coordinating-0", "es-es-coordinating-1"},
+			wantSelected: []string{"es-es-coordinating-0", "es-es-coordinating-1"},
+		},
+		{
+			name:                     "switch the external service to another NodeSpec",
+			externalServiceNodeSpecs: []string{"master"},
+			pods: []runtime.Object{
+				pod("es-es-master-0", "es-es-master", false),
+				pod("es-es-coordinating-0", "es-es-coordinating", true),
+			},
+			wantLabeled:  []string{"es-es-master-0"},
+			wantSelected: []string{"es-es-master-0"},
+		},
+		{
+			name:                     "select all the nodes again",
+			externalServiceNodeSpecs: nil,
+			pods: []runtime.Object{
+				pod("es-es-master-0", "es-es-master", false),
+				pod("es-es-coordinating-0", "es-es-coordinating", true),
+			},
+			wantLabeled:  nil,
+			wantSelected: []string{"es-es-master-0", "es-es-coordinating-0"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			es := v1alpha1.Elasticsearch{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "es"},
+				Spec:       v1alpha1.ElasticsearchSpec{ExternalServiceNodeSpecs: tt.externalServiceNodeSpecs},
+			}
+			c := k8s.WrapClient(fake.NewFakeClient(tt.pods...))
+			require.NoError(t, ReconcileExternalServiceLabels(c, es))
+
+			var pods corev1.PodList
+			require.NoError(t, c.List(&client.ListOptions{}, &pods))
+			selector := labels.SelectorFromSet(NewExternalService(es).Spec.Selector)
+			var labeled, selected []string
+			for _, p := range pods.Items {
+				if _, exists := p.Labels[label.ExternalServiceLabelName]; exists {
+					labeled = append(labeled, p.Name)
+				}
+				if selector.Matches(labels.Set(p.Labels)) {
+					selected = append(selected, p.Name)
+				}
+			}
+			require.ElementsMatch(t, tt.wantLabeled, labeled)
+			require.ElementsMatch(t, tt.wantSelected, selected)
+		})
+	}
+}
+
+func TestElasticsearchURL_ExternalServiceNodeSpecs(t *testing.T) {
+	es := v1alpha1.Elasticsearch{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "my-ns", Name: "my-cluster"},
+		Spec:       v1alpha1.ElasticsearchSpec{ExternalServiceNodeSpecs: []string{"coordinating"}},
+	}
+	pods := []corev1.Pod{
+		{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: "my-ns",
+				Name:      "my-cluster-es-master-0",
+				Labels: map[string]string{
+					label.HTTPSchemeLabelName:      "http",
+					label.StatefulSetNameLabelName: "my-cluster-es-master",
+				},
+			},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: "my-ns",
+				Name:      "my-cluster-es-coordinating-0",
+				Labels: map[string]string{
+					label.HTTPSchemeLabelName:      "http",
+					label.StatefulSetNameLabelName: "my-cluster-es-coordinating",
+					label.ExternalServiceLabelName: "true",
+				},
+			},
+		},
+	}
+	// scheme change in progress: only request the pods backing the external service
+	for i := 0; i < 10; i++ {
+		require.Equal(t, "http://my-cluster-es-coordinating-0.my-cluster-es-coordinating.my-ns:9200", ElasticsearchURL(es, pods))
+	}
+}
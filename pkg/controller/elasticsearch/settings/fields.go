@@ -19,16 +19,19 @@ const (
 	PathData = "path.data"
 	PathLogs = "path.logs"
 
+	XPackSecurityAuthcRealms                        = "xpack.security.authc.realms"
 	XPackSecurityAuthcReservedRealmEnabled          = "xpack.security.authc.reserved_realm.enabled"
 	XPackSecurityEnabled                            = "xpack.security.enabled"
 	XPackSecurityHttpSslCertificate                 = "xpack.security.http.ssl.certificate"
 	XPackSecurityHttpSslCertificateAuthorities      = "xpack.security.http.ssl.certificate_authorities"
+	XPackSecurityHttpSslClientAuthentication        = "xpack.security.http.ssl.client_authentication"
 	XPackSecurityHttpSslEnabled                     = "xpack.security.http.ssl.enabled"
 	XPackSecurityHttpSslKey                         = "xpack.security.http.ssl.key"
 	XPackSecurityTransportSslCertificate            = "xpack.security.transport.ssl.certificate"
 	XPackSecurityTransportSslCertificateAuthorities = "xpack.security.transport.ssl.certificate_authorities"
 	XPackSecurityTransportSslEnabled                = "xpack.security.transport.ssl.enabled"
 	XPackSecurityTransportSslKey                    = "xpack.security.transport.ssl.key"
+	XPackSecurityTransportSslTrustRestrictionsPath  = "xpack.security.transport.ssl.trust_restrictions.path"
 	XPackSecurityTransportSslVerificationMode       = "xpack.security.transport.ssl.verification_mode"
 )
 
@@ -50,5 +53,6 @@ var Blacklist = []string{
 	XPackSecurityTransportSslCertificate,
 	XPackSecurityTransportSslEnabled,
 	XPackSecurityTransportSslKey,
+	XPackSecurityTransportSslTrustRestrictionsPath,
 	XPackSecurityTransportSslVerificationMode,
 }
@@ -8,8 +8,10 @@ import (
 	"path"
 
 	"github.com/elastic/cloud-on-k8s/pkg/apis/common/v1alpha1"
+	esv1alpha1 "github.com/elastic/cloud-on-k8s/pkg/apis/elasticsearch/v1alpha1"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/common/certificates"
 	common "github.com/elastic/cloud-on-k8s/pkg/controller/common/settings"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/version"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/volume"
 )
 
@@ -17,16 +19,24 @@ import (
 // parameters.
 func NewMergedESConfig(
 	clusterName string,
+	ver version.Version,
 	httpConfig v1alpha1.HTTPConfig,
+	transportConfig esv1alpha1.TransportConfig,
+	auth esv1alpha1.Auth,
 	userConfig v1alpha1.Config,
 ) (CanonicalConfig, error) {
 	config, err := common.NewCanonicalConfigFrom(userConfig.Data)
 	if err != nil {
 		return CanonicalConfig{}, err
 	}
+	realmsCfg, err := realmsConfig(ver, httpConfig, auth)
+	if err != nil {
+		return CanonicalConfig{}, err
+	}
 	err = config.MergeWith(
 		baseConfig(clusterName).CanonicalConfig,
-		xpackConfig(httpConfig).CanonicalConfig,
+		xpackConfig(httpConfig, transportConfig).CanonicalConfig,
+		realmsCfg,
 	)
 	if err != nil {
 		return CanonicalConfig{}, err
@@ -54,7 +64,7 @@ func baseConfig(clusterName string) *CanonicalConfig {
 }
 
 // xpackConfig returns the configuration bit related to XPack settings
-func xpackConfig(httpCfg v1alpha1.HTTPConfig) *CanonicalConfig {
+func xpackConfig(httpCfg v1alpha1.HTTPConfig, transportCfg esv1alpha1.TransportConfig) *CanonicalConfig {
 	// enable x-pack security, including TLS
 	cfg := map[string]interface{}{
 		// x-pack security general settings
@@ -83,5 +93,12 @@ func xpackConfig(httpCfg v1alpha1.HTTPConfig) *CanonicalConfig {
 			path.Join(volume.TransportCertificatesSecretVolumeMountPath, certificates.CAFileName),
 		},
 	}
+	if transportCfg.TLS.TrustRestrictions != nil {
+		// only trust the certificates listed in the trust restrictions file
+		cfg[XPackSecurityTransportSslTrustRestrictionsPath] = path.Join(
+			volume.TransportCertificatesSecretVolumeMountPath,
+			volume.TransportTrustRestrictionsFile,
+		)
+	}
 	return &CanonicalConfig{common.MustCanonicalConfig(cfg)}
 }
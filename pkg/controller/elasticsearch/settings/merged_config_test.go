@@ -0,0 +1,39 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package settings
+
+import (
+	"testing"
+
+	"github.com/elastic/cloud-on-k8s/pkg/apis/common/v1alpha1"
+	esv1alpha1 "github.com/elastic/cloud-on-k8s/pkg/apis/elasticsearch/v1alpha1"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_xpackConfig_TrustRestrictions(t *testing.T) {
+	tests := []struct {
+		name         string
+		transportCfg esv1alpha1.TransportConfig
+		want         []string
+	}{
+		{
+			name: "trust restrictions disabled by default",
+			want: nil,
+		},
+		{
+			name: "trust restrictions enabled",
+			transportCfg: esv1alpha1.TransportConfig{TLS: esv1alpha1.TransportTLSOptions{
+				TrustRestrictions: &esv1alpha1.TrustRestrictions{},
+			}},
+			want: []string{XPackSecurityTransportSslTrustRestrictionsPath},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := xpackConfig(v1alpha1.HTTPConfig{}, tt.transportCfg)
+			require.Equal(t, tt.want, cfg.HasKeys([]string{XPackSecurityTransportSslTrustRestrictionsPath}))
+		})
+	}
+}
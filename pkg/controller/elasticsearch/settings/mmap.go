@@ -0,0 +1,59 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package settings
+
+import (
+	common "github.com/elastic/cloud-on-k8s/pkg/controller/common/settings"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/version"
+)
+
+const (
+	// NodeStoreAllowMmap controls the memory-mapping of the index files since Elasticsearch 7.0.
+	NodeStoreAllowMmap = "node.store.allow_mmap"
+	// NodeStoreAllowMmapfs controls the memory-mapping of the index files before Elasticsearch 7.0.
+	NodeStoreAllowMmapfs = "node.store.allow_mmapfs"
+)
+
+// allowMmapSetting returns the name of the setting controlling the memory-mapping of the index files
+// for the given Elasticsearch version.
+func allowMmapSetting(ver version.Version) string {
+	if ver.Major < 7 {
+		return NodeStoreAllowMmapfs
+	}
+	return NodeStoreAllowMmap
+}
+
+// storeSettings is the subset of the node settings related to the memory-mapping of the index files.
+type storeSettings struct {
+	Node struct {
+		Store struct {
+			AllowMmap   *bool `config:"allow_mmap"`
+			AllowMmapfs *bool `config:"allow_mmapfs"`
+		} `config:"store"`
+	} `config:"node"`
+}
+
+// MmapEnabled returns true if Elasticsearch memory-maps its index files with the given configuration,
+// which requires a large enough vm.max_map_count on the Kubernetes node.
+func (c CanonicalConfig) MmapEnabled(ver version.Version) (bool, error) {
+	var cfg storeSettings
+	if err := c.CanonicalConfig.Unpack(&cfg); err != nil {
+		return false, err
+	}
+	allowMmap := cfg.Node.Store.AllowMmap
+	if allowMmapSetting(ver) == NodeStoreAllowMmapfs {
+		allowMmap = cfg.Node.Store.AllowMmapfs
+	}
+	return allowMmap == nil || *allowMmap, nil
+}
+
+// DisableMmap configures Elasticsearch not to memory-map its index files, unless explicitly configured by the user.
+func (c CanonicalConfig) DisableMmap(ver version.Version) error {
+	setting := allowMmapSetting(ver)
+	if len(c.HasKeys([]string{setting})) > 0 {
+		return nil
+	}
+	return c.MergeWith(common.MustCanonicalConfig(map[string]interface{}{setting: false}))
+}
@@ -0,0 +1,68 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package settings
+
+import (
+	"testing"
+
+	common "github.com/elastic/cloud-on-k8s/pkg/controller/common/settings"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/version"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCanonicalConfig_DisableMmap(t *testing.T) {
+	tests := []struct {
+		name          string
+		cfg           map[string]interface{}
+		ver           version.Version
+		wantEnabled   bool
+		wantSetting   string
+		wantUnchanged bool
+	}{
+		{
+			name:        "mmap enabled by default",
+			cfg:         map[string]interface{}{},
+			ver:         version.MustParse("7.3.0"),
+			wantEnabled: true,
+			wantSetting: NodeStoreAllowMmap,
+		},
+		{
+			name:        "mmapfs setting before 7.0",
+			cfg:         map[string]interface{}{},
+			ver:         version.MustParse("6.8.0"),
+			wantEnabled: true,
+			wantSetting: NodeStoreAllowMmapfs,
+		},
+		{
+			name:          "keep the user setting",
+			cfg:           map[string]interface{}{NodeStoreAllowMmap: true},
+			ver:           version.MustParse("7.3.0"),
+			wantEnabled:   true,
+			wantSetting:   NodeStoreAllowMmap,
+			wantUnchanged: true,
+		},
+		{
+			name:          "mmap disabled by the user",
+			cfg:           map[string]interface{}{NodeStoreAllowMmap: "false"},
+			ver:           version.MustParse("7.3.0"),
+			wantSetting:   NodeStoreAllowMmap,
+			wantUnchanged: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := CanonicalConfig{common.MustCanonicalConfig(tt.cfg)}
+			enabled, err := cfg.MmapEnabled(tt.ver)
+			require.NoError(t, err)
+			require.Equal(t, tt.wantEnabled, enabled)
+
+			require.NoError(t, cfg.DisableMmap(tt.ver))
+			enabled, err = cfg.MmapEnabled(tt.ver)
+			require.NoError(t, err)
+			require.Equal(t, tt.wantUnchanged && tt.wantEnabled, enabled)
+			require.Equal(t, []string{tt.wantSetting}, cfg.HasKeys([]string{tt.wantSetting}))
+		})
+	}
+}
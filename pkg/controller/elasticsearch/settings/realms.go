@@ -0,0 +1,247 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package settings
+
+import (
+	"path"
+
+	commonv1alpha1 "github.com/elastic/cloud-on-k8s/pkg/apis/common/v1alpha1"
+	"github.com/elastic/cloud-on-k8s/pkg/apis/elasticsearch/v1alpha1"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/certificates"
+	common "github.com/elastic/cloud-on-k8s/pkg/controller/common/settings"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/version"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/volume"
+	"github.com/elastic/cloud-on-k8s/pkg/utils/stringsutil"
+)
+
+const (
+	// FileRealmName is the name of the file realm, holding the users managed by the operator.
+	FileRealmName = "file1"
+	// NativeRealmName is the name of the native realm.
+	NativeRealmName = "native1"
+
+	// fileRealmOrder and nativeRealmOrder place the file and native realms before the realms specified by the user,
+	// whose order cannot be negative.
+	fileRealmOrder   = -100
+	nativeRealmOrder = -99
+
+	// SAMLMetadataKey is the key of the identity provider metadata in the secret referenced by a SAML realm.
+	SAMLMetadataKey = "metadata.xml"
+	// OIDCClientSecretKey is the key of the client secret in the secret referenced by an OpenID Connect realm.
+	OIDCClientSecretKey = "client_secret"
+	// LDAPBindPasswordKey is the key of the bind password in the secret referenced by an LDAP realm.
+	LDAPBindPasswordKey = "bind_password"
+	// RealmCAKey is the key of the certificate authorities in the secret referenced by LDAP and PKI realms.
+	RealmCAKey = certificates.CAFileName
+)
+
+// RealmSecretFile is a file referenced by the settings of a realm, provided by the user in a secret.
+type RealmSecretFile struct {
+	// RealmName is the name of the realm referencing the file.
+	RealmName string
+	// SecretName is the name of the secret containing the file.
+	SecretName string
+	// Key is the key of the file in the secret, also used as file name.
+	Key string
+	// Setting is the realm setting referencing the path of the file.
+	Setting string
+	// List is true if the setting expects a list of paths.
+	List bool
+}
+
+// Path returns the path of the file in the Elasticsearch container.
+func (f RealmSecretFile) Path() string {
+	return path.Join(volume.RealmsVolumeMountPath, f.RealmName, f.Key)
+}
+
+// realmSecureSetting is a secure setting of a realm, provided by the user in a secret.
+type realmSecureSetting struct {
+	secretName string
+	key        string
+	setting    string
+}
+
+// RealmSecretFiles returns the files referenced by the given realms, to be mounted in the Elasticsearch containers.
+// There is at most one file per realm.
+func RealmSecretFiles(auth v1alpha1.Auth) []RealmSecretFile {
+	var files []RealmSecretFile
+	for _, realm := range auth.Realms {
+		switch {
+		case realm.SAML != nil:
+			files = append(files, RealmSecretFile{
+				RealmName:  realm.Name,
+				SecretName: realm.SAML.IdPMetadata.SecretName,
+				Key:        SAMLMetadataKey,
+				Setting:    "idp.metadata.path",
+			})
+		case realm.LDAP != nil && realm.LDAP.CertificateAuthorities.SecretName != "":
+			files = append(files, RealmSecretFile{
+				RealmName:  realm.Name,
+				SecretName: realm.LDAP.CertificateAuthorities.SecretName,
+				Key:        RealmCAKey,
+				Setting:    "ssl.certificate_authorities",
+				List:       true,
+			})
+		case realm.PKI != nil:
+			files = append(files, RealmSecretFile{
+				RealmName:  realm.Name,
+				SecretName: realm.PKI.CertificateAuthorities.SecretName,
+				Key:        RealmCAKey,
+				Setting:    "certificate_authorities",
+				List:       true,
+			})
+		}
+	}
+	return files
+}
+
+func realmSecureSettings(realm v1alpha1.Realm) []realmSecureSetting {
+	switch {
+	case realm.OIDC != nil:
+		return []realmSecureSetting{{
+			secretName: realm.OIDC.ClientSecret.SecretName,
+			key:        OIDCClientSecretKey,
+			setting:    "rp.client_secret",
+		}}
+	case realm.LDAP != nil && realm.LDAP.BindPassword.SecretName != "":
+		return []realmSecureSetting{{
+			secretName: realm.LDAP.BindPassword.SecretName,
+			key:        LDAPBindPasswordKey,
+			setting:    "secure_bind_password",
+		}}
+	default:
+		return nil
+	}
+}
+
+// RealmsSecureSettings returns the secure settings of the given realms, to be injected into the keystore.
+func RealmsSecureSettings(ver version.Version, auth v1alpha1.Auth) []commonv1alpha1.SecretSource {
+	var sources []commonv1alpha1.SecretSource
+	for _, realm := range auth.Realms {
+		for _, s := range realmSecureSettings(realm) {
+			sources = append(sources, commonv1alpha1.SecretSource{
+				SecretName: s.secretName,
+				Entries: []commonv1alpha1.KeyToPath{{
+					Key:  s.key,
+					Path: realmSettingsPrefix(ver, realm.Type(), realm.Name) + "." + s.setting,
+				}},
+			})
+		}
+	}
+	return sources
+}
+
+// realmSettingsPrefix returns the prefix of the settings of a realm.
+// Elasticsearch 7.x expects the realm type in the settings names, 6.x expects a `type` setting.
+func realmSettingsPrefix(ver version.Version, realmType v1alpha1.RealmType, name string) string {
+	if ver.Major < 7 {
+		return stringsutil.Concat(XPackSecurityAuthcRealms, ".", name)
+	}
+	return stringsutil.Concat(XPackSecurityAuthcRealms, ".", string(realmType), ".", name)
+}
+
+// realmsConfig returns the configuration of the realms specified by the user.
+// If there is none, the default realms of Elasticsearch are used.
+func realmsConfig(ver version.Version, httpCfg commonv1alpha1.HTTPConfig, auth v1alpha1.Auth) (*common.CanonicalConfig, error) {
+	if len(auth.Realms) == 0 {
+		return nil, nil
+	}
+
+	// configuring any realm disables the default file and native realms, which must be configured explicitly
+	cfg := common.NewCanonicalConfig()
+	err := cfg.MergeWith(
+		realmConfig(ver, "file", FileRealmName, map[string]interface{}{"order": fileRealmOrder}),
+		realmConfig(ver, "native", NativeRealmName, map[string]interface{}{"order": nativeRealmOrder}),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	files := make(map[string]RealmSecretFile)
+	for _, f := range RealmSecretFiles(auth) {
+		files[f.RealmName] = f
+	}
+	for _, realm := range auth.Realms {
+		if realm.Config != nil {
+			userCfg, err := common.NewCanonicalConfigFrom(map[string]interface{}{
+				realmSettingsPrefix(ver, realm.Type(), realm.Name): realm.Config.Data,
+			})
+			if err != nil {
+				return nil, err
+			}
+			if err := cfg.MergeWith(userCfg); err != nil {
+				return nil, err
+			}
+		}
+
+		settings := typedRealmSettings(realm)
+		settings["order"] = realm.Order
+		if f, exists := files[realm.Name]; exists {
+			if f.List {
+				settings[f.Setting] = []string{f.Path()}
+			} else {
+				settings[f.Setting] = f.Path()
+			}
+		}
+		if err := cfg.MergeWith(realmConfig(ver, string(realm.Type()), realm.Name, settings)); err != nil {
+			return nil, err
+		}
+
+		if realm.PKI != nil && httpCfg.TLS.Enabled() {
+			// request client certificates, without requiring them
+			if err := cfg.MergeWith(common.MustNewSingleValue(XPackSecurityHttpSslClientAuthentication, "optional")); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return cfg, nil
+}
+
+// realmConfig returns the configuration of a single realm from its settings.
+func realmConfig(ver version.Version, realmType string, name string, settings map[string]interface{}) *common.CanonicalConfig {
+	if ver.Major < 7 {
+		settings["type"] = realmType
+	}
+	return common.MustCanonicalConfig(map[string]interface{}{
+		realmSettingsPrefix(ver, v1alpha1.RealmType(realmType), name): settings,
+	})
+}
+
+// typedRealmSettings returns the settings derived from the type-specific section of the realm.
+func typedRealmSettings(realm v1alpha1.Realm) map[string]interface{} {
+	settings := make(map[string]interface{})
+	setIfNotEmpty := func(setting string, value string) {
+		if value != "" {
+			settings[setting] = value
+		}
+	}
+	switch {
+	case realm.SAML != nil:
+		setIfNotEmpty("idp.entity_id", realm.SAML.IdPEntityID)
+		setIfNotEmpty("sp.entity_id", realm.SAML.SPEntityID)
+		setIfNotEmpty("sp.acs", realm.SAML.SPACS)
+		setIfNotEmpty("sp.logout", realm.SAML.SPLogout)
+		setIfNotEmpty("attributes.principal", realm.SAML.AttributesPrincipal)
+	case realm.OIDC != nil:
+		setIfNotEmpty("rp.client_id", realm.OIDC.ClientID)
+		setIfNotEmpty("rp.redirect_uri", realm.OIDC.RedirectURI)
+		setIfNotEmpty("rp.response_type", realm.OIDC.ResponseType)
+		setIfNotEmpty("op.issuer", realm.OIDC.Issuer)
+		setIfNotEmpty("op.authorization_endpoint", realm.OIDC.AuthorizationEndpoint)
+		setIfNotEmpty("op.token_endpoint", realm.OIDC.TokenEndpoint)
+		setIfNotEmpty("op.jwkset_path", realm.OIDC.JWKSetURL)
+		setIfNotEmpty("claims.principal", realm.OIDC.ClaimsPrincipal)
+	case realm.LDAP != nil:
+		if len(realm.LDAP.URLs) > 0 {
+			settings["url"] = realm.LDAP.URLs
+		}
+		setIfNotEmpty("bind_dn", realm.LDAP.BindDN)
+		setIfNotEmpty("user_search.base_dn", realm.LDAP.UserSearchBaseDN)
+		setIfNotEmpty("group_search.base_dn", realm.LDAP.GroupSearchBaseDN)
+	case realm.PKI != nil:
+		setIfNotEmpty("username_pattern", realm.PKI.UsernamePattern)
+	}
+	return settings
+}
@@ -0,0 +1,155 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package settings
+
+import (
+	"testing"
+
+	commonv1alpha1 "github.com/elastic/cloud-on-k8s/pkg/apis/common/v1alpha1"
+	"github.com/elastic/cloud-on-k8s/pkg/apis/elasticsearch/v1alpha1"
+	common "github.com/elastic/cloud-on-k8s/pkg/controller/common/settings"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/version"
+	"github.com/stretchr/testify/require"
+)
+
+var testRealms = v1alpha1.Auth{
+	Realms: []v1alpha1.Realm{
+		{
+			Name:  "saml1",
+			Order: 0,
+			Config: &commonv1alpha1.Config{Data: map[string]interface{}{
+				"attributes.groups": "groups",
+				// overridden by the typed setting
+				"idp.entity_id": "other",
+			}},
+			SAML: &v1alpha1.SAMLRealm{
+				IdPMetadata: commonv1alpha1.SecretRef{SecretName: "idp-metadata"},
+				IdPEntityID: "https://idp.example.com",
+			},
+		},
+		{
+			Name:  "pki1",
+			Order: 1,
+			PKI:   &v1alpha1.PKIRealm{CertificateAuthorities: commonv1alpha1.SecretRef{SecretName: "pki-ca"}},
+		},
+	},
+}
+
+func Test_realmsConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		version version.Version
+		httpCfg commonv1alpha1.HTTPConfig
+		auth    v1alpha1.Auth
+		want    *common.CanonicalConfig
+	}{
+		{
+			name:    "no realms",
+			version: version.MustParse("7.2.0"),
+			auth:    v1alpha1.Auth{},
+			want:    nil,
+		},
+		{
+			name:    "realms in 7.x",
+			version: version.MustParse("7.2.0"),
+			auth:    testRealms,
+			want: common.MustCanonicalConfig(map[string]interface{}{
+				"xpack.security.authc.realms.file.file1.order":                 -100,
+				"xpack.security.authc.realms.native.native1.order":             -99,
+				"xpack.security.authc.realms.saml.saml1.order":                 0,
+				"xpack.security.authc.realms.saml.saml1.attributes.groups":     "groups",
+				"xpack.security.authc.realms.saml.saml1.idp.entity_id":         "https://idp.example.com",
+				"xpack.security.authc.realms.saml.saml1.idp.metadata.path":     "/usr/share/elasticsearch/config/realms/saml1/metadata.xml",
+				"xpack.security.authc.realms.pki.pki1.order":                   1,
+				"xpack.security.authc.realms.pki.pki1.certificate_authorities": []string{"/usr/share/elasticsearch/config/realms/pki1/ca.crt"},
+				XPackSecurityHttpSslClientAuthentication:                       "optional",
+			}),
+		},
+		{
+			name:    "realms in 6.x",
+			version: version.MustParse("6.8.0"),
+			auth:    testRealms,
+			want: common.MustCanonicalConfig(map[string]interface{}{
+				"xpack.security.authc.realms.file1.type":                   "file",
+				"xpack.security.authc.realms.file1.order":                  -100,
+				"xpack.security.authc.realms.native1.type":                 "native",
+				"xpack.security.authc.realms.native1.order":                -99,
+				"xpack.security.authc.realms.saml1.type":                   "saml",
+				"xpack.security.authc.realms.saml1.order":                  0,
+				"xpack.security.authc.realms.saml1.attributes.groups":      "groups",
+				"xpack.security.authc.realms.saml1.idp.entity_id":          "https://idp.example.com",
+				"xpack.security.authc.realms.saml1.idp.metadata.path":      "/usr/share/elasticsearch/config/realms/saml1/metadata.xml",
+				"xpack.security.authc.realms.pki1.type":                    "pki",
+				"xpack.security.authc.realms.pki1.order":                   1,
+				"xpack.security.authc.realms.pki1.certificate_authorities": []string{"/usr/share/elasticsearch/config/realms/pki1/ca.crt"},
+				XPackSecurityHttpSslClientAuthentication:                   "optional",
+			}),
+		},
+		{
+			name:    "no client authentication without HTTP TLS",
+			version: version.MustParse("7.2.0"),
+			httpCfg: commonv1alpha1.HTTPConfig{
+				TLS: commonv1alpha1.TLSOptions{SelfSignedCertificate: &commonv1alpha1.SelfSignedCertificate{Disabled: true}},
+			},
+			auth: v1alpha1.Auth{Realms: testRealms.Realms[1:]},
+			want: common.MustCanonicalConfig(map[string]interface{}{
+				"xpack.security.authc.realms.file.file1.order":                 -100,
+				"xpack.security.authc.realms.native.native1.order":             -99,
+				"xpack.security.authc.realms.pki.pki1.order":                   1,
+				"xpack.security.authc.realms.pki.pki1.certificate_authorities": []string{"/usr/share/elasticsearch/config/realms/pki1/ca.crt"},
+			}),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := realmsConfig(tt.version, tt.httpCfg, tt.auth)
+			require.NoError(t, err)
+			if tt.want == nil {
+				require.Nil(t, got)
+				return
+			}
+			require.Empty(t, tt.want.Diff(got, nil))
+		})
+	}
+}
+
+func TestRealmsSecureSettings(t *testing.T) {
+	auth := v1alpha1.Auth{
+		Realms: []v1alpha1.Realm{
+			{
+				Name: "oidc1",
+				OIDC: &v1alpha1.OIDCRealm{ClientSecret: commonv1alpha1.SecretRef{SecretName: "oidc"}},
+			},
+			{
+				Name: "ldap1",
+				LDAP: &v1alpha1.LDAPRealm{BindPassword: commonv1alpha1.SecretRef{SecretName: "ldap"}},
+			},
+			{
+				Name: "ldap2",
+				LDAP: &v1alpha1.LDAPRealm{},
+			},
+		},
+	}
+	require.Equal(t, []commonv1alpha1.SecretSource{
+		{
+			SecretName: "oidc",
+			Entries: []commonv1alpha1.KeyToPath{{
+				Key:  OIDCClientSecretKey,
+				Path: "xpack.security.authc.realms.oidc.oidc1.rp.client_secret",
+			}},
+		},
+		{
+			SecretName: "ldap",
+			Entries: []commonv1alpha1.KeyToPath{{
+				Key:  LDAPBindPasswordKey,
+				Path: "xpack.security.authc.realms.ldap.ldap1.secure_bind_password",
+			}},
+		},
+	}, RealmsSecureSettings(version.MustParse("7.2.0"), auth))
+	require.Equal(t,
+		"xpack.security.authc.realms.ldap1.secure_bind_password",
+		RealmsSecureSettings(version.MustParse("6.8.0"), auth)[1].Entries[0].Path,
+	)
+}
@@ -0,0 +1,52 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package sset
+
+import (
+	"strconv"
+	"strings"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/elastic/cloud-on-k8s/pkg/utils/k8s"
+)
+
+// GetActualVolumeClaimsForStatefulSet returns the existing PersistentVolumeClaims created from the volume claim
+// templates of this StatefulSet, for any pod ordinal.
+func GetActualVolumeClaimsForStatefulSet(c k8s.Client, statefulSet appsv1.StatefulSet) ([]corev1.PersistentVolumeClaim, error) {
+	var pvcs corev1.PersistentVolumeClaimList
+	if err := c.List(&client.ListOptions{Namespace: statefulSet.Namespace}, &pvcs); err != nil {
+		return nil, err
+	}
+	claims := make([]corev1.PersistentVolumeClaim, 0, len(pvcs.Items))
+	for _, pvc := range pvcs.Items {
+		if _, isClaim := VolumeClaimOrdinal(statefulSet, pvc.Name); isClaim {
+			claims = append(claims, pvc)
+		}
+	}
+	return claims, nil
+}
+
+// VolumeClaimOrdinal returns the ordinal of the pod the PersistentVolumeClaim with the given name was created for,
+// and whether it was created from one of the volume claim templates of this StatefulSet.
+// The StatefulSet controller names the claims <template name>-<StatefulSet name>-<pod ordinal>.
+func VolumeClaimOrdinal(statefulSet appsv1.StatefulSet, claimName string) (int32, bool) {
+	for _, template := range statefulSet.Spec.VolumeClaimTemplates {
+		prefix := template.Name + "-" + statefulSet.Name + "-"
+		if !strings.HasPrefix(claimName, prefix) {
+			continue
+		}
+		suffix := strings.TrimPrefix(claimName, prefix)
+		ordinal, err := strconv.ParseInt(suffix, 10, 32)
+		// reject signs and leading zeros, which the StatefulSet controller never produces
+		if err != nil || ordinal < 0 || strconv.FormatInt(ordinal, 10) != suffix {
+			continue
+		}
+		return int32(ordinal), true
+	}
+	return 0, false
+}
@@ -0,0 +1,97 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package sset
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/elastic/cloud-on-k8s/pkg/utils/k8s"
+)
+
+var pvcStatefulSet = appsv1.StatefulSet{
+	ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "es-data"},
+	Spec: appsv1.StatefulSetSpec{
+		VolumeClaimTemplates: []corev1.PersistentVolumeClaim{
+			{ObjectMeta: metav1.ObjectMeta{Name: "elasticsearch-data"}},
+			{ObjectMeta: metav1.ObjectMeta{Name: "logs"}},
+		},
+	},
+}
+
+func TestVolumeClaimOrdinal(t *testing.T) {
+	tests := []struct {
+		name        string
+		claimName   string
+		wantOrdinal int32
+		wantIsClaim bool
+	}{
+		{
+			name:        "claim of the first template",
+			claimName:   "elasticsearch-data-es-data-0",
+			wantOrdinal: 0,
+			wantIsClaim: true,
+		},
+		{
+			name:        "claim of the second template",
+			claimName:   "logs-es-data-12",
+			wantOrdinal: 12,
+			wantIsClaim: true,
+		},
+		{
+			name:        "claim of another StatefulSet with the same prefix",
+			claimName:   "elasticsearch-data-es-data-hot-0",
+			wantIsClaim: false,
+		},
+		{
+			name:        "claim of another StatefulSet with a numeric suffix",
+			claimName:   "elasticsearch-data-es-data-1-0",
+			wantIsClaim: false,
+		},
+		{
+			name:        "leading zero",
+			claimName:   "elasticsearch-data-es-data-01",
+			wantIsClaim: false,
+		},
+		{
+			name:        "unknown template",
+			claimName:   "other-es-data-0",
+			wantIsClaim: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ordinal, isClaim := VolumeClaimOrdinal(pvcStatefulSet, tt.claimName)
+			require.Equal(t, tt.wantIsClaim, isClaim)
+			require.Equal(t, tt.wantOrdinal, ordinal)
+		})
+	}
+}
+
+func TestGetActualVolumeClaimsForStatefulSet(t *testing.T) {
+	pvc := func(namespace, name string) runtime.Object {
+		return &corev1.PersistentVolumeClaim{ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name}}
+	}
+	c := k8s.WrapClient(fake.NewFakeClient(
+		pvc("ns", "elasticsearch-data-es-data-0"),
+		pvc("ns", "elasticsearch-data-es-data-3"),
+		pvc("ns", "logs-es-data-0"),
+		pvc("ns", "elasticsearch-data-es-master-0"),
+		pvc("other-ns", "elasticsearch-data-es-data-1"),
+	))
+	claims, err := GetActualVolumeClaimsForStatefulSet(c, pvcStatefulSet)
+	require.NoError(t, err)
+	names := make([]string, 0, len(claims))
+	for _, claim := range claims {
+		names = append(names, claim.Name)
+	}
+	require.ElementsMatch(t, []string{"elasticsearch-data-es-data-0", "elasticsearch-data-es-data-3", "logs-es-data-0"}, names)
+}
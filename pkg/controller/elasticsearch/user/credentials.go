@@ -55,6 +55,8 @@ type UserCredentials interface {
 type ClearTextCredentials struct {
 	users  []User
 	secret corev1.Secret
+	// provided are the user-provided passwords by user name, which are never generated nor rotated.
+	provided map[string][]byte
 }
 
 func keysEqual(v1, v2 map[string][]byte) bool {
@@ -78,7 +80,8 @@ func (c *ClearTextCredentials) Reset(secret corev1.Secret) {
 	}
 }
 
-// NeedsUpdate is true for clear text credentials if the secret contains the same keys as the reference secret.
+// NeedsUpdate is true for clear text credentials if the secret does not contain the same keys as the reference secret,
+// or if it does not contain the user-provided passwords.
 func (c *ClearTextCredentials) NeedsUpdate(other corev1.Secret) bool {
 	// for generated secrets as long as the key exists we can work with it. Rotate secrets by deleting them (?)
 	for _, user := range c.users {
@@ -86,9 +89,31 @@ func (c *ClearTextCredentials) NeedsUpdate(other corev1.Secret) bool {
 			return true
 		}
 	}
+	for name, password := range c.provided {
+		if !bytes.Equal(other.Data[name], password) {
+			return true
+		}
+	}
 	return false
 }
 
+// withProvidedPasswords replaces the generated passwords of these credentials with the given user-provided ones,
+// by user name. Passwords of users not part of these credentials are ignored.
+func (c *ClearTextCredentials) withProvidedPasswords(passwords map[string][]byte) {
+	for i, u := range c.users {
+		password, exists := passwords[u.Id()]
+		if !exists {
+			continue
+		}
+		if c.provided == nil {
+			c.provided = make(map[string][]byte)
+		}
+		c.provided[u.Id()] = password
+		c.users[i].password = string(password)
+		c.secret.Data[u.Id()] = password
+	}
+}
+
 // Users returns the users slice stored in the struct.
 func (c *ClearTextCredentials) Users() []User {
 	return c.users
@@ -134,6 +159,11 @@ func (hc *HashedCredentials) NeedsUpdate(other corev1.Secret) bool {
 		return true
 	}
 
+	// Check for probe user password update
+	if !bytes.Equal(other.Data[InternalProbeUserName], hc.secret.Data[InternalProbeUserName]) {
+		return true
+	}
+
 	// Check for users update
 	otherUsers := make(map[string][]byte)
 	for _, user := range strings.Split(string(other.Data[ElasticUsersFile]), "\n") {
@@ -166,6 +196,16 @@ func (hc *HashedCredentials) Secret() corev1.Secret {
 	return hc.secret
 }
 
+// withProbePassword stores the clear text password of the probe user next to the users file, for the readiness probe
+// to use the same password as the file realm of the node: both files are updated at once.
+func (hc *HashedCredentials) withProbePassword(users []User) {
+	for _, u := range users {
+		if u.Id() == InternalProbeUserName {
+			hc.secret.Data[InternalProbeUserName] = []byte(u.Password())
+		}
+	}
+}
+
 // NewInternalUserCredentials creates a secret for the ES user used by the controller.
 func NewInternalUserCredentials(es types.NamespacedName) *ClearTextCredentials {
 	return usersToClearTextCredentials(es, ElasticInternalUsersSecretName(es.Name), newInternalUsers())
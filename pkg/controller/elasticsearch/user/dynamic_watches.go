@@ -0,0 +1,50 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package user
+
+import (
+	"github.com/elastic/cloud-on-k8s/pkg/apis/elasticsearch/v1alpha1"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/finalizer"
+	common "github.com/elastic/cloud-on-k8s/pkg/controller/common/user"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/watches"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/name"
+	"github.com/elastic/cloud-on-k8s/pkg/utils/k8s"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// providedPasswordsWatchKey returns the key used by the dynamic watch registration for the secret containing
+// user-provided passwords.
+func providedPasswordsWatchKey(esName string) string {
+	return name.ESNamer.Suffix(esName, "provided-passwords")
+}
+
+// watchProvidedPasswords reconciles the dynamic watch on the secret containing user-provided passwords.
+func watchProvidedPasswords(dynamicWatches watches.DynamicWatches, es v1alpha1.Elasticsearch) error {
+	key, exists := common.ProvidedPasswordsSecretKey(es)
+	watch := watches.NamedWatch{
+		Name:    providedPasswordsWatchKey(es.Name),
+		Watched: []types.NamespacedName{key},
+		Watcher: k8s.ExtractNamespacedName(&es),
+	}
+
+	if exists {
+		return dynamicWatches.Secrets.AddHandler(watch)
+	}
+	// remove the watch if no longer configured.
+	dynamicWatches.Secrets.RemoveHandlerForKey(watch.Key())
+	return nil
+}
+
+// DynamicWatchesFinalizer returns a Finalizer for dynamic watches related to user-provided passwords.
+func DynamicWatchesFinalizer(dynamicWatches watches.DynamicWatches, es v1alpha1.Elasticsearch) finalizer.Finalizer {
+	return finalizer.Finalizer{
+		Name: "finalizer.elasticsearch.k8s.elastic.co/provided-passwords-secret",
+		Execute: func() error {
+			// es resource is being finalized, so we no longer need the dynamic watch
+			dynamicWatches.Secrets.RemoveHandlerForKey(providedPasswordsWatchKey(es.Name))
+			return nil
+		},
+	}
+}
@@ -4,7 +4,10 @@
 
 package user
 
-import "github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/client"
+import (
+	common "github.com/elastic/cloud-on-k8s/pkg/controller/common/user"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/client"
+)
 
 const (
 	// ExternalUserName also known as the 'elastic'
@@ -59,6 +62,9 @@ type InternalUsers struct {
 	ControllerUser User
 	ProbeUser      User
 	KeystoreUser   User
+	// PendingControllerUser holds the new password of the controller user while a password rotation is in progress,
+	// nil if there is none.
+	PendingControllerUser *User
 }
 
 // NewInternalUsersFrom constructs a new struct with internal users from the given credentials of those users.
@@ -75,5 +81,9 @@ func NewInternalUsersFrom(users ClearTextCredentials) *InternalUsers {
 			internalUsers.KeystoreUser = user
 		}
 	}
+	if password, exists := users.Secret().Data[common.PendingPasswordKey(InternalControllerUserName)]; exists {
+		pending := New(InternalControllerUserName, Password(string(password)), Roles(SuperUserBuiltinRole))
+		internalUsers.PendingControllerUser = &pending
+	}
 	return &internalUsers
 }
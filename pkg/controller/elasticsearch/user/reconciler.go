@@ -6,8 +6,11 @@ package user
 
 import (
 	"github.com/elastic/cloud-on-k8s/pkg/apis/elasticsearch/v1alpha1"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/annotation"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/common/reconciler"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/common/user"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/watches"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/label"
 	"github.com/elastic/cloud-on-k8s/pkg/utils/k8s"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/runtime"
@@ -46,7 +49,7 @@ func ReconcileUserCredentialsSecret(
 func aggregateAllUsers(customUsers corev1.SecretList, defaultUsers ...ClearTextCredentials) ([]user.User, error) {
 	var allUsers []user.User
 	for _, clearText := range defaultUsers {
-		for _, u := range clearText.Users() {
+		for _, u := range clearText.realmUsers() {
 			usr := u
 			allUsers = append(allUsers, usr)
 		}
@@ -71,12 +74,27 @@ func aggregateAllUsers(customUsers corev1.SecretList, defaultUsers ...ClearTextC
 // into the Elasticsearch config directory which the file realm of ES security can directly understand.
 // A second file called 'users_roles' is contained in this third secret as well which describes
 // role assignments for the users specified in the first file.
+// Passwords of the 'internal-users' and 'elastic-user' secrets are rotated when requested with the
+// PasswordRotationAnnotationName annotation of the Elasticsearch resource: the new passwords are first stored
+// in the file realm only, and replace the current ones once accepted by all the nodes.
+// The password of the 'elastic' user can be provided by the user through the secret referenced in the
+// specification of the Elasticsearch resource, in which case it is never generated nor rotated.
 func ReconcileUsers(
 	c k8s.Client,
 	scheme *runtime.Scheme,
 	es v1alpha1.Elasticsearch,
+	dynamicWatches watches.DynamicWatches,
 ) (*InternalUsers, error) {
 
+	// watch the secret containing user-provided passwords, to update the users when it changes
+	if err := watchProvidedPasswords(dynamicWatches, es); err != nil {
+		return nil, err
+	}
+	providedPasswords, err := user.ProvidedPasswords(c, es)
+	if err != nil {
+		return nil, err
+	}
+
 	nsn := k8s.ExtractNamespacedName(&es)
 	internalSecrets := NewInternalUserCredentials(nsn)
 	if err := ReconcileUserCredentialsSecret(c, scheme, es, internalSecrets); err != nil {
@@ -84,6 +102,7 @@ func ReconcileUsers(
 	}
 
 	externalSecrets := NewExternalUserCredentials(nsn)
+	externalSecrets.withProvidedPasswords(providedPasswords)
 	if err := ReconcileUserCredentialsSecret(c, scheme, es, externalSecrets); err != nil {
 		return nil, err
 	}
@@ -96,6 +115,11 @@ func ReconcileUsers(
 		return nil, err
 	}
 
+	rotated, err := rotatePasswords(c, es, internalSecrets, externalSecrets, customUsers)
+	if err != nil {
+		return nil, err
+	}
+
 	allUsers, err := aggregateAllUsers(customUsers, *internalSecrets, *externalSecrets)
 	if err != nil {
 		return nil, err
@@ -104,9 +128,17 @@ func ReconcileUsers(
 	if err != nil {
 		return nil, err
 	}
+	elasticUsersRolesSecret.withProbePassword(internalSecrets.realmUsers())
 	if err := ReconcileUserCredentialsSecret(c, scheme, es, elasticUsersRolesSecret); err != nil {
 		return nil, err
 	}
+	if rotated {
+		// speed up the propagation of the file realm with the pending passwords to the nodes
+		annotation.MarkPodsAsUpdated(c, client.ListOptions{
+			Namespace:     es.Namespace,
+			LabelSelector: label.NewLabelSelectorForElasticsearch(es),
+		})
+	}
 
 	return NewInternalUsersFrom(*internalSecrets), nil
 }
@@ -5,13 +5,22 @@
 package user
 
 import (
+	"strings"
 	"testing"
 
+	commonv1alpha1 "github.com/elastic/cloud-on-k8s/pkg/apis/common/v1alpha1"
+	"github.com/elastic/cloud-on-k8s/pkg/apis/elasticsearch/v1alpha1"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/common/user"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/watches"
+	"github.com/elastic/cloud-on-k8s/pkg/utils/k8s"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
 	corev1 "k8s.io/api/core/v1"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 )
 
 func Test_aggregateAllUsers(t *testing.T) {
@@ -154,3 +163,68 @@ func containsAllNames(t *testing.T, names []string, users []user.User) {
 		}
 	}
 }
+
+// realmPasswordMatches returns true if the password of the given user in the file realm matches password.
+func realmPasswordMatches(t *testing.T, c k8s.Client, esName string, userName string, password []byte) bool {
+	var realm corev1.Secret
+	require.NoError(t, c.Get(types.NamespacedName{Namespace: "ns", Name: XPackFileRealmSecretName(esName)}, &realm))
+	prefix := userName + ":"
+	for _, line := range strings.Split(string(realm.Data[ElasticUsersFile]), "\n") {
+		if strings.HasPrefix(line, prefix) {
+			return bcrypt.CompareHashAndPassword([]byte(strings.TrimPrefix(line, prefix)), password) == nil
+		}
+	}
+	return false
+}
+
+func TestReconcileUsers_ProvidedPasswords(t *testing.T) {
+	require.NoError(t, v1alpha1.AddToScheme(scheme.Scheme))
+	es := v1alpha1.Elasticsearch{
+		ObjectMeta: v1.ObjectMeta{Namespace: "ns", Name: "es"},
+		Spec: v1alpha1.ElasticsearchSpec{
+			Auth: v1alpha1.Auth{PasswordsSecret: commonv1alpha1.SecretRef{SecretName: "passwords"}},
+		},
+	}
+	passwords := corev1.Secret{
+		ObjectMeta: v1.ObjectMeta{Namespace: "ns", Name: "passwords"},
+		Data:       map[string][]byte{ExternalUserName: []byte("changeme")},
+	}
+	c := k8s.WrapClient(fake.NewFakeClient(&es))
+	w := watches.NewDynamicWatches()
+
+	// the passwords secret does not exist yet, but is watched
+	_, err := ReconcileUsers(c, scheme.Scheme, es, w)
+	require.Error(t, err)
+	require.Contains(t, w.Secrets.Registrations(), providedPasswordsWatchKey(es.Name))
+
+	// the elastic user password is the provided one
+	require.NoError(t, c.Create(&passwords))
+	_, err = ReconcileUsers(c, scheme.Scheme, es, w)
+	require.NoError(t, err)
+	elasticUser := getSecret(t, c, ElasticExternalUsersSecretName(es.Name))
+	require.Equal(t, "changeme", string(elasticUser.Data[ExternalUserName]))
+	require.True(t, realmPasswordMatches(t, c, es.Name, ExternalUserName, []byte("changeme")))
+
+	// the elastic user password is updated with the provided one
+	passwords.Data[ExternalUserName] = []byte("changed")
+	require.NoError(t, c.Update(&passwords))
+	_, err = ReconcileUsers(c, scheme.Scheme, es, w)
+	require.NoError(t, err)
+	elasticUser = getSecret(t, c, ElasticExternalUsersSecretName(es.Name))
+	require.Equal(t, "changed", string(elasticUser.Data[ExternalUserName]))
+	require.True(t, realmPasswordMatches(t, c, es.Name, ExternalUserName, []byte("changed")))
+
+	// the provided password is not rotated
+	es.Annotations = map[string]string{user.PasswordRotationAnnotationName: "1"}
+	_, err = ReconcileUsers(c, scheme.Scheme, es, w)
+	require.NoError(t, err)
+	elasticUser = getSecret(t, c, ElasticExternalUsersSecretName(es.Name))
+	require.Equal(t, "changed", string(elasticUser.Data[ExternalUserName]))
+	require.False(t, user.PasswordRotationRequested(es, elasticUser))
+
+	// the watch is removed once the passwords secret is not referenced anymore
+	es.Spec.Auth.PasswordsSecret = commonv1alpha1.SecretRef{}
+	_, err = ReconcileUsers(c, scheme.Scheme, es, w)
+	require.NoError(t, err)
+	require.NotContains(t, w.Secrets.Registrations(), providedPasswordsWatchKey(es.Name))
+}
@@ -0,0 +1,152 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package user
+
+import (
+	"strings"
+
+	"github.com/elastic/cloud-on-k8s/pkg/apis/elasticsearch/v1alpha1"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/annotation"
+	common "github.com/elastic/cloud-on-k8s/pkg/controller/common/user"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/label"
+	"github.com/elastic/cloud-on-k8s/pkg/utils/k8s"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/runtime/log"
+)
+
+var log = logf.Log.WithName("user")
+
+// rotate generates new passwords for the users of these credentials and stores them as pending passwords in the
+// underlying secret. The current passwords are kept in use until the rotation is completed.
+func (c *ClearTextCredentials) rotate(k8sClient k8s.Client, es v1alpha1.Elasticsearch) error {
+	secret := c.secret
+	secret.Data = make(map[string][]byte, len(c.secret.Data))
+	for k, v := range c.secret.Data {
+		secret.Data[k] = v
+	}
+	secret.Annotations = make(map[string]string, len(c.secret.Annotations))
+	for k, v := range c.secret.Annotations {
+		secret.Annotations[k] = v
+	}
+
+	for _, u := range c.users {
+		if _, provided := c.provided[u.Id()]; provided {
+			// user-provided passwords are rotated by updating the secret they are read from
+			continue
+		}
+		// the pending password of a rotation still in progress is replaced as well, so that the new passwords
+		// are only propagated once the file realm contains all the users of this rotation
+		secret.Data[common.PendingPasswordKey(u.Id())] = common.RandomPasswordBytes()
+	}
+	common.SetPasswordRotation(es, &secret)
+
+	if err := k8sClient.Update(&secret); err != nil {
+		return err
+	}
+	c.Reset(secret)
+	return nil
+}
+
+// realmUsers returns the users of these credentials as they must be stored in the file realm: with their pending
+// password while a rotation is in progress.
+func (c *ClearTextCredentials) realmUsers() []User {
+	users := make([]User, len(c.users))
+	for i, u := range c.users {
+		users[i] = u
+		if pending, exists := c.secret.Data[common.PendingPasswordKey(u.Id())]; exists {
+			users[i].password = string(pending)
+		}
+	}
+	return users
+}
+
+// rotatePasswords rotates the passwords of the internal and external users if requested on the Elasticsearch
+// resource. The rotation waits for the users of the associated resources to be rotated first, so that the file realm
+// propagated to the nodes at the end of the rotation contains all the new passwords.
+// It returns true if any password was rotated.
+func rotatePasswords(
+	c k8s.Client,
+	es v1alpha1.Elasticsearch,
+	internal, external *ClearTextCredentials,
+	customUsers corev1.SecretList,
+) (bool, error) {
+	for _, s := range customUsers.Items {
+		if common.PasswordRotationRequested(es, s) {
+			log.V(1).Info("Waiting for the user to be rotated", "namespace", es.Namespace, "es_name", es.Name, "user", s.Name)
+			return false, nil
+		}
+	}
+	rotated := false
+	if common.PasswordRotationRequested(es, internal.Secret()) {
+		log.Info("Rotating internal users passwords", "namespace", es.Namespace, "es_name", es.Name)
+		if err := internal.rotate(c, es); err != nil {
+			return rotated, err
+		}
+		rotated = true
+	}
+	if common.PasswordRotationRequested(es, external.Secret()) {
+		log.Info("Rotating elastic user password", "namespace", es.Namespace, "es_name", es.Name)
+		if err := external.rotate(c, es); err != nil {
+			return rotated, err
+		}
+		rotated = true
+	}
+	return rotated, nil
+}
+
+// CompletePasswordRotation replaces the passwords of the internal and external users with the pending ones, once they
+// are propagated to all the Elasticsearch nodes. It then records the completed rotation on the Elasticsearch resource,
+// for the users of the associated resources to be switched to their new passwords as well.
+func CompletePasswordRotation(c k8s.Client, es v1alpha1.Elasticsearch) error {
+	internalSecret, err := completePendingPasswords(c, es, ElasticInternalUsersSecretName(es.Name))
+	if err != nil {
+		return err
+	}
+	if _, err := completePendingPasswords(c, es, ElasticExternalUsersSecretName(es.Name)); err != nil {
+		return err
+	}
+
+	rotation := es.Annotations[common.PasswordRotationAnnotationName]
+	if rotation == "" || internalSecret.Annotations[common.PasswordRotationAnnotationName] != rotation ||
+		common.PasswordRotationPropagated(es) {
+		// no rotation done for the current request, or already recorded
+		return nil
+	}
+	log.Info("Password rotation completed", "namespace", es.Namespace, "es_name", es.Name)
+	rotated := es.DeepCopy()
+	rotated.Annotations[common.PasswordsRotatedAnnotationName] = rotation
+	return c.Update(rotated)
+}
+
+// completePendingPasswords replaces the passwords stored in the given secret with the pending ones, and returns the
+// updated secret.
+func completePendingPasswords(c k8s.Client, es v1alpha1.Elasticsearch, secretName string) (corev1.Secret, error) {
+	var secret corev1.Secret
+	if err := c.Get(types.NamespacedName{Namespace: es.Namespace, Name: secretName}, &secret); err != nil {
+		return secret, err
+	}
+	updated := false
+	for key, pending := range secret.Data {
+		if strings.HasSuffix(key, common.PendingPasswordSuffix) {
+			secret.Data[strings.TrimSuffix(key, common.PendingPasswordSuffix)] = pending
+			delete(secret.Data, key)
+			updated = true
+		}
+	}
+	if !updated {
+		return secret, nil
+	}
+	if err := c.Update(&secret); err != nil {
+		return secret, err
+	}
+	// speed up the propagation of the new passwords of the probe and keystore users to the nodes
+	annotation.MarkPodsAsUpdated(c, client.ListOptions{
+		Namespace:     es.Namespace,
+		LabelSelector: label.NewLabelSelectorForElasticsearch(es),
+	})
+	return secret, nil
+}
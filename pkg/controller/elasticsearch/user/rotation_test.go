@@ -0,0 +1,121 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package user
+
+import (
+	"testing"
+
+	"github.com/elastic/cloud-on-k8s/pkg/apis/elasticsearch/v1alpha1"
+	common "github.com/elastic/cloud-on-k8s/pkg/controller/common/user"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/watches"
+	"github.com/elastic/cloud-on-k8s/pkg/utils/k8s"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func getSecret(t *testing.T, c k8s.Client, name string) corev1.Secret {
+	var secret corev1.Secret
+	require.NoError(t, c.Get(types.NamespacedName{Namespace: "ns", Name: name}, &secret))
+	return secret
+}
+
+func TestReconcileUsers_PasswordRotation(t *testing.T) {
+	require.NoError(t, v1alpha1.AddToScheme(scheme.Scheme))
+	es := v1alpha1.Elasticsearch{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "es"}}
+	associationUser := corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "ns",
+			Name:      "kibana-user",
+			Labels:    common.NewLabels(k8s.ExtractNamespacedName(&es)),
+		},
+		Data: map[string][]byte{
+			common.UserName:     []byte("kibana-user"),
+			common.PasswordHash: []byte("$2a$10$lUhwc8cRDX1mK7S5dgT3teDjAvrrdMjTIoiDMtLfE9lCTTiWYRDoy"),
+			common.UserRoles:    []byte("kibana_system"),
+		},
+	}
+	c := k8s.WrapClient(fake.NewFakeClient(&es, &associationUser))
+
+	// initial passwords
+	internalUsers, err := ReconcileUsers(c, scheme.Scheme, es, watches.NewDynamicWatches())
+	require.NoError(t, err)
+	require.Nil(t, internalUsers.PendingControllerUser)
+	initialInternal := getSecret(t, c, ElasticInternalUsersSecretName(es.Name))
+	initialExternal := getSecret(t, c, ElasticExternalUsersSecretName(es.Name))
+
+	// request a rotation: it waits for the association user to be rotated first
+	es.Annotations = map[string]string{common.PasswordRotationAnnotationName: "1"}
+	_, err = ReconcileUsers(c, scheme.Scheme, es, watches.NewDynamicWatches())
+	require.NoError(t, err)
+	require.Equal(t, initialInternal.Data, getSecret(t, c, ElasticInternalUsersSecretName(es.Name)).Data)
+	require.Equal(t, initialExternal.Data, getSecret(t, c, ElasticExternalUsersSecretName(es.Name)).Data)
+
+	// once the association user is rotated, pending passwords are generated
+	associationUser = getSecret(t, c, associationUser.Name)
+	common.SetPasswordRotation(es, &associationUser)
+	require.NoError(t, c.Update(&associationUser))
+	internalUsers, err = ReconcileUsers(c, scheme.Scheme, es, watches.NewDynamicWatches())
+	require.NoError(t, err)
+	rotatedInternal := getSecret(t, c, ElasticInternalUsersSecretName(es.Name))
+	rotatedExternal := getSecret(t, c, ElasticExternalUsersSecretName(es.Name))
+	for _, secret := range []corev1.Secret{rotatedInternal, rotatedExternal} {
+		require.Equal(t, "1", secret.Annotations[common.PasswordRotationAnnotationName])
+	}
+	for _, name := range []string{InternalControllerUserName, InternalProbeUserName, InternalKeystoreUserName} {
+		// the current passwords are still in use
+		require.Equal(t, initialInternal.Data[name], rotatedInternal.Data[name])
+		require.NotEmpty(t, rotatedInternal.Data[common.PendingPasswordKey(name)])
+	}
+	require.Equal(t, initialExternal.Data[ExternalUserName], rotatedExternal.Data[ExternalUserName])
+	require.NotEmpty(t, rotatedExternal.Data[common.PendingPasswordKey(ExternalUserName)])
+	// the operator gets both the current and the pending passwords
+	require.Equal(t, string(initialInternal.Data[InternalControllerUserName]), internalUsers.ControllerUser.Password())
+	require.NotNil(t, internalUsers.PendingControllerUser)
+	require.Equal(t,
+		string(rotatedInternal.Data[common.PendingPasswordKey(InternalControllerUserName)]),
+		internalUsers.PendingControllerUser.Password(),
+	)
+	// the file realm contains the pending passwords
+	require.True(t, realmPasswordMatches(t, c, es.Name, InternalControllerUserName,
+		rotatedInternal.Data[common.PendingPasswordKey(InternalControllerUserName)]))
+	require.True(t, realmPasswordMatches(t, c, es.Name, ExternalUserName,
+		rotatedExternal.Data[common.PendingPasswordKey(ExternalUserName)]))
+
+	// passwords are not rotated again for the same request
+	_, err = ReconcileUsers(c, scheme.Scheme, es, watches.NewDynamicWatches())
+	require.NoError(t, err)
+	require.Equal(t, rotatedInternal.Data, getSecret(t, c, ElasticInternalUsersSecretName(es.Name)).Data)
+	require.Equal(t, rotatedExternal.Data, getSecret(t, c, ElasticExternalUsersSecretName(es.Name)).Data)
+
+	// a new rotation before the previous one is completed replaces the pending passwords
+	es.Annotations[common.PasswordRotationAnnotationName] = "2"
+	associationUser = getSecret(t, c, associationUser.Name)
+	common.SetPasswordRotation(es, &associationUser)
+	require.NoError(t, c.Update(&associationUser))
+	_, err = ReconcileUsers(c, scheme.Scheme, es, watches.NewDynamicWatches())
+	require.NoError(t, err)
+	secondInternal := getSecret(t, c, ElasticInternalUsersSecretName(es.Name))
+	pendingKey := common.PendingPasswordKey(InternalControllerUserName)
+	require.Equal(t, initialInternal.Data[InternalControllerUserName], secondInternal.Data[InternalControllerUserName])
+	require.NotEqual(t, rotatedInternal.Data[pendingKey], secondInternal.Data[pendingKey])
+
+	// completing the rotation switches to the pending passwords, and records it on the Elasticsearch resource
+	require.NoError(t, CompletePasswordRotation(c, es))
+	completedInternal := getSecret(t, c, ElasticInternalUsersSecretName(es.Name))
+	require.Len(t, completedInternal.Data, 3)
+	require.Equal(t, secondInternal.Data[pendingKey], completedInternal.Data[InternalControllerUserName])
+	require.NotContains(t, getSecret(t, c, ElasticExternalUsersSecretName(es.Name)).Data, common.PendingPasswordKey(ExternalUserName))
+	var updatedES v1alpha1.Elasticsearch
+	require.NoError(t, c.Get(types.NamespacedName{Namespace: "ns", Name: es.Name}, &updatedES))
+	require.True(t, common.PasswordRotationPropagated(updatedES))
+	internalUsers, err = ReconcileUsers(c, scheme.Scheme, updatedES, watches.NewDynamicWatches())
+	require.NoError(t, err)
+	require.Nil(t, internalUsers.PendingControllerUser)
+	require.Equal(t, string(secondInternal.Data[pendingKey]), internalUsers.ControllerUser.Password())
+}
@@ -0,0 +1,129 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package validation
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	estype "github.com/elastic/cloud-on-k8s/pkg/apis/elasticsearch/v1alpha1"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/license"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/validation"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/settings"
+	esvolume "github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/volume"
+	apimachineryvalidation "k8s.io/apimachinery/pkg/util/validation"
+)
+
+// realmNameMaxLength is the maximum length of a realm name, so that the name of the volume holding the files
+// of the realm is a valid DNS label.
+const realmNameMaxLength = apimachineryvalidation.DNS1123LabelMaxLength - len(esvolume.RealmVolumeNamePrefix)
+
+// validRealms checks that the authentication realms have unique names and orders and reference the required secrets.
+func validRealms(ctx Context) validation.Result {
+	if err := validateRealms(ctx.Proposed.Elasticsearch.Spec.Auth.Realms); err != nil {
+		msg := fmt.Sprintf("%s: %s", invalidRealmsErrMsg, err)
+		return validation.Result{
+			Error:   errors.New(msg),
+			Reason:  msg,
+			Allowed: false,
+		}
+	}
+	return validation.OK
+}
+
+func validateRealms(realms []estype.Realm) error {
+	names := make(map[string]struct{}, len(realms))
+	orders := make(map[int]string, len(realms))
+	for _, realm := range realms {
+		if errs := apimachineryvalidation.IsDNS1123Label(realm.Name); len(errs) > 0 {
+			return fmt.Errorf("invalid realm name '%s': [%s]", realm.Name, strings.Join(errs, ","))
+		}
+		if len(realm.Name) > realmNameMaxLength {
+			return fmt.Errorf("realm name '%s' exceeds allowed length of %d", realm.Name, realmNameMaxLength)
+		}
+		if realm.Name == settings.FileRealmName || realm.Name == settings.NativeRealmName {
+			return fmt.Errorf("realm name '%s' is reserved", realm.Name)
+		}
+		if _, exists := names[realm.Name]; exists {
+			return fmt.Errorf("duplicate realm name '%s'", realm.Name)
+		}
+		names[realm.Name] = struct{}{}
+
+		if realm.Order < 0 {
+			return fmt.Errorf("order of realm '%s' must not be negative", realm.Name)
+		}
+		if other, exists := orders[realm.Order]; exists {
+			return fmt.Errorf("realms '%s' and '%s' have the same order %d", other, realm.Name, realm.Order)
+		}
+		orders[realm.Order] = realm.Name
+
+		if err := validateRealmType(realm); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func validateRealmType(realm estype.Realm) error {
+	var missingSecret string
+	switch realm.Type() {
+	case estype.SAMLRealmType:
+		if realm.SAML.IdPMetadata.SecretName == "" {
+			missingSecret = "idpMetadata"
+		}
+	case estype.OIDCRealmType:
+		if realm.OIDC.ClientSecret.SecretName == "" {
+			missingSecret = "clientSecret"
+		}
+	case estype.LDAPRealmType:
+	case estype.PKIRealmType:
+		if realm.PKI.CertificateAuthorities.SecretName == "" {
+			missingSecret = "certificateAuthorities"
+		}
+	default:
+		return fmt.Errorf("realm '%s' must specify exactly one of saml, oidc, ldap or pki", realm.Name)
+	}
+	if missingSecret != "" {
+		return fmt.Errorf("realm '%s' must reference a %s secret", realm.Name, missingSecret)
+	}
+	return nil
+}
+
+// enterpriseRealmTypes are the types of the authentication realms that require an enterprise license.
+// Other realm types, such as LDAP and PKI, are enabled by Elasticsearch according to its own version and license.
+var enterpriseRealmTypes = map[estype.RealmType]struct{}{
+	estype.SAMLRealmType: {},
+	estype.OIDCRealmType: {},
+}
+
+// ValidateLicense checks that the features used by the given cluster are allowed by the installed licenses.
+// SAML and OpenID Connect realms are not available with a basic license and require an enterprise license.
+func ValidateLicense(es estype.Elasticsearch, checker license.Checker) ([]validation.Result, error) {
+	var enterpriseRealms []estype.Realm
+	for _, realm := range es.Spec.Auth.Realms {
+		if _, enterprise := enterpriseRealmTypes[realm.Type()]; enterprise {
+			enterpriseRealms = append(enterpriseRealms, realm)
+		}
+	}
+	if len(enterpriseRealms) == 0 {
+		return nil, nil
+	}
+	enabled, err := checker.EnterpriseFeaturesEnabled()
+	if err != nil {
+		return nil, err
+	}
+	if enabled {
+		return nil, nil
+	}
+	results := make([]validation.Result, 0, len(enterpriseRealms))
+	for _, realm := range enterpriseRealms {
+		results = append(results, validation.Result{
+			Allowed: false,
+			Reason:  fmt.Sprintf("%s: realm '%s' of type %s", realmsLicenseRequiredMsg, realm.Name, realm.Type()),
+		})
+	}
+	return results, nil
+}
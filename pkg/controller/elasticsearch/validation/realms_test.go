@@ -0,0 +1,216 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package validation
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	common "github.com/elastic/cloud-on-k8s/pkg/apis/common/v1alpha1"
+	estype "github.com/elastic/cloud-on-k8s/pkg/apis/elasticsearch/v1alpha1"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/license"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/validation"
+	"github.com/stretchr/testify/require"
+)
+
+func samlRealm(name string, order int) estype.Realm {
+	return estype.Realm{
+		Name:  name,
+		Order: order,
+		SAML:  &estype.SAMLRealm{IdPMetadata: common.SecretRef{SecretName: "idp-metadata"}},
+	}
+}
+
+func Test_validRealms(t *testing.T) {
+	tests := []struct {
+		name    string
+		realms  []estype.Realm
+		wantErr string
+	}{
+		{
+			name: "no realms: OK",
+		},
+		{
+			name: "realms of all types: OK",
+			realms: []estype.Realm{
+				samlRealm("saml1", 0),
+				{Name: "oidc1", Order: 1, OIDC: &estype.OIDCRealm{ClientSecret: common.SecretRef{SecretName: "oidc"}}},
+				{Name: "ldap1", Order: 2, LDAP: &estype.LDAPRealm{URLs: []string{"ldaps://ldap.example.com:636"}}},
+				{Name: "pki1", Order: 3, PKI: &estype.PKIRealm{CertificateAuthorities: common.SecretRef{SecretName: "ca"}}},
+			},
+		},
+		{
+			name:    "duplicate names: NOT OK",
+			realms:  []estype.Realm{samlRealm("saml1", 0), samlRealm("saml1", 1)},
+			wantErr: "invalid authentication realms: duplicate realm name 'saml1'",
+		},
+		{
+			name:    "duplicate orders: NOT OK",
+			realms:  []estype.Realm{samlRealm("saml1", 2), samlRealm("saml2", 2)},
+			wantErr: "invalid authentication realms: realms 'saml1' and 'saml2' have the same order 2",
+		},
+		{
+			name:    "negative order: NOT OK",
+			realms:  []estype.Realm{samlRealm("saml1", -1)},
+			wantErr: "invalid authentication realms: order of realm 'saml1' must not be negative",
+		},
+		{
+			name:    "reserved name: NOT OK",
+			realms:  []estype.Realm{samlRealm("file1", 0)},
+			wantErr: "invalid authentication realms: realm name 'file1' is reserved",
+		},
+		{
+			name:    "invalid name: NOT OK",
+			realms:  []estype.Realm{samlRealm("SAML.1", 0)},
+			wantErr: "invalid authentication realms: invalid realm name 'SAML.1'",
+		},
+		{
+			name:    "name too long: NOT OK",
+			realms:  []estype.Realm{samlRealm(strings.Repeat("a", 41), 0)},
+			wantErr: fmt.Sprintf("invalid authentication realms: realm name '%s' exceeds allowed length of 40", strings.Repeat("a", 41)),
+		},
+		{
+			name:    "no type: NOT OK",
+			realms:  []estype.Realm{{Name: "realm1"}},
+			wantErr: "invalid authentication realms: realm 'realm1' must specify exactly one of saml, oidc, ldap or pki",
+		},
+		{
+			name: "several types: NOT OK",
+			realms: []estype.Realm{{
+				Name: "realm1",
+				LDAP: &estype.LDAPRealm{},
+				PKI:  &estype.PKIRealm{CertificateAuthorities: common.SecretRef{SecretName: "ca"}},
+			}},
+			wantErr: "invalid authentication realms: realm 'realm1' must specify exactly one of saml, oidc, ldap or pki",
+		},
+		{
+			name:    "missing required secret: NOT OK",
+			realms:  []estype.Realm{{Name: "oidc1", OIDC: &estype.OIDCRealm{ClientID: "kibana"}}},
+			wantErr: "invalid authentication realms: realm 'oidc1' must reference a clientSecret secret",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			es := estype.Elasticsearch{
+				Spec: estype.ElasticsearchSpec{
+					Version: "7.2.0",
+					Auth:    estype.Auth{Realms: tt.realms},
+				},
+			}
+			ctx, err := NewValidationContext(nil, es)
+			require.NoError(t, err)
+			result := validRealms(*ctx)
+			if tt.wantErr == "" {
+				require.Equal(t, validation.OK, result)
+				return
+			}
+			require.False(t, result.Allowed)
+			require.Contains(t, result.Reason, tt.wantErr)
+		})
+	}
+}
+
+type fakeLicenseChecker struct {
+	enabled bool
+	err     error
+}
+
+func (f fakeLicenseChecker) EnterpriseFeaturesEnabled() (bool, error) {
+	return f.enabled, f.err
+}
+
+func (f fakeLicenseChecker) Valid(l license.EnterpriseLicense) (bool, error) {
+	return f.enabled, f.err
+}
+
+func TestValidateLicense(t *testing.T) {
+	withRealms := func(realms ...estype.Realm) estype.Elasticsearch {
+		return estype.Elasticsearch{Spec: estype.ElasticsearchSpec{Auth: estype.Auth{Realms: realms}}}
+	}
+	oidcRealm := estype.Realm{Name: "oidc1", Order: 1, OIDC: &estype.OIDCRealm{ClientSecret: common.SecretRef{SecretName: "oidc"}}}
+	ldapRealm := estype.Realm{Name: "ldap1", Order: 2, LDAP: &estype.LDAPRealm{URLs: []string{"ldaps://ldap.example.com:636"}}}
+	pkiRealm := estype.Realm{Name: "pki1", Order: 3, PKI: &estype.PKIRealm{CertificateAuthorities: common.SecretRef{SecretName: "ca"}}}
+	licenseRequired := func(name, realmType string) validation.Result {
+		return validation.Result{
+			Allowed: false,
+			Reason:  fmt.Sprintf("%s: realm '%s' of type %s", realmsLicenseRequiredMsg, name, realmType),
+		}
+	}
+	tests := []struct {
+		name    string
+		es      estype.Elasticsearch
+		checker license.Checker
+		want    []validation.Result
+		wantErr bool
+	}{
+		{
+			name:    "no realms, no enterprise license: OK",
+			es:      estype.Elasticsearch{},
+			checker: fakeLicenseChecker{enabled: false},
+		},
+		{
+			name:    "saml realm with an enterprise license: OK",
+			es:      withRealms(samlRealm("saml1", 0)),
+			checker: fakeLicenseChecker{enabled: true},
+		},
+		{
+			name:    "saml realm without an enterprise license: NOT OK",
+			es:      withRealms(samlRealm("saml1", 0)),
+			checker: fakeLicenseChecker{enabled: false},
+			want:    []validation.Result{licenseRequired("saml1", "saml")},
+		},
+		{
+			name:    "oidc realm with an enterprise license: OK",
+			es:      withRealms(oidcRealm),
+			checker: fakeLicenseChecker{enabled: true},
+		},
+		{
+			name:    "oidc realm without an enterprise license: NOT OK",
+			es:      withRealms(oidcRealm),
+			checker: fakeLicenseChecker{enabled: false},
+			want:    []validation.Result{licenseRequired("oidc1", "oidc")},
+		},
+		{
+			name:    "ldap realm without an enterprise license: OK",
+			es:      withRealms(ldapRealm),
+			checker: fakeLicenseChecker{enabled: false},
+		},
+		{
+			name:    "pki realm without an enterprise license: OK",
+			es:      withRealms(pkiRealm),
+			checker: fakeLicenseChecker{enabled: false},
+		},
+		{
+			name:    "ldap and pki realms do not need to check the license",
+			es:      withRealms(ldapRealm, pkiRealm),
+			checker: fakeLicenseChecker{err: errors.New("boom")},
+		},
+		{
+			name:    "realms of all types without an enterprise license: only saml and oidc NOT OK",
+			es:      withRealms(samlRealm("saml1", 0), oidcRealm, ldapRealm, pkiRealm),
+			checker: fakeLicenseChecker{enabled: false},
+			want:    []validation.Result{licenseRequired("saml1", "saml"), licenseRequired("oidc1", "oidc")},
+		},
+		{
+			name:    "error while checking the license",
+			es:      withRealms(samlRealm("saml1", 0)),
+			checker: fakeLicenseChecker{err: errors.New("boom")},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ValidateLicense(tt.es, tt.checker)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -40,6 +40,10 @@ func validUpgradePath(ctx Context) validation.Result {
 	}
 
 	v := esversion.SupportedVersions(ctx.Proposed.Version)
+	if ctx.Proposed.Elasticsearch.Spec.UpdateStrategy.FullClusterRestart() {
+		// nodes of different versions never run together
+		v = esversion.FullClusterRestartSupportedVersions(ctx.Proposed.Version)
+	}
 	if v == nil {
 		return validation.Result{Allowed: false, Reason: unsupportedVersion(&ctx.Proposed.Version)}
 	}
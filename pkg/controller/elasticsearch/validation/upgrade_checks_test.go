@@ -16,6 +16,11 @@ import (
 	"k8s.io/client-go/kubernetes/scheme"
 )
 
+func fullClusterRestart(es *estype.Elasticsearch) *estype.Elasticsearch {
+	es.Spec.UpdateStrategy.Type = estype.FullClusterRestartStrategyType
+	return es
+}
+
 func es(v string) *estype.Elasticsearch {
 	return &estype.Elasticsearch{
 		ObjectMeta: metav1.ObjectMeta{
@@ -125,6 +130,30 @@ func Test_validUpgradePath(t *testing.T) {
 			},
 			want: validation.OK,
 		},
+		{
+			name: "not wire compatible but full cluster restart validation.OK",
+			args: args{
+				current:  es("6.5.0"),
+				proposed: *fullClusterRestart(es("7.0.0")),
+			},
+			want: validation.OK,
+		},
+		{
+			name: "too old for a full cluster restart FAIL",
+			args: args{
+				current:  es("5.6.0"),
+				proposed: *fullClusterRestart(es("7.0.0")),
+			},
+			want: validation.Result{Allowed: false, Reason: "unsupported version upgrade from 5.6.0 to 7.0.0"},
+		},
+		{
+			name: "unsupported version with a full cluster restart FAIL",
+			args: args{
+				current:  es("7.0.0"),
+				proposed: *fullClusterRestart(es("8.0.0")),
+			},
+			want: validation.Result{Allowed: false, Reason: "unsupported version: 8.0.0"},
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
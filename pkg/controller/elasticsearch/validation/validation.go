@@ -12,13 +12,18 @@ import (
 )
 
 const (
-	cfgInvalidMsg            = "configuration invalid"
-	masterRequiredMsg        = "Elasticsearch needs to have at least one master node"
-	parseVersionErrMsg       = "Cannot parse Elasticsearch version"
-	parseStoredVersionErrMsg = "Cannot parse current Elasticsearch version"
-	invalidSanIPErrMsg       = "invalid SAN IP address"
-	pvcImmutableMsg          = "Volume claim templates cannot be modified"
-	invalidNamesErrMsg       = "Elasticsearch configuration would generate resources with invalid names"
+	cfgInvalidMsg                = "configuration invalid"
+	masterRequiredMsg            = "Elasticsearch needs to have at least one master node"
+	parseVersionErrMsg           = "Cannot parse Elasticsearch version"
+	parseStoredVersionErrMsg     = "Cannot parse current Elasticsearch version"
+	invalidSanIPErrMsg           = "invalid SAN IP address"
+	invalidPrivateKeyErrMsg      = "invalid private key options"
+	invalidRealmsErrMsg          = "invalid authentication realms"
+	realmsLicenseRequiredMsg     = "SAML and OpenID Connect realms require an enterprise license"
+	pvcImmutableMsg              = "Volume claim templates cannot be modified"
+	invalidNamesErrMsg           = "Elasticsearch configuration would generate resources with invalid names"
+	invalidPluginsErrMsg         = "invalid plugins"
+	invalidExternalServiceErrMsg = "invalid external service NodeSpecs"
 )
 
 // Validation is a function from a currently stored Elasticsearch spec and proposed new spec
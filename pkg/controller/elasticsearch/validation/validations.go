@@ -12,7 +12,9 @@ import (
 	"strconv"
 	"strings"
 
+	commonv1alpha1 "github.com/elastic/cloud-on-k8s/pkg/apis/common/v1alpha1"
 	"github.com/elastic/cloud-on-k8s/pkg/apis/elasticsearch/v1alpha1"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/certificates"
 	common "github.com/elastic/cloud-on-k8s/pkg/controller/common/settings"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/common/validation"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/name"
@@ -20,6 +22,7 @@ import (
 	esversion "github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/version"
 	netutil "github.com/elastic/cloud-on-k8s/pkg/utils/net"
 	"github.com/elastic/cloud-on-k8s/pkg/utils/set"
+	corev1 "k8s.io/api/core/v1"
 )
 
 // Validations are all registered Elasticsearch validations.
@@ -31,6 +34,10 @@ var Validations = []Validation{
 	validUpgradePath,
 	noBlacklistedSettings,
 	validSanIP,
+	validPrivateKeyOptions,
+	validRealms,
+	validPlugins,
+	validExternalServiceNodeSpecs,
 	pvcModification,
 }
 
@@ -141,6 +148,109 @@ func validSanIP(ctx Context) validation.Result {
 	return validation.OK
 }
 
+// validPrivateKeyOptions checks that the private key options of the HTTP and transport certificates can be used to
+// generate private keys.
+func validPrivateKeyOptions(ctx Context) validation.Result {
+	spec := ctx.Proposed.Elasticsearch.Spec
+	for _, options := range []*commonv1alpha1.PrivateKeyOptions{spec.HTTP.TLS.PrivateKey, spec.Transport.TLS.PrivateKey} {
+		if err := validatePrivateKeyOptions(options); err != nil {
+			msg := fmt.Sprintf("%s: %s", invalidPrivateKeyErrMsg, err)
+			return validation.Result{
+				Error:   errors.New(msg),
+				Reason:  msg,
+				Allowed: false,
+			}
+		}
+	}
+	return validation.OK
+}
+
+func validatePrivateKeyOptions(options *commonv1alpha1.PrivateKeyOptions) error {
+	if options == nil || (options.Algorithm == "" && options.Size == 0) {
+		return nil
+	}
+	if options.Algorithm != "" {
+		return certificates.DefaultKeyParams.WithOverrides(options).Validate()
+	}
+	// the algorithm defaults to the one of the operator, which is not known here:
+	// the size must be valid for at least one of the algorithms
+	for _, algorithm := range []commonv1alpha1.PrivateKeyAlgorithm{
+		commonv1alpha1.RSAPrivateKeyAlgorithm,
+		commonv1alpha1.ECDSAPrivateKeyAlgorithm,
+	} {
+		if err := (certificates.KeyParams{Algorithm: algorithm, Size: options.Size}).Validate(); err == nil {
+			return nil
+		}
+	}
+	return fmt.Errorf("unsupported private key size %d", options.Size)
+}
+
+// validPlugins checks that each plugin is either a remote plugin or a bundle stored in a volume of the pod template.
+func validPlugins(ctx Context) validation.Result {
+	for _, node := range ctx.Proposed.Elasticsearch.Spec.Nodes {
+		for _, plugin := range node.Plugins {
+			if err := validatePlugin(plugin, node.PodTemplate.Spec.Volumes); err != nil {
+				msg := fmt.Sprintf("%s: node %s: %s", invalidPluginsErrMsg, node.Name, err)
+				return validation.Result{
+					Error:   errors.New(msg),
+					Reason:  msg,
+					Allowed: false,
+				}
+			}
+		}
+	}
+	return validation.OK
+}
+
+func validatePlugin(plugin v1alpha1.Plugin, volumes []corev1.Volume) error {
+	if (plugin.Name == "") == (plugin.Bundle == nil) {
+		return errors.New("exactly one of name or bundle must be specified")
+	}
+	if plugin.Bundle == nil {
+		return nil
+	}
+	if plugin.Bundle.Path == "" {
+		return fmt.Errorf("bundle path must be specified in volume %s", plugin.Bundle.VolumeName)
+	}
+	for _, v := range volumes {
+		if v.Name == plugin.Bundle.VolumeName {
+			return nil
+		}
+	}
+	return fmt.Errorf("bundle volume %s not found in the pod template", plugin.Bundle.VolumeName)
+}
+
+// validExternalServiceNodeSpecs checks that the NodeSpecs backing the external HTTP service exist,
+// and have at least one node in total.
+func validExternalServiceNodeSpecs(ctx Context) validation.Result {
+	es := ctx.Proposed.Elasticsearch
+	if len(es.Spec.ExternalServiceNodeSpecs) == 0 {
+		return validation.OK
+	}
+	var err error
+	nodeCount := int32(0)
+	for _, nodeSpecName := range es.Spec.ExternalServiceNodeSpecs {
+		node := getNode(nodeSpecName, es)
+		if node == nil {
+			err = fmt.Errorf("NodeSpec %s not found", nodeSpecName)
+			break
+		}
+		nodeCount += node.NodeCount
+	}
+	if err == nil && nodeCount == 0 {
+		err = errors.New("at least one node is required")
+	}
+	if err != nil {
+		msg := fmt.Sprintf("%s: %s", invalidExternalServiceErrMsg, err)
+		return validation.Result{
+			Error:   errors.New(msg),
+			Reason:  msg,
+			Allowed: false,
+		}
+	}
+	return validation.OK
+}
+
 // pvcModification ensures no PVCs are changed, as volume claim templates are immutable in stateful sets
 func pvcModification(ctx Context) validation.Result {
 	if ctx.Current == nil {
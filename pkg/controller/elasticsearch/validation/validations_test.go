@@ -445,6 +445,185 @@ func Test_validSanIP(t *testing.T) {
 	}
 }
 
+func Test_validPrivateKeyOptions(t *testing.T) {
+	tests := []struct {
+		name      string
+		http      *common.PrivateKeyOptions
+		transport *common.PrivateKeyOptions
+		wantErr   string
+	}{
+		{
+			name: "no private key options: OK",
+		},
+		{
+			name:      "valid algorithms and sizes: OK",
+			http:      &common.PrivateKeyOptions{Algorithm: common.ECDSAPrivateKeyAlgorithm, Size: 384},
+			transport: &common.PrivateKeyOptions{Algorithm: common.RSAPrivateKeyAlgorithm, Size: 4096},
+		},
+		{
+			name: "algorithm without size: OK",
+			http: &common.PrivateKeyOptions{Algorithm: common.ECDSAPrivateKeyAlgorithm},
+		},
+		{
+			name:      "size without algorithm valid for one of the algorithms: OK",
+			transport: &common.PrivateKeyOptions{Size: 384},
+		},
+		{
+			name:    "unsupported algorithm: NOT OK",
+			http:    &common.PrivateKeyOptions{Algorithm: "DSA"},
+			wantErr: "invalid private key options: unsupported private key algorithm DSA",
+		},
+		{
+			name:      "size invalid for the algorithm: NOT OK",
+			transport: &common.PrivateKeyOptions{Algorithm: common.ECDSAPrivateKeyAlgorithm, Size: 2048},
+			wantErr:   "invalid private key options: unsupported private key size 2048 for algorithm ECDSA",
+		},
+		{
+			name:    "size without algorithm invalid for all algorithms: NOT OK",
+			http:    &common.PrivateKeyOptions{Size: 1024},
+			wantErr: "invalid private key options: unsupported private key size 1024",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			es := estype.Elasticsearch{
+				Spec: estype.ElasticsearchSpec{
+					Version:   "7.2.0",
+					HTTP:      common.HTTPConfig{TLS: common.TLSOptions{PrivateKey: tt.http}},
+					Transport: estype.TransportConfig{TLS: estype.TransportTLSOptions{PrivateKey: tt.transport}},
+				},
+			}
+			ctx, err := NewValidationContext(nil, es)
+			require.NoError(t, err)
+			want := validation.OK
+			if tt.wantErr != "" {
+				want = validation.Result{Allowed: false, Reason: tt.wantErr, Error: fmt.Errorf(tt.wantErr)}
+			}
+			require.Equal(t, want, validPrivateKeyOptions(*ctx))
+		})
+	}
+}
+
+func Test_validPlugins(t *testing.T) {
+	bundleVolumes := []corev1.Volume{{Name: "plugins"}}
+	tests := []struct {
+		name    string
+		plugins []estype.Plugin
+		volumes []corev1.Volume
+		wantErr string
+	}{
+		{
+			name: "no plugins: OK",
+		},
+		{
+			name: "remote plugins and bundle: OK",
+			plugins: []estype.Plugin{
+				{Name: "analysis-icu"},
+				{Name: "https://example.com/plugin.zip"},
+				{Bundle: &estype.PluginBundle{VolumeName: "plugins", Path: "plugin.zip"}},
+			},
+			volumes: bundleVolumes,
+		},
+		{
+			name:    "neither name nor bundle: NOT OK",
+			plugins: []estype.Plugin{{}},
+			wantErr: "invalid plugins: node default: exactly one of name or bundle must be specified",
+		},
+		{
+			name: "both name and bundle: NOT OK",
+			plugins: []estype.Plugin{
+				{Name: "analysis-icu", Bundle: &estype.PluginBundle{VolumeName: "plugins", Path: "plugin.zip"}},
+			},
+			volumes: bundleVolumes,
+			wantErr: "invalid plugins: node default: exactly one of name or bundle must be specified",
+		},
+		{
+			name:    "bundle without path: NOT OK",
+			plugins: []estype.Plugin{{Bundle: &estype.PluginBundle{VolumeName: "plugins"}}},
+			volumes: bundleVolumes,
+			wantErr: "invalid plugins: node default: bundle path must be specified in volume plugins",
+		},
+		{
+			name:    "bundle volume not in the pod template: NOT OK",
+			plugins: []estype.Plugin{{Bundle: &estype.PluginBundle{VolumeName: "other", Path: "plugin.zip"}}},
+			volumes: bundleVolumes,
+			wantErr: "invalid plugins: node default: bundle volume other not found in the pod template",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			es := estype.Elasticsearch{
+				Spec: estype.ElasticsearchSpec{
+					Version: "7.2.0",
+					Nodes: []estype.NodeSpec{
+						{
+							Name:        "default",
+							Plugins:     tt.plugins,
+							PodTemplate: corev1.PodTemplateSpec{Spec: corev1.PodSpec{Volumes: tt.volumes}},
+						},
+					},
+				},
+			}
+			ctx, err := NewValidationContext(nil, es)
+			require.NoError(t, err)
+			want := validation.OK
+			if tt.wantErr != "" {
+				want = validation.Result{Allowed: false, Reason: tt.wantErr, Error: fmt.Errorf(tt.wantErr)}
+			}
+			require.Equal(t, want, validPlugins(*ctx))
+		})
+	}
+}
+
+func Test_validExternalServiceNodeSpecs(t *testing.T) {
+	nodes := []estype.NodeSpec{
+		{Name: "master", NodeCount: 3},
+		{Name: "coordinating", NodeCount: 2},
+		{Name: "ingest", NodeCount: 0},
+	}
+	tests := []struct {
+		name      string
+		nodeSpecs []string
+		wantErr   string
+	}{
+		{
+			name: "all nodes: OK",
+		},
+		{
+			name:      "existing NodeSpecs: OK",
+			nodeSpecs: []string{"coordinating", "ingest"},
+		},
+		{
+			name:      "unknown NodeSpec: NOT OK",
+			nodeSpecs: []string{"coordinating", "client"},
+			wantErr:   "invalid external service NodeSpecs: NodeSpec client not found",
+		},
+		{
+			name:      "no node: NOT OK",
+			nodeSpecs: []string{"ingest"},
+			wantErr:   "invalid external service NodeSpecs: at least one node is required",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			es := estype.Elasticsearch{
+				Spec: estype.ElasticsearchSpec{
+					Version:                  "7.2.0",
+					ExternalServiceNodeSpecs: tt.nodeSpecs,
+					Nodes:                    nodes,
+				},
+			}
+			ctx, err := NewValidationContext(nil, es)
+			require.NoError(t, err)
+			want := validation.OK
+			if tt.wantErr != "" {
+				want = validation.Result{Allowed: false, Reason: tt.wantErr, Error: fmt.Errorf(tt.wantErr)}
+			}
+			require.Equal(t, want, validExternalServiceNodeSpecs(*ctx))
+		})
+	}
+}
+
 func Test_pvcModified(t *testing.T) {
 	failedValidation := validation.Result{Allowed: false, Reason: pvcImmutableMsg}
 	current := getEsCluster()
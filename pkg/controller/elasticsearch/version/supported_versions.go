@@ -41,6 +41,22 @@ func SupportedVersions(v version.Version) *LowestHighestSupportedVersions {
 	}
 }
 
+// FullClusterRestartSupportedVersions returns the range of versions a cluster can be upgraded from to the given version
+// with a full cluster restart. Nodes of different versions never run together during a full cluster restart, which
+// allows a wider range than the wire-format compatibility required by rolling upgrades.
+func FullClusterRestartSupportedVersions(v version.Version) *LowestHighestSupportedVersions {
+	switch v.Major {
+	case 6, 7:
+		return &LowestHighestSupportedVersions{
+			// indices created in any 6.x version can be read by 6.x and 7.x nodes
+			LowestSupportedVersion:  version.MustParse("6.0.0"),
+			HighestSupportedVersion: SupportedVersions(v).HighestSupportedVersion,
+		}
+	default:
+		return nil
+	}
+}
+
 // VerifySupportsExistingPods checks the given pods against the supported version range in lh.
 func (lh LowestHighestSupportedVersions) VerifySupportsExistingPods(
 	pods []corev1.Pod,
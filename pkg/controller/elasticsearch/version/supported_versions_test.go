@@ -73,6 +73,44 @@ func TestSupportedVersions(t *testing.T) {
 	}
 }
 
+func TestFullClusterRestartSupportedVersions(t *testing.T) {
+	tests := []struct {
+		name        string
+		v           version.Version
+		supported   []version.Version
+		unsupported []version.Version
+	}{
+		{
+			name:        "6.x",
+			v:           version.MustParse("6.8.0"),
+			supported:   []version.Version{version.MustParse("6.0.0"), version.MustParse("6.8.0")},
+			unsupported: []version.Version{version.MustParse("5.6.0"), version.MustParse("7.0.0")},
+		},
+		{
+			name: "7.x",
+			v:    version.MustParse("7.2.0"),
+			supported: []version.Version{
+				version.MustParse("6.0.0"), // not wire compatible, but indices are readable
+				version.MustParse("6.5.0"),
+				version.MustParse("7.99.99"),
+			},
+			unsupported: []version.Version{version.MustParse("5.6.0"), version.MustParse("8.0.0")},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			vs := FullClusterRestartSupportedVersions(tt.v)
+			for _, v := range tt.supported {
+				require.NoError(t, vs.Supports(v))
+			}
+			for _, v := range tt.unsupported {
+				require.Error(t, vs.Supports(v))
+			}
+		})
+	}
+	require.Nil(t, FullClusterRestartSupportedVersions(version.MustParse("8.0.0")))
+}
+
 func Test_lowestHighestSupportedVersions_VerifySupportsExistingPods(t *testing.T) {
 	newPodWithVersionLabel := func(v version.Version) corev1.Pod {
 		return corev1.Pod{
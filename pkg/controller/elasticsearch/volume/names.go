@@ -16,10 +16,14 @@ const (
 
 	TransportCertificatesSecretVolumeName      = "elastic-internal-transport-certificates"
 	TransportCertificatesSecretVolumeMountPath = "/usr/share/elasticsearch/config/transport-certs"
+	TransportTrustRestrictionsFile             = "trust.yml"
 
 	HTTPCertificatesSecretVolumeName      = "elastic-internal-http-certificates"
 	HTTPCertificatesSecretVolumeMountPath = "/usr/share/elasticsearch/config/http-certs"
 
+	RealmVolumeNamePrefix = "elastic-internal-realm-"
+	RealmsVolumeMountPath = "/usr/share/elasticsearch/config/realms"
+
 	XPackFileRealmVolumeName      = "elastic-internal-xpack-file-realm"
 	XPackFileRealmVolumeMountPath = "/mnt/elastic-internal/xpack-file-realm"
 
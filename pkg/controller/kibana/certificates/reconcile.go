@@ -23,6 +23,7 @@ func Reconcile(
 	kb v1alpha1.Kibana,
 	services []coverv1.Service,
 	rotation certificates.RotationParams,
+	keyParams certificates.KeyParams,
 ) *reconciler.Results {
 	selfSignedCert := kb.Spec.HTTP.TLS.SelfSignedCertificate
 	if selfSignedCert != nil && selfSignedCert.Disabled {
@@ -30,17 +31,24 @@ func Reconcile(
 	}
 	results := reconciler.Results{}
 
+	keyParams = keyParams.WithOverrides(kb.Spec.HTTP.TLS.PrivateKey)
+	if err := keyParams.Validate(); err != nil {
+		return results.WithError(err)
+	}
+
 	labels := label.NewLabels(kb.Name)
 
 	// reconcile CA certs first
-	httpCa, err := certificates.ReconcileCAForOwner(
+	httpCa, err := certificates.ReconcileCA(
 		d.K8sClient(),
 		d.Scheme(),
 		name.KBNamer,
 		&kb,
 		labels,
 		certificates.HTTPCAType,
+		kb.Spec.HTTP.TLS.CertificateAuthority,
 		rotation,
+		keyParams,
 	)
 	if err != nil {
 		return results.WithError(err)
@@ -57,10 +65,11 @@ func Reconcile(
 		&kb,
 		name.KBNamer,
 		httpCa,
-		kb.Spec.HTTP.TLS,
+		http.TLSOptions(kb.Spec.HTTP),
 		labels,
 		services,
 		rotation, // todo correct rotation
+		keyParams,
 	)
 	if err != nil {
 		return results.WithError(err)
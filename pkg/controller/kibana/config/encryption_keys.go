@@ -0,0 +1,119 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package config
+
+import (
+	"reflect"
+
+	"github.com/elastic/cloud-on-k8s/pkg/apis/kibana/v1alpha1"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/reconciler"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/settings"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/version"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/kibana/label"
+	kbname "github.com/elastic/cloud-on-k8s/pkg/controller/kibana/name"
+	"github.com/elastic/cloud-on-k8s/pkg/utils/k8s"
+	"github.com/elastic/cloud-on-k8s/pkg/utils/stringsutil"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/rand"
+	"k8s.io/client-go/kubernetes/scheme"
+)
+
+const (
+	// EncryptionKeysRotationAnnotation can be set on a Kibana resource to request the rotation of its encryption keys.
+	// New keys are generated every time its value changes.
+	EncryptionKeysRotationAnnotation = "kibana.k8s.elastic.co/encryption-keys-rotation"
+
+	encryptionKeysSecretSuffix = "encryption-keys"
+	// encryptionKeyLength is the length of the generated keys, Kibana requires at least 32 characters.
+	encryptionKeyLength = 32
+)
+
+// encryptedSavedObjectsMinVersion is the first Kibana version supporting the encryption of saved objects.
+var encryptedSavedObjectsMinVersion = version.MustParse("7.1.0")
+
+// EncryptionKeysSecretName returns the name of the secret holding the encryption keys of the given Kibana resource.
+func EncryptionKeysSecretName(kb v1alpha1.Kibana) string {
+	return kbname.KBNamer.Suffix(kb.Name, encryptionKeysSecretSuffix)
+}
+
+// encryptionKeySettings returns the encryption key settings supported by the given Kibana version,
+// excluding the ones already specified in the user configuration.
+func encryptionKeySettings(kb v1alpha1.Kibana, ver version.Version) ([]string, error) {
+	keys := []string{XpackSecurityEncryptionKey, XpackReportingEncryptionKey}
+	if ver.IsSameOrAfter(encryptedSavedObjectsMinVersion) {
+		keys = append(keys, XpackEncryptedSavedObjectsEncryptionKey)
+	}
+	if kb.Spec.Config == nil {
+		return keys, nil
+	}
+	userSettings, err := settings.NewCanonicalConfigFrom(kb.Spec.Config.Data)
+	if err != nil {
+		return nil, err
+	}
+	for _, userKey := range userSettings.HasKeys(keys) {
+		keys = stringsutil.RemoveStringInSlice(userKey, keys)
+	}
+	return keys, nil
+}
+
+// ReconcileEncryptionKeysSecret reconciles the secret holding the encryption keys of the given Kibana resource.
+// The keys are generated once and preserved across reconciliations, so that all the Kibana instances share the
+// same keys and user sessions survive restarts. A key is generated again if it is removed from the secret, all
+// keys are generated again if the value of the rotation annotation of the Kibana resource changes.
+// The secret is meant to be injected into the Kibana keystore.
+func ReconcileEncryptionKeysSecret(client k8s.Client, kb v1alpha1.Kibana, ver version.Version) (*corev1.Secret, error) {
+	keys, err := encryptionKeySettings(kb, ver)
+	if err != nil {
+		return nil, err
+	}
+	rotation := kb.Annotations[EncryptionKeysRotationAnnotation]
+
+	expected := corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: kb.Namespace,
+			Name:      EncryptionKeysSecretName(kb),
+			Labels:    label.NewLabels(kb.Name),
+			Annotations: map[string]string{
+				EncryptionKeysRotationAnnotation: rotation,
+			},
+		},
+		Data: make(map[string][]byte, len(keys)),
+	}
+	for _, key := range keys {
+		expected.Data[key] = []byte(rand.String(encryptionKeyLength))
+	}
+
+	reconciled := corev1.Secret{}
+	return &reconciled, reconciler.ReconcileResource(reconciler.Params{
+		Client:     client,
+		Scheme:     scheme.Scheme,
+		Owner:      &kb,
+		Expected:   &expected,
+		Reconciled: &reconciled,
+		NeedsUpdate: func() bool {
+			// re-use the existing keys, unless a rotation was requested
+			if reconciled.Annotations[EncryptionKeysRotationAnnotation] == rotation {
+				for key := range expected.Data {
+					if existing := reconciled.Data[key]; len(existing) > 0 {
+						expected.Data[key] = existing
+					}
+				}
+			}
+			return !reflect.DeepEqual(reconciled.Labels, expected.Labels) ||
+				reconciled.Annotations[EncryptionKeysRotationAnnotation] != rotation ||
+				len(reconciled.Data) != len(expected.Data) ||
+				(len(expected.Data) > 0 && !reflect.DeepEqual(reconciled.Data, expected.Data))
+		},
+		UpdateReconciled: func() {
+			reconciled.Labels = expected.Labels
+			if reconciled.Annotations == nil {
+				reconciled.Annotations = make(map[string]string)
+			}
+			reconciled.Annotations[EncryptionKeysRotationAnnotation] = rotation
+			reconciled.Data = expected.Data
+		},
+	})
+}
@@ -0,0 +1,148 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package config
+
+import (
+	"testing"
+
+	commonv1alpha1 "github.com/elastic/cloud-on-k8s/pkg/apis/common/v1alpha1"
+	"github.com/elastic/cloud-on-k8s/pkg/apis/kibana/v1alpha1"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/version"
+	"github.com/elastic/cloud-on-k8s/pkg/utils/k8s"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func Test_encryptionKeySettings(t *testing.T) {
+	tests := []struct {
+		name    string
+		kb      v1alpha1.Kibana
+		version version.Version
+		want    []string
+	}{
+		{
+			name:    "6.x: no saved objects encryption key",
+			kb:      defaultKibana,
+			version: version.MustParse("6.8.0"),
+			want:    []string{XpackSecurityEncryptionKey, XpackReportingEncryptionKey},
+		},
+		{
+			name:    "7.x: all keys",
+			kb:      defaultKibana,
+			version: version.MustParse("7.2.0"),
+			want:    []string{XpackSecurityEncryptionKey, XpackReportingEncryptionKey, XpackEncryptedSavedObjectsEncryptionKey},
+		},
+		{
+			name: "keys specified by the user are ignored",
+			kb: v1alpha1.Kibana{
+				Spec: v1alpha1.KibanaSpec{
+					Config: &commonv1alpha1.Config{Data: map[string]interface{}{
+						XpackReportingEncryptionKey: "user-provided-key",
+					}},
+				},
+			},
+			version: version.MustParse("7.2.0"),
+			want:    []string{XpackSecurityEncryptionKey, XpackEncryptedSavedObjectsEncryptionKey},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := encryptionKeySettings(tt.kb, tt.version)
+			require.NoError(t, err)
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestReconcileEncryptionKeysSecret(t *testing.T) {
+	ver := version.MustParse("7.2.0")
+	existingKeys := func(rotation string) *corev1.Secret {
+		return &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace:   "test-ns",
+				Name:        "test-kb-encryption-keys",
+				Annotations: map[string]string{EncryptionKeysRotationAnnotation: rotation},
+			},
+			Data: map[string][]byte{
+				XpackSecurityEncryptionKey:  []byte("existing-security-key"),
+				XpackReportingEncryptionKey: []byte("existing-reporting-key"),
+			},
+		}
+	}
+	withRotation := func(rotation string) v1alpha1.Kibana {
+		kb := *defaultKibana.DeepCopy()
+		kb.Annotations = map[string]string{EncryptionKeysRotationAnnotation: rotation}
+		return kb
+	}
+	tests := []struct {
+		name           string
+		kb             v1alpha1.Kibana
+		initialObjects []runtime.Object
+		assertions     func(secret corev1.Secret)
+	}{
+		{
+			name: "keys are generated",
+			kb:   defaultKibana,
+			assertions: func(secret corev1.Secret) {
+				require.Len(t, secret.Data, 3)
+				for _, key := range secret.Data {
+					require.Len(t, key, encryptionKeyLength)
+				}
+			},
+		},
+		{
+			name:           "existing keys are preserved, missing ones are generated",
+			kb:             defaultKibana,
+			initialObjects: []runtime.Object{existingKeys("")},
+			assertions: func(secret corev1.Secret) {
+				require.Len(t, secret.Data, 3)
+				require.Equal(t, "existing-security-key", string(secret.Data[XpackSecurityEncryptionKey]))
+				require.Equal(t, "existing-reporting-key", string(secret.Data[XpackReportingEncryptionKey]))
+				require.Len(t, secret.Data[XpackEncryptedSavedObjectsEncryptionKey], encryptionKeyLength)
+			},
+		},
+		{
+			name:           "keys are preserved while the rotation annotation does not change",
+			kb:             withRotation("1"),
+			initialObjects: []runtime.Object{existingKeys("1")},
+			assertions: func(secret corev1.Secret) {
+				require.Equal(t, "existing-security-key", string(secret.Data[XpackSecurityEncryptionKey]))
+				require.Equal(t, "existing-reporting-key", string(secret.Data[XpackReportingEncryptionKey]))
+			},
+		},
+		{
+			name:           "keys are rotated on rotation annotation change",
+			kb:             withRotation("2"),
+			initialObjects: []runtime.Object{existingKeys("1")},
+			assertions: func(secret corev1.Secret) {
+				require.Equal(t, "2", secret.Annotations[EncryptionKeysRotationAnnotation])
+				require.Len(t, secret.Data, 3)
+				assert.NotEqual(t, "existing-security-key", string(secret.Data[XpackSecurityEncryptionKey]))
+				assert.NotEqual(t, "existing-reporting-key", string(secret.Data[XpackReportingEncryptionKey]))
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sc := scheme.Scheme
+			require.NoError(t, v1alpha1.SchemeBuilder.AddToScheme(sc))
+			k8sClient := k8s.WrapClient(fake.NewFakeClientWithScheme(sc, tt.initialObjects...))
+
+			_, err := ReconcileEncryptionKeysSecret(k8sClient, tt.kb, ver)
+			require.NoError(t, err)
+
+			var secret corev1.Secret
+			err = k8sClient.Get(types.NamespacedName{Namespace: "test-ns", Name: EncryptionKeysSecretName(tt.kb)}, &secret)
+			require.NoError(t, err)
+			tt.assertions(secret)
+		})
+	}
+}
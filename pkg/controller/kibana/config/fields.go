@@ -23,4 +23,8 @@ const (
 	ServerSSLEnabled     = "server.ssl.enabled"
 	ServerSSLCertificate = "server.ssl.certificate"
 	ServerSSLKey         = "server.ssl.key"
+
+	XpackSecurityEncryptionKey              = "xpack.security.encryptionKey"
+	XpackReportingEncryptionKey             = "xpack.reporting.encryptionKey"
+	XpackEncryptedSavedObjectsEncryptionKey = "xpack.encryptedSavedObjects.encryptionKey"
 )
@@ -85,6 +85,10 @@ func kibanaTLSSettings(kb v1alpha1.Kibana) map[string]interface{} {
 }
 
 func elasticsearchTLSSettings(kb v1alpha1.Kibana) map[string]interface{} {
+	if !kb.AssociationConf().CAIsConfigured() {
+		// rely on the system trust store, e.g. for an external Elasticsearch cluster with a publicly trusted certificate
+		return nil
+	}
 	esCertsVolumeMountPath := es.CaCertSecretVolume(kb).VolumeMount().MountPath
 	return map[string]interface{}{
 		ElasticsearchSslCertificateAuthorities: path.Join(esCertsVolumeMountPath, certificates.CertFileName),
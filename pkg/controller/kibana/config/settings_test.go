@@ -23,9 +23,6 @@ elasticsearch:
   - ""
   username: ""
   password: ""
-  ssl:
-    certificateAuthorities: /usr/share/kibana/config/elasticsearch-certs/tls.crt
-    verificationMode: certificate
 server:
   host: "0"
   name: ""
@@ -101,6 +98,20 @@ func TestNewConfigSettings(t *testing.T) {
 			},
 			want: append(defaultConfig, []byte(`foo: bar`)...),
 		},
+		{
+			name: "with Elasticsearch CA",
+			args: args{
+				kb: func() v1alpha1.Kibana {
+					kb := v1alpha1.Kibana{}
+					kb.SetAssociationConf(&commonv1alpha1.AssociationConf{CASecretName: "es-ca"})
+					return kb
+				}(),
+			},
+			want: append(defaultConfig, []byte(`
+elasticsearch.ssl.certificateAuthorities: /usr/share/kibana/config/elasticsearch-certs/tls.crt
+elasticsearch.ssl.verificationMode: certificate
+`)...),
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
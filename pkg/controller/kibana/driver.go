@@ -8,6 +8,7 @@ import (
 	"crypto/sha256"
 	"fmt"
 
+	commonv1alpha1 "github.com/elastic/cloud-on-k8s/pkg/apis/common/v1alpha1"
 	kbtype "github.com/elastic/cloud-on-k8s/pkg/apis/kibana/v1alpha1"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/common"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/common/certificates"
@@ -15,6 +16,8 @@ import (
 	driver2 "github.com/elastic/cloud-on-k8s/pkg/controller/common/driver"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/common/events"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/common/finalizer"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/heap"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/ingress"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/common/keystore"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/common/operator"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/common/reconciler"
@@ -48,6 +51,7 @@ var initContainersParameters = keystore.InitContainerParameters{
 type driver struct {
 	client          k8s.Client
 	scheme          *runtime.Scheme
+	version         version.Version
 	settingsFactory func(kb kbtype.Kibana) map[string]interface{}
 	dynamicWatches  watches.DynamicWatches
 	recorder        record.EventRecorder
@@ -85,7 +89,7 @@ func secretWatchFinalizer(kibana kbtype.Kibana, watches watches.DynamicWatches)
 	}
 }
 
-func (d *driver) deploymentParams(kb *kbtype.Kibana) (*DeploymentParams, error) {
+func (d *driver) deploymentParams(kb *kbtype.Kibana, heapMemoryPercentage int) (*DeploymentParams, error) {
 	// setup a keystore with secure settings in an init container, if specified by the user
 	keystoreResources, err := keystore.NewResources(
 		d,
@@ -98,7 +102,7 @@ func (d *driver) deploymentParams(kb *kbtype.Kibana) (*DeploymentParams, error)
 		return nil, err
 	}
 
-	kibanaPodSpec := pod.NewPodTemplateSpec(*kb, keystoreResources)
+	kibanaPodSpec := pod.NewPodTemplateSpec(*kb, keystoreResources, heapMemoryPercentage)
 
 	// Build a checksum of the configuration, which we can use to cause the Deployment to roll Kibana
 	// instances in case of any change in the CA file, secure settings or credentials contents.
@@ -148,10 +152,8 @@ func (d *driver) deploymentParams(kb *kbtype.Kibana) (*DeploymentParams, error)
 
 		// TODO: this is a little ugly as it reaches into the ES controller bits
 		esCertsVolume := es.CaCertSecretVolume(*kb)
-		configVolume := config.SecretVolume(*kb)
 
-		kibanaPodSpec.Spec.Volumes = append(kibanaPodSpec.Spec.Volumes,
-			esCertsVolume.Volume(), configVolume.Volume())
+		kibanaPodSpec.Spec.Volumes = append(kibanaPodSpec.Spec.Volumes, esCertsVolume.Volume())
 
 		for i := range kibanaPodSpec.Spec.InitContainers {
 			kibanaPodSpec.Spec.InitContainers[i].VolumeMounts = append(kibanaPodSpec.Spec.InitContainers[i].VolumeMounts,
@@ -159,10 +161,15 @@ func (d *driver) deploymentParams(kb *kbtype.Kibana) (*DeploymentParams, error)
 		}
 
 		kibanaContainer := pod.GetKibanaContainer(kibanaPodSpec.Spec)
-		kibanaContainer.VolumeMounts = append(kibanaContainer.VolumeMounts,
-			esCertsVolume.VolumeMount(), configVolume.VolumeMount())
+		kibanaContainer.VolumeMounts = append(kibanaContainer.VolumeMounts, esCertsVolume.VolumeMount())
 	}
 
+	// the Kibana configuration is mounted whether Elasticsearch is reached with a CA or not
+	configVolume := config.SecretVolume(*kb)
+	kibanaPodSpec.Spec.Volumes = append(kibanaPodSpec.Spec.Volumes, configVolume.Volume())
+	kibanaContainer := pod.GetKibanaContainer(kibanaPodSpec.Spec)
+	kibanaContainer.VolumeMounts = append(kibanaContainer.VolumeMounts, configVolume.VolumeMount())
+
 	if kb.Spec.HTTP.TLS.Enabled() {
 		// fetch the secret to calculate the checksum
 		var httpCerts corev1.Secret
@@ -180,7 +187,6 @@ func (d *driver) deploymentParams(kb *kbtype.Kibana) (*DeploymentParams, error)
 		// add volume/mount for http certs to pod spec
 		httpCertsVolume := http.HTTPCertSecretVolume(kbname.KBNamer, kb.Name)
 		kibanaPodSpec.Spec.Volumes = append(kibanaPodSpec.Spec.Volumes, httpCertsVolume.Volume())
-		kibanaContainer := pod.GetKibanaContainer(kibanaPodSpec.Spec)
 		kibanaContainer.VolumeMounts = append(kibanaContainer.VolumeMounts, httpCertsVolume.VolumeMount())
 
 	}
@@ -225,11 +231,27 @@ func (d *driver) Reconcile(
 		return results.WithError(err)
 	}
 
-	results.WithResults(kbcerts.Reconcile(d, *kb, []corev1.Service{*svc}, params.CACertRotation))
+	results.WithResults(kbcerts.Reconcile(d, *kb, []corev1.Service{*svc}, params.CACertRotation, params.CertKeyParams))
 	if results.HasError() {
 		return &results
 	}
 
+	if err := ingress.Reconcile(d.client, d.scheme, kb, kbname.KBNamer, kb.Spec.HTTP, *svc, label.NewLabels(kb.Name)); err != nil {
+		return results.WithError(err)
+	}
+
+	if err := reconcileNetworkPolicy(d.client, d.scheme, *kb); err != nil {
+		return results.WithError(err)
+	}
+
+	// only warn about the heap size when the specification changed since the last reconciliation
+	specChanged := kb.Generation != state.originalKibana.Status.ObservedGeneration
+	if container := pod.GetKibanaContainer(kb.Spec.PodTemplate.Spec); specChanged && container != nil {
+		if msg := heap.ExceedsMemoryLimit(*container, pod.HeapOptions); msg != "" {
+			d.recorder.Event(kb, corev1.EventTypeWarning, events.EventReasonValidation, msg)
+		}
+	}
+
 	kbSettings, err := config.NewConfigSettings(d.client, *kb)
 	if err != nil {
 		return results.WithError(err)
@@ -245,7 +267,18 @@ func (d *driver) Reconcile(
 		return results.WithError(err)
 	}
 
-	deploymentParams, err := d.deploymentParams(kb)
+	encryptionKeys, err := config.ReconcileEncryptionKeysSecret(d.client, *kb, d.version)
+	if err != nil {
+		return results.WithError(err)
+	}
+	// inject the encryption keys into the keystore, before the user-provided secure settings so that they can be overridden
+	keystoreKb := kb.DeepCopy()
+	keystoreKb.Spec.SecureSettings = append(
+		[]commonv1alpha1.SecretSource{{SecretName: encryptionKeys.Name}},
+		kb.Spec.SecureSettings...,
+	)
+
+	deploymentParams, err := d.deploymentParams(keystoreKb, params.HeapMemoryPercentage)
 	if err != nil {
 		return results.WithError(err)
 	}
@@ -255,6 +288,7 @@ func (d *driver) Reconcile(
 		return results.WithError(err)
 	}
 	state.UpdateKibanaState(reconciledDp)
+	state.Kibana.Status.Version = kb.Spec.Version
 	return &results
 }
 
@@ -268,6 +302,7 @@ func newDriver(
 	d := driver{
 		client:         client,
 		scheme:         scheme,
+		version:        version,
 		dynamicWatches: watches,
 		recorder:       recorder,
 	}
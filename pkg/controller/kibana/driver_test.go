@@ -13,6 +13,7 @@ import (
 	"github.com/elastic/cloud-on-k8s/pkg/controller/common/certificates/http"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/common/version"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/common/watches"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/kibana/pod"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/kibana/volume"
 	"github.com/elastic/cloud-on-k8s/pkg/utils/k8s"
 	"github.com/stretchr/testify/assert"
@@ -87,6 +88,30 @@ func TestDriverDeploymentParams(t *testing.T) {
 			}(),
 			wantErr: false,
 		},
+		{
+			name: "with external Elasticsearch without CA",
+			args: args{
+				kb: func() *kbtype.Kibana {
+					kb := kibanaFixture()
+					kb.SetAssociationConf(&v1alpha1.AssociationConf{
+						AuthSecretName: "test-auth",
+						AuthSecretKey:  "kibana-user",
+						URL:            "http://external-es:9200",
+					})
+					return kb
+				},
+				initialObjects: defaultInitialObjects,
+			},
+			want: func() *DeploymentParams {
+				params := expectedDeploymentParams()
+				// the configuration is mounted without the Elasticsearch CA
+				spec := &params.PodTemplateSpec.Spec
+				spec.Volumes = append(spec.Volumes[:1], spec.Volumes[2:]...)
+				spec.Containers[0].VolumeMounts = append(spec.Containers[0].VolumeMounts[:1], spec.Containers[0].VolumeMounts[2:]...)
+				return params
+			}(),
+			wantErr: false,
+		},
 		{
 			name: "with podTemplate specified",
 			args: args{
@@ -99,6 +124,10 @@ func TestDriverDeploymentParams(t *testing.T) {
 				for i, c := range p.PodTemplateSpec.Spec.Containers {
 					if c.Name == kbtype.KibanaContainerName {
 						p.PodTemplateSpec.Spec.Containers[i].Resources = customResourceLimits
+						// half of the memory limit is used as heap size
+						p.PodTemplateSpec.Spec.Containers[i].Env = []corev1.EnvVar{
+							{Name: pod.EnvNodeOptions, Value: "--max-old-space-size=1024"},
+						}
 					}
 				}
 				return p
@@ -206,7 +235,7 @@ func TestDriverDeploymentParams(t *testing.T) {
 			d, err := newDriver(client, s, *kbVersion, w, record.NewFakeRecorder(100))
 			assert.NoError(t, err)
 
-			got, err := d.deploymentParams(kb)
+			got, err := d.deploymentParams(kb, 50)
 			if tt.wantErr {
 				require.Error(t, err)
 				return
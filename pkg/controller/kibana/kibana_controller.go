@@ -8,20 +8,29 @@ import (
 	"reflect"
 	"sync/atomic"
 
+	apmv1alpha1 "github.com/elastic/cloud-on-k8s/pkg/apis/apm/v1alpha1"
+	commonv1alpha1 "github.com/elastic/cloud-on-k8s/pkg/apis/common/v1alpha1"
 	kibanav1alpha1 "github.com/elastic/cloud-on-k8s/pkg/apis/kibana/v1alpha1"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/common"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/common/annotation"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/common/association"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/certificates/http"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/common/events"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/common/finalizer"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/ingress"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/common/keystore"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/networkpolicy"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/common/operator"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/reconciler"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/common/version"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/common/watches"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/kibana/label"
+	kbname "github.com/elastic/cloud-on-k8s/pkg/controller/kibana/name"
 	"github.com/elastic/cloud-on-k8s/pkg/utils/k8s"
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
+	extensionsv1beta1 "k8s.io/api/extensions/v1beta1"
+	networkingv1 "k8s.io/api/networking/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
@@ -49,7 +58,10 @@ func Add(mgr manager.Manager, params operator.Parameters) error {
 	if err != nil {
 		return err
 	}
-	return addWatches(c, reconciler)
+	if err := addWatches(c, reconciler); err != nil {
+		return err
+	}
+	return ingress.WatchRoutes(c, mgr.GetRESTMapper(), &kibanav1alpha1.Kibana{})
 }
 
 // newReconciler returns a new reconcile.Reconciler
@@ -93,6 +105,35 @@ func addWatches(c controller.Controller, r *ReconcileKibana) error {
 		return err
 	}
 
+	// Watch ingresses
+	if err := c.Watch(&source.Kind{Type: &extensionsv1beta1.Ingress{}}, &handler.EnqueueRequestForOwner{
+		IsController: true,
+		OwnerType:    &kibanav1alpha1.Kibana{},
+	}); err != nil {
+		return err
+	}
+
+	// Watch network policies
+	if err := c.Watch(&source.Kind{Type: &networkingv1.NetworkPolicy{}}, &handler.EnqueueRequestForOwner{
+		IsController: true,
+		OwnerType:    &kibanav1alpha1.Kibana{},
+	}); err != nil {
+		return err
+	}
+
+	// Watch APM Server resources, to update the network policy of the Kibana they are associated to, or dissociated from
+	if err := c.Watch(&source.Kind{Type: &apmv1alpha1.ApmServer{}}, &networkpolicy.ReferenceHandler{
+		References: func(object runtime.Object) []reconcile.Request {
+			as, ok := object.(*apmv1alpha1.ApmServer)
+			if !ok {
+				return nil
+			}
+			return networkpolicy.ReferencedRequests(as.Spec.KibanaRef, as.Namespace)
+		},
+	}); err != nil {
+		return err
+	}
+
 	// Watch secrets
 	if err := c.Watch(&source.Kind{Type: &corev1.Secret{}}, &handler.EnqueueRequestForOwner{
 		IsController: true,
@@ -185,19 +226,58 @@ func (r *ReconcileKibana) isCompatible(kb *kibanav1alpha1.Kibana) (bool, error)
 }
 
 func (r *ReconcileKibana) doReconcile(request reconcile.Request, kb *kibanav1alpha1.Kibana) (reconcile.Result, error) {
+	state := NewState(request, kb)
+	state.Kibana.Status.ObservedGeneration = kb.Generation
+
 	ver, err := version.Parse(kb.Spec.Version)
+	state.UpdateCondition(common.InvalidCondition(err))
 	if err != nil {
 		k8s.EmitErrorEvent(r.recorder, err, kb, events.EventReasonValidation, "Invalid version '%s': %v", kb.Spec.Version, err)
+		state.UpdateCondition((&reconciler.Results{}).WithError(err).ReconcilingCondition())
+		if err := r.updateStatus(state); err != nil && !errors.IsConflict(err) {
+			return reconcile.Result{}, err
+		}
 		return reconcile.Result{}, err
 	}
 
-	state := NewState(request, kb)
-	driver, err := newDriver(r, r.scheme, *ver, r.dynamicWatches, r.recorder)
+	// a version upgrade must wait for the Elasticsearch cluster to run the new version
+	upgradeStatus, err := association.ReconcileUpgradeStatus(r.Client, r.recorder, kb, *ver, kb.Status.UpgradeStatus)
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+	state.Kibana.Status.UpgradeStatus = upgradeStatus
+	upgradeHeldBack := upgradeStatus == commonv1alpha1.WaitingForElasticsearchUpgrade
+	reconciledVersion := association.ReconciledVersion(kb.Spec.Version, upgradeStatus, kb.Status.Version)
+	if reconciledVersion == "" {
+		// Kibana is not deployed yet, there is nothing to reconcile until the upgrade proceeds
+		state.UpdateCondition(association.WaitingForElasticsearchUpgradeCondition(*ver))
+		state.UpdateCondition((&reconciler.Results{}).WithResult(association.UpgradeRequeue).ReconcilingCondition())
+		if err := r.updateStatus(state); err != nil && !errors.IsConflict(err) {
+			return reconcile.Result{}, err
+		}
+		return association.UpgradeRequeue, nil
+	}
+	// keep reconciling the deployed version while the version change is held back
+	reconciledKb, reconciledVer := kb, ver
+	if reconciledVersion != kb.Spec.Version {
+		reconciledKb = kb.DeepCopy()
+		reconciledKb.Spec.Version = reconciledVersion
+		if reconciledVer, err = version.Parse(reconciledVersion); err != nil {
+			return reconcile.Result{}, err
+		}
+	}
+
+	driver, err := newDriver(r, r.scheme, *reconciledVer, r.dynamicWatches, r.recorder)
 	if err != nil {
 		return reconcile.Result{}, err
 	}
 	// version specific reconcile
-	results := driver.Reconcile(&state, kb, r.params)
+	results := driver.Reconcile(&state, reconciledKb, r.params)
+	if upgradeHeldBack {
+		state.UpdateCondition(association.WaitingForElasticsearchUpgradeCondition(*ver))
+		results.WithResult(association.UpgradeRequeue)
+	}
+	state.UpdateCondition(results.ReconcilingCondition())
 
 	// update status
 	err = r.updateStatus(state)
@@ -228,5 +308,6 @@ func (r *ReconcileKibana) finalizersFor(kb *kibanav1alpha1.Kibana) []finalizer.F
 	return []finalizer.Finalizer{
 		secretWatchFinalizer(*kb, r.dynamicWatches),
 		keystore.Finalizer(k8s.ExtractNamespacedName(kb), r.dynamicWatches, kb.Kind()),
+		http.DynamicWatchesFinalizer(r.dynamicWatches, kb.Kind(), kb.Name, kbname.KBNamer),
 	}
 }
@@ -0,0 +1,62 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package kibana
+
+import (
+	"sort"
+
+	apmv1alpha1 "github.com/elastic/cloud-on-k8s/pkg/apis/apm/v1alpha1"
+	kbtype "github.com/elastic/cloud-on-k8s/pkg/apis/kibana/v1alpha1"
+	apmlabels "github.com/elastic/cloud-on-k8s/pkg/controller/apmserver/labels"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/networkpolicy"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/kibana/label"
+	kbname "github.com/elastic/cloud-on-k8s/pkg/controller/kibana/name"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/kibana/pod"
+	"github.com/elastic/cloud-on-k8s/pkg/utils/k8s"
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// reconcileNetworkPolicy ensures the NetworkPolicy of Kibana exists if enabled in the spec, and is deleted otherwise.
+// The HTTP port is only reachable from the pods of the APM Servers associated to Kibana and the sources specified in the spec.
+func reconcileNetworkPolicy(c k8s.Client, scheme *runtime.Scheme, kb kbtype.Kibana) error {
+	if !kb.Spec.NetworkPolicy.Enabled {
+		return networkpolicy.Reconcile(c, scheme, &kb, false, newNetworkPolicy(kb, nil))
+	}
+
+	httpPeers, err := associatedApmServerPeers(c, kb)
+	if err != nil {
+		return err
+	}
+	httpPeers = append(httpPeers, kb.Spec.NetworkPolicy.HTTPFrom...)
+
+	return networkpolicy.Reconcile(c, scheme, &kb, true, newNetworkPolicy(kb, httpPeers))
+}
+
+func newNetworkPolicy(kb kbtype.Kibana, httpPeers []networkingv1.NetworkPolicyPeer) networkingv1.NetworkPolicy {
+	labels := label.NewLabels(kb.Name)
+	return networkpolicy.New(kbname.KBNamer, &kb, labels, labels, networkpolicy.PortRule(pod.HTTPPort, httpPeers...))
+}
+
+// associatedApmServerPeers returns peers matching the pods of the APM Servers referencing Kibana, in a stable order.
+func associatedApmServerPeers(c k8s.Client, kb kbtype.Kibana) ([]networkingv1.NetworkPolicyPeer, error) {
+	var apmServers apmv1alpha1.ApmServerList
+	if err := c.List(&client.ListOptions{}, &apmServers); err != nil {
+		return nil, err
+	}
+	sort.Slice(apmServers.Items, func(i, j int) bool {
+		return k8s.LessNamespacedName(&apmServers.Items[i], &apmServers.Items[j])
+	})
+
+	kbNSN := k8s.ExtractNamespacedName(&kb)
+	var peers []networkingv1.NetworkPolicyPeer
+	for _, as := range apmServers.Items {
+		if networkpolicy.References(as.Spec.KibanaRef, as.Namespace, kbNSN) {
+			peers = append(peers, networkpolicy.PodsPeer(kb.Namespace, as.Namespace, apmlabels.NewLabels(as.Name)))
+		}
+	}
+	return peers, nil
+}
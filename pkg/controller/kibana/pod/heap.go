@@ -0,0 +1,49 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package pod
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/heap"
+)
+
+// EnvNodeOptions is the environment variable containing the options of the Node.js process running Kibana.
+const EnvNodeOptions = "NODE_OPTIONS"
+
+var (
+	// maxOldSpaceSizeRegexp matches the Node.js option setting the heap size in MB, capturing its value
+	maxOldSpaceSizeRegexp = regexp.MustCompile(`^--max[-_]old[-_]space[-_]size=(\d+)$`)
+
+	// HeapOptions describes how the heap size of Kibana is specified.
+	HeapOptions = heap.Options{
+		EnvVarName: EnvNodeOptions,
+		Parse:      parseNodeHeapSizeMi,
+		Format: func(sizeMi int64) string {
+			return fmt.Sprintf("--max-old-space-size=%d", sizeMi)
+		},
+	}
+)
+
+// parseNodeHeapSizeMi returns the heap size specified in the given Node.js options, the last occurrence taking precedence.
+func parseNodeHeapSizeMi(opts string) (int64, bool) {
+	var match []string
+	for _, opt := range strings.Fields(opts) {
+		if m := maxOldSpaceSizeRegexp.FindStringSubmatch(opt); m != nil {
+			match = m
+		}
+	}
+	if match == nil {
+		return 0, false
+	}
+	size, err := strconv.ParseInt(match[1], 10, 64)
+	if err != nil {
+		return 0, true
+	}
+	return size, true
+}
@@ -0,0 +1,32 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package pod
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_parseNodeHeapSizeMi(t *testing.T) {
+	tests := []struct {
+		opts          string
+		wantSizeMi    int64
+		wantSpecified bool
+	}{
+		{opts: "", wantSizeMi: 0, wantSpecified: false},
+		{opts: "--no-warnings", wantSizeMi: 0, wantSpecified: false},
+		{opts: "--max-old-space-size=2048", wantSizeMi: 2048, wantSpecified: true},
+		{opts: "--no-warnings --max_old_space_size=1024", wantSizeMi: 1024, wantSpecified: true},
+		{opts: "--max-old-space-size=1024 --max-old-space-size=512", wantSizeMi: 512, wantSpecified: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.opts, func(t *testing.T) {
+			sizeMi, specified := parseNodeHeapSizeMi(tt.opts)
+			assert.Equal(t, tt.wantSizeMi, sizeMi)
+			assert.Equal(t, tt.wantSpecified, specified)
+		})
+	}
+}
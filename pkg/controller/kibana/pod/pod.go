@@ -54,12 +54,13 @@ func imageWithVersion(image string, version string) string {
 	return stringsutil.Concat(image, ":", version)
 }
 
-func NewPodTemplateSpec(kb v1alpha1.Kibana, keystore *keystore.Resources) corev1.PodTemplateSpec {
+func NewPodTemplateSpec(kb v1alpha1.Kibana, keystore *keystore.Resources, heapMemoryPercentage int) corev1.PodTemplateSpec {
 	builder := defaults.NewPodTemplateBuilder(kb.Spec.PodTemplate, v1alpha1.KibanaContainerName).
 		WithLabels(label.NewLabels(kb.Name)).
 		WithDockerImage(kb.Spec.Image, imageWithVersion(defaultImageRepositoryAndName, kb.Spec.Version)).
 		WithReadinessProbe(readinessProbe(kb.Spec.HTTP.TLS.Enabled())).
 		WithPorts(ports).
+		WithHeapSize(HeapOptions, heapMemoryPercentage, 0).
 		WithVolumes(volume.KibanaDataVolume.Volume()).
 		WithVolumeMounts(volume.KibanaDataVolume.VolumeMount())
 
@@ -5,6 +5,8 @@
 package kibana
 
 import (
+	"strconv"
+
 	corev1 "k8s.io/api/core/v1"
 
 	kibanav1alpha1 "github.com/elastic/cloud-on-k8s/pkg/apis/kibana/v1alpha1"
@@ -12,6 +14,7 @@ import (
 	"github.com/elastic/cloud-on-k8s/pkg/controller/kibana/label"
 	kbname "github.com/elastic/cloud-on-k8s/pkg/controller/kibana/name"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/kibana/pod"
+	"github.com/elastic/cloud-on-k8s/pkg/utils/stringsutil"
 )
 
 func NewService(kb kibanav1alpha1.Kibana) *corev1.Service {
@@ -33,3 +36,8 @@ func NewService(kb kibanav1alpha1.Kibana) *corev1.Service {
 
 	return defaults.SetServiceDefaults(&svc, labels, labels, ports)
 }
+
+// ExternalServiceURL returns the URL used to reach Kibana's external endpoint.
+func ExternalServiceURL(kb kibanav1alpha1.Kibana) string {
+	return stringsutil.Concat(kb.Spec.HTTP.Scheme(), "://", kbname.HTTPService(kb.Name), ".", kb.Namespace, ".svc:", strconv.Itoa(pod.HTTPPort))
+}
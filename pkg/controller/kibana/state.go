@@ -5,7 +5,9 @@
 package kibana
 
 import (
+	commonv1alpha1 "github.com/elastic/cloud-on-k8s/pkg/apis/common/v1alpha1"
 	"github.com/elastic/cloud-on-k8s/pkg/apis/kibana/v1alpha1"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common"
 	v1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
@@ -35,4 +37,11 @@ func (s State) UpdateKibanaState(deployment v1.Deployment) {
 			s.Kibana.Status.Health = v1alpha1.KibanaGreen
 		}
 	}
+	s.UpdateCondition(common.DeploymentReadyCondition(deployment))
+	s.UpdateCondition(common.DeploymentUpgradeCondition(deployment))
+}
+
+// UpdateCondition sets the given condition in the Kibana status.
+func (s State) UpdateCondition(condition commonv1alpha1.Condition) {
+	s.Kibana.Status.Conditions = s.Kibana.Status.Conditions.Set(condition)
 }
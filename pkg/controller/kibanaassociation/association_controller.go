@@ -19,12 +19,14 @@ import (
 	"github.com/elastic/cloud-on-k8s/pkg/controller/common/finalizer"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/common/operator"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/common/user"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/version"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/common/watches"
 	esname "github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/name"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/services"
 	elasticsearchuser "github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/user"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/kibana/label"
 	kblabel "github.com/elastic/cloud-on-k8s/pkg/controller/kibana/label"
+	kbname "github.com/elastic/cloud-on-k8s/pkg/controller/kibana/name"
 	"github.com/elastic/cloud-on-k8s/pkg/utils/k8s"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
@@ -55,6 +57,10 @@ import (
 // - copy ES CA public cert secret into Kibana namespace
 // - reconcile on any change from watching Kibana, Elasticsearch, Users and secrets
 //
+// If the Kibana resource references an external Elasticsearch cluster instead, the
+// user-provided credentials and CA are copied into the same secrets and the connectivity
+// to the external cluster is checked.
+//
 // If reference to an Elasticsearch cluster is not set in the Kibana resource,
 // this controller does nothing.
 
@@ -86,11 +92,12 @@ func Add(mgr manager.Manager, params operator.Parameters) error {
 func newReconciler(mgr manager.Manager, params operator.Parameters) *ReconcileAssociation {
 	client := k8s.WrapClient(mgr.GetClient())
 	return &ReconcileAssociation{
-		Client:     client,
-		scheme:     mgr.GetScheme(),
-		watches:    watches.NewDynamicWatches(),
-		recorder:   mgr.GetRecorder(name),
-		Parameters: params,
+		Client:            client,
+		scheme:            mgr.GetScheme(),
+		watches:           watches.NewDynamicWatches(),
+		recorder:          mgr.GetRecorder(name),
+		Parameters:        params,
+		checkConnectivity: association.CheckConnectivity,
 	}
 }
 
@@ -113,6 +120,8 @@ type ReconcileAssociation struct {
 	recorder record.EventRecorder
 	watches  watches.DynamicWatches
 	operator.Parameters
+	// checkConnectivity verifies that an external Elasticsearch cluster can be reached
+	checkConnectivity association.ConnectivityChecker
 	// iteration is the number of times this controller has run its Reconcile method
 	iteration uint64
 }
@@ -169,6 +178,7 @@ func (r *ReconcileAssociation) Reconcile(request reconcile.Request) (reconcile.R
 	if !reflect.DeepEqual(kibana.Status.AssociationStatus, newStatus) {
 		oldStatus := kibana.Status.AssociationStatus
 		kibana.Status.AssociationStatus = newStatus
+		kibana.Status.Conditions = kibana.Status.Conditions.Set(association.ReadyCondition(newStatus))
 		if err := r.Status().Update(&kibana); err != nil {
 			if apierrors.IsConflict(err) {
 				// Conflicts are expected and will be resolved on next loop
@@ -189,7 +199,7 @@ func (r *ReconcileAssociation) Reconcile(request reconcile.Request) (reconcile.R
 
 func resultFromStatus(status commonv1alpha1.AssociationStatus) reconcile.Result {
 	switch status {
-	case commonv1alpha1.AssociationPending:
+	case commonv1alpha1.AssociationPending, commonv1alpha1.AssociationFailed:
 		return defaultRequeue // retry
 	default:
 		return reconcile.Result{} // we are done or there is not much we can do
@@ -214,6 +224,22 @@ func (r *ReconcileAssociation) reconcileInternal(kibana *kbtype.Kibana) (commonv
 		log.Error(err, "Error while trying to delete orphaned resources. Continuing.", "namespace", kibana.Namespace, "kibana_name", kibana.Name)
 	}
 
+	if kibana.Spec.ExternalElasticsearchRef.IsDefined() {
+		if kibana.Spec.ElasticsearchRef.IsDefined() {
+			r.recorder.Event(kibana, corev1.EventTypeWarning, events.EventAssociationError,
+				"elasticsearchRef and externalElasticsearchRef cannot be set at the same time")
+			return commonv1alpha1.AssociationFailed, nil
+		}
+		// stop watching any ES cluster previously referenced for this Kibana resource
+		r.watches.ElasticsearchClusters.RemoveHandlerForKey(elasticsearchWatchName(kibanaKey))
+		r.watches.Secrets.RemoveHandlerForKey(elasticsearchWatchName(kibanaKey))
+		r.watches.Secrets.RemoveHandlerForKey(esCAWatchName(kibanaKey))
+		r.watches.Secrets.RemoveHandlerForKey(providedPasswordsWatchName(kibanaKey))
+		return r.reconcileExternal(kibana)
+	}
+	// stop watching the secrets of any external ES cluster previously referenced
+	r.watches.Secrets.RemoveHandlerForKey(externalSecretsWatchName(kibanaKey))
+
 	if kibana.Spec.ElasticsearchRef.Name == "" {
 		// stop watching any ES cluster previously referenced for this Kibana resource
 		r.watches.ElasticsearchClusters.RemoveHandlerForKey(elasticsearchWatchName(kibanaKey))
@@ -238,11 +264,10 @@ func (r *ReconcileAssociation) reconcileInternal(kibana *kbtype.Kibana) (commonv
 		return commonv1alpha1.AssociationFailed, err
 	}
 
-	userSecretKey := association.UserKey(kibana, kibanaUserSuffix)
-	// watch the user secret in the ES namespace
+	// watch the user secrets in the ES namespace
 	if err := r.watches.Secrets.AddHandler(watches.NamedWatch{
 		Name:    elasticsearchWatchName(kibanaKey),
-		Watched: []types.NamespacedName{userSecretKey},
+		Watched: association.UserKeys(kibana, kibanaUserSuffix),
 		Watcher: kibanaKey,
 	}); err != nil {
 		return commonv1alpha1.AssociationFailed, err
@@ -268,7 +293,12 @@ func (r *ReconcileAssociation) reconcileInternal(kibana *kbtype.Kibana) (commonv
 		return commonv1alpha1.AssociationFailed, err
 	}
 
-	if err := association.ReconcileEsUser(
+	// watch the user-provided passwords of the ES cluster, which may contain the password of the Kibana user
+	if err := r.reconcileProvidedPasswordsWatch(kibanaKey, es); err != nil {
+		return commonv1alpha1.AssociationFailed, err
+	}
+
+	authSecret, err := association.ReconcileEsUser(
 		r.Client,
 		r.scheme,
 		kibana,
@@ -278,7 +308,10 @@ func (r *ReconcileAssociation) reconcileInternal(kibana *kbtype.Kibana) (commonv
 		},
 		elasticsearchuser.KibanaSystemUserBuiltinRole,
 		kibanaUserSuffix,
-		es); err != nil {
+		es,
+		types.NamespacedName{Namespace: kibana.Namespace, Name: kbname.Deployment(kibana.Name)},
+	)
+	if err != nil {
 		return commonv1alpha1.AssociationPending, err
 	}
 
@@ -286,9 +319,12 @@ func (r *ReconcileAssociation) reconcileInternal(kibana *kbtype.Kibana) (commonv
 	if err != nil {
 		return commonv1alpha1.AssociationPending, err
 	}
+	if caSecretName == "" {
+		// ES CA not created yet, we'll be notified to reconcile later
+		return commonv1alpha1.AssociationPending, nil
+	}
 
 	// construct the expected association configuration
-	authSecret := association.ClearTextSecretKeySelector(kibana, kibanaUserSuffix)
 	expectedESAssoc := &commonv1alpha1.AssociationConf{
 		AuthSecretName: authSecret.Name,
 		AuthSecretKey:  authSecret.Key,
@@ -296,22 +332,106 @@ func (r *ReconcileAssociation) reconcileInternal(kibana *kbtype.Kibana) (commonv
 		URL:            services.ExternalServiceURL(es),
 	}
 
-	// update the association configuration if necessary
-	if !reflect.DeepEqual(expectedESAssoc, kibana.AssociationConf()) {
-		log.Info("Updating Kibana spec with Elasticsearch backend configuration", "namespace", kibana.Namespace, "kibana_name", kibana.Name)
-		if err := association.UpdateAssociationConf(r.Client, kibana, expectedESAssoc); err != nil {
-			if errors.IsConflict(err) {
-				return commonv1alpha1.AssociationPending, nil
-			}
-			log.Error(err, "Failed to update association configuration", "namespace", kibana.Namespace, "kibana_name", kibana.Name)
-			return commonv1alpha1.AssociationPending, err
+	if updated, err := r.updateAssociationConf(kibana, expectedESAssoc); !updated {
+		return commonv1alpha1.AssociationPending, err
+	}
+
+	return commonv1alpha1.AssociationEstablished, nil
+}
+
+// reconcileExternal sets up the association with an Elasticsearch cluster not managed by the operator.
+func (r *ReconcileAssociation) reconcileExternal(kibana *kbtype.Kibana) (commonv1alpha1.AssociationStatus, error) {
+	kibanaKey := k8s.ExtractNamespacedName(kibana)
+	externalRef := kibana.Spec.ExternalElasticsearchRef
+
+	// watch the user-provided secrets to reconcile on any change
+	if err := r.watches.Secrets.AddHandler(watches.NamedWatch{
+		Name:    externalSecretsWatchName(kibanaKey),
+		Watched: association.ExternalSecretKeys(kibana),
+		Watcher: kibanaKey,
+	}); err != nil {
+		return commonv1alpha1.AssociationFailed, err
+	}
+
+	labels := kblabel.NewLabels(kibana.Name)
+	labels[AssociationLabelName] = kibana.Name
+	labels[AssociationLabelNamespace] = kibana.Namespace
+
+	authSecret, err := association.ReconcileExternalUserSecret(r.Client, r.scheme, kibana, labels, kibanaUserSuffix)
+	if err != nil {
+		k8s.EmitErrorEvent(r.recorder, err, kibana, events.EventAssociationError, "Failed to reconcile external Elasticsearch credentials: %v", err)
+		if apierrors.IsNotFound(err) {
+			// we'll be notified to reconcile once the secret is created
+			return commonv1alpha1.AssociationPending, nil
 		}
-		kibana.SetAssociationConf(expectedESAssoc)
+		return commonv1alpha1.AssociationFailed, nil
+	}
+
+	caSecretName, err := association.ReconcileExternalCASecret(r.Client, r.scheme, kibana, labels, ElasticsearchCASecretSuffix)
+	if err != nil {
+		k8s.EmitErrorEvent(r.recorder, err, kibana, events.EventAssociationError, "Failed to reconcile external Elasticsearch CA: %v", err)
+		if apierrors.IsNotFound(err) {
+			return commonv1alpha1.AssociationPending, nil
+		}
+		return commonv1alpha1.AssociationFailed, nil
+	}
+
+	expectedESAssoc := &commonv1alpha1.AssociationConf{
+		AuthSecretName: authSecret.Name,
+		AuthSecretKey:  authSecret.Key,
+		CASecretName:   caSecretName,
+		URL:            externalRef.URL,
+	}
+	if updated, err := r.updateAssociationConf(kibana, expectedESAssoc); !updated {
+		return commonv1alpha1.AssociationPending, err
+	}
+
+	ver, err := version.Parse(kibana.Spec.Version)
+	if err != nil {
+		return commonv1alpha1.AssociationFailed, err
+	}
+	if err := r.checkConnectivity(r.Client, kibana, *ver); err != nil {
+		k8s.EmitErrorEvent(r.recorder, err, kibana, events.EventAssociationError,
+			"Failed to connect to external Elasticsearch cluster %s: %v", externalRef.URL, err)
+		return commonv1alpha1.AssociationFailed, nil
 	}
 
 	return commonv1alpha1.AssociationEstablished, nil
 }
 
+// updateAssociationConf updates the association configuration if necessary.
+// It returns false if the configuration could not be updated yet.
+func (r *ReconcileAssociation) updateAssociationConf(kibana *kbtype.Kibana, expected *commonv1alpha1.AssociationConf) (bool, error) {
+	if reflect.DeepEqual(expected, kibana.AssociationConf()) {
+		return true, nil
+	}
+	log.Info("Updating Kibana spec with Elasticsearch backend configuration", "namespace", kibana.Namespace, "kibana_name", kibana.Name)
+	if err := association.UpdateAssociationConf(r.Client, kibana, expected); err != nil {
+		if errors.IsConflict(err) {
+			return false, nil
+		}
+		log.Error(err, "Failed to update association configuration", "namespace", kibana.Namespace, "kibana_name", kibana.Name)
+		return false, err
+	}
+	kibana.SetAssociationConf(expected)
+	return true, nil
+}
+
+// reconcileProvidedPasswordsWatch sets a watch on the secret containing the user-provided passwords of the given
+// Elasticsearch cluster, or removes it if there is none.
+func (r *ReconcileAssociation) reconcileProvidedPasswordsWatch(kibanaKey types.NamespacedName, es estype.Elasticsearch) error {
+	secretKey, exists := user.ProvidedPasswordsSecretKey(es)
+	if !exists {
+		r.watches.Secrets.RemoveHandlerForKey(providedPasswordsWatchName(kibanaKey))
+		return nil
+	}
+	return r.watches.Secrets.AddHandler(watches.NamedWatch{
+		Name:    providedPasswordsWatchName(kibanaKey),
+		Watched: []types.NamespacedName{secretKey},
+		Watcher: kibanaKey,
+	})
+}
+
 func (r *ReconcileAssociation) reconcileElasticsearchCA(kibana *kbtype.Kibana, es types.NamespacedName) (string, error) {
 	kibanaKey := k8s.ExtractNamespacedName(kibana)
 	// watch ES CA secret to reconcile on any change
@@ -344,6 +464,8 @@ func deleteOrphanedResources(c k8s.Client, kibana *kbtype.Kibana) error {
 		return err
 	}
 
+	externalRef := kibana.Spec.ExternalElasticsearchRef
+
 	// Namespace in reference can be empty, in that case we compare it with the namespace of Kibana
 	var esRefNamespace string
 	if kibana.Spec.ElasticsearchRef.IsDefined() && kibana.Spec.ElasticsearchRef.Namespace != "" {
@@ -354,15 +476,23 @@ func deleteOrphanedResources(c k8s.Client, kibana *kbtype.Kibana) error {
 
 	for _, s := range secrets.Items {
 		if metav1.IsControlledBy(&s, kibana) || hasBeenCreatedBy(&s, kibana) {
-			if !kibana.Spec.ElasticsearchRef.IsDefined() {
+			if !kibana.Spec.ElasticsearchRef.IsDefined() && !externalRef.IsDefined() {
 				// look for association secrets owned by this kibana instance
 				// which should not exist since no ES referenced in the spec
 				log.Info("Deleting secret", "namespace", s.Namespace, "secret_name", s.Name, "kibana_name", kibana.Name)
 				if err := c.Delete(&s); err != nil && !apierrors.IsNotFound(err) {
 					return err
 				}
+			} else if externalRef.IsDefined() && externalRef.CASecret.SecretName == "" &&
+				s.Name == association.ElasticsearchCACertSecretName(kibana, ElasticsearchCASecretSuffix) {
+				// CA copy is not used anymore with an external cluster relying on the system trust store
+				log.Info("Deleting secret", "namespace", s.Namespace, "secret_name", s.Name, "kibana_name", kibana.Name)
+				if err := c.Delete(&s); err != nil && !apierrors.IsNotFound(err) {
+					return err
+				}
 			} else if value, ok := s.Labels[common.TypeLabelName]; ok && value == user.UserType &&
-				esRefNamespace != s.Namespace {
+				(externalRef.IsDefined() || esRefNamespace != s.Namespace) {
+				// User secret is not needed with an external cluster
 				// User secret may live in an other namespace, check if it has changed
 				log.Info("Deleting secret", "namespace", s.Namespace, "secretname", s.Name, "kibana_name", kibana.Name)
 				if err := c.Delete(&s); err != nil && !apierrors.IsNotFound(err) {
@@ -304,6 +304,75 @@ func Test_deleteOrphanedResources(t *testing.T) {
 			},
 			wantErr: false,
 		},
+		{
+			name: "External ES ref without CA, orphan ES user & CA for previous es ref exist",
+			kibana: kbtype.Kibana{
+				ObjectMeta: kibanaFixtureObjectMeta,
+				Spec: kbtype.KibanaSpec{
+					ExternalElasticsearchRef: &commonv1alpha1.ExternalElasticsearchRef{
+						URL:        "https://elasticsearch.example.com:9200",
+						AuthSecret: commonv1alpha1.SecretRef{SecretName: "external-es-credentials"},
+					},
+				},
+			},
+			initialObjects: []runtime.Object{
+				&corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      userSecretName,
+						Namespace: kibanaFixture.Namespace,
+						Labels: map[string]string{
+							AssociationLabelName:      kibanaFixture.Name,
+							AssociationLabelNamespace: kibanaFixture.Namespace,
+						},
+						OwnerReferences: []metav1.OwnerReference{
+							ownerRefFixture,
+						},
+					},
+				},
+				&corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      userName,
+						Namespace: kibanaFixture.Namespace,
+						Labels: map[string]string{
+							AssociationLabelName:      kibanaFixture.Name,
+							AssociationLabelNamespace: kibanaFixture.Namespace,
+							common.TypeLabelName:      user.UserType,
+						},
+						OwnerReferences: []metav1.OwnerReference{
+							esRefFixture,
+						},
+					},
+				},
+				&corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      association.ElasticsearchCACertSecretName(&kibanaFixture, ElasticsearchCASecretSuffix),
+						Namespace: kibanaFixture.Namespace,
+						Labels: map[string]string{
+							AssociationLabelName: kibanaFixture.Name,
+						},
+						OwnerReferences: []metav1.OwnerReference{
+							ownerRefFixture,
+						},
+					},
+				},
+			},
+			postCondition: func(c k8s.Client) {
+				// the secret holding the external credentials is still used
+				assert.NoError(t, c.Get(types.NamespacedName{
+					Namespace: kibanaFixture.Namespace,
+					Name:      userSecretName,
+				}, &corev1.Secret{}))
+				assert.Error(t, c.Get(types.NamespacedName{
+					Namespace: kibanaFixture.Namespace,
+					Name:      userName,
+				}, &corev1.Secret{}))
+				assert.Error(t, c.Get(types.NamespacedName{
+					Namespace: kibanaFixture.Namespace,
+					Name:      association.ElasticsearchCACertSecretName(&kibanaFixture, ElasticsearchCASecretSuffix),
+				}, &corev1.Secret{}))
+			},
+			wantErr: false,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
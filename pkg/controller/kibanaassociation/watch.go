@@ -9,6 +9,7 @@ import (
 	kbtype "github.com/elastic/cloud-on-k8s/pkg/apis/kibana/v1alpha1"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/common/finalizer"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/common/watches"
+	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/types"
 	"sigs.k8s.io/controller-runtime/pkg/controller"
@@ -40,6 +41,14 @@ func addWatches(c controller.Controller, r *ReconcileAssociation) error {
 		return err
 	}
 
+	// Watch Deployments owned by a Kibana resource, to delete the previous user of a password rotation once rolled out
+	if err := c.Watch(&source.Kind{Type: &appsv1.Deployment{}}, &handler.EnqueueRequestForOwner{
+		OwnerType:    &kbtype.Kibana{},
+		IsController: true,
+	}); err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -54,6 +63,18 @@ func esCAWatchName(kibana types.NamespacedName) string {
 	return kibana.Namespace + "-" + kibana.Name + "-ca-watch"
 }
 
+// providedPasswordsWatchName returns the name of the watch setup on the secret containing the user-provided
+// passwords of the Elasticsearch cluster.
+func providedPasswordsWatchName(kibana types.NamespacedName) string {
+	return kibana.Namespace + "-" + kibana.Name + "-provided-passwords-watch"
+}
+
+// externalSecretsWatchName returns the name of the watch setup on the user-provided secrets
+// of an external Elasticsearch cluster.
+func externalSecretsWatchName(kibana types.NamespacedName) string {
+	return kibana.Namespace + "-" + kibana.Name + "-external-es-watch"
+}
+
 // watchFinalizer ensure that we remove watches for Elasticsearch clusters that we are no longer interested in
 // because not referenced by any Kibana resource.
 func watchFinalizer(kibanaKey types.NamespacedName, w watches.DynamicWatches) finalizer.Finalizer {
@@ -62,6 +83,8 @@ func watchFinalizer(kibanaKey types.NamespacedName, w watches.DynamicWatches) fi
 		Execute: func() error {
 			w.ElasticsearchClusters.RemoveHandlerForKey(elasticsearchWatchName(kibanaKey))
 			w.Secrets.RemoveHandlerForKey(esCAWatchName(kibanaKey))
+			w.Secrets.RemoveHandlerForKey(providedPasswordsWatchName(kibanaKey))
+			w.Secrets.RemoveHandlerForKey(externalSecretsWatchName(kibanaKey))
 			return nil
 		},
 	}
@@ -34,6 +34,14 @@ func ExtractNamespacedName(object metav1.Object) types.NamespacedName {
 	}
 }
 
+// LessNamespacedName returns true if the first object is ordered before the second one by namespace, then name.
+func LessNamespacedName(a, b metav1.Object) bool {
+	if a.GetNamespace() != b.GetNamespace() {
+		return a.GetNamespace() < b.GetNamespace()
+	}
+	return a.GetName() < b.GetName()
+}
+
 // IsAvailable checks if both conditions ContainersReady and PodReady of a Pod are true.
 func IsPodReady(pod corev1.Pod) bool {
 	conditionsTrue := 0
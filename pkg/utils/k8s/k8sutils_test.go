@@ -31,6 +31,16 @@ func TestExtractNamespacedName(t *testing.T) {
 	)
 }
 
+func TestLessNamespacedName(t *testing.T) {
+	secret := func(namespace, name string) *v1.Secret {
+		return &v1.Secret{ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name}}
+	}
+	assert.True(t, LessNamespacedName(secret("a", "b"), secret("b", "a")))
+	assert.True(t, LessNamespacedName(secret("a", "a"), secret("a", "b")))
+	assert.False(t, LessNamespacedName(secret("a", "b"), secret("a", "a")))
+	assert.False(t, LessNamespacedName(secret("a", "a"), secret("a", "a")))
+}
+
 func TestGetServiceDNSName(t *testing.T) {
 	type args struct {
 		svc corev1.Service
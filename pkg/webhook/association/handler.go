@@ -0,0 +1,106 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package association
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	apmtype "github.com/elastic/cloud-on-k8s/pkg/apis/apm/v1alpha1"
+	commonv1alpha1 "github.com/elastic/cloud-on-k8s/pkg/apis/common/v1alpha1"
+	kbtype "github.com/elastic/cloud-on-k8s/pkg/apis/kibana/v1alpha1"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/association"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/version"
+	"github.com/elastic/cloud-on-k8s/pkg/utils/k8s"
+	"k8s.io/api/admission/v1beta1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/runtime/inject"
+	logf "sigs.k8s.io/controller-runtime/pkg/runtime/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission/types"
+)
+
+var log = logf.Log.WithName("association-validation")
+
+// ValidationHandler rejects resources associated to an Elasticsearch cluster that specify a version newer than the
+// version of that cluster, which they cannot run, and resources referencing both a managed and an external
+// Elasticsearch cluster. It is exposed as an admission.Handler.
+type ValidationHandler struct {
+	client  client.Client
+	decoder types.Decoder
+	// decode decodes the resource of the request and returns it along with its version.
+	decode func(decoder types.Decoder, r types.Request) (commonv1alpha1.Associated, string, error)
+}
+
+// NewKibanaValidationHandler returns a ValidationHandler for Kibana resources.
+func NewKibanaValidationHandler() *ValidationHandler {
+	return &ValidationHandler{
+		decode: func(decoder types.Decoder, r types.Request) (commonv1alpha1.Associated, string, error) {
+			var kb kbtype.Kibana
+			err := decoder.Decode(r, &kb)
+			return &kb, kb.Spec.Version, err
+		},
+	}
+}
+
+// NewApmServerValidationHandler returns a ValidationHandler for APM Server resources.
+func NewApmServerValidationHandler() *ValidationHandler {
+	return &ValidationHandler{
+		decode: func(decoder types.Decoder, r types.Request) (commonv1alpha1.Associated, string, error) {
+			var as apmtype.ApmServer
+			err := decoder.Decode(r, &as)
+			return &as, as.Spec.Version, err
+		},
+	}
+}
+
+// Handle processes AdmissionRequests.
+func (v *ValidationHandler) Handle(ctx context.Context, r types.Request) types.Response {
+	if r.AdmissionRequest.Operation == v1beta1.Delete {
+		return admission.ValidationResponse(true, "allowing all deletes")
+	}
+	associated, specVersion, err := v.decode(v.decoder, r)
+	if err != nil {
+		log.Error(err, "Failed to decode request")
+		return admission.ErrorResponse(http.StatusBadRequest, err)
+	}
+	esRef := associated.ElasticsearchRef()
+	if esRef.IsDefined() && associated.ExternalElasticsearchRef().IsDefined() {
+		return admission.ValidationResponse(false, "elasticsearchRef and externalElasticsearchRef cannot be set at the same time")
+	}
+	ver, err := version.Parse(specVersion)
+	if err != nil {
+		return admission.ValidationResponse(false, fmt.Sprintf("invalid version %s: %s", specVersion, err.Error()))
+	}
+	esVersion, err := association.ElasticsearchVersion(k8s.WrapClient(v.client), associated)
+	if err != nil {
+		log.Error(err, "Failed to retrieve the version of the referenced Elasticsearch cluster")
+		return admission.ErrorResponse(http.StatusInternalServerError, err)
+	}
+	if esVersion != nil && !esVersion.IsSameOrAfter(*ver) {
+		return admission.ValidationResponse(false, fmt.Sprintf(
+			"version %s is newer than the version %s of the referenced Elasticsearch cluster, upgrade Elasticsearch first",
+			ver, esVersion,
+		))
+	}
+	return admission.ValidationResponse(true, "")
+}
+
+var _ admission.Handler = &ValidationHandler{}
+
+func (v *ValidationHandler) InjectDecoder(d types.Decoder) error {
+	v.decoder = d
+	return nil
+}
+
+var _ inject.Decoder = &ValidationHandler{}
+
+func (v *ValidationHandler) InjectClient(c client.Client) error {
+	v.client = c
+	return nil
+}
+
+var _ inject.Client = &ValidationHandler{}
@@ -0,0 +1,173 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package association
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	apmtype "github.com/elastic/cloud-on-k8s/pkg/apis/apm/v1alpha1"
+	commonv1alpha1 "github.com/elastic/cloud-on-k8s/pkg/apis/common/v1alpha1"
+	estype "github.com/elastic/cloud-on-k8s/pkg/apis/elasticsearch/v1alpha1"
+	kbtype "github.com/elastic/cloud-on-k8s/pkg/apis/kibana/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/api/admission/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission/types"
+)
+
+type mockDecoder struct {
+	obj runtime.Object
+}
+
+func (m mockDecoder) Decode(_ types.Request, o runtime.Object) error {
+	reflect.ValueOf(o).Elem().Set(reflect.ValueOf(m.obj).Elem())
+	return nil
+}
+
+func TestValidationHandler_Handle(t *testing.T) {
+	require.NoError(t, estype.AddToScheme(scheme.Scheme))
+
+	es := &estype.Elasticsearch{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "es"},
+		Spec:       estype.ElasticsearchSpec{Version: "7.2.0"},
+	}
+	kibana := func(v string, ref string) *kbtype.Kibana {
+		return &kbtype.Kibana{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "kb"},
+			Spec: kbtype.KibanaSpec{
+				Version:          v,
+				ElasticsearchRef: commonv1alpha1.ObjectSelector{Name: ref},
+			},
+		}
+	}
+	apmServer := func(v string) *apmtype.ApmServer {
+		return &apmtype.ApmServer{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "as"},
+			Spec: apmtype.ApmServerSpec{
+				Version:          v,
+				ElasticsearchRef: commonv1alpha1.ObjectSelector{Name: "es"},
+			},
+		}
+	}
+
+	tests := []struct {
+		name        string
+		handler     *ValidationHandler
+		operation   v1beta1.Operation
+		obj         runtime.Object
+		wantAllowed bool
+		wantReason  string
+	}{
+		{
+			name:        "deletes are allowed",
+			handler:     NewKibanaValidationHandler(),
+			operation:   v1beta1.Delete,
+			obj:         kibana("7.3.0", "es"),
+			wantAllowed: true,
+			wantReason:  "allowing all deletes",
+		},
+		{
+			name:        "no Elasticsearch reference",
+			handler:     NewKibanaValidationHandler(),
+			operation:   v1beta1.Update,
+			obj:         kibana("7.3.0", ""),
+			wantAllowed: true,
+		},
+		{
+			name:        "referenced Elasticsearch does not exist",
+			handler:     NewKibanaValidationHandler(),
+			operation:   v1beta1.Update,
+			obj:         kibana("7.3.0", "other"),
+			wantAllowed: true,
+		},
+		{
+			name:        "same version as Elasticsearch",
+			handler:     NewKibanaValidationHandler(),
+			operation:   v1beta1.Update,
+			obj:         kibana("7.2.0", "es"),
+			wantAllowed: true,
+		},
+		{
+			name:        "older version than Elasticsearch",
+			handler:     NewApmServerValidationHandler(),
+			operation:   v1beta1.Create,
+			obj:         apmServer("7.1.0"),
+			wantAllowed: true,
+		},
+		{
+			name:        "Kibana version newer than Elasticsearch",
+			handler:     NewKibanaValidationHandler(),
+			operation:   v1beta1.Update,
+			obj:         kibana("7.3.0", "es"),
+			wantAllowed: false,
+			wantReason:  "version 7.3.0 is newer than the version 7.2.0 of the referenced Elasticsearch cluster, upgrade Elasticsearch first",
+		},
+		{
+			name:        "APM Server version newer than Elasticsearch",
+			handler:     NewApmServerValidationHandler(),
+			operation:   v1beta1.Update,
+			obj:         apmServer("7.3.0"),
+			wantAllowed: false,
+			wantReason:  "version 7.3.0 is newer than the version 7.2.0 of the referenced Elasticsearch cluster, upgrade Elasticsearch first",
+		},
+		{
+			name:      "both managed and external Elasticsearch references",
+			handler:   NewKibanaValidationHandler(),
+			operation: v1beta1.Create,
+			obj: func() runtime.Object {
+				kb := kibana("7.2.0", "es")
+				kb.Spec.ExternalElasticsearchRef = &commonv1alpha1.ExternalElasticsearchRef{
+					URL:        "https://external-es:9200",
+					AuthSecret: commonv1alpha1.SecretRef{SecretName: "external-es-auth"},
+				}
+				return kb
+			}(),
+			wantAllowed: false,
+			wantReason:  "elasticsearchRef and externalElasticsearchRef cannot be set at the same time",
+		},
+		{
+			name:      "external Elasticsearch reference only",
+			handler:   NewApmServerValidationHandler(),
+			operation: v1beta1.Create,
+			obj: func() runtime.Object {
+				as := apmServer("7.3.0")
+				as.Spec.ElasticsearchRef = commonv1alpha1.ObjectSelector{}
+				as.Spec.ExternalElasticsearchRef = &commonv1alpha1.ExternalElasticsearchRef{
+					URL:        "https://external-es:9200",
+					AuthSecret: commonv1alpha1.SecretRef{SecretName: "external-es-auth"},
+				}
+				return as
+			}(),
+			wantAllowed: true,
+		},
+		{
+			name:        "invalid version",
+			handler:     NewKibanaValidationHandler(),
+			operation:   v1beta1.Update,
+			obj:         kibana("not-a-version", "es"),
+			wantAllowed: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.NoError(t, tt.handler.InjectClient(fake.NewFakeClient(es)))
+			require.NoError(t, tt.handler.InjectDecoder(mockDecoder{obj: tt.obj}))
+			got := tt.handler.Handle(context.Background(), types.Request{
+				AdmissionRequest: &v1beta1.AdmissionRequest{Operation: tt.operation},
+			})
+			require.NotNil(t, got.Response)
+			assert.Equal(t, tt.wantAllowed, got.Response.Allowed)
+			if tt.wantReason != "" {
+				assert.Equal(t, tt.wantReason, string(got.Response.Result.Reason))
+			}
+		})
+	}
+}
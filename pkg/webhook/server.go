@@ -7,7 +7,10 @@ package webhook
 import (
 	"context"
 
+	apmv1alpha1 "github.com/elastic/cloud-on-k8s/pkg/apis/apm/v1alpha1"
 	"github.com/elastic/cloud-on-k8s/pkg/apis/elasticsearch/v1alpha1"
+	kbv1alpha1 "github.com/elastic/cloud-on-k8s/pkg/apis/kibana/v1alpha1"
+	"github.com/elastic/cloud-on-k8s/pkg/webhook/association"
 	"github.com/elastic/cloud-on-k8s/pkg/webhook/elasticsearch"
 	"github.com/elastic/cloud-on-k8s/pkg/webhook/license"
 	admission "k8s.io/api/admissionregistration/v1beta1"
@@ -53,6 +56,30 @@ func RegisterValidations(mgr manager.Manager, params Parameters) error {
 		return err
 	}
 
+	kbWh, err := builder.NewWebhookBuilder().
+		Name("validation.kibana.elastic.co").
+		Validating().
+		FailurePolicy(admission.Ignore).
+		ForType(&kbv1alpha1.Kibana{}).
+		Handlers(association.NewKibanaValidationHandler()).
+		WithManager(mgr).
+		Build()
+	if err != nil {
+		return err
+	}
+
+	apmWh, err := builder.NewWebhookBuilder().
+		Name("validation.apm.elastic.co").
+		Validating().
+		FailurePolicy(admission.Ignore).
+		ForType(&apmv1alpha1.ApmServer{}).
+		Handlers(association.NewApmServerValidationHandler()).
+		WithManager(mgr).
+		Build()
+	if err != nil {
+		return err
+	}
+
 	disabled := !params.AutoInstall
 	if params.AutoInstall {
 		// nasty side effect in register function
@@ -75,5 +102,5 @@ func RegisterValidations(mgr manager.Manager, params Parameters) error {
 		return err
 	}
 
-	return svr.Register(esWh, licWh)
+	return svr.Register(esWh, licWh, kbWh, apmWh)
 }